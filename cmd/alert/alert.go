@@ -0,0 +1,238 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/alert"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/usage"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// CodeAlert is the error code returned by --fail-on-alert
+const CodeAlert = "ALERT_TRIGGERED"
+
+// options configures an alert run
+type options struct {
+	targets     []string
+	metrics     []string
+	mode        string
+	webhook     string
+	failOnAlert bool
+	failFast    bool
+	rule        alert.Rule
+}
+
+// NewAlertCmd creates the alert command
+func NewAlertCmd() *cobra.Command {
+	var opts options
+	var below, above, changePct float64
+
+	c := &cobra.Command{
+		Use:   "alert",
+		Short: "Notify a webhook when metrics cross thresholds",
+		Long: `Fetch metrics for one or more targets and check them against thresholds:
+--below and --above compare the current value, --change-pct compares it to
+the value seen by the previous run (kept in the local state file).
+
+Triggered alerts are posted to --webhook as a Slack-compatible message with
+previous and current values and a link to Ahrefs. Every check is written to
+the output. The command exits 0 whether or not an alert fired, unless
+--fail-on-alert is set.
+
+A target that fails, such as one the API doesn't know, is written with its
+error after the others' checks, and the command exits 3; --fail-fast stops
+at the first instead.
+
+Metrics: ` + strings.Join(alert.Metrics(), ", "),
+		Example: `  # Alert Slack when domain rating drops below 70
+  ahrefs alert --target example.com --metric domain_rating --below 70 --webhook $SLACK_URL
+
+  # Several targets and metrics; alert on a 10% swing since the last run
+  ahrefs alert -t example.com -t example.org --metric org_traffic,refdomains --change-pct 10
+
+  # Fail a cron job or CI step when anything fires
+  ahrefs alert --target example.com --metric refdomains --below 1000 --fail-on-alert`,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			flags := cobraCmd.Flags()
+			if flags.Changed("below") {
+				opts.rule.Below = &below
+			}
+			if flags.Changed("above") {
+				opts.rule.Above = &above
+			}
+			if flags.Changed("change-pct") {
+				opts.rule.ChangePct = &changePct
+			}
+			return run(cobraCmd.Context(), opts)
+		},
+	}
+
+	c.Flags().StringSliceVarP(&opts.targets, "target", "t", nil, "Target domain or URL (repeatable)")
+	c.Flags().StringSliceVar(&opts.metrics, "metric", []string{"domain_rating"}, "Metric to check (repeatable)")
+	c.Flags().StringVarP(&opts.mode, "mode", "m", "domain", "Mode: exact, domain, prefix, subdomains")
+	c.Flags().Float64Var(&below, "below", 0, "Alert when a metric is below this value")
+	c.Flags().Float64Var(&above, "above", 0, "Alert when a metric is above this value")
+	c.Flags().Float64Var(&changePct, "change-pct", 0, "Alert when a metric changed by at least this percent since the last run")
+	c.Flags().StringVar(&opts.webhook, "webhook", "", "Webhook URL to post triggered alerts to (e.g., a Slack incoming webhook)")
+	c.Flags().BoolVar(&opts.failOnAlert, "fail-on-alert", false, "Exit non-zero when any alert is triggered")
+	c.Flags().BoolVar(&opts.failFast, "fail-fast", false, "Stop at the first target that fails instead of checking the others")
+
+	cmd.BindEnv(c.Flags(), "webhook", "AHREFS_WEBHOOK_URL")
+	cmd.SetAllowedValues(c, "metric", alert.Metrics()...)
+	cmd.SetAllowedValues(c, "mode", models.Strings(models.Modes())...)
+	c.MarkFlagRequired("target")
+	c.MarkFlagsOneRequired("below", "above", "change-pct")
+
+	return c
+}
+
+func run(ctx context.Context, opts options) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	for _, m := range opts.metrics {
+		if _, ok := alert.Sources[m]; !ok {
+			return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("unknown metric %q", m),
+				"Supported metrics: "+strings.Join(alert.Metrics(), ", "))
+		}
+	}
+
+	apiKey := flags.APIKey
+	if apiKey == "" {
+		apiKey = config.GetAPIKey()
+	}
+	if apiKey == "" {
+		return cmd.ErrAPIKeyRequired
+	}
+
+	c := client.NewClient(client.Config{
+		APIKey:      apiKey,
+		BaseURL:     flags.BaseURL,
+		BaseURLs:    flags.BaseURLs,
+		Headers:     flags.Headers,
+		Timeout:     flags.Timeout,
+		MaxBodySize: flags.MaxBodySize,
+		MaxRetries:  flags.MaxRetries,
+		Middleware:  cmd.ClientMiddleware(flags),
+		Logger:      flags.Log(),
+		OnRetry:     flags.Progress.Retry,
+	})
+
+	// A templated --output writes a file per target; check the names first
+	outputs, err := cmd.TargetOutputs(flags, "alert", opts.targets)
+	if err != nil {
+		return err
+	}
+
+	state, err := alert.LoadState(alert.StateFileName)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var results, triggered []alert.Result
+	totalUnits := 0
+	batch := cmd.NewBatch(flags, opts.failFast)
+	for i, target := range opts.targets {
+		values, units, fetchErr := alert.Fetch(ctx, c, target, opts.mode, opts.metrics)
+		recordUsage(flags.Log(), now, units)
+		if err := batch.Done(target, fetchErr); err != nil {
+			return err
+		}
+		for _, n := range units {
+			totalUnits += n
+		}
+
+		// A failed target has no values to check
+		if fetchErr == nil {
+			for _, metric := range opts.metrics {
+				res := opts.rule.Check(target, metric, values[metric], state.Previous(target, metric))
+				state.Set(target, metric, values[metric], now)
+				results = append(results, res)
+				if res.Triggered {
+					triggered = append(triggered, res)
+				}
+			}
+		}
+		flags.Progress.Target(target, i+1, len(opts.targets), len(results), totalUnits)
+	}
+
+	if err := state.Save(alert.StateFileName); err != nil {
+		return err
+	}
+
+	if opts.webhook != "" && len(triggered) > 0 {
+		if err := alert.Notify(ctx, opts.webhook, triggered); err != nil {
+			return err
+		}
+	}
+
+	if outputs == nil {
+		err = writeResults(flags, results, batch.Failures)
+	} else {
+		for i, target := range opts.targets {
+			if err = writeResults(outputs[i], targetResults(results, target), batch.FailuresOf(target)); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := batch.Err(); err != nil {
+		return err
+	}
+
+	if opts.failOnAlert && len(triggered) > 0 {
+		return cmd.NewError(CodeAlert, fmt.Sprintf("%d alert(s) triggered", len(triggered)), "")
+	}
+	return nil
+}
+
+// writeResults writes results, and the targets that failed, with the
+// output settings of flags
+func writeResults(flags cmd.GlobalFlags, results []alert.Result, failures []output.Failure) error {
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return err
+	}
+	w.SetFailures(failures)
+	if err := w.WriteSuccess(results, nil); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+// targetResults returns the results of target
+func targetResults(results []alert.Result, target string) []alert.Result {
+	var kept []alert.Result
+	for _, r := range results {
+		if r.Target == target {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// recordUsage adds the units spent per endpoint to the usage ledger when a
+// budget is being tracked
+func recordUsage(log *slog.Logger, now time.Time, units map[string]int) {
+	cfg, err := config.Load()
+	if err != nil || !cfg.Budget.Enabled() {
+		return
+	}
+	for endpoint, n := range units {
+		if err := usage.Record(usage.Entry{Time: now, Endpoint: endpoint, Units: n}); err != nil {
+			log.Warn("failed to record usage", "err", err)
+		}
+	}
+}
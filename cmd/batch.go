@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+)
+
+// Batch tracks the targets of a command run for several, so one that fails,
+// such as a target the API doesn't know, doesn't lose the results of the
+// others: its error is kept as a failure, written with their rows, and the
+// command exits with ExitPartial. With FailFast the first failure ends the
+// run instead.
+type Batch struct {
+	FailFast bool
+
+	// Failures are the targets that failed, in order
+	Failures []output.Failure
+
+	targets int
+	first   error // the error of the first failure
+	log     *slog.Logger
+}
+
+// NewBatch returns a batch warning of each failure with the logger of flags
+func NewBatch(flags GlobalFlags, failFast bool) *Batch {
+	return &Batch{FailFast: failFast, log: flags.Log()}
+}
+
+// Done records the outcome of target. It returns err when the batch fails
+// fast, and nil otherwise, so the caller moves on to the next target.
+func (b *Batch) Done(target string, err error) error {
+	b.targets++
+	if err == nil {
+		return nil
+	}
+	if b.FailFast {
+		return err
+	}
+	if b.first == nil {
+		b.first = err
+	}
+	if b.log != nil {
+		b.log.Warn(fmt.Sprintf("%s failed; continuing with the other targets", target), "err", err)
+	}
+	formatted := output.FormatError(err)
+	b.Failures = append(b.Failures, output.Failure{
+		Target:  target,
+		Code:    fmt.Sprint(formatted["code"]),
+		Message: fmt.Sprint(formatted["message"]),
+	})
+	return nil
+}
+
+// FailuresOf returns the failures of target
+func (b *Batch) FailuresOf(target string) []output.Failure {
+	var kept []output.Failure
+	for _, f := range b.Failures {
+		if f.Target == target {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// Err returns the error the command ends with, once its output is written:
+// nil when every target succeeded, a partial failure when some did, and the
+// error of the first target when none did
+func (b *Batch) Err() error {
+	switch {
+	case len(b.Failures) == 0:
+		return nil
+	case len(b.Failures) == b.targets && b.targets == 1:
+		return b.first
+	case len(b.Failures) == b.targets:
+		return fmt.Errorf("all %d targets failed, %s with: %w", b.targets, b.Failures[0].Target, b.first)
+	}
+	return &Error{
+		Code:       CodePartial,
+		Message:    fmt.Sprintf("%d of %d targets failed", len(b.Failures), b.targets),
+		Suggestion: "The output lists each failed target with its error; run them again, or pass --fail-fast to stop at the first failure",
+	}
+}
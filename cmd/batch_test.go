@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+func TestBatch(t *testing.T) {
+	notFound := &client.APIError{StatusCode: 404, Code: "NOT_FOUND", Message: "target not found"}
+
+	tests := []struct {
+		name     string
+		outcomes map[string]error // by target, run in the order of targets
+		targets  []string
+		wantCode int
+		wantErr  string
+	}{
+		{"all ok", map[string]error{}, []string{"a.example", "b.example"}, 0, ""},
+		{"some fail", map[string]error{"b.example": notFound}, []string{"a.example", "b.example", "c.example"}, ExitPartial, "1 of 3 targets failed"},
+		{"all fail", map[string]error{"a.example": notFound, "b.example": errors.New("timeout")}, []string{"a.example", "b.example"}, ExitError, "all 2 targets failed, a.example with: "},
+		{"single target fails", map[string]error{"a.example": notFound}, []string{"a.example"}, ExitError, "target not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Batch
+			for _, target := range tt.targets {
+				if err := b.Done(target, tt.outcomes[target]); err != nil {
+					t.Fatalf("Done(%s) error = %v, want nil without FailFast", target, err)
+				}
+			}
+			err := b.Err()
+			if code := ExitCode(err); code != tt.wantCode {
+				t.Errorf("ExitCode(%v) = %d, want %d", err, code, tt.wantCode)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Err() = %v, want %q", err, tt.wantErr)
+			}
+			if len(b.Failures) != len(tt.outcomes) {
+				t.Errorf("Failures = %+v, want one per failed target", b.Failures)
+			}
+		})
+	}
+}
+
+func TestBatch_Failures(t *testing.T) {
+	var b Batch
+	b.Done("a.example", nil)
+	b.Done("b.example", &client.APIError{StatusCode: 404, Code: "NOT_FOUND", Message: "target not found"})
+	b.Done("c.example", NewError(CodeUsage, "bad target", ""))
+
+	if f := b.FailuresOf("b.example"); len(f) != 1 || f[0].Code != "NOT_FOUND" || f[0].Message != "target not found" {
+		t.Errorf("FailuresOf(b.example) = %+v, want the API error's code and message", f)
+	}
+	if f := b.FailuresOf("c.example"); len(f) != 1 || f[0].Code != CodeUsage {
+		t.Errorf("FailuresOf(c.example) = %+v, want the CLI error's code", f)
+	}
+	if f := b.FailuresOf("a.example"); len(f) != 0 {
+		t.Errorf("FailuresOf(a.example) = %+v, want none", f)
+	}
+}
+
+func TestBatch_FailFast(t *testing.T) {
+	b := Batch{FailFast: true}
+	failure := errors.New("timeout")
+	if err := b.Done("a.example", nil); err != nil {
+		t.Fatalf("Done() error = %v", err)
+	}
+	if err := b.Done("b.example", failure); err != failure {
+		t.Errorf("Done() error = %v, want the target's error", err)
+	}
+	if len(b.Failures) != 0 {
+		t.Errorf("Failures = %+v, want none kept when failing fast", b.Failures)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// byteUnits are the size suffixes --max-body-size accepts, largest first
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// byteSize is a flag value holding a size in bytes, given as a number with
+// an optional B, KB, MB, or GB suffix. 0 means no limit and is stored as -1.
+type byteSize int64
+
+// parseByteSize parses a size such as 100MB or 512KB
+func parseByteSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	unit := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: want a number of bytes with an optional KB, MB, or GB suffix", value)
+	}
+	return n * unit, nil
+}
+
+func (b *byteSize) String() string {
+	n := int64(*b)
+	if n < 0 {
+		return "0"
+	}
+	for _, u := range byteUnits {
+		if n >= u.size && n%u.size == 0 {
+			return strconv.FormatInt(n/u.size, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10) + "B"
+}
+
+func (b *byteSize) Type() string { return "size" }
+
+func (b *byteSize) Set(value string) error {
+	n, err := parseByteSize(value)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		n = -1
+	}
+	*b = byteSize(n)
+	return nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import "testing"
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantStr string
+		wantErr bool
+	}{
+		{value: "100MB", want: 100 << 20, wantStr: "100MB"},
+		{value: "1gb", want: 1 << 30, wantStr: "1GB"},
+		{value: " 512 KB", want: 512 << 10, wantStr: "512KB"},
+		{value: "1536", want: 1536, wantStr: "1536B"},
+		{value: "2048B", want: 2048, wantStr: "2KB"},
+		{value: "0", want: -1, wantStr: "0"},
+		{value: "-5MB", wantErr: true},
+		{value: "lots", wantErr: true},
+		{value: "1.5GB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var b byteSize
+			err := b.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if int64(b) != tt.want || b.String() != tt.wantStr {
+				t.Errorf("Set(%q) = %d (%s), want %d (%s)", tt.value, int64(b), b.String(), tt.want, tt.wantStr)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/history"
+	"github.com/spf13/cobra"
+)
+
+// Entry describes a kept response
+type Entry struct {
+	Command string    `json:"command"`
+	SavedAt time.Time `json:"saved_at"`
+	Age     string    `json:"age"`
+	Size    int64     `json:"size_bytes"`
+}
+
+// NewCacheCmd creates the cache command
+func NewCacheCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect responses kept in the local cache",
+		Long: `Inspect the responses kept in the CLI cache directory.
+
+The latest successful response of each site-explorer command and set of
+flags is kept, and --last writes it again in any output format without an
+API call.`,
+	}
+
+	c.AddCommand(newListCmd())
+	return c
+}
+
+func newListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List kept responses with their age and size",
+		Example: `  # Responses --last can replay, newest first
+  ahrefs cache ls --format table
+
+  # Replay one as CSV
+  ahrefs site-explorer backlinks --target ahrefs.com --last --format csv`,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runList(cobraCmd.Context())
+		},
+	}
+}
+
+func runList(ctx context.Context) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	infos, err := history.List()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	entries := make([]Entry, 0, len(infos))
+	for _, info := range infos {
+		entries = append(entries, Entry{
+			Command: info.Key,
+			SavedAt: info.SavedAt,
+			Age:     now.Sub(info.SavedAt).Round(time.Second).String(),
+			Size:    info.Size,
+		})
+	}
+
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteSuccess(entries, nil); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 
 	"github.com/aminemat/ahrefs-cli/internal/config"
 	"github.com/spf13/cobra"
@@ -16,6 +18,8 @@ func NewConfigCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(newSetKeyCmd())
+	cmd.AddCommand(newSetDefaultCmd())
+	cmd.AddCommand(newSetBudgetCmd())
 	cmd.AddCommand(newShowCmd())
 	cmd.AddCommand(newValidateCmd())
 
@@ -33,9 +37,11 @@ func newSetKeyCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			apiKey := args[0]
 
-			cfg := &config.Config{
-				APIKey: apiKey,
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
 			}
+			cfg.APIKey = apiKey
 
 			if err := config.Save(cfg); err != nil {
 				return fmt.Errorf("failed to save config: %w", err)
@@ -47,6 +53,138 @@ func newSetKeyCmd() *cobra.Command {
 	}
 }
 
+func newSetDefaultCmd() *cobra.Command {
+	var command string
+	c := &cobra.Command{
+		Use:   "set-default <flag> <value>",
+		Short: "Set a default value for a flag",
+		Long: `Save a default value for any flag to the configuration file (~/.ahrefsrc).
+
+Config defaults apply when neither the flag nor its environment variable is set.
+Pass an empty value to remove a default.
+
+With --command, the default applies to that command alone, named by its path
+below ahrefs such as "site-explorer backlinks". A command's defaults take
+precedence over environment variables and the defaults of every command, so
+one command can retry more or time out sooner than the rest.`,
+		Args: cobra.ExactArgs(2),
+		Example: `  # Default to CSV output
+  ahrefs config set-default format csv
+
+  # Default country for keyword commands
+  ahrefs config set-default country us
+
+  # Remove a default
+  ahrefs config set-default country ""
+
+  # Retry backlinks pulls harder, and fail domain rating checks fast
+  ahrefs config set-default --command "site-explorer backlinks" max-retries 8
+  ahrefs config set-default --command "site-explorer backlinks" timeout 5m
+  ahrefs config set-default --command "site-explorer domain-rating" timeout 10s`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, value := args[0], args[1]
+
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			if command == "" {
+				cfg.Defaults = setDefault(cfg.Defaults, name, value)
+			} else {
+				if cfg.Commands == nil {
+					cfg.Commands = map[string]map[string]string{}
+				}
+				if cfg.Commands[command] = setDefault(cfg.Commands[command], name, value); len(cfg.Commands[command]) == 0 {
+					delete(cfg.Commands, command)
+				}
+			}
+
+			if err := config.Save(cfg); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+
+			if command == "" {
+				fmt.Printf("Default for --%s saved\n", name)
+			} else {
+				fmt.Printf("Default for --%s of %s saved\n", name, command)
+			}
+			return nil
+		},
+	}
+	c.Flags().StringVar(&command, "command", "", `Set the default for this command alone, e.g. "site-explorer backlinks"`)
+	return c
+}
+
+// setDefault sets the default of flag name in defaults to value, or removes
+// it when value is empty, returning the defaults
+func setDefault(defaults map[string]string, name, value string) map[string]string {
+	if value == "" {
+		delete(defaults, name)
+		return defaults
+	}
+	if defaults == nil {
+		defaults = map[string]string{}
+	}
+	defaults[name] = value
+	return defaults
+}
+
+func newSetBudgetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set-budget <monthly-units> [warn-percent]",
+		Short: "Set a monthly API unit budget",
+		Long: `Save budget.monthly_units and budget.warn_percent to the configuration file.
+
+Once a budget is set, units consumed by each command are recorded in a local
+usage ledger. A warning is printed on stderr when month-to-date usage reaches
+warn-percent (default 80) of the budget, and an error when it is exceeded.
+Commands still exit 0 unless --enforce-budget is set.
+
+Set monthly-units to 0 to disable tracking.`,
+		Args: cobra.RangeArgs(1, 2),
+		Example: `  # 100k units per month, warn at 80%
+  ahrefs config set-budget 100000
+
+  # Warn at 90%
+  ahrefs config set-budget 100000 90
+
+  # Disable budget tracking
+  ahrefs config set-budget 0`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			monthly, err := strconv.Atoi(args[0])
+			if err != nil || monthly < 0 {
+				return fmt.Errorf("invalid monthly units %q: must be a non-negative integer", args[0])
+			}
+
+			warn := 0
+			if len(args) == 2 {
+				warn, err = strconv.Atoi(args[1])
+				if err != nil || warn < 1 || warn > 100 {
+					return fmt.Errorf("invalid warn percent %q: must be between 1 and 100", args[1])
+				}
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			cfg.Budget = config.Budget{MonthlyUnits: monthly, WarnPercent: warn}
+
+			if err := config.Save(cfg); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+
+			if monthly == 0 {
+				fmt.Println("Budget tracking disabled")
+			} else {
+				fmt.Printf("Monthly budget set to %d units (warn at %d%%)\n", monthly, cfg.Budget.WarnAt())
+			}
+			return nil
+		},
+	}
+}
+
 func newShowCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "show",
@@ -67,6 +205,25 @@ func newShowCmd() *cobra.Command {
 				fmt.Printf("API Key: %s\n", masked)
 			}
 
+			if cfg.Budget.Enabled() {
+				fmt.Printf("Budget: %d units/month (warn at %d%%)\n", cfg.Budget.MonthlyUnits, cfg.Budget.WarnAt())
+			}
+
+			if len(cfg.Defaults) > 0 {
+				fmt.Println("Defaults:")
+				printDefaults(cfg.Defaults)
+			}
+
+			commands := make([]string, 0, len(cfg.Commands))
+			for command := range cfg.Commands {
+				commands = append(commands, command)
+			}
+			sort.Strings(commands)
+			for _, command := range commands {
+				fmt.Printf("Defaults of %s:\n", command)
+				printDefaults(cfg.Commands[command])
+			}
+
 			return nil
 		},
 	}
@@ -96,3 +253,15 @@ func maskAPIKey(key string) string {
 	}
 	return key[:4] + "****" + key[len(key)-4:]
 }
+
+// printDefaults prints flag defaults indented, one per line sorted by flag
+func printDefaults(defaults map[string]string) {
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %s: %s\n", name, defaults[name])
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/redact"
+)
+
+// Process exit codes
+const (
+	// ExitError is the exit code of a failed command
+	ExitError = 1
+
+	// ExitPartial is the exit code of a command run for several targets
+	// some of which failed, while the others' results were written
+	ExitPartial = 3
+
+	// ExitPanic is the exit code of a command that crashed, EX_SOFTWARE
+	ExitPanic = 70
+)
+
+// ExitCode returns the process exit code for a command's error
+func ExitCode(err error) int {
+	var coded *Error
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &coded) && coded.Code == CodePanic:
+		return ExitPanic
+	case errors.As(err, &coded) && coded.Code == CodePartial:
+		return ExitPartial
+	}
+	var piped *PipeError
+	if errors.As(err, &piped) && piped.ExitCode > 0 {
+		return piped.ExitCode
+	}
+	return ExitError
+}
+
+// panicError converts a recovered panic into a coded error, writing its stack
+// to a crash log whose path the suggestion names
+func panicError(r interface{}, stack []byte, args []string) error {
+	suggestion := "This is a bug. Please report it at https://github.com/aminemat/ahrefs-cli/issues"
+	path, err := writeCrashLog(r, stack, args, time.Now())
+	if err != nil {
+		commandLogger(rootCmd).Warn("crash log not written", "err", err)
+	} else {
+		suggestion += ", attaching the crash log " + path
+	}
+	return NewError(CodePanic, fmt.Sprintf("unexpected panic: %v", r), suggestion)
+}
+
+// writeCrashLog writes the panic value, command line, and stack to a new file
+// in the crashes directory of the cache, and returns its path
+func writeCrashLog(r interface{}, stack []byte, args []string, now time.Time) (string, error) {
+	cache, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cache, "crashes")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create crash directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(dir, "crash-"+now.UTC().Format("20060102T150405Z")+"-*.log")
+	if err != nil {
+		return "", fmt.Errorf("failed to create crash log: %w", err)
+	}
+	defer f.Close()
+
+	// Secret flags are masked in the args; keys anywhere else by value
+	apiKey, _ := rootCmd.PersistentFlags().GetString("api-key")
+	w := redact.Writer(f, secrets(apiKey)...)
+
+	fmt.Fprintf(w, "ahrefs %s (%s %s/%s)\n", rootCmd.Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(w, "time: %s\n", now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "args: %s\n", strings.Join(redactArgs(args), " "))
+	fmt.Fprintf(w, "panic: %v\n\n", r)
+	if _, err := w.Write(stack); err != nil {
+		return "", fmt.Errorf("failed to write crash log: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// secretFlags are flags whose values are left out of crash logs
+var secretFlags = []string{"--api-key", "--notify-header", "--header", "--token"}
+
+// redactArgs returns args with the values of secret flags masked
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i, arg := range redacted {
+		for _, name := range secretFlags {
+			switch {
+			case arg == name && i+1 < len(redacted):
+				redacted[i+1] = "***"
+			case strings.HasPrefix(arg, name+"="):
+				redacted[i] = name + "=***"
+			}
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,252 @@
+package difffiles
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/rowfile"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// Kinds of change between the rows of two files
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+// keySeparator joins the values of several --key columns
+const keySeparator = " | "
+
+// Change is a row added, removed, or changed between two saved outputs,
+// identified by its key. A changed row has one change per field that
+// differs; added and removed rows have no field.
+type Change struct {
+	Key    string `json:"key"`
+	Change string `json:"change" enum:"added,removed,changed"`
+	Field  string `json:"field"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+}
+
+// options configures a diff
+type options struct {
+	key        []string
+	ignore     []string
+	duplicates string
+}
+
+// NewDiffFilesCmd creates the diff-files command
+func NewDiffFilesCmd() *cobra.Command {
+	var opts options
+
+	c := &cobra.Command{
+		Use:   "diff-files <old> <new>",
+		Short: "Compare two saved outputs row by row",
+		Long: `Compare two outputs saved with --output, joining their rows on --key, and
+write the rows added, removed, and changed, with the old and new value of
+every field that changed. No request is made, so no units are spent.
+
+Each file is JSON, NDJSON, or CSV, told by its extension (.json, .ndjson or
+.jsonl, .csv) or else by its content, and the two needn't match. Values are
+compared as the text of a CSV cell, so a JSON export compares equal to a CSV
+one of the same rows. Only the columns both files have are compared, and
+fetched_at is ignored, as it differs on every run; --ignore sets the columns
+left out.
+
+A key shared by several rows of a file is an error, as their changes can't
+be told apart. Join on more columns with a comma-separated --key, or keep
+the first or last of them with --duplicates.`,
+		Example: `  # Backlinks gained, lost, and changed since last week's export
+  ahrefs diff-files backlinks-old.json backlinks-new.json --key url_from,url_to --format table
+
+  # Keyword positions, from CSV exports, as CSV
+  ahrefs diff-files keywords-june.csv keywords-july.csv --key keyword --ignore fetched_at,traffic --format csv
+
+  # Referring domains, keeping the last row of any domain listed twice
+  ahrefs diff-files old.ndjson new.ndjson --key domain --duplicates last`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runDiffFiles(cobraCmd.Context(), args[0], args[1], opts)
+		},
+	}
+
+	c.Flags().StringSliceVar(&opts.key, "key", nil, "Comma-separated columns identifying a row in both files (required)")
+	c.Flags().StringSliceVar(&opts.ignore, "ignore", []string{output.TimestampField}, "Comma-separated columns left out of the comparison")
+	c.Flags().StringVar(&opts.duplicates, "duplicates", "error", "Rows sharing a key: error, first (keep the first), last (keep the last)")
+	cmd.SetAllowedValues(c, "duplicates", "error", "first", "last")
+	c.MarkFlagRequired("key")
+
+	return c
+}
+
+func runDiffFiles(ctx context.Context, oldPath, newPath string, opts options) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	oldFile, err := read(oldPath, opts)
+	if err != nil {
+		return err
+	}
+	newFile, err := read(newPath, opts)
+	if err != nil {
+		return err
+	}
+	oldRows, err := index(oldPath, oldFile, opts)
+	if err != nil {
+		return err
+	}
+	newRows, err := index(newPath, newFile, opts)
+	if err != nil {
+		return err
+	}
+
+	fields, oldOnly, newOnly := compared(oldFile, newFile, opts)
+	log := flags.Log()
+	if len(oldOnly) > 0 {
+		log.Info(fmt.Sprintf("Columns only %s has aren't compared: %s", oldPath, strings.Join(oldOnly, ", ")))
+	}
+	if len(newOnly) > 0 {
+		log.Info(fmt.Sprintf("Columns only %s has aren't compared: %s", newPath, strings.Join(newOnly, ", ")))
+	}
+
+	changes := diff(oldRows, newRows, fields)
+	counts := map[string]map[string]bool{}
+	for _, c := range changes {
+		if counts[c.Change] == nil {
+			counts[c.Change] = map[string]bool{}
+		}
+		counts[c.Change][c.Key] = true
+	}
+	log.Info(fmt.Sprintf("%d rows added, %d removed, %d changed", len(counts[changeAdded]), len(counts[changeRemoved]), len(counts[changeChanged])))
+
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteSuccess(changes, nil); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+// read reads the saved output in path, which must have the --key columns
+// unless it has no rows
+func read(path string, opts options) (*rowfile.File, error) {
+	f, err := rowfile.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved output: %w", err)
+	}
+	if len(f.Rows) == 0 {
+		return f, nil
+	}
+	for _, name := range opts.key {
+		if !f.HasColumn(name) {
+			return nil, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("%s has no %s column", path, name),
+				"Use --key with columns of both files: "+strings.Join(f.Columns, ", "))
+		}
+	}
+	return f, nil
+}
+
+// keyedRows are the rows of a file by key, in the order of the file
+type keyedRows struct {
+	keys []string
+	rows map[string]map[string]string
+}
+
+// index keys the rows of f, the file in path, by their --key columns,
+// keeping the first or last of rows sharing one as --duplicates says
+func index(path string, f *rowfile.File, opts options) (keyedRows, error) {
+	indexed := keyedRows{rows: make(map[string]map[string]string, len(f.Rows))}
+	shared := map[string]int{}
+	for _, row := range f.Rows {
+		values := make([]string, len(opts.key))
+		for i, name := range opts.key {
+			values[i] = row[name]
+		}
+		key := strings.Join(values, keySeparator)
+
+		if _, ok := indexed.rows[key]; !ok {
+			indexed.keys = append(indexed.keys, key)
+			indexed.rows[key] = row
+			continue
+		}
+		shared[key]++
+		if opts.duplicates == "last" {
+			indexed.rows[key] = row
+		}
+	}
+
+	if len(shared) > 0 && opts.duplicates == "error" {
+		example := ""
+		for _, key := range indexed.keys {
+			if shared[key] > 0 {
+				example = key
+				break
+			}
+		}
+		return keyedRows{}, cmd.NewError(cmd.CodeUsage,
+			fmt.Sprintf("%s has %d %s values shared by several rows, such as %q (%d rows)", path, len(shared), strings.Join(opts.key, keySeparator), example, shared[example]+1),
+			"Join on more columns, e.g. --key "+strings.Join(opts.key, ",")+",<column>, or keep one row per key with --duplicates first or --duplicates last")
+	}
+	return indexed, nil
+}
+
+// compared returns the columns compared, those of the old file that the new
+// one has too, in the old file's order, but for the key and ignored columns.
+// It also returns the columns only one of the files has.
+func compared(oldFile, newFile *rowfile.File, opts options) (fields, oldOnly, newOnly []string) {
+	skip := map[string]bool{}
+	for _, name := range opts.key {
+		skip[name] = true
+	}
+	for _, name := range opts.ignore {
+		skip[name] = true
+	}
+	bothHave := len(oldFile.Rows) > 0 && len(newFile.Rows) > 0
+	for _, name := range oldFile.Columns {
+		switch {
+		case skip[name]:
+		case newFile.HasColumn(name):
+			fields = append(fields, name)
+		case bothHave:
+			oldOnly = append(oldOnly, name)
+		}
+	}
+	for _, name := range newFile.Columns {
+		if !skip[name] && !oldFile.HasColumn(name) && bothHave {
+			newOnly = append(newOnly, name)
+		}
+	}
+	return fields, oldOnly, newOnly
+}
+
+// diff returns the changes from oldRows to newRows: the rows removed or
+// changed, in the old file's order, then the rows added, in the new one's
+func diff(oldRows, newRows keyedRows, fields []string) []Change {
+	changes := []Change{}
+	for _, key := range oldRows.keys {
+		newRow, ok := newRows.rows[key]
+		if !ok {
+			changes = append(changes, Change{Key: key, Change: changeRemoved})
+			continue
+		}
+		oldRow := oldRows.rows[key]
+		for _, field := range fields {
+			if oldRow[field] != newRow[field] {
+				changes = append(changes, Change{Key: key, Change: changeChanged, Field: field, Old: oldRow[field], New: newRow[field]})
+			}
+		}
+	}
+	for _, key := range newRows.keys {
+		if _, ok := oldRows.rows[key]; !ok {
+			changes = append(changes, Change{Key: key, Change: changeAdded})
+		}
+	}
+	return changes
+}
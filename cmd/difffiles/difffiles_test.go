@@ -0,0 +1,153 @@
+package difffiles
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/rowfile"
+	"github.com/spf13/pflag"
+)
+
+// execDiff runs the diff-files command with args, and returns its output
+func execDiff(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	c := NewDiffFilesCmd()
+	cmd.AddCommands(c)
+	c.Root().PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if list, ok := f.Value.(pflag.SliceValue); ok {
+			_ = list.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	var buf bytes.Buffer
+	c.SetOut(&buf)
+	c.SetContext(context.Background())
+	if err := cmd.Prepare(c, args); err != nil {
+		t.Fatalf("Prepare(%v) error = %v", args, err)
+	}
+	err := c.RunE(c, c.Flags().Args())
+	return buf.String(), err
+}
+
+// writeFile writes data to name in a temporary directory
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiffFiles(t *testing.T) {
+	// Last week's backlinks as JSON, this week's as CSV
+	oldPath := writeFile(t, "old.json", `{"status":"success","data":{"backlinks":[
+		{"url_from":"https://a.example/","url_to":"https://t.com/","domain_rating":40,"anchor":"t","fetched_at":"2024-06-01T00:00:00Z"},
+		{"url_from":"https://b.example/","url_to":"https://t.com/","domain_rating":20,"anchor":"gone","fetched_at":"2024-06-01T00:00:00Z"},
+		{"url_from":"https://a.example/","url_to":"https://t.com/x","domain_rating":40,"anchor":"x","fetched_at":"2024-06-01T00:00:00Z"}]}}`)
+	newPath := writeFile(t, "new.csv", "url_from,url_to,domain_rating,anchor,fetched_at\n"+
+		"https://a.example/,https://t.com/,45,t,2024-06-08T00:00:00Z\n"+
+		"https://a.example/,https://t.com/x,40,\"x, y\",2024-06-08T00:00:00Z\n"+
+		"https://c.example/,https://t.com/,10,new,2024-06-08T00:00:00Z\n")
+
+	out, err := execDiff(t, oldPath, newPath, "--key", "url_from,url_to", "--format", "csv")
+	if err != nil {
+		t.Fatalf("diff-files error = %v", err)
+	}
+	want := "key,change,field,old,new\n" +
+		"https://a.example/ | https://t.com/,changed,domain_rating,40,45\n" +
+		"https://b.example/ | https://t.com/,removed,,,\n" +
+		"https://a.example/ | https://t.com/x,changed,anchor,x,\"x, y\"\n" +
+		"https://c.example/ | https://t.com/,added,,,\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	// Nothing changed but the fetch time
+	out, err = execDiff(t, oldPath, oldPath, "--key", "url_from,url_to", "--format", "json")
+	if err != nil {
+		t.Fatalf("diff-files error = %v", err)
+	}
+	if !strings.Contains(out, `"data": []`) {
+		t.Errorf("output = %s, want no changes", out)
+	}
+}
+
+func TestDiffFiles_Errors(t *testing.T) {
+	oldPath := writeFile(t, "old.csv", "domain,domain_rating\na.example,10\na.example,12\n")
+	newPath := writeFile(t, "new.csv", "domain,domain_rating\na.example,15\n")
+
+	for _, args := range [][]string{
+		{oldPath, newPath, "--key", "domain"},
+		{oldPath, newPath, "--key", "url_from"},
+	} {
+		_, err := execDiff(t, args...)
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+			t.Errorf("%v: error = %v, want a usage error", args, err)
+		}
+	}
+	if _, err := execDiff(t, oldPath, filepath.Join(t.TempDir(), "missing.csv"), "--key", "domain"); err == nil {
+		t.Error("diff-files with a missing file should fail")
+	}
+}
+
+func TestIndex_Duplicates(t *testing.T) {
+	f := &rowfile.File{
+		Columns: []string{"domain", "domain_rating"},
+		Rows: []map[string]string{
+			{"domain": "a.example", "domain_rating": "10"},
+			{"domain": "b.example", "domain_rating": "30"},
+			{"domain": "a.example", "domain_rating": "12"},
+			{"domain": "a.example", "domain_rating": "14"},
+		},
+	}
+
+	_, err := index("old.csv", f, options{key: []string{"domain"}, duplicates: "error"})
+	if err == nil || !strings.Contains(err.Error(), `old.csv has 1 domain values shared by several rows, such as "a.example" (3 rows)`) {
+		t.Errorf("index() error = %v, want the shared key", err)
+	}
+
+	for dup, want := range map[string]string{"first": "10", "last": "14"} {
+		got, err := index("old.csv", f, options{key: []string{"domain"}, duplicates: dup})
+		if err != nil {
+			t.Fatalf("index() error = %v", err)
+		}
+		if !reflect.DeepEqual(got.keys, []string{"a.example", "b.example"}) || got.rows["a.example"]["domain_rating"] != want {
+			t.Errorf("--duplicates %s: index() = %+v, want a.example's rating %s", dup, got, want)
+		}
+	}
+
+	// A second key column tells the rows apart
+	f.Columns = append(f.Columns, "url")
+	for i, row := range f.Rows {
+		row["url"] = string(rune('a' + i))
+	}
+	if _, err := index("old.csv", f, options{key: []string{"domain", "url"}, duplicates: "error"}); err != nil {
+		t.Errorf("index() error = %v, want none with a compound key", err)
+	}
+}
+
+func TestCompared(t *testing.T) {
+	oldFile := &rowfile.File{Columns: []string{"keyword", "volume", "position", "fetched_at", "cpc"}, Rows: []map[string]string{{}}}
+	newFile := &rowfile.File{Columns: []string{"keyword", "position", "volume", "fetched_at", "traffic"}, Rows: []map[string]string{{}}}
+
+	fields, oldOnly, newOnly := compared(oldFile, newFile, options{key: []string{"keyword"}, ignore: []string{"fetched_at"}})
+	if !reflect.DeepEqual(fields, []string{"volume", "position"}) {
+		t.Errorf("fields = %v, want volume, position", fields)
+	}
+	if !reflect.DeepEqual(oldOnly, []string{"cpc"}) || !reflect.DeepEqual(newOnly, []string{"traffic"}) {
+		t.Errorf("columns only in one file = %v, %v, want cpc, traffic", oldOnly, newOnly)
+	}
+}
@@ -0,0 +1,393 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// checkTimeout bounds each network check
+	checkTimeout = 10 * time.Second
+
+	// maxClockSkew is the largest tolerated difference from the API server clock
+	maxClockSkew = 5 * time.Minute
+
+	// lowUnitsRatio warns when less than this share of the unit limit remains
+	lowUnitsRatio = 0.1
+)
+
+// Check statuses
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Check is the result of a single diagnostic
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Remedy string `json:"remedy,omitempty"`
+}
+
+// NewDoctorCmd creates the doctor command
+func NewDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose configuration, connectivity, and API access",
+		Long: `Run a battery of checks and report pass/fail with remedies:
+config file, file permissions, API key, network reachability, proxy settings,
+clock skew, remaining API units, and cache directory writability.
+
+Exits non-zero when any check fails, so CI can gate on it.`,
+		Example: `  # Run all checks
+  ahrefs doctor
+
+  # Machine-readable report
+  ahrefs doctor --format json`,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runDoctor(cobraCmd.Context())
+		},
+	}
+}
+
+func runDoctor(ctx context.Context) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	var checks []Check
+	checks = append(checks, checkConfig()...)
+
+	apiKeyCheck, apiKey := checkAPIKey(flags.APIKey)
+	checks = append(checks, apiKeyCheck)
+	base := flags.BaseURL
+	if base == "" {
+		base = client.BaseURL
+	}
+	checks = append(checks, checkNetwork(ctx, base), checkProxy(base), checkClock(ctx, base))
+	checks = append(checks, checkAPIAccess(ctx, apiKey, base, flags)...)
+	checks = append(checks, checkCacheDir())
+
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteSuccess(checks, nil); err != nil {
+		w.Abort()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, c := range checks {
+		if c.Status == StatusFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// checkConfig verifies the config file is readable, parseable, and private
+func checkConfig() []Check {
+	fileCheck := Check{Name: "config_file"}
+	permCheck := Check{Name: "config_permissions"}
+
+	path, err := config.Path()
+	if err != nil {
+		fileCheck.Status = StatusFail
+		fileCheck.Detail = err.Error()
+		permCheck.Status = StatusSkip
+		return []Check{fileCheck, permCheck}
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		fileCheck.Status = StatusWarn
+		fileCheck.Detail = fmt.Sprintf("%s not found (using flags and environment only)", path)
+		fileCheck.Remedy = "Run 'ahrefs config set-key <your-key>' to create it"
+		permCheck.Status = StatusSkip
+		return []Check{fileCheck, permCheck}
+	}
+	if err != nil {
+		fileCheck.Status = StatusFail
+		fileCheck.Detail = err.Error()
+		permCheck.Status = StatusSkip
+		return []Check{fileCheck, permCheck}
+	}
+
+	if _, err := config.Load(); err != nil {
+		fileCheck.Status = StatusFail
+		fileCheck.Detail = err.Error()
+		fileCheck.Remedy = fmt.Sprintf("Fix or remove %s and run 'ahrefs config set-key <your-key>'", path)
+	} else {
+		fileCheck.Status = StatusPass
+		fileCheck.Detail = path
+	}
+
+	switch {
+	case !config.PermissionsApply:
+		permCheck.Status = StatusSkip
+		permCheck.Detail = "not applicable on Windows"
+	case info.Mode().Perm()&0077 != 0:
+		permCheck.Status = StatusFail
+		permCheck.Detail = fmt.Sprintf("%s is accessible by other users (%s)", path, info.Mode().Perm())
+		permCheck.Remedy = fmt.Sprintf("Run 'chmod 600 %s'", path)
+	default:
+		permCheck.Status = StatusPass
+		permCheck.Detail = info.Mode().Perm().String()
+	}
+
+	return []Check{fileCheck, permCheck}
+}
+
+// checkAPIKey resolves the API key the same way commands do
+func checkAPIKey(flagKey string) (Check, string) {
+	check := Check{Name: "api_key"}
+	_, suggestion, _ := client.Suggest(http.StatusUnauthorized)
+
+	switch {
+	case flagKey != "":
+		check.Status = StatusPass
+		check.Detail = "from --api-key flag or AHREFS_API_KEY"
+		return check, flagKey
+	case config.GetAPIKey() != "":
+		check.Status = StatusPass
+		check.Detail = "from config file"
+		return check, config.GetAPIKey()
+	default:
+		check.Status = StatusFail
+		check.Detail = "no API key configured"
+		check.Remedy = suggestion
+		return check, ""
+	}
+}
+
+// checkNetwork verifies a TCP connection to the API host can be opened
+func checkNetwork(ctx context.Context, base string) Check {
+	check := Check{Name: "network"}
+
+	u, err := url.Parse(base)
+	if err != nil || u.Hostname() == "" {
+		check.Status = StatusFail
+		check.Detail = fmt.Sprintf("invalid base URL %q", base)
+		check.Remedy = "Fix --base-url or AHREFS_BASE_URL"
+		return check
+	}
+
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	dialer := net.Dialer{Timeout: checkTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = fmt.Sprintf("cannot reach %s: %v", host, err)
+		check.Remedy = "Check your internet connection, firewall, and proxy settings"
+		return check
+	}
+	conn.Close()
+
+	check.Status = StatusPass
+	check.Detail = fmt.Sprintf("%s:%s reachable", host, port)
+	return check
+}
+
+// checkProxy reports whether an HTTP proxy will be used for API requests
+func checkProxy(base string) Check {
+	check := Check{Name: "proxy", Status: StatusPass}
+
+	req, err := http.NewRequest(http.MethodGet, base, nil)
+	if err != nil {
+		check.Status = StatusSkip
+		check.Detail = err.Error()
+		return check
+	}
+
+	proxyURL, err := http.ProxyFromEnvironment(req)
+	switch {
+	case err != nil:
+		check.Status = StatusFail
+		check.Detail = fmt.Sprintf("invalid proxy configuration: %v", err)
+		check.Remedy = "Fix the HTTPS_PROXY / HTTP_PROXY environment variables"
+	case proxyURL != nil:
+		redacted := *proxyURL
+		redacted.User = nil
+		check.Detail = fmt.Sprintf("using proxy %s", redacted.String())
+	default:
+		check.Detail = "no proxy configured"
+	}
+	return check
+}
+
+// checkClock compares the local clock with the API server's Date header
+func checkClock(ctx context.Context, base string) Check {
+	check := Check{Name: "clock_skew"}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, base, nil)
+	if err != nil {
+		check.Status = StatusSkip
+		check.Detail = err.Error()
+		return check
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		check.Status = StatusSkip
+		check.Detail = "API server unreachable"
+		return check
+	}
+	resp.Body.Close()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		check.Status = StatusSkip
+		check.Detail = "server did not report its time"
+		return check
+	}
+
+	skew := time.Since(serverTime).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxClockSkew {
+		check.Status = StatusFail
+		check.Detail = fmt.Sprintf("local clock differs from server by %s", skew)
+		check.Remedy = "Synchronize your system clock (e.g., enable NTP)"
+		return check
+	}
+
+	check.Status = StatusPass
+	check.Detail = fmt.Sprintf("within %s of server time", skew)
+	return check
+}
+
+// checkAPIAccess validates the API key and reports remaining units using the
+// free subscription usage endpoint
+func checkAPIAccess(ctx context.Context, apiKey, base string, flags cmd.GlobalFlags) []Check {
+	keyCheck := Check{Name: "api_key_valid"}
+	unitsCheck := Check{Name: "remaining_units"}
+
+	if apiKey == "" {
+		keyCheck.Status = StatusSkip
+		unitsCheck.Status = StatusSkip
+		return []Check{keyCheck, unitsCheck}
+	}
+
+	maxRetries := 1
+	if flags.MaxRetries < 0 {
+		maxRetries = flags.MaxRetries
+	}
+	c := client.NewClient(client.Config{
+		APIKey:      apiKey,
+		BaseURL:     base,
+		Headers:     flags.Headers,
+		Timeout:     flags.Timeout,
+		MaxBodySize: flags.MaxBodySize,
+		MaxRetries:  maxRetries,
+		Middleware:  cmd.ClientMiddleware(flags),
+		Logger:      flags.Log(),
+	})
+
+	resp, err := c.Get(ctx, "/subscription-info/limits-and-usage", url.Values{})
+	if err != nil {
+		keyCheck.Status = StatusFail
+		keyCheck.Detail = err.Error()
+		var apiErr *client.APIError
+		if errors.As(err, &apiErr) {
+			keyCheck.Remedy = apiErr.Suggestion
+		} else {
+			keyCheck.Remedy = "Check network connectivity to the API"
+		}
+		unitsCheck.Status = StatusSkip
+		return []Check{keyCheck, unitsCheck}
+	}
+
+	var result models.LimitsAndUsageResponse
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		keyCheck.Status = StatusPass
+		unitsCheck.Status = StatusSkip
+		unitsCheck.Detail = fmt.Sprintf("failed to parse usage response: %v", err)
+		return []Check{keyCheck, unitsCheck}
+	}
+
+	usage := result.LimitsAndUsage
+	keyCheck.Status = StatusPass
+	keyCheck.Detail = usage.Subscription
+
+	limit, used := usage.UnitsLimitWorkspace, usage.UnitsUsageWorkspace
+	if limit == 0 {
+		unitsCheck.Status = StatusPass
+		unitsCheck.Detail = fmt.Sprintf("%d units used, no limit reported", used)
+		return []Check{keyCheck, unitsCheck}
+	}
+
+	remaining := limit - used
+	unitsCheck.Detail = fmt.Sprintf("%d of %d units remaining (resets %s)", remaining, limit, usage.UsageResetDate)
+	switch {
+	case remaining <= 0:
+		unitsCheck.Status = StatusFail
+		_, unitsCheck.Remedy, _ = client.Suggest(http.StatusTooManyRequests)
+	case float64(remaining) < float64(limit)*lowUnitsRatio:
+		unitsCheck.Status = StatusWarn
+		unitsCheck.Remedy = "Fewer than 10% of units remain this period"
+	default:
+		unitsCheck.Status = StatusPass
+	}
+
+	return []Check{keyCheck, unitsCheck}
+}
+
+// checkCacheDir verifies the cache directory exists and is writable
+func checkCacheDir() Check {
+	check := Check{Name: "cache_dir"}
+
+	dir, err := config.CacheDir()
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = err.Error()
+		check.Remedy = "Ensure your user cache directory exists and is writable"
+		return check
+	}
+
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		check.Status = StatusFail
+		check.Detail = fmt.Sprintf("%s is not writable: %v", dir, err)
+		check.Remedy = fmt.Sprintf("Fix permissions on %s", dir)
+		return check
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	check.Status = StatusPass
+	check.Detail = dir
+	return check
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// allowedValuer is implemented by flag values restricted to a set of values
+type allowedValuer interface {
+	AllowedValues() []string
+}
+
+// enumValue is a string flag value accepting only the values allowed, which
+// --list-commands reports. A flag without a default may also be set empty,
+// which leaves it unset.
+type enumValue struct {
+	pflag.Value
+	allowed []string
+	empty   bool
+}
+
+func (e *enumValue) Set(value string) error {
+	if value == "" && e.empty || slices.Contains(e.allowed, value) {
+		return e.Value.Set(value)
+	}
+	return fmt.Errorf("want one of %s", strings.Join(e.allowed, ", "))
+}
+
+// AllowedValues returns the values the flag accepts
+func (e *enumValue) AllowedValues() []string {
+	return e.allowed
+}
+
+// allowedValues returns the values flag accepts, from its value type or, for
+// flags that aren't restricted, the values recorded for it
+func allowedValues(flag *pflag.Flag) []string {
+	if v, ok := flag.Value.(allowedValuer); ok {
+		return v.AllowedValues()
+	}
+	return flag.Annotations[allowedValuesAnnotation]
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envAnnotation is the flag annotation key holding the environment variable name
+const envAnnotation = "ahrefs_env"
+
+// envPrefix starts the environment variable derived for a persistent flag
+const envPrefix = "AHREFS_"
+
+// envDenied are the persistent flags never read from the environment
+var envDenied = map[string]bool{
+	// A variable left exported must not let --header or the config file
+	// replace the Authorization header carrying the API key
+	"allow-override-auth": true,
+	// Re-raising panics with the stack trace is for debugging one run
+	"debug-panic": true,
+}
+
+// BindEnv registers env as the environment variable providing the default
+// for the named flag and documents it in the flag's usage string.
+//
+// Defaults are resolved with the precedence flag > env > command config >
+// config > built-in.
+func BindEnv(flags *pflag.FlagSet, name, env string) {
+	flag := flags.Lookup(name)
+	if flag == nil {
+		panic(fmt.Sprintf("BindEnv: flag %q not defined", name))
+	}
+	flag.Usage = fmt.Sprintf("%s [env: %s]", flag.Usage, env)
+	_ = flags.SetAnnotation(name, envAnnotation, []string{env})
+}
+
+// applyDefaults fills every flag that wasn't set on the command line from its
+// environment variable, the config file's defaults for c, or the config file
+// defaults section, in that order, and returns the flags it filled
+func applyDefaults(c *cobra.Command) ([]*pflag.Flag, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		commandLogger(c).Warn("ignoring config defaults", "err", err)
+		cfg = &config.Config{}
+	}
+
+	var filled []*pflag.Flag
+	var applyErr error
+	c.Flags().VisitAll(func(flag *pflag.Flag) {
+		if applyErr != nil || flag.Changed {
+			return
+		}
+
+		value, source, ok := lookupDefault(flag, flagEnv(c, flag), cfg, commandName(c))
+		if !ok {
+			return
+		}
+		if err := flag.Value.Set(value); err != nil {
+			applyErr = fmt.Errorf("invalid value %q for --%s from %s: %w", value, flag.Name, source, err)
+			return
+		}
+		filled = append(filled, flag)
+	})
+
+	return filled, applyErr
+}
+
+// validateFlags checks c's required flags and flag groups, counting the
+// flags applyDefaults filled as given, so a value from the environment or
+// config conflicts with another flag as it would on the command line.
+// Cobra checks required flags again after the pre-run hooks, where those
+// filled are waived.
+func validateFlags(c *cobra.Command, filled []*pflag.Flag) error {
+	for _, flag := range filled {
+		flag.Changed = true
+	}
+	err := c.ValidateRequiredFlags()
+	if err == nil {
+		err = c.ValidateFlagGroups()
+	}
+	for _, flag := range filled {
+		flag.Changed = false
+		if _, ok := flag.Annotations[cobra.BashCompOneRequiredFlag]; ok {
+			flag.Annotations[cobra.BashCompOneRequiredFlag] = []string{"false"}
+		}
+	}
+	return err
+}
+
+// lookupDefault returns the default for flag when running command, and where
+// it came from. env is the flag's environment variable, if any.
+func lookupDefault(flag *pflag.Flag, env string, cfg *config.Config, command string) (value, source string, ok bool) {
+	if value, ok := os.LookupEnv(env); ok && env != "" && value != "" {
+		return value, env, true
+	}
+
+	if value, ok := cfg.Commands[command][flag.Name]; ok && value != "" {
+		return value, fmt.Sprintf("config defaults of %q", command), true
+	}
+
+	if value, ok := cfg.Defaults[flag.Name]; ok && value != "" {
+		return value, "config defaults", true
+	}
+
+	return "", "", false
+}
+
+// flagEnv returns the environment variable of c's flag: the one bound by
+// BindEnv, or for a persistent flag, AHREFS_ and its name in upper case with
+// underscores, such as AHREFS_DRY_RUN for --dry-run. It's "" for a flag
+// without one.
+func flagEnv(c *cobra.Command, flag *pflag.Flag) string {
+	if envs, found := flag.Annotations[envAnnotation]; found && len(envs) > 0 {
+		return envs[0]
+	}
+	if c.PersistentFlags().Lookup(flag.Name) == nil && c.InheritedFlags().Lookup(flag.Name) == nil {
+		return ""
+	}
+	return autoEnv(flag.Name)
+}
+
+// autoEnv returns the environment variable derived for the persistent flag
+// name, or "" when it's in envDenied
+func autoEnv(name string) string {
+	if envDenied[name] {
+		return ""
+	}
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// documentEnv adds the environment variable derived for each flag of flags
+// to its usage string, as BindEnv does for the one it binds
+func documentEnv(flags *pflag.FlagSet) {
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if _, bound := flag.Annotations[envAnnotation]; bound {
+			return
+		}
+		if env := autoEnv(flag.Name); env != "" {
+			flag.Usage = fmt.Sprintf("%s [env: %s]", flag.Usage, env)
+		}
+	})
+}
+
+// commandName returns the path of c below the root command, such as
+// "site-explorer backlinks", which its config defaults are set for
+func commandName(c *cobra.Command) string {
+	return strings.TrimPrefix(c.CommandPath(), rootName+" ")
+}
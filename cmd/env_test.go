@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestApplyDefaults_Precedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		env        string
+		configVal  string
+		commandVal string
+		want       string
+	}{
+		{name: "built-in default", want: "json"},
+		{name: "config default", configVal: "table", want: "table"},
+		{name: "env over config", env: "csv", configVal: "table", want: "csv"},
+		{name: "flag over env", args: []string{"--format", "yaml"}, env: "csv", configVal: "table", want: "yaml"},
+		{name: "command config", commandVal: "csv", want: "csv"},
+		{name: "env over command config", env: "yaml", configVal: "table", commandVal: "csv", want: "yaml"},
+		{name: "command config over config defaults", configVal: "table", commandVal: "csv", want: "csv"},
+		{name: "flag over command config", args: []string{"--format", "yaml"}, commandVal: "csv", want: "yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("AHREFS_FORMAT", tt.env)
+			cfg := &config.Config{Defaults: map[string]string{"format": tt.configVal}}
+			if tt.commandVal != "" {
+				// Another command's defaults don't apply
+				cfg.Commands = map[string]map[string]string{"test": {"format": tt.commandVal}, "other": {"format": "table"}}
+			}
+			if err := config.Save(cfg); err != nil {
+				t.Fatalf("config.Save() error = %v", err)
+			}
+
+			var format string
+			c := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+			c.Flags().StringVar(&format, "format", "json", "Output format")
+			BindEnv(c.Flags(), "format", "AHREFS_FORMAT")
+
+			if err := c.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+			if _, err := applyDefaults(c); err != nil {
+				t.Fatalf("applyDefaults() error = %v", err)
+			}
+
+			if format != tt.want {
+				t.Errorf("format = %v, want %v", format, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDefaults_InvalidEnvValue(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AHREFS_TIMEOUT", "soon")
+
+	c := &cobra.Command{Use: "test"}
+	c.Flags().Duration("timeout", 0, "Timeout")
+	BindEnv(c.Flags(), "timeout", "AHREFS_TIMEOUT")
+
+	if _, err := applyDefaults(c); err == nil {
+		t.Error("applyDefaults() with invalid duration should return error")
+	}
+}
+
+func TestBindEnv_Usage(t *testing.T) {
+	c := &cobra.Command{Use: "test"}
+	c.Flags().String("country", "", "Country code")
+	BindEnv(c.Flags(), "country", "AHREFS_COUNTRY")
+
+	got := c.Flags().Lookup("country").Usage
+	want := "Country code [env: AHREFS_COUNTRY]"
+	if got != want {
+		t.Errorf("Usage = %q, want %q", got, want)
+	}
+}
+
+func TestApplyDefaults_PersistentEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AHREFS_DRY_RUN", "true")
+	t.Setenv("AHREFS_CONFIRM_THRESHOLD", "50")
+	t.Setenv("AHREFS_NOTIFY_HEADER", "X-Token: secret")
+	t.Setenv("AHREFS_ALLOW_OVERRIDE_AUTH", "true")
+	t.Setenv("AHREFS_LOCAL", "set")
+
+	parent := &cobra.Command{Use: "parent"}
+	parent.PersistentFlags().Bool("dry-run", false, "")
+	parent.PersistentFlags().Int("confirm-threshold", 1000, "")
+	parent.PersistentFlags().StringArray("notify-header", nil, "")
+	parent.PersistentFlags().Bool("allow-override-auth", false, "")
+	c := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	c.Flags().String("local", "", "")
+	parent.AddCommand(c)
+	if err := c.ParseFlags(nil); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+
+	if _, err := applyDefaults(c); err != nil {
+		t.Fatalf("applyDefaults() error = %v", err)
+	}
+	if dryRun, _ := c.Flags().GetBool("dry-run"); !dryRun {
+		t.Error("--dry-run should be set from AHREFS_DRY_RUN")
+	}
+	if threshold, _ := c.Flags().GetInt("confirm-threshold"); threshold != 50 {
+		t.Errorf("--confirm-threshold = %d, want 50 from AHREFS_CONFIRM_THRESHOLD", threshold)
+	}
+	if headers, _ := c.Flags().GetStringArray("notify-header"); len(headers) != 1 || headers[0] != "X-Token: secret" {
+		t.Errorf("--notify-header = %q, want it from AHREFS_NOTIFY_HEADER", headers)
+	}
+	if allow, _ := c.Flags().GetBool("allow-override-auth"); allow {
+		t.Error("--allow-override-auth should never be read from the environment")
+	}
+	if local, _ := c.Flags().GetString("local"); local != "" {
+		t.Errorf("--local = %q, want no environment variable for a local flag", local)
+	}
+}
+
+func TestDocumentEnv(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Bool("dry-run", false, "Validate request without executing")
+	flags.Bool("allow-override-auth", false, "Allow overriding auth")
+	flags.String("country", "", "Country code")
+	BindEnv(flags, "country", "AHREFS_COUNTRY")
+	documentEnv(flags)
+
+	for name, want := range map[string]string{
+		"dry-run":             "Validate request without executing [env: AHREFS_DRY_RUN]",
+		"allow-override-auth": "Allow overriding auth",
+		"country":             "Country code [env: AHREFS_COUNTRY]",
+	} {
+		if got := flags.Lookup(name).Usage; got != want {
+			t.Errorf("--%s usage = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestApplyDefaults_Validated(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		defaults map[string]string
+		wantErr  string
+	}{
+		{name: "invalid header from env", env: map[string]string{"AHREFS_HEADER": "bogus"}, defaults: map[string]string{"name": "ada"},
+			wantErr: "want 'Name: value'"},
+		{name: "exclusive flag from config", args: []string{"--a", "1"}, defaults: map[string]string{"name": "ada", "b": "2"},
+			wantErr: "[a b] were all set"},
+		{name: "required flag from config", defaults: map[string]string{"name": "ada"}},
+		{name: "required flag missing", wantErr: `"name" not set`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			cfg := &config.Config{Commands: map[string]map[string]string{"check": tt.defaults}}
+			if err := config.Save(cfg); err != nil {
+				t.Fatalf("config.Save() error = %v", err)
+			}
+
+			ran := false
+			c := &cobra.Command{Use: "check", RunE: func(*cobra.Command, []string) error {
+				ran = true
+				return nil
+			}}
+			c.Flags().String("a", "", "")
+			c.Flags().String("b", "", "")
+			c.Flags().String("name", "", "")
+			c.MarkFlagsMutuallyExclusive("a", "b")
+			c.MarkFlagRequired("name")
+			rootCmd.AddCommand(c)
+			defer rootCmd.RemoveCommand(c)
+			defer resetFlags(rootCmd)
+			rootCmd.SetErr(&bytes.Buffer{})
+			defer rootCmd.SetErr(nil)
+
+			err := execute(context.Background(), append([]string{"check"}, tt.args...))
+			if tt.wantErr == "" {
+				if err != nil || !ran {
+					t.Errorf("execute() error = %v, ran %v, want it run", err, ran)
+				}
+				return
+			}
+			var cliErr *Error
+			if !errors.As(err, &cliErr) || cliErr.Code != CodeUsage || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("execute() error = %v, want a usage error mentioning %q", err, tt.wantErr)
+			}
+			if ran {
+				t.Error("command ran despite the invalid value")
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// Error codes for failures raised by the CLI itself rather than the API
+const (
+	CodeUsage  = "USAGE_ERROR"
+	CodeAuth   = "AUTH_ERROR"
+	CodeConfig = "CONFIG_ERROR"
+	CodeBudget = "BUDGET_EXCEEDED"
+	CodePanic  = "INTERNAL_ERROR"
+
+	// CodePartial is the error of a command run for several targets some
+	// of which failed
+	CodePartial = "PARTIAL_FAILURE"
+)
+
+// Error is a CLI failure with a machine-readable code and a suggested remedy
+type Error struct {
+	Code       string
+	Message    string
+	Suggestion string
+	Err        error
+
+	// Details lists the individual problems, such as each invalid parameter
+	Details []client.FieldError
+}
+
+// NewError creates a coded CLI error
+func NewError(code, message, suggestion string) *Error {
+	return &Error{Code: code, Message: message, Suggestion: suggestion}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the machine-readable error code
+func (e *Error) ErrorCode() string {
+	return e.Code
+}
+
+// ErrorSuggestion returns the suggested remedy
+func (e *Error) ErrorSuggestion() string {
+	return e.Suggestion
+}
+
+// ErrorDetails returns the individual problems
+func (e *Error) ErrorDetails() []client.FieldError {
+	return e.Details
+}
+
+// ErrAPIKeyRequired is returned when no API key can be resolved
+var ErrAPIKeyRequired = NewError(CodeAuth,
+	"API key required. Set via --api-key flag, AHREFS_API_KEY env var, or 'ahrefs config set-key'",
+	"Run 'ahrefs config set-key <your-key>' to configure")
+
+// usageError wraps errors raised by cobra before a command runs
+func usageError(c *cobra.Command, err error) error {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return err
+	}
+	return &Error{
+		Code:       CodeUsage,
+		Message:    err.Error(),
+		Suggestion: fmt.Sprintf("Run '%s --help' for usage", c.CommandPath()),
+		Err:        err,
+	}
+}
+
+// defaultsError reports an invalid environment or config default
+func defaultsError(err error) error {
+	return &Error{
+		Code:       CodeConfig,
+		Message:    err.Error(),
+		Suggestion: "Fix the environment variable or run 'ahrefs config show' to check config defaults",
+		Err:        err,
+	}
+}
+
+// reportError prints err to stderr, as a single JSON object when JSON errors
+// are enabled for c's command line args and as plain text otherwise
+func reportError(c *cobra.Command, err error, args []string) {
+	stderr := stderrOf(c)
+	verbose := globalFlags(c).Verbose
+
+	if wantJSONErrors(c, args) {
+		formatted := output.FormatError(err)
+		if verbose {
+			formatted = output.FormatVerboseError(err)
+		}
+		payload := map[string]interface{}{
+			"status": "error",
+			"error":  formatted,
+		}
+		if encErr := json.NewEncoder(stderr).Encode(payload); encErr == nil {
+			return
+		}
+	}
+
+	fmt.Fprintf(stderr, "Error: %v\n", err)
+
+	// The server's explanation, such as why a where expression was rejected
+	var apiErr *client.APIError
+	if verbose && errors.As(err, &apiErr) && apiErr.RawDetail != "" {
+		fmt.Fprintf(stderr, "Response body:\n%s\n", apiErr.RawDetail)
+	}
+
+	var coded *Error
+	if errors.As(err, &coded) && coded.Code == CodeUsage {
+		fmt.Fprintln(stderr)
+		fmt.Fprint(stderr, c.UsageString())
+	}
+}
+
+// wantJSONErrors reports whether errors should be emitted as JSON: with
+// --json-errors or an explicit --format json. Raw arguments are consulted too
+// because flag parsing may have failed before the flags were bound.
+func wantJSONErrors(c *cobra.Command, args []string) bool {
+	flags := globalFlags(c)
+	if flags.JSONErrors {
+		return true
+	}
+	if f := c.Flags().Lookup("format"); f != nil && f.Changed && flags.OutputFormat == "json" {
+		return true
+	}
+
+	for i, arg := range args {
+		switch {
+		case arg == "--json-errors" || arg == "--json-errors=true":
+			return true
+		case arg == "--format=json":
+			return true
+		case arg == "--format" && i+1 < len(args) && args[i+1] == "json":
+			return true
+		case arg == "--":
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// ExitSummary is the line --print-exit-summary writes to stderr as the
+// process ends, whatever the output format
+type ExitSummary struct {
+	Command       string `json:"command"`
+	OK            bool   `json:"ok"`
+	Rows          int    `json:"rows"`
+	UnitsConsumed int    `json:"units_consumed"`
+	Requests      int    `json:"requests"`
+	DurationMS    int64  `json:"duration_ms"`
+	Cached        bool   `json:"cached"`
+	ExitCode      int    `json:"exit_code"`
+
+	// Timing is where the time of the requests went
+	Timing ExitTiming `json:"timing"`
+}
+
+// printExitSummary writes the summary of c's run to stderr as a single JSON
+// line when --print-exit-summary is set
+func printExitSummary(c *cobra.Command, run *runStats, runErr error, elapsed time.Duration) {
+	if !exitSummaryRequested(c) {
+		return
+	}
+	s := summarize(c, "", run, runErr, elapsed)
+	run.mu.Lock()
+	timing := exitTiming(run.timing)
+	run.mu.Unlock()
+	line, err := json.Marshal(ExitSummary{
+		Command:       s.Command,
+		OK:            runErr == nil,
+		Rows:          s.Rows,
+		UnitsConsumed: s.UnitsConsumed,
+		Requests:      s.Requests,
+		DurationMS:    s.DurationMS,
+		Cached:        s.Cached,
+		ExitCode:      s.ExitCode,
+		Timing:        timing,
+	})
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(c.ErrOrStderr(), string(line))
+}
+
+// exitSummaryRequested reports whether --print-exit-summary is set. Its
+// environment variable is checked here too, as a run that fails flag
+// validation ends before environment variables are applied.
+func exitSummaryRequested(c *cobra.Command) bool {
+	flag := c.Flags().Lookup("print-exit-summary")
+	if flag == nil {
+		return false
+	}
+	if flag.Value.String() == "true" {
+		return true
+	}
+	if flag.Changed {
+		return false
+	}
+	requested, _ := strconv.ParseBool(os.Getenv("AHREFS_PRINT_EXIT_SUMMARY"))
+	return requested
+}
@@ -0,0 +1,87 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+)
+
+// lastLine returns the last line of s
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
+
+func TestPrintExitSummary(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+	srv := secretServer(t)
+
+	tests := []struct {
+		name string
+		args []string
+		env  string
+		want cmd.ExitSummary
+	}{
+		{
+			name: "success",
+			args: []string{"site-explorer", "backlinks", "-t", "t.com", "--format", "csv", "--print-exit-summary"},
+			want: cmd.ExitSummary{Command: "ahrefs site-explorer backlinks", OK: true, Rows: 1, Requests: 1,
+				Timing: cmd.ExitTiming{Attempts: 1}},
+		},
+		{
+			name: "cached",
+			args: []string{"site-explorer", "backlinks", "-t", "t.com", "--last", "--print-exit-summary"},
+			want: cmd.ExitSummary{Command: "ahrefs site-explorer backlinks", OK: true, Rows: 1, Cached: true},
+		},
+		{
+			name: "API error",
+			args: []string{"site-explorer", "refdomains", "-t", "t.com", "--no-retry", "--print-exit-summary"},
+			want: cmd.ExitSummary{Command: "ahrefs site-explorer refdomains", Requests: 1, ExitCode: cmd.ExitError,
+				Timing: cmd.ExitTiming{Attempts: 1}},
+		},
+		{
+			name: "usage error from the environment",
+			args: []string{"site-explorer", "backlinks", "--bogus-flag"},
+			env:  "true",
+			want: cmd.ExitSummary{Command: "ahrefs site-explorer backlinks", ExitCode: cmd.ExitError},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AHREFS_PRINT_EXIT_SUMMARY", tt.env)
+			_, stderr, _ := cmd.Run(t, append(tt.args, "--api-key", "test-key", "--base-url", srv.URL)...)
+
+			var got cmd.ExitSummary
+			if err := json.Unmarshal([]byte(lastLine(stderr)), &got); err != nil {
+				t.Fatalf("last stderr line %q is not a summary: %v", lastLine(stderr), err)
+			}
+			// Each attempt has a connection, new or kept from an earlier
+			// test; the times vary
+			if conns := got.Timing.NewConnections + got.Timing.ReusedConnections; conns != got.Timing.Attempts {
+				t.Errorf("timing has %d connections for %d attempts", conns, got.Timing.Attempts)
+			}
+			got.DurationMS = 0
+			got.Timing = cmd.ExitTiming{Attempts: got.Timing.Attempts}
+			if got != tt.want {
+				t.Errorf("summary = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintExitSummary_Off(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AHREFS_PRINT_EXIT_SUMMARY", "")
+	srv := secretServer(t)
+
+	_, stderr, err := cmd.Run(t, "site-explorer", "backlinks", "-t", "t.com", "--api-key", "test-key", "--base-url", srv.URL)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if strings.Contains(stderr, `"exit_code"`) {
+		t.Errorf("stderr = %q, want no summary without --print-exit-summary", stderr)
+	}
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+// Run executes args against the root command as Execute does, returning what
+// it wrote to stdout and stderr, for tests of commands added to it
+func Run(t *testing.T, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	defer resetFlags(rootCmd)
+
+	var out, errOut bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&errOut)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetErr(nil)
+
+	err = execute(context.Background(), args)
+	return out.String(), errOut.String(), err
+}
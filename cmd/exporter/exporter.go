@@ -0,0 +1,352 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/paths"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout bounds how long scrapes in flight may finish on exit
+const shutdownTimeout = 5 * time.Second
+
+// options configures the exporter
+type options struct {
+	targetsFile string
+	interval    time.Duration
+	listen      string
+	mode        string
+	rps         float64
+}
+
+// NewExporterCmd creates the exporter command
+func NewExporterCmd() *cobra.Command {
+	var opts options
+
+	c := &cobra.Command{
+		Use:   "exporter",
+		Short: "Expose site metrics as Prometheus gauges",
+		Long: `Periodically fetch domain rating, organic traffic, organic keywords,
+referring domains, and live backlinks for every target and serve them on
+/metrics in the Prometheus text format, labeled by target.
+
+Scrapes always return the last-known values; a failed refresh keeps the
+previous value and increments ahrefs_api_errors_total. Units consumed are
+exported as ahrefs_units_consumed_total.
+
+The targets file lists one domain or URL per line; blank lines and lines
+starting with # are ignored.`,
+		Example: `  # Refresh every 6 hours, serve on :9309
+  ahrefs exporter --targets-file domains.txt --interval 6h --listen :9309
+
+  # Scrape config
+  scrape_configs:
+    - job_name: ahrefs
+      static_configs:
+        - targets: ['localhost:9309']`,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return run(cobraCmd.Context(), opts)
+		},
+	}
+
+	c.Flags().StringVar(&opts.targetsFile, "targets-file", "", "File listing one target per line (required)")
+	c.Flags().DurationVar(&opts.interval, "interval", 6*time.Hour, "Time between refreshes")
+	c.Flags().StringVar(&opts.listen, "listen", ":9309", "Address to serve /metrics on")
+	c.Flags().StringVar(&opts.mode, "mode", "domain", "Mode: exact, domain, prefix, subdomains")
+	c.Flags().Float64Var(&opts.rps, "rps", 1, "Maximum API requests per second")
+	c.MarkFlagRequired("targets-file")
+
+	return c
+}
+
+func run(ctx context.Context, opts options) error {
+	if opts.interval <= 0 || opts.rps <= 0 {
+		return fmt.Errorf("--interval and --rps must be positive")
+	}
+
+	targets, err := readTargets(paths.Expand(opts.targetsFile))
+	if err != nil {
+		return err
+	}
+
+	flags := cmd.GetGlobalFlags(ctx)
+	apiKey := flags.APIKey
+	if apiKey == "" {
+		apiKey = config.GetAPIKey()
+	}
+	if apiKey == "" {
+		return cmd.ErrAPIKeyRequired
+	}
+
+	c := client.NewClient(client.Config{
+		APIKey:      apiKey,
+		BaseURL:     flags.BaseURL,
+		BaseURLs:    flags.BaseURLs,
+		Headers:     flags.Headers,
+		Timeout:     flags.Timeout,
+		MaxBodySize: flags.MaxBodySize,
+		MaxRetries:  flags.MaxRetries,
+		RateLimit:   opts.rps,
+		Middleware:  cmd.ClientMiddleware(flags),
+		Logger:      flags.Log(),
+	})
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	m := newMetrics()
+	r := &refresher{client: c, metrics: m, mode: opts.mode, log: flags.Log()}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m)
+	srv := &http.Server{Addr: opts.listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	r.log.Info(fmt.Sprintf("Exporting %d target(s) on http://%s/metrics every %s", len(targets), opts.listen, opts.interval))
+
+	go func() {
+		ticker := time.NewTicker(opts.interval)
+		defer ticker.Stop()
+		for {
+			r.refresh(ctx, targets)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// readTargets reads one target per line, skipping blanks and # comments
+func readTargets(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open targets file: %w", err)
+	}
+	defer f.Close()
+
+	var targets []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read targets file: %w", err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets in %s", path)
+	}
+	return targets, nil
+}
+
+// refresher fetches metrics for targets into metrics
+type refresher struct {
+	client  *client.Client
+	metrics *metrics
+	mode    string
+	log     *slog.Logger
+}
+
+// refresh updates every target's gauges. Failed requests leave the previous
+// values in place.
+func (r *refresher) refresh(ctx context.Context, targets []string) {
+	date := time.Now().UTC().Format("2006-01-02")
+
+	for _, target := range targets {
+		params := url.Values{"target": {target}, "mode": {r.mode}, "date": {date}}
+
+		refreshed := false
+
+		var dr models.DomainRatingResponse
+		if r.get(ctx, "/site-explorer/domain-rating", params, &dr) {
+			r.metrics.setGauge("ahrefs_domain_rating", target, dr.DomainRating.DomainRating)
+			refreshed = true
+		}
+
+		var site models.MetricsResponse
+		if r.get(ctx, "/site-explorer/metrics", params, &site) {
+			r.metrics.setGauge("ahrefs_org_traffic", target, float64(site.Metrics.OrgTraffic))
+			r.metrics.setGauge("ahrefs_org_keywords", target, float64(site.Metrics.OrgKeywords))
+			refreshed = true
+		}
+
+		var stats models.BacklinksStatsResponse
+		if r.get(ctx, "/site-explorer/backlinks-stats", params, &stats) {
+			r.metrics.setGauge("ahrefs_refdomains", target, float64(stats.Metrics.Refdomains))
+			r.metrics.setGauge("ahrefs_backlinks", target, float64(stats.Metrics.Live))
+			refreshed = true
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		if refreshed {
+			r.metrics.setGauge("ahrefs_last_refresh_timestamp_seconds", target, float64(time.Now().Unix()))
+		}
+	}
+}
+
+// get performs one request, recording units and errors. The client paces
+// requests to --rps.
+func (r *refresher) get(ctx context.Context, endpoint string, params url.Values, result interface{}) bool {
+	resp, err := r.client.Get(ctx, endpoint, params)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.metrics.addError(endpoint)
+			r.log.Warn("request failed", "err", err, "endpoint", endpoint, "target", params.Get("target"))
+		}
+		return false
+	}
+	r.metrics.addUnits(resp.Meta.UnitsConsumed)
+
+	if err := json.Unmarshal(resp.Body, result); err != nil {
+		r.metrics.addError(endpoint)
+		r.log.Warn("failed to parse response", "err", err, "endpoint", endpoint, "target", params.Get("target"))
+		return false
+	}
+	return true
+}
+
+// gaugeHelp documents each exported gauge
+var gaugeHelp = map[string]string{
+	"ahrefs_domain_rating":                  "Domain rating of the target.",
+	"ahrefs_org_traffic":                    "Estimated monthly organic traffic of the target.",
+	"ahrefs_org_keywords":                   "Number of organic keywords the target ranks for.",
+	"ahrefs_refdomains":                     "Number of referring domains linking to the target.",
+	"ahrefs_backlinks":                      "Number of live backlinks to the target.",
+	"ahrefs_last_refresh_timestamp_seconds": "Unix time of the last refresh that updated any of the target's metrics.",
+}
+
+// metrics holds the last-known values served on /metrics
+type metrics struct {
+	mu        sync.RWMutex
+	gauges    map[string]map[string]float64
+	units     float64
+	apiErrors map[string]float64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		gauges:    map[string]map[string]float64{},
+		apiErrors: map[string]float64{},
+	}
+}
+
+func (m *metrics) setGauge(name, target string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.gauges[name] == nil {
+		m.gauges[name] = map[string]float64{}
+	}
+	m.gauges[name][target] = value
+}
+
+func (m *metrics) addUnits(units int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.units += float64(units)
+}
+
+func (m *metrics) addError(endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiErrors[endpoint]++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format, sorted
+// by name and label for stable output
+func (m *metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var b strings.Builder
+
+	names := make([]string, 0, len(m.gauges))
+	for name := range m.gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, gaugeHelp[name], name)
+		writeSamples(&b, name, "target", m.gauges[name])
+	}
+
+	fmt.Fprintf(&b, "# HELP ahrefs_units_consumed_total API units consumed by the exporter.\n# TYPE ahrefs_units_consumed_total counter\n")
+	fmt.Fprintf(&b, "ahrefs_units_consumed_total %s\n", formatValue(m.units))
+
+	fmt.Fprintf(&b, "# HELP ahrefs_api_errors_total Failed API requests by endpoint.\n# TYPE ahrefs_api_errors_total counter\n")
+	writeSamples(&b, "ahrefs_api_errors_total", "endpoint", m.apiErrors)
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// writeSamples writes one sample per label value, sorted by label value
+func writeSamples(b *strings.Builder, name, label string, values map[string]float64) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s{%s=\"%s\"} %s\n", name, label, labelEscaper.Replace(k), formatValue(values[k]))
+	}
+}
+
+// labelEscaper escapes label values for the text exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,92 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/internal/logging"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+func TestRefresher(t *testing.T) {
+	failing := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("X-API-Units-Consumed", "50")
+		switch r.URL.Path {
+		case "/site-explorer/domain-rating":
+			io.WriteString(w, `{"domain_rating":{"domain_rating":91}}`)
+		case "/site-explorer/metrics":
+			io.WriteString(w, `{"metrics":{"org_traffic":672410,"org_keywords":1184230}}`)
+		case "/site-explorer/backlinks-stats":
+			io.WriteString(w, `{"metrics":{"live":4210398,"refdomains":61870}}`)
+		}
+	}))
+	defer srv.Close()
+
+	m := newMetrics()
+	r := &refresher{
+		client:  client.NewClient(client.Config{APIKey: "test", BaseURL: srv.URL, MaxRetries: 1}),
+		metrics: m,
+		mode:    "domain",
+		log:     logging.Discard(),
+	}
+
+	r.refresh(context.Background(), []string{"ahrefs.com"})
+	failing = true
+	r.refresh(context.Background(), []string{"ahrefs.com"})
+
+	var buf bytes.Buffer
+	m.WriteTo(&buf)
+	got := buf.String()
+
+	for _, want := range []string{
+		"# TYPE ahrefs_domain_rating gauge",
+		`ahrefs_domain_rating{target="ahrefs.com"} 91`,
+		`ahrefs_org_traffic{target="ahrefs.com"} 672410`,
+		`ahrefs_org_keywords{target="ahrefs.com"} 1184230`,
+		`ahrefs_refdomains{target="ahrefs.com"} 61870`,
+		`ahrefs_backlinks{target="ahrefs.com"} 4210398`,
+		"ahrefs_units_consumed_total 150",
+		`ahrefs_api_errors_total{endpoint="/site-explorer/metrics"} 1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestWriteSamples_EscapesLabels(t *testing.T) {
+	var b strings.Builder
+	writeSamples(&b, "m", "target", map[string]float64{`a"b\c`: 1})
+
+	want := `m{target="a\"b\\c"} 1` + "\n"
+	if b.String() != want {
+		t.Errorf("writeSamples() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestReadTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	if err := os.WriteFile(path, []byte("# monitored\nahrefs.com\n\n  example.com  \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readTargets(path)
+	if err != nil {
+		t.Fatalf("readTargets() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != "ahrefs.com" || got[1] != "example.com" {
+		t.Errorf("readTargets() = %v, want [ahrefs.com example.com]", got)
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/redact"
+	"github.com/spf13/pflag"
+)
+
+// sensitiveWords mark a header as sensitive when its name contains one, so
+// its value is masked in diagnostics without being listed in
+// sensitive_headers
+var sensitiveWords = []string{"auth", "token", "key", "secret", "password", "cookie", "session"}
+
+// requestHeaders are the extra headers sent with every API request
+type requestHeaders struct {
+	header    http.Header
+	sensitive map[string]bool // canonical names of the headers to mask
+}
+
+// loadRequestHeaders returns the headers of the config file and of the
+// --header values parsed into fs, which replace a config header of the same
+// name. ${NAME} in a value is replaced by the environment variable NAME.
+// Authorization carries the API key, so replacing it takes
+// --allow-override-auth.
+func loadRequestHeaders(fs *pflag.FlagSet) (requestHeaders, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	h := requestHeaders{header: http.Header{}, sensitive: map[string]bool{}}
+	for _, name := range cfg.SensitiveHeaders {
+		h.sensitive[http.CanonicalHeaderKey(name)] = true
+	}
+
+	names := make([]string, 0, len(cfg.Headers))
+	for name := range cfg.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := h.set(name, cfg.Headers[name], "config header"); err != nil {
+			return requestHeaders{}, err
+		}
+	}
+
+	values, _ := fs.GetStringArray("header")
+	flagged := map[string]bool{}
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return requestHeaders{}, NewError(CodeUsage, fmt.Sprintf("invalid --header %q: want 'Name: value'", v),
+				"Give each header as --header 'X-Corp-Auth: ${CORP_TOKEN}'")
+		}
+		// Repeating a header sends each value; one from the flag replaces
+		// the config's
+		if key := http.CanonicalHeaderKey(name); !flagged[key] {
+			h.header.Del(key)
+			flagged[key] = true
+		}
+		if err := h.add(name, strings.TrimSpace(value), "--header"); err != nil {
+			return requestHeaders{}, err
+		}
+	}
+
+	if allow, _ := fs.GetBool("allow-override-auth"); !allow && h.header.Get("Authorization") != "" {
+		return requestHeaders{}, NewError(CodeUsage, "an Authorization header would replace the API key",
+			"Pass --allow-override-auth to send your own Authorization header, e.g. for a proxy that authenticates requests itself")
+	}
+	return h, nil
+}
+
+// set sets the header name to value, expanded, replacing any value it has
+func (h requestHeaders) set(name, value, source string) error {
+	h.header.Del(name)
+	return h.add(name, value, source)
+}
+
+// add adds value, expanded, to the header name
+func (h requestHeaders) add(name, value, source string) error {
+	var unset []string
+	expanded := os.Expand(value, func(env string) string {
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			unset = append(unset, env)
+		}
+		return v
+	})
+	if len(unset) > 0 {
+		return NewError(CodeUsage, fmt.Sprintf("%s %s uses ${%s}, which isn't set", source, name, unset[0]),
+			"Export the environment variable, or write the value in place")
+	}
+	h.header.Add(name, expanded)
+	return nil
+}
+
+// isSensitive reports whether the value of the header name is masked in
+// diagnostics: it is listed in sensitive_headers, or its name suggests a
+// credential
+func (h requestHeaders) isSensitive(name string) bool {
+	if h.sensitive[http.CanonicalHeaderKey(name)] {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, word := range sensitiveWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// secrets returns the values of the sensitive headers, to be scrubbed from
+// everything printed
+func (h requestHeaders) secrets() []string {
+	var secrets []string
+	for name, values := range h.header {
+		if h.isSensitive(name) {
+			secrets = append(secrets, values...)
+		}
+	}
+	return secrets
+}
+
+// String lists the headers as "Name: value" pairs, sorted by name, with the
+// values of sensitive ones masked
+func (h requestHeaders) String() string {
+	names := make([]string, 0, len(h.header))
+	for name := range h.header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		for _, value := range h.header[name] {
+			if h.isSensitive(name) {
+				value = redact.Mask
+			}
+			pairs = append(pairs, name+": "+value)
+		}
+	}
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/spf13/pflag"
+)
+
+// headerFlags returns a flag set with --header values and, when allow is
+// set, --allow-override-auth
+func headerFlags(allow bool, values ...string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.StringArray("header", values, "")
+	fs.Bool("allow-override-auth", allow, "")
+	return fs
+}
+
+func TestLoadRequestHeaders(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CORP_TOKEN", "corp-secret-123")
+	err := config.Save(&config.Config{
+		Headers:          map[string]string{"X-Corp-Auth": "${CORP_TOKEN}", "X-Team": "seo", "X-Region": "eu"},
+		SensitiveHeaders: []string{"x-region"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := loadRequestHeaders(headerFlags(false, "X-Team: growth", "x-team: ops", "X-Trace:abc"))
+	if err != nil {
+		t.Fatalf("loadRequestHeaders() error = %v", err)
+	}
+	if got := h.header.Get("X-Corp-Auth"); got != "corp-secret-123" {
+		t.Errorf("X-Corp-Auth = %q, want the expanded variable", got)
+	}
+	// --header replaces the config's value, and repeats add to each other
+	if got := h.header.Values("X-Team"); len(got) != 2 || got[0] != "growth" || got[1] != "ops" {
+		t.Errorf("X-Team = %q, want the --header values", got)
+	}
+	if want := "X-Corp-Auth: ***, X-Region: ***, X-Team: growth, X-Team: ops, X-Trace: abc"; h.String() != want {
+		t.Errorf("String() = %q, want %q", h.String(), want)
+	}
+	if secrets := strings.Join(h.secrets(), ","); !strings.Contains(secrets, "corp-secret-123") || !strings.Contains(secrets, "eu") || strings.Contains(secrets, "growth") {
+		t.Errorf("secrets() = %q, want the sensitive values alone", secrets)
+	}
+}
+
+func TestLoadRequestHeaders_Errors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name   string
+		allow  bool
+		values []string
+		want   string
+	}{
+		{"no colon", false, []string{"X-Corp-Auth"}, "want 'Name: value'"},
+		{"unset variable", false, []string{"X-Corp-Auth: ${AHREFS_TEST_UNSET}"}, "${AHREFS_TEST_UNSET}"},
+		{"authorization", false, []string{"Authorization: Basic abc"}, "replace the API key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadRequestHeaders(headerFlags(tt.allow, tt.values...))
+			var cliErr *Error
+			if !errors.As(err, &cliErr) || cliErr.Code != CodeUsage || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadRequestHeaders(%q) error = %v, want a usage error mentioning %q", tt.values, err, tt.want)
+			}
+		})
+	}
+
+	h, err := loadRequestHeaders(headerFlags(true, "Authorization: Basic abc"))
+	if err != nil || h.header.Get("Authorization") != "Basic abc" {
+		t.Errorf("loadRequestHeaders(--allow-override-auth) = %v, %v, want the Authorization header", h.header, err)
+	}
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// globalFlagsKey is the context key of the global flags a command runs with
+type globalFlagsKey struct{}
+
+// WithGlobalFlags returns a copy of ctx carrying flags. Commands run with the
+// context use them in place of the root command's, so front ends can run
+// several commands at once, each with its own settings and output.
+func WithGlobalFlags(ctx context.Context, flags GlobalFlags) context.Context {
+	return context.WithValue(ctx, globalFlagsKey{}, flags)
+}
+
+// GetGlobalFlags returns the global flag values carried by ctx. A context
+// without them gets the built-in defaults, writing JSON to stdout.
+func GetGlobalFlags(ctx context.Context) GlobalFlags {
+	if ctx != nil {
+		if flags, ok := ctx.Value(globalFlagsKey{}).(GlobalFlags); ok {
+			return flags
+		}
+	}
+	return GlobalFlags{OutputFormat: string(output.FormatJSON), ConfirmAbove: 1000, Stdout: os.Stdout}
+}
+
+// Prepare parses args into c's flags and applies the same validation and
+// default resolution as the command line, without running c. It lets other
+// front ends, such as the HTTP server, execute commands directly. c's context
+// is left carrying the global flags parsed, with output going to c.OutOrStdout.
+func Prepare(c *cobra.Command, args []string) error {
+	if err := c.ParseFlags(args); err != nil {
+		return usageError(c, err)
+	}
+	filled, err := applyDefaults(c)
+	if err != nil {
+		return defaultsError(err)
+	}
+	if err := validateFlags(c, filled); err != nil {
+		return usageError(c, err)
+	}
+	flags, err := loadGlobalFlags(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	flags.ctx = ctx
+	c.SetContext(WithGlobalFlags(ctx, flags))
+	return nil
+}
@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/jobs"
+	"github.com/spf13/cobra"
+)
+
+// Job describes an export --resume can continue
+type Job struct {
+	Endpoint  string    `json:"endpoint"`
+	Params    string    `json:"params"`
+	Pages     int       `json:"pages"`
+	Rows      int       `json:"rows"`
+	Units     int       `json:"units"`
+	Next      string    `json:"next"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Age       string    `json:"age"`
+}
+
+// NewJobsCmd creates the jobs command
+func NewJobsCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "jobs",
+		Short: "Inspect exports that can be resumed",
+		Long: `Inspect the exports of every page (--all) that stopped before their last
+page. Each records its progress after every page, and running the same
+command with --resume continues it from there, keeping the rows already
+fetched. An export's state is removed once it completes.`,
+	}
+
+	c.AddCommand(newListCmd())
+	return c
+}
+
+func newListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List exports that can be resumed",
+		Example: `  # Exports that stopped part way, most recent first
+  ahrefs jobs ls --format table
+
+  # Continue one with the command that started it
+  ahrefs site-explorer backlinks --target ahrefs.com --all --resume --format csv -o backlinks.csv`,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runList(cobraCmd.Context())
+		},
+	}
+}
+
+func runList(ctx context.Context) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	list, err := jobs.List()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	rows := make([]Job, 0, len(list))
+	for _, j := range list {
+		next := j.Cursor
+		if next == "" {
+			next = fmt.Sprintf("offset %d", j.Offset)
+		}
+		rows = append(rows, Job{
+			Endpoint:  j.Endpoint,
+			Params:    j.Params,
+			Pages:     j.Pages,
+			Rows:      j.Rows,
+			Units:     j.Units,
+			Next:      next,
+			UpdatedAt: j.UpdatedAt,
+			Age:       now.Sub(j.UpdatedAt).Round(time.Second).String(),
+		})
+	}
+
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteSuccess(rows, nil); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
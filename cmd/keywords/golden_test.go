@@ -0,0 +1,66 @@
+package keywords
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/fixture"
+	"github.com/aminemat/ahrefs-cli/internal/fixture/golden"
+	"github.com/spf13/pflag"
+)
+
+// responseTime matches the fields of the output that change between runs
+var responseTime = regexp.MustCompile(`"(response_time_ms|total_wait_ms)": \d+`)
+
+// TestGolden runs keywords difficulty against the sample fixtures in
+// examples/fixtures and compares its output with testdata/golden. go test
+// -update rewrites the golden files.
+func TestGolden(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	fixtures, err := fixture.Load("../../examples/fixtures")
+	if err != nil {
+		t.Fatalf("sample fixtures: %v", err)
+	}
+	srv := httptest.NewServer(fixture.Handler(fixtures))
+	defer srv.Close()
+
+	for _, format := range []string{"json", "table"} {
+		t.Run(format, func(t *testing.T) {
+			group := NewKeywordsCmd()
+			cmd.AddCommands(group)
+			sub, _, err := group.Find([]string{"difficulty"})
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+
+			// Global flags outlive a run, so start each from their defaults
+			sub.Root().PersistentFlags().VisitAll(func(f *pflag.Flag) {
+				if list, ok := f.Value.(pflag.SliceValue); ok {
+					_ = list.Replace(nil)
+				} else {
+					_ = f.Value.Set(f.DefValue)
+				}
+				f.Changed = false
+			})
+			var buf bytes.Buffer
+			sub.SetOut(&buf)
+			sub.SetContext(context.Background())
+			args := []string{"best crm", "-c", "us", "--format", format, "--api-key", "test-key", "--base-url", srv.URL}
+			if err := cmd.Prepare(sub, args); err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+			if err := sub.RunE(sub, sub.Flags().Args()); err != nil {
+				t.Fatalf("difficulty error = %v", err)
+			}
+
+			out := responseTime.ReplaceAllString(buf.String(), `"$1": 0`)
+			golden.Assert(t, filepath.Join("testdata", "golden", "difficulty."+format), []byte(out))
+		})
+	}
+}
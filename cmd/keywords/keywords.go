@@ -0,0 +1,259 @@
+package keywords
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/redact"
+	"github.com/aminemat/ahrefs-cli/internal/usage"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// CodeNoData is the error code returned when Ahrefs has no data for a keyword
+const CodeNoData = "NO_DATA"
+
+const (
+	overviewPath = "/keywords-explorer/overview"
+	serpPath     = "/serp-overview/serp-overview"
+)
+
+// options configures a difficulty lookup
+type options struct {
+	keyword string
+	country string
+	noSERP  bool
+}
+
+// NewKeywordsCmd creates the keywords command
+func NewKeywordsCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:     "keywords",
+		Short:   "Keywords Explorer lookups",
+		Long:    "Look up Keywords Explorer metrics for individual keywords.",
+		Aliases: []string{"kw"},
+	}
+	c.AddCommand(newDifficultyCmd())
+	return c
+}
+
+// newDifficultyCmd creates the keywords difficulty command
+func newDifficultyCmd() *cobra.Command {
+	var opts options
+
+	c := &cobra.Command{
+		Use:   "difficulty <keyword>",
+		Short: "Get a keyword's difficulty, volume, CPC, and clicks",
+		Long: `Get the Keywords Explorer metrics of a single keyword: keyword difficulty
+(KD), search volume, CPC, and clicks, with the top-ranking page and its domain
+rating from the keyword's SERP.
+
+The SERP costs a second request; --no-serp skips it. A keyword Ahrefs has no
+data for is an error (` + CodeNoData + `), so scripts can tell it from a zero.`,
+		Example: `  # Difficulty and volume of a keyword in the US
+  ahrefs keywords difficulty "best crm" --country us
+
+  # As a key/value table
+  ahrefs keywords difficulty "best crm" --country us --format table
+
+  # Just the difficulty, for scripting
+  ahrefs keywords difficulty "best crm" --country us --value difficulty`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			opts.keyword = args[0]
+			return run(cobraCmd.Context(), opts)
+		},
+	}
+
+	c.Flags().StringVarP(&opts.country, "country", "c", "us", "Country code (e.g., us, gb, de)")
+	c.Flags().BoolVar(&opts.noSERP, "no-serp", false, "Skip the SERP request for the top-ranking page and its domain rating")
+	cmd.BindEnv(c.Flags(), "country", "AHREFS_COUNTRY")
+	cmd.SetSuggestedValues(c, "country", models.Strings(models.Countries())...)
+
+	return c
+}
+
+func run(ctx context.Context, opts options) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	opts.keyword = strings.TrimSpace(opts.keyword)
+	opts.country = strings.ToLower(strings.TrimSpace(opts.country))
+	if opts.keyword == "" {
+		return cmd.NewError(cmd.CodeUsage, "the keyword is empty", `Quote the keyword, e.g. ahrefs keywords difficulty "best crm"`)
+	}
+	if opts.country == "" {
+		return cmd.NewError(cmd.CodeUsage, "--country is required", "Add --country, e.g. --country us")
+	}
+	if _, err := models.ParseCountry(opts.country); err != nil {
+		return cmd.NewError(cmd.CodeUsage, err.Error(), "Use a two-letter ISO 3166-1 country code, e.g. --country us")
+	}
+
+	apiKey := flags.APIKey
+	if apiKey == "" {
+		apiKey = config.GetAPIKey()
+	}
+	if apiKey == "" {
+		return cmd.ErrAPIKeyRequired
+	}
+
+	c := client.NewClient(client.Config{
+		APIKey:      apiKey,
+		BaseURL:     flags.BaseURL,
+		BaseURLs:    flags.BaseURLs,
+		Headers:     flags.Headers,
+		Timeout:     flags.Timeout,
+		MaxBodySize: flags.MaxBodySize,
+		MaxRetries:  flags.MaxRetries,
+		Middleware:  cmd.ClientMiddleware(flags),
+		Logger:      flags.Log(),
+		OnRetry:     flags.Progress.Retry,
+	})
+
+	if flags.DryRun {
+		printDryRun(redact.Writer(flags.Stdout, flags.Secrets()...), c, opts)
+		return nil
+	}
+
+	// Open the destination first so a bad --output fails before any units
+	// are spent
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return err
+	}
+
+	kd, meta, err := lookup(ctx, c, opts)
+	if err != nil {
+		w.WriteError(err)
+		w.Abort()
+		return err
+	}
+
+	if flags.ValueField != "" {
+		err = w.WriteValue(kd, flags.ValueField)
+	} else {
+		err = w.WriteSuccess(kd, &meta)
+	}
+	if err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+// overviewParams returns the parameters of the overview request for opts
+func overviewParams(opts options) url.Values {
+	params := url.Values{}
+	params.Set("keywords", opts.keyword)
+	params.Set("country", opts.country)
+	params.Set("select", "keyword,difficulty,volume,cpc,clicks")
+	return params
+}
+
+// serpParams returns the parameters of the SERP request for opts
+func serpParams(opts options) url.Values {
+	params := url.Values{}
+	params.Set("keyword", opts.keyword)
+	params.Set("country", opts.country)
+	params.Set("select", "position,url,domain_rating")
+	params.Set("top_positions", "1")
+	return params
+}
+
+// printDryRun describes the requests that would be sent
+func printDryRun(w io.Writer, c *client.Client, opts options) {
+	fmt.Fprintf(w, "✓ Valid request. Would call: GET %s\n", c.URL(overviewPath, overviewParams(opts)))
+	if !opts.noSERP {
+		fmt.Fprintf(w, "  And: GET %s\n", c.URL(serpPath, serpParams(opts)))
+	}
+}
+
+// lookup requests the keyword's overview and, unless skipped, its top
+// result. The meta sums both requests.
+func lookup(ctx context.Context, c *client.Client, opts options) (models.KeywordDifficulty, client.ResponseMeta, error) {
+	kd := models.KeywordDifficulty{Keyword: opts.keyword, Country: opts.country}
+
+	var overview models.KeywordsOverviewResponse
+	meta, err := get(ctx, c, overviewPath, overviewParams(opts), &overview)
+	if err != nil {
+		return kd, meta, err
+	}
+	row, ok := findKeyword(overview.Keywords, opts.keyword)
+	if !ok || (row.Volume == nil && row.Difficulty == nil) {
+		return kd, meta, cmd.NewError(CodeNoData, fmt.Sprintf("no data for %q in %s", opts.keyword, opts.country),
+			"Check the spelling, or try another --country")
+	}
+	kd.Difficulty, kd.Volume, kd.CPC, kd.Clicks = row.Difficulty, row.Volume, row.CPC, row.Clicks
+
+	if opts.noSERP {
+		return kd, meta, nil
+	}
+	var serp models.SERPOverviewResponse
+	serpMeta, err := get(ctx, c, serpPath, serpParams(opts), &serp)
+	meta.UnitsConsumed += serpMeta.UnitsConsumed
+	meta.ResponseTimeMS += serpMeta.ResponseTimeMS
+	meta.RateLimitRemaining = serpMeta.RateLimitRemaining
+	if err != nil {
+		return kd, meta, fmt.Errorf("SERP: %w", err)
+	}
+	if top, ok := topResult(serp.Positions); ok {
+		kd.TopURL, kd.TopDomainRating = top.URL, top.DomainRating
+	}
+	return kd, meta, nil
+}
+
+// get requests endpoint and decodes its response into result, recording the
+// units spent
+func get(ctx context.Context, c *client.Client, endpoint string, params url.Values, result interface{}) (client.ResponseMeta, error) {
+	resp, err := c.Get(ctx, endpoint, params)
+	if err != nil {
+		return client.ResponseMeta{}, err
+	}
+	recordUsage(cmd.GetGlobalFlags(ctx).Log(), time.Now(), endpoint, resp.Meta.UnitsConsumed)
+	if err := json.Unmarshal(resp.Body, result); err != nil {
+		return resp.Meta, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return resp.Meta, nil
+}
+
+// findKeyword returns the row for keyword, matched regardless of case
+func findKeyword(rows []models.KeywordOverview, keyword string) (models.KeywordOverview, bool) {
+	for _, row := range rows {
+		if strings.EqualFold(strings.TrimSpace(row.Keyword), keyword) {
+			return row, true
+		}
+	}
+	return models.KeywordOverview{}, false
+}
+
+// topResult returns the best-placed result
+func topResult(positions []models.SERPPosition) (models.SERPPosition, bool) {
+	var top models.SERPPosition
+	found := false
+	for _, p := range positions {
+		if p.Position > 0 && (!found || p.Position < top.Position) {
+			top, found = p, true
+		}
+	}
+	return top, found
+}
+
+// recordUsage adds the units spent on endpoint to the usage ledger when a
+// budget is being tracked
+func recordUsage(log *slog.Logger, now time.Time, endpoint string, units int) {
+	cfg, err := config.Load()
+	if err != nil || !cfg.Budget.Enabled() || units == 0 {
+		return
+	}
+	if err := usage.Record(usage.Entry{Time: now, Endpoint: endpoint, Units: units}); err != nil {
+		log.Warn("failed to record usage", "err", err)
+	}
+}
@@ -0,0 +1,96 @@
+package keywords
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// newServer serves overview as the overview response and a SERP whose top
+// result is on domain rating 91
+func newServer(t *testing.T, overview string) (*client.Client, *[]string) {
+	t.Helper()
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case overviewPath:
+			fmt.Fprint(w, overview)
+		case serpPath:
+			fmt.Fprint(w, `{"positions":[{"position":2,"url":"https://b.com/","domain_rating":50},{"position":1,"url":"https://a.com/crm","domain_rating":91}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("HOME", t.TempDir())
+	return client.NewClient(client.Config{APIKey: "k", BaseURL: srv.URL}), &paths
+}
+
+func TestLookup(t *testing.T) {
+	c, paths := newServer(t, `{"keywords":[{"keyword":"Best CRM","difficulty":72,"volume":14000,"cpc":2150,"clicks":9000}]}`)
+
+	kd, _, err := lookup(context.Background(), c, options{keyword: "best crm", country: "us"})
+	if err != nil {
+		t.Fatalf("lookup() error = %v", err)
+	}
+	if kd.Difficulty == nil || *kd.Difficulty != 72 || kd.Volume == nil || *kd.Volume != 14000 || kd.Clicks == nil || *kd.Clicks != 9000 {
+		t.Errorf("lookup() = %+v, want KD 72, volume 14000, clicks 9000", kd)
+	}
+	if kd.TopURL != "https://a.com/crm" || kd.TopDomainRating == nil || *kd.TopDomainRating != 91 {
+		t.Errorf("top result = %q DR %v, want https://a.com/crm DR 91", kd.TopURL, kd.TopDomainRating)
+	}
+	if len(*paths) != 2 {
+		t.Errorf("requests = %v, want the overview and the SERP", *paths)
+	}
+}
+
+func TestLookup_NoSERP(t *testing.T) {
+	c, paths := newServer(t, `{"keywords":[{"keyword":"best crm","difficulty":72,"volume":14000}]}`)
+
+	kd, _, err := lookup(context.Background(), c, options{keyword: "best crm", country: "us", noSERP: true})
+	if err != nil {
+		t.Fatalf("lookup() error = %v", err)
+	}
+	if kd.TopURL != "" || kd.TopDomainRating != nil || len(*paths) != 1 {
+		t.Errorf("lookup() = %+v after %v, want no SERP request", kd, *paths)
+	}
+}
+
+func TestLookup_NoData(t *testing.T) {
+	for name, overview := range map[string]string{
+		"missing": `{"keywords":[]}`,
+		"null":    `{"keywords":[{"keyword":"best crm","difficulty":null,"volume":null}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, paths := newServer(t, overview)
+
+			_, _, err := lookup(context.Background(), c, options{keyword: "best crm", country: "us"})
+			var cliErr *cmd.Error
+			if !errors.As(err, &cliErr) || cliErr.Code != CodeNoData {
+				t.Fatalf("lookup() error = %v, want %s", err, CodeNoData)
+			}
+			if len(*paths) != 1 {
+				t.Errorf("requests = %v, want no SERP request", *paths)
+			}
+		})
+	}
+}
+
+func TestTopResult(t *testing.T) {
+	if _, ok := topResult(nil); ok {
+		t.Error("topResult(nil) found a result")
+	}
+	top, ok := topResult([]models.SERPPosition{{Position: 0, URL: "ad"}, {Position: 3, URL: "c"}, {Position: 1, URL: "a"}})
+	if !ok || top.URL != "a" {
+		t.Errorf("topResult() = %+v, want a", top)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// RequestLimits returns the timeout and retries c should give the requests it
+// makes on behalf of another command, such as sync's backlinks pulls for
+// site-explorer backlinks: those set in the config defaults of command, as
+// client.Request takes them. They are 0, leaving the client's, where the
+// config sets none or c's own flag was given on the command line or its
+// environment variable. Retries are -1 whenever c's --no-retry resolved to
+// true, wherever it was set.
+func RequestLimits(c *cobra.Command, command string) (time.Duration, int, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		commandLogger(c).Warn("ignoring config defaults", "err", err)
+		return 0, 0, nil
+	}
+	defaults := cfg.Commands[command]
+	source := fmt.Sprintf("config defaults of %q", command)
+
+	var timeout time.Duration
+	if value := defaults["timeout"]; value != "" && !overridden(c, "timeout") {
+		if timeout, err = time.ParseDuration(value); err != nil {
+			return 0, 0, defaultsError(fmt.Errorf("invalid value %q for --timeout from %s: %w", value, source, err))
+		}
+	}
+
+	if noRetry, _ := c.Flags().GetBool("no-retry"); noRetry {
+		return timeout, -1, nil
+	}
+
+	var maxRetries int
+	if !overridden(c, "max-retries") && !overridden(c, "no-retry") {
+		if value := defaults["max-retries"]; value != "" {
+			if maxRetries, err = strconv.Atoi(value); err != nil {
+				return 0, 0, defaultsError(fmt.Errorf("invalid value %q for --max-retries from %s: %w", value, source, err))
+			}
+			if maxRetries <= 0 {
+				maxRetries = -1
+			}
+		}
+		if noRetry, _ := strconv.ParseBool(defaults["no-retry"]); noRetry {
+			maxRetries = -1
+		}
+	}
+	return timeout, maxRetries, nil
+}
+
+// overridden reports whether c's flag name was given on the command line or
+// by its environment variable, either of which comes before config defaults
+func overridden(c *cobra.Command, name string) bool {
+	flag := c.Flags().Lookup(name)
+	if flag == nil {
+		return false
+	}
+	env := flagEnv(c, flag)
+	return flag.Changed || env != "" && os.Getenv(env) != ""
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// limitsConfig sets a default timeout for every command and limits of their
+// own for the pull and check commands
+var limitsConfig = &config.Config{
+	Defaults: map[string]string{"timeout": "30s"},
+	Commands: map[string]map[string]string{
+		"limits pull":  {"timeout": "5m", "max-retries": "8"},
+		"limits check": {"timeout": "10s", "max-retries": "0"},
+	},
+}
+
+func TestLimits_Resolution(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		wantTimeout time.Duration
+		wantRetries int
+	}{
+		{name: "command config", args: []string{"limits", "pull"}, wantTimeout: 5 * time.Minute, wantRetries: 8},
+		{name: "no retries from command config", args: []string{"limits", "check"}, wantTimeout: 10 * time.Second, wantRetries: -1},
+		{name: "config defaults", args: []string{"limits", "other"}, wantTimeout: 30 * time.Second, wantRetries: 3},
+		{name: "env over config defaults", args: []string{"limits", "other"}, env: map[string]string{"AHREFS_TIMEOUT": "1m", "AHREFS_MAX_RETRIES": "5"},
+			wantTimeout: time.Minute, wantRetries: 5},
+		{name: "env over command config", args: []string{"limits", "pull"}, env: map[string]string{"AHREFS_TIMEOUT": "1m", "AHREFS_MAX_RETRIES": "5"},
+			wantTimeout: time.Minute, wantRetries: 5},
+		{name: "no-retry env over command config", args: []string{"limits", "pull"}, env: map[string]string{"AHREFS_NO_RETRY": "1"},
+			wantTimeout: 5 * time.Minute, wantRetries: -1},
+		{name: "flags over command config", args: []string{"limits", "pull", "--timeout", "20s", "--max-retries", "1"},
+			wantTimeout: 20 * time.Second, wantRetries: 1},
+		{name: "flags before the command", args: []string{"--timeout", "20s", "limits", "pull"}, wantTimeout: 20 * time.Second, wantRetries: 8},
+		{name: "no-retry over command config", args: []string{"limits", "pull", "--no-retry"}, wantTimeout: 5 * time.Minute, wantRetries: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if err := config.Save(limitsConfig); err != nil {
+				t.Fatalf("config.Save() error = %v", err)
+			}
+
+			var got GlobalFlags
+			group := &cobra.Command{Use: "limits"}
+			for _, name := range []string{"pull", "check", "other"} {
+				group.AddCommand(&cobra.Command{Use: name, RunE: func(c *cobra.Command, _ []string) error {
+					got = GetGlobalFlags(c.Context())
+					return nil
+				}})
+			}
+			rootCmd.AddCommand(group)
+			defer rootCmd.RemoveCommand(group)
+			defer resetFlags(rootCmd)
+			rootCmd.SetErr(&bytes.Buffer{})
+			defer rootCmd.SetErr(nil)
+
+			if err := execute(context.Background(), tt.args); err != nil {
+				t.Fatalf("execute(%v) error = %v", tt.args, err)
+			}
+			if got.Timeout != tt.wantTimeout || got.MaxRetries != tt.wantRetries {
+				t.Errorf("timeout, retries = %v, %d, want %v, %d", got.Timeout, got.MaxRetries, tt.wantTimeout, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		args        []string
+		env         map[string]string
+		wantTimeout time.Duration
+		wantRetries int
+	}{
+		{name: "command config", command: "limits pull", wantTimeout: 5 * time.Minute, wantRetries: 8},
+		{name: "no retries", command: "limits check", wantTimeout: 10 * time.Second, wantRetries: -1},
+		{name: "no command config", command: "limits other"},
+		{name: "own flags given", command: "limits pull", args: []string{"--timeout", "1m", "--no-retry"}, wantRetries: -1},
+		{name: "own timeout given", command: "limits pull", args: []string{"--timeout", "1m"}, wantRetries: 8},
+		{name: "own env given", command: "limits pull", env: map[string]string{"AHREFS_TIMEOUT": "1m", "AHREFS_MAX_RETRIES": "5"}},
+		{name: "no-retry env", command: "limits pull", env: map[string]string{"AHREFS_NO_RETRY": "true"}, wantTimeout: 5 * time.Minute, wantRetries: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if err := config.Save(limitsConfig); err != nil {
+				t.Fatalf("config.Save() error = %v", err)
+			}
+
+			c := &cobra.Command{Use: "sync"}
+			c.Flags().Duration("timeout", 0, "")
+			c.Flags().Int("max-retries", 3, "")
+			c.Flags().Bool("no-retry", false, "")
+			BindEnv(c.Flags(), "timeout", "AHREFS_TIMEOUT")
+			BindEnv(c.Flags(), "max-retries", "AHREFS_MAX_RETRIES")
+			BindEnv(c.Flags(), "no-retry", "AHREFS_NO_RETRY")
+			if err := c.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+			if _, err := applyDefaults(c); err != nil {
+				t.Fatalf("applyDefaults() error = %v", err)
+			}
+
+			timeout, retries, err := RequestLimits(c, tt.command)
+			if err != nil {
+				t.Fatalf("RequestLimits() error = %v", err)
+			}
+			if timeout != tt.wantTimeout || retries != tt.wantRetries {
+				t.Errorf("RequestLimits() = %v, %d, want %v, %d", timeout, retries, tt.wantTimeout, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestRequestLimits_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := &config.Config{Commands: map[string]map[string]string{"limits pull": {"timeout": "soon"}}}
+	if err := config.Save(cfg); err != nil {
+		t.Fatalf("config.Save() error = %v", err)
+	}
+
+	c := &cobra.Command{Use: "sync"}
+	c.Flags().Duration("timeout", 0, "")
+	if _, _, err := RequestLimits(c, "limits pull"); err == nil {
+		t.Error("RequestLimits() with an invalid timeout should return an error")
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/logging"
+	"github.com/aminemat/ahrefs-cli/internal/redact"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Log returns the logger diagnostics are written to. Flags built by hand,
+// without a Logger, log text to stderr at the level --verbose and --quiet
+// select.
+func (f GlobalFlags) Log() *slog.Logger {
+	if f.Logger != nil {
+		return f.Logger
+	}
+	verbosity := f.Verbosity
+	if verbosity == 0 && f.Verbose {
+		verbosity = 1
+	}
+	return logging.New(redact.Writer(os.Stderr, f.Secrets()...), f.LogFormat, logging.Level(verbosity, f.Quiet))
+}
+
+// Secrets returns the API keys diagnostics are scrubbed of: the --api-key
+// or AHREFS_API_KEY value, and the config file's
+func (f GlobalFlags) Secrets() []string {
+	return append(secrets(f.APIKey), f.headers.secrets()...)
+}
+
+// secrets returns flagKey and the config file's API key
+func secrets(flagKey string) []string {
+	keys := []string{flagKey}
+	if cfg, err := config.Load(); err == nil {
+		keys = append(keys, cfg.APIKey)
+	}
+	return keys
+}
+
+// stderrOf returns c's stderr, scrubbed of the API keys so diagnostics can
+// be shared as they are
+func stderrOf(c *cobra.Command) io.Writer {
+	apiKey, _ := c.Flags().GetString("api-key")
+	headers, _ := loadRequestHeaders(c.Flags())
+	return redact.Writer(c.ErrOrStderr(), append(secrets(apiKey), headers.secrets()...)...)
+}
+
+// verbosityOf returns the --verbose count parsed into fs
+func verbosityOf(fs *pflag.FlagSet) int {
+	if f := fs.Lookup("verbose"); f != nil {
+		if v, ok := f.Value.(*verbosity); ok {
+			return int(*v)
+		}
+	}
+	return 0
+}
+
+// commandLogger returns the logger for c's diagnostics, as its --verbose,
+// --quiet, and --log-format flags ask, with the command in every record
+func commandLogger(c *cobra.Command) *slog.Logger {
+	fs := c.Flags()
+	format, _ := fs.GetString("log-format")
+	quiet, _ := fs.GetBool("quiet")
+	level := logging.Level(verbosityOf(fs), quiet)
+	return logging.New(stderrOf(c), format, level).With("command", c.CommandPath())
+}
+
+// logRequests returns middleware logging each API request at debug level,
+// with its endpoint, target, attempts, and duration. -vv also logs requests
+// as they are sent, with their parameters and the extra headers, sensitive
+// values masked.
+func logRequests(log *slog.Logger, headers requestHeaders) client.Middleware {
+	return func(next client.Handler) client.Handler {
+		return func(ctx context.Context, req client.Request) (*client.Response, error) {
+			if !log.Enabled(ctx, slog.LevelDebug) {
+				return next(ctx, req)
+			}
+			sending := []any{"method", req.Method, "endpoint", req.Endpoint, "params", req.Params.Encode()}
+			if len(headers.header) > 0 {
+				sending = append(sending, "headers", headers.String())
+			}
+			log.Log(ctx, logging.LevelTrace, "Sending API request", sending...)
+
+			start := time.Now()
+			resp, err := next(ctx, req)
+			attrs := []any{"endpoint", req.Endpoint, "target", req.Params.Get("target")}
+			if err != nil {
+				attempt := 1
+				var reqErr *client.RequestError
+				if errors.As(err, &reqErr) {
+					attempt = reqErr.Retries + 1
+				}
+				attrs = append(attrs, "attempt", attempt, "duration", time.Since(start), "err", err)
+				log.DebugContext(ctx, "API request failed", attrs...)
+				return resp, err
+			}
+			attrs = append(attrs, "attempt", resp.Meta.Retries+1, "duration", time.Since(start), "status", resp.StatusCode, "units", resp.Meta.UnitsConsumed)
+			log.DebugContext(ctx, "API request", attrs...)
+			return resp, err
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/internal/logging"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// runLoggingCommand executes args against the root command with a "log-cmd"
+// subcommand logging a record at each level, returning stderr
+func runLoggingCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	logCmd := &cobra.Command{
+		Use: "log-cmd",
+		RunE: func(c *cobra.Command, args []string) error {
+			log := GetGlobalFlags(c.Context()).Log()
+			log.Log(c.Context(), logging.LevelTrace, "trace")
+			log.Debug("debug")
+			log.Info("info")
+			log.Warn("warn")
+			return nil
+		},
+	}
+	rootCmd.AddCommand(logCmd)
+	defer rootCmd.RemoveCommand(logCmd)
+	defer resetFlags(rootCmd)
+
+	var stderr bytes.Buffer
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetOut(&bytes.Buffer{})
+	defer rootCmd.SetErr(nil)
+	defer rootCmd.SetOut(nil)
+
+	err := execute(context.Background(), append([]string{"log-cmd"}, args...))
+	return stderr.String(), err
+}
+
+func TestLogging_Levels(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  string
+		want string
+	}{
+		{name: "default", want: "info\nWarning: warn\n"},
+		{name: "verbose", args: []string{"-v"}, want: "debug\ninfo\nWarning: warn\n"},
+		{name: "very verbose", args: []string{"-vv"}, want: "trace\ndebug\ninfo\nWarning: warn\n"},
+		{name: "counted", args: []string{"--verbose", "--verbose"}, want: "trace\ndebug\ninfo\nWarning: warn\n"},
+		{name: "quiet", args: []string{"-q", "-v"}, want: ""},
+		{name: "env boolean", env: "true", want: "debug\ninfo\nWarning: warn\n"},
+		{name: "env count", env: "2", want: "trace\ndebug\ninfo\nWarning: warn\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AHREFS_VERBOSE", tt.env)
+			stderr, err := runLoggingCommand(t, tt.args...)
+			if err != nil {
+				t.Fatalf("execute() error = %v", err)
+			}
+			if stderr != tt.want {
+				t.Errorf("stderr = %q, want %q", stderr, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogging_JSON(t *testing.T) {
+	stderr, err := runLoggingCommand(t, "--log-format", "json")
+	if err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("stderr = %q, want the info and warning records", stderr)
+	}
+	for i, want := range []string{"INFO", "WARN"} {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &record); err != nil {
+			t.Fatalf("line %q is not JSON: %v", lines[i], err)
+		}
+		if record["level"] != want || record["command"] != "ahrefs log-cmd" {
+			t.Errorf("record = %v, want level %s and the command", record, want)
+		}
+	}
+}
+
+func TestLogging_InvalidFormat(t *testing.T) {
+	_, err := runLoggingCommand(t, "--log-format", "xml")
+	var coded *Error
+	if !errors.As(err, &coded) || coded.Code != CodeUsage {
+		t.Errorf("execute() error = %v, want a usage error", err)
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not found"}`)
+			return
+		}
+		w.Header().Set("X-API-Units-Consumed", "5")
+		fmt.Fprint(w, `{}`)
+	}))
+	defer api.Close()
+
+	var buf bytes.Buffer
+	c := client.NewClient(client.Config{
+		APIKey:     "test",
+		BaseURL:    api.URL,
+		Middleware: []client.Middleware{logRequests(logging.New(&buf, logging.FormatText, slog.LevelDebug), requestHeaders{})},
+	})
+	params := url.Values{"target": {"ahrefs.com"}}
+	if _, err := c.Get(context.Background(), "/site-explorer/domain-rating", params); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := c.Get(context.Background(), "/fail", params); err == nil {
+		t.Fatal("Get() expected an error")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log = %q, want a line per request", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "API request endpoint=/site-explorer/domain-rating target=ahrefs.com attempt=1 duration=") {
+		t.Errorf("log = %q, want the request's fields", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "API request failed: ") || !strings.Contains(lines[1], "endpoint=/fail") {
+		t.Errorf("log = %q, want the failure", lines[1])
+	}
+}
+
+func TestLogRequests_Headers(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+	defer api.Close()
+
+	var buf bytes.Buffer
+	headers := requestHeaders{header: http.Header{"X-Corp-Auth": {"corp-secret"}, "X-Team": {"seo"}}}
+	c := client.NewClient(client.Config{
+		APIKey:     "test",
+		BaseURL:    api.URL,
+		Middleware: []client.Middleware{logRequests(logging.New(&buf, logging.FormatText, logging.LevelTrace), headers)},
+	})
+	if _, err := c.Get(context.Background(), "/site-explorer/domain-rating", url.Values{}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if log := buf.String(); !strings.Contains(log, `headers="X-Corp-Auth: ***, X-Team: seo"`) || strings.Contains(log, "corp-secret") {
+		t.Errorf("log = %q, want the headers with the sensitive value masked", log)
+	}
+}
@@ -0,0 +1,169 @@
+package mockserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/fixture"
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout bounds how long in-flight requests may finish on exit
+const shutdownTimeout = 5 * time.Second
+
+// options configures the mock server
+type options struct {
+	fixtures    string
+	host        string
+	port        int
+	latency     time.Duration
+	errorRate   float64
+	errorStatus int
+}
+
+// NewMockServerCmd creates the mock-server command
+func NewMockServerCmd() *cobra.Command {
+	var opts options
+
+	c := &cobra.Command{
+		Use:   "mock-server",
+		Short: "Serve canned API responses for testing",
+		Long: `Run a local HTTP server that answers API requests from fixture files, so
+scripts wrapping this CLI can be tested without real API calls.
+
+Each *.json file in --fixtures holds one request/response pair:
+
+  {
+    "request":  {"method": "GET", "path": "/site-explorer/domain-rating",
+                 "query": {"target": ["ahrefs.com"]}},
+    "response": {"status": 200, "headers": {"X-API-Units-Consumed": "50"},
+                 "body": {"domain_rating": {"domain_rating": 91}}}
+  }
+
+A request matches when the path matches and it carries every listed query
+parameter; the fixture listing the most parameters wins. Unmatched requests
+get a 404. Point the CLI at the server with --base-url and any API key.`,
+		Example: `  # Serve the sample fixtures
+  ahrefs mock-server --fixtures ./examples/fixtures --port 8080
+
+  # In another shell
+  ahrefs site-explorer domain-rating --target ahrefs.com \
+    --base-url http://localhost:8080 --api-key test
+
+  # Add 200ms latency and fail 10% of requests with 429
+  ahrefs mock-server --latency 200ms --error-rate 0.1 --error-status 429`,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return run(cobraCmd.Context(), opts, cmd.GetGlobalFlags(cobraCmd.Context()).Log())
+		},
+	}
+
+	c.Flags().StringVar(&opts.fixtures, "fixtures", "./fixtures", "Directory of fixture files")
+	c.Flags().StringVar(&opts.host, "host", "127.0.0.1", "Address to listen on")
+	c.Flags().IntVar(&opts.port, "port", 8080, "Port to listen on")
+	c.Flags().DurationVar(&opts.latency, "latency", 0, "Delay before every response (e.g., 200ms)")
+	c.Flags().Float64Var(&opts.errorRate, "error-rate", 0, "Fraction of requests (0-1) answered with --error-status")
+	c.Flags().IntVar(&opts.errorStatus, "error-status", http.StatusInternalServerError, "Status code for injected errors")
+
+	return c
+}
+
+func run(ctx context.Context, opts options, log *slog.Logger) error {
+	if opts.errorRate < 0 || opts.errorRate > 1 {
+		return fmt.Errorf("--error-rate must be between 0 and 1, got %v", opts.errorRate)
+	}
+
+	fixtures, err := fixture.Load(opts.fixtures)
+	if err != nil {
+		return err
+	}
+	if len(fixtures) == 0 {
+		return fmt.Errorf("no fixtures found in %s", opts.fixtures)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	addr := net.JoinHostPort(opts.host, strconv.Itoa(opts.port))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newHandler(fixtures, opts, log),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	log.Info(fmt.Sprintf("Serving %d fixture(s) from %s on http://%s", len(fixtures), opts.fixtures, addr))
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handler answers requests from fixtures
+type handler struct {
+	fixtures []fixture.Fixture
+	opts     options
+	log      *slog.Logger
+}
+
+func newHandler(fixtures []fixture.Fixture, opts options, log *slog.Logger) *handler {
+	return &handler{fixtures: fixtures, opts: opts, log: log}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	if h.opts.latency > 0 {
+		select {
+		case <-time.After(h.opts.latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	status, source := h.respond(w, r)
+	h.log.Info(r.Method+" "+r.URL.RequestURI(), "status", status, "source", source, "duration", time.Since(start))
+}
+
+// respond writes the response and returns its status and what produced it
+func (h *handler) respond(w http.ResponseWriter, r *http.Request) (int, string) {
+	if h.opts.errorRate > 0 && rand.Float64() < h.opts.errorRate {
+		fixture.WriteError(w, h.opts.errorStatus, "INJECTED_ERROR", "injected error")
+		return h.opts.errorStatus, "[injected]"
+	}
+
+	f, ok := fixture.Find(h.fixtures, r.Method, r.URL.Path, r.URL.Query())
+	if !ok {
+		fixture.WriteError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("no fixture for %s %s", r.Method, r.URL.RequestURI()))
+		return http.StatusNotFound, "[no fixture]"
+	}
+
+	f.Response.Write(w)
+	return f.Response.StatusCode(), f.Name
+}
@@ -0,0 +1,73 @@
+package mockserver
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/internal/fixture"
+	"github.com/aminemat/ahrefs-cli/internal/logging"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+func TestHandler(t *testing.T) {
+	fixtures, err := fixture.Load("../../examples/fixtures")
+	if err != nil {
+		t.Fatalf("sample fixtures: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		opts       options
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "matched", path: "/site-explorer/domain-rating?target=ahrefs.com", wantStatus: 200, wantBody: `"domain_rating": 91`},
+		{name: "specific fixture", path: "/site-explorer/domain-rating?target=unauthorized.example", wantStatus: 401, wantBody: "Invalid API key"},
+		{name: "unmatched", path: "/site-explorer/nope", wantStatus: 404, wantBody: "no fixture"},
+		{name: "injected error", opts: options{errorRate: 1, errorStatus: 429}, path: "/site-explorer/domain-rating", wantStatus: 429, wantBody: "injected error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log bytes.Buffer
+			rec := httptest.NewRecorder()
+			newHandler(fixtures, tt.opts, logging.New(&log, logging.FormatText, slog.LevelInfo)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", rec.Body.String(), tt.wantBody)
+			}
+			if !strings.Contains(log.String(), tt.path) {
+				t.Errorf("log = %q, want request logged", log.String())
+			}
+		})
+	}
+}
+
+func TestHandler_WithClient(t *testing.T) {
+	fixtures, err := fixture.Load("../../examples/fixtures")
+	if err != nil {
+		t.Fatalf("sample fixtures: %v", err)
+	}
+
+	var log bytes.Buffer
+	srv := httptest.NewServer(newHandler(fixtures, options{}, logging.New(&log, logging.FormatText, slog.LevelInfo)))
+	defer srv.Close()
+
+	c := client.NewClient(client.Config{APIKey: "test", BaseURL: srv.URL})
+	resp, err := c.Get(context.Background(), "/site-explorer/backlinks-stats", nil)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if resp.Meta.UnitsConsumed != 50 {
+		t.Errorf("UnitsConsumed = %d, want 50", resp.Meta.UnitsConsumed)
+	}
+}
@@ -0,0 +1,182 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/alert"
+	"github.com/aminemat/ahrefs-cli/internal/yaml"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// defaultInterval applies when neither the file nor a check sets one
+const defaultInterval = time.Hour
+
+// fileConfig is the monitors file as written by the user
+type fileConfig struct {
+	Interval string         `json:"interval"`
+	Mode     string         `json:"mode"`
+	Notify   []notifyConfig `json:"notify"`
+	Checks   []checkConfig  `json:"checks"`
+}
+
+type notifyConfig struct {
+	Type    string `json:"type"`
+	URL     string `json:"url"`
+	Command string `json:"command"`
+}
+
+type checkConfig struct {
+	Name      string   `json:"name"`
+	Target    string   `json:"target"`
+	Metric    string   `json:"metric"`
+	Endpoint  string   `json:"endpoint"`
+	Mode      string   `json:"mode"`
+	Interval  string   `json:"interval"`
+	Below     *float64 `json:"below"`
+	Above     *float64 `json:"above"`
+	ChangePct *float64 `json:"change_pct"`
+}
+
+// check is a validated check ready to be scheduled
+type check struct {
+	name     string
+	target   string
+	metric   string
+	mode     string
+	source   alert.Source
+	rule     alert.Rule
+	interval time.Duration
+}
+
+// monitors is a validated monitors file
+type monitors struct {
+	checks []check
+	notify []notifyConfig
+}
+
+// loadConfig reads and validates the monitors file at path
+func loadConfig(path string) (*monitors, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, cmd.NewError(cmd.CodeConfig, fmt.Sprintf("failed to read monitors file: %v", err), "Pass an existing file with --config")
+	}
+
+	var raw fileConfig
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, invalid(path, err.Error())
+	}
+	return raw.compile(path)
+}
+
+func (raw fileConfig) compile(path string) (*monitors, error) {
+	interval, err := parseInterval(raw.Interval, defaultInterval)
+	if err != nil {
+		return nil, invalid(path, "interval: "+err.Error())
+	}
+	mode := raw.Mode
+	if mode == "" {
+		mode = models.ModeDomain.String()
+	}
+
+	for i, n := range raw.Notify {
+		switch n.Type {
+		case "webhook":
+			if n.URL == "" {
+				return nil, invalid(path, fmt.Sprintf("notify[%d]: webhook needs a url", i))
+			}
+		case "command":
+			if n.Command == "" {
+				return nil, invalid(path, fmt.Sprintf("notify[%d]: command needs a command", i))
+			}
+		case "stdout":
+		default:
+			return nil, invalid(path, fmt.Sprintf("notify[%d]: unknown type %q (use webhook, command, or stdout)", i, n.Type))
+		}
+	}
+
+	if len(raw.Checks) == 0 {
+		return nil, invalid(path, "no checks defined")
+	}
+
+	cfg := &monitors{notify: raw.Notify}
+	seen := map[string]bool{}
+	for i, c := range raw.Checks {
+		chk, err := c.compile(mode, interval)
+		if err != nil {
+			return nil, invalid(path, fmt.Sprintf("checks[%d]: %v", i, err))
+		}
+		if seen[chk.name] {
+			return nil, invalid(path, fmt.Sprintf("checks[%d]: duplicate name %q", i, chk.name))
+		}
+		seen[chk.name] = true
+		cfg.checks = append(cfg.checks, chk)
+	}
+	return cfg, nil
+}
+
+func (c checkConfig) compile(mode string, interval time.Duration) (check, error) {
+	if c.Target == "" || c.Metric == "" {
+		return check{}, fmt.Errorf("target and metric are required")
+	}
+	if c.Below == nil && c.Above == nil && c.ChangePct == nil {
+		return check{}, fmt.Errorf("set at least one of below, above, or change_pct")
+	}
+
+	chk := check{
+		name:   c.Name,
+		target: c.Target,
+		metric: c.Metric,
+		mode:   mode,
+		rule:   alert.Rule{Below: c.Below, Above: c.Above, ChangePct: c.ChangePct},
+	}
+	if chk.name == "" {
+		chk.name = c.Target + " " + c.Metric
+	}
+	if c.Mode != "" {
+		chk.mode = c.Mode
+	}
+	m, err := models.ParseMode(chk.mode)
+	if err != nil {
+		return check{}, err
+	}
+	chk.mode = m.String()
+
+	// Known metrics need no endpoint; with one, metric names a numeric field
+	// of that endpoint's response
+	if c.Endpoint != "" {
+		chk.source = alert.Source{Endpoint: c.Endpoint, Field: c.Metric}
+	} else if src, ok := alert.Sources[c.Metric]; ok {
+		chk.source = src
+	} else {
+		return check{}, fmt.Errorf("unknown metric %q; set endpoint, or use one of %s", c.Metric, strings.Join(alert.Metrics(), ", "))
+	}
+
+	if chk.interval, err = parseInterval(c.Interval, interval); err != nil {
+		return check{}, fmt.Errorf("interval: %v", err)
+	}
+	return chk, nil
+}
+
+// parseInterval parses a duration such as "30m", returning def when s is
+// empty
+func parseInterval(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < time.Minute {
+		return 0, fmt.Errorf("%s is shorter than the 1m minimum", s)
+	}
+	return d, nil
+}
+
+func invalid(path, msg string) error {
+	return cmd.NewError(cmd.CodeConfig, fmt.Sprintf("invalid monitors file %s: %s", path, msg), "See 'ahrefs monitor --help' for the file format")
+}
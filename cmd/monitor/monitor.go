@@ -0,0 +1,326 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/alert"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/usage"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// StateFileName is the name of the monitor's last-values file inside the
+// cache directory, keyed by check name
+const StateFileName = "monitor-state.json"
+
+// maxStartDelay bounds the random delay before a check's first run
+const maxStartDelay = time.Minute
+
+// options configures a monitor run
+type options struct {
+	configFile string
+	once       bool
+	rps        float64
+}
+
+// NewMonitorCmd creates the monitor command
+func NewMonitorCmd() *cobra.Command {
+	var opts options
+
+	c := &cobra.Command{
+		Use:   "monitor",
+		Short: "Run scheduled metric checks and send notifications",
+		Long: `Run the checks in a monitors file on a schedule until interrupted.
+
+Each check reads one metric for one target and fires when it is below or
+above a threshold, or has changed by change_pct percent since its previous
+run. Last values are kept in the local state directory, so deltas survive
+restarts. Triggered checks are sent to every notifier:
+
+  webhook   posts a Slack-compatible message to url
+  command   runs command with sh -c, the event as JSON on stdin
+  stdout    writes the event as a JSON line (the default with no notifiers)
+
+Checks start at a random offset within the first minute and each interval
+is jittered by up to 10%, so checks sharing an interval do not fire
+together; --rps caps the API request rate across all checks. Use --once to
+run every check a single time, e.g. from cron.
+
+File format (YAML or JSON):
+
+  interval: 1h            # default for checks; minimum 1m
+  mode: domain            # default for checks
+  notify:
+    - type: webhook
+      url: https://hooks.slack.com/services/...
+    - type: command
+      command: ./page-oncall.sh
+  checks:
+    - name: dr-floor
+      target: example.com
+      metric: domain_rating
+      below: 70
+    - target: example.com
+      metric: refdomains
+      change_pct: 5
+      interval: 6h
+    - target: example.com/blog/
+      mode: prefix
+      endpoint: /site-explorer/pages-stats   # any single-object endpoint
+      metric: urls                           # numeric field of its response
+      above: 5000
+
+Without endpoint, metric is one of the alert command's metrics.`,
+		Example: `  # Run until interrupted
+  ahrefs monitor --config monitors.yaml
+
+  # Run every check once from cron; events go to the configured notifiers
+  ahrefs monitor --config monitors.yaml --once`,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return run(cobraCmd.Context(), opts)
+		},
+	}
+
+	c.Flags().StringVar(&opts.configFile, "config", "", "Monitors file (required)")
+	c.Flags().BoolVar(&opts.once, "once", false, "Run every check once and exit")
+	c.Flags().Float64Var(&opts.rps, "rps", 1, "Maximum API requests per second")
+	c.MarkFlagRequired("config")
+
+	return c
+}
+
+func run(ctx context.Context, opts options) error {
+	if opts.rps <= 0 {
+		return fmt.Errorf("--rps must be positive")
+	}
+
+	cfg, err := loadConfig(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	flags := cmd.GetGlobalFlags(ctx)
+	apiKey := flags.APIKey
+	if apiKey == "" {
+		apiKey = config.GetAPIKey()
+	}
+	if apiKey == "" {
+		return cmd.ErrAPIKeyRequired
+	}
+
+	state, err := alert.LoadState(StateFileName)
+	if err != nil {
+		return err
+	}
+
+	m := &monitor{
+		client: client.NewClient(client.Config{
+			APIKey:      apiKey,
+			BaseURL:     flags.BaseURL,
+			BaseURLs:    flags.BaseURLs,
+			Headers:     flags.Headers,
+			Timeout:     flags.Timeout,
+			MaxBodySize: flags.MaxBodySize,
+			MaxRetries:  flags.MaxRetries,
+			RateLimit:   opts.rps,
+			Middleware:  cmd.ClientMiddleware(flags),
+			Logger:      flags.Log(),
+		}),
+		notifiers: newNotifiers(cfg.notify, flags.Stdout),
+		state:     state,
+		log:       flags.Log(),
+	}
+
+	if opts.once {
+		failed := 0
+		for _, c := range cfg.checks {
+			if !m.evaluate(ctx, c) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d check(s) failed", failed, len(cfg.checks))
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	m.log.Info(fmt.Sprintf("Monitoring %d check(s)", len(cfg.checks)))
+	var wg sync.WaitGroup
+	for _, c := range cfg.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.schedule(ctx, c)
+		}()
+	}
+	wg.Wait()
+	m.log.Info("Stopped")
+	return nil
+}
+
+// monitor evaluates checks and dispatches their events
+type monitor struct {
+	client    *client.Client
+	notifiers []notifier
+	log       *slog.Logger
+
+	mu    sync.Mutex
+	state alert.State
+}
+
+// schedule runs c every interval, with jitter, until ctx is done
+func (m *monitor) schedule(ctx context.Context, c check) {
+	delay := rand.N(min(c.interval, maxStartDelay))
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		m.evaluate(ctx, c)
+		delay = jitter(c.interval)
+	}
+}
+
+// jitter returns d randomly adjusted by up to 10% either way
+func jitter(d time.Duration) time.Duration {
+	spread := d / 10
+	return d - spread + rand.N(2*spread+1)
+}
+
+// evaluate runs c once, records its value, and notifies when it fires. It
+// reports whether the check could be evaluated.
+func (m *monitor) evaluate(ctx context.Context, c check) bool {
+	now := time.Now()
+	value, units, err := alert.FetchSource(ctx, m.client, c.target, c.mode, c.source)
+	recordUsage(m.log, now, c.source.Endpoint, units)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.log.Warn("check "+c.name, "err", err, "target", c.target, "endpoint", c.source.Endpoint)
+		}
+		return false
+	}
+
+	m.mu.Lock()
+	res := c.rule.Check(c.target, c.metric, value, m.state.Previous(c.name, c.metric))
+	m.state.Set(c.name, c.metric, value, now)
+	err = m.state.Save(StateFileName)
+	m.mu.Unlock()
+	if err != nil {
+		m.log.Warn("state not saved", "err", err)
+	}
+
+	if !res.Triggered {
+		return true
+	}
+	ev := event{Time: now.UTC(), Check: c.name, Result: res}
+	for _, n := range m.notifiers {
+		if err := n.notify(ctx, ev); err != nil && ctx.Err() == nil {
+			m.log.Warn("check "+c.name, "err", err, "target", c.target)
+		}
+	}
+	return true
+}
+
+// event is a triggered check as delivered to notifiers
+type event struct {
+	Time  time.Time `json:"time"`
+	Check string    `json:"check"`
+	alert.Result
+}
+
+// notifier delivers events somewhere
+type notifier interface {
+	notify(ctx context.Context, ev event) error
+}
+
+func newNotifiers(cfgs []notifyConfig, stdout io.Writer) []notifier {
+	if len(cfgs) == 0 {
+		return []notifier{&streamNotifier{w: stdout}}
+	}
+
+	var ns []notifier
+	for _, n := range cfgs {
+		switch n.Type {
+		case "webhook":
+			ns = append(ns, webhookNotifier(n.URL))
+		case "command":
+			ns = append(ns, commandNotifier(n.Command))
+		case "stdout":
+			ns = append(ns, &streamNotifier{w: stdout})
+		}
+	}
+	return ns
+}
+
+// webhookNotifier posts events to a Slack-compatible webhook
+type webhookNotifier string
+
+func (url webhookNotifier) notify(ctx context.Context, ev event) error {
+	return alert.Notify(ctx, string(url), []alert.Result{ev.Result})
+}
+
+// commandNotifier runs a shell command with the event as JSON on stdin
+type commandNotifier string
+
+func (command commandNotifier) notify(ctx context.Context, ev event) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	c := exec.CommandContext(ctx, "sh", "-c", string(command))
+	c.Stdin = bytes.NewReader(payload)
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("notify command %q: %w", string(command), err)
+	}
+	return nil
+}
+
+// streamNotifier writes events as newline-delimited JSON
+type streamNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *streamNotifier) notify(ctx context.Context, ev event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.NewEncoder(s.w).Encode(ev)
+}
+
+// recordUsage adds the units spent to the usage ledger when a budget is
+// being tracked
+func recordUsage(log *slog.Logger, now time.Time, endpoint string, units int) {
+	if units == 0 {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil || !cfg.Budget.Enabled() {
+		return
+	}
+	if err := usage.Record(usage.Entry{Time: now, Endpoint: endpoint, Units: units}); err != nil {
+		log.Warn("failed to record usage", "err", err)
+	}
+}
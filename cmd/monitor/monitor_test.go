@@ -0,0 +1,135 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/alert"
+	"github.com/aminemat/ahrefs-cli/internal/logging"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitors.yaml")
+	os.WriteFile(path, []byte(`interval: 2h
+notify:
+  - type: command
+    command: cat
+checks:
+  - name: dr-floor
+    target: example.com
+    metric: domain_rating
+    below: 70
+  - target: example.com/blog/
+    mode: prefix
+    endpoint: /site-explorer/pages-stats
+    metric: urls
+    change_pct: 5
+    interval: 30m
+`), 0600)
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if len(cfg.checks) != 2 || len(cfg.notify) != 1 {
+		t.Fatalf("loadConfig() = %d checks, %d notifiers, want 2, 1", len(cfg.checks), len(cfg.notify))
+	}
+
+	dr := cfg.checks[0]
+	if dr.name != "dr-floor" || dr.mode != "domain" || dr.interval != 2*time.Hour || dr.source != alert.Sources["domain_rating"] {
+		t.Errorf("checks[0] = %+v", dr)
+	}
+	pages := cfg.checks[1]
+	if pages.name != "example.com/blog/ urls" || pages.mode != "prefix" || pages.interval != 30*time.Minute ||
+		pages.source != (alert.Source{Endpoint: "/site-explorer/pages-stats", Field: "urls"}) {
+		t.Errorf("checks[1] = %+v", pages)
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"no checks", "interval: 1h\n", "no checks defined"},
+		{"no threshold", "checks:\n  - target: a.com\n    metric: domain_rating\n", "set at least one of below"},
+		{"unknown metric", "checks:\n  - target: a.com\n    metric: nope\n    below: 1\n", `unknown metric "nope"`},
+		{"short interval", "interval: 10s\nchecks:\n  - target: a.com\n    metric: live\n    below: 1\n", "shorter than the 1m minimum"},
+		{"bad notifier", "notify:\n  - type: email\nchecks:\n  - target: a.com\n    metric: live\n    below: 1\n", `unknown type "email"`},
+		{"flow map", "checks:\n  - {name: x}\n", "unsupported"},
+		{"duplicate name", "checks:\n  - name: x\n    target: a.com\n    metric: live\n    below: 1\n  - name: x\n    target: b.com\n    metric: live\n    below: 1\n", `duplicate name "x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "monitors.yaml")
+			os.WriteFile(path, []byte(tt.src), 0600)
+			_, err := loadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadConfig() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	dr := 72.0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"domain_rating": map[string]float64{"domain_rating": dr}})
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	m := &monitor{
+		client:    client.NewClient(client.Config{APIKey: "test", BaseURL: srv.URL}),
+		notifiers: newNotifiers(nil, &out),
+		state:     alert.State{},
+		log:       logging.Discard(),
+	}
+	below := 70.0
+	c := check{name: "dr", target: "example.com", metric: "domain_rating", mode: "domain",
+		source: alert.Sources["domain_rating"], rule: alert.Rule{Below: &below}}
+
+	if !m.evaluate(context.Background(), c) {
+		t.Fatal("evaluate() = false, want true")
+	}
+	if out.Len() != 0 {
+		t.Errorf("evaluate() above threshold wrote %q, want nothing", out.String())
+	}
+
+	dr = 65
+	m.evaluate(context.Background(), c)
+	var ev event
+	if err := json.Unmarshal(out.Bytes(), &ev); err != nil {
+		t.Fatalf("event %q: %v", out.String(), err)
+	}
+	if ev.Check != "dr" || ev.Current != 65 || ev.Previous == nil || *ev.Previous != 72 || !ev.Triggered {
+		t.Errorf("event = %+v", ev)
+	}
+
+	// Values persist across runs
+	state, err := alert.LoadState(StateFileName)
+	if err != nil || state.Previous("dr", "domain_rating") == nil || *state.Previous("dr", "domain_rating") != 65 {
+		t.Errorf("saved state = %v, %v, want dr at 65", state, err)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := jitter(time.Hour); d < 54*time.Minute || d > 66*time.Minute {
+			t.Fatalf("jitter(1h) = %v, want within 10%%", d)
+		}
+	}
+}
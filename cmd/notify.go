@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// notifyTimeout bounds each completion webhook attempt
+	notifyTimeout = 5 * time.Second
+
+	// notifyRetryDelay is the pause before the single retry
+	notifyRetryDelay = time.Second
+)
+
+// Summary is the completion report posted to --notify-webhook
+type Summary struct {
+	Command       string `json:"command"`
+	Target        string `json:"target,omitempty"`
+	Rows          int    `json:"rows"`
+	UnitsConsumed int    `json:"units_consumed"`
+	Requests      int    `json:"requests"`
+	DurationMS    int64  `json:"duration_ms"`
+	Cached        bool   `json:"cached"`
+	ExitCode      int    `json:"exit_code"`
+	OutputFile    string `json:"output_file,omitempty"`
+}
+
+// runStats tracks what an invocation produced, for its --notify-webhook and
+// --print-exit-summary summaries
+type runStats struct {
+	mu       sync.Mutex
+	units    int
+	requests int
+	cached   bool
+	outputs  []*output.Writer
+
+	// timing sums where the time of the requests went
+	timing client.Timing
+}
+
+// track counts w's rows in the summary
+func (r *runStats) track(w *output.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outputs = append(r.outputs, w)
+}
+
+// countRequests is client middleware adding each request, and the units and
+// timing of its response, to the summary
+func (r *runStats) countRequests(next client.Handler) client.Handler {
+	return func(ctx context.Context, req client.Request) (*client.Response, error) {
+		resp, err := next(ctx, req)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests++
+		if resp != nil {
+			r.units += resp.Meta.UnitsConsumed
+			r.timing.Add(resp.Meta.Timing)
+		}
+		var reqErr *client.RequestError
+		if errors.As(err, &reqErr) {
+			r.timing.Add(reqErr.Timing)
+		}
+		return resp, err
+	}
+}
+
+// MarkCached records that the command wrote a response kept from an earlier
+// run instead of requesting it
+func (f GlobalFlags) MarkCached() {
+	if f.run == nil {
+		return
+	}
+	f.run.mu.Lock()
+	defer f.run.mu.Unlock()
+	f.run.cached = true
+}
+
+// parseNotifyHeaders parses --notify-header values of the form "Name: value"
+func parseNotifyHeaders(values []string) (http.Header, error) {
+	h := http.Header{}
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --notify-header %q: want 'Name: value'", v)
+		}
+		h.Add(name, strings.TrimSpace(value))
+	}
+	return h, nil
+}
+
+// notifyCompletion posts the summary of c's run to --notify-webhook. Failures
+// are reported as warnings and never change the command's result.
+func notifyCompletion(c *cobra.Command, run *runStats, runErr error, elapsed time.Duration) {
+	flags := globalFlags(c)
+	if flags.NotifyWebhook == "" {
+		return
+	}
+	headers, err := parseNotifyHeaders(flags.NotifyHeaders)
+	if err != nil {
+		flags.Log().Warn("completion webhook not sent", "err", err)
+		return
+	}
+
+	s := summarize(c, flags.OutputFile, run, runErr, elapsed)
+	if err := postSummary(context.Background(), flags.NotifyWebhook, headers, s); err != nil {
+		flags.Log().Warn("completion webhook failed", "err", err)
+	}
+}
+
+// summarize builds the summary of c's run
+func summarize(c *cobra.Command, outputFile string, run *runStats, runErr error, elapsed time.Duration) Summary {
+	s := Summary{
+		Command:    c.CommandPath(),
+		DurationMS: elapsed.Milliseconds(),
+		OutputFile: outputFile,
+	}
+	s.ExitCode = ExitCode(runErr)
+	// Page commands take their target as --url
+	for _, name := range []string{"target", "url"} {
+		if f := c.Flags().Lookup(name); f != nil {
+			s.Target = flagString(f.Value)
+			break
+		}
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	s.UnitsConsumed = run.units
+	s.Requests = run.requests
+	s.Cached = run.cached
+	for _, w := range run.outputs {
+		s.Rows += w.Rows()
+	}
+	return s
+}
+
+// flagString returns a flag's value, joining list flags with commas
+func flagString(v interface{ String() string }) string {
+	if list, ok := v.(interface{ GetSlice() []string }); ok {
+		return strings.Join(list.GetSlice(), ",")
+	}
+	return v.String()
+}
+
+// postSummary sends s to url, retrying once on failure
+func postSummary(ctx context.Context, url string, headers http.Header, s Summary) error {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	httpClient := &http.Client{Timeout: notifyTimeout}
+	err = postOnce(ctx, httpClient, url, headers, payload)
+	if err == nil {
+		return nil
+	}
+
+	select {
+	case <-time.After(notifyRetryDelay):
+	case <-ctx.Done():
+		return err
+	}
+	return postOnce(ctx, httpClient, url, headers, payload)
+}
+
+func postOnce(ctx context.Context, httpClient *http.Client, url string, headers http.Header, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
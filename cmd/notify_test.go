@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+func TestExecute_NotifyWebhook(t *testing.T) {
+	tests := []struct {
+		name         string
+		runErr       error
+		failFirst    bool
+		wantExitCode int
+		wantPosts    int
+	}{
+		{name: "success", wantExitCode: 0, wantPosts: 1},
+		{name: "command failure", runErr: errors.New("boom"), wantExitCode: 1, wantPosts: 1},
+		{name: "retried once", failFirst: true, wantExitCode: 0, wantPosts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu      sync.Mutex
+				posts   int
+				summary Summary
+				token   string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				posts++
+				if tt.failFirst && posts == 1 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				token = r.Header.Get("X-Token")
+				_ = json.NewDecoder(r.Body).Decode(&summary)
+			}))
+			defer srv.Close()
+
+			_, err := runWithTestCommand(t, tt.runErr, "test-cmd", "--target", "example.com",
+				"--notify-webhook", srv.URL, "--notify-header", "X-Token: s3cret", "-o", "out.csv")
+			if !errors.Is(err, tt.runErr) {
+				t.Errorf("execute() error = %v, want %v", err, tt.runErr)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if posts != tt.wantPosts {
+				t.Errorf("posts = %d, want %d", posts, tt.wantPosts)
+			}
+			if token != "s3cret" {
+				t.Errorf("X-Token = %q, want s3cret", token)
+			}
+			want := Summary{Command: "ahrefs test-cmd", Target: "example.com", ExitCode: tt.wantExitCode, OutputFile: "out.csv"}
+			summary.DurationMS = 0
+			if summary != want {
+				t.Errorf("summary = %+v, want %+v", summary, want)
+			}
+		})
+	}
+}
+
+func TestExecute_NotifyWebhookFailureIgnored(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if _, err := runWithTestCommand(t, nil, "test-cmd", "--target", "example.com", "--notify-webhook", srv.URL); err != nil {
+		t.Errorf("execute() error = %v, want nil", err)
+	}
+}
+
+func TestSummarize_RowsAndUnits(t *testing.T) {
+	run := &runStats{}
+	w, err := OpenOutput(GlobalFlags{OutputFormat: "json", Stdout: &bytes.Buffer{}, run: run})
+	if err != nil {
+		t.Fatalf("OpenOutput() error = %v", err)
+	}
+	rows := map[string]interface{}{"backlinks": []interface{}{map[string]interface{}{}, map[string]interface{}{}}}
+	if err := w.WriteSuccess(rows, nil); err != nil {
+		t.Fatalf("WriteSuccess() error = %v", err)
+	}
+
+	handler := run.countRequests(func(context.Context, client.Request) (*client.Response, error) {
+		return &client.Response{Meta: client.ResponseMeta{UnitsConsumed: 7}}, nil
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := handler(context.Background(), client.Request{}); err != nil {
+			t.Fatalf("handler() error = %v", err)
+		}
+	}
+
+	s := summarize(rootCmd, "", run, nil, 0)
+	if s.Rows != 2 || s.UnitsConsumed != 14 {
+		t.Errorf("summarize() rows = %d, units = %d, want 2, 14", s.Rows, s.UnitsConsumed)
+	}
+}
+
+func TestParseNotifyHeaders(t *testing.T) {
+	h, err := parseNotifyHeaders([]string{"X-Token: abc: def", "Authorization:Bearer x"})
+	if err != nil {
+		t.Fatalf("parseNotifyHeaders() error = %v", err)
+	}
+	if got := h.Get("X-Token"); got != "abc: def" {
+		t.Errorf("X-Token = %q, want %q", got, "abc: def")
+	}
+	if got := h.Get("Authorization"); got != "Bearer x" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer x")
+	}
+
+	if _, err := parseNotifyHeaders([]string{"no-colon"}); err == nil || !strings.Contains(err.Error(), "no-colon") {
+		t.Errorf("parseNotifyHeaders() error = %v, want invalid header error", err)
+	}
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/internal/bigquery"
+	"github.com/aminemat/ahrefs-cli/internal/objstore"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// OpenOutput creates a writer for the --output destination: command output
+// when unset, an s3:// or gs:// object uploaded on Close, or a local file.
+// Callers should Abort instead of Close when the output is incomplete.
+// A templated --output is expanded by ExpandOutput first.
+// A --preset is applied to the writer; commands that know the endpoint
+// should also call SetEndpoint.
+func OpenOutput(flags GlobalFlags) (*output.Writer, error) {
+	w, err := openPreset(flags)
+	if err != nil {
+		return nil, err
+	}
+	w.SetSecrets(flags.Secrets()...)
+	w.SetVerbose(flags.Verbose)
+	if flags.Location != nil {
+		w.SetLocation(flags.Location)
+	}
+	if flags.run != nil {
+		flags.run.track(w)
+	}
+	return w, nil
+}
+
+// ListPresets is the --preset value that lists the presets a command takes
+// instead of running it
+const ListPresets = "list"
+
+// ListsPresetsAnnotation marks commands that handle --preset list, as they
+// have column presets of their own to list
+const ListsPresetsAnnotation = "ahrefs_lists_presets"
+
+// presetListing reports whether c is run with --preset list, which is a usage
+// error for commands that don't list presets
+func presetListing(c *cobra.Command) (bool, error) {
+	if preset, _ := c.Flags().GetString("preset"); preset != ListPresets {
+		return false, nil
+	}
+	if c.Annotations[ListsPresetsAnnotation] == "" {
+		return false, NewError(CodeUsage, fmt.Sprintf("%s has no column presets to list", c.CommandPath()),
+			"Output presets every command takes: "+strings.Join(output.PresetNames(), ", "))
+	}
+	return true, nil
+}
+
+// SchemaFlag is the flag of commands that print the JSON Schema of their
+// response instead of running
+const SchemaFlag = "schema"
+
+// schemaListing reports whether c is run with --schema
+func schemaListing(c *cobra.Command) bool {
+	schema, _ := c.Flags().GetBool(SchemaFlag)
+	return schema
+}
+
+// waiveRequiredFlags lets c run without its required flags, which cobra
+// otherwise checks again after the pre-run hooks
+func waiveRequiredFlags(c *cobra.Command) {
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[cobra.BashCompOneRequiredFlag]; ok {
+			f.Annotations[cobra.BashCompOneRequiredFlag] = []string{"false"}
+		}
+	})
+}
+
+// openPreset opens the output and applies --preset
+func openPreset(flags GlobalFlags) (*output.Writer, error) {
+	if flags.Preset == "" {
+		return openOutput(flags)
+	}
+
+	preset, err := output.LookupPreset(flags.Preset)
+	if err != nil {
+		return nil, NewError(CodeUsage, err.Error(), "")
+	}
+	if flags.OutputFormat != string(output.FormatCSV) {
+		return nil, NewError(CodeUsage, "--preset requires --format csv", "Add --format csv")
+	}
+	w, err := openOutput(flags)
+	if err != nil {
+		return nil, err
+	}
+	w.SetPreset(preset)
+	return w, nil
+}
+
+func openOutput(flags GlobalFlags) (*output.Writer, error) {
+	if flags.PipeTo != "" {
+		return openPipe(flags)
+	}
+	if flags.RotateSize > 0 || flags.RotateRows > 0 {
+		return openRotating(flags)
+	}
+	if flags.Append || flags.ResumeOutput {
+		return openAppend(flags)
+	}
+
+	// Commands with a target expand a templated --output before opening it
+	if flags.outputTemplate == "" {
+		var err error
+		if flags, err = ExpandOutput(flags, OutputVars{}); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case flags.OutputFile == "":
+		if tty := pageable(flags); tty != nil {
+			return output.NewWriterCloser(flags.OutputFormat, newPagedOutput(tty)), nil
+		}
+		return output.NewWriterTo(flags.OutputFormat, flags.Stdout), nil
+	case bigquery.IsURL(flags.OutputFile):
+		return nil, NewError(CodeUsage, "this command can't write to BigQuery",
+			"bq:// output is supported by site-explorer commands; use a file or object URL here")
+	case objstore.IsURL(flags.OutputFile):
+		ctx := flags.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		obj, err := objstore.Create(ctx, flags.OutputFile, objstore.Options{
+			ContentType: output.ContentType(flags.OutputFormat),
+			SSE:         flags.SSE,
+			KMSKey:      flags.SSEKMSKey,
+		})
+		if err != nil {
+			return nil, NewError(CodeConfig, err.Error(), "Check the object URL and your cloud credentials")
+		}
+		return output.NewWriterCloser(flags.OutputFormat, obj), nil
+	case flags.outputTemplate != "":
+		return createTemplated(flags)
+	default:
+		return output.NewWriter(flags.OutputFormat, flags.OutputFile)
+	}
+}
+
+// openRotating opens the --output file for --rotate-size and --rotate-rows,
+// adding to the last of its successors with --append
+func openRotating(flags GlobalFlags) (*output.Writer, error) {
+	switch {
+	case flags.OutputFormat != string(output.FormatCSV):
+		return nil, NewError(CodeUsage, "--rotate-size and --rotate-rows split CSV rows between files", "Add --format csv")
+	case flags.OutputFile == "" || bigquery.IsURL(flags.OutputFile) || objstore.IsURL(flags.OutputFile):
+		return nil, NewError(CodeUsage, "--rotate-size and --rotate-rows split a local --output file", "Add --output with a file path")
+	case flags.ResumeOutput:
+		return nil, NewError(CodeUsage, "--resume-output can't continue a rotated file", "Remove --resume-output, or the rotation flags")
+	}
+
+	if flags.outputTemplate == "" {
+		var err error
+		if flags, err = ExpandOutput(flags, OutputVars{}); err != nil {
+			return nil, err
+		}
+	}
+	if flags.outputTemplate != "" {
+		// As createTemplated does
+		if err := os.MkdirAll(filepath.Dir(flags.OutputFile), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	return output.NewRotatingWriter(flags.OutputFormat, flags.OutputFile, output.Rotation{
+		MaxBytes: flags.RotateSize,
+		MaxRows:  flags.RotateRows,
+	}, flags.Append)
+}
+
+// openAppend opens the --output file for --append, continuing it with
+// --resume-output
+func openAppend(flags GlobalFlags) (*output.Writer, error) {
+	switch {
+	case !flags.Append:
+		return nil, NewError(CodeUsage, "--resume-output continues a file with --append", "Add --append")
+	case flags.OutputFormat != string(output.FormatCSV):
+		return nil, NewError(CodeUsage, "--append requires --format csv", "Add --format csv")
+	case flags.OutputFile == "" || bigquery.IsURL(flags.OutputFile) || objstore.IsURL(flags.OutputFile):
+		return nil, NewError(CodeUsage, "--append adds to a local --output file", "Add --output with a file path")
+	case IsOutputTemplate(flags.OutputFile) || flags.outputTemplate != "":
+		return nil, NewError(CodeUsage, "--append adds to a single --output file, not a file per target", "Remove the placeholders from --output")
+	}
+
+	w, err := output.NewAppendWriter(flags.OutputFormat, flags.OutputFile, flags.ResumeOutput)
+	if err != nil {
+		return nil, err
+	}
+	if n := w.Resumed(); n > 0 {
+		flags.Log().Info(fmt.Sprintf("Continuing %s after the %d rows it has", flags.OutputFile, n))
+	}
+	return w, nil
+}
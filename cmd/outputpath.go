@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/objstore"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+)
+
+// placeholderPattern matches the placeholders of a templated --output
+var placeholderPattern = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// placeholders are the names a templated --output may use
+var placeholders = []string{"target", "endpoint", "date", "format"}
+
+// OutputVars are the values substituted into a templated --output
+type OutputVars struct {
+	Target   string
+	Endpoint string
+}
+
+// IsOutputTemplate reports whether path has placeholders, such as
+// reports/{target}-{date}.csv, making it a file per target
+func IsOutputTemplate(path string) bool {
+	return placeholderPattern.MatchString(path)
+}
+
+// ExpandOutput returns flags with a templated --output expanded for vars.
+// {target} is made safe for a file name, {date} is today's YYYY-MM-DD, and
+// {format} is --format. Outputs without placeholders are left as they are.
+// It's a usage error for vars to have no value for a placeholder used, or,
+// unless --force or --append is set, for the file named to exist already.
+func ExpandOutput(flags GlobalFlags, vars OutputVars) (GlobalFlags, error) {
+	if !IsOutputTemplate(flags.OutputFile) {
+		return flags, nil
+	}
+
+	var unknown, missing []string
+	value := func(m, v string) string {
+		if v == "" {
+			missing = append(missing, m)
+		}
+		return SafeFileName(v)
+	}
+	expanded := placeholderPattern.ReplaceAllStringFunc(flags.OutputFile, func(m string) string {
+		switch m[1 : len(m)-1] {
+		case "target":
+			return value(m, vars.Target)
+		case "endpoint":
+			return value(m, vars.Endpoint)
+		case "date":
+			return time.Now().Format("2006-01-02")
+		case "format":
+			return flags.OutputFormat
+		default:
+			unknown = append(unknown, m)
+			return m
+		}
+	})
+	if len(unknown) > 0 {
+		return flags, NewError(CodeUsage, fmt.Sprintf("unknown --output placeholder %s", strings.Join(unknown, ", ")),
+			"Use {"+strings.Join(placeholders, "}, {")+"}")
+	}
+	if len(missing) > 0 {
+		return flags, NewError(CodeUsage, fmt.Sprintf("this command has no value for --output placeholder %s", strings.Join(missing, ", ")),
+			"Use {date} and {format} only")
+	}
+
+	// Refuse before any request is made, rather than once there's output
+	if !flags.Force && !flags.Append && !objstore.IsURL(expanded) {
+		_, err := os.Stat(expanded)
+		if err == nil {
+			return flags, NewError(CodeUsage, fmt.Sprintf("--output %s already exists", expanded),
+				"Add --force to overwrite it, or placeholders to "+flags.OutputFile+" that tell the files apart")
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return flags, err
+		}
+	}
+
+	flags.outputTemplate = flags.OutputFile
+	flags.OutputFile = expanded
+	return flags, nil
+}
+
+// unsafeFileChars are the runs of characters replaced in file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SafeFileName turns s, such as a target URL, into a file name: its scheme
+// is dropped and runs of other than letters, digits, dots, dashes, and
+// underscores become a dash
+func SafeFileName(s string) string {
+	if _, rest, ok := strings.Cut(s, "://"); ok {
+		s = rest
+	}
+	s = strings.Trim(unsafeFileChars.ReplaceAllString(s, "-"), "-.")
+	if s == "" {
+		return "_"
+	}
+	return s
+}
+
+// templatedFile is a local file named by a templated --output. Aborting
+// the output removes it, so a failed run doesn't leave a file that stops
+// the next from writing it.
+type templatedFile struct {
+	*os.File
+}
+
+// Abort closes and removes the file
+func (f templatedFile) Abort() error {
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// createTemplated creates the local file a templated --output names, and
+// its directories
+func createTemplated(flags GlobalFlags) (*output.Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(flags.OutputFile), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.Create(flags.OutputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	return output.NewWriterCloser(flags.OutputFormat, templatedFile{f}), nil
+}
+
+// TargetOutputs returns the global flags each of targets writes its output
+// with when --output is templated, or nil when they share one output. It's
+// a usage error for targets to name the same file, unless --force is set.
+func TargetOutputs(flags GlobalFlags, endpoint string, targets []string) ([]GlobalFlags, error) {
+	if !IsOutputTemplate(flags.OutputFile) {
+		return nil, nil
+	}
+
+	outputs := make([]GlobalFlags, len(targets))
+	named := map[string]string{}
+	for i, target := range targets {
+		f, err := ExpandOutput(flags, OutputVars{Target: target, Endpoint: endpoint})
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := named[f.OutputFile]; ok && !flags.Force {
+			return nil, NewError(CodeUsage, fmt.Sprintf("targets %s and %s both write to %s", other, target, f.OutputFile),
+				"Add {target} to --output, or --force to let the last one win")
+		}
+		named[f.OutputFile] = target
+		outputs[i] = f
+	}
+	return outputs, nil
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSafeFileName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"ahrefs.com", "ahrefs.com"},
+		{"https://ahrefs.com/blog/?p=1", "ahrefs.com-blog-p-1"},
+		{"sub.example.co.uk:8080/a b", "sub.example.co.uk-8080-a-b"},
+		{"../../etc", "etc"},
+		{"://", "_"},
+	}
+	for _, tt := range tests {
+		if got := SafeFileName(tt.in); got != tt.want {
+			t.Errorf("SafeFileName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandOutput(t *testing.T) {
+	dir := t.TempDir()
+	flags := GlobalFlags{OutputFormat: "csv", OutputFile: filepath.Join(dir, "{target}/{endpoint}-{date}.{format}")}
+
+	got, err := ExpandOutput(flags, OutputVars{Target: "https://ahrefs.com/", Endpoint: "backlinks"})
+	if err != nil {
+		t.Fatalf("ExpandOutput() error = %v", err)
+	}
+	want := filepath.Join(dir, "ahrefs.com", "backlinks-"+time.Now().Format("2006-01-02")+".csv")
+	if got.OutputFile != want {
+		t.Errorf("OutputFile = %q, want %q", got.OutputFile, want)
+	}
+
+	// The file's directories are created when it's opened
+	w, err := OpenOutput(got)
+	if err != nil {
+		t.Fatalf("OpenOutput() error = %v", err)
+	}
+	if err := w.WriteSuccess([]map[string]int{{"a": 1}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An existing file is only overwritten with --force
+	_, err = ExpandOutput(flags, OutputVars{Target: "ahrefs.com", Endpoint: "backlinks"})
+	var coded *Error
+	if !errors.As(err, &coded) || coded.Code != CodeUsage {
+		t.Errorf("ExpandOutput() of an existing file error = %v, want a usage error", err)
+	}
+	flags.Force = true
+	if _, err := ExpandOutput(flags, OutputVars{Target: "ahrefs.com", Endpoint: "backlinks"}); err != nil {
+		t.Errorf("ExpandOutput(--force) error = %v", err)
+	}
+
+	plain := GlobalFlags{OutputFile: filepath.Join(dir, "out.json")}
+	if got, err := ExpandOutput(plain, OutputVars{Target: "ahrefs.com"}); err != nil || got.OutputFile != plain.OutputFile {
+		t.Errorf("ExpandOutput() without placeholders = %q, %v; want it unchanged", got.OutputFile, err)
+	}
+}
+
+func TestExpandOutput_Errors(t *testing.T) {
+	for _, output := range []string{"{nope}.json", "{target}.json"} {
+		_, err := ExpandOutput(GlobalFlags{OutputFile: filepath.Join(t.TempDir(), output)}, OutputVars{Endpoint: "usage"})
+		var coded *Error
+		if !errors.As(err, &coded) || coded.Code != CodeUsage {
+			t.Errorf("ExpandOutput(%s) error = %v, want a usage error", output, err)
+		}
+	}
+}
+
+func TestOpenOutput_TemplatedAbort(t *testing.T) {
+	flags, err := ExpandOutput(GlobalFlags{OutputFormat: "json", OutputFile: filepath.Join(t.TempDir(), "{target}.json")}, OutputVars{Target: "a.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := OpenOutput(flags)
+	if err != nil {
+		t.Fatalf("OpenOutput() error = %v", err)
+	}
+	w.Abort()
+	if _, err := os.Stat(flags.OutputFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file after Abort: %v, want it removed", err)
+	}
+}
+
+func TestOpenOutput_Append(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "out.csv")
+	for _, flags := range []GlobalFlags{
+		{OutputFormat: "csv", OutputFile: file, ResumeOutput: true},
+		{OutputFormat: "json", OutputFile: file, Append: true},
+		{OutputFormat: "csv", Append: true},
+		{OutputFormat: "csv", OutputFile: "s3://bucket/out.csv", Append: true},
+		{OutputFormat: "csv", OutputFile: filepath.Join(dir, "{target}.csv"), Append: true},
+	} {
+		_, err := OpenOutput(flags)
+		var coded *Error
+		if !errors.As(err, &coded) || coded.Code != CodeUsage {
+			t.Errorf("OpenOutput(%+v) error = %v, want a usage error", flags, err)
+		}
+	}
+
+	w, err := OpenOutput(GlobalFlags{OutputFormat: "csv", OutputFile: file, Append: true, ResumeOutput: true})
+	if err != nil {
+		t.Fatalf("OpenOutput(--append --resume-output) error = %v", err)
+	}
+	w.Close()
+}
+
+func TestOpenOutput_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "out.csv")
+	for _, flags := range []GlobalFlags{
+		{OutputFormat: "json", OutputFile: file, RotateRows: 10},
+		{OutputFormat: "csv", RotateRows: 10},
+		{OutputFormat: "csv", OutputFile: "gs://bucket/out.csv", RotateSize: 1 << 20},
+		{OutputFormat: "csv", OutputFile: file, RotateRows: 10, Append: true, ResumeOutput: true},
+	} {
+		_, err := OpenOutput(flags)
+		var coded *Error
+		if !errors.As(err, &coded) || coded.Code != CodeUsage {
+			t.Errorf("OpenOutput(%+v) error = %v, want a usage error", flags, err)
+		}
+	}
+
+	// A templated --output is rotated once expanded
+	flags, err := ExpandOutput(GlobalFlags{OutputFormat: "csv", OutputFile: filepath.Join(dir, "{target}", "report.csv"), RotateRows: 1}, OutputVars{Target: "a.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := OpenOutput(flags)
+	if err != nil {
+		t.Fatalf("OpenOutput(--rotate-rows) error = %v", err)
+	}
+	if err := w.WriteSuccess([]map[string]int{{"n": 1}, {"n": 2}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "a.com", "report.1.csv"))
+	if err != nil || string(data) != "n\n2\n" {
+		t.Errorf("second file = %q (%v), want the header and the second row", data, err)
+	}
+
+	// With --append, the file existing is no reason to refuse
+	flags.OutputFile, flags.Append = flags.outputTemplate, true
+	if _, err := ExpandOutput(flags, OutputVars{Target: "a.com"}); err != nil {
+		t.Errorf("ExpandOutput(--append) error = %v, want the existing file appended to", err)
+	}
+}
+
+func TestTargetOutputs(t *testing.T) {
+	dir := t.TempDir()
+
+	outputs, err := TargetOutputs(GlobalFlags{OutputFile: filepath.Join(dir, "all.json")}, "alert", []string{"a.com", "b.com"})
+	if err != nil || outputs != nil {
+		t.Errorf("TargetOutputs() without placeholders = %v, %v; want nil", outputs, err)
+	}
+
+	outputs, err = TargetOutputs(GlobalFlags{OutputFile: filepath.Join(dir, "{target}.json")}, "alert", []string{"a.com", "b.com"})
+	if err != nil {
+		t.Fatalf("TargetOutputs() error = %v", err)
+	}
+	if len(outputs) != 2 || !strings.HasSuffix(outputs[1].OutputFile, "b.com.json") {
+		t.Errorf("TargetOutputs() = %v, want a file per target", outputs)
+	}
+
+	flags := GlobalFlags{OutputFile: filepath.Join(dir, "{endpoint}.json")}
+	if _, err := TargetOutputs(flags, "alert", []string{"a.com", "b.com"}); err == nil {
+		t.Error("TargetOutputs() of targets naming one file succeeded, want an error")
+	}
+	flags.Force = true
+	if _, err := TargetOutputs(flags, "alert", []string{"a.com", "b.com"}); err != nil {
+		t.Errorf("TargetOutputs(--force) error = %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/internal/pager"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+)
+
+// pagerCommand returns the shell command table output is paged with:
+// AHREFS_PAGER, then PAGER, or "" for the internal pager. "cat" turns paging
+// off, as it does for git.
+func pagerCommand() string {
+	for _, name := range []string{"AHREFS_PAGER", "PAGER"} {
+		if command := strings.TrimSpace(os.Getenv(name)); command != "" {
+			return command
+		}
+	}
+	return ""
+}
+
+// pageable returns the terminal table output to stdout is paged on, or nil
+// when it isn't: --no-pager is set, or stdout isn't a terminal, such as when
+// it is redirected to a file or another program
+func pageable(flags GlobalFlags) *os.File {
+	if flags.NoPager || flags.OutputFormat != string(output.FormatTable) || pagerCommand() == "cat" {
+		return nil
+	}
+	tty, ok := flags.Stdout.(*os.File)
+	if !ok || !pager.IsTerminal(tty) {
+		return nil
+	}
+	return tty
+}
+
+// pagedOutput holds table output until it is complete, then shows it on the
+// terminal: straight away when it fits on the screen, else through the pager
+type pagedOutput struct {
+	buf    bytes.Buffer
+	tty    *os.File
+	stderr *os.File
+}
+
+// newPagedOutput returns output paged on tty
+func newPagedOutput(tty *os.File) *pagedOutput {
+	return &pagedOutput{tty: tty, stderr: os.Stderr}
+}
+
+func (p *pagedOutput) Write(b []byte) (int, error) {
+	return p.buf.Write(b)
+}
+
+// Close shows the output, paging it when it's longer than the screen
+func (p *pagedOutput) Close() error {
+	height, _, err := pager.Size(p.tty)
+	if err != nil || pager.Fits(p.buf.Bytes(), height) {
+		return p.Abort()
+	}
+	if command := pagerCommand(); command != "" {
+		return p.pipe(command)
+	}
+	// The internal pager reads keys from stdin, so needs it to be the
+	// terminal too
+	if !pager.IsTerminal(os.Stdin) {
+		return p.Abort()
+	}
+	return pager.Run(p.buf.Bytes(), os.Stdin, p.tty)
+}
+
+// Abort writes the output without paging it, as output cut short by an error
+// is
+func (p *pagedOutput) Abort() error {
+	_, err := p.tty.Write(p.buf.Bytes())
+	return err
+}
+
+// pipe pages the output with the shell command. A pager quitting before it
+// has read everything is the user being done, not an error.
+func (p *pagedOutput) pipe(command string) error {
+	pg, err := startPipe(command, p.tty, p.stderr)
+	if err != nil {
+		return p.Abort()
+	}
+	pg.Write(p.buf.Bytes())
+	err = pg.Close()
+	var failed *PipeError
+	switch {
+	case errors.As(err, &failed) && failed.ExitCode > 0:
+		return fmt.Errorf("pager %q exited with status %d", command, failed.ExitCode)
+	case errors.As(err, &failed):
+		return fmt.Errorf("pager %q: %w", command, failed.Err)
+	}
+	return err
+}
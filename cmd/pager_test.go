@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPageable(t *testing.T) {
+	t.Setenv("AHREFS_PAGER", "")
+	t.Setenv("PAGER", "")
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// Paging needs a terminal, which tests don't run on, so every case is
+	// unpaged
+	for _, flags := range []GlobalFlags{
+		{OutputFormat: "table", Stdout: &bytes.Buffer{}},
+		{OutputFormat: "table", Stdout: file},
+		{OutputFormat: "table", Stdout: os.Stdout, NoPager: true},
+		{OutputFormat: "json", Stdout: os.Stdout},
+	} {
+		if tty := pageable(flags); tty != nil {
+			t.Errorf("pageable(%+v) = %v, want nil", flags, tty)
+		}
+	}
+}
+
+func TestPagerCommand(t *testing.T) {
+	t.Setenv("PAGER", "less")
+	t.Setenv("AHREFS_PAGER", "")
+	if got := pagerCommand(); got != "less" {
+		t.Errorf("pagerCommand() = %q, want $PAGER", got)
+	}
+	t.Setenv("AHREFS_PAGER", "most")
+	if got := pagerCommand(); got != "most" {
+		t.Errorf("pagerCommand() = %q, want $AHREFS_PAGER over $PAGER", got)
+	}
+}
+
+func TestPagedOutput_NotATerminal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// The screen size can't be read, so the output is written as it is
+	p := newPagedOutput(file)
+	p.Write([]byte("a  b\n"))
+	p.Write([]byte("1  2\n"))
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "a  b\n1  2\n" {
+		t.Errorf("output = %q, want the table", got)
+	}
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+)
+
+// pipeWaitDelay is how long an aborted --pipe-to command has to exit after
+// it is interrupted, before it is killed
+const pipeWaitDelay = 10 * time.Second
+
+// PipeError is a --pipe-to command that failed. The process exits with the
+// command's exit code.
+type PipeError struct {
+	Command  string
+	ExitCode int // -1 when the command was ended by a signal
+	Err      error
+}
+
+func (e *PipeError) Error() string {
+	if e.ExitCode > 0 {
+		return fmt.Sprintf("--pipe-to command %q exited with status %d", e.Command, e.ExitCode)
+	}
+	return fmt.Sprintf("--pipe-to command %q: %v", e.Command, e.Err)
+}
+
+// Unwrap returns the error the command ended with
+func (e *PipeError) Unwrap() error {
+	return e.Err
+}
+
+// openPipe starts the --pipe-to command and returns a writer feeding it the
+// rows of the output as NDJSON. The response meta, which the rows leave out,
+// is written to stderr.
+func openPipe(flags GlobalFlags) (*output.Writer, error) {
+	switch {
+	case flags.OutputFile != "":
+		return nil, NewError(CodeUsage, "--pipe-to replaces --output", "Leave out --output, or have the command write the file")
+	case flags.Append || flags.ResumeOutput:
+		return nil, NewError(CodeUsage, "--append adds to an --output file, not a --pipe-to command", "Leave out --append and --resume-output")
+	case flags.Preset != "":
+		return nil, NewError(CodeUsage, "--preset shapes CSV output, and --pipe-to writes NDJSON", "Leave out --preset")
+	}
+
+	stderr := flags.stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	p, err := startPipe(flags.PipeTo, flags.Stdout, stderr)
+	if err != nil {
+		return nil, NewError(CodeConfig, err.Error(), "Check the --pipe-to command")
+	}
+	w := output.NewWriterCloser(string(output.FormatNDJSON), p)
+	w.SetMetaWriter(stderr)
+	return w, nil
+}
+
+// pipe is a running --pipe-to command, written to through its stdin
+type pipe struct {
+	command string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+
+	// broken is set once the command stops reading its stdin. The rest of
+	// the output is discarded, and whether that was a failure is up to the
+	// command's exit code.
+	broken bool
+}
+
+// startPipe starts command with sh, its output going to stdout and stderr
+func startPipe(command string, stdout, stderr io.Writer) (*pipe, error) {
+	c := exec.Command("sh", "-c", command)
+	c.Stdout = stdout
+	c.Stderr = stderr
+	c.WaitDelay = pipeWaitDelay
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start --pipe-to command %q: %w", command, err)
+	}
+	return &pipe{command: command, cmd: c, stdin: stdin}, nil
+}
+
+// Write writes b to the command's stdin. A command that has stopped
+// reading isn't an error here; its exit code says whether it failed.
+func (p *pipe) Write(b []byte) (int, error) {
+	if p.broken {
+		return len(b), nil
+	}
+	n, err := p.stdin.Write(b)
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) {
+		p.broken = true
+		return len(b), nil
+	}
+	return n, err
+}
+
+// Close ends the command's input and waits for it to exit
+func (p *pipe) Close() error {
+	p.stdin.Close()
+	return p.wait()
+}
+
+// Abort interrupts the command, so it can tell the output is incomplete,
+// and waits for it to exit. It is killed if it doesn't within
+// pipeWaitDelay.
+func (p *pipe) Abort() error {
+	p.cmd.Process.Signal(os.Interrupt)
+	p.stdin.Close()
+	return p.wait()
+}
+
+// wait waits for the command to exit, returning a PipeError if it failed
+func (p *pipe) wait() error {
+	err := p.cmd.Wait()
+	if err == nil {
+		return nil
+	}
+	pe := &PipeError{Command: p.command, ExitCode: -1, Err: err}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		pe.ExitCode = exit.ExitCode()
+	}
+	return pe
+}
@@ -0,0 +1,124 @@
+package cmd_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+)
+
+// rowsServer serves n backlinks for every request
+func rowsServer(t *testing.T, n int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-API-Units-Consumed", "25")
+		rows := make([]string, n)
+		for i := range rows {
+			rows[i] = fmt.Sprintf(`{"url_from":"https://s.com/%d","anchor":"row %d"}`, i, i)
+		}
+		fmt.Fprintf(w, `{"backlinks":[%s]}`, strings.Join(rows, ","))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// consumer writes a fake --pipe-to consumer script running body, and
+// returns the command running it
+func consumer(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "consumer.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPipeTo(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := rowsServer(t, 3)
+	received := filepath.Join(t.TempDir(), "rows.ndjson")
+	command := consumer(t, `cat > "$1"; echo "loaded $(wc -l < "$1") rows"`) + " " + received
+
+	stdout, stderr, err := cmd.Run(t, "site-explorer", "backlinks", "-t", "t.com", "--format", "csv",
+		"--pipe-to", command, "--api-key", "test-key", "--base-url", srv.URL)
+	if err != nil {
+		t.Fatalf("Run() error = %v, stderr %s", err, stderr)
+	}
+
+	data, err := os.ReadFile(received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "{") || !strings.Contains(lines[2], `"anchor":"row 2"`) {
+		t.Errorf("rows piped = %q, want 3 NDJSON lines", data)
+	}
+	if strings.TrimSpace(stdout) != "loaded 3 rows" {
+		t.Errorf("stdout = %q, want the command's output", stdout)
+	}
+	if !strings.Contains(stderr, `{"meta":{`) || !strings.Contains(stderr, `"units_consumed":25`) {
+		t.Errorf("stderr = %q, want the response meta", stderr)
+	}
+}
+
+func TestPipeTo_ExitCode(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := rowsServer(t, 3)
+
+	_, _, err := cmd.Run(t, "site-explorer", "backlinks", "-t", "t.com",
+		"--pipe-to", consumer(t, "cat > /dev/null; exit 3"), "--api-key", "test-key", "--base-url", srv.URL)
+	var piped *cmd.PipeError
+	if !errors.As(err, &piped) || cmd.ExitCode(err) != 3 {
+		t.Errorf("error = %v, exit code %d, want the command's 3", err, cmd.ExitCode(err))
+	}
+}
+
+func TestPipeTo_BrokenPipe(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// More than a pipe buffer of rows, so writes go on after the command
+	// stops reading
+	srv := rowsServer(t, 5000)
+
+	for _, tt := range []struct {
+		body string
+		code int
+	}{
+		{"head -n 1", 0},
+		{"head -n 1; exit 4", 4},
+	} {
+		stdout, _, err := cmd.Run(t, "site-explorer", "backlinks", "-t", "t.com",
+			"--pipe-to", consumer(t, tt.body), "--api-key", "test-key", "--base-url", srv.URL)
+		if cmd.ExitCode(err) != tt.code {
+			t.Errorf("%s: error = %v, want exit code %d", tt.body, err, tt.code)
+		}
+		if !strings.Contains(stdout, `"anchor":"row 0"`) || strings.Count(stdout, "\n") != 1 {
+			t.Errorf("%s: stdout = %.100q, want the first row", tt.body, stdout)
+		}
+	}
+}
+
+func TestPipeTo_Usage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := rowsServer(t, 1)
+	out := filepath.Join(t.TempDir(), "out.csv")
+
+	for _, args := range [][]string{
+		{"--output", out},
+		{"--output", out, "--append", "--format", "csv"},
+		{"--preset", "looker", "--format", "csv"},
+	} {
+		_, _, err := cmd.Run(t, append([]string{"site-explorer", "backlinks", "-t", "t.com", "--pipe-to", "cat",
+			"--api-key", "test-key", "--base-url", srv.URL}, args...)...)
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+			t.Errorf("%v: error = %v, want a usage error", args, err)
+		}
+	}
+}
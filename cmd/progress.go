@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// reportDone reports the end of c's run as the last progress event, with the
+// totals of the run summary
+func reportDone(c *cobra.Command, inv *invocation, runErr error, elapsed time.Duration) {
+	if inv.progress == nil {
+		return
+	}
+	s := summarize(c, "", inv.run, runErr, elapsed)
+	inv.progress.Finish(runErr == nil, s.ExitCode, s.Rows, s.UnitsConsumed, s.Requests)
+}
@@ -0,0 +1,60 @@
+package cmd_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/progress"
+)
+
+func TestProgress_JSON(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-API-Units-Consumed", "10")
+		if r.URL.Query().Get("offset") == "" {
+			fmt.Fprint(w, `{"backlinks":[{"url_from":"https://a.example/"},{"url_from":"https://b.example/"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"backlinks":[{"url_from":"https://c.example/"}]}`)
+	}))
+	defer srv.Close()
+
+	_, stderr, err := cmd.Run(t, "site-explorer", "backlinks", "-t", "t.com", "--limit", "2", "--all", "--progress", "json",
+		"--format", "csv", "--api-key", "test-key", "--base-url", srv.URL)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var events []progress.Event
+	sc := bufio.NewScanner(strings.NewReader(stderr))
+	for sc.Scan() {
+		var e progress.Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("stderr line %q is not an event: %v", sc.Text(), err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 2 pages and done:\n%s", len(events), stderr)
+	}
+	for i, want := range []progress.Event{
+		{Event: "page", Endpoint: "/site-explorer/backlinks", Page: 1, Rows: 2, Units: 10},
+		{Event: "page", Endpoint: "/site-explorer/backlinks", Page: 2, Rows: 3, Units: 20},
+	} {
+		got := events[i]
+		got.ElapsedMS = 0
+		if got != want {
+			t.Errorf("event %d = %+v, want %+v", i, got, want)
+		}
+	}
+	done := events[2]
+	if done.Event != "done" || done.OK == nil || !*done.OK || *done.ExitCode != 0 || done.Rows != 3 || done.Units != 20 || done.Requests != 2 {
+		t.Errorf("done event = %+v, want ok with 3 rows, 20 units, 2 requests", done)
+	}
+}
@@ -0,0 +1,80 @@
+package cmd_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/cmd/keywords"
+	"github.com/aminemat/ahrefs-cli/cmd/siteexplorer"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+)
+
+func init() {
+	cmd.AddCommands(siteexplorer.NewSiteExplorerCmd(), keywords.NewKeywordsCmd())
+}
+
+// secretServer returns an API that answers backlinks and rejects everything
+// else, echoing the bearer token in the error as a misbehaving API or proxy
+// might
+func secretServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/site-explorer/backlinks" {
+			fmt.Fprint(w, `{"backlinks":[{"url_from":"https://a.com/","url_to":"https://t.com/","anchor":"a"}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(w, `{"error":"invalid API key %s","details":[{"field":"Authorization","message":"Bearer %s"}]}`, token, token)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestDiagnostics_NeverPrintKey runs commands down every path that prints
+// requests, logs, or errors, and checks the API key appears in none of
+// their output
+func TestDiagnostics_NeverPrintKey(t *testing.T) {
+	const key = "sk_live_0123456789abcdef"
+	srv := secretServer(t)
+
+	runs := [][]string{
+		{"site-explorer", "backlinks", "-t", "t.com", "-vv"},
+		{"site-explorer", "backlinks", "-t", "t.com", "-vv", "--log-format", "json", "--check-schema"},
+		{"site-explorer", "backlinks", "-t", "t.com", "--dry-run", "-vv"},
+		{"site-explorer", "refdomains", "-t", "t.com", "-vv", "--no-retry"},
+		{"site-explorer", "refdomains", "-t", "t.com", "--json-errors", "--log-format", "json", "-vv"},
+		{"site-explorer", "refdomains", "-t", "t.com", "--format", "table"},
+		{"keywords", "difficulty", "best crm", "-c", "us", "-vv"},
+		{"keywords", "difficulty", "best crm", "-c", "us", "--dry-run"},
+		{"site-explorer", "backlinks", "--bogus-flag"},
+	}
+	for _, source := range []string{"flag", "config"} {
+		for _, args := range runs {
+			t.Run(source+" "+strings.Join(args, " "), func(t *testing.T) {
+				t.Setenv("HOME", t.TempDir())
+				t.Setenv("AHREFS_API_KEY", "")
+				args = append(args, "--base-url", srv.URL)
+				if source == "flag" {
+					args = append(args, "--api-key", key)
+				} else if err := config.Save(&config.Config{APIKey: key}); err != nil {
+					t.Fatalf("Save() error = %v", err)
+				}
+
+				stdout, stderr, _ := cmd.Run(t, args...)
+				if stdout+stderr == "" {
+					t.Fatal("the command printed nothing to check")
+				}
+				for name, out := range map[string]string{"stdout": stdout, "stderr": stderr} {
+					if strings.Contains(out, key) {
+						t.Errorf("%s contains the API key:\n%s", name, out)
+					}
+				}
+			})
+		}
+	}
+}
@@ -1,40 +1,55 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/aminemat/ahrefs-cli/internal/logging"
+	"github.com/aminemat/ahrefs-cli/internal/paths"
+	"github.com/aminemat/ahrefs-cli/internal/progress"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
-var (
-	// Global flags
-	apiKey       string
-	outputFormat string
-	outputFile   string
-	verbose      bool
-	quiet        bool
-	dryRun       bool
-	listCommands bool
-)
+// rootName is the name of the root command
+const rootName = "ahrefs"
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "ahrefs",
+	Use:   rootName,
 	Short: "Ahrefs API CLI - AI agent-friendly interface to Ahrefs API v3",
 	Long: `Ahrefs CLI is a command-line interface for the Ahrefs API v3.
 
 Designed for maximum discoverability and ease of use by AI coding agents.
-Every command supports --help, --describe, and --list-fields for introspection.
+Every command supports --help, --describe, and --list-fields for introspection,
+and site-explorer commands print the JSON Schema of their response with --schema.
 
 Authentication:
   Set API key via --api-key flag or AHREFS_API_KEY environment variable.
   Or use 'ahrefs config set-key <key>' to persist in config file.
 
+Defaults:
+  Flags are resolved as flag > environment variable > command config defaults >
+  config defaults > built-in.
+  Each flag's environment variable is listed in its usage, e.g. AHREFS_FORMAT.
+  Set config defaults with 'ahrefs config set-default <flag> <value>', or for one
+  command with --command, e.g. a longer --timeout for site-explorer backlinks.
+
 Output Formats:
-  json (default), yaml, csv, table
+  json (default), yaml, csv, table, markdown
 
 Examples:
   # Get domain rating
@@ -47,15 +62,55 @@ Examples:
   ahrefs site-explorer backlinks --describe`,
 	Version: "0.1.0",
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Flags left off the command line are filled from the environment
+		// and config first, so their values are validated like the rest
+		filled, err := applyDefaults(cmd)
+		if err != nil {
+			return defaultsError(err)
+		}
+
+		// --preset list and --schema print presets or the response schema
+		// in place of running the command, so they need none of the
+		// command's flags
+		listing, err := presetListing(cmd)
+		if err != nil {
+			return err
+		}
+		listing = listing || schemaListing(cmd)
+		// Cobra validates required flags after this hook; do it first so
+		// missing flags are reported as usage errors
+		if listing {
+			waiveRequiredFlags(cmd)
+		} else if err := validateFlags(cmd, filled); err != nil {
+			return usageError(cmd, err)
+		}
+		flags, err := loadGlobalFlags(cmd)
+		if err != nil {
+			return err
+		}
+		inv := invocationOf(cmd.Context())
+		inv.started = true
+
 		// Handle --list-commands at root level
-		if listCommands {
-			return printCommandList(cmd.Root())
+		if layout, _ := cmd.Flags().GetString("list-commands"); layout != "" {
+			filter, _ := cmd.Flags().GetString("filter")
+			return printCommandList(cmd.OutOrStdout(), cmd.Root(), layout, filter)
+		}
+
+		// Runs are tracked for their summaries, and for the timing report -v
+		// logs
+		verbose := flags.Log().Enabled(cmd.Context(), slog.LevelDebug)
+		if flags.NotifyWebhook != "" || flags.PrintExitSummary || flags.Progress != nil || verbose {
+			flags.run = inv.run
 		}
+		inv.progress = flags.Progress
+		flags.ctx = cmd.Context()
+		cmd.SetContext(WithGlobalFlags(cmd.Context(), flags))
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// If --list-commands was specified, it was already handled in PersistentPreRunE
-		if listCommands {
+		if layout, _ := cmd.Flags().GetString("list-commands"); layout != "" {
 			return nil
 		}
 		// Otherwise show help
@@ -64,21 +119,151 @@ Examples:
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// Commands run with a context canceled on SIGINT or SIGTERM.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return execute(ctx, os.Args[1:])
+}
+
+// execute runs the root command with args under ctx, reporting every failure,
+// including usage errors and panics, through reportError
+func execute(ctx context.Context, args []string) (err error) {
+	inv := &invocation{args: args, run: &runStats{}}
+	ctx = context.WithValue(ctx, invocationKey{}, inv)
+	rootCmd.SetArgs(args)
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return usageError(c, err)
+	})
+
+	// The summary comes last, after a panic is reported
+	c := rootCmd
+	start := time.Now()
+	defer func() {
+		reportDone(c, inv, err, time.Since(start))
+		reportTiming(c, inv.run, time.Since(start))
+		printExitSummary(c, inv.run, err, time.Since(start))
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			if reraise, _ := rootCmd.PersistentFlags().GetBool("debug-panic"); reraise {
+				panic(r)
+			}
+			err = panicError(r, debug.Stack(), args)
+			reportError(rootCmd, err, args)
+		}
+	}()
+
+	defer func() {
+		notifyCompletion(c, inv.run, err, time.Since(start))
+	}()
+
+	defer shutdownTelemetry()
+
+	c, err = rootCmd.ExecuteContextC(ctx)
+	if err != nil {
+		if !inv.started {
+			err = usageError(c, err)
+		}
+		reportError(c, err, args)
+	}
+	return err
+}
+
+// invocation is the state of one execution of the root command
+type invocation struct {
+	// args holds the unparsed command line for error reporting
+	args []string
+
+	// started is set once flag and argument validation has passed
+	started bool
+
+	// run tracks what the command produced, for --notify-webhook,
+	// --print-exit-summary, --progress, and the -v timing report
+	run *runStats
+
+	// progress reports the run's progress events, when --progress is set
+	progress *progress.Reporter
+}
+
+// invocationKey is the context key of the current invocation
+type invocationKey struct{}
+
+// invocationOf returns the invocation ctx belongs to, or a blank one for
+// commands run outside execute
+func invocationOf(ctx context.Context) *invocation {
+	if ctx != nil {
+		if inv, ok := ctx.Value(invocationKey{}).(*invocation); ok {
+			return inv
+		}
+	}
+	return &invocation{run: &runStats{}}
 }
 
 func init() {
 	// Global flags available to all commands
-	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", os.Getenv("AHREFS_API_KEY"), "Ahrefs API key (or set AHREFS_API_KEY env var)")
-	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", "json", "Output format: json, yaml, csv, table")
-	rootCmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "", "Output file (default: stdout)")
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output (show request/response details)")
-	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Quiet mode (errors only)")
-	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Validate request without executing")
+	rootCmd.PersistentFlags().String("api-key", "", "Ahrefs API key")
+	rootCmd.PersistentFlags().StringSlice("base-url", nil, "API base URL (default: https://api.ahrefs.com/v3); repeat for base URLs to fail over to, in order, when one can't be reached")
+	rootCmd.PersistentFlags().StringArray("header", nil, "Header sent with every API request, e.g. 'X-Corp-Auth: ${CORP_TOKEN}' (repeatable); adds to the config file's headers")
+	rootCmd.PersistentFlags().Bool("allow-override-auth", false, "Let --header or the config file replace the Authorization header carrying the API key")
+	rootCmd.PersistentFlags().String("format", "json", "Output format: json, yaml, csv, table, markdown")
+	rootCmd.PersistentFlags().StringP("output", "o", "", "Output file or s3:// or gs:// object URL (default: stdout), uploaded in a single request of at most 5 GiB to S3; {target}, {endpoint}, {date}, and {format} make a file per target")
+	rootCmd.PersistentFlags().Bool("force", false, "Overwrite files a templated --output names that already exist")
+	rootCmd.PersistentFlags().Bool("append", false, "Add CSV rows to the end of --output instead of replacing it, with the header only in an empty file")
+	rotateSize := byteSize(-1)
+	rootCmd.PersistentFlags().Var(&rotateSize, "rotate-size", "Continue CSV --output in a numbered successor (report.1.csv, report.2.csv, ...) once a file reaches this size, e.g. 100MB; 0 for no limit")
+	rootCmd.PersistentFlags().Int("rotate-rows", 0, "Continue CSV --output in a numbered successor once a file has this many rows; 0 for no limit")
+	rootCmd.PersistentFlags().Bool("resume-output", false, "With --append, continue an --output file an interrupted run wrote: skip the rows it has and remove a row cut off partway")
+	rootCmd.PersistentFlags().String("pipe-to", "", "Write the rows as NDJSON to the stdin of this shell command, e.g. a Parquet converter, exiting with its exit code")
+	rootCmd.PersistentFlags().Bool("no-pager", false, "Write table output straight to the terminal instead of paging it when it's longer than the screen")
+	rootCmd.PersistentFlags().String("preset", "", "Shape CSV output for a downstream tool ("+strings.Join(output.PresetNames(), ", ")+"), or pick a site-explorer command's columns; 'list' shows them")
+	rootCmd.PersistentFlags().String("sse", "", "Server-side encryption for s3:// output: AES256 or aws:kms")
+	rootCmd.PersistentFlags().String("sse-kms-key", "", "KMS key for s3:// or gs:// output encryption")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "HTTP request timeout (e.g., 30s, 2m; default 60s)")
+	maxBodySize := byteSize(client.DefaultMaxBodySize)
+	rootCmd.PersistentFlags().Var(&maxBodySize, "max-body-size", "Largest response body read into memory, e.g. 500MB; 0 for no limit")
+	rootCmd.PersistentFlags().Int("max-retries", client.DefaultMaxRetries, "Retries of a request failing with a rate limit or server error; 0 for none")
+	rootCmd.PersistentFlags().Bool("no-retry", false, "Fail on the first error instead of retrying rate limits and server errors, e.g. when an orchestrator retries")
+	rootCmd.PersistentFlags().String("value", "", "Print only the value of this field (from a single object or the first row)")
+	rootCmd.PersistentFlags().Bool("timestamp", false, "Add a fetched_at ISO-8601 field to every row of the output")
+	rootCmd.PersistentFlags().Var(&timezone{}, "timezone", "IANA time zone, e.g. America/New_York, of the timestamps in the output and of today in relative dates such as 7d (default: local)")
+	verbose := verbosity(0)
+	rootCmd.PersistentFlags().VarP(&verbose, "verbose", "v", "Log each request's details to stderr; -vv logs more")
+	rootCmd.PersistentFlags().Lookup("verbose").NoOptDefVal = "+1"
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Quiet mode (errors only)")
+	rootCmd.PersistentFlags().String("log-format", logging.FormatText, "Format of diagnostics on stderr: text, or json for log pipelines")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Validate request without executing")
+	rootCmd.PersistentFlags().Bool("check-schema", false, "Warn when response fields differ from the typed model (implied by --verbose)")
+	rootCmd.PersistentFlags().Bool("json-errors", false, "Emit every error as a single JSON object on stderr (implied by an explicit --format json)")
+	rootCmd.PersistentFlags().Bool("estimate", false, "Print the estimated unit cost without executing")
+	rootCmd.PersistentFlags().Bool("confirm", false, "Prompt before executing requests estimated above --confirm-threshold")
+	rootCmd.PersistentFlags().Int("confirm-threshold", 1000, "Unit estimate above which --confirm prompts")
+	rootCmd.PersistentFlags().Bool("enforce-budget", false, "Fail instead of warning when the monthly unit budget is exceeded")
+	rootCmd.PersistentFlags().String("notify-webhook", "", "POST a JSON run summary to this URL when the command finishes")
+	rootCmd.PersistentFlags().StringArray("notify-header", nil, "Header for --notify-webhook, e.g. 'X-Token: secret' (repeatable)")
+	rootCmd.PersistentFlags().Bool("print-exit-summary", false, "Write a JSON line summarizing the run (rows, units, requests, exit code) to stderr at exit")
+	rootCmd.PersistentFlags().String("progress", "", "Report progress on stderr: bar for a status line, json for an event per line at each page, target, retry, and the end")
+	rootCmd.PersistentFlags().Bool("debug-panic", false, "Re-raise panics with the Go stack trace instead of reporting them")
+	_ = rootCmd.PersistentFlags().MarkHidden("debug-panic")
+
+	// Each persistent flag takes a default from AHREFS_ and its name, e.g.
+	// AHREFS_DRY_RUN, other than those in envDenied
+	documentEnv(rootCmd.PersistentFlags())
+
+	SetAllowedValues(rootCmd, "format", "json", "yaml", "csv", "table", "markdown")
+	// Site-explorer commands and the config file add presets of their own
+	SetSuggestedValues(rootCmd, "preset", append(output.PresetNames(), ListPresets)...)
+	SetAllowedValues(rootCmd, "log-format", logging.Formats...)
+	SetAllowedValues(rootCmd, "progress", progress.Formats...)
 
 	// Root-level flags
-	rootCmd.Flags().BoolVar(&listCommands, "list-commands", false, "List all available commands as JSON")
+	rootCmd.Flags().String("list-commands", "", "List all available commands as JSON: a tree, or with =flat one entry per command")
+	rootCmd.Flags().Lookup("list-commands").NoOptDefVal = listTree
+	rootCmd.Flags().String("filter", "", "With --list-commands, only list commands whose path contains this")
+	SetAllowedValues(rootCmd, "list-commands", listTree, listFlat)
 }
 
 // AddCommands adds all subcommands to root
@@ -92,23 +277,101 @@ type CommandInfo struct {
 	Use         string        `json:"use"`
 	Short       string        `json:"short"`
 	Long        string        `json:"long"`
+	Endpoint    string        `json:"endpoint,omitempty"`
 	Subcommands []CommandInfo `json:"subcommands,omitempty"`
 	Flags       []FlagInfo    `json:"flags,omitempty"`
 	Examples    string        `json:"examples,omitempty"`
 }
 
+// CommandEntry is a command in the flat --list-commands listing, named by
+// its path below the root, e.g. "site-explorer backlinks"
+type CommandEntry struct {
+	Path     string     `json:"path"`
+	Short    string     `json:"short"`
+	Endpoint string     `json:"endpoint,omitempty"`
+	Flags    []FlagInfo `json:"flags,omitempty"`
+}
+
+// The --list-commands layouts
+const (
+	listTree = "tree"
+	listFlat = "flat"
+)
+
+// EndpointAnnotation records the API endpoint path a command requests, so
+// --list-commands can map commands to their units pricing
+const EndpointAnnotation = "ahrefs_endpoint"
+
+// FlagInfo represents metadata about a flag for introspection
 type FlagInfo struct {
-	Name      string `json:"name"`
-	Shorthand string `json:"shorthand,omitempty"`
-	Usage     string `json:"usage"`
-	DefValue  string `json:"default,omitempty"`
-	Required  bool   `json:"required"`
+	Name          string   `json:"name"`
+	Shorthand     string   `json:"shorthand,omitempty"`
+	Type          string   `json:"type"`
+	Usage         string   `json:"usage"`
+	DefValue      string   `json:"default,omitempty"`
+	Required      bool     `json:"required"`
+	AllowedValues []string `json:"allowed_values,omitempty"`
+}
+
+// allowedValuesAnnotation is the flag annotation key listing enum values
+const allowedValuesAnnotation = "ahrefs_allowed_values"
+
+// SetAllowedValues restricts an enum-like flag to values, reported by
+// --list-commands and offered by shell completion. Any other value is a
+// usage error when the flag is parsed. Flags other than strings are only
+// documented and completed, like with SetSuggestedValues.
+func SetAllowedValues(c *cobra.Command, name string, values ...string) {
+	flags := c.Flags()
+	if flags.Lookup(name) == nil {
+		flags = c.PersistentFlags()
+	}
+	if flag := flags.Lookup(name); flag != nil && flag.Value.Type() == "string" {
+		flag.Value = &enumValue{Value: flag.Value, allowed: values, empty: flag.DefValue == ""}
+	}
+	SetSuggestedValues(c, name, values...)
 }
 
-// printCommandList outputs all available commands as JSON
-func printCommandList(cmd *cobra.Command) error {
-	info := buildCommandInfo(cmd)
-	enc := json.NewEncoder(os.Stdout)
+// SetSuggestedValues records the usual values of a flag so they are reported
+// by --list-commands and offered by shell completion, without restricting it
+// to them
+func SetSuggestedValues(c *cobra.Command, name string, values ...string) {
+	flags := c.Flags()
+	if flags.Lookup(name) == nil {
+		flags = c.PersistentFlags()
+	}
+	_ = flags.SetAnnotation(name, allowedValuesAnnotation, values)
+	_ = c.RegisterFlagCompletionFunc(name, cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// SetAllowedListValues is SetAllowedValues for a comma-separated list flag:
+// completion offers the values for the item after the last comma
+func SetAllowedListValues(c *cobra.Command, name string, values ...string) {
+	flags := c.Flags()
+	if flags.Lookup(name) == nil {
+		flags = c.PersistentFlags()
+	}
+	_ = flags.SetAnnotation(name, allowedValuesAnnotation, values)
+	_ = c.RegisterFlagCompletionFunc(name, func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		done := ""
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			done = toComplete[:i+1]
+		}
+		completions := make([]string, 0, len(values))
+		for _, v := range values {
+			completions = append(completions, done+v)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	})
+}
+
+// printCommandList outputs the commands under cmd as JSON, in layout, only
+// those whose path contains filter when it is set
+func printCommandList(w io.Writer, cmd *cobra.Command, layout, filter string) error {
+	var info interface{} = buildCommandInfo(cmd, filter)
+	if layout == listFlat {
+		info = FlatCommandInfo(cmd, filter)
+	}
+	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
 	if err := enc.Encode(info); err != nil {
 		return fmt.Errorf("failed to encode command list: %w", err)
@@ -116,59 +379,288 @@ func printCommandList(cmd *cobra.Command) error {
 	return nil
 }
 
-// buildCommandInfo recursively builds command metadata
-func buildCommandInfo(cmd *cobra.Command) CommandInfo {
+// BuildCommandInfo recursively builds command metadata
+func BuildCommandInfo(cmd *cobra.Command) CommandInfo {
+	return buildCommandInfo(cmd, "")
+}
+
+// buildCommandInfo builds the metadata of cmd and those of its subcommands
+// that match filter, or have subcommands that do. A match keeps all of its
+// subcommands.
+func buildCommandInfo(cmd *cobra.Command, filter string) CommandInfo {
 	info := CommandInfo{
 		Name:     cmd.Name(),
 		Use:      cmd.Use,
 		Short:    cmd.Short,
 		Long:     cmd.Long,
+		Endpoint: cmd.Annotations[EndpointAnnotation],
+		Flags:    flagInfo(cmd),
 		Examples: cmd.Example,
 	}
 
-	// Add flags
+	// Add subcommands recursively
+	for _, subcmd := range cmd.Commands() {
+		if subcmd.Hidden || !matchesFilter(subcmd, filter) {
+			continue
+		}
+		sub := filter
+		if pathMatches(subcmd, filter) {
+			sub = ""
+		}
+		info.Subcommands = append(info.Subcommands, buildCommandInfo(subcmd, sub))
+	}
+
+	return info
+}
+
+// FlatCommandInfo lists the runnable commands under cmd whose path contains
+// filter, sorted by path
+func FlatCommandInfo(cmd *cobra.Command, filter string) []CommandEntry {
+	entries := []CommandEntry{}
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, sub := range c.Commands() {
+			if sub.Hidden {
+				continue
+			}
+			if sub.Runnable() && pathMatches(sub, filter) {
+				entries = append(entries, CommandEntry{
+					Path:     commandPath(sub),
+					Short:    sub.Short,
+					Endpoint: sub.Annotations[EndpointAnnotation],
+					Flags:    flagInfo(sub),
+				})
+			}
+			walk(sub)
+		}
+	}
+	walk(cmd)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// commandPath is the path of c below the root command
+func commandPath(c *cobra.Command) string {
+	return strings.TrimPrefix(strings.TrimPrefix(c.CommandPath(), c.Root().Name()), " ")
+}
+
+// pathMatches reports whether the path of c contains filter, ignoring case
+func pathMatches(c *cobra.Command, filter string) bool {
+	return strings.Contains(strings.ToLower(commandPath(c)), strings.ToLower(filter))
+}
+
+// matchesFilter reports whether c or any of its subcommands match filter
+func matchesFilter(c *cobra.Command, filter string) bool {
+	if pathMatches(c, filter) {
+		return true
+	}
+	for _, sub := range c.Commands() {
+		if !sub.Hidden && matchesFilter(sub, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// flagInfo returns the metadata of the flags of cmd, leaving out hidden
+// developer flags
+func flagInfo(cmd *cobra.Command) []FlagInfo {
+	var flags []FlagInfo
 	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
 		flagInfo := FlagInfo{
-			Name:      flag.Name,
-			Shorthand: flag.Shorthand,
-			Usage:     flag.Usage,
-			DefValue:  flag.DefValue,
+			Name:          flag.Name,
+			Shorthand:     flag.Shorthand,
+			Type:          flag.Value.Type(),
+			Usage:         flag.Usage,
+			DefValue:      flag.DefValue,
+			AllowedValues: allowedValues(flag),
 		}
-		// Check if required
-		if requiredAnnotation, ok := flag.Annotations["required"]; ok && len(requiredAnnotation) > 0 {
+		// MarkFlagRequired records required flags under cobra's bash completion annotation
+		if required, ok := flag.Annotations[cobra.BashCompOneRequiredFlag]; ok && len(required) > 0 && required[0] == "true" {
 			flagInfo.Required = true
 		}
-		info.Flags = append(info.Flags, flagInfo)
+		flags = append(flags, flagInfo)
 	})
+	return flags
+}
 
-	// Add subcommands recursively
-	for _, subcmd := range cmd.Commands() {
-		if !subcmd.Hidden {
-			info.Subcommands = append(info.Subcommands, buildCommandInfo(subcmd))
+// globalFlags reads the global flag values parsed into c's flags, as
+// loadGlobalFlags does, leaving out headers that don't parse. It's for
+// reporting on a command, which loadGlobalFlags has checked before it ran.
+func globalFlags(c *cobra.Command) GlobalFlags {
+	flags, _ := loadGlobalFlags(c)
+	return flags
+}
+
+// loadGlobalFlags reads the global flag values parsed into c's flags, which
+// include those inherited from the root command. Flags c doesn't have are
+// left zero. An invalid --header or config file header is an error, with
+// the other values still read.
+func loadGlobalFlags(c *cobra.Command) (GlobalFlags, error) {
+	fs := c.Flags()
+	str := func(name string) string {
+		v, _ := fs.GetString(name)
+		return v
+	}
+	boolean := func(name string) bool {
+		v, _ := fs.GetBool(name)
+		return v
+	}
+	timeout, _ := fs.GetDuration("timeout")
+	var maxBodySize, rotateSize int64
+	if f := fs.Lookup("max-body-size"); f != nil {
+		if size, ok := f.Value.(*byteSize); ok {
+			maxBodySize = int64(*size)
 		}
 	}
+	if f := fs.Lookup("rotate-size"); f != nil {
+		if size, ok := f.Value.(*byteSize); ok {
+			rotateSize = int64(*size)
+		}
+	}
+	rotateRows, _ := fs.GetInt("rotate-rows")
+	maxRetries, err := fs.GetInt("max-retries")
+	if err == nil && maxRetries <= 0 || boolean("no-retry") {
+		maxRetries = -1
+	}
+	location := time.Local
+	if f := fs.Lookup("timezone"); f != nil {
+		if zone, ok := f.Value.(*timezone); ok {
+			location = zone.location()
+		}
+	}
+	confirmAbove, _ := fs.GetInt("confirm-threshold")
+	notifyHeaders, _ := fs.GetStringArray("notify-header")
+	baseURLs, _ := fs.GetStringSlice("base-url")
+	headers, headersErr := loadRequestHeaders(fs)
+	var baseURL string
+	if len(baseURLs) > 0 {
+		baseURL = baseURLs[0]
+	}
+	verbosity := verbosityOf(fs)
 
-	return info
-}
-
-// GetGlobalFlags returns the current global flag values
-func GetGlobalFlags() GlobalFlags {
 	return GlobalFlags{
-		APIKey:       apiKey,
-		OutputFormat: outputFormat,
-		OutputFile:   outputFile,
-		Verbose:      verbose,
-		Quiet:        quiet,
-		DryRun:       dryRun,
-	}
+		APIKey:           str("api-key"),
+		BaseURL:          baseURL,
+		BaseURLs:         baseURLs,
+		Headers:          headers.header,
+		headers:          headers,
+		OutputFormat:     str("format"),
+		OutputFile:       paths.Expand(str("output")),
+		Preset:           str("preset"),
+		SSE:              str("sse"),
+		SSEKMSKey:        str("sse-kms-key"),
+		Timeout:          timeout,
+		MaxBodySize:      maxBodySize,
+		MaxRetries:       maxRetries,
+		ValueField:       str("value"),
+		Timestamp:        boolean("timestamp"),
+		Location:         location,
+		Verbose:          verbosity > 0,
+		Verbosity:        verbosity,
+		Quiet:            boolean("quiet"),
+		LogFormat:        str("log-format"),
+		DryRun:           boolean("dry-run"),
+		CheckSchema:      boolean("check-schema"),
+		JSONErrors:       boolean("json-errors"),
+		Estimate:         boolean("estimate"),
+		Confirm:          boolean("confirm"),
+		ConfirmAbove:     confirmAbove,
+		EnforceBudget:    boolean("enforce-budget"),
+		NotifyWebhook:    str("notify-webhook"),
+		NotifyHeaders:    notifyHeaders,
+		PrintExitSummary: boolean("print-exit-summary"),
+		Progress:         progress.New(stderrOf(c), str("progress")),
+		Force:            boolean("force"),
+		Append:           boolean("append"),
+		ResumeOutput:     boolean("resume-output"),
+		RotateSize:       rotateSize,
+		RotateRows:       rotateRows,
+		PipeTo:           str("pipe-to"),
+		NoPager:          boolean("no-pager"),
+		Stdout:           c.OutOrStdout(),
+		stderr:           stderrOf(c),
+		Logger:           commandLogger(c),
+	}, headersErr
 }
 
 // GlobalFlags holds all global flag values
 type GlobalFlags struct {
-	APIKey       string
-	OutputFormat string
-	OutputFile   string
-	Verbose      bool
-	Quiet        bool
-	DryRun       bool
+	APIKey           string
+	BaseURL          string
+	OutputFormat     string
+	OutputFile       string
+	Preset           string
+	SSE              string
+	SSEKMSKey        string
+	Timeout          time.Duration
+	MaxBodySize      int64 // bytes; 0 for the client default, negative for no limit
+	MaxRetries       int   // 0 for the client default, negative for none
+	ValueField       string
+	Timestamp        bool
+	Verbose          bool
+	Verbosity        int // times --verbose was given
+	Quiet            bool
+	LogFormat        string
+	DryRun           bool
+	CheckSchema      bool
+	JSONErrors       bool
+	Estimate         bool
+	Confirm          bool
+	ConfirmAbove     int
+	EnforceBudget    bool
+	NotifyWebhook    string
+	NotifyHeaders    []string
+	PrintExitSummary bool
+	Force            bool
+	Append           bool
+	ResumeOutput     bool  // with Append, skip the rows the output file has
+	RotateSize       int64 // bytes; 0 or negative for no limit
+	RotateRows       int
+	PipeTo           string
+	NoPager          bool
+
+	// BaseURLs are the --base-url values in order of preference, for
+	// failing over; BaseURL is the first
+	BaseURLs []string
+
+	// Headers are sent with every API request, from --header and the
+	// config file
+	Headers http.Header
+
+	// Location is the --timezone timestamps are written in, and relative
+	// dates resolved in; see Now
+	Location *time.Location
+
+	// Progress receives the progress events of --progress; nil discards
+	// them
+	Progress *progress.Reporter
+
+	// Stdout receives command output
+	Stdout io.Writer
+
+	// Logger receives diagnostics; see Log for when it is nil
+	Logger *slog.Logger
+
+	// headers are Headers, knowing which to mask
+	headers requestHeaders
+
+	// outputTemplate is the templated --output OutputFile was expanded from
+	outputTemplate string
+
+	// stderr receives the output of a --pipe-to command's stderr, and the
+	// response meta its rows leave out; os.Stderr when nil
+	stderr io.Writer
+
+	// run, when set, tracks the outputs, requests, units, and timing of the
+	// invocation for its --notify-webhook and --print-exit-summary summaries
+	// and the -v timing report
+	run *runStats
+
+	// ctx is the command's context, which bounds the upload of an object
+	// --output; context.Background() when nil
+	ctx context.Context
 }
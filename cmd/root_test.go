@@ -0,0 +1,483 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// resetFlags restores every flag on c and its children to its default value,
+// and drops the context of the last run, which cobra would otherwise hand
+// the next one
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if list, ok := f.Value.(pflag.SliceValue); ok {
+			_ = list.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.PersistentFlags().VisitAll(reset)
+	c.Flags().VisitAll(reset)
+	c.SetContext(nil)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// runWithTestCommand executes args against the root command with an extra
+// "test-cmd" subcommand whose RunE returns runErr, capturing stderr
+func runWithTestCommand(t *testing.T, runErr error, args ...string) (string, error) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	testCmd := &cobra.Command{
+		Use: "test-cmd",
+		RunE: func(*cobra.Command, []string) error {
+			return runErr
+		},
+	}
+	testCmd.Flags().String("target", "", "Target")
+	_ = testCmd.MarkFlagRequired("target")
+
+	rootCmd.AddCommand(testCmd)
+	defer rootCmd.RemoveCommand(testCmd)
+	defer resetFlags(rootCmd)
+
+	var stderr bytes.Buffer
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetOut(&bytes.Buffer{})
+	defer rootCmd.SetErr(nil)
+	defer rootCmd.SetOut(nil)
+
+	err := execute(context.Background(), args)
+	return stderr.String(), err
+}
+
+// decodeJSONError parses a single JSON error object from stderr output
+func decodeJSONError(t *testing.T, stderr string) map[string]interface{} {
+	t.Helper()
+
+	if strings.Count(strings.TrimSpace(stderr), "\n") != 0 {
+		t.Fatalf("stderr should contain a single JSON line, got %q", stderr)
+	}
+
+	var payload struct {
+		Status string                 `json:"status"`
+		Error  map[string]interface{} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(stderr), &payload); err != nil {
+		t.Fatalf("stderr is not valid JSON: %v (%q)", err, stderr)
+	}
+	if payload.Status != "error" {
+		t.Errorf("status = %v, want error", payload.Status)
+	}
+	return payload.Error
+}
+
+func TestExecute_JSONErrors(t *testing.T) {
+	apiErr := &client.APIError{
+		StatusCode: http.StatusUnauthorized,
+		Code:       "AUTH_ERROR",
+		Message:    "Invalid API key",
+		Suggestion: "Check your API key",
+	}
+
+	tests := []struct {
+		name           string
+		runErr         error
+		args           []string
+		wantCode       string
+		wantSuggestion bool
+	}{
+		{
+			name:           "unknown flag",
+			args:           []string{"--json-errors", "test-cmd", "--target", "x", "--bogus"},
+			wantCode:       CodeUsage,
+			wantSuggestion: true,
+		},
+		{
+			name:           "missing required flag",
+			args:           []string{"test-cmd", "--json-errors"},
+			wantCode:       CodeUsage,
+			wantSuggestion: true,
+		},
+		{
+			name:           "unknown command",
+			args:           []string{"--format", "json", "no-such-command"},
+			wantCode:       CodeUsage,
+			wantSuggestion: true,
+		},
+		{
+			name:           "wrapped API error",
+			runErr:         fmt.Errorf("request failed after 3 retries: %w", apiErr),
+			args:           []string{"test-cmd", "--target", "x", "--json-errors"},
+			wantCode:       "AUTH_ERROR",
+			wantSuggestion: true,
+		},
+		{
+			name:           "missing API key",
+			runErr:         ErrAPIKeyRequired,
+			args:           []string{"test-cmd", "--target", "x", "--format=json"},
+			wantCode:       CodeAuth,
+			wantSuggestion: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stderr, err := runWithTestCommand(t, tt.runErr, tt.args...)
+			if err == nil {
+				t.Fatal("execute() should return error")
+			}
+
+			errObj := decodeJSONError(t, stderr)
+			if errObj["code"] != tt.wantCode {
+				t.Errorf("code = %v, want %v", errObj["code"], tt.wantCode)
+			}
+			if errObj["message"] == "" {
+				t.Error("message should not be empty")
+			}
+			if _, ok := errObj["suggestion"]; ok != tt.wantSuggestion {
+				t.Errorf("suggestion present = %v, want %v", ok, tt.wantSuggestion)
+			}
+		})
+	}
+}
+
+func TestExecute_PlainTextErrors(t *testing.T) {
+	stderr, err := runWithTestCommand(t, nil, "test-cmd")
+	if err == nil {
+		t.Fatal("execute() should return error")
+	}
+
+	if !strings.HasPrefix(stderr, "Error: required flag(s) \"target\" not set") {
+		t.Errorf("stderr = %q, want plain text error", stderr)
+	}
+	if !strings.Contains(stderr, "Usage:") {
+		t.Error("usage errors should print usage in plain text mode")
+	}
+}
+
+func TestExecute_ErrorDetails(t *testing.T) {
+	apiErr := &client.APIError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "VALIDATION_ERROR",
+		Message:    "Invalid request",
+		Details:    []client.FieldError{{Field: "limit", Message: "must be at most 1000"}},
+	}
+
+	stderr, _ := runWithTestCommand(t, apiErr, "test-cmd", "--target", "x")
+	if !strings.Contains(stderr, "Invalid request (limit: must be at most 1000)") {
+		t.Errorf("stderr = %q, want the details in the plain text error", stderr)
+	}
+
+	stderr, _ = runWithTestCommand(t, apiErr, "test-cmd", "--target", "x", "--json-errors")
+	errObj := decodeJSONError(t, stderr)
+	details, _ := errObj["details"].([]interface{})
+	if len(details) != 1 || fmt.Sprint(details[0]) != "map[field:limit message:must be at most 1000]" {
+		t.Errorf("details = %v, want the limit field error", errObj["details"])
+	}
+}
+
+func TestExecute_ErrorRawDetail(t *testing.T) {
+	apiErr := &client.APIError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "VALIDATION_ERROR",
+		Message:    "Invalid where",
+		RawDetail:  "{\n  \"error\": \"unexpected token at column 14\"\n}",
+	}
+
+	stderr, _ := runWithTestCommand(t, apiErr, "test-cmd", "--target", "x")
+	if strings.Contains(stderr, "Response body") {
+		t.Errorf("stderr = %q, want the body left out without --verbose", stderr)
+	}
+	stderr, _ = runWithTestCommand(t, apiErr, "test-cmd", "--target", "x", "--verbose")
+	if !strings.Contains(stderr, "Response body:\n"+apiErr.RawDetail+"\n") {
+		t.Errorf("stderr = %q, want the body with --verbose", stderr)
+	}
+
+	stderr, _ = runWithTestCommand(t, apiErr, "test-cmd", "--target", "x", "--json-errors")
+	errObj := decodeJSONError(t, stderr)
+	if errObj["raw_detail_summary"] != `{ "error": "unexpected token at column 14" }` || errObj["raw_detail"] != nil {
+		t.Errorf("error = %v, want the body summarized", errObj)
+	}
+	stderr, _ = runWithTestCommand(t, apiErr, "test-cmd", "--target", "x", "--json-errors", "--verbose")
+	errObj = decodeJSONError(t, stderr)
+	if errObj["raw_detail"] != apiErr.RawDetail {
+		t.Errorf("error = %v, want the body in full with --verbose", errObj)
+	}
+}
+
+func TestExecute_Panic(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	panicCmd := &cobra.Command{
+		Use: "panic-cmd",
+		Run: func(*cobra.Command, []string) {
+			panic("boom")
+		},
+	}
+	rootCmd.AddCommand(panicCmd)
+	defer rootCmd.RemoveCommand(panicCmd)
+	defer resetFlags(rootCmd)
+
+	var stderr bytes.Buffer
+	rootCmd.SetErr(&stderr)
+	defer rootCmd.SetErr(nil)
+
+	if err := execute(context.Background(), []string{"panic-cmd", "--json-errors"}); err == nil {
+		t.Fatal("execute() should return error on panic")
+	}
+
+	errObj := decodeJSONError(t, stderr.String())
+	if errObj["code"] != CodePanic {
+		t.Errorf("code = %v, want %v", errObj["code"], CodePanic)
+	}
+
+	suggestion, _ := errObj["suggestion"].(string)
+	_, path, ok := strings.Cut(suggestion, "crash log ")
+	if !ok {
+		t.Fatalf("suggestion = %q, want the crash log path", suggestion)
+	}
+	log, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading crash log: %v", err)
+	}
+	if !strings.Contains(string(log), "panic: boom") || !strings.Contains(string(log), "goroutine") {
+		t.Errorf("crash log = %s, want the panic value and stack", log)
+	}
+}
+
+func TestExecute_DebugPanic(t *testing.T) {
+	panicCmd := &cobra.Command{
+		Use: "panic-cmd",
+		Run: func(*cobra.Command, []string) {
+			panic("boom")
+		},
+	}
+	rootCmd.AddCommand(panicCmd)
+	defer rootCmd.RemoveCommand(panicCmd)
+	defer resetFlags(rootCmd)
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered %v, want the original panic", r)
+		}
+	}()
+	execute(context.Background(), []string{"panic-cmd", "--debug-panic"})
+	t.Error("execute() should re-raise the panic with --debug-panic")
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("failed"), ExitError},
+		{NewError(CodeUsage, "bad flag", ""), ExitError},
+		{fmt.Errorf("wrapped: %w", NewError(CodePanic, "unexpected panic", "")), ExitPanic},
+		{NewError(CodePartial, "1 of 2 targets failed", ""), ExitPartial},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{"site-explorer", "--api-key", "secret", "--notify-header=X-Token: t", "-t", "example.com"}
+	want := "site-explorer --api-key *** --notify-header=*** -t example.com"
+	if got := strings.Join(redactArgs(args), " "); got != want {
+		t.Errorf("redactArgs() = %q, want %q", got, want)
+	}
+	if args[2] != "secret" {
+		t.Error("redactArgs() modified its argument")
+	}
+}
+
+func TestExecute_Context(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "caller")
+
+	var got context.Context
+	ctxCmd := &cobra.Command{
+		Use: "ctx-cmd",
+		RunE: func(c *cobra.Command, args []string) error {
+			got = c.Context()
+			return nil
+		},
+	}
+	rootCmd.AddCommand(ctxCmd)
+	defer rootCmd.RemoveCommand(ctxCmd)
+	defer resetFlags(rootCmd)
+
+	if err := execute(ctx, []string{"ctx-cmd"}); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+	if got == nil || got.Value(key{}) != "caller" {
+		t.Error("command context was not derived from the context passed to execute()")
+	}
+}
+
+func TestSetAllowedListValues_Completion(t *testing.T) {
+	c := &cobra.Command{Use: "test"}
+	c.Flags().StringSlice("features", nil, "")
+	SetAllowedListValues(c, "features", "video", "sitelinks")
+
+	complete, ok := c.GetFlagCompletionFunc("features")
+	if !ok {
+		t.Fatal("no completion registered for --features")
+	}
+	got, _ := complete(c, nil, "local_pack,vi")
+	want := []string{"local_pack,video", "local_pack,sitelinks"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("completions = %v, want %v", got, want)
+	}
+	if values := c.Flags().Lookup("features").Annotations[allowedValuesAnnotation]; len(values) != 2 {
+		t.Errorf("allowed values = %v, want both listed", values)
+	}
+}
+
+func TestSetAllowedValues_Restricts(t *testing.T) {
+	c := &cobra.Command{Use: "test"}
+	c.Flags().String("kind", "", "")
+	c.Flags().String("size", "small", "")
+	c.Flags().StringSlice("tags", nil, "")
+	SetAllowedValues(c, "kind", "a", "b")
+	SetAllowedValues(c, "size", "small", "large")
+	SetAllowedValues(c, "tags", "x", "y")
+
+	tests := []struct {
+		flag, value string
+		wantErr     bool
+	}{
+		{"kind", "a", false},
+		{"kind", "c", true},
+		{"kind", "", false},
+		{"size", "large", false},
+		{"size", "", true},
+		{"tags", "z", false},
+	}
+	for _, tt := range tests {
+		if err := c.Flags().Set(tt.flag, tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("--%s=%q error = %v, wantErr %v", tt.flag, tt.value, err, tt.wantErr)
+		}
+	}
+
+	info := BuildCommandInfo(c)
+	for _, f := range info.Flags {
+		if len(f.AllowedValues) != 2 || f.Type == "" {
+			t.Errorf("--%s: type %q, allowed values %v, want its type and both values", f.Name, f.Type, f.AllowedValues)
+		}
+	}
+}
+
+// commandTree returns a root with subcommands added out of order, one of
+// them hidden
+func commandTree() *cobra.Command {
+	root := &cobra.Command{Use: "ahrefs"}
+	run := func(*cobra.Command, []string) error { return nil }
+	group := &cobra.Command{Use: "site-explorer", Short: "Site Explorer"}
+	refdomains := &cobra.Command{Use: "refdomains", Short: "Referring domains", RunE: run,
+		Annotations: map[string]string{EndpointAnnotation: "/site-explorer/refdomains"}}
+	refdomains.Flags().Int("limit", 100, "Rows")
+	group.AddCommand(
+		refdomains,
+		&cobra.Command{Use: "backlinks", Short: "Backlinks", RunE: run,
+			Annotations: map[string]string{EndpointAnnotation: "/site-explorer/backlinks"}},
+		&cobra.Command{Use: "internal", Hidden: true, RunE: run},
+	)
+	root.AddCommand(&cobra.Command{Use: "usage", Short: "Units used", RunE: run}, group)
+	return root
+}
+
+func TestFlatCommandInfo(t *testing.T) {
+	root := commandTree()
+
+	var paths []string
+	for _, e := range FlatCommandInfo(root, "") {
+		paths = append(paths, e.Path)
+	}
+	if want := []string{"site-explorer backlinks", "site-explorer refdomains", "usage"}; fmt.Sprint(paths) != fmt.Sprint(want) {
+		t.Errorf("FlatCommandInfo() paths = %q, want %q", paths, want)
+	}
+
+	entries := FlatCommandInfo(root, "REFDOM")
+	if len(entries) != 1 {
+		t.Fatalf("FlatCommandInfo(REFDOM) = %+v, want refdomains alone", entries)
+	}
+	if e := entries[0]; e.Endpoint != "/site-explorer/refdomains" || len(e.Flags) != 1 || e.Flags[0].Name != "limit" {
+		t.Errorf("FlatCommandInfo(REFDOM) = %+v, want its endpoint and flags", e)
+	}
+
+	if entries := FlatCommandInfo(root, "nothing"); entries == nil || len(entries) != 0 {
+		t.Errorf("FlatCommandInfo(nothing) = %#v, want an empty list", entries)
+	}
+}
+
+func TestBuildCommandInfo_Filter(t *testing.T) {
+	info := buildCommandInfo(commandTree(), "backlinks")
+	if len(info.Subcommands) != 1 || info.Subcommands[0].Name != "site-explorer" {
+		t.Fatalf("subcommands = %+v, want site-explorer alone", info.Subcommands)
+	}
+	group := info.Subcommands[0]
+	if len(group.Subcommands) != 1 || group.Subcommands[0].Endpoint != "/site-explorer/backlinks" {
+		t.Errorf("site-explorer subcommands = %+v, want backlinks with its endpoint", group.Subcommands)
+	}
+
+	// A matching group keeps all its commands
+	info = buildCommandInfo(commandTree(), "site-explorer")
+	if len(info.Subcommands) != 1 || len(info.Subcommands[0].Subcommands) != 2 {
+		t.Errorf("subcommands = %+v, want site-explorer with both its commands", info.Subcommands)
+	}
+}
+
+func TestExecute_ListCommandsFlat(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	testCmd := &cobra.Command{Use: "test-cmd", RunE: func(*cobra.Command, []string) error { return nil },
+		Annotations: map[string]string{EndpointAnnotation: "/test/endpoint"}}
+	rootCmd.AddCommand(testCmd)
+	defer rootCmd.RemoveCommand(testCmd)
+	defer resetFlags(rootCmd)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	defer rootCmd.SetOut(nil)
+
+	if err := execute(context.Background(), []string{"--list-commands=flat", "--filter", "test-cmd"}); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+	var entries []CommandEntry
+	if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+		t.Fatalf("output %s isn't a JSON list: %v", out.String(), err)
+	}
+	if len(entries) != 1 || entries[0].Path != "test-cmd" || entries[0].Endpoint != "/test/endpoint" {
+		t.Errorf("entries = %+v, want test-cmd and its endpoint", entries)
+	}
+
+	// Without a value it is the tree
+	resetFlags(rootCmd)
+	out.Reset()
+	if err := execute(context.Background(), []string{"--list-commands"}); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+	var info CommandInfo
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil || info.Name != rootName {
+		t.Errorf("--list-commands output = %.200s, error = %v, want the tree", out.String(), err)
+	}
+}
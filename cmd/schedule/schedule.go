@@ -0,0 +1,421 @@
+package schedule
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/schedule"
+	"github.com/spf13/cobra"
+)
+
+// executable returns the path of the ahrefs binary crontab lines and runs
+// invoke
+var executable = os.Executable
+
+// Job is a scheduled job, as listed
+type Job struct {
+	Name    string `json:"name"`
+	Cron    string `json:"cron"`
+	Command string `json:"command"`
+	Webhook string `json:"webhook"`
+
+	// The outcome of the last run; LastRun is empty until the first
+	LastRun  string `json:"last_run"`
+	ExitCode int    `json:"exit_code"`
+	Rows     int    `json:"rows"`
+	Units    int    `json:"units"`
+	Crontab  string `json:"crontab"`
+}
+
+// addOptions configures the job added
+type addOptions struct {
+	cron    string
+	webhook string
+	replace bool
+}
+
+// NewScheduleCmd creates the schedule command
+func NewScheduleCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "schedule",
+		Short: "Run commands from cron, without the monitor daemon",
+		Long: `Save commands as named jobs and run them from cron. A job is saved to the
+configuration file with its cron schedule, and schedule add prints the
+crontab line running it with ahrefs schedule run, to add with crontab -e.
+
+A run executes the saved command with --print-exit-summary, and keeps its
+rows, units, and exit code as the job's last run, listed by schedule ls.
+With a webhook, the run's summary is posted to it as with --notify-webhook.
+Units are recorded in the usage ledger as for any command once a budget is
+set (see ahrefs config set-budget).
+
+With --dry-run, schedule add prints the crontab line without saving the job,
+and schedule run prints the job's requests without spending units.`,
+	}
+
+	c.AddCommand(newAddCmd())
+	c.AddCommand(newListCmd())
+	c.AddCommand(newRemoveCmd())
+	c.AddCommand(newRunCmd())
+	return c
+}
+
+func newAddCmd() *cobra.Command {
+	var opts addOptions
+
+	c := &cobra.Command{
+		Use:   "add <name> --cron <expression> -- <command> [flags]",
+		Short: "Save a command as a scheduled job and print its crontab line",
+		Long: `Save the command after -- as the job name, run on the cron schedule of
+--cron, and print the crontab line running it. The command is given as on
+the command line below ahrefs, and its flags are kept as written, so
+relative --output paths are resolved in the directory cron runs in, which
+is usually the home directory.`,
+		Example: `  # Export backlinks every Monday at 7:00
+  ahrefs schedule add weekly-links --cron "0 7 * * 1" -- \
+    site-explorer backlinks --target example.com --all -o s3://bucket/backlinks-{date}.csv
+
+  # Add the line it prints to the crontab in one go
+  (crontab -l; ahrefs schedule add daily-dr --cron @daily -- \
+    site-explorer domain-rating --target example.com -o dr.ndjson --append) | crontab -
+
+  # Post each run's summary to a webhook
+  ahrefs schedule add weekly-links --cron "0 7 * * 1" --webhook https://hooks.example.com/ahrefs --replace -- \
+    site-explorer backlinks --target example.com --all -o backlinks.csv`,
+		Args: func(c *cobra.Command, args []string) error {
+			if c.ArgsLenAtDash() != 1 || len(args) < 2 {
+				return fmt.Errorf("want the job name, then the command after --")
+			}
+			return nil
+		},
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runAdd(cobraCmd, args[0], args[1:], opts)
+		},
+	}
+
+	c.Flags().StringVar(&opts.cron, "cron", "", `Cron schedule, such as "0 7 * * 1" or @daily (required)`)
+	c.Flags().StringVar(&opts.webhook, "webhook", "", "POST the summary of each run to this URL, as with --notify-webhook")
+	c.Flags().BoolVar(&opts.replace, "replace", false, "Replace the job of the same name, if any")
+	c.MarkFlagRequired("cron")
+
+	return c
+}
+
+func runAdd(c *cobra.Command, name string, args []string, opts addOptions) error {
+	flags := cmd.GetGlobalFlags(c.Context())
+
+	if err := schedule.ValidateName(name); err != nil {
+		return cmd.NewError(cmd.CodeUsage, err.Error(), "Name the job like weekly-links")
+	}
+	if err := schedule.ValidateCron(opts.cron); err != nil {
+		return cmd.NewError(cmd.CodeUsage, err.Error(), `Use five fields, such as --cron "0 7 * * 1" for Mondays at 7:00, or a macro such as @daily`)
+	}
+	if err := checkCommand(c.Root(), args); err != nil {
+		return err
+	}
+	if opts.webhook != "" {
+		if u, err := url.Parse(opts.webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --webhook %q", opts.webhook), "Use an http or https URL")
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if _, ok := cfg.Schedules[name]; ok && !opts.replace {
+		return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("job %s already exists", name), "Add --replace to replace it, or remove it with ahrefs schedule rm "+name)
+	}
+	exe, err := executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the ahrefs binary: %w", err)
+	}
+	line := schedule.CrontabLine(opts.cron, exe, name)
+
+	if flags.DryRun {
+		fmt.Fprintln(flags.Stdout, line)
+		return nil
+	}
+	if cfg.Schedules == nil {
+		cfg.Schedules = map[string]config.Schedule{}
+	}
+	cfg.Schedules[name] = config.Schedule{Cron: opts.cron, Args: args, Webhook: opts.webhook}
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	flags.Log().Info(fmt.Sprintf("Saved job %s; add this line to your crontab with crontab -e", name))
+	fmt.Fprintln(flags.Stdout, line)
+	return nil
+}
+
+// checkCommand returns a usage error when args don't start with a command
+// of root that a job can run
+func checkCommand(root *cobra.Command, args []string) error {
+	found, _, err := root.Find(args)
+	if err != nil || found == root || !found.Runnable() {
+		return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("unknown command %q", strings.Join(args, " ")),
+			"Give the command after -- as on the command line below ahrefs, e.g. -- site-explorer backlinks --target example.com")
+	}
+	for p := found; p != nil; p = p.Parent() {
+		if p.Name() == "schedule" && p.Parent() == root {
+			return cmd.NewError(cmd.CodeUsage, "a job can't run the schedule command", "Schedule the command the job runs instead")
+		}
+	}
+	return nil
+}
+
+func newListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List scheduled jobs and their last runs",
+		Example: `  # Jobs, their schedules, and how their last runs went
+  ahrefs schedule ls --format table
+
+  # The crontab lines of every job
+  ahrefs schedule ls | jq -r '.data[].crontab'`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runList(cobraCmd.Context())
+		},
+	}
+}
+
+func runList(ctx context.Context) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	state, err := schedule.LoadState()
+	if err != nil {
+		return err
+	}
+	exe, err := executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the ahrefs binary: %w", err)
+	}
+
+	names := make([]string, 0, len(cfg.Schedules))
+	for name := range cfg.Schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([]Job, 0, len(names))
+	for _, name := range names {
+		s := cfg.Schedules[name]
+		job := Job{
+			Name:    name,
+			Cron:    s.Cron,
+			Command: shellJoin(s.Args),
+			Webhook: s.Webhook,
+			Crontab: schedule.CrontabLine(s.Cron, exe, name),
+		}
+		if run, ok := state[name]; ok {
+			job.LastRun = run.Time.Format(time.RFC3339)
+			job.ExitCode = run.ExitCode
+			job.Rows = run.Rows
+			job.Units = run.UnitsConsumed
+		}
+		rows = append(rows, job)
+	}
+
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteSuccess(rows, nil); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+func newRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm <name>...",
+		Aliases: []string{"remove"},
+		Short:   "Remove scheduled jobs",
+		Long: `Remove jobs from the configuration file, with their last runs. Their
+crontab lines aren't changed: remove them with crontab -e, as runs of a
+removed job fail.`,
+		Example: `  # Stop exporting backlinks weekly
+  ahrefs schedule rm weekly-links`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runRemove(cobraCmd.Context(), args)
+		},
+	}
+}
+
+func runRemove(ctx context.Context, names []string) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	for _, name := range names {
+		if _, ok := cfg.Schedules[name]; !ok {
+			return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("no job named %s", name), "List the jobs with ahrefs schedule ls")
+		}
+	}
+	exe, err := executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the ahrefs binary: %w", err)
+	}
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, schedule.CrontabLine(cfg.Schedules[name].Cron, exe, name))
+		delete(cfg.Schedules, name)
+	}
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	state, err := schedule.LoadState()
+	if err == nil {
+		for _, name := range names {
+			delete(state, name)
+		}
+		err = state.Save()
+	}
+	if err != nil {
+		flags.Log().Warn("last runs not removed", "err", err)
+	}
+
+	flags.Log().Info(fmt.Sprintf("Removed %s; remove the crontab lines with crontab -e:\n%s", strings.Join(names, ", "), strings.Join(lines, "\n")))
+	return nil
+}
+
+func newRunCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "run <name>",
+		Short: "Run a scheduled job, as its crontab line does",
+		Long: `Run the command of a job with --print-exit-summary, and with --notify-webhook
+when the job has a webhook, keeping its rows, units, and exit code as the
+job's last run. The command's output and errors are its own, and the run
+fails when the command does.
+
+With --dry-run the command prints its requests without making them, and
+the job's last run is left as it was.`,
+		Example: `  # Run a job now, as cron would
+  ahrefs schedule run weekly-links
+
+  # Check what it would request
+  ahrefs schedule run weekly-links --dry-run`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runJob(cobraCmd.Context(), cobraCmd.ErrOrStderr(), args[0])
+		},
+	}
+}
+
+func runJob(ctx context.Context, stderr io.Writer, name string) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	job, ok := cfg.Schedules[name]
+	if !ok {
+		return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("no job named %s", name), "List the jobs with ahrefs schedule ls, or add it with ahrefs schedule add")
+	}
+	exe, err := executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the ahrefs binary: %w", err)
+	}
+
+	args := append(slices.Clone(job.Args), "--print-exit-summary")
+	if job.Webhook != "" {
+		args = append(args, "--notify-webhook", job.Webhook)
+	}
+	if flags.DryRun {
+		args = append(args, "--dry-run")
+	}
+	flags.Log().Debug("Running job "+name, "command", shellJoin(args))
+
+	var errs bytes.Buffer
+	c := exec.CommandContext(ctx, exe, args...)
+	c.Stdout = flags.Stdout
+	c.Stderr = io.MultiWriter(stderr, &errs)
+	start := time.Now()
+	runErr := c.Run()
+	if runErr != nil && c.ProcessState == nil {
+		return fmt.Errorf("failed to run job %s: %w", name, runErr)
+	}
+	exitCode := c.ProcessState.ExitCode()
+
+	if !flags.DryRun {
+		run := schedule.Run{Time: start.UTC(), OK: runErr == nil, ExitCode: exitCode, DurationMS: time.Since(start).Milliseconds()}
+		if s, ok := exitSummary(errs.Bytes()); ok {
+			run.Rows, run.UnitsConsumed, run.DurationMS = s.Rows, s.UnitsConsumed, s.DurationMS
+		}
+		state, err := schedule.LoadState()
+		if err == nil {
+			state[name] = run
+			err = state.Save()
+		}
+		if err != nil {
+			flags.Log().Warn("last run not saved", "err", err)
+		}
+	}
+
+	switch {
+	case runErr == nil:
+		return nil
+	case exitCode == cmd.ExitPartial:
+		return &cmd.Error{Code: cmd.CodePartial, Message: fmt.Sprintf("job %s partially failed", name), Err: runErr}
+	}
+	return fmt.Errorf("job %s failed with exit status %d", name, exitCode)
+}
+
+// exitSummary returns the --print-exit-summary line in the stderr of a run,
+// the last line that is one
+func exitSummary(stderr []byte) (cmd.ExitSummary, bool) {
+	var found cmd.ExitSummary
+	ok := false
+	scanner := bufio.NewScanner(bytes.NewReader(stderr))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var s cmd.ExitSummary
+		line := scanner.Bytes()
+		if bytes.HasPrefix(line, []byte("{")) && json.Unmarshal(line, &s) == nil && s.Command != "" {
+			found, ok = s, true
+		}
+	}
+	return found, ok
+}
+
+// shellJoin returns args as a shell command line, quoting those that need it
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789/._-+=:,@") == "" {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
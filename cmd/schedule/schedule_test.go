@@ -0,0 +1,239 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/schedule"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	// A command for jobs to run
+	cmd.AddCommands(&cobra.Command{Use: "export", RunE: func(*cobra.Command, []string) error { return nil }})
+}
+
+// execSchedule runs the schedule command with args, and returns its output
+func execSchedule(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	c := NewScheduleCmd()
+	cmd.AddCommands(c)
+	sub, rest, err := c.Find(args)
+	if err != nil {
+		t.Fatalf("Find(%v) error = %v", args, err)
+	}
+	c.Root().PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if list, ok := f.Value.(pflag.SliceValue); ok {
+			_ = list.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	var buf bytes.Buffer
+	sub.SetOut(&buf)
+	sub.SetErr(&bytes.Buffer{})
+	sub.SetContext(context.Background())
+	if err := cmd.Prepare(sub, rest); err != nil {
+		return "", err
+	}
+	err = sub.RunE(sub, sub.Flags().Args())
+	return buf.String(), err
+}
+
+// fakeBinary stands in for the ahrefs binary: it writes its arguments to
+// args in dir, and an exit summary to stderr, and exits with status exit
+func fakeBinary(t *testing.T, exit string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ahrefs")
+	script := "#!/bin/sh\n" +
+		"printf '%s\\n' \"$@\" > " + filepath.Join(dir, "args") + "\n" +
+		"echo 'Writing rows' >&2\n" +
+		`echo '{"command":"ahrefs export","ok":true,"rows":42,"units_consumed":210,"requests":3,"duration_ms":1500,"exit_code":0}' >&2` + "\n" +
+		"exit " + exit + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := executable
+	executable = func() (string, error) { return path, nil }
+	t.Cleanup(func() { executable = old })
+	return dir
+}
+
+func TestAdd(t *testing.T) {
+	fakeBinary(t, "0")
+
+	out, err := execSchedule(t, "add", "weekly", "--cron", "0 7 * * 1", "--", "export", "--target", "example.com", "-o", "links.csv")
+	if err != nil {
+		t.Fatalf("add error = %v", err)
+	}
+	if !strings.HasPrefix(out, "0 7 * * 1 ") || !strings.HasSuffix(out, "/ahrefs schedule run weekly\n") {
+		t.Errorf("add output = %q, want the crontab line", out)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config.Schedule{Cron: "0 7 * * 1", Args: []string{"export", "--target", "example.com", "-o", "links.csv"}}
+	if !reflect.DeepEqual(cfg.Schedules["weekly"], want) {
+		t.Errorf("saved job = %+v, want %+v", cfg.Schedules["weekly"], want)
+	}
+
+	if _, err := execSchedule(t, "add", "weekly", "--cron", "@daily", "--", "export"); err == nil {
+		t.Error("adding a job of the same name should fail without --replace")
+	}
+	if _, err := execSchedule(t, "add", "weekly", "--cron", "@daily", "--replace", "--", "export"); err != nil {
+		t.Errorf("add --replace error = %v", err)
+	}
+}
+
+func TestAdd_DryRun(t *testing.T) {
+	fakeBinary(t, "0")
+
+	out, err := execSchedule(t, "add", "weekly", "--cron", "@daily", "--dry-run", "--", "export")
+	if err != nil || !strings.HasSuffix(out, "schedule run weekly\n") {
+		t.Fatalf("add --dry-run = %q, %v, want the crontab line", out, err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Schedules) != 0 {
+		t.Errorf("add --dry-run saved %+v", cfg.Schedules)
+	}
+}
+
+func TestAdd_Invalid(t *testing.T) {
+	fakeBinary(t, "0")
+
+	for _, args := range [][]string{
+		{"add", "weekly", "--cron", "0 7 * *", "--", "export"},
+		{"add", "two words", "--cron", "@daily", "--", "export"},
+		{"add", "weekly", "--cron", "@daily", "--", "unknown-command"},
+		{"add", "weekly", "--cron", "@daily", "--", "schedule", "ls"},
+		{"add", "weekly", "--cron", "@daily", "--webhook", "hooks.example.com", "--", "export"},
+	} {
+		_, err := execSchedule(t, args...)
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+			t.Errorf("%v: error = %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := fakeBinary(t, "0")
+	if _, err := execSchedule(t, "add", "weekly", "--cron", "@daily", "--webhook", "https://hooks.example.com/x", "--", "export", "--target", "example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := execSchedule(t, "run", "weekly"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "export\n--target\nexample.com\n--print-exit-summary\n--notify-webhook\nhttps://hooks.example.com/x\n"; string(args) != want {
+		t.Errorf("command run with %q, want %q", args, want)
+	}
+
+	state, err := schedule.LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := state["weekly"]
+	if !run.OK || run.Rows != 42 || run.UnitsConsumed != 210 || run.DurationMS != 1500 || run.Time.IsZero() {
+		t.Errorf("last run = %+v, want the exit summary's", run)
+	}
+
+	out, err := execSchedule(t, "ls", "--format", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(out, "\n"); len(lines) != 3 || !strings.Contains(lines[1], ",0,42,210,") {
+		t.Errorf("ls output = %q, want the job with its last run", out)
+	}
+}
+
+func TestRun_DryRun(t *testing.T) {
+	dir := fakeBinary(t, "0")
+	if _, err := execSchedule(t, "add", "weekly", "--cron", "@daily", "--", "export"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := execSchedule(t, "run", "weekly", "--dry-run"); err != nil {
+		t.Fatalf("run --dry-run error = %v", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if !strings.HasSuffix(string(args), "--dry-run\n") {
+		t.Errorf("command run with %q, want --dry-run", args)
+	}
+	if state, _ := schedule.LoadState(); len(state) != 0 {
+		t.Errorf("run --dry-run kept %+v as a last run", state)
+	}
+}
+
+func TestRun_Failure(t *testing.T) {
+	fakeBinary(t, "3")
+	if _, err := execSchedule(t, "add", "weekly", "--cron", "@daily", "--", "export"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := execSchedule(t, "run", "weekly")
+	if cmd.ExitCode(err) != cmd.ExitPartial {
+		t.Errorf("run error = %v, want the command's partial failure", err)
+	}
+	state, _ := schedule.LoadState()
+	if run := state["weekly"]; run.OK || run.ExitCode != 3 {
+		t.Errorf("last run = %+v, want exit code 3", run)
+	}
+
+	_, err = execSchedule(t, "run", "unknown")
+	var coded *cmd.Error
+	if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+		t.Errorf("running an unknown job: error = %v, want a usage error", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	fakeBinary(t, "0")
+	for _, name := range []string{"weekly", "daily"} {
+		if _, err := execSchedule(t, "add", name, "--cron", "@daily", "--", "export"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := execSchedule(t, "run", "weekly"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := execSchedule(t, "rm", "weekly", "missing"); err == nil {
+		t.Error("rm of a missing job should fail")
+	}
+	if _, err := execSchedule(t, "rm", "weekly"); err != nil {
+		t.Fatalf("rm error = %v", err)
+	}
+
+	cfg, _ := config.Load()
+	if _, ok := cfg.Schedules["weekly"]; ok || len(cfg.Schedules) != 1 {
+		t.Errorf("jobs after rm = %+v, want daily alone", cfg.Schedules)
+	}
+	if state, _ := schedule.LoadState(); len(state) != 0 {
+		t.Errorf("last runs after rm = %+v, want none", state)
+	}
+}
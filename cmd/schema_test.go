@@ -0,0 +1,24 @@
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+)
+
+// TestSchema_NeedsNoFlags checks --schema prints the response schema without
+// the command's required flags
+func TestSchema_NeedsNoFlags(t *testing.T) {
+	out, _, err := cmd.Run(t, "site-explorer", "backlinks", "--schema")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if !strings.Contains(out, `"title": "BacklinksResponse"`) {
+		t.Errorf("output = %q, want the backlinks schema", out)
+	}
+
+	if _, _, err := cmd.Run(t, "site-explorer", "backlinks"); err == nil {
+		t.Error("error = nil without --schema, want --target required")
+	}
+}
@@ -0,0 +1,336 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/redact"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// shutdownTimeout bounds how long in-flight requests may finish on exit
+const shutdownTimeout = 10 * time.Second
+
+// queryFlags are the command flags a request may give as query parameters:
+// those sent to the API as parameters or shaping the rows of the response.
+// Each command takes those of them it has. Flags that read files or stdin
+// (--where-file), keep state between runs (--all, --cursor, --resume,
+// --last), look up or log anything on the server's side (--resolve-ips,
+// --explain-filter, --stats), or tune its resources (--concurrency) are left
+// out, so a client can't reach beyond the API through the gateway.
+var queryFlags = map[string]bool{
+	// API parameters
+	"target": true, "url": true, "mode": true, "auto-mode": true, "limit": true, "offset": true,
+	"select": true, "where": true, "order-by": true, "country": true, "lenient": true,
+	"date": true, "date-from": true, "date-to": true, "date-compared": true, "since": true, "until": true,
+	"history": true, "interval": true, "months": true, "link-type": true,
+	"first-seen-since": true, "first-seen-until": true, "exclude-domain": true, "exclude-own": true,
+	"serp-features": true, "exclude-serp-features": true, "by": true, "brand": true, "top": true,
+
+	// Rows of the response
+	"count-only": true, "sample": true, "aggregate": true, "group-by-domain": true,
+	"movement": true, "cluster": true, "clusters-only": true, "compare-url": true,
+	"summary": true, "summary-only": true, "histogram-only": true, "stats-only": true,
+	"expand-domains": true, "expand-limit": true, "detect-language": true,
+	"merge-url-variants": true, "normalize-text": true, "casefold": true,
+}
+
+// GroupFunc constructs a fresh command group, such as site-explorer, whose
+// subcommands are served as routes
+type GroupFunc func() *cobra.Command
+
+// NewServeCmd creates the serve command, exposing the subcommands of groups
+func NewServeCmd(groups ...GroupFunc) *cobra.Command {
+	var addr, token string
+
+	c := &cobra.Command{
+		Use:   "serve",
+		Short: "Expose commands as a local REST API",
+		Long: `Run an HTTP gateway that maps commands to routes, so dashboards and scripts
+can query Ahrefs through one locally-credentialed process instead of
+distributing API keys.
+
+Routes mirror the command tree and take command flags as query parameters:
+
+  GET /site-explorer/backlinks?target=ahrefs.com&limit=10&order-by=domain_rating:desc
+
+Only flags that are API parameters or shape the rows returned are taken;
+others, such as --where-file, --all, --resume, and --last, are a 400.
+
+Responses use the usual output envelope; errors use the JSON error envelope
+with a matching HTTP status. GET /healthz reports liveness without
+authentication. Global flags given to serve (--format, --timeout, --api-key,
+...) apply to every request. Requests are executed concurrently.`,
+		Example: `  # Serve on localhost:8787
+  ahrefs serve
+
+  # Require a bearer token from clients
+  AHREFS_SERVE_TOKEN=s3cret ahrefs serve --http :8787
+
+  # Query it
+  curl -H 'Authorization: Bearer s3cret' \
+    'http://localhost:8787/site-explorer/domain-rating?target=ahrefs.com'`,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			flags := cmd.GetGlobalFlags(cobraCmd.Context())
+			if flags.OutputFile != "" || flags.Confirm {
+				return cmd.NewError(cmd.CodeUsage, "--output and --confirm can't be used with serve",
+					"Remove --output/--confirm (or AHREFS_OUTPUT) when running serve")
+			}
+
+			s := newServer(groups, token, flags.Log())
+			s.flags = flags
+			return s.run(cobraCmd.Context(), addr)
+		},
+	}
+
+	c.Flags().StringVar(&addr, "http", "127.0.0.1:8787", "Address to listen on")
+	c.Flags().StringVar(&token, "token", "", "Bearer token clients must send (default: no authentication)")
+	cmd.BindEnv(c.Flags(), "token", "AHREFS_SERVE_TOKEN")
+
+	return c
+}
+
+// server executes commands for HTTP requests
+type server struct {
+	groups map[string]GroupFunc
+	token  string
+	log    *slog.Logger
+
+	// flags are the global flags commands run with; each request gets its
+	// own output
+	flags cmd.GlobalFlags
+}
+
+func newServer(groups []GroupFunc, token string, log *slog.Logger) *server {
+	s := &server{groups: map[string]GroupFunc{}, token: token, log: log}
+	for _, g := range groups {
+		s.groups[g().Name()] = g
+	}
+	return s
+}
+
+// run serves until ctx is cancelled or the process is interrupted, then
+// shuts down gracefully
+func (s *server) run(ctx context.Context, addr string) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	s.log.Info("Serving on http://" + addr)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.log.Info("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"status":"ok"}`)
+	})
+	mux.Handle("GET /", s.authorize(http.HandlerFunc(s.runCommand)))
+	return s.logRequests(mux)
+}
+
+// authorize rejects requests without the configured bearer token
+func (s *server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, cmd.NewError(cmd.CodeAuth,
+					"missing or invalid bearer token", "Send 'Authorization: Bearer <token>'"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// runCommand executes the command named by the request path with the query
+// parameters as flags
+func (s *server) runCommand(w http.ResponseWriter, r *http.Request) {
+	sub, err := s.lookup(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	args, err := queryArgs(sub, r.URL.Path, r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := cmd.Prepare(sub, args); err != nil {
+		writeError(w, statusFor(err), err, s.flags.Secrets()...)
+		return
+	}
+
+	var buf bytes.Buffer
+	flags := s.flags
+	flags.Stdout = &buf
+	sub.SetContext(cmd.WithGlobalFlags(r.Context(), flags))
+	runErr := sub.RunE(sub, nil)
+
+	status := http.StatusOK
+	if runErr != nil {
+		status = statusFor(runErr)
+		if buf.Len() == 0 {
+			writeError(w, status, runErr, s.flags.Secrets()...)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", output.ContentType(s.flags.OutputFormat))
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// lookup returns a fresh instance of the command at path, e.g.
+// /site-explorer/backlinks
+func (s *server) lookup(path string) (*cobra.Command, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	notFound := cmd.NewError("NOT_FOUND", fmt.Sprintf("no command at %s", path),
+		"Routes are /<group>/<command>; run 'ahrefs --list-commands' to see commands")
+	if len(parts) != 2 {
+		return nil, notFound
+	}
+
+	newGroup, ok := s.groups[parts[0]]
+	if !ok {
+		return nil, notFound
+	}
+	for _, sub := range newGroup().Commands() {
+		if sub.Name() == parts[1] && sub.RunE != nil {
+			return sub, nil
+		}
+	}
+	return nil, notFound
+}
+
+// queryArgs converts query parameters to command-line flags of c, the
+// command at path. Parameters that aren't among c's queryFlags are a usage
+// error.
+func queryArgs(c *cobra.Command, path string, query map[string][]string) ([]string, error) {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, k := range keys {
+		if !queryFlags[k] || c.Flags().Lookup(k) == nil {
+			return nil, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("%s doesn't take the query parameter %q", path, k),
+				"Parameters it takes: "+strings.Join(queryParams(c), ", "))
+		}
+		for _, v := range query[k] {
+			args = append(args, fmt.Sprintf("--%s=%s", k, v))
+		}
+	}
+	return args, nil
+}
+
+// queryParams returns the names of the queryFlags c has, in order
+func queryParams(c *cobra.Command) []string {
+	var names []string
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if queryFlags[f.Name] {
+			names = append(names, f.Name)
+		}
+	})
+	return names
+}
+
+// statusFor maps a command error to an HTTP status
+func statusFor(err error) int {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 {
+		return apiErr.StatusCode
+	}
+
+	var coded *cmd.Error
+	if errors.As(err, &coded) {
+		switch coded.Code {
+		case cmd.CodeUsage:
+			return http.StatusBadRequest
+		case cmd.CodeBudget:
+			return http.StatusTooManyRequests
+		case cmd.CodeAuth, cmd.CodeConfig:
+			// The gateway's own credentials or config are at fault
+			return http.StatusInternalServerError
+		}
+	}
+	return http.StatusBadGateway
+}
+
+// writeError writes err in the JSON error envelope, with secrets masked
+func writeError(w http.ResponseWriter, status int, err error, secrets ...string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(redact.Writer(w, secrets...)).Encode(map[string]interface{}{
+		"status": "error",
+		"error":  output.FormatError(err),
+	})
+}
+
+// logRequests logs each request with its status and duration
+func (s *server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		s.log.Info(r.Method+" "+r.URL.RequestURI(), "remote", r.RemoteAddr, "status", rec.status, "duration", time.Since(start))
+	})
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,228 @@
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/cmd/siteexplorer"
+	"github.com/aminemat/ahrefs-cli/internal/logging"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// newTestGroup builds a group whose greet command echoes --target and whose
+// fail command returns an API error
+func newTestGroup() *cobra.Command {
+	group := &cobra.Command{Use: "test-group"}
+
+	var name string
+	greet := &cobra.Command{
+		Use: "greet",
+		RunE: func(c *cobra.Command, _ []string) error {
+			fmt.Fprintf(cmd.GetGlobalFlags(c.Context()).Stdout, `{"hello":%q}`, name)
+			return nil
+		},
+	}
+	greet.Flags().StringVar(&name, "target", "", "Target")
+	greet.Flags().String("where-file", "", "Filter file")
+	greet.MarkFlagRequired("target")
+
+	fail := &cobra.Command{
+		Use: "fail",
+		RunE: func(*cobra.Command, []string) error {
+			return &client.APIError{StatusCode: http.StatusTooManyRequests, Message: "slow down"}
+		},
+	}
+
+	group.AddCommand(greet, fail)
+	return group
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		auth       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "healthz", path: "/healthz", token: "secret", wantStatus: 200, wantBody: `"ok"`},
+		{name: "command", path: "/test-group/greet?target=ada", wantStatus: 200, wantBody: `{"hello":"ada"}`},
+		{name: "flags are per request", path: "/test-group/greet", wantStatus: 400, wantBody: "USAGE_ERROR"},
+		{name: "unknown flag", path: "/test-group/greet?target=ada&bogus=1", wantStatus: 400, wantBody: `doesn't take the query parameter \"bogus\"`},
+		{name: "flag not served", path: "/test-group/greet?target=ada&where-file=/etc/passwd", wantStatus: 400, wantBody: "Parameters it takes: target"},
+		{name: "unknown route", path: "/test-group/nope", wantStatus: 404, wantBody: "NOT_FOUND"},
+		{name: "API error status", path: "/test-group/fail", wantStatus: 429, wantBody: "slow down"},
+		{name: "missing token", path: "/test-group/greet?target=ada", token: "secret", wantStatus: 401, wantBody: "AUTH_ERROR"},
+		{name: "wrong token", path: "/test-group/greet?target=ada", token: "secret", auth: "Bearer nope", wantStatus: 401},
+		{name: "valid token", path: "/test-group/greet?target=ada", token: "secret", auth: "Bearer secret", wantStatus: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer([]GroupFunc{newTestGroup}, tt.token, logging.Discard())
+			srv := httptest.NewServer(s.handler())
+			defer srv.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", resp.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+// TestServer_Concurrent runs two site-explorer commands at once against a
+// mock API that answers only once both are in flight. Run with -race to check
+// that commands share no flag or output state.
+func TestServer_Concurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	ratings := map[string]int{"a.example": 11, "b.example": 22}
+	var inFlight atomic.Int32
+	both := make(chan struct{})
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if inFlight.Add(1) == 2 {
+			close(both)
+		}
+		select {
+		case <-both:
+		case <-time.After(5 * time.Second):
+			t.Error("requests were not served concurrently")
+		}
+		fmt.Fprintf(w, `{"domain_rating":{"domain_rating":%d}}`, ratings[r.URL.Query().Get("target")])
+	}))
+	defer api.Close()
+
+	s := newServer([]GroupFunc{siteexplorer.NewSiteExplorerCmd}, "", logging.Discard())
+	s.flags = cmd.GlobalFlags{APIKey: "test-key", BaseURL: api.URL, OutputFormat: "json"}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	var wg sync.WaitGroup
+	for target, rating := range ratings {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(srv.URL + "/site-explorer/domain-rating?target=" + target)
+			if err != nil {
+				t.Errorf("%s: request failed: %v", target, err)
+				return
+			}
+			defer resp.Body.Close()
+
+			var body struct {
+				Data struct {
+					DomainRating struct {
+						DomainRating int `json:"domain_rating"`
+					} `json:"domain_rating"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Errorf("%s: invalid response: %v", target, err)
+				return
+			}
+			if resp.StatusCode != http.StatusOK || body.Data.DomainRating.DomainRating != rating {
+				t.Errorf("%s: status = %d, domain_rating = %d, want 200, %d", target, resp.StatusCode, body.Data.DomainRating.DomainRating, rating)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestServer_QueryFlags checks that site-explorer flags reading files or
+// keeping state are refused before the command is prepared or run
+func TestServer_QueryFlags(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.RawQuery, "root") {
+			t.Errorf("file contents sent upstream: %s", r.URL)
+		}
+		fmt.Fprint(w, `{"backlinks":[]}`)
+	}))
+	defer api.Close()
+
+	filter := t.TempDir() + "/filter.txt"
+	if err := os.WriteFile(filter, []byte("root:x:0:0"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newServer([]GroupFunc{siteexplorer.NewSiteExplorerCmd}, "", logging.Discard())
+	s.flags = cmd.GlobalFlags{APIKey: "test-key", BaseURL: api.URL, OutputFormat: "json"}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	tests := []struct {
+		query      string
+		wantStatus int
+	}{
+		{"target=ahrefs.com&where-file=" + filter + "&explain-filter=true", http.StatusBadRequest},
+		{"target=ahrefs.com&where-file=-", http.StatusBadRequest},
+		{"target=ahrefs.com&resume=true", http.StatusBadRequest},
+		{"target=ahrefs.com&all=true", http.StatusBadRequest},
+		{"target=ahrefs.com&last=true", http.StatusBadRequest},
+		{"target=ahrefs.com&cursor=abc", http.StatusBadRequest},
+		{"target=ahrefs.com&limit=10&where=" + url.QueryEscape(`{"field":"is_dofollow","is":["eq",1]}`), http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(srv.URL + "/site-explorer/backlinks?" + tt.query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d (body %s)", tt.query, resp.StatusCode, tt.wantStatus, body)
+		}
+	}
+}
+
+func TestStatusFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "usage", err: cmd.NewError(cmd.CodeUsage, "bad", ""), want: 400},
+		{name: "budget", err: cmd.NewError(cmd.CodeBudget, "over", ""), want: 429},
+		{name: "missing key", err: cmd.ErrAPIKeyRequired, want: 500},
+		{name: "upstream", err: fmt.Errorf("wrapped: %w", &client.APIError{StatusCode: 404}), want: 404},
+		{name: "non-JSON success", err: &client.APIError{StatusCode: 200, Code: client.CodeGateway}, want: 502},
+		{name: "other", err: errors.New("connection refused"), want: 502},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusFor(tt.err); got != tt.want {
+				t.Errorf("statusFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
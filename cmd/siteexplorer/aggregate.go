@@ -0,0 +1,68 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aminemat/ahrefs-cli/internal/publicsuffix"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// aggregateRefDomains returns a transform replacing the referring domains in
+// a response body with one row per TLD or registrable domain, as by says
+func aggregateRefDomains(by string) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		var resp models.RefDomainsResponse
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		data, err := json.Marshal(models.RefDomainGroupsResponse{Groups: aggregateDomains(resp.RefDomains, by)})
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// aggregateDomains rolls domains up by their TLD, or with by
+// models.AggregationRegistrable their registrable domain, most domains first. A domain that is itself a
+// public suffix is its own group.
+func aggregateDomains(domains []models.RefDomain, by string) []models.RefDomainGroup {
+	groups := map[string]*models.RefDomainGroup{}
+	var order []*models.RefDomainGroup
+	for _, d := range domains {
+		key := publicsuffix.TLD(d.Domain)
+		if models.Aggregation(by) == models.AggregationRegistrable {
+			if key = publicsuffix.Registrable(d.Domain); key == "" {
+				key = d.Domain
+			}
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &models.RefDomainGroup{Group: key}
+			groups[key] = g
+			order = append(order, g)
+		}
+
+		g.Domains++
+		g.Backlinks += d.Backlinks
+		g.MaxDomainRating = max(g.MaxDomainRating, d.DomainRating)
+	}
+
+	rows := make([]models.RefDomainGroup, 0, len(order))
+	for _, g := range order {
+		rows = append(rows, *g)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Domains != rows[j].Domains {
+			return rows[i].Domains > rows[j].Domains
+		}
+		return rows[i].Backlinks > rows[j].Backlinks
+	})
+	return rows
+}
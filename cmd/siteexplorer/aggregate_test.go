@@ -0,0 +1,75 @@
+package siteexplorer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestAggregateDomains(t *testing.T) {
+	domains := []models.RefDomain{
+		{Domain: "cs.stanford.edu", DomainRating: 80, Backlinks: 4},
+		{Domain: "mit.edu", DomainRating: 90, Backlinks: 1},
+		{Domain: "blog.example.co.uk", DomainRating: 30, Backlinks: 7},
+		{Domain: "shop.example.co.uk", DomainRating: 35, Backlinks: 2},
+		{Domain: "www.stanford.edu", DomainRating: 85, Backlinks: 3},
+		{Domain: "co.uk", Backlinks: 1},
+	}
+
+	tests := []struct {
+		by   string
+		want []models.RefDomainGroup
+	}{
+		{
+			by: "tld",
+			want: []models.RefDomainGroup{
+				{Group: "uk", Domains: 3, Backlinks: 10, MaxDomainRating: 35},
+				{Group: "edu", Domains: 3, Backlinks: 8, MaxDomainRating: 90},
+			},
+		},
+		{
+			by: "registrable",
+			want: []models.RefDomainGroup{
+				{Group: "example.co.uk", Domains: 2, Backlinks: 9, MaxDomainRating: 35},
+				{Group: "stanford.edu", Domains: 2, Backlinks: 7, MaxDomainRating: 85},
+				{Group: "mit.edu", Domains: 1, Backlinks: 1, MaxDomainRating: 90},
+				{Group: "co.uk", Domains: 1, Backlinks: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			if got := aggregateDomains(domains, tt.by); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("aggregateDomains() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregate_AllPages(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// Three referring domains, two per page
+	domains := []string{"a.edu", "b.gov", "c.edu"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		var rows []string
+		for i := offset; i < len(domains) && i < offset+2; i++ {
+			rows = append(rows, fmt.Sprintf(`{"domain":%q,"domain_rating":%d,"backlinks":%d}`, domains[i], 10*(i+1), i+1))
+		}
+		fmt.Fprintf(w, `{"refdomains":[%s]}`, strings.Join(rows, ","))
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"refdomains", "-t", "t.com", "--all", "--limit", "2", "--aggregate", "tld", "--format", "csv"})
+	want := "group,domains,backlinks,max_domain_rating\nedu,2,4,30\ngov,1,2,20\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
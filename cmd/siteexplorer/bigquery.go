@@ -0,0 +1,96 @@
+package siteexplorer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/bigquery"
+)
+
+// bigQueryExport loads a response into the table named by a bq:// --output
+type bigQueryExport struct {
+	table  bigquery.Table
+	schema []bigquery.Field
+	key    string // list field of the response; "" for single objects
+
+	client *bigquery.Client
+}
+
+// newBigQueryExport derives the table schema from the response model of
+// result, narrowed to columns when --select is used
+func newBigQueryExport(rawURL string, result interface{}, columns []string) (*bigQueryExport, error) {
+	table, err := bigquery.ParseURL(rawURL)
+	if err != nil {
+		return nil, cmd.NewError(cmd.CodeUsage, err.Error(), "Use --output bq://<project>/<dataset>/<table>")
+	}
+
+	rowType, key := bigquery.RowType(reflect.TypeOf(result))
+	schema, err := bigquery.SchemaOf(rowType)
+	if err != nil {
+		return nil, err
+	}
+	if columns != nil {
+		if schema, err = bigquery.Select(schema, columns); err != nil {
+			return nil, cmd.NewError(cmd.CodeUsage, err.Error(), "Only --select columns listed by --list-fields can be loaded into BigQuery")
+		}
+	}
+
+	return &bigQueryExport{table: table, schema: schema, key: key}, nil
+}
+
+// connect resolves credentials, so a broken setup fails before units are
+// spent
+func (e *bigQueryExport) connect(ctx context.Context) error {
+	client, err := bigquery.NewClient(ctx)
+	if err != nil {
+		return cmd.NewError(cmd.CodeConfig, err.Error(), "Check your Google Cloud credentials")
+	}
+	e.client = client
+	return nil
+}
+
+// load creates the table if needed and streams the response rows into it
+func (e *bigQueryExport) load(ctx context.Context, body []byte, fetchedAt time.Time, log *slog.Logger) error {
+	rows, err := bigquery.Rows(body, e.key, fetchedAt)
+	if err != nil {
+		return err
+	}
+
+	created, err := e.client.EnsureTable(ctx, e.table, e.schema)
+	if err != nil {
+		return err
+	}
+	if created {
+		log.Info(fmt.Sprintf("Created table %s", e.table))
+	}
+
+	if err := e.client.Insert(ctx, e.table, rows, strconv.FormatInt(fetchedAt.UnixNano(), 36)); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Loaded %d row(s) into %s", len(rows), e.table))
+	return nil
+}
+
+// printSchema describes the destination table for --dry-run
+func (e *bigQueryExport) printSchema(w io.Writer) {
+	fmt.Fprintf(w, "  BigQuery table: %s (created if missing, partitioned by %s)\n", e.table, bigquery.PartitionField)
+	fmt.Fprintln(w, "  Schema:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	var printFields func(fields []bigquery.Field, indent string)
+	printFields = func(fields []bigquery.Field, indent string) {
+		for _, f := range fields {
+			fmt.Fprintf(tw, "    %s%s\t%s\t%s\n", indent, f.Name, f.Type, f.Mode)
+			printFields(f.Fields, indent+"  ")
+		}
+	}
+	printFields(e.schema, "")
+	tw.Flush()
+}
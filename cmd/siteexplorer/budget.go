@@ -0,0 +1,107 @@
+package siteexplorer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/usage"
+)
+
+// loadBudget returns the configured monthly budget, or a disabled budget when
+// the config can't be read
+func loadBudget() config.Budget {
+	cfg, err := config.Load()
+	if err != nil {
+		return config.Budget{}
+	}
+	return cfg.Budget
+}
+
+// checkBudgetBefore refuses to run a request when --enforce-budget is set and
+// the budget is already exhausted
+func checkBudgetBefore(budget config.Budget, flags cmd.GlobalFlags) error {
+	if !budget.Enabled() || !flags.EnforceBudget {
+		return nil
+	}
+
+	now := time.Now()
+	used, err := usage.MonthToDate(now)
+	if err != nil {
+		return err
+	}
+
+	if s := usage.Check(now, used, budget); s.Status == usage.StatusExceeded {
+		return budgetError(s)
+	}
+	return nil
+}
+
+// spendGuard stops the follow-up requests a command makes once the units it
+// has spent exhaust the budget, when --enforce-budget is set
+type spendGuard struct {
+	budget config.Budget
+	used   int // month-to-date units before the command
+}
+
+// newSpendGuard returns the guard for budget, or nil when it isn't enforced
+func newSpendGuard(budget config.Budget, flags cmd.GlobalFlags) *spendGuard {
+	if !budget.Enabled() || !flags.EnforceBudget {
+		return nil
+	}
+	used, err := usage.MonthToDate(time.Now())
+	if err != nil {
+		// checkBudgetBefore has already reported an unreadable ledger
+		return nil
+	}
+	return &spendGuard{budget: budget, used: used}
+}
+
+// check refuses another request once the spent units leave none of the
+// budget for it
+func (g *spendGuard) check(spent int) error {
+	if g == nil || g.used+spent < g.budget.MonthlyUnits {
+		return nil
+	}
+	return budgetError(usage.Check(time.Now(), g.used+spent, g.budget))
+}
+
+// trackUsage records units consumed by a request in the usage ledger and
+// logs the month-to-date standing. Exceeding the budget is only
+// an error with --enforce-budget.
+func trackUsage(budget config.Budget, flags cmd.GlobalFlags, endpoint string, units int) error {
+	if !budget.Enabled() {
+		return nil
+	}
+
+	now := time.Now()
+	if err := usage.Record(usage.Entry{Time: now, Endpoint: endpoint, Units: units}); err != nil {
+		flags.Log().Warn("failed to record usage", "err", err)
+		return nil
+	}
+
+	used, err := usage.MonthToDate(now)
+	if err != nil {
+		flags.Log().Warn("failed to read usage", "err", err)
+		return nil
+	}
+
+	s := usage.Check(now, used, budget)
+	switch s.Status {
+	case usage.StatusWarn:
+		flags.Log().Warn(fmt.Sprintf("%d of %d monthly units used (%.0f%%)", s.UnitsUsed, s.MonthlyUnits, s.PercentUsed))
+	case usage.StatusExceeded:
+		if flags.EnforceBudget {
+			return budgetError(s)
+		}
+		flags.Log().Error(fmt.Sprintf("monthly budget exceeded: %d of %d units used", s.UnitsUsed, s.MonthlyUnits))
+	}
+	return nil
+}
+
+func budgetError(s usage.Standing) error {
+	return cmd.NewError(cmd.CodeBudget,
+		fmt.Sprintf("monthly budget exceeded: %d of %d units used in %s", s.UnitsUsed, s.MonthlyUnits, s.Month),
+		"Run 'ahrefs usage --budget' to review, or raise budget.monthly_units with 'ahrefs config set-budget'")
+}
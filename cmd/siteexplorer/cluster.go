@@ -0,0 +1,285 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// clusterings are the accepted values for the --cluster flag
+var clusterings = []string{"url", "tokens"}
+
+// clusterColumns are the columns --cluster adds
+var clusterColumns = []string{"cluster_id", "cluster_label"}
+
+// clusterFields are the columns keywords are clustered by, added to --select
+// when it leaves them out
+var clusterFields = []string{"keyword", "url", "position", "volume"}
+
+// clusterConflicts are the flags replacing the rows --cluster and
+// --clusters-only group
+var clusterConflicts = []string{"movement"}
+
+// tokenOverlap is the share of their words two keywords must have in common
+// to be clustered by tokens
+const tokenOverlap = 0.5
+
+// stopWords are left out of the words keywords are compared by
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "at": true, "by": true, "for": true,
+	"from": true, "how": true, "in": true, "is": true, "of": true, "on": true, "or": true,
+	"the": true, "to": true, "vs": true, "what": true, "with": true,
+}
+
+// clusterKeywords returns a transform clustering the organic keywords of a
+// response body, as by says, after applying prev when it's set. The rows get
+// their cluster_id and cluster_label, or with only are replaced by a row per
+// cluster.
+func clusterKeywords(by string, only bool, prev transform) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		if prev != nil {
+			var err error
+			if body, err = prev(ctx, body, fetch); err != nil {
+				return nil, err
+			}
+		}
+
+		var resp interface{}
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		obj, _ := resp.(map[string]interface{})
+		rows, _ := obj["keywords"].([]interface{})
+
+		keywords := make([]models.OrganicKeyword, 0, len(rows))
+		for _, row := range rows {
+			fields, _ := row.(map[string]interface{})
+			keywords = append(keywords, models.OrganicKeyword{
+				Keyword:      stringField(fields, "keyword"),
+				URL:          stringField(fields, "url"),
+				Position:     intField(fields, "position"),
+				SearchVolume: intField(fields, "volume"),
+				Traffic:      intField(fields, "traffic"),
+			})
+		}
+		clusters, ids := clusterRows(keywords, by)
+
+		var out interface{} = models.KeywordClustersResponse{Clusters: clusters}
+		if !only {
+			for i, row := range rows {
+				if fields, ok := row.(map[string]interface{}); ok {
+					c := clusters[ids[keywords[i].Keyword]-1]
+					fields["cluster_id"], fields["cluster_label"] = c.ClusterID, c.ClusterLabel
+				}
+			}
+			out = resp
+		}
+
+		data, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// stringField returns the string value of a generically decoded row's field
+func stringField(fields map[string]interface{}, name string) string {
+	s, _ := fields[name].(string)
+	return s
+}
+
+// intField returns the whole number value of a generically decoded row's
+// field, or 0
+func intField(fields map[string]interface{}, name string) int {
+	n, ok := fields[name].(json.Number)
+	if !ok {
+		return 0
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0
+	}
+	return int(f)
+}
+
+// clusterRows clusters the keywords of rows: those whose top URL, the one
+// ranking best for them, is the same, and with by "tokens" also those
+// sharing at least half their words. A keyword listed once per URL is
+// clustered once. Clusters are numbered from 1 by their volume, largest
+// first, and labelled with their keyword of the highest volume. The cluster
+// ID of each keyword is returned with them.
+func clusterRows(rows []models.OrganicKeyword, by string) ([]models.KeywordCluster, map[string]int) {
+	// Each keyword's best ranking row, with its traffic over every URL
+	index := map[string]int{}
+	var keywords []models.OrganicKeyword
+	for _, row := range rows {
+		i, ok := index[row.Keyword]
+		if !ok {
+			index[row.Keyword] = len(keywords)
+			keywords = append(keywords, row)
+			continue
+		}
+		k := &keywords[i]
+		traffic := k.Traffic + row.Traffic
+		if ranksAbove(row, *k) {
+			*k = row
+		}
+		k.Traffic = traffic
+		k.SearchVolume = max(k.SearchVolume, row.SearchVolume)
+	}
+
+	parent := make([]int, len(keywords))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		parent[find(i)] = find(j)
+	}
+
+	byURL := map[string]int{}
+	for i, k := range keywords {
+		if k.URL == "" {
+			continue
+		}
+		if j, ok := byURL[k.URL]; ok {
+			union(i, j)
+		} else {
+			byURL[k.URL] = i
+		}
+	}
+	if by == "tokens" {
+		clusterTokens(keywords, union)
+	}
+
+	groups := map[int]*models.KeywordCluster{}
+	var order []*models.KeywordCluster
+	labelVolume := map[*models.KeywordCluster]int{}
+	for i, k := range keywords {
+		root := find(i)
+		c, ok := groups[root]
+		if !ok {
+			c = &models.KeywordCluster{}
+			groups[root] = c
+			order = append(order, c)
+		}
+		if c.Keywords == 0 || k.SearchVolume > labelVolume[c] {
+			c.ClusterLabel, c.URL, labelVolume[c] = k.Keyword, k.URL, k.SearchVolume
+		}
+		c.Keywords++
+		c.Volume += k.SearchVolume
+		c.Traffic += k.Traffic
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].Volume != order[j].Volume {
+			return order[i].Volume > order[j].Volume
+		}
+		return order[i].Keywords > order[j].Keywords
+	})
+	clusters := make([]models.KeywordCluster, 0, len(order))
+	for i, c := range order {
+		c.ClusterID = i + 1
+		clusters = append(clusters, *c)
+	}
+
+	ids := make(map[string]int, len(keywords))
+	for i, k := range keywords {
+		ids[k.Keyword] = groups[find(i)].ClusterID
+	}
+	return clusters, ids
+}
+
+// ranksAbove reports whether a ranks better than b. An unknown position,
+// 0, ranks below every other.
+func ranksAbove(a, b models.OrganicKeyword) bool {
+	switch {
+	case a.Position == 0:
+		return false
+	case b.Position == 0:
+		return true
+	}
+	return a.Position < b.Position
+}
+
+// clusterTokens calls union for every two keywords sharing at least
+// tokenOverlap of their words, stop words aside
+func clusterTokens(keywords []models.OrganicKeyword, union func(i, j int)) {
+	tokens := make([][]string, len(keywords))
+	withToken := map[string][]int{}
+	for i, k := range keywords {
+		tokens[i] = keywordTokens(k.Keyword)
+		for _, t := range tokens[i] {
+			withToken[t] = append(withToken[t], i)
+		}
+	}
+
+	for i := range keywords {
+		compared := map[int]bool{}
+		for _, t := range tokens[i] {
+			for _, j := range withToken[t] {
+				if j <= i || compared[j] {
+					continue
+				}
+				compared[j] = true
+				if overlap(tokens[i], tokens[j]) >= tokenOverlap {
+					union(i, j)
+				}
+			}
+		}
+	}
+}
+
+// keywordTokens returns the distinct lowercase words of keyword, stop words
+// aside, sorted
+func keywordTokens(keyword string) []string {
+	words := strings.FieldsFunc(strings.ToLower(keyword), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := words[:0]
+	for _, w := range words {
+		if !stopWords[w] {
+			tokens = append(tokens, w)
+		}
+	}
+	slices.Sort(tokens)
+	return slices.Compact(tokens)
+}
+
+// overlap returns the Jaccard similarity of two sorted sets of words: the
+// words they share over all the words of either
+func overlap(a, b []string) float64 {
+	shared := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	all := len(a) + len(b) - shared
+	if all == 0 {
+		return 0
+	}
+	return float64(shared) / float64(all)
+}
@@ -0,0 +1,129 @@
+package siteexplorer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// clusterFixture is an organic keywords response of two pages, with a
+// keyword ranking with both
+const clusterFixture = `{"keywords":[
+	{"keyword":"backlink checker","position":1,"volume":32000,"traffic":9100,"url":"https://ahrefs.com/backlink-checker"},
+	{"keyword":"free backlink checker","position":2,"volume":8000,"traffic":1500,"url":"https://ahrefs.com/backlink-checker"},
+	{"keyword":"keyword generator","position":2,"volume":21000,"traffic":4300,"url":"https://ahrefs.com/keyword-generator"},
+	{"keyword":"backlinks","position":9,"volume":12000,"traffic":100,"url":"https://ahrefs.com/blog/backlinks"},
+	{"keyword":"backlinks","position":4,"volume":12000,"traffic":600,"url":"https://ahrefs.com/backlink-checker"},
+	{"keyword":"seo keyword generator tool","position":5,"volume":900,"traffic":40,"url":"https://ahrefs.com/blog/seo-tools"}
+]}`
+
+func TestClusterRows(t *testing.T) {
+	var rows []models.OrganicKeyword
+	for _, r := range []struct {
+		keyword  string
+		position int
+		volume   int
+		traffic  int
+		url      string
+	}{
+		{"backlink checker", 1, 32000, 9100, "/backlink-checker"},
+		{"free backlink checker", 2, 8000, 1500, "/backlink-checker"},
+		{"keyword generator", 2, 21000, 4300, "/keyword-generator"},
+		{"backlinks", 9, 12000, 100, "/blog/backlinks"},
+		{"backlinks", 4, 12000, 600, "/backlink-checker"},
+		{"seo keyword generator tool", 5, 900, 40, "/blog/seo-tools"},
+	} {
+		rows = append(rows, models.OrganicKeyword{Keyword: r.keyword, Position: r.position, SearchVolume: r.volume, Traffic: r.traffic, URL: r.url})
+	}
+
+	tests := []struct {
+		by      string
+		want    []models.KeywordCluster
+		wantIDs map[string]int
+	}{
+		{
+			by: "url",
+			want: []models.KeywordCluster{
+				{ClusterID: 1, ClusterLabel: "backlink checker", Keywords: 3, Volume: 52000, Traffic: 11300, URL: "/backlink-checker"},
+				{ClusterID: 2, ClusterLabel: "keyword generator", Keywords: 1, Volume: 21000, Traffic: 4300, URL: "/keyword-generator"},
+				{ClusterID: 3, ClusterLabel: "seo keyword generator tool", Keywords: 1, Volume: 900, Traffic: 40, URL: "/blog/seo-tools"},
+			},
+			wantIDs: map[string]int{"backlink checker": 1, "free backlink checker": 1, "backlinks": 1, "keyword generator": 2, "seo keyword generator tool": 3},
+		},
+		{
+			by: "tokens",
+			want: []models.KeywordCluster{
+				{ClusterID: 1, ClusterLabel: "backlink checker", Keywords: 3, Volume: 52000, Traffic: 11300, URL: "/backlink-checker"},
+				{ClusterID: 2, ClusterLabel: "keyword generator", Keywords: 2, Volume: 21900, Traffic: 4340, URL: "/keyword-generator"},
+			},
+			wantIDs: map[string]int{"backlink checker": 1, "free backlink checker": 1, "backlinks": 1, "keyword generator": 2, "seo keyword generator tool": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			got, ids := clusterRows(rows, tt.by)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clusterRows() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("clusterRows() IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"best CRM for small business", "small business CRM", 0.75},
+		{"how to build backlinks", "backlinks", 0.5},
+		{"seo", "ppc", 0},
+		{"the", "of", 0},
+	}
+	for _, tt := range tests {
+		if got := overlap(keywordTokens(tt.a), keywordTokens(tt.b)); got != tt.want {
+			t.Errorf("overlap(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCluster_Command(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var selects []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		selects = append(selects, r.URL.Query().Get("select"))
+		fmt.Fprint(w, clusterFixture)
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"organic-keywords", "-t", "ahrefs.com", "-c", "us", "--cluster", "url",
+		"--select", "keyword,traffic", "--format", "csv"})
+	want := "keyword,traffic,url,position,volume,cluster_id,cluster_label\n" +
+		"backlink checker,9100,https://ahrefs.com/backlink-checker,1,32000,1,backlink checker\n" +
+		"free backlink checker,1500,https://ahrefs.com/backlink-checker,2,8000,1,backlink checker\n" +
+		"keyword generator,4300,https://ahrefs.com/keyword-generator,2,21000,2,keyword generator\n" +
+		"backlinks,100,https://ahrefs.com/blog/backlinks,9,12000,1,backlink checker\n" +
+		"backlinks,600,https://ahrefs.com/backlink-checker,4,12000,1,backlink checker\n" +
+		"seo keyword generator tool,40,https://ahrefs.com/blog/seo-tools,5,900,3,seo keyword generator tool\n"
+	if out != want {
+		t.Errorf("--cluster output = %q, want %q", out, want)
+	}
+	if selects[0] != "keyword,traffic,url,position,volume" {
+		t.Errorf("select = %q, want the clustered columns added", selects[0])
+	}
+
+	out = runCommand(t, srv.URL, []string{"organic-keywords", "-t", "ahrefs.com", "-c", "us", "--cluster", "tokens", "--clusters-only", "--format", "csv"})
+	want = "cluster_id,cluster_label,keywords,volume,traffic,url\n" +
+		"1,backlink checker,3,52000,11300,https://ahrefs.com/backlink-checker\n" +
+		"2,keyword generator,2,21900,4340,https://ahrefs.com/keyword-generator\n"
+	if out != want {
+		t.Errorf("--clusters-only output = %q, want %q", out, want)
+	}
+}
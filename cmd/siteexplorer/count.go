@@ -0,0 +1,95 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/pricing"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// rowCount is where a stats endpoint reports the rows of a list endpoint
+type rowCount struct {
+	Path  string
+	Field []string // keys leading to the count in the response
+}
+
+// countConflicts are the flags that change which rows are written, so their
+// count isn't the stats endpoint's
+var countConflicts = []string{
+	"all", "resume", "cursor", "movement", "expand-domains", "group-by-domain",
+	"aggregate", "compare-url", "exclude-own", "exclude-domain", "clusters-only",
+	"merge-url-variants", "summary", "summary-only", "histogram", "histogram-only",
+	"history", "normalize-text",
+}
+
+// countRequest returns the params of the stats request --count-only makes
+// for a request of e with params: those the stats endpoint accepts. Filters
+// can't be counted that way.
+func (e endpoint) countRequest(params url.Values, page pageOptions) (url.Values, error) {
+	if params.Get("where") != "" {
+		return nil, cmd.NewError(cmd.CodeUsage, "--count-only counts every row, so it can't apply --where or the filters added to it",
+			"Remove the filters, or fetch the filtered rows with --all")
+	}
+	if len(page.Countries) > 1 {
+		return nil, cmd.NewError(cmd.CodeUsage, "--count-only takes a single --country", "Run the command once per country")
+	}
+
+	accepted := apiParams[e.Count.Path]
+	counted := url.Values{}
+	for name, values := range params {
+		// the list's --select names its columns, not the stats'
+		if name != "select" && slices.Contains(accepted, name) {
+			counted[name] = values
+		}
+	}
+	return counted, nil
+}
+
+// countRows returns a transform reading the count of e's rows from a stats
+// response, with the cost of fetching them all at the most rows per request
+func (e endpoint) countRows() transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		count, err := e.Count.read(body)
+		if err != nil {
+			return nil, err
+		}
+
+		est := pricing.EstimateUnits(e.Path, count, e.MaxLimit)
+		data, err := json.Marshal(models.RowCount{
+			Endpoint:       e.Path,
+			Count:          count,
+			Requests:       est.Requests,
+			EstimatedUnits: est.Units,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// read returns the count in a stats response body
+func (c rowCount) read(body io.Reader) (int, error) {
+	var v interface{}
+	if _, err := decodeResponse(body, &v); err != nil {
+		return 0, fmt.Errorf("failed to parse response: %w", err)
+	}
+	for _, key := range c.Field {
+		obj, _ := v.(map[string]interface{})
+		v = obj[key]
+	}
+	n, ok := v.(json.Number)
+	count, err := n.Int64()
+	if !ok || err != nil {
+		return 0, fmt.Errorf("failed to parse response: no %s count", strings.Join(c.Field, "."))
+	}
+	return int(count), nil
+}
@@ -0,0 +1,99 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/pricing"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// countServer serves backlinks stats and site metrics, recording the queries
+func countServer(t *testing.T, queries *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.Path+"?"+r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/site-explorer/backlinks-stats":
+			fmt.Fprint(w, `{"metrics":{"live":2500,"refdomains":120}}`)
+		case metricsPath:
+			fmt.Fprint(w, `{"metrics":{"org_keywords":4321,"org_traffic":600}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCountOnly(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var queries []string
+	srv := countServer(t, &queries)
+
+	out := runCommand(t, srv.URL, []string{"backlinks", "-t", "t.com", "--count-only", "--format", "json"})
+	var got struct {
+		Data models.RowCount `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	est := pricing.EstimateUnits("/site-explorer/backlinks", 2500, 1000)
+	want := models.RowCount{Endpoint: "/site-explorer/backlinks", Count: 2500, Requests: 3, EstimatedUnits: est.Units}
+	if got.Data != want {
+		t.Errorf("count = %+v, want %+v", got.Data, want)
+	}
+	if len(queries) != 1 || !strings.HasPrefix(queries[0], "/site-explorer/backlinks-stats?") || strings.Contains(queries[0], "limit") {
+		t.Errorf("requests = %v, want a single stats request without the list's params", queries)
+	}
+}
+
+func TestCountOnly_Value(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var queries []string
+	srv := countServer(t, &queries)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"refdomains", "-t", "t.com", "--count-only", "--value", "count"}, "120"},
+		{[]string{"organic-keywords", "-t", "t.com", "-c", "us", "--select", "keyword", "--count-only", "--value", "count"}, "4321"},
+	}
+	for _, tt := range tests {
+		if out := strings.TrimSpace(runCommand(t, srv.URL, tt.args)); out != tt.want {
+			t.Errorf("%v: output = %q, want %q", tt.args, out, tt.want)
+		}
+	}
+	if q := queries[len(queries)-1]; !strings.Contains(q, "country=us") || strings.Contains(q, "select") {
+		t.Errorf("metrics request = %q, want the country and not the list's --select", q)
+	}
+}
+
+func TestCountOnly_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var queries []string
+	srv := countServer(t, &queries)
+
+	for _, args := range [][]string{
+		{"backlinks", "-t", "t.com", "--count-only", "--where", "domain_rating>50"},
+		{"backlinks", "-t", "t.com", "--count-only", "--dofollow"},
+		{"organic-keywords", "-t", "t.com", "-c", "us,gb", "--count-only"},
+	} {
+		_, err := execCommand(t, srv.URL, args)
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+			t.Errorf("%v: error = %v, want a usage error", args, err)
+		}
+	}
+	if len(queries) != 0 {
+		t.Errorf("requests = %v, want none", queries)
+	}
+}
@@ -0,0 +1,137 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+// maxCountryRequests bounds how many countries are requested at once
+const maxCountryRequests = 4
+
+// parseCountries splits a --country list into its codes, dropping blanks
+// and repeats
+func parseCountries(value string) []string {
+	var countries []string
+	seen := map[string]bool{}
+	for _, code := range strings.Split(value, ",") {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code != "" && !seen[code] {
+			seen[code] = true
+			countries = append(countries, code)
+		}
+	}
+	return countries
+}
+
+// fetchCountries requests each of page.Countries separately and merges their
+// responses: rows are tagged with their country, and a single-object
+// response becomes a row per country. Countries that fail are left out and
+// reported in meta.Errors; only when all fail is it an error.
+func fetchCountries(ctx context.Context, c *client.Client, endpoint string, params url.Values, page pageOptions, log *slog.Logger) (io.ReadCloser, client.ResponseMeta, error) {
+	countries := page.Countries
+	page.Countries = nil
+
+	bodies := make([]map[string]interface{}, len(countries))
+	metas := make([]client.ResponseMeta, len(countries))
+	errs := make([]error, len(countries))
+
+	sem := make(chan struct{}, maxCountryRequests)
+	var wg sync.WaitGroup
+	for i, country := range countries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			p := cloneValues(params)
+			p.Set("country", country)
+			bodies[i], metas[i], errs[i] = fetchObject(ctx, c, endpoint, p, page, log)
+		}()
+	}
+	wg.Wait()
+
+	var meta client.ResponseMeta
+	merged := map[string]interface{}{}
+	failed := 0
+	for i, country := range countries {
+		meta.UnitsConsumed += metas[i].UnitsConsumed
+		meta.ResponseTimeMS += metas[i].ResponseTimeMS
+		meta.Requests += max(metas[i].Requests, 1)
+		meta.Attempts += metas[i].Attempts
+		meta.TotalWaitMS += metas[i].TotalWaitMS
+		if errs[i] != nil {
+			failed++
+			meta.Errors = append(meta.Errors, fmt.Sprintf("country %s: %v", country, errs[i]))
+			continue
+		}
+		meta.RateLimitRemaining = metas[i].RateLimitRemaining
+		mergeCountry(merged, bodies[i], country)
+	}
+	if failed == len(countries) {
+		return nil, meta, fmt.Errorf("country %s: %w", countries[0], errs[0])
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, meta, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), meta, nil
+}
+
+// fetchObject fetches a response and decodes it generically
+func fetchObject(ctx context.Context, c *client.Client, endpoint string, params url.Values, page pageOptions, log *slog.Logger) (map[string]interface{}, client.ResponseMeta, error) {
+	body, meta, err := fetch(ctx, c, endpoint, params, page, log)
+	if err != nil {
+		return nil, meta, err
+	}
+	defer body.Close()
+
+	var v interface{}
+	if _, err := decodeResponse(body, &v); err != nil {
+		return nil, meta, fmt.Errorf("failed to parse response: %w", err)
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, meta, fmt.Errorf("failed to parse response: not an object")
+	}
+	return obj, meta, nil
+}
+
+// mergeCountry appends the rows of a country's response to merged, tagged
+// with country. Lists are appended to; an object becomes a row of the list
+// under its key. Other fields, such as paging tokens, are dropped.
+func mergeCountry(merged, body map[string]interface{}, country string) {
+	for key, v := range body {
+		var rows []interface{}
+		switch v := v.(type) {
+		case []interface{}:
+			rows = v
+		case map[string]interface{}:
+			rows = []interface{}{v}
+		default:
+			continue
+		}
+
+		list, _ := merged[key].([]interface{})
+		if list == nil {
+			list = []interface{}{}
+		}
+		for _, row := range rows {
+			if obj, ok := row.(map[string]interface{}); ok {
+				obj["country"] = country
+			}
+			list = append(list, row)
+		}
+		merged[key] = list
+	}
+}
@@ -0,0 +1,133 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestParseCountries(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"us", []string{"us"}},
+		{"us, GB,de", []string{"us", "gb", "de"}},
+		{"us,,us,gb,", []string{"us", "gb"}},
+	}
+	for _, tt := range tests {
+		if got := parseCountries(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCountries(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// countryServer answers with the country requested in each body, failing for
+// the countries in fail
+func countryServer(t *testing.T, fail ...string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		country := r.URL.Query().Get("country")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-API-Units-Consumed", "3")
+		for _, f := range fail {
+			if country == f {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"error":"unknown country %s"}`, country)
+				return
+			}
+		}
+		if strings.HasSuffix(r.URL.Path, "/metrics") {
+			fmt.Fprintf(w, `{"metrics":{"org_traffic":%d}}`, len(country)*100+int(country[0]))
+			return
+		}
+		fmt.Fprintf(w, `{"keywords":[{"keyword":"%s one","position":1},{"keyword":"%s two","position":2}],"next":"x"}`, country, country)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCountries_Metrics(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := countryServer(t, "gb")
+
+	out := runCommand(t, srv.URL, []string{"metrics", "-t", "t.com", "--country", "us,gb,de", "--format", "json"})
+	var got struct {
+		Data models.CountryMetricsResponse `json:"data"`
+		Meta struct {
+			Errors []string `json:"errors"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+
+	want := []models.CountryMetrics{{Country: "us", OrgTraffic: 317}, {Country: "de", OrgTraffic: 300}}
+	if !reflect.DeepEqual(got.Data.Metrics, want) {
+		t.Errorf("metrics = %+v, want %+v", got.Data.Metrics, want)
+	}
+	if len(got.Meta.Errors) != 1 || !strings.HasPrefix(got.Meta.Errors[0], "country gb: ") {
+		t.Errorf("meta errors = %q, want the gb failure", got.Meta.Errors)
+	}
+}
+
+func TestCountries_OrganicKeywordsCSV(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := countryServer(t)
+
+	out := runCommand(t, srv.URL, []string{"organic-keywords", "-t", "t.com", "-c", "us,gb", "--select", "keyword,country", "--format", "csv"})
+	want := "keyword,country\nus one,us\nus two,us\ngb one,gb\ngb two,gb\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestCountries_AllFail(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := countryServer(t, "us", "gb")
+
+	_, err := execCommand(t, srv.URL, []string{"metrics", "-t", "t.com", "--country", "us,gb"})
+	if err == nil || !strings.Contains(err.Error(), "country us") {
+		t.Errorf("error = %v, want the first country's failure", err)
+	}
+}
+
+func TestCountries_DryRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AHREFS_COUNTRY", "")
+
+	out := runCommand(t, "https://api.ahrefs.com/v3", []string{"metrics", "-t", "t.com", "--country", "us,gb", "--dry-run"})
+	for _, want := range []string{
+		"Would call: GET https://api.ahrefs.com/v3/site-explorer/metrics?country=us&mode=domain&target=t.com\n",
+		"  And: GET https://api.ahrefs.com/v3/site-explorer/metrics?country=gb&mode=domain&target=t.com\n",
+		"(2 request(s), 2 row(s))",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dry-run output = %q, want it to contain %q", out, want)
+		}
+	}
+}
+
+func TestCountries_MetricsUnits(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := countryServer(t)
+
+	out := runCommand(t, srv.URL, []string{"metrics", "-t", "t.com", "--country", "us,gb,de", "--format", "json"})
+	var got struct {
+		Meta struct {
+			UnitsConsumed int `json:"units_consumed"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if got.Meta.UnitsConsumed != 9 {
+		t.Errorf("units consumed = %d, want 9", got.Meta.UnitsConsumed)
+	}
+}
@@ -0,0 +1,89 @@
+package siteexplorer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout is the format of dates sent to the API
+const dateLayout = "2006-01-02"
+
+// parseDate parses a date flag: a YYYY-MM-DD date, "today", or a time ago
+// relative to now, such as 30d, 6w, 3m, or 1y
+func parseDate(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "today" {
+		return truncateDay(now), nil
+	}
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, nil
+	}
+
+	if len(value) >= 2 {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err == nil && n >= 0 {
+			day := truncateDay(now)
+			switch value[len(value)-1] {
+			case 'd':
+				return day.AddDate(0, 0, -n), nil
+			case 'w':
+				return day.AddDate(0, 0, -7*n), nil
+			case 'm':
+				return day.AddDate(0, -n, 0), nil
+			case 'y':
+				return day.AddDate(-n, 0, 0), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q: want YYYY-MM-DD, today, or a time ago such as 30d, 6w, 3m, or 1y", value)
+}
+
+// anchor returns the time relative dates of the request are resolved from
+func (f requestFlags) anchor() time.Time {
+	if f.now.IsZero() {
+		return time.Now()
+	}
+	return f.now
+}
+
+// truncateDay returns midnight UTC of t's date in t's location, so today is
+// the date in --timezone
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// firstSeenConditions returns the filter conditions matching links first
+// seen from since to until inclusive. Either bound may be empty.
+func firstSeenConditions(since, until string, now time.Time) ([]*filterNode, error) {
+	var conds []*filterNode
+	var from, to time.Time
+	for _, b := range []struct {
+		flag, value, op string
+		t               *time.Time
+	}{
+		{"--first-seen-since", since, "gte", &from},
+		{"--first-seen-until", until, "lte", &to},
+	} {
+		if b.value == "" {
+			continue
+		}
+		t, err := parseDate(b.value, now)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.flag, err)
+		}
+		*b.t = t
+		conds = append(conds, isCond("first_seen", b.op, t.Format(dateLayout)).from(b.flag))
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return nil, fmt.Errorf("--first-seen-since %s is after --first-seen-until %s", from.Format(dateLayout), to.Format(dateLayout))
+	}
+	return conds, nil
+}
+
+// isStructured reports whether a --where expression is structured JSON, the
+// only kind other filters can be combined with
+func isStructured(where string) bool {
+	return strings.HasPrefix(strings.TrimSpace(where), "{")
+}
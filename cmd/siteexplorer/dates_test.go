@@ -0,0 +1,164 @@
+package siteexplorer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2024, 3, 31, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "2024-01-15", want: "2024-01-15"},
+		{value: "today", want: "2024-03-31"},
+		{value: " Today ", want: "2024-03-31"},
+		{value: "0d", want: "2024-03-31"},
+		{value: "30d", want: "2024-03-01"},
+		{value: "2w", want: "2024-03-17"},
+		// Month ends roll over like time.AddDate: February 31st is March 2nd
+		{value: "1m", want: "2024-03-02"},
+		{value: "1y", want: "2023-03-31"},
+		{value: "", wantErr: true},
+		{value: "d", wantErr: true},
+		{value: "-3d", wantErr: true},
+		{value: "3h", wantErr: true},
+		{value: "2024-13-01", wantErr: true},
+		{value: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.value, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.Format(dateLayout) != tt.want {
+			t.Errorf("parseDate(%q) = %s, want %s", tt.value, got.Format(dateLayout), tt.want)
+		}
+	}
+}
+
+func TestParseDate_Timezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		now   time.Time
+		value string
+		want  string
+	}{
+		// 23:30 in New York is already the next day in UTC
+		{name: "today late in New York", now: time.Date(2024, 3, 10, 23, 30, 0, 0, newYork), value: "today", want: "2024-03-10"},
+		{name: "today late in New York, in UTC", now: time.Date(2024, 3, 10, 23, 30, 0, 0, newYork).UTC(), value: "today", want: "2024-03-11"},
+		// 08:00 in Tokyo is still the day before in UTC
+		{name: "today early in Tokyo", now: time.Date(2024, 3, 11, 8, 0, 0, 0, tokyo), value: "today", want: "2024-03-11"},
+		{name: "today early in Tokyo, in UTC", now: time.Date(2024, 3, 11, 8, 0, 0, 0, tokyo).UTC(), value: "today", want: "2024-03-10"},
+		// The day clocks go forward is 23 hours long and the day they go
+		// back 25, but days ago are counted on the calendar
+		{name: "across spring forward", now: time.Date(2024, 3, 11, 0, 30, 0, 0, newYork), value: "1d", want: "2024-03-10"},
+		{name: "across spring forward, a week", now: time.Date(2024, 3, 14, 23, 30, 0, 0, newYork), value: "7d", want: "2024-03-07"},
+		{name: "across fall back", now: time.Date(2024, 11, 4, 0, 30, 0, 0, newYork), value: "1d", want: "2024-11-03"},
+		{name: "across fall back, a week", now: time.Date(2024, 11, 3, 23, 30, 0, 0, newYork), value: "1w", want: "2024-10-27"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDate(tt.value, tt.now)
+			if err != nil {
+				t.Fatalf("parseDate(%q) error = %v", tt.value, err)
+			}
+			if got.Format(dateLayout) != tt.want {
+				t.Errorf("parseDate(%q) at %s = %s, want %s", tt.value, tt.now, got.Format(dateLayout), tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstSeenSince_Timezone(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query().Get("where")
+		w.Write([]byte(`{"backlinks":[]}`))
+	}))
+	defer srv.Close()
+
+	// Kiritimati is UTC+14, so today there is tomorrow in UTC for most of
+	// the day
+	if _, err := execCommand(t, srv.URL, []string{"backlinks", "-t", "example.com", "--first-seen-since", "today", "--timezone", "Pacific/Kiritimati"}); err != nil {
+		t.Fatalf("execute error = %v", err)
+	}
+	want := time.Now().In(mustLoadLocation(t, "Pacific/Kiritimati")).Format(dateLayout)
+	if !strings.Contains(got, want) {
+		t.Errorf("where = %s, want first_seen from %s, today in Kiritimati", got, want)
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestFirstSeenConditions(t *testing.T) {
+	now := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	const since = `{"field":"first_seen","is":["gte","2024-03-01"]}`
+	const until = `{"field":"first_seen","is":["lte","2024-03-24"]}`
+
+	tests := []struct {
+		name         string
+		since, until string
+		want         []string
+	}{
+		{name: "since", since: "30d", want: []string{since}},
+		{name: "until", until: "1w", want: []string{until}},
+		{name: "both", since: "2024-03-01", until: "1w", want: []string{since, until}},
+		{name: "neither"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := firstSeenConditions(tt.since, tt.until, now)
+			if err != nil {
+				t.Fatalf("firstSeenConditions() error = %v", err)
+			}
+			if got := filterStrings(got); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("firstSeenConditions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstSeenConditions_Invalid(t *testing.T) {
+	now := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	if _, err := firstSeenConditions("7d", "30d", now); err == nil || !strings.Contains(err.Error(), "is after") {
+		t.Errorf("firstSeenConditions() error = %v, want one about the reversed window", err)
+	}
+	if _, err := firstSeenConditions("", "last week", now); err == nil || !strings.Contains(err.Error(), "--first-seen-until") {
+		t.Errorf("firstSeenConditions() error = %v, want one naming the flag", err)
+	}
+}
+
+func TestFirstSeen_TextWhere(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, err := execCommand(t, "http://127.0.0.1:0", []string{"backlinks", "-t", "example.com", "--where", "domain_rating>50", "--first-seen-since", "30d"})
+	if err == nil || !strings.Contains(err.Error(), "structured --where") {
+		t.Errorf("error = %v, want one asking for a structured --where", err)
+	}
+}
@@ -0,0 +1,268 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// snippetRadius is how many bytes either side of a decoding error are quoted
+const snippetRadius = 40
+
+// decodeError is a response that stopped being valid JSON part way through
+type decodeError struct {
+	Offset  int64
+	Snippet string
+	Err     error
+}
+
+func (e *decodeError) Error() string {
+	if errors.Is(e.Err, io.ErrUnexpectedEOF) {
+		return fmt.Sprintf("response ended early at byte %d; it may have been cut off by a proxy or timeout", e.Offset)
+	}
+	if e.Snippet == "" {
+		return fmt.Sprintf("invalid JSON at byte %d: %v", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("invalid JSON at byte %d near %q: %v", e.Offset, e.Snippet, e.Err)
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeResponse decodes the JSON object read from r into result, a pointer
+// to a response model or to interface{}. List fields are decoded one row at
+// a time, so only the decoded rows are held in memory rather than the whole
+// body as well. It returns the other top-level fields, decoded generically,
+// for finding the next-page token.
+func decodeResponse(r io.Reader, result interface{}) (map[string]interface{}, error) {
+	rec := &recentReader{r: r}
+	dec := json.NewDecoder(rec)
+	dec.UseNumber()
+
+	d := &streamDecoder{dec: dec, result: result}
+	fields, err := d.decode()
+	if err != nil {
+		return nil, rec.decodeError(dec, err)
+	}
+	return fields, nil
+}
+
+// streamDecoder walks the top level of a response with a json.Decoder
+type streamDecoder struct {
+	dec    *json.Decoder
+	result interface{}
+}
+
+func (d *streamDecoder) decode() (map[string]interface{}, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		// Not an object: there are no rows to stream
+		v, err := d.value(tok)
+		if err != nil {
+			return nil, err
+		}
+		return nil, d.assign(reflect.ValueOf(d.result), v)
+	}
+
+	generic := map[string]interface{}{}
+	fields := map[string]interface{}{}
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+
+		if tok, err = d.dec.Token(); err != nil {
+			return nil, err
+		}
+		if tok == json.Delim('[') {
+			rows, err := d.rows(key)
+			if err != nil {
+				return nil, err
+			}
+			generic[key] = rows
+			continue
+		}
+
+		v, err := d.value(tok)
+		if err != nil {
+			return nil, err
+		}
+		generic[key] = v
+		fields[key] = v
+		if f, ok := d.field(key); ok {
+			if err := d.assign(f.Addr(), v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := d.dec.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := d.dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the response")
+	}
+
+	if p, ok := d.result.(*interface{}); ok {
+		*p = generic
+	}
+	return fields, nil
+}
+
+// rows decodes the array under key, whose opening bracket has been read,
+// into the matching slice field of the result. For a generic result the rows
+// are returned instead.
+func (d *streamDecoder) rows(key string) (interface{}, error) {
+	f, typed := d.field(key)
+	typed = typed && f.Kind() == reflect.Slice
+	_, generic := d.result.(*interface{})
+
+	var rows []interface{}
+	if generic {
+		rows = []interface{}{}
+	}
+	for d.dec.More() {
+		switch {
+		case typed:
+			f.Set(reflect.Append(f, reflect.Zero(f.Type().Elem())))
+			if err := d.dec.Decode(f.Index(f.Len() - 1).Addr().Interface()); err != nil {
+				return nil, err
+			}
+		case generic:
+			var row interface{}
+			if err := d.dec.Decode(&row); err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		default:
+			var skip json.RawMessage
+			if err := d.dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := d.dec.Token(); err != nil {
+		return nil, err
+	}
+	if typed && f.IsNil() {
+		f.Set(reflect.MakeSlice(f.Type(), 0, 0))
+	}
+	return rows, nil
+}
+
+// value decodes the rest of the value starting with tok
+func (d *streamDecoder) value(tok json.Token) (interface{}, error) {
+	switch tok {
+	case json.Delim('{'):
+		obj := map[string]interface{}{}
+		for d.dec.More() {
+			key, err := d.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			var v interface{}
+			if err := d.dec.Decode(&v); err != nil {
+				return nil, err
+			}
+			obj[key.(string)] = v
+		}
+		_, err := d.dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for d.dec.More() {
+			var v interface{}
+			if err := d.dec.Decode(&v); err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err := d.dec.Token()
+		return list, err
+	}
+	return tok, nil
+}
+
+// field returns the field of a struct result with the JSON name key
+func (d *streamDecoder) field(key string) (reflect.Value, bool) {
+	v := reflect.ValueOf(d.result)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		if f.IsExported() && name == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// assign stores the generic value v into the pointer dst
+func (d *streamDecoder) assign(dst reflect.Value, v interface{}) error {
+	if p, ok := dst.Interface().(*interface{}); ok {
+		*p = v
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst.Interface())
+}
+
+// recentReader remembers the last two chunks read, so errors can quote the
+// bytes around where decoding failed
+type recentReader struct {
+	r         io.Reader
+	prev, cur []byte
+	end       int64 // bytes read so far
+}
+
+func (r *recentReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.prev, r.cur = r.cur, r.prev[:0]
+		r.cur = append(r.cur, p[:n]...)
+		r.end += int64(n)
+	}
+	return n, err
+}
+
+// decodeError wraps err with the offset it occurred at and the surrounding
+// bytes when they were read recently. Type errors are reported at the end of
+// the row they occur in.
+func (r *recentReader) decodeError(dec *json.Decoder, err error) error {
+	offset := dec.InputOffset()
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		offset = r.end
+		err = io.ErrUnexpectedEOF
+	}
+
+	recent := append(append([]byte{}, r.prev...), r.cur...)
+	from := r.end - int64(len(recent))
+	lo, hi := offset-snippetRadius-from, offset+snippetRadius-from
+	lo, hi = max(lo, 0), min(hi, int64(len(recent)))
+	snippet := ""
+	if lo < hi {
+		snippet = string(recent[lo:hi])
+	}
+	return &decodeError{Offset: offset, Snippet: snippet, Err: err}
+}
@@ -0,0 +1,208 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestDecodeResponse_Typed(t *testing.T) {
+	body := `{"next_cursor":"abc","anchors":[{"anchor":"a","backlinks":3},{"anchor":"b"}],"stats":{"total":2}}`
+
+	var got models.AnchorsResponse
+	fields, err := decodeResponse(iotest.OneByteReader(strings.NewReader(body)), &got)
+	if err != nil {
+		t.Fatalf("decodeResponse() error = %v", err)
+	}
+
+	want := models.AnchorsResponse{Anchors: []models.Anchor{{Anchor: "a", Backlinks: 3}, {Anchor: "b"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeResponse() result = %+v, want %+v", got, want)
+	}
+	if fields["next_cursor"] != "abc" || findCursor("/site-explorer/anchors", fields) != "abc" {
+		t.Errorf("decodeResponse() fields = %v, want next_cursor abc", fields)
+	}
+	if _, ok := fields["anchors"]; ok {
+		t.Error("decodeResponse() fields include the rows")
+	}
+}
+
+func TestDecodeResponse_Generic(t *testing.T) {
+	body := `{"pagination":{"next_cursor":"p2"},"anchors":[{"anchor":"a","backlinks":12345678901234567890}],"empty":[]}`
+
+	var got interface{}
+	fields, err := decodeResponse(strings.NewReader(body), &got)
+	if err != nil {
+		t.Fatalf("decodeResponse() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"pagination": map[string]interface{}{"next_cursor": "p2"},
+		"anchors":    []interface{}{map[string]interface{}{"anchor": "a", "backlinks": json.Number("12345678901234567890")}},
+		"empty":      []interface{}{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeResponse() result = %#v, want %#v", got, want)
+	}
+	if next := findCursor("/site-explorer/anchors", fields); next != "p2" {
+		t.Errorf("findCursor() = %q, want p2", next)
+	}
+}
+
+func TestDecodeResponse_SingleObject(t *testing.T) {
+	var got models.DomainRatingResponse
+	if _, err := decodeResponse(strings.NewReader(`{"domain_rating":{"domain_rating":91}}`), &got); err != nil {
+		t.Fatalf("decodeResponse() error = %v", err)
+	}
+	if got.DomainRating.DomainRating != 91 {
+		t.Errorf("domain_rating = %v, want 91", got.DomainRating.DomainRating)
+	}
+}
+
+func TestDecodeResponse_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantOffset  int64
+		wantSnippet string
+		wantError   string
+	}{
+		{
+			name:        "invalid row",
+			body:        `{"anchors":[{"anchor":"a"},{"anchor":"b",oops}]}`,
+			wantOffset:  42,
+			wantSnippet: `{"anchor":"b",oops}`,
+		},
+		{
+			name:        "wrong type",
+			body:        `{"anchors":[{"anchor":"a","backlinks":"many"}]}`,
+			wantOffset:  45,
+			wantSnippet: `"backlinks":"many"}`,
+		},
+		{
+			name:       "truncated",
+			body:       `{"anchors":[{"anchor":"a"},{"anch`,
+			wantOffset: 33,
+			wantError:  "response ended early at byte 33",
+		},
+		{
+			name:       "trailing data",
+			body:       `{"anchors":[]} {}`,
+			wantOffset: 16,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got models.AnchorsResponse
+			_, err := decodeResponse(strings.NewReader(tt.body), &got)
+
+			var decErr *decodeError
+			if !errors.As(err, &decErr) {
+				t.Fatalf("decodeResponse() error = %v, want *decodeError", err)
+			}
+			if decErr.Offset != tt.wantOffset {
+				t.Errorf("Offset = %d, want %d (%v)", decErr.Offset, tt.wantOffset, err)
+			}
+			if !strings.Contains(decErr.Snippet, tt.wantSnippet) {
+				t.Errorf("Snippet = %q, want it to contain %q", decErr.Snippet, tt.wantSnippet)
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Error() = %q, want it to contain %q", err.Error(), tt.wantError)
+			}
+		})
+	}
+}
+
+// rowsReader generates a backlinks response of about size bytes without
+// holding it in memory
+type rowsReader struct {
+	row  []byte
+	left int
+	rows int
+	buf  bytes.Buffer
+	done bool
+}
+
+func newRowsReader(size int) *rowsReader {
+	r := &rowsReader{
+		row:  []byte(`{"url_from":"https://example.com/some/long/path/to/a/page","url_to":"https://ahrefs.com/","anchor":"backlink checker","domain_rating":71.5,"http_code":200,"first_seen":"2023-04-01T12:00:00Z","is_dofollow":true,"languages":["en"]}`),
+		left: size,
+	}
+	r.buf.WriteString(`{"backlinks":[`)
+	return r
+}
+
+func (r *rowsReader) Read(p []byte) (int, error) {
+	for r.buf.Len() < len(p) && !r.done {
+		if r.left <= 0 {
+			r.buf.WriteString(`]}`)
+			r.done = true
+			break
+		}
+		if r.rows > 0 {
+			r.buf.WriteByte(',')
+		}
+		r.buf.Write(r.row)
+		r.rows++
+		r.left -= len(r.row) + 1
+	}
+	if r.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return r.buf.Read(p)
+}
+
+// heapInUse returns the live heap after a collection
+func heapInUse() uint64 {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// BenchmarkDecodeResponse compares streaming a 50MB response into the model
+// with buffering it first. MB-live is the heap held once decoding is done:
+// the rows alone when streamed, the rows and the body when buffered.
+func BenchmarkDecodeResponse(b *testing.B) {
+	const size = 50 << 20
+
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			before := heapInUse()
+			var result models.BacklinksResponse
+			if _, err := decodeResponse(newRowsReader(size), &result); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportMetric(float64(heapInUse()-before)/(1<<20), "MB-live")
+			runtime.KeepAlive(result)
+		}
+	})
+
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			before := heapInUse()
+			body, err := io.ReadAll(newRowsReader(size))
+			if err != nil {
+				b.Fatal(err)
+			}
+			var result models.BacklinksResponse
+			if err := json.Unmarshal(body, &result); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportMetric(float64(heapInUse()-before)/(1<<20), "MB-live")
+			runtime.KeepAlive(body)
+			runtime.KeepAlive(result)
+		}
+	})
+}
@@ -0,0 +1,489 @@
+package siteexplorer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/redact"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+const (
+	domainRatingPath    = "/site-explorer/domain-rating"
+	backlinksPath       = "/site-explorer/backlinks"
+	organicKeywordsPath = "/site-explorer/organic-keywords"
+
+	// digestKeywords is how many of the top keywords on each date are
+	// compared for the biggest movements
+	digestKeywords = 1000
+)
+
+// digestOptions configures a digest
+type digestOptions struct {
+	target  string
+	mode    string
+	modeSet bool
+	since   string
+	until   string
+	country string
+	top     int
+}
+
+// NewDigestCmd creates the digest command
+func NewDigestCmd() *cobra.Command {
+	var opts digestOptions
+
+	c := &cobra.Command{
+		Use:   "digest",
+		Short: "Summarize how a target changed over a period",
+		Long: `Summarize how a target changed from --since to --until: its domain rating,
+referring domains, and organic traffic at either end, the strongest backlinks
+first seen in the period, and the keywords whose positions moved the most.
+
+Each section is made from its own requests. A section that fails is left
+out, with the error logged and listed in the output, and the rest of the
+digest is still written; the command fails only when every section does.
+Keyword movements compare the top ` + strconv.Itoa(digestKeywords) + ` keywords by traffic in --country
+on each date, as organic-keywords --movement does.
+
+--format markdown writes a compact digest for pasting into Slack or an
+email; json and yaml write the same sections as data. The units spent on
+every request are totalled at the bottom.`,
+		Example: `  # Last week's changes, ready to paste
+  ahrefs digest --target example.com --since 7d --format markdown
+
+  # A month, with the top 5 backlinks and keyword moves in the UK
+  ahrefs digest --target example.com --since 1m --country gb --top 5 --format markdown
+
+  # The same sections as JSON, for a script
+  ahrefs digest --target example.com --since 2024-06-01 --until 2024-06-30`,
+		Args: cobra.NoArgs,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			opts.modeSet = cobraCmd.Flags().Changed("mode")
+			return runDigest(cobraCmd.Context(), opts)
+		},
+	}
+
+	c.Flags().StringVarP(&opts.target, "target", "t", "", "Target domain or URL (required)")
+	c.Flags().StringVarP(&opts.mode, "mode", "m", string(models.ModeDomain), "Mode: exact, domain, prefix, subdomains")
+	c.Flags().StringVar(&opts.since, "since", "7d", "Start of the period: YYYY-MM-DD or a time ago, such as 7d, 4w, or 1m")
+	c.Flags().StringVar(&opts.until, "until", "today", "End of the period: YYYY-MM-DD, today, or a time ago")
+	c.Flags().StringVarP(&opts.country, "country", "c", "us", "Country of the keyword movements (e.g., us, gb, de)")
+	c.Flags().IntVar(&opts.top, "top", 10, "Number of new backlinks and keyword movements listed")
+	cmd.BindEnv(c.Flags(), "country", "AHREFS_COUNTRY")
+	cmd.SetAllowedValues(c, "mode", models.Strings(models.Modes())...)
+	cmd.SetSuggestedValues(c, "country", models.Strings(models.Countries())...)
+	c.MarkFlagRequired("target")
+
+	return c
+}
+
+func runDigest(ctx context.Context, opts digestOptions) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	switch output.Format(flags.OutputFormat) {
+	case output.FormatCSV, output.FormatTable:
+		return cmd.NewError(cmd.CodeUsage, "a digest has sections, not rows, so can't be written as "+flags.OutputFormat,
+			"Use --format markdown, json, or yaml")
+	}
+
+	target, mode, err := resolveTarget(opts.target, opts.mode, opts.modeSet, false)
+	if err != nil {
+		return err
+	}
+	now := flags.Now()
+	from, err := parseDate(opts.since, now)
+	if err != nil {
+		return cmd.NewError(cmd.CodeUsage, "--since: "+err.Error(), "Use e.g. --since 7d or --since 2024-06-01")
+	}
+	to, err := parseDate(opts.until, now)
+	if err != nil {
+		return cmd.NewError(cmd.CodeUsage, "--until: "+err.Error(), "Use e.g. --until today or --until 2024-06-30")
+	}
+	if from.After(to) {
+		return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("--since %s is after --until %s", from.Format(dateLayout), to.Format(dateLayout)),
+			"Swap the dates, or leave out --until for today")
+	}
+	if _, err := models.ParseCountry(opts.country); err != nil {
+		return cmd.NewError(cmd.CodeUsage, err.Error(), "Use a two-letter ISO 3166-1 country code, e.g. --country us")
+	}
+	if opts.top < 1 {
+		return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("--top must be at least 1, got %d", opts.top), "Use e.g. --top 10")
+	}
+
+	apiKey := flags.APIKey
+	if apiKey == "" {
+		apiKey = config.GetAPIKey()
+	}
+	if apiKey == "" {
+		return cmd.ErrAPIKeyRequired
+	}
+
+	c := client.NewClient(client.Config{
+		APIKey:      apiKey,
+		BaseURL:     flags.BaseURL,
+		BaseURLs:    flags.BaseURLs,
+		Headers:     flags.Headers,
+		Timeout:     flags.Timeout,
+		MaxBodySize: flags.MaxBodySize,
+		MaxRetries:  flags.MaxRetries,
+		Middleware:  cmd.ClientMiddleware(flags),
+		Logger:      flags.Log(),
+		OnRetry:     flags.Progress.Retry,
+	})
+
+	d := models.Digest{Target: target, Mode: string(mode), From: from.Format(dateLayout), To: to.Format(dateLayout)}
+	sections := digestSections(d, strings.ToLower(strings.TrimSpace(opts.country)), opts.top)
+
+	if flags.DryRun {
+		printDigestDryRun(redact.Writer(flags.Stdout, flags.Secrets()...), c, sections)
+		return nil
+	}
+
+	budget := loadBudget()
+	if err := checkBudgetBefore(budget, flags); err != nil {
+		return err
+	}
+
+	// Open the destination first so a bad --output fails before any units
+	// are spent
+	w, err := newWriter(flags)
+	if err != nil {
+		return err
+	}
+
+	units, err := collectDigest(ctx, c, sections, newSpendGuard(budget, flags), &d)
+	for _, e := range d.Errors {
+		flags.Log().Warn(fmt.Sprintf("digest: %s: %s", e.Section, e.Error))
+	}
+	if err != nil {
+		w.WriteError(err)
+		w.Abort()
+		return err
+	}
+
+	if err := writeAndClose(w, flags, digestReport(d), &client.ResponseMeta{UnitsConsumed: d.UnitsConsumed}); err != nil {
+		return err
+	}
+	for _, endpoint := range sortedKeys(units) {
+		if err := trackUsage(budget, flags, endpoint, units[endpoint]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// digestRequest is a request a digest section is made from
+type digestRequest struct {
+	endpoint string
+	params   url.Values
+}
+
+// digestSection is a part of a digest. fill decodes the bodies of its
+// requests, in order, into the digest.
+type digestSection struct {
+	name     string
+	requests []digestRequest
+	fill     func(bodies [][]byte, d *models.Digest) error
+}
+
+// digestSections returns the sections of a digest of d's target and period,
+// with the keyword movements of country and top rows of the lists
+func digestSections(d models.Digest, country string, top int) []digestSection {
+	at := func(endpoint, date string, extra ...string) digestRequest {
+		params := url.Values{}
+		params.Set("target", d.Target)
+		params.Set("mode", d.Mode)
+		if date != "" {
+			params.Set("date", date)
+		}
+		for i := 0; i+1 < len(extra); i += 2 {
+			params.Set(extra[i], extra[i+1])
+		}
+		return digestRequest{endpoint, params}
+	}
+	from, _ := time.Parse(dateLayout, d.From)
+	firstSeen, _ := firstSeenConditions(d.From, "", from)
+
+	return []digestSection{
+		{
+			name:     "domain_rating",
+			requests: []digestRequest{at(domainRatingPath, d.From), at(domainRatingPath, d.To)},
+			fill: func(bodies [][]byte, d *models.Digest) error {
+				var prev, cur models.DomainRatingResponse
+				if err := unmarshalBodies(bodies, &prev, &cur); err != nil {
+					return err
+				}
+				d.DomainRating = metricChange(prev.DomainRating.DomainRating, cur.DomainRating.DomainRating)
+				return nil
+			},
+		},
+		{
+			name:     "refdomains",
+			requests: []digestRequest{at(refDomainsHistoryPath, "", "date_from", d.From, "date_to", d.To)},
+			fill: func(bodies [][]byte, d *models.Digest) error {
+				var history models.RefDomainsHistoryResponse
+				if err := unmarshalBodies(bodies, &history); err != nil {
+					return err
+				}
+				rows := history.RefDomains
+				if len(rows) == 0 {
+					return fmt.Errorf("no referring domains history from %s to %s", d.From, d.To)
+				}
+				sort.SliceStable(rows, func(i, j int) bool { return rows[i].Date < rows[j].Date })
+				d.RefDomains = metricChange(float64(rows[0].RefDomains), float64(rows[len(rows)-1].RefDomains))
+				return nil
+			},
+		},
+		{
+			name: "org_traffic",
+			requests: []digestRequest{
+				at(metricsPath, d.From, "select", "org_traffic"),
+				at(metricsPath, d.To, "select", "org_traffic"),
+			},
+			fill: func(bodies [][]byte, d *models.Digest) error {
+				var prev, cur models.MetricsResponse
+				if err := unmarshalBodies(bodies, &prev, &cur); err != nil {
+					return err
+				}
+				d.OrgTraffic = metricChange(float64(prev.Metrics.OrgTraffic), float64(cur.Metrics.OrgTraffic))
+				return nil
+			},
+		},
+		{
+			name: "new_backlinks",
+			requests: []digestRequest{at(backlinksPath, "",
+				"select", "url_from,url_to,domain_rating,anchor,first_seen",
+				"where", allOf(firstSeen...).String(),
+				"history", models.HistorySince(from).String(),
+				"order_by", "domain_rating:desc",
+				"limit", strconv.Itoa(top),
+			)},
+			fill: func(bodies [][]byte, d *models.Digest) error {
+				var resp models.BacklinksResponse
+				if err := unmarshalBodies(bodies, &resp); err != nil {
+					return err
+				}
+				d.NewBacklinks = append([]models.Backlink{}, resp.Backlinks...)
+				return nil
+			},
+		},
+		{
+			name: "keyword_movements",
+			requests: []digestRequest{
+				at(organicKeywordsPath, d.From, keywordParams(country)...),
+				at(organicKeywordsPath, d.To, keywordParams(country)...),
+			},
+			fill: func(bodies [][]byte, d *models.Digest) error {
+				var prev, cur models.OrganicKeywordsResponse
+				if err := unmarshalBodies(bodies, &prev, &cur); err != nil {
+					return err
+				}
+				d.KeywordMovements = biggestMovements(compareKeywords(prev.Keywords, cur.Keywords), top)
+				return nil
+			},
+		},
+	}
+}
+
+// keywordParams are the parameters of the keywords compared for movements
+func keywordParams(country string) []string {
+	return []string{
+		"country", country,
+		"select", "keyword,position,volume,url",
+		"order_by", "traffic:desc",
+		"limit", strconv.Itoa(digestKeywords),
+	}
+}
+
+// collectDigest makes each section of d, spending at most what guard
+// allows. A section whose request or response fails is recorded in
+// d.Errors; the error returned is the first section's when every section
+// failed. It returns the units spent per endpoint.
+func collectDigest(ctx context.Context, c *client.Client, sections []digestSection, guard *spendGuard, d *models.Digest) (map[string]int, error) {
+	units := map[string]int{}
+	var first error
+	for _, s := range sections {
+		err := func() error {
+			var bodies [][]byte
+			for _, r := range s.requests {
+				if err := guard.check(d.UnitsConsumed); err != nil {
+					return err
+				}
+				resp, err := c.Get(ctx, r.endpoint, r.params)
+				if err != nil {
+					return err
+				}
+				units[r.endpoint] += resp.Meta.UnitsConsumed
+				d.UnitsConsumed += resp.Meta.UnitsConsumed
+				bodies = append(bodies, resp.Body)
+			}
+			return s.fill(bodies, d)
+		}()
+		if err != nil {
+			d.Errors = append(d.Errors, models.DigestError{Section: s.name, Error: err.Error()})
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	if len(d.Errors) == len(sections) {
+		return units, first
+	}
+	return units, nil
+}
+
+// unmarshalBodies decodes each body into the result at its index
+func unmarshalBodies(bodies [][]byte, results ...interface{}) error {
+	for i, result := range results {
+		if err := json.Unmarshal(bodies[i], result); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
+
+// metricChange returns the change of a metric from prev to cur
+func metricChange(prev, cur float64) *models.MetricChange {
+	m := &models.MetricChange{Previous: prev, Current: cur, Change: round2(cur - prev)}
+	if prev != 0 {
+		pct := round2((cur - prev) / prev * 100)
+		m.ChangePercent = &pct
+	}
+	return m
+}
+
+// biggestMovements returns the top keywords that moved up or down, biggest
+// moves first as compareKeywords sorts them
+func biggestMovements(moves []models.KeywordMovement, top int) []models.KeywordMovement {
+	kept := []models.KeywordMovement{}
+	for _, m := range moves {
+		if len(kept) == top {
+			break
+		}
+		if m.Movement == "up" || m.Movement == "down" {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// printDigestDryRun describes the requests a digest would send
+func printDigestDryRun(w io.Writer, c *client.Client, sections []digestSection) {
+	first := true
+	for _, s := range sections {
+		for _, r := range s.requests {
+			if first {
+				fmt.Fprintf(w, "✓ Valid request. Would call: GET %s\n", c.URL(r.endpoint, r.params))
+				first = false
+			} else {
+				fmt.Fprintf(w, "  And: GET %s\n", c.URL(r.endpoint, r.params))
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// digestReport is a digest written as Markdown by --format markdown
+type digestReport models.Digest
+
+// Markdown renders the digest as a heading, a line per metric, the new
+// backlinks and keyword movements as lists, and the units spent
+func (r digestReport) Markdown() string {
+	var b strings.Builder
+	failed := map[string]string{}
+	for _, e := range r.Errors {
+		failed[e.Section] = e.Error
+	}
+
+	fmt.Fprintf(&b, "## %s: %s to %s\n\n", r.Target, r.From, r.To)
+	for _, m := range []struct {
+		section, label string
+		change         *models.MetricChange
+	}{
+		{"domain_rating", "Domain rating", r.DomainRating},
+		{"refdomains", "Referring domains", r.RefDomains},
+		{"org_traffic", "Organic traffic", r.OrgTraffic},
+	} {
+		fmt.Fprintf(&b, "- **%s:** %s\n", m.label, describeChange(m.change, failed[m.section]))
+	}
+
+	b.WriteString("\n**New backlinks**\n")
+	switch {
+	case failed["new_backlinks"] != "":
+		fmt.Fprintf(&b, "_Unavailable: %s_\n", failed["new_backlinks"])
+	case len(r.NewBacklinks) == 0:
+		b.WriteString("_None_\n")
+	}
+	for i, l := range r.NewBacklinks {
+		fmt.Fprintf(&b, "%d. DR %s %s → %s", i+1, formatNumber(l.DomainRating), l.URLFrom, l.URLTo)
+		if l.Anchor != "" {
+			fmt.Fprintf(&b, " (%q)", l.Anchor)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n**Keyword movements**\n")
+	switch {
+	case failed["keyword_movements"] != "":
+		fmt.Fprintf(&b, "_Unavailable: %s_\n", failed["keyword_movements"])
+	case len(r.KeywordMovements) == 0:
+		b.WriteString("_None_\n")
+	}
+	for _, m := range r.KeywordMovements {
+		arrow := "↑"
+		if m.Movement == "down" {
+			arrow = "↓"
+		}
+		fmt.Fprintf(&b, "- %s %s: %d → %d (%+d)\n", arrow, m.Keyword, *m.PreviousPosition, *m.Position, *m.PositionDiff)
+	}
+
+	fmt.Fprintf(&b, "\n_%d units used_\n", r.UnitsConsumed)
+	return b.String()
+}
+
+// describeChange describes a metric's change, or why it is missing
+func describeChange(m *models.MetricChange, failure string) string {
+	if m == nil {
+		return "_unavailable: " + failure + "_"
+	}
+	s := fmt.Sprintf("%s → %s (%s", formatNumber(m.Previous), formatNumber(m.Current), signed(m.Change))
+	if m.ChangePercent != nil {
+		s += ", " + signed(*m.ChangePercent) + "%"
+	}
+	return s + ")"
+}
+
+// formatNumber formats f without trailing zeros
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// signed formats f with its sign, + for zero
+func signed(f float64) string {
+	if f < 0 {
+		return formatNumber(f)
+	}
+	return "+" + formatNumber(f)
+}
@@ -0,0 +1,186 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/spf13/pflag"
+)
+
+// execDigest runs the digest command with args against baseURL, and returns
+// its output
+func execDigest(t *testing.T, baseURL string, args ...string) (string, error) {
+	t.Helper()
+
+	c := NewDigestCmd()
+	cmd.AddCommands(c)
+	c.Root().PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if list, ok := f.Value.(pflag.SliceValue); ok {
+			_ = list.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	var buf bytes.Buffer
+	c.SetOut(&buf)
+	c.SetContext(context.Background())
+	args = append(args, "--api-key", "test-key", "--base-url", baseURL)
+	if err := cmd.Prepare(c, args); err != nil {
+		t.Fatalf("Prepare(%v) error = %v", args, err)
+	}
+	err := c.RunE(c, nil)
+	return buf.String(), err
+}
+
+// digestServer serves the responses of a digest from 2024-06-01 to
+// 2024-06-08, failing the endpoints in failing, at 5 units a request
+func digestServer(t *testing.T, failing ...string) *httptest.Server {
+	t.Helper()
+
+	bodies := map[string]string{
+		"/site-explorer/domain-rating 2024-06-01":    `{"domain_rating":{"domain_rating":70}}`,
+		"/site-explorer/domain-rating 2024-06-08":    `{"domain_rating":{"domain_rating":72}}`,
+		"/site-explorer/refdomains-history ":         `{"refdomains":[{"date":"2024-06-08","refdomains":1216},{"date":"2024-06-01","refdomains":1200}]}`,
+		"/site-explorer/metrics 2024-06-01":          `{"metrics":{"org_traffic":10000}}`,
+		"/site-explorer/metrics 2024-06-08":          `{"metrics":{"org_traffic":9500}}`,
+		"/site-explorer/backlinks ":                  `{"backlinks":[{"url_from":"https://news.example.org/a","url_to":"https://example.com/","domain_rating":88,"anchor":"example"}]}`,
+		"/site-explorer/organic-keywords 2024-06-01": `{"keywords":[{"keyword":"best crm","position":12,"url":"https://example.com/crm"},{"keyword":"crm app","position":3,"url":"https://example.com/app"},{"keyword":"crm","position":5,"url":"https://example.com/"}]}`,
+		"/site-explorer/organic-keywords 2024-06-08": `{"keywords":[{"keyword":"best crm","position":4,"url":"https://example.com/crm"},{"keyword":"crm app","position":9,"url":"https://example.com/app"},{"keyword":"crm","position":5,"url":"https://example.com/"}]}`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range failing {
+			if r.URL.Path == path {
+				http.Error(w, `{"error":"boom"}`, http.StatusBadRequest)
+				return
+			}
+		}
+		body, ok := bodies[r.URL.Path+" "+r.URL.Query().Get("date")]
+		if !ok {
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-API-Units-Consumed", "5")
+		w.Write([]byte(body))
+	}))
+}
+
+func TestDigest_JSON(t *testing.T) {
+	srv := digestServer(t)
+	defer srv.Close()
+
+	out, err := execDigest(t, srv.URL, "--target", "example.com", "--since", "2024-06-01", "--until", "2024-06-08")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	var resp struct {
+		Data models.Digest `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("output %q: %v", out, err)
+	}
+	d := resp.Data
+
+	if d.DomainRating == nil || d.DomainRating.Change != 2 {
+		t.Errorf("domain_rating = %+v, want a change of 2", d.DomainRating)
+	}
+	if d.RefDomains == nil || d.RefDomains.Previous != 1200 || d.RefDomains.Change != 16 {
+		t.Errorf("refdomains = %+v, want 1200 to 1216", d.RefDomains)
+	}
+	if d.OrgTraffic == nil || d.OrgTraffic.Change != -500 || *d.OrgTraffic.ChangePercent != -5 {
+		t.Errorf("org_traffic = %+v, want -500, -5%%", d.OrgTraffic)
+	}
+	if len(d.NewBacklinks) != 1 || d.NewBacklinks[0].DomainRating != 88 {
+		t.Errorf("new_backlinks = %+v", d.NewBacklinks)
+	}
+	var moved []string
+	for _, m := range d.KeywordMovements {
+		moved = append(moved, m.Keyword+" "+m.Movement)
+	}
+	if got, want := strings.Join(moved, ", "), "best crm up, crm app down"; got != want {
+		t.Errorf("keyword_movements = %s, want %s", got, want)
+	}
+	if d.UnitsConsumed != 40 || len(d.Errors) != 0 {
+		t.Errorf("units_consumed = %d, errors = %v, want 40 and none", d.UnitsConsumed, d.Errors)
+	}
+}
+
+func TestDigest_Markdown(t *testing.T) {
+	srv := digestServer(t, "/site-explorer/refdomains-history")
+	defer srv.Close()
+
+	out, err := execDigest(t, srv.URL, "--target", "example.com", "--since", "2024-06-01", "--until", "2024-06-08", "--format", "markdown")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	for _, want := range []string{
+		"## example.com: 2024-06-01 to 2024-06-08\n",
+		"- **Domain rating:** 70 → 72 (+2, +2.86%)\n",
+		"- **Referring domains:** _unavailable: ",
+		"- **Organic traffic:** 10000 → 9500 (-500, -5%)\n",
+		`1. DR 88 https://news.example.org/a → https://example.com/ ("example")` + "\n",
+		"- ↑ best crm: 12 → 4 (+8)\n- ↓ crm app: 3 → 9 (-6)\n",
+		"_35 units used_\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDigest_EverySectionFailing(t *testing.T) {
+	srv := digestServer(t, "/site-explorer/domain-rating", "/site-explorer/refdomains-history", "/site-explorer/metrics",
+		"/site-explorer/backlinks", "/site-explorer/organic-keywords")
+	defer srv.Close()
+
+	if _, err := execDigest(t, srv.URL, "--target", "example.com", "--since", "2024-06-01", "--until", "2024-06-08"); err == nil {
+		t.Fatal("error = nil, want the first section's")
+	}
+}
+
+func TestDigest_DryRun(t *testing.T) {
+	out, err := execDigest(t, "https://api.ahrefs.com/v3", "--target", "example.com", "--since", "2024-06-01", "--until", "2024-06-08", "--top", "5", "--dry-run")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	for _, want := range []string{
+		"/site-explorer/domain-rating?date=2024-06-01&mode=domain&target=example.com",
+		"/site-explorer/refdomains-history?date_from=2024-06-01&date_to=2024-06-08&mode=domain&target=example.com",
+		"history=since%3A2024-06-01&limit=5&mode=domain&order_by=domain_rating%3Adesc",
+		"/site-explorer/organic-keywords?country=us&date=2024-06-08&limit=1000",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dry run missing %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "GET "); n != 8 {
+		t.Errorf("dry run has %d requests, want 8", n)
+	}
+}
+
+func TestDigest_Usage(t *testing.T) {
+	for _, args := range [][]string{
+		{"--target", "example.com", "--since", "2024-06-08", "--until", "2024-06-01"},
+		{"--target", "example.com", "--since", "soon"},
+		{"--target", "example.com", "--top", "0"},
+		{"--target", "example.com", "--country", "zz"},
+		{"--target", "example.com/page"},
+		{"--target", "example.com", "--format", "csv"},
+	} {
+		_, err := execDigest(t, "http://127.0.0.1:1", args...)
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+			t.Errorf("%v: error = %v, want a usage error", args, err)
+		}
+	}
+}
@@ -0,0 +1,595 @@
+package siteexplorer
+
+import "github.com/aminemat/ahrefs-cli/pkg/models"
+
+// endpoints are the Site Explorer endpoints, one command each
+var endpoints = []endpoint{
+	{
+		Name:  "domain-rating",
+		Path:  "/site-explorer/domain-rating",
+		Short: "Get domain rating for a target",
+		Long: `Get the domain rating (DR) for a domain or URL.
+
+Domain Rating is a metric that shows the strength of a website's backlink profile
+on a logarithmic scale from 0 to 100, with the latter being the strongest.`,
+		Example: `  # Get domain rating for a domain
+  ahrefs site-explorer domain-rating --target example.com
+
+  # Get domain rating for a specific URL
+  ahrefs site-explorer domain-rating --target example.com/page --mode exact
+
+  # Get historical domain rating
+  ahrefs site-explorer domain-rating --target example.com --date 2024-01-01`,
+		Date:   true,
+		Rules:  []paramRule{validDate("date")},
+		Result: func() interface{} { return &models.DomainRatingResponse{} },
+	},
+	{
+		Name:  "backlinks",
+		Path:  "/site-explorer/backlinks",
+		Short: "Get backlinks for a target",
+		Long: `List backlinks pointing to a target domain or URL.
+
+--dofollow, --nofollow, --ugc, --sponsored, and --link-type are shortcuts for
+filters on each link's attributes, added to a structured --where; --dry-run
+shows the filter sent. A dofollow link has none of the other attributes, so
+--dofollow can't be combined with them.`,
+		Example: `  # Get backlinks for a domain
+  ahrefs site-explorer backlinks --target example.com --limit 100
+
+  # Get specific fields
+  ahrefs site-explorer backlinks --target example.com \
+    --select url_from,domain_rating,anchor --limit 50
+
+  # Filter backlinks
+  ahrefs site-explorer backlinks --target example.com \
+    --where 'domain_rating>50' --limit 100
+
+  # Dofollow text links only
+  ahrefs site-explorer backlinks --target example.com --dofollow --link-type text
+
+  # Backlinks first seen in the last 30 days
+  ahrefs site-explorer backlinks --target example.com --first-seen-since 30d
+
+  # Backlinks lost in June, with when and why
+  ahrefs site-explorer backlinks --target example.com --history lost \
+    --date-from 2024-06-01 --date-to 2024-06-30 --format csv
+
+  # Every backlink ever seen, lost ones included
+  ahrefs site-explorer backlinks --target example.com --history all_time --all
+
+  # One row per referring domain across every page
+  ahrefs site-explorer backlinks --target example.com --all --group-by-domain
+
+  # How many backlinks --all would fetch, and the units it would use
+  ahrefs site-explorer backlinks --target example.com --count-only
+
+  # The language of each anchor, for an international link audit
+  ahrefs site-explorer backlinks --target example.com --detect-language --format csv`,
+		List:           true,
+		MaxLimit:       1000,
+		OrderBy:        "domain_rating:desc",
+		LinkFilters:    backlinkFilters,
+		FirstSeen:      true,
+		History:        true,
+		GroupByDomain:  true,
+		DetectLanguage: true,
+		Count:          &rowCount{Path: "/site-explorer/backlinks-stats", Field: []string{"metrics", "live"}},
+		Result:         func() interface{} { return &models.BacklinksResponse{} },
+	},
+	{
+		Name:  "backlinks-stats",
+		Path:  "/site-explorer/backlinks-stats",
+		Short: "Get backlinks statistics",
+		Long:  "Get aggregated statistics about backlinks for a target.",
+		Example: `  # Get backlinks stats for a domain
+  ahrefs site-explorer backlinks-stats --target example.com
+
+  # Get stats for a specific URL
+  ahrefs site-explorer backlinks-stats --target example.com/page --mode exact`,
+		Date:   true,
+		Rules:  []paramRule{validDate("date")},
+		Result: func() interface{} { return &models.BacklinksStatsResponse{} },
+	},
+	{
+		Name:  "refdomains",
+		Path:  "/site-explorer/refdomains",
+		Short: "Get referring domains",
+		Long: `List referring domains that contain backlinks to the target.
+
+--dofollow keeps domains with at least one dofollow link to the target, and
+--nofollow those with none.
+
+--aggregate tld or registrable replaces the rows with one per top-level
+domain (edu, gov, uk) or registrable domain (example.co.uk for
+blog.example.co.uk), with its domains, their backlinks, and the highest
+domain rating among them. Only the rows fetched are aggregated, so combine it
+with --all for every referring domain.
+
+--resolve-ips adds the IPv4 address each domain resolves to (its A record)
+and the /24 subnet of it, looked up in DNS from this machine, so it uses no
+units. --group-by-subnet replaces the rows with one per subnet, most domains
+first, to spot domains sharing infrastructure, such as a link network on one
+host. Domains that don't resolve within --resolve-timeout are left without an
+address. Each domain is looked up once per run.
+
+--histogram counts the referring domains fetched in each range of ten domain
+rating points: 0-10, 11-20, and so on up to 91-100, with their percent of
+the domains. The histogram is written to stderr as a table with a bar per
+range, and included in JSON and YAML output. --histogram-only writes it in
+place of the domains, a row per range, with the bars in table output. Add
+--all to count every referring domain rather than the first page.`,
+		Example: `  # Get referring domains for a domain
+  ahrefs site-explorer refdomains --target example.com --limit 100
+
+  # Get specific fields
+  ahrefs site-explorer refdomains --target example.com \
+    --select domain,domain_rating,backlinks --limit 50
+
+  # Filter and sort by domain rating
+  ahrefs site-explorer refdomains --target example.com \
+    --where 'domain_rating>50' --order-by domain_rating:desc --limit 100
+
+  # Domains linking with at least one dofollow link
+  ahrefs site-explorer refdomains --target example.com --dofollow
+
+  # Just the number of referring domains
+  ahrefs site-explorer refdomains --target example.com --count-only --value count
+
+  # How many .edu and .gov domains link to the target
+  ahrefs site-explorer refdomains --target example.com --all --aggregate tld
+
+  # Referring domains sharing a /24 subnet, most domains first
+  ahrefs site-explorer refdomains --target example.com --all \
+    --group-by-subnet --format table
+
+  # How the referring domains spread across domain rating ranges
+  ahrefs site-explorer refdomains --target example.com --all \
+    --histogram-only --format table`,
+		List:        true,
+		Aggregate:   true,
+		ResolveIPs:  true,
+		DRHistogram: true,
+		MaxLimit:    1000,
+		OrderBy:     "domain_rating:desc",
+		LinkFilters: refdomainFilters,
+		Count:       &rowCount{Path: "/site-explorer/backlinks-stats", Field: []string{"metrics", "refdomains"}},
+		Result:      func() interface{} { return &models.RefDomainsResponse{} },
+	},
+	{
+		Name:  "anchors",
+		Path:  "/site-explorer/anchors",
+		Short: "Get anchor text distribution",
+		Long: `List anchor texts used in backlinks pointing to the target.
+
+--expand-domains N requests the top referring domains linking with each of
+the first N anchors, --expand-limit per anchor by domain rating, and nests
+them under the anchor in JSON and YAML output. CSV and table output have a
+row per anchor and domain instead. Each anchor costs a refdomains request;
+--concurrency bounds how many run at once, and with --enforce-budget no more
+are made once the budget is spent. --verbose reports each anchor's units.
+
+--summary adds a breakdown of the anchor texts fetched: their most common
+words, each weighted by the backlinks of the anchors using it, and how many
+anchors are empty or name an image. --brand terms split the anchors and their
+backlinks into branded and non-branded. --summary-only writes the breakdown
+in place of the anchors, a row per word in CSV and table output. Add --all to
+summarise every anchor rather than the first page.
+
+--normalize-text merges anchor texts that differ only in how they're
+written: composed and decomposed accents, curly and straight quotes,
+zero-width characters, and non-breaking or repeated spaces, and with
+--casefold, case. The merged row has the first anchor's text, normalized,
+the total backlinks, and a normalized_from count.`,
+		Example: `  # Get anchor texts for a domain
+  ahrefs site-explorer anchors --target example.com --limit 100
+
+  # Get anchor texts with backlink count
+  ahrefs site-explorer anchors --target example.com \
+    --select anchor,backlinks,refdomains --limit 50
+
+  # The top 5 referring domains of each of the top 10 anchors
+  ahrefs site-explorer anchors --target example.com --limit 10 \
+    --expand-domains 10 --expand-limit 5 --format csv
+
+  # Anchor texts with their language and its confidence
+  ahrefs site-explorer anchors --target example.com --detect-language
+
+  # The most common words of the anchor texts, and how many are branded
+  ahrefs site-explorer anchors --target example.com --all \
+    --summary-only --brand example,"example inc"
+
+  # One row per anchor text however it's written or capitalised
+  ahrefs site-explorer anchors --target example.com --all \
+    --normalize-text --casefold --format csv`,
+		List:           true,
+		MaxLimit:       1000,
+		OrderBy:        "backlinks:desc",
+		ExpandDomains:  true,
+		DetectLanguage: true,
+		AnchorSummary:  true,
+		NormalizeText:  []string{"anchor"},
+		Result:         func() interface{} { return &models.AnchorsResponse{} },
+	},
+	{
+		Name:  "organic-keywords",
+		Path:  "/site-explorer/organic-keywords",
+		Short: "Get organic keywords",
+		Long: `List organic keywords that the target ranks for in search engines.
+
+--movement requests the keywords for --date and for --date-compared
+separately and compares them on keyword and URL, like the Movements tab of
+the web UI. A keyword ranking with a different URL on each date is compared
+across the two, with the earlier URL as previous_url. Keywords ranking on one
+date only are new or lost; as keywords beyond --limit count as unranked, use
+--all for complete results.
+
+--serp-features keeps keywords whose results have any of the features
+listed, and --exclude-serp-features drops those with any of them. They are
+added to the request's filter, unless --where is in the text syntax: then
+rows are filtered after they are fetched, and fewer than --limit may be
+returned.
+
+--cluster groups the keywords for content planning: keywords whose best
+ranking URL is the same share a cluster, and with --cluster tokens so do
+keywords sharing at least half their words. Each row gets a cluster_id and
+a cluster_label, the cluster's keyword of the highest volume.
+--clusters-only writes a row per cluster instead, with its total volume and
+traffic. Only the rows fetched are clustered, so use --all for every
+keyword.`,
+		Example: `  # Get organic keywords for a domain
+  ahrefs site-explorer organic-keywords --target example.com --limit 100
+
+  # Get keywords for a specific country
+  ahrefs site-explorer organic-keywords --target example.com \
+    --country us --limit 50
+
+  # The top 50 keywords in each of three countries, tagged with the country
+  ahrefs site-explorer organic-keywords --target example.com \
+    --country us,gb,de --limit 50
+
+  # Get high-traffic keywords
+  ahrefs site-explorer organic-keywords --target example.com \
+    --where 'traffic>100' --order-by traffic:desc --limit 100
+
+  # Keywords with a featured snippet or People Also Ask box
+  ahrefs site-explorer organic-keywords --target example.com \
+    --serp-features featured_snippet,people_also_ask
+
+  # Keywords lost since the start of the month
+  ahrefs site-explorer organic-keywords --target example.com --country us \
+    --date 2024-06-30 --date-compared 2024-06-01 --movement lost --all
+
+  # Topic clusters of every keyword, with their total volume
+  ahrefs site-explorer organic-keywords --target example.com --country us \
+    --all --cluster tokens --clusters-only --format csv
+
+  # How many keywords the target ranks for in the US, before exporting them
+  ahrefs site-explorer organic-keywords --target example.com --country us --count-only`,
+		List:          true,
+		MaxLimit:      1000,
+		OrderBy:       "traffic:desc",
+		Country:       true,
+		Countries:     true,
+		Date:          true,
+		SERPFeatures:  true,
+		Movement:      true,
+		Cluster:       true,
+		NormalizeText: []string{"keyword", "url"},
+		Count:         &rowCount{Path: "/site-explorer/metrics", Field: []string{"metrics", "org_keywords"}},
+		Rules:         keywordRules,
+		Result:        func() interface{} { return &models.OrganicKeywordsResponse{} },
+	},
+	{
+		Name:  "page-keywords",
+		Path:  "/site-explorer/organic-keywords",
+		Short: "Get the organic keywords of a single page",
+		Long: `List the organic keywords a single page ranks for, with its position,
+the keyword's volume, the page's traffic, and the SERP features. This is
+organic-keywords for --url in exact mode, with those columns by default.
+
+--compare-url requests a competing page's keywords with the same flags and
+lists them alongside: the page's keywords get the competitor's position and
+traffic, and keywords only the competitor ranks for follow, marked
+competitor_only, with no position or traffic of the page's. Keywords beyond
+--limit count as unranked, so use --all for a complete gap.`,
+		Example: `  # Keywords of a blog post in the US
+  ahrefs site-explorer page-keywords --url https://example.com/post --country us
+
+  # Content gap: keywords a competing post ranks for that this one doesn't
+  ahrefs site-explorer page-keywords --url https://example.com/post --country us \
+    --compare-url https://competitor.com/post --all --format csv`,
+		List:          true,
+		MaxLimit:      1000,
+		OrderBy:       "traffic:desc",
+		Country:       true,
+		Date:          true,
+		PageURL:       true,
+		DefaultSelect: "keyword,position,volume,traffic,serp_features",
+		CompareURL:    true,
+		NormalizeText: []string{"keyword"},
+		Rules:         keywordRules,
+		Result:        func() interface{} { return &models.OrganicKeywordsResponse{} },
+	},
+	{
+		Name:  "top-pages",
+		Path:  "/site-explorer/top-pages",
+		Short: "Get top pages by organic traffic",
+		Long: `List pages that receive the most organic search traffic.
+
+The API may list a page more than once, under its http and https URLs or with
+and without www. or a trailing slash. --merge-url-variants merges those rows
+into the first listed, summing their traffic and keeping the highest URL
+rating, with a merged_from column counting the rows merged. Only the rows
+fetched are merged, so use --all to merge every page.`,
+		Example: `  # Get top pages for a domain
+  ahrefs site-explorer top-pages --target example.com --limit 100
+
+  # Get top pages in a specific country
+  ahrefs site-explorer top-pages --target example.com \
+    --country us --limit 50
+
+  # Get top pages with specific fields
+  ahrefs site-explorer top-pages --target example.com \
+    --select url,traffic,keywords --limit 100
+
+  # One row per page, whatever the URL variants listed
+  ahrefs site-explorer top-pages --target example.com --all --merge-url-variants`,
+		List:             true,
+		MaxLimit:         1000,
+		OrderBy:          "traffic:desc",
+		Country:          true,
+		MergeURLVariants: true,
+		Result:           func() interface{} { return &models.TopPagesResponse{} },
+	},
+	{
+		Name:  "organic-competitors",
+		Path:  competitorsPath,
+		Short: "Get organic search competitors",
+		Long:  "List domains competing with the target for the same organic keywords.",
+		Example: `  # Get organic competitors for a domain
+  ahrefs site-explorer organic-competitors --target example.com --country us
+
+  # Competitors sharing the most keywords
+  ahrefs site-explorer organic-competitors --target example.com \
+    --order-by keywords_common:desc --limit 20`,
+		List:     true,
+		MaxLimit: 1000,
+		OrderBy:  "keywords_common:desc",
+		Country:  true,
+		Date:     true,
+		Rules:    []paramRule{validDate("date")},
+		Result:   func() interface{} { return &models.OrganicCompetitorsResponse{} },
+	},
+	{
+		Name:  "traffic-share",
+		Path:  "/site-explorer/top-pages",
+		Short: "Get each page's or domain's share of organic traffic",
+		Long: `List the share of organic traffic of the target's top pages, or with
+--by domains of the target and its organic competitors, with entity, traffic,
+and percent columns. Percents are of the rows listed, largest first, and sum
+to 100.
+
+--by pages requests top-pages; --by domains requests organic-competitors and
+the target's own traffic from metrics, so it costs one more request than
+--dry-run shows. Rows come by traffic unless --order-by says otherwise.`,
+		Example: `  # How a site's traffic splits across its top 20 pages
+  ahrefs site-explorer traffic-share --target example.com --limit 20 --format table
+
+  # Who owns the organic traffic: the target and its top 10 competitors
+  ahrefs site-explorer traffic-share --target example.com --by domains \
+    --country us --limit 10 --format table`,
+		List:         true,
+		MaxLimit:     1000,
+		OrderBy:      "traffic:desc",
+		Country:      true,
+		TrafficShare: true,
+		Result:       func() interface{} { return &models.TrafficSharesResponse{} },
+	},
+	{
+		Name:  "broken-backlinks",
+		Path:  "/site-explorer/broken-backlinks",
+		Short: "Get broken backlinks",
+		Long:  "List backlinks pointing to non-existing pages (404 errors) on the target.",
+		Example: `  # Get broken backlinks for a domain
+  ahrefs site-explorer broken-backlinks --target example.com --limit 100
+
+  # Get broken backlinks sorted by domain rating
+  ahrefs site-explorer broken-backlinks --target example.com \
+    --order-by domain_rating:desc --limit 50`,
+		List:     true,
+		MaxLimit: 1000,
+		OrderBy:  "domain_rating:desc",
+		Result:   func() interface{} { return &models.BrokenBacklinksResponse{} },
+	},
+	{
+		Name:  "linked-domains",
+		Path:  "/site-explorer/linked-domains",
+		Short: "Get linked domains",
+		Long: `List domains that the target links out to.
+
+--exclude-own and --exclude-domain drop rows after they are fetched, so a page
+can return fewer than --limit rows. --exclude-own compares registrable domains,
+aware of suffixes such as co.uk: shop.example.co.uk and www.example.co.uk are
+the same site, but other.co.uk is not.`,
+		Example: `  # Get linked domains for a domain
+  ahrefs site-explorer linked-domains --target example.com --limit 100
+
+  # Filter by domain rating
+  ahrefs site-explorer linked-domains --target example.com \
+    --where 'domain_rating>50' --order-by domain_rating:desc --limit 50
+
+  # Leave out the target's own subdomains and a sister site
+  ahrefs site-explorer linked-domains --target shop.example.co.uk \
+    --exclude-own --exclude-domain example-group.com`,
+		List:           true,
+		MaxLimit:       1000,
+		OrderBy:        "domain_rating:desc",
+		ExcludeDomains: true,
+		Result:         func() interface{} { return &models.LinkedDomainsResponse{} },
+	},
+	{
+		Name:  "metrics",
+		Path:  "/site-explorer/metrics",
+		Short: "Get site metrics overview",
+		Long:  "Get organic and paid traffic metrics for a target.",
+		Example: `  # Get metrics for a domain
+  ahrefs site-explorer metrics --target example.com
+
+  # Get metrics for a specific country
+  ahrefs site-explorer metrics --target example.com --country us
+
+  # Compare markets: a row per country
+  ahrefs site-explorer metrics --target example.com --country us,gb,de --format table`,
+		Select:          true,
+		Country:         true,
+		Countries:       true,
+		Result:          func() interface{} { return &models.MetricsResponse{} },
+		CountriesResult: func() interface{} { return &models.CountryMetricsResponse{} },
+	},
+	{
+		Name:  "metrics-history",
+		Path:  "/site-explorer/metrics-history",
+		Short: "Get historical metrics",
+		Long: `Get historical organic and paid traffic metrics for a target.
+
+--interval asks the API for daily, weekly, or monthly rows, and keeps the
+last row of each interval should it return finer ones. JSON output states
+the interval in meta.`,
+		Example: `  # Get metrics history for a domain
+  ahrefs site-explorer metrics-history --target example.com
+
+  # Get metrics history for a specific date range
+  ahrefs site-explorer metrics-history --target example.com \
+    --date-from 2024-01-01 --date-to 2024-12-31
+
+  # Get metrics history for a specific country
+  ahrefs site-explorer metrics-history --target example.com --country us
+
+  # One row per month over several years
+  ahrefs site-explorer metrics-history --target example.com \
+    --date-from 2020-01-01 --interval monthly`,
+		Select:    true,
+		Country:   true,
+		DateRange: true,
+		Interval:  true,
+		Rules:     []paramRule{requireParam("date_from", "the history starts there"), validDate("date_from"), validDate("date_to"), datesInOrder("date_from", "date_to")},
+		Result:    func() interface{} { return &models.MetricsHistoryResponse{} },
+	},
+	{
+		Name:  "refdomains-history",
+		Path:  refDomainsHistoryPath,
+		Short: "Get the history of referring domains",
+		Long: `Get the number of referring domains of a target over time.
+
+--interval asks the API for daily, weekly, or monthly rows, and keeps the
+last row of each interval should it return finer ones.`,
+		Example: `  # Referring domains of a domain since the start of 2024
+  ahrefs site-explorer refdomains-history --target example.com --date-from 2024-01-01
+
+  # One row per month
+  ahrefs site-explorer refdomains-history --target example.com \
+    --date-from 2022-01-01 --interval monthly`,
+		DateRange: true,
+		Interval:  true,
+		Rules:     []paramRule{requireParam("date_from", "the history starts there"), validDate("date_from"), validDate("date_to"), datesInOrder("date_from", "date_to")},
+		Result:    func() interface{} { return &models.RefDomainsHistoryResponse{} },
+	},
+	{
+		Name:  "url-rating-history",
+		Path:  "/site-explorer/url-rating-history",
+		Short: "Get the history of a page's URL rating",
+		Long: `Get the URL rating of a page over time, a row per date.
+
+--target is the page's URL, requested in exact mode; a target that isn't a
+URL fails before any request is made. --interval asks the API for daily,
+weekly, or monthly rows, and keeps the last row of each interval should it
+return finer ones.`,
+		Example: `  # URL rating of a page since the start of 2024
+  ahrefs site-explorer url-rating-history --target https://example.com/blog/post \
+    --date-from 2024-01-01
+
+  # A monthly time series as CSV, for a spreadsheet
+  ahrefs site-explorer url-rating-history --target https://example.com/blog/post \
+    --date-from 2022-01-01 --interval monthly --format csv -o ur.csv`,
+		URLTarget: true,
+		DateRange: true,
+		Interval:  true,
+		Rules:     []paramRule{requireParam("date_from", "the history starts there"), validDate("date_from"), validDate("date_to"), datesInOrder("date_from", "date_to")},
+		Result:    func() interface{} { return &models.URLRatingHistoryResponse{} },
+	},
+	{
+		Name:  "link-velocity",
+		Path:  refDomainsHistoryPath,
+		Short: "Report the monthly growth of referring domains",
+		Long: `Report how fast a target gains or loses referring domains: for each of the
+last --months months, its referring domains at the end of the month, the net
+new domains since the month before, and their growth in percent.
+
+The monthly referring domains history is requested from the month before
+the first, and the rows are computed from it. A month without history has
+no values, nor has the growth of the month after it.
+
+The summary, logged to stderr and included in JSON and YAML output, has
+the net change over the period and its trend: growing or declining when
+the average monthly growth, the slope of the least squares line through
+the months, is more than 1% of the average referring domains either way,
+and flat otherwise.`,
+		Example: `  # Referring domains gained each month over the last year
+  ahrefs site-explorer link-velocity --target example.com --months 12
+
+  # The first half of 2024, as a table
+  ahrefs site-explorer link-velocity --target example.com --months 6 \
+    --date-to 2024-06-30 --format table`,
+		Velocity: true,
+		Rules:    []paramRule{validDate("date_to")},
+		Result:   func() interface{} { return &models.LinkVelocityResponse{} },
+	},
+	{
+		Name:  "pages-by-traffic",
+		Path:  "/site-explorer/pages-by-traffic",
+		Short: "Get pages sorted by traffic",
+		Long:  "List pages sorted by organic search traffic.",
+		Example: `  # Get pages by traffic for a domain
+  ahrefs site-explorer pages-by-traffic --target example.com --limit 100
+
+  # Get pages by traffic for a specific country
+  ahrefs site-explorer pages-by-traffic --target example.com \
+    --country us --limit 50`,
+		List:     true,
+		MaxLimit: 1000,
+		OrderBy:  "traffic:desc",
+		Country:  true,
+		Result:   func() interface{} { return &models.PagesByTrafficResponse{} },
+	},
+	{
+		Name:  "best-by-links",
+		Path:  "/site-explorer/best-by-links",
+		Short: "Get best pages by backlinks",
+		Long: `List pages sorted by the number of backlinks they receive.
+
+--merge-url-variants merges the rows of a page listed under URLs that differ
+only by scheme, www., trailing slash, or the host's case, summing their
+backlinks and traffic and keeping the highest URL rating and referring
+domains, with a merged_from column counting the rows merged.`,
+		Example: `  # Get best pages by links for a domain
+  ahrefs site-explorer best-by-links --target example.com --limit 100
+
+  # Get pages with most referring domains
+  ahrefs site-explorer best-by-links --target example.com \
+    --order-by refdomains:desc --limit 50
+
+  # Pages by links first seen in 2024, showing the filter sent
+  ahrefs site-explorer best-by-links --target example.com \
+    --first-seen-since 2024-01-01 --first-seen-until 2024-12-31 --dry-run
+
+  # Backlinks per page, merging http/https and trailing-slash variants
+  ahrefs site-explorer best-by-links --target example.com --merge-url-variants`,
+		List:             true,
+		MaxLimit:         1000,
+		OrderBy:          "backlinks:desc",
+		FirstSeen:        true,
+		MergeURLVariants: true,
+		Result:           func() interface{} { return &models.BestByLinksResponse{} },
+	},
+}
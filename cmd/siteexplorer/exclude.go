@@ -0,0 +1,86 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/internal/publicsuffix"
+)
+
+// domainExclusion leaves out the domains of the target's own site and of
+// known sister sites, for --exclude-own and --exclude-domain
+type domainExclusion struct {
+	own     string   // the target's registrable domain, with --exclude-own
+	domains []string // sister sites, each with its subdomains
+}
+
+// empty reports whether no domains are excluded
+func (x domainExclusion) empty() bool {
+	return x.own == "" && len(x.domains) == 0
+}
+
+// excludes reports whether domain is left out
+func (x domainExclusion) excludes(domain string) bool {
+	domain = hostName(domain)
+	if x.own != "" && publicsuffix.Registrable(domain) == x.own {
+		return true
+	}
+	for _, d := range x.domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterDomains is a transform dropping the excluded rows of every list in a
+// response, by their domain field
+func (x domainExclusion) filterDomains(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+	var resp interface{}
+	if _, err := decodeResponse(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if obj, ok := resp.(map[string]interface{}); ok {
+		for key, v := range obj {
+			rows, ok := v.([]interface{})
+			if !ok {
+				continue
+			}
+			kept := []interface{}{}
+			for _, row := range rows {
+				fields, _ := row.(map[string]interface{})
+				if domain, _ := fields["domain"].(string); domain == "" || !x.excludes(domain) {
+					kept = append(kept, row)
+				}
+			}
+			obj[key] = kept
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// hostName returns the host name of a target or domain, without a scheme,
+// port, path, or wildcard
+func hostName(target string) string {
+	host := strings.ToLower(strings.TrimSpace(target))
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "*."), ".")
+}
@@ -0,0 +1,83 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestHostName(t *testing.T) {
+	tests := map[string]string{
+		"example.com":                     "example.com",
+		"https://Blog.Example.com/page?x": "blog.example.com",
+		"example.co.uk:8080":              "example.co.uk",
+		"*.example.com/":                  "example.com",
+		"example.com.":                    "example.com",
+	}
+	for target, want := range tests {
+		if got := hostName(target); got != want {
+			t.Errorf("hostName(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestDomainExclusion_Excludes(t *testing.T) {
+	x := requestFlags{
+		target:         "https://shop.example.co.uk/",
+		excludeOwn:     true,
+		excludeDomains: []string{"Sister.com", " "},
+	}.exclusion()
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{"example.co.uk", true},
+		{"www.example.co.uk", true},
+		{"other.co.uk", false},
+		{"example.com", false},
+		{"sister.com", true},
+		{"blog.sister.com", true},
+		{"notsister.com", false},
+	}
+	for _, tt := range tests {
+		if got := x.excludes(tt.domain); got != tt.want {
+			t.Errorf("excludes(%q) = %v, want %v", tt.domain, got, tt.want)
+		}
+	}
+}
+
+func TestLinkedDomains_Exclude(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"linked_domains":[{"domain":"blog.example.co.uk"},{"domain":"news.co.uk"},{"domain":"sister.com"},{"domain":"example.com"}]}`)
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"linked-domains", "-t", "example.co.uk", "--exclude-own", "--exclude-domain", "sister.com", "--format", "json"})
+	var got struct {
+		Data models.LinkedDomainsResponse `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	want := []models.LinkedDomain{{Domain: "news.co.uk"}, {Domain: "example.com"}}
+	if !reflect.DeepEqual(got.Data.LinkedDomains, want) {
+		t.Errorf("linked domains = %+v, want %+v", got.Data.LinkedDomains, want)
+	}
+}
+
+func TestLinkedDomains_ExcludeOwnSuffix(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, err := execCommand(t, "http://127.0.0.1:0", []string{"linked-domains", "-t", "co.uk", "--exclude-own"})
+	if err == nil || !strings.Contains(err.Error(), "no registrable domain") {
+		t.Errorf("error = %v, want one about the registrable domain", err)
+	}
+}
@@ -0,0 +1,165 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// refdomainsPath is the endpoint anchors are expanded with
+const refdomainsPath = "/site-explorer/refdomains"
+
+// anchorExpansion lists the referring domains linking with the top anchors of
+// an anchors response, for --expand-domains
+type anchorExpansion struct {
+	anchors     int // how many anchors are expanded, from the first
+	domains     int // referring domains listed per anchor
+	concurrency int // most refdomains requests in flight
+
+	// flatten writes one row per anchor and domain, for tabular output
+	flatten bool
+
+	// log, when set, gets the units each anchor consumed at debug level
+	log *slog.Logger
+}
+
+// transform returns the transform expanding the anchors of target
+func (x anchorExpansion) transform(target, mode string) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		var resp models.AnchorsResponse
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		anchors := make([]models.AnchorDomains, len(resp.Anchors))
+		for i, a := range resp.Anchors {
+			anchors[i] = models.AnchorDomains{
+				Anchor:      a.Anchor,
+				Backlinks:   a.Backlinks,
+				Refdomains:  a.Refdomains,
+				FirstSeen:   a.FirstSeen,
+				LastVisited: a.LastVisited,
+			}
+		}
+		if err := x.expand(ctx, fetch, target, mode, anchors); err != nil {
+			return nil, err
+		}
+
+		var v interface{} = models.AnchorDomainsResponse{Anchors: anchors}
+		if x.flatten {
+			v = models.AnchorDomainRowsResponse{Rows: flattenAnchors(anchors)}
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// expand fills in the domains of the first anchors, requesting up to
+// x.concurrency at once. The first failure stops the others.
+func (x anchorExpansion) expand(ctx context.Context, fetch fetchFunc, target, mode string, anchors []models.AnchorDomains) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	n := min(x.anchors, len(anchors))
+	errs := make([]error, n)
+	sem := make(chan struct{}, max(x.concurrency, 1))
+	var wg sync.WaitGroup
+start:
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break start
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			domains, units, err := x.refdomains(ctx, fetch, target, mode, anchors[i].Anchor)
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			anchors[i].Domains = domains
+			if x.log != nil {
+				x.log.Debug("Expanded anchor", "anchor", anchors[i].Anchor, "domains", len(domains), "units", units)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Report the failure that stopped the others rather than their
+	// cancellation
+	var first error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// refdomains requests the top referring domains linking with anchor, and
+// returns them with the units consumed
+func (x anchorExpansion) refdomains(ctx context.Context, fetch fetchFunc, target, mode, anchor string) ([]models.RefDomain, int, error) {
+	params := url.Values{}
+	params.Set("target", target)
+	params.Set("mode", mode)
+	params.Set("limit", strconv.Itoa(x.domains))
+	params.Set("order_by", "domain_rating:desc")
+	params.Set("where", isCond("anchor", "eq", anchor).String())
+
+	body, meta, err := fetch(ctx, refdomainsPath, params, pageOptions{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("anchor %q: %w", anchor, err)
+	}
+	defer body.Close()
+
+	var resp models.RefDomainsResponse
+	if _, err := decodeResponse(body, &resp); err != nil {
+		return nil, meta.UnitsConsumed, fmt.Errorf("anchor %q: failed to parse response: %w", anchor, err)
+	}
+	return resp.RefDomains, meta.UnitsConsumed, nil
+}
+
+// flattenAnchors returns a row per anchor and referring domain, and a row
+// without a domain for anchors that have none
+func flattenAnchors(anchors []models.AnchorDomains) []models.AnchorDomainRow {
+	rows := []models.AnchorDomainRow{}
+	for _, a := range anchors {
+		row := models.AnchorDomainRow{Anchor: a.Anchor, Backlinks: a.Backlinks, Refdomains: a.Refdomains}
+		if len(a.Domains) == 0 {
+			rows = append(rows, row)
+			continue
+		}
+		for _, d := range a.Domains {
+			row.Domain = d.Domain
+			row.DomainRating = d.DomainRating
+			row.DomainLinks = d.Backlinks
+			row.FirstSeen = d.FirstSeen
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
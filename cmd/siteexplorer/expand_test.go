@@ -0,0 +1,181 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/logging"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+const anchorsBody = `{"anchors":[
+	{"anchor":"shoes","backlinks":40,"refdomains":9},
+	{"anchor":"\"best\" boots","backlinks":20,"refdomains":5},
+	{"anchor":"socks","backlinks":3,"refdomains":1}]}`
+
+// refdomainsFetch returns a fetchFunc answering refdomains requests with one
+// domain named after the anchor filtered on, and tracking the most requests
+// in flight at once
+func refdomainsFetch(t *testing.T, inFlight *int) fetchFunc {
+	var mu sync.Mutex
+	current := 0
+	return func(ctx context.Context, endpoint string, params url.Values, page pageOptions) (io.ReadCloser, client.ResponseMeta, error) {
+		mu.Lock()
+		current++
+		*inFlight = max(*inFlight, current)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		current--
+		mu.Unlock()
+
+		if endpoint != refdomainsPath {
+			t.Errorf("endpoint = %s, want %s", endpoint, refdomainsPath)
+		}
+		var where struct {
+			Field string        `json:"field"`
+			Is    []interface{} `json:"is"`
+		}
+		if err := json.Unmarshal([]byte(params.Get("where")), &where); err != nil {
+			t.Errorf("where = %s, not valid JSON: %v", params.Get("where"), err)
+		}
+		anchor := fmt.Sprint(where.Is[1])
+		body := fmt.Sprintf(`{"refdomains":[{"domain":%q,"domain_rating":50,"backlinks":2}]}`, strings.ReplaceAll(anchor, `"`, "")+".com")
+		return io.NopCloser(strings.NewReader(body)), client.ResponseMeta{UnitsConsumed: 7}, nil
+	}
+}
+
+func TestAnchorExpansion(t *testing.T) {
+	var log bytes.Buffer
+	x := anchorExpansion{anchors: 2, domains: 5, concurrency: 4, log: logging.New(&log, logging.FormatText, slog.LevelDebug)}
+	inFlight := 0
+
+	out, err := x.transform("t.com", "domain")(context.Background(), strings.NewReader(anchorsBody), refdomainsFetch(t, &inFlight))
+	if err != nil {
+		t.Fatalf("transform error = %v", err)
+	}
+	var got models.AnchorDomainsResponse
+	if err := json.NewDecoder(out).Decode(&got); err != nil {
+		t.Fatalf("invalid transformed body: %v", err)
+	}
+
+	want := []models.AnchorDomains{
+		{Anchor: "shoes", Backlinks: 40, Refdomains: 9, Domains: []models.RefDomain{{Domain: "shoes.com", DomainRating: 50, Backlinks: 2}}},
+		{Anchor: `"best" boots`, Backlinks: 20, Refdomains: 5, Domains: []models.RefDomain{{Domain: "best boots.com", DomainRating: 50, Backlinks: 2}}},
+		{Anchor: "socks", Backlinks: 3, Refdomains: 1},
+	}
+	if !reflect.DeepEqual(got.Anchors, want) {
+		t.Errorf("anchors = %+v, want %+v", got.Anchors, want)
+	}
+	if !strings.Contains(log.String(), "Expanded anchor anchor=shoes domains=1 units=7") || strings.Contains(log.String(), "socks") {
+		t.Errorf("log = %q, want the units of each expanded anchor", log.String())
+	}
+}
+
+func TestAnchorExpansion_Concurrency(t *testing.T) {
+	x := anchorExpansion{anchors: 3, domains: 1, concurrency: 2}
+	inFlight := 0
+	if _, err := x.transform("t.com", "domain")(context.Background(), strings.NewReader(anchorsBody), refdomainsFetch(t, &inFlight)); err != nil {
+		t.Fatalf("transform error = %v", err)
+	}
+	if inFlight != 2 {
+		t.Errorf("most requests in flight = %d, want 2", inFlight)
+	}
+}
+
+func TestAnchorExpansion_Flatten(t *testing.T) {
+	x := anchorExpansion{anchors: 1, domains: 5, concurrency: 1, flatten: true}
+	inFlight := 0
+	out, err := x.transform("t.com", "domain")(context.Background(), strings.NewReader(anchorsBody), refdomainsFetch(t, &inFlight))
+	if err != nil {
+		t.Fatalf("transform error = %v", err)
+	}
+	var got models.AnchorDomainRowsResponse
+	if err := json.NewDecoder(out).Decode(&got); err != nil {
+		t.Fatalf("invalid transformed body: %v", err)
+	}
+
+	want := []models.AnchorDomainRow{
+		{Anchor: "shoes", Backlinks: 40, Refdomains: 9, Domain: "shoes.com", DomainRating: 50, DomainLinks: 2},
+		{Anchor: `"best" boots`, Backlinks: 20, Refdomains: 5},
+		{Anchor: "socks", Backlinks: 3, Refdomains: 1},
+	}
+	if !reflect.DeepEqual(got.Rows, want) {
+		t.Errorf("rows = %+v, want %+v", got.Rows, want)
+	}
+}
+
+func TestAnchorExpansion_FailureStopsOthers(t *testing.T) {
+	budgetErr := errors.New("monthly budget exceeded")
+	calls := 0
+	fetch := func(ctx context.Context, endpoint string, params url.Values, page pageOptions) (io.ReadCloser, client.ResponseMeta, error) {
+		calls++
+		return nil, client.ResponseMeta{}, budgetErr
+	}
+
+	x := anchorExpansion{anchors: 3, domains: 1, concurrency: 1}
+	_, err := x.transform("t.com", "domain")(context.Background(), strings.NewReader(anchorsBody), fetch)
+	if !errors.Is(err, budgetErr) {
+		t.Fatalf("transform error = %v, want %v", err, budgetErr)
+	}
+	if calls != 1 {
+		t.Errorf("refdomains requests = %d, want 1", calls)
+	}
+}
+
+func TestRunRequest_ExpandDomains(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var paths []string
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/anchors") {
+			fmt.Fprint(w, `{"anchors":[{"anchor":"a","backlinks":1,"refdomains":1}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"refdomains":[{"domain":"x.com","domain_rating":10}]}`)
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"anchors", "-t", "t.com", "--expand-domains", "1", "--format", "csv"})
+	want := "anchor,backlinks,refdomains,domain,domain_rating,domain_backlinks,first_seen\na,1,1,x.com,10,0,\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if len(paths) != 2 || paths[1] != refdomainsPath {
+		t.Errorf("requested paths = %v, want anchors then refdomains", paths)
+	}
+}
+
+func TestSpendGuard(t *testing.T) {
+	var none *spendGuard
+	if err := none.check(1 << 20); err != nil {
+		t.Errorf("nil guard check() error = %v", err)
+	}
+
+	g := &spendGuard{budget: config.Budget{MonthlyUnits: 100}, used: 90}
+	if err := g.check(5); err != nil {
+		t.Errorf("check(5) error = %v, want nil", err)
+	}
+	if err := g.check(10); err == nil {
+		t.Error("check(10) error = nil, want the budget exceeded")
+	}
+}
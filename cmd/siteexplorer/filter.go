@@ -0,0 +1,313 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+)
+
+// Operators of a filterNode
+const (
+	opAnd    = "and"
+	opOr     = "or"
+	opNot    = "not"
+	opIs     = "is"      // a field compared with a value
+	opListIs = "list_is" // the items of a list field compared with a value
+	opWhere  = "where"   // a --where expression, sent as written
+)
+
+// filterNode is a node of a structured filter expression. --where and the
+// shortcuts that add to it, such as --dofollow, are combined as nodes, which
+// String renders in the API's JSON syntax, so --explain-filter can show how
+// the expression sent was built.
+type filterNode struct {
+	Op    string `json:"op"`
+	Field string `json:"field,omitempty"`
+
+	// Quantifier is any or all, for list_is
+	Quantifier string      `json:"quantifier,omitempty"`
+	Compare    string      `json:"compare,omitempty"`
+	Value      interface{} `json:"value,omitempty"`
+
+	// Nodes are the operands of and, or, and not. A where node has the
+	// expression's own nodes, when it is structured, for showing only.
+	Nodes []*filterNode `json:"nodes,omitempty"`
+
+	// Expr is the expression of a where node
+	Expr string `json:"expr,omitempty"`
+
+	// Flag is the flag the node comes from
+	Flag string `json:"flag,omitempty"`
+}
+
+// isCond returns the condition that field compares to value
+func isCond(field, compare string, value interface{}) *filterNode {
+	return &filterNode{Op: opIs, Field: field, Compare: compare, Value: value}
+}
+
+// listCond returns the condition that any or all of the items of the list
+// field compare to value
+func listCond(field, quantifier, compare string, value interface{}) *filterNode {
+	return &filterNode{Op: opListIs, Field: field, Quantifier: quantifier, Compare: compare, Value: value}
+}
+
+// whereExpr returns the node of a --where expression, or nil for none
+func whereExpr(where string) *filterNode {
+	if where == "" {
+		return nil
+	}
+	n := &filterNode{Op: opWhere, Expr: where, Flag: "--where"}
+	if parsed := parseFilter(where); parsed != nil {
+		n.Nodes = []*filterNode{parsed}
+	}
+	return n
+}
+
+// allOf returns the node matching every one of nodes, leaving out nils: nil
+// for none, and the node itself for one
+func allOf(nodes ...*filterNode) *filterNode {
+	return combine(opAnd, nodes)
+}
+
+// anyOf returns the node matching any of nodes, as allOf does
+func anyOf(nodes ...*filterNode) *filterNode {
+	return combine(opOr, nodes)
+}
+
+// combine joins nodes with op
+func combine(op string, nodes []*filterNode) *filterNode {
+	var kept []*filterNode
+	for _, n := range nodes {
+		if n != nil {
+			kept = append(kept, n)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return nil
+	case 1:
+		return kept[0]
+	}
+	return &filterNode{Op: op, Nodes: kept}
+}
+
+// not returns the node matching what n doesn't
+func not(n *filterNode) *filterNode {
+	return &filterNode{Op: opNot, Nodes: []*filterNode{n}}
+}
+
+// from returns a copy of n marked as coming from flag
+func (n *filterNode) from(flag string) *filterNode {
+	c := *n
+	c.Flag = flag
+	return &c
+}
+
+// String returns the expression n compiles into, "" for nil
+func (n *filterNode) String() string {
+	if n == nil {
+		return ""
+	}
+	switch n.Op {
+	case opAnd, opOr:
+		parts := make([]string, len(n.Nodes))
+		for i, node := range n.Nodes {
+			parts[i] = node.String()
+		}
+		return fmt.Sprintf(`{%s:[%s]}`, jsonValue(n.Op), strings.Join(parts, ","))
+	case opNot:
+		return fmt.Sprintf(`{"not":%s}`, n.Nodes[0])
+	case opIs:
+		return fmt.Sprintf(`{"field":%s,"is":[%s,%s]}`, jsonValue(n.Field), jsonValue(n.Compare), jsonValue(n.Value))
+	case opListIs:
+		return fmt.Sprintf(`{"field":%s,"list_is":{%s:[%s,%s]}}`, jsonValue(n.Field), jsonValue(n.Quantifier), jsonValue(n.Compare), jsonValue(n.Value))
+	}
+	return n.Expr
+}
+
+// jsonValue returns v encoded as JSON, leaving <, >, and & as they are
+func jsonValue(v interface{}) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "null"
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// parseFilter returns the nodes of a structured expression, or nil when it
+// isn't one or uses syntax the nodes don't cover
+func parseFilter(where string) *filterNode {
+	if !isStructured(where) {
+		return nil
+	}
+	dec := json.NewDecoder(strings.NewReader(where))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil
+	}
+	return parseNode(v)
+}
+
+// parseNode returns the node of a generically decoded expression, or nil
+func parseNode(v interface{}) *filterNode {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	switch {
+	case len(obj) == 1 && (obj[opAnd] != nil || obj[opOr] != nil):
+		op := opAnd
+		if obj[opOr] != nil {
+			op = opOr
+		}
+		items, _ := obj[op].([]interface{})
+		n := &filterNode{Op: op}
+		for _, item := range items {
+			child := parseNode(item)
+			if child == nil {
+				return nil
+			}
+			n.Nodes = append(n.Nodes, child)
+		}
+		return n
+	case len(obj) == 1 && obj[opNot] != nil:
+		if child := parseNode(obj[opNot]); child != nil {
+			return not(child)
+		}
+	case len(obj) == 2 && obj["is"] != nil:
+		field, _ := obj["field"].(string)
+		if compare, value, ok := comparison(obj["is"]); ok && field != "" {
+			return isCond(field, compare, value)
+		}
+	case len(obj) == 2 && obj[opListIs] != nil:
+		field, _ := obj["field"].(string)
+		list, _ := obj[opListIs].(map[string]interface{})
+		for quantifier, c := range list {
+			if compare, value, ok := comparison(c); ok && field != "" && len(list) == 1 {
+				return listCond(field, quantifier, compare, value)
+			}
+		}
+	}
+	return nil
+}
+
+// comparison returns the operator and value of a ["op", value] pair
+func comparison(v interface{}) (string, interface{}, bool) {
+	pair, _ := v.([]interface{})
+	if len(pair) != 2 {
+		return "", nil, false
+	}
+	compare, ok := pair[0].(string)
+	return compare, pair[1], ok
+}
+
+// requestFilter returns the filter of a request with the flag values f:
+// --where and the shortcuts added to it, in flag order. SERP features go in
+// only when --where is structured, as the text syntax can't be combined;
+// they are applied to the response instead.
+func (e endpoint) requestFilter(f requestFlags) (*filterNode, error) {
+	conds, err := f.links.conditions(e.LinkFilters)
+	if err != nil {
+		return nil, err
+	}
+	if f.firstSeenSince != "" || f.firstSeenUntil != "" {
+		dates, err := firstSeenConditions(f.firstSeenSince, f.firstSeenUntil, f.anchor())
+		if err != nil {
+			return nil, cmd.NewError(cmd.CodeUsage, err.Error(), "Use YYYY-MM-DD, today, or a time ago such as 30d, 6w, 3m, or 1y")
+		}
+		conds = append(conds, dates...)
+	}
+	_, window, err := historyRequest(f)
+	if err != nil {
+		return nil, err
+	}
+	conds = append(conds, window...)
+	if len(conds) > 0 && f.where != "" && !isStructured(f.where) {
+		return nil, cmd.NewError(cmd.CodeUsage, "filter shortcuts such as --dofollow and --first-seen-since need a structured --where",
+			`Write --where as JSON, e.g. {"field":"domain_rating","is":["gt",50]}`)
+	}
+	if filter := f.serpFilter(); !filter.empty() {
+		if err := filter.validate(); err != nil {
+			return nil, err
+		}
+		if !filter.clientSide(f.where) {
+			conds = append(conds, filter.conditions()...)
+		}
+	}
+	return allOf(append([]*filterNode{whereExpr(f.where)}, conds...)...), nil
+}
+
+// responseFilters describes the filters f applies to the response rather
+// than sending
+func (f requestFlags) responseFilters() []string {
+	var notes []string
+	if filter := f.serpFilter(); filter.clientSide(f.where) {
+		notes = append(notes, "--serp-features and --exclude-serp-features: keywords are filtered by their SERP features, as --where is in the text syntax")
+	}
+	if x := f.exclusion(); !x.empty() {
+		notes = append(notes, "--exclude-own and --exclude-domain: rows of the excluded domains are dropped")
+	}
+	return notes
+}
+
+// filterExplanation is what --explain-filter reports
+type filterExplanation struct {
+	Filter   *filterNode `json:"filter"`
+	Where    string      `json:"where"`
+	Response []string    `json:"applied_to_response,omitempty"`
+}
+
+// explainFilter writes how the filter of a request was built: its nodes, the
+// expression sent, and the filters applied to the response instead. With format
+// json it is a single JSON object, for log pipelines.
+func explainFilter(w io.Writer, format string, filter *filterNode, sent string, response []string) {
+	x := filterExplanation{Filter: filter, Where: sent, Response: response}
+	if format == "json" {
+		line, _ := json.Marshal(x)
+		fmt.Fprintln(w, string(line))
+		return
+	}
+
+	if filter == nil {
+		fmt.Fprintln(w, "Filter: none")
+	} else {
+		fmt.Fprintln(w, "Filter:")
+		filter.explain(w, "  ")
+		fmt.Fprintf(w, "Sent: where=%s\n", x.Where)
+	}
+	for _, note := range response {
+		fmt.Fprintf(w, "Applied to the response: %s\n", note)
+	}
+}
+
+// explain writes n and its nodes as a tree, a line each, indented by indent
+func (n *filterNode) explain(w io.Writer, indent string) {
+	var line string
+	switch n.Op {
+	case opIs:
+		line = fmt.Sprintf("%s %s %s", n.Field, n.Compare, jsonValue(n.Value))
+	case opListIs:
+		line = fmt.Sprintf("%s %s item %s %s", n.Field, n.Quantifier, n.Compare, jsonValue(n.Value))
+	case opWhere:
+		line = "where, sent as written: " + n.Expr
+		if len(n.Nodes) == 0 && !isStructured(n.Expr) {
+			line += " (text syntax)"
+		}
+	default:
+		line = n.Op
+	}
+	if n.Flag != "" {
+		line += " ← " + n.Flag
+	}
+	fmt.Fprintln(w, indent+line)
+	for _, node := range n.Nodes {
+		node.explain(w, indent+"  ")
+	}
+}
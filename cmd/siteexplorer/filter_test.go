@@ -0,0 +1,179 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// filterStrings returns the expressions of nodes, nil for none
+func filterStrings(nodes []*filterNode) []string {
+	var s []string
+	for _, n := range nodes {
+		s = append(s, n.String())
+	}
+	return s
+}
+
+func TestAllOf(t *testing.T) {
+	a, b := isCond("a", "eq", 1), isCond("b", "eq", 2)
+	const dr = `{"field":"domain_rating","is":["gt",50]}`
+
+	tests := []struct {
+		where string
+		conds []*filterNode
+		want  string
+	}{
+		{want: ""},
+		{where: dr, want: dr},
+		{conds: []*filterNode{a}, want: `{"field":"a","is":["eq",1]}`},
+		{conds: []*filterNode{a, nil, b}, want: `{"and":[{"field":"a","is":["eq",1]},{"field":"b","is":["eq",2]}]}`},
+		{where: dr, conds: []*filterNode{a}, want: `{"and":[` + dr + `,{"field":"a","is":["eq",1]}]}`},
+	}
+	for _, tt := range tests {
+		got := allOf(append([]*filterNode{whereExpr(tt.where)}, tt.conds...)...).String()
+		if got != tt.want {
+			t.Errorf("allOf(%q, %v) = %s, want %s", tt.where, filterStrings(tt.conds), got, tt.want)
+		}
+	}
+}
+
+func TestFilter_Precedence(t *testing.T) {
+	// An or in --where stays an operand of the and the shortcuts join
+	const where = `{"or":[{"field":"domain_rating","is":["gt",50]},{"field":"url_rating","is":["gt",30]}]}`
+	set := true
+	f := requestFlags{
+		where:          where,
+		links:          linkFlags{attrs: map[string]*bool{"dofollow": &set}},
+		firstSeenSince: "2024-03-01",
+		serpFeatures:   []string{"video", "image_pack"},
+	}
+	got, err := endpoint{LinkFilters: backlinkFilters}.requestFilter(f)
+	if err != nil {
+		t.Fatalf("requestFilter() error = %v", err)
+	}
+
+	want := `{"and":[` + where +
+		`,{"field":"is_dofollow","is":["eq",true]}` +
+		`,{"field":"first_seen","is":["gte","2024-03-01"]}` +
+		`,{"or":[{"field":"serp_features","list_is":{"any":["eq","video"]}},{"field":"serp_features","list_is":{"any":["eq","image_pack"]}}]}]}`
+	if got.String() != want {
+		t.Errorf("requestFilter() = %s, want %s", got, want)
+	}
+	if got.Op != opAnd || len(got.Nodes) != 4 {
+		t.Fatalf("requestFilter() = %+v, want an and of 4 nodes", got)
+	}
+	for i, flag := range []string{"--where", "--dofollow", "--first-seen-since", "--serp-features"} {
+		if got.Nodes[i].Flag != flag {
+			t.Errorf("node %d flag = %q, want %q", i, got.Nodes[i].Flag, flag)
+		}
+	}
+	if parsed := got.Nodes[0].Nodes; len(parsed) != 1 || parsed[0].Op != opOr || len(parsed[0].Nodes) != 2 {
+		t.Errorf("--where nodes = %+v, want its or of 2 conditions", parsed)
+	}
+}
+
+func TestFilter_Escaping(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`say "hi"`, `{"field":"anchor","is":["eq","say \"hi\""]}`},
+		{`C:\path`, `{"field":"anchor","is":["eq","C:\\path"]}`},
+		{"<b>&amp;</b>", `{"field":"anchor","is":["eq","<b>&amp;</b>"]}`},
+		{"café ☕", `{"field":"anchor","is":["eq","café ☕"]}`},
+		{"tab\there", `{"field":"anchor","is":["eq","tab\there"]}`},
+	}
+	for _, tt := range tests {
+		got := isCond("anchor", "eq", tt.value).String()
+		if got != tt.want {
+			t.Errorf("isCond(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(got), &v); err != nil {
+			t.Errorf("isCond(%q) = %s, not valid JSON: %v", tt.value, got, err)
+		}
+	}
+}
+
+func TestWhereExpr(t *testing.T) {
+	tests := []struct {
+		where  string
+		parsed bool
+	}{
+		{`{"field":"traffic","is":["gt",100]}`, true},
+		{`{"not":{"field":"serp_features","list_is":{"all":["eq","video"]}}}`, true},
+		{`{"and":[{"field":"a","is":["eq",1]},{"or":[{"field":"b","is":["eq",2]}]}]}`, true},
+		{`{"field":"traffic","is":["gt",100],"extra":1}`, false},
+		{`{"and":[`, false},
+		{"traffic>100", false},
+	}
+	for _, tt := range tests {
+		n := whereExpr(tt.where)
+		if n.String() != tt.where {
+			t.Errorf("whereExpr(%q) = %s, want it sent as written", tt.where, n)
+		}
+		if parsed := len(n.Nodes) == 1; parsed != tt.parsed {
+			t.Errorf("whereExpr(%q) parsed = %v, want %v", tt.where, parsed, tt.parsed)
+		}
+	}
+	if n := whereExpr(""); n != nil {
+		t.Errorf("whereExpr(\"\") = %+v, want nil", n)
+	}
+}
+
+func TestExplainFilter(t *testing.T) {
+	set := true
+	f := requestFlags{
+		where: `{"field":"traffic","is":["gt",100]}`,
+		links: linkFlags{attrs: map[string]*bool{"nofollow": &set}, linkType: "image"},
+	}
+	filter, err := endpoint{LinkFilters: backlinkFilters}.requestFilter(f)
+	if err != nil {
+		t.Fatalf("requestFilter() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	explainFilter(&buf, "text", filter, filter.String(), nil)
+	want := `Filter:
+  and
+    where, sent as written: {"field":"traffic","is":["gt",100]} ← --where
+      traffic gt 100
+    is_nofollow eq true ← --nofollow
+    is_image eq true ← --link-type image
+Sent: where={"and":[{"field":"traffic","is":["gt",100]},{"field":"is_nofollow","is":["eq",true]},{"field":"is_image","is":["eq",true]}]}
+`
+	if buf.String() != want {
+		t.Errorf("explainFilter() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	explainFilter(&buf, "json", filter, filter.String(), []string{"rows of the excluded domains are dropped"})
+	var x filterExplanation
+	if err := json.Unmarshal(buf.Bytes(), &x); err != nil {
+		t.Fatalf("explainFilter() = %s, not JSON: %v", buf.String(), err)
+	}
+	if x.Where != filter.String() || x.Filter.Op != opAnd || len(x.Filter.Nodes) != 3 || len(x.Response) != 1 {
+		t.Errorf("explainFilter() = %+v", x)
+	}
+}
+
+func TestExplainFilter_ClientSide(t *testing.T) {
+	f := requestFlags{where: "traffic>100", serpFeatures: []string{"video"}}
+	filter, err := endpoint{}.requestFilter(f)
+	if err != nil {
+		t.Fatalf("requestFilter() error = %v", err)
+	}
+	if filter.String() != "traffic>100" {
+		t.Errorf("requestFilter() = %s, want --where alone", filter)
+	}
+
+	var buf bytes.Buffer
+	explainFilter(&buf, "text", filter, filter.String(), f.responseFilters())
+	for _, want := range []string{"(text syntax) ← --where", "Sent: where=traffic>100", "Applied to the response: --serp-features"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("explainFilter() = %q, want %q in it", buf.String(), want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// Shorthands shared by all site-explorer commands. Root persistent flags
+// already use -o, -v, and -q, so these must not reuse them.
+const (
+	shorthandTarget  = "t"
+	shorthandMode    = "m"
+	shorthandLimit   = "l"
+	shorthandCountry = "c"
+)
+
+// addTargetFlag registers the required --target/-t flag
+func addTargetFlag(c *cobra.Command, target *string) {
+	c.Flags().StringVarP(target, "target", shorthandTarget, "", "Target domain or URL (required)")
+	c.MarkFlagRequired("target")
+}
+
+// addModeFlag registers the --mode/-m flag, defaulting to def
+func addModeFlag(c *cobra.Command, mode *string, def models.Mode) {
+	c.Flags().StringVarP(mode, "mode", shorthandMode, def.String(), "Mode: exact, domain, prefix, subdomains")
+	cmd.SetAllowedValues(c, "mode", models.Strings(models.Modes())...)
+}
+
+// addLimitFlag registers the --limit/-l flag
+func addLimitFlag(c *cobra.Command, limit *int) {
+	c.Flags().IntVarP(limit, "limit", shorthandLimit, 100, "Maximum number of results")
+}
+
+// addCountryFlag registers the --country/-c flag
+func addCountryFlag(c *cobra.Command, country *string) {
+	c.Flags().StringVarP(country, "country", shorthandCountry, "", "Country code (e.g., us, gb, de)")
+	cmd.BindEnv(c.Flags(), "country", "AHREFS_COUNTRY")
+	cmd.SetSuggestedValues(c, "country", models.Strings(models.Countries())...)
+}
+
+// addCountriesFlag registers --country/-c taking a comma-separated list
+func addCountriesFlag(c *cobra.Command, country *string) {
+	addCountryFlag(c, country)
+	c.Flags().Lookup("country").Usage = "Country code, or a comma-separated list to request each (e.g., us or us,gb,de)"
+	cmd.SetAllowedListValues(c, "country", models.Strings(models.Countries())...)
+}
+
+// addPageFlags registers --all, --max-rows, --cursor, and --resume for list
+// endpoints
+func addPageFlags(c *cobra.Command, page *pageOptions) {
+	c.Flags().BoolVar(&page.All, "all", false, "Fetch every page, following continuation tokens or falling back to --offset paging")
+	c.Flags().IntVar(&page.MaxRows, "max-rows", 0, "Stop --all once this many rows have been fetched, bounding its cost; 0 for every page")
+	c.Flags().StringVar(&page.Cursor, "cursor", "", "Continuation token to resume paging from")
+	c.Flags().BoolVar(&page.Resume, "resume", false, "Continue an interrupted export of every page from its last completed page, keeping the rows already fetched (implies --all)")
+	c.MarkFlagsMutuallyExclusive("cursor", "resume")
+}
+
+// addWhereFlags registers --where and --where-file, which both fill where
+func addWhereFlags(c *cobra.Command, where *string) {
+	c.Flags().StringVar(where, "where", "", "Filter expression (Ahrefs filter syntax)")
+	c.Flags().Var(&whereFile{where: where, stdin: os.Stdin}, "where-file", "Read the filter expression from a file, or - for stdin")
+	c.MarkFlagsMutuallyExclusive("where", "where-file")
+}
+
+// whereFile is a flag value that loads a filter expression from a file
+type whereFile struct {
+	path  string
+	where *string
+	stdin io.Reader
+}
+
+func (f *whereFile) String() string { return f.path }
+
+func (f *whereFile) Type() string { return "path" }
+
+func (f *whereFile) Set(path string) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(f.stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read filter expression: %w", err)
+	}
+
+	expr := strings.TrimSpace(string(data))
+	if expr == "" {
+		return fmt.Errorf("filter expression in %s is empty", path)
+	}
+	// Structured filters are JSON; catch mangled ones before they reach the API
+	if strings.HasPrefix(expr, "{") || strings.HasPrefix(expr, "[") {
+		var v interface{}
+		if err := json.Unmarshal([]byte(expr), &v); err != nil {
+			return fmt.Errorf("filter expression in %s is not valid JSON: %w", path, err)
+		}
+	}
+
+	f.path = path
+	*f.where = expr
+	return nil
+}
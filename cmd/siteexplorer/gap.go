@@ -0,0 +1,99 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// gapColumns are the columns compareURL adds to the page's keywords
+var gapColumns = []string{"competitor_position", "competitor_traffic", "competitor_only"}
+
+// compareURL returns a transform that lists the organic keywords in a
+// response alongside those of the competing page, fetched with the same
+// parameters and paging. The page's keywords come first, with the
+// competitor's position and traffic; keywords only the competitor ranks for
+// follow, marked competitor_only.
+func compareURL(endpoint string, params url.Values, page pageOptions, competitor string) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		ours, err := decodeKeywordRows(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		theirParams := cloneValues(params)
+		theirParams.Set("target", competitor)
+		theirBody, _, err := fetch(ctx, endpoint, theirParams, page)
+		if err != nil {
+			return nil, fmt.Errorf("--compare-url: %w", err)
+		}
+		defer theirBody.Close()
+		theirs, err := decodeKeywordRows(theirBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse response for %s: %w", competitor, err)
+		}
+
+		data, err := json.Marshal(map[string]interface{}{"keywords": keywordGaps(ours, theirs)})
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// decodeKeywordRows decodes the keywords of an organic keywords response
+// generically, keeping the columns --select asked for
+func decodeKeywordRows(body io.Reader) ([]map[string]interface{}, error) {
+	var resp struct {
+		Keywords []map[string]interface{} `json:"keywords"`
+	}
+	if _, err := decodeResponse(body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Keywords, nil
+}
+
+// keywordGaps pairs the rows of each keyword, matched regardless of case,
+// and appends the competitor's unpaired rows as competitor_only
+func keywordGaps(ours, theirs []map[string]interface{}) []map[string]interface{} {
+	theirIndex := map[string]map[string]interface{}{}
+	for _, row := range theirs {
+		if key := keywordKey(row); theirIndex[key] == nil {
+			theirIndex[key] = row
+		}
+	}
+
+	rows := []map[string]interface{}{}
+	ranked := map[string]bool{}
+	for _, row := range ours {
+		key := keywordKey(row)
+		ranked[key] = true
+		their := theirIndex[key]
+		row["competitor_position"] = their["position"]
+		row["competitor_traffic"] = their["traffic"]
+		row["competitor_only"] = false
+		rows = append(rows, row)
+	}
+	for _, row := range theirs {
+		key := keywordKey(row)
+		if ranked[key] {
+			continue
+		}
+		ranked[key] = true
+		row["competitor_position"], row["position"] = row["position"], nil
+		row["competitor_traffic"], row["traffic"] = row["traffic"], nil
+		row["competitor_only"] = true
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// keywordKey returns the key rows of the same keyword are matched on
+func keywordKey(row map[string]interface{}) string {
+	keyword, _ := row["keyword"].(string)
+	return strings.ToLower(strings.TrimSpace(keyword))
+}
@@ -0,0 +1,84 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeywordGaps(t *testing.T) {
+	ours := []map[string]interface{}{
+		{"keyword": "crm tips", "position": 3.0, "traffic": 40.0},
+		{"keyword": "best crm", "position": 8.0, "traffic": 10.0},
+	}
+	theirs := []map[string]interface{}{
+		{"keyword": "Best CRM", "position": 2.0, "traffic": 90.0},
+		{"keyword": "crm pricing", "position": 5.0, "traffic": 30.0, "volume": 700.0},
+	}
+
+	got := keywordGaps(ours, theirs)
+	want := []map[string]interface{}{
+		{"keyword": "crm tips", "position": 3.0, "traffic": 40.0, "competitor_position": nil, "competitor_traffic": nil, "competitor_only": false},
+		{"keyword": "best crm", "position": 8.0, "traffic": 10.0, "competitor_position": 2.0, "competitor_traffic": 90.0, "competitor_only": false},
+		{"keyword": "crm pricing", "position": nil, "traffic": nil, "volume": 700.0, "competitor_position": 5.0, "competitor_traffic": 30.0, "competitor_only": true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("keywordGaps() = %v, want %v", got, want)
+	}
+}
+
+func TestPageKeywords_CompareURL(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		requests = append(requests, q.Get("mode")+" "+q.Get("target")+" "+q.Get("select"))
+		w.Header().Set("Content-Type", "application/json")
+		if q.Get("target") == "https://a.com/post" {
+			fmt.Fprint(w, `{"keywords":[{"keyword":"best crm","position":4,"volume":900,"traffic":50,"serp_features":["video"]}]}`)
+		} else {
+			fmt.Fprint(w, `{"keywords":[{"keyword":"best crm","position":1,"volume":900,"traffic":300},{"keyword":"crm pricing","position":6,"volume":400,"traffic":20}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"page-keywords", "--url", "https://a.com/post", "--compare-url", "https://b.com/post", "--format", "csv"})
+
+	const sel = "exact %s keyword,position,volume,traffic,serp_features"
+	wantRequests := []string{fmt.Sprintf(sel, "https://a.com/post"), fmt.Sprintf(sel, "https://b.com/post")}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("requests = %q, want %q", requests, wantRequests)
+	}
+	want := strings.Join([]string{
+		"keyword,position,volume,traffic,serp_features,competitor_position,competitor_traffic,competitor_only",
+		"best crm,4,900,50,video,1,300,false",
+		"crm pricing,,400,,,6,20,true",
+	}, "\n") + "\n"
+	if out != want {
+		t.Errorf("output =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestPageKeywords_JSON(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"keywords":[{"keyword":"best crm","position":4,"traffic":50}]}`)
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"page-keywords", "--url", "https://a.com/post", "--format", "json"})
+	var got struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if _, ok := got.Data["keywords"]; !ok {
+		t.Errorf("output = %s, want the page's keywords", out)
+	}
+}
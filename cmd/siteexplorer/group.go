@@ -0,0 +1,93 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// maxGroupAnchors is how many distinct anchors a grouped row lists before
+// summarizing the rest as a count
+const maxGroupAnchors = 10
+
+// groupBacklinks replaces the backlinks in a response body with one row per
+// referring domain
+func groupBacklinks(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+	var resp models.BacklinksResponse
+	if _, err := decodeResponse(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	data, err := json.Marshal(models.BacklinkDomainsResponse{Domains: groupByDomain(resp.Backlinks)})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// groupByDomain aggregates links by the domain of their referring page, most
+// links first
+func groupByDomain(links []models.Backlink) []models.BacklinkDomain {
+	type group struct {
+		models.BacklinkDomain
+		dofollow int
+		anchors  map[string]bool
+	}
+
+	groups := map[string]*group{}
+	var order []*group
+	for _, l := range links {
+		domain := referringDomain(l.URLFrom)
+		g, ok := groups[domain]
+		if !ok {
+			g = &group{BacklinkDomain: models.BacklinkDomain{Domain: domain}, anchors: map[string]bool{}}
+			groups[domain] = g
+			order = append(order, g)
+		}
+
+		g.Links++
+		g.BestDomainRating = max(g.BestDomainRating, l.DomainRating)
+		if l.IsDofollow {
+			g.dofollow++
+		}
+		if l.FirstSeen != "" && (g.FirstSeen == "" || l.FirstSeen < g.FirstSeen) {
+			g.FirstSeen = l.FirstSeen
+		}
+		if !g.anchors[l.Anchor] {
+			g.anchors[l.Anchor] = true
+			if len(g.Anchors) < maxGroupAnchors {
+				g.Anchors = append(g.Anchors, l.Anchor)
+			}
+		}
+	}
+
+	domains := make([]models.BacklinkDomain, 0, len(order))
+	for _, g := range order {
+		if extra := len(g.anchors) - len(g.Anchors); extra > 0 {
+			g.Anchors = append(g.Anchors, fmt.Sprintf("(+%d more)", extra))
+		}
+		g.DofollowShare = math.Round(float64(g.dofollow)/float64(g.Links)*100) / 100
+		domains = append(domains, g.BacklinkDomain)
+	}
+	sort.SliceStable(domains, func(i, j int) bool {
+		return domains[i].Links > domains[j].Links
+	})
+	return domains
+}
+
+// referringDomain returns the host of rawURL without a leading www.
+func referringDomain(rawURL string) string {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
@@ -0,0 +1,113 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+)
+
+func TestGroupByDomain(t *testing.T) {
+	links := []models.Backlink{
+		{URLFrom: "https://blog.example.org/a", DomainRating: 40, Anchor: "x", IsDofollow: true, FirstSeen: "2024-03-01"},
+		{URLFrom: "https://www.other.com/", DomainRating: 70, Anchor: "home", FirstSeen: "2023-01-01"},
+		{URLFrom: "https://blog.example.org/b", DomainRating: 45, Anchor: "y", FirstSeen: "2024-01-15"},
+		{URLFrom: "https://blog.example.org/c", DomainRating: 42, Anchor: "x", IsDofollow: true},
+	}
+
+	want := []models.BacklinkDomain{
+		{Domain: "blog.example.org", Links: 3, BestDomainRating: 45, Anchors: models.StringList{"x", "y"}, DofollowShare: 0.67, FirstSeen: "2024-01-15"},
+		{Domain: "other.com", Links: 1, BestDomainRating: 70, Anchors: models.StringList{"home"}, DofollowShare: 0, FirstSeen: "2023-01-01"},
+	}
+	if got := groupByDomain(links); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupByDomain() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGroupByDomain_AnchorCap(t *testing.T) {
+	var links []models.Backlink
+	for i := 0; i < maxGroupAnchors+3; i++ {
+		links = append(links, models.Backlink{URLFrom: "https://example.com/" + strconv.Itoa(i), Anchor: "anchor " + strconv.Itoa(i)})
+	}
+
+	got := groupByDomain(links)[0].Anchors
+	if len(got) != maxGroupAnchors+1 || got[maxGroupAnchors] != "(+3 more)" {
+		t.Errorf("Anchors = %v, want %d anchors then (+3 more)", got, maxGroupAnchors)
+	}
+}
+
+func TestGroupBacklinks_AllPages(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// Two pages of backlinks from the same domain, paged by offset
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		var rows []string
+		for i := offset; i < 3 && i < offset+2; i++ {
+			rows = append(rows, fmt.Sprintf(`{"url_from":"https://example.com/%d","url_to":"https://target.com/","domain_rating":%d,"anchor":"a%d","is_dofollow":true}`, i, 10+i, i))
+		}
+		fmt.Fprintf(w, `{"backlinks":[%s]}`, strings.Join(rows, ","))
+	}))
+	defer srv.Close()
+	c := client.NewClient(client.Config{APIKey: "test-key", BaseURL: srv.URL})
+
+	params := url.Values{"target": {"target.com"}, "limit": {"2"}}
+	body, meta, err := fetch(context.Background(), c, "/site-explorer/backlinks", params, pageOptions{All: true}, nil)
+	if err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+	grouped, err := groupBacklinks(context.Background(), body, nil)
+	if err != nil {
+		t.Fatalf("groupBacklinks() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	flags := cmd.GlobalFlags{OutputFormat: "csv", Stdout: &buf}
+	w := output.NewWriterTo(flags.OutputFormat, &buf)
+	if err := writeResponse(w, flags, grouped, "", params, &models.BacklinkDomainsResponse{}, &meta); err != nil {
+		t.Fatalf("writeResponse() error = %v", err)
+	}
+
+	want := "domain,links,best_domain_rating,anchors,dofollow_share,first_seen\nexample.com,3,12,\"a0,a1,a2\",1,\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestGroupBacklinks_JSON(t *testing.T) {
+	body := `{"backlinks":[{"url_from":"https://example.com/a","url_to":"https://t.com/","anchor":"a","domain_rating":5}]}`
+	grouped, err := groupBacklinks(context.Background(), strings.NewReader(body), nil)
+	if err != nil {
+		t.Fatalf("groupBacklinks() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	flags := cmd.GlobalFlags{OutputFormat: "json", Stdout: &buf}
+	w := output.NewWriterTo(flags.OutputFormat, &buf)
+	if err := writeResponse(w, flags, grouped, "", url.Values{}, &models.BacklinkDomainsResponse{}, nil); err != nil {
+		t.Fatalf("writeResponse() error = %v", err)
+	}
+
+	var got struct {
+		Data models.BacklinkDomainsResponse `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", buf.String(), err)
+	}
+	want := []models.BacklinkDomain{{Domain: "example.com", Links: 1, BestDomainRating: 5, Anchors: models.StringList{"a"}}}
+	if !reflect.DeepEqual(got.Data.Domains, want) {
+		t.Errorf("domains = %+v, want %+v", got.Data.Domains, want)
+	}
+}
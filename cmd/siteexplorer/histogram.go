@@ -0,0 +1,121 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// histogramBarWidth is the width of the bar of a histogram's largest bucket
+const histogramBarWidth = 40
+
+// histogramConflicts are the flags replacing the rows --histogram counts
+var histogramConflicts = []string{"aggregate"}
+
+// drHistogram returns a transform adding the spread of the referring domains
+// of a response body across domain rating ranges, or with only replacing the
+// rows by it. With bars, the rows of only have a bar each, for table output.
+// Without only, the histogram is also written to table as a table of its
+// own, when table is set.
+func drHistogram(only, bars bool, table io.Writer) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		var resp interface{}
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		obj, _ := resp.(map[string]interface{})
+		rows, _ := obj["refdomains"].([]interface{})
+
+		buckets := histogramBuckets(rows)
+		var out interface{}
+		switch {
+		case only && bars:
+			out = models.DRHistogramBars{Buckets: drawBars(buckets)}
+		case only:
+			out = models.DRHistogram{Buckets: buckets}
+		default:
+			if table != nil {
+				if err := writeHistogram(table, buckets); err != nil {
+					return nil, err
+				}
+			}
+			out = map[string]interface{}{"refdomains": rows, "histogram": buckets}
+		}
+		data, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// histogramBuckets counts generically decoded referring domain rows by their
+// domain rating: 0-10, 11-20, and so on up to 91-100. A rating between two
+// ranges, such as 10.5, is counted in the higher. Rows without a domain
+// rating aren't counted.
+func histogramBuckets(rows []interface{}) []models.DRBucket {
+	buckets := make([]models.DRBucket, 10)
+	for i := range buckets {
+		b := &buckets[i]
+		b.MinDR, b.MaxDR = i*10+1, (i+1)*10
+		if i == 0 {
+			b.MinDR = 0
+		}
+		b.Range = fmt.Sprintf("%d-%d", b.MinDR, b.MaxDR)
+	}
+
+	rated := 0
+	for _, row := range rows {
+		fields, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		dr, ok := numberField(fields, "domain_rating")
+		if !ok {
+			continue
+		}
+		i := int(math.Ceil(dr/10)) - 1
+		buckets[min(max(i, 0), len(buckets)-1)].Domains++
+		rated++
+	}
+	for i := range buckets {
+		buckets[i].Percent = percentOf(buckets[i].Domains, rated)
+	}
+	return buckets
+}
+
+// drawBars returns buckets with bars of their domains, the largest bucket's
+// histogramBarWidth long. A bucket with any domains has a bar of at least
+// one character.
+func drawBars(buckets []models.DRBucket) []models.DRBucketBar {
+	most := 0
+	for _, b := range buckets {
+		most = max(most, b.Domains)
+	}
+	bars := make([]models.DRBucketBar, len(buckets))
+	for i, b := range buckets {
+		bars[i].DRBucket = b
+		if b.Domains > 0 {
+			width := max(int(math.Round(float64(b.Domains)/float64(most)*histogramBarWidth)), 1)
+			bars[i].Bar = strings.Repeat("#", width)
+		}
+	}
+	return bars
+}
+
+// writeHistogram writes buckets to w as a table, with a bar each
+func writeHistogram(w io.Writer, buckets []models.DRBucket) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DR\tDOMAINS\tPERCENT\t")
+	for _, b := range drawBars(buckets) {
+		fmt.Fprintf(tw, "%s\t%d\t%g%%\t%s\n", b.Range, b.Domains, b.Percent, b.Bar)
+	}
+	return tw.Flush()
+}
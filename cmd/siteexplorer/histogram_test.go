@@ -0,0 +1,105 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/internal/fixture"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// histogramServer serves testdata/histogram: referring domains with domain
+// ratings on and around the bounds of the ranges
+func histogramServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	fixtures, err := fixture.Load("testdata/histogram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(fixture.Handler(fixtures))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHistogram_Boundaries(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := histogramServer(t)
+
+	out := runCommand(t, srv.URL, []string{"refdomains", "-t", "t.com", "--histogram-only", "--format", "csv"})
+	want := "range,min_dr,max_dr,domains,percent\n" +
+		"0-10,0,10,2,20\n" +
+		"11-20,11,20,3,30\n" +
+		"21-30,21,30,0,0\n" +
+		"31-40,31,40,0,0\n" +
+		"41-50,41,50,0,0\n" +
+		"51-60,51,60,1,10\n" +
+		"61-70,61,70,0,0\n" +
+		"71-80,71,80,0,0\n" +
+		"81-90,81,90,1,10\n" +
+		"91-100,91,100,3,30\n"
+	if out != want {
+		t.Errorf("--histogram-only output = %q, want %q", out, want)
+	}
+}
+
+func TestHistogram_Table(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := histogramServer(t)
+
+	out := runCommand(t, srv.URL, []string{"refdomains", "-t", "t.com", "--histogram-only", "--format", "table"})
+	lines := strings.Split(out, "\n")
+	if !strings.Contains(lines[0], "bar") {
+		t.Errorf("header = %q, want a bar column", lines[0])
+	}
+	for _, tt := range []struct {
+		line int
+		bar  string
+	}{
+		{2, strings.Repeat("#", 27)},
+		{3, strings.Repeat("#", 40)},
+		{7, strings.Repeat("#", 13)},
+	} {
+		if !strings.HasSuffix(strings.TrimSpace(lines[tt.line]), " "+tt.bar) {
+			t.Errorf("row %q, want a bar of %d", lines[tt.line], len(tt.bar))
+		}
+	}
+	if strings.Contains(lines[4], "#") {
+		t.Errorf("empty range row %q has a bar", lines[4])
+	}
+}
+
+func TestHistogram_WithRows(t *testing.T) {
+	srv := histogramServer(t)
+	resp, err := srv.Client().Get(srv.URL + "/site-explorer/refdomains")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var table bytes.Buffer
+	out, err := drHistogram(false, false, &table)(context.Background(), resp.Body, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(out)
+	var got models.RefDomainsHistogramResponse
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", data, err)
+	}
+	if len(got.RefDomains) != 11 || len(got.Histogram) != 10 || got.Histogram[9].Domains != 3 {
+		t.Errorf("response = %+v, want the 11 rows and 10 ranges", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 11 || !strings.HasPrefix(lines[0], "DR") {
+		t.Fatalf("histogram table = %q, want a header and 10 ranges", table.String())
+	}
+	if fields := strings.Fields(lines[2]); len(fields) != 4 || fields[0] != "11-20" || fields[1] != "3" || fields[2] != "30%" {
+		t.Errorf("histogram row = %q, want 11-20 with 3 domains, 30%%, and a bar", lines[2])
+	}
+}
@@ -0,0 +1,74 @@
+package siteexplorer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// Values of --history
+const (
+	historyLive    = "live"
+	historyAllTime = "all_time"
+	historyNew     = "new"
+	historyLost    = "lost"
+)
+
+// histories are the values of --history
+var histories = []string{historyLive, historyAllTime, historyNew, historyLost}
+
+// lostColumns are the columns --history lost adds to --select, saying when
+// and why each link was lost
+var lostColumns = []string{"last_seen", "lost_reason", "drop_reason"}
+
+// historyRequest returns the history parameter of --history, and for new
+// and lost links the filter conditions keeping those first seen or lost
+// from --date-from to --date-to inclusive. Those links are requested from
+// every link seen since --date-from, live or not.
+func historyRequest(f requestFlags) (models.History, []*filterNode, error) {
+	switch f.history {
+	case "":
+		return "", nil, nil
+	case historyLive, historyAllTime:
+		if f.historyFrom != "" || f.historyTo != "" {
+			return "", nil, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("--date-from and --date-to set the window of --history new or lost, not %s", f.history),
+				"Use --history new or --history lost, or leave out the dates")
+		}
+		return models.History(f.history), nil, nil
+	}
+
+	if f.historyFrom == "" {
+		return "", nil, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("--history %s needs --date-from", f.history),
+			"Add --date-from, such as --date-from 30d for the last 30 days")
+	}
+	field := "first_seen"
+	if f.history == historyLost {
+		field = "last_seen"
+	}
+	var from, to time.Time
+	var conds []*filterNode
+	for _, b := range []struct {
+		flag, value, op string
+		t               *time.Time
+	}{
+		{"--date-from", f.historyFrom, "gte", &from},
+		{"--date-to", f.historyTo, "lte", &to},
+	} {
+		if b.value == "" {
+			continue
+		}
+		t, err := parseDate(b.value, f.anchor())
+		if err != nil {
+			return "", nil, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("%s: %v", b.flag, err), "Use YYYY-MM-DD, today, or a time ago such as 30d, 6w, 3m, or 1y")
+		}
+		*b.t = t
+		conds = append(conds, isCond(field, b.op, t.Format(dateLayout)).from("--history "+f.history))
+	}
+	if !to.IsZero() && from.After(to) {
+		return "", nil, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("--date-from %s is after --date-to %s", from.Format(dateLayout), to.Format(dateLayout)),
+			"Swap the dates")
+	}
+	return models.HistorySince(from), conds, nil
+}
@@ -0,0 +1,62 @@
+package siteexplorer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestHistoryRequest(t *testing.T) {
+	now := time.Date(2024, 7, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		f       requestFlags
+		history models.History
+		conds   []string
+	}{
+		{requestFlags{}, "", nil},
+		{requestFlags{history: "live"}, models.HistoryLive, nil},
+		{requestFlags{history: "all_time"}, models.HistoryAllTime, nil},
+		{requestFlags{history: "new", historyFrom: "30d", now: now}, "since:2024-06-01",
+			[]string{`{"field":"first_seen","is":["gte","2024-06-01"]}`}},
+		{requestFlags{history: "lost", historyFrom: "2024-06-01", historyTo: "today", now: now}, "since:2024-06-01",
+			[]string{`{"field":"last_seen","is":["gte","2024-06-01"]}`, `{"field":"last_seen","is":["lte","2024-07-01"]}`}},
+	}
+	for _, tt := range tests {
+		history, conds, err := historyRequest(tt.f)
+		if err != nil {
+			t.Errorf("historyRequest(%+v) error = %v", tt.f, err)
+			continue
+		}
+		if got := filterStrings(conds); history != tt.history || !reflect.DeepEqual(got, tt.conds) {
+			t.Errorf("historyRequest(%+v) = %q, %v, want %q, %v", tt.f, history, got, tt.history, tt.conds)
+		}
+	}
+}
+
+func TestHistoryRequest_Invalid(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		f    requestFlags
+		want string
+	}{
+		{requestFlags{history: "live", historyFrom: "2024-06-01"}, "window of --history new or lost"},
+		{requestFlags{history: "all_time", historyTo: "2024-06-01"}, "window of --history new or lost"},
+		{requestFlags{history: "lost"}, "needs --date-from"},
+		{requestFlags{history: "new", historyFrom: "last week", now: now}, "--date-from"},
+		{requestFlags{history: "new", historyFrom: "7d", historyTo: "30d", now: now}, "is after"},
+	}
+	for _, tt := range tests {
+		_, _, err := historyRequest(tt.f)
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("historyRequest(%+v) error = %v, want a usage error about %q", tt.f, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// intervalParam is the metrics-history parameter setting its granularity
+const intervalParam = "history_grouping"
+
+// downsample returns a transform keeping the last row of each interval of a
+// history response, for when the API returns finer rows than asked for. Rows
+// are bucketed by their date field; those without one are kept as they are.
+func downsample(interval string) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		var resp interface{}
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		if obj, ok := resp.(map[string]interface{}); ok {
+			for key, v := range obj {
+				if rows, ok := v.([]interface{}); ok {
+					obj[key] = lastPerInterval(rows, interval)
+				}
+			}
+		}
+
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// lastPerInterval keeps the latest row of each interval, in the order the
+// intervals first appear
+func lastPerInterval(rows []interface{}, interval string) []interface{} {
+	type bucket struct {
+		row  interface{}
+		date string
+	}
+	buckets := map[string]*bucket{}
+	var order []*bucket
+	kept := []interface{}{}
+	for _, row := range rows {
+		obj, _ := row.(map[string]interface{})
+		date, _ := obj["date"].(string)
+		key, ok := intervalKey(date, interval)
+		if !ok {
+			order = append(order, &bucket{row: row})
+			continue
+		}
+		b, seen := buckets[key]
+		if !seen {
+			b = &bucket{}
+			buckets[key] = b
+			order = append(order, b)
+		}
+		if date >= b.date {
+			b.row, b.date = row, date
+		}
+	}
+	for _, b := range order {
+		kept = append(kept, b.row)
+	}
+	return kept
+}
+
+// intervalKey names the interval containing date, a YYYY-MM-DD date or a
+// timestamp starting with one
+func intervalKey(date, interval string) (string, bool) {
+	if len(date) < 10 {
+		return "", false
+	}
+	t, err := time.Parse("2006-01-02", date[:10])
+	if err != nil {
+		return "", false
+	}
+	switch interval {
+	case "weekly":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), true
+	case "monthly":
+		return t.Format("2006-01"), true
+	}
+	return t.Format("2006-01-02"), true
+}
@@ -0,0 +1,106 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestLastPerInterval(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"date": "2024-01-30", "org_traffic": 1},
+		map[string]interface{}{"date": "2024-01-31", "org_traffic": 2},
+		map[string]interface{}{"date": "2024-02-01", "org_traffic": 3},
+		map[string]interface{}{"date": "2024-02-05T00:00:00Z", "org_traffic": 4},
+		map[string]interface{}{"org_traffic": 5},
+	}
+	traffic := func(rows []interface{}) []interface{} {
+		var out []interface{}
+		for _, r := range rows {
+			out = append(out, r.(map[string]interface{})["org_traffic"])
+		}
+		return out
+	}
+
+	tests := []struct {
+		interval string
+		want     []interface{}
+	}{
+		{"daily", []interface{}{1, 2, 3, 4, 5}},
+		// 2024-01-30 to 2024-02-01 are ISO week 5; 2024-02-05 starts week 6
+		{"weekly", []interface{}{3, 4, 5}},
+		{"monthly", []interface{}{2, 4, 5}},
+	}
+	for _, tt := range tests {
+		if got := traffic(lastPerInterval(rows, tt.interval)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lastPerInterval(%s) = %v, want %v", tt.interval, got, tt.want)
+		}
+	}
+}
+
+func TestLastPerInterval_Descending(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"date": "2024-02-02", "org_traffic": 3},
+		map[string]interface{}{"date": "2024-02-01", "org_traffic": 2},
+		map[string]interface{}{"date": "2024-01-31", "org_traffic": 1},
+	}
+	got := lastPerInterval(rows, "monthly")
+	want := []interface{}{rows[0], rows[2]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lastPerInterval() = %v, want %v", got, want)
+	}
+}
+
+func TestMetricsHistory_Interval(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var grouping string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grouping = r.URL.Query().Get(intervalParam)
+		w.Header().Set("Content-Type", "application/json")
+		// Daily rows regardless of the grouping asked for
+		fmt.Fprint(w, `{"metrics":[{"date":"2024-01-01","org_traffic":1},{"date":"2024-01-02","org_traffic":2},{"date":"2024-02-01","org_traffic":3}]}`)
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"metrics-history", "-t", "t.com", "--interval", "monthly", "--format", "json"})
+	var got struct {
+		Data models.MetricsHistoryResponse `json:"data"`
+		Meta struct {
+			Interval string `json:"interval"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+
+	want := []models.MetricsHistoryEntry{{Date: "2024-01-02", OrgTraffic: 2}, {Date: "2024-02-01", OrgTraffic: 3}}
+	if !reflect.DeepEqual(got.Data.Metrics, want) {
+		t.Errorf("metrics = %+v, want %+v", got.Data.Metrics, want)
+	}
+	if grouping != "monthly" {
+		t.Errorf("%s = %q, want monthly", intervalParam, grouping)
+	}
+	if got.Meta.Interval != "monthly" {
+		t.Errorf("meta interval = %q, want monthly", got.Meta.Interval)
+	}
+}
+
+func TestMetricsHistory_InvalidInterval(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	c, _, err := NewSiteExplorerCmd().Find([]string{"metrics-history"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Prepare(c, []string{"-t", "t.com", "--interval", "hourly", "--dry-run"})
+	var coded *cmd.Error
+	if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+		t.Errorf("error = %v, want an invalid --interval usage error", err)
+	}
+}
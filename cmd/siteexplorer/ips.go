@@ -0,0 +1,156 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+
+	"github.com/aminemat/ahrefs-cli/internal/resolve"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// ipColumns are the columns --resolve-ips adds
+var ipColumns = []string{"ip", "subnet"}
+
+// ipConflicts are the flags replacing the rows --resolve-ips and
+// --group-by-subnet look up the domains of
+var ipConflicts = []string{"aggregate", "histogram", "histogram-only"}
+
+// resolveIPs returns a transform setting the ip and subnet of every
+// referring domain of a response body, after applying prev when it's set.
+// Domains that don't resolve are left with neither.
+func resolveIPs(r *resolve.Resolver, log *slog.Logger, prev transform) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		if prev != nil {
+			var err error
+			if body, err = prev(ctx, body, fetch); err != nil {
+				return nil, err
+			}
+		}
+
+		var resp interface{}
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		obj, _ := resp.(map[string]interface{})
+		rows, _ := obj["refdomains"].([]interface{})
+
+		var domains []string
+		for _, row := range rows {
+			if fields, ok := row.(map[string]interface{}); ok {
+				if domain, ok := fields["domain"].(string); ok {
+					domains = append(domains, domain)
+				}
+			}
+		}
+		ips, err := lookupDomains(ctx, r, log, domains)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if fields, ok := row.(map[string]interface{}); ok {
+				domain, _ := fields["domain"].(string)
+				fields["ip"], fields["subnet"] = ips[domain], resolve.Subnet(ips[domain])
+			}
+		}
+
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// groupBySubnet returns a transform replacing the referring domains in a
+// response body with one row per /24 subnet they resolve to, after applying
+// prev when it's set. See subnetGroups.
+func groupBySubnet(r *resolve.Resolver, log *slog.Logger, prev transform) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		if prev != nil {
+			var err error
+			if body, err = prev(ctx, body, fetch); err != nil {
+				return nil, err
+			}
+		}
+
+		var resp models.RefDomainsResponse
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		domains := make([]string, len(resp.RefDomains))
+		for i, d := range resp.RefDomains {
+			domains[i] = d.Domain
+		}
+		ips, err := lookupDomains(ctx, r, log, domains)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(models.SubnetGroupsResponse{Subnets: subnetGroups(resp.RefDomains, ips)})
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// lookupDomains resolves domains with r, logging how many didn't resolve
+func lookupDomains(ctx context.Context, r *resolve.Resolver, log *slog.Logger, domains []string) (map[string]string, error) {
+	ips, err := r.Resolve(ctx, domains)
+	if err != nil {
+		return nil, err
+	}
+	unique := map[string]bool{}
+	for _, d := range domains {
+		unique[d] = true
+	}
+	if failed := len(unique) - len(ips); failed > 0 {
+		log.Info(fmt.Sprintf("%d of %d referring domains didn't resolve to an IPv4 address", failed, len(unique)))
+	}
+	return ips, nil
+}
+
+// subnetGroups rolls domains up by the /24 subnet of their address in ips,
+// most domains first. Domains without an address are left out.
+func subnetGroups(domains []models.RefDomain, ips map[string]string) []models.SubnetGroup {
+	groups := map[string]*models.SubnetGroup{}
+	addrs := map[string]map[string]bool{}
+	var order []*models.SubnetGroup
+	for _, d := range domains {
+		subnet := resolve.Subnet(ips[d.Domain])
+		if subnet == "" {
+			continue
+		}
+		g, ok := groups[subnet]
+		if !ok {
+			g = &models.SubnetGroup{Subnet: subnet}
+			groups[subnet] = g
+			addrs[subnet] = map[string]bool{}
+			order = append(order, g)
+		}
+
+		g.Domains++
+		g.Backlinks += d.Backlinks
+		g.MaxDomainRating = max(g.MaxDomainRating, d.DomainRating)
+		g.DomainNames = append(g.DomainNames, d.Domain)
+		addrs[subnet][ips[d.Domain]] = true
+	}
+
+	rows := make([]models.SubnetGroup, 0, len(order))
+	for _, g := range order {
+		g.IPs = len(addrs[g.Subnet])
+		rows = append(rows, *g)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Domains != rows[j].Domains {
+			return rows[i].Domains > rows[j].Domains
+		}
+		return rows[i].Backlinks > rows[j].Backlinks
+	})
+	return rows
+}
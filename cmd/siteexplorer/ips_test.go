@@ -0,0 +1,103 @@
+package siteexplorer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/resolve"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// staticResolver answers lookups from records, failing for other hosts
+func staticResolver(records map[string]string) *resolve.Resolver {
+	return &resolve.Resolver{
+		Lookup: func(ctx context.Context, host string) ([]net.IP, error) {
+			ip, ok := records[host]
+			if !ok {
+				return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+			}
+			return []net.IP{net.ParseIP(ip)}, nil
+		},
+		Concurrency: 2,
+	}
+}
+
+func TestResolveIPs(t *testing.T) {
+	r := staticResolver(map[string]string{"a.com": "203.0.113.7"})
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	body := strings.NewReader(`{"refdomains":[{"domain":"a.com","domain_rating":40},{"domain":"gone.com","domain_rating":10}]}`)
+
+	out, err := resolveIPs(r, log, nil)(context.Background(), body, nil)
+	if err != nil {
+		t.Fatalf("resolveIPs() error = %v", err)
+	}
+	got, _ := io.ReadAll(out)
+	want := `{"refdomains":[{"domain":"a.com","domain_rating":40,"ip":"203.0.113.7","subnet":"203.0.113.0/24"},{"domain":"gone.com","domain_rating":10,"ip":"","subnet":""}]}`
+	if string(got) != want {
+		t.Errorf("resolveIPs() = %s, want %s", got, want)
+	}
+}
+
+func TestSubnetGroups(t *testing.T) {
+	domains := []models.RefDomain{
+		{Domain: "a.com", DomainRating: 20, Backlinks: 3},
+		{Domain: "b.com", DomainRating: 50, Backlinks: 1},
+		{Domain: "c.com", DomainRating: 30, Backlinks: 9},
+		{Domain: "d.com", DomainRating: 10, Backlinks: 2},
+		{Domain: "gone.com", DomainRating: 90, Backlinks: 5},
+	}
+	ips := map[string]string{
+		"a.com": "203.0.113.7",
+		"b.com": "203.0.113.200",
+		"c.com": "198.51.100.4",
+		"d.com": "203.0.113.7",
+	}
+
+	got := subnetGroups(domains, ips)
+	want := []models.SubnetGroup{
+		{Subnet: "203.0.113.0/24", Domains: 3, IPs: 2, Backlinks: 6, MaxDomainRating: 50, DomainNames: models.StringList{"a.com", "b.com", "d.com"}},
+		{Subnet: "198.51.100.0/24", Domains: 1, IPs: 1, Backlinks: 9, MaxDomainRating: 30, DomainNames: models.StringList{"c.com"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("subnetGroups() = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolveIPs_Conflicts(t *testing.T) {
+	for _, args := range [][]string{
+		{"--resolve-ips", "--group-by-subnet"},
+		{"--group-by-subnet", "--select", "domain"},
+		{"--resolve-ips", "--aggregate", "tld"},
+	} {
+		c, _, err := NewSiteExplorerCmd().Find([]string{"refdomains"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.ParseFlags(args); err != nil {
+			t.Fatalf("ParseFlags(%v) error = %v", args, err)
+		}
+		if err := c.ValidateFlagGroups(); err == nil {
+			t.Errorf("ValidateFlagGroups() should reject %v", args)
+		}
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	for _, args := range [][]string{
+		{"--resolve-ips", "--resolve-concurrency", "0"},
+		{"--group-by-subnet", "--resolve-timeout", "0s"},
+	} {
+		args = append([]string{"refdomains", "-t", "t.com"}, args...)
+		_, err := execCommand(t, "http://127.0.0.1:1", args)
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+			t.Errorf("%v: error = %v, want a usage error", args, err)
+		}
+	}
+}
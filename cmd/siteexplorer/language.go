@@ -0,0 +1,62 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aminemat/ahrefs-cli/internal/langdetect"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// languageColumns are the columns --detect-language adds
+var languageColumns = []string{"language", "language_confidence"}
+
+// languageConflicts are the flags replacing the rows --detect-language adds
+// columns to
+var languageConflicts = []string{"group-by-domain", "expand-domains"}
+
+// languageResult returns the result --detect-language decodes the response
+// of path into
+func languageResult(path string) interface{} {
+	if path == "/site-explorer/anchors" {
+		return &models.AnchorLanguagesResponse{}
+	}
+	return &models.BacklinkLanguagesResponse{}
+}
+
+// anchorLanguages is a transform setting the language and
+// language_confidence of every row of a response with an anchor, detected in
+// the anchor text
+func anchorLanguages(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+	var resp interface{}
+	if _, err := decodeResponse(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if obj, ok := resp.(map[string]interface{}); ok {
+		for _, v := range obj {
+			rows, _ := v.([]interface{})
+			for _, row := range rows {
+				fields, ok := row.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				anchor, ok := fields["anchor"].(string)
+				if !ok {
+					continue
+				}
+				lang := langdetect.Detect(anchor)
+				fields["language"], fields["language_confidence"] = lang.Language, lang.Confidence
+			}
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
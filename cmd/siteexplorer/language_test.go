@@ -0,0 +1,67 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var selects []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		selects = append(selects, r.URL.Query().Get("select"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"anchors":[{"anchor":"the best guide to link building","backlinks":3},{"anchor":"mehr erfahren","backlinks":2},{"anchor":"ok","backlinks":1}]}`)
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"anchors", "-t", "t.com", "--detect-language", "--format", "json"})
+	var got struct {
+		Data models.AnchorLanguagesResponse `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	var langs []string
+	for _, a := range got.Data.Anchors {
+		langs = append(langs, fmt.Sprintf("%s:%v", a.Language, a.LanguageConfidence > 0))
+	}
+	if want := "en:true de:true und:false"; strings.Join(langs, " ") != want {
+		t.Errorf("languages = %v, want %s", langs, want)
+	}
+
+	// The anchor is requested to detect its language, and the columns added
+	out = runCommand(t, srv.URL, []string{"anchors", "-t", "t.com", "--detect-language", "--select", "backlinks", "--format", "csv"})
+	if selects[len(selects)-1] != "backlinks,anchor" {
+		t.Errorf("select = %q, want the anchor added", selects[len(selects)-1])
+	}
+	if header, _, _ := strings.Cut(out, "\n"); header != "backlinks,anchor,language,language_confidence" {
+		t.Errorf("CSV header = %q", header)
+	}
+}
+
+func TestDetectLanguage_Conflicts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, args := range [][]string{
+		{"anchors", "-t", "t.com", "--detect-language", "--expand-domains", "3"},
+		{"backlinks", "-t", "t.com", "--detect-language", "--group-by-domain"},
+	} {
+		c, _, err := NewSiteExplorerCmd().Find(args[:1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = cmd.Prepare(c, args[1:])
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+			t.Errorf("%v: error = %v, want a usage error", args, err)
+		}
+	}
+}
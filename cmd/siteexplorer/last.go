@@ -0,0 +1,130 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/bigquery"
+	"github.com/aminemat/ahrefs-cli/internal/history"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// lastResponse is where the latest successful response of a command and its
+// request flags is kept, for --last
+type lastResponse struct {
+	key     string
+	command string
+
+	// replay writes the kept response instead of making the request
+	replay bool
+}
+
+// newLastResponse returns the kept response of c with its current flags
+func newLastResponse(c *cobra.Command, replay bool) lastResponse {
+	return lastResponse{key: requestKey(c), command: c.CommandPath(), replay: replay}
+}
+
+// requestKey identifies the request of c by its path and the flags of its
+// own that aren't at their defaults. Global flags only shape the output, so
+// a response can be written again in another format.
+func requestKey(c *cobra.Command) string {
+	parts := []string{c.CommandPath()}
+	c.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Name == "last" || f.Name == "help" || f.Value.String() == f.DefValue {
+			return
+		}
+		parts = append(parts, fmt.Sprintf("--%s=%s", f.Name, f.Value))
+	})
+	return strings.Join(parts, " ")
+}
+
+// name returns the name of the command, such as backlinks
+func (l lastResponse) name() string {
+	return l.command[strings.LastIndex(l.command, " ")+1:]
+}
+
+// save keeps body, the response written for the command. A failure is only
+// a warning, as the command itself succeeded.
+func (l lastResponse) save(flags cmd.GlobalFlags, body []byte, meta client.ResponseMeta) {
+	err := history.Save(history.Entry{
+		Key:        l.key,
+		Command:    l.command,
+		SavedAt:    time.Now().UTC(),
+		Meta:       meta,
+		Body:       bytes.TrimSpace(body),
+		Validators: meta.Validators,
+	})
+	if err != nil {
+		flags.Log().Warn("response not kept for --last", "err", err)
+	}
+}
+
+// replayLast writes the response kept for l through the output settings of
+// flags, without a request
+func replayLast(flags cmd.GlobalFlags, l lastResponse, params url.Values, result interface{}, extra []string) error {
+	if bigquery.IsURL(flags.OutputFile) {
+		return cmd.NewError(cmd.CodeUsage, "--last can't load into BigQuery", "Write to a file or object URL with --output")
+	}
+
+	entry, err := history.Load(l.key)
+	if errors.Is(err, history.ErrNotFound) {
+		return cmd.NewError(cmd.CodeUsage, "no response kept for this command with these flags",
+			"Run it once without --last; see 'ahrefs cache ls' for the responses kept")
+	}
+	if err != nil {
+		return err
+	}
+	flags.Log().Info(fmt.Sprintf("Replaying the response of %s ago; no units used", time.Since(entry.SavedAt).Round(time.Second)))
+
+	flags.MarkCached()
+
+	meta := entry.Meta
+	meta.UnitsConsumed = 0
+	meta.Requests = 0
+	meta.ResponseTimeMS = 0
+	meta.Attempts, meta.TotalWaitMS, meta.Cached = 0, 0, true
+
+	w, err := newWriter(flags)
+	if err != nil {
+		return err
+	}
+	return writeResponse(w, flags, bytes.NewReader(entry.Body), "", outputColumns(params, extra), result, &meta)
+}
+
+// fetch requests the response of l, a single request. When the response
+// kept for l had caching headers, the request is conditional on it having
+// changed, and the API's 304 serves the kept body, marked revalidated.
+func (l lastResponse) fetch(ctx context.Context, c *client.Client, endpoint string, params url.Values, log *slog.Logger) (io.ReadCloser, client.ResponseMeta, error) {
+	req := client.Request{Method: http.MethodGet, Endpoint: endpoint, Params: params, Stream: true}
+	entry, err := history.Load(l.key)
+	if err == nil {
+		req.Conditional = entry.Validators
+	}
+
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return nil, client.ResponseMeta{}, err
+	}
+	if !resp.NotModified {
+		return resp.Stream, resp.Meta, nil
+	}
+	log.Debug(fmt.Sprintf("Unchanged since %s; writing the kept response", entry.SavedAt.Local().Format(time.DateTime)),
+		"units", resp.Meta.UnitsConsumed)
+	meta := resp.Meta
+	meta.Revalidated, meta.Cached = true, true
+	if meta.Validators.Empty() {
+		meta.Validators = entry.Validators
+	}
+	return io.NopCloser(bytes.NewReader(entry.Body)), meta, nil
+}
@@ -0,0 +1,111 @@
+package siteexplorer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+)
+
+func TestLast_Replay(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"keywords":[{"keyword":"seo tools","position":3}]}`)
+	}))
+	defer srv.Close()
+
+	runCommand(t, srv.URL, []string{"organic-keywords", "-t", "ahrefs.com", "--format", "json"})
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("requests = %d, want 1", n)
+	}
+
+	out := runCommand(t, srv.URL, []string{"organic-keywords", "-t", "ahrefs.com", "--last", "--format", "csv"})
+	if n := requests.Load(); n != 1 {
+		t.Errorf("requests after --last = %d, want 1", n)
+	}
+	if !strings.Contains(out, "seo tools") {
+		t.Errorf("--last output = %q, want the kept response", out)
+	}
+
+	_, err := execCommand(t, srv.URL, []string{"organic-keywords", "-t", "example.com", "--last"})
+	var cliErr *cmd.Error
+	if !errors.As(err, &cliErr) || cliErr.Code != cmd.CodeUsage {
+		t.Errorf("--last for another target error = %v, want a usage error", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("requests after a missing --last = %d, want 1", n)
+	}
+}
+
+func TestLast_Revalidate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	var conditions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditions = append(conditions, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.Header().Set("X-API-Units-Consumed", "0")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("X-API-Units-Consumed", "5")
+		fmt.Fprint(w, `{"keywords":[{"keyword":"seo tools","position":3}]}`)
+	}))
+	defer srv.Close()
+
+	args := []string{"organic-keywords", "-t", "ahrefs.com", "--format", "json"}
+	first := runCommand(t, srv.URL, args)
+	second := runCommand(t, srv.URL, args)
+
+	if len(conditions) != 2 || conditions[0] != "" || conditions[1] != `"v1"` {
+		t.Fatalf("If-None-Match sent = %q, want none then the kept ETag", conditions)
+	}
+	if !strings.Contains(second, "seo tools") || !strings.Contains(second, `"revalidated": true`) || !strings.Contains(second, `"cached": true`) {
+		t.Errorf("revalidated output = %s, want the kept response marked revalidated and cached", second)
+	}
+	if strings.Contains(first, "revalidated") || strings.Contains(first, "cached") {
+		t.Errorf("first output = %s, want it not marked revalidated or cached", first)
+	}
+	if !strings.Contains(first, `"units_consumed": 5`) || strings.Contains(second, "units_consumed") {
+		t.Errorf("units in meta = %s then %s, want only the first request's 5", first, second)
+	}
+
+	// A request that isn't written as it came isn't revalidated
+	runCommand(t, srv.URL, append(args, "--all"))
+	if conditions[2] != "" {
+		t.Errorf("If-None-Match sent with --all = %q, want none", conditions[2])
+	}
+}
+
+func TestLast_NoValidators(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	var conditional atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+			conditional.Add(1)
+		}
+		fmt.Fprint(w, `{"keywords":[{"keyword":"seo tools","position":3}]}`)
+	}))
+	defer srv.Close()
+
+	args := []string{"organic-keywords", "-t", "ahrefs.com", "--format", "json"}
+	runCommand(t, srv.URL, args)
+	out := runCommand(t, srv.URL, args)
+	if conditional.Load() != 0 || strings.Contains(out, "revalidated") {
+		t.Errorf("conditional requests = %d, output = %s, want none without caching headers", conditional.Load(), out)
+	}
+}
@@ -0,0 +1,99 @@
+package siteexplorer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/spf13/cobra"
+)
+
+// linkFilters maps the link filter shortcuts an endpoint supports, the
+// attributes and link types, to the filter conditions they compile into
+type linkFilters map[string]*filterNode
+
+// backlinkFilters filter backlinks on each link's attributes
+var backlinkFilters = linkFilters{
+	"dofollow":  isTrue("is_dofollow"),
+	"nofollow":  isTrue("is_nofollow"),
+	"ugc":       isTrue("is_ugc"),
+	"sponsored": isTrue("is_sponsored"),
+	"text":      isTrue("is_text"),
+	"image":     isTrue("is_image"),
+	"redirect":  isTrue("is_redirect"),
+	"frame":     isTrue("is_frame"),
+}
+
+// refdomainFilters filter referring domains on their links to the target:
+// dofollow keeps domains with at least one dofollow link, nofollow those
+// with none. Other attributes aren't counted per domain.
+var refdomainFilters = linkFilters{
+	"dofollow": isCond("dofollow_links", "gt", 0),
+	"nofollow": isCond("dofollow_links", "eq", 0),
+}
+
+// linkAttributes are the link filter shortcuts that are boolean flags; the
+// rest are --link-type values
+var linkAttributes = []string{"dofollow", "nofollow", "ugc", "sponsored"}
+
+// linkTypes are the accepted values for the --link-type flag
+var linkTypes = []string{"text", "image", "redirect", "frame"}
+
+// isTrue returns the condition that a boolean field is set
+func isTrue(field string) *filterNode {
+	return isCond(field, "eq", true)
+}
+
+// linkFlags holds the values of the link filter shortcuts
+type linkFlags struct {
+	attrs    map[string]*bool
+	linkType string
+}
+
+// addLinkFlags registers the shortcuts of filters on c. A dofollow link has
+// no nofollow, ugc, or sponsored attribute, so --dofollow excludes those.
+func addLinkFlags(c *cobra.Command, filters linkFilters, f *linkFlags) {
+	f.attrs = map[string]*bool{}
+	for _, attr := range linkAttributes {
+		if _, ok := filters[attr]; ok {
+			f.attrs[attr] = c.Flags().Bool(attr, false, fmt.Sprintf("Only %s links (shortcut for a --where condition)", attr))
+		}
+	}
+	for _, attr := range linkAttributes[1:] {
+		if f.attrs["dofollow"] != nil && f.attrs[attr] != nil {
+			c.MarkFlagsMutuallyExclusive("dofollow", attr)
+		}
+	}
+
+	var types []string
+	for _, t := range linkTypes {
+		if _, ok := filters[t]; ok {
+			types = append(types, t)
+		}
+	}
+	if len(types) > 0 {
+		c.Flags().StringVar(&f.linkType, "link-type", "", "Only links of this type: "+strings.Join(types, ", "))
+		cmd.SetAllowedValues(c, "link-type", types...)
+	}
+}
+
+// conditions returns the filter conditions of the shortcuts set, in flag
+// order
+func (f linkFlags) conditions(filters linkFilters) ([]*filterNode, error) {
+	var conds []*filterNode
+	for _, attr := range linkAttributes {
+		if set := f.attrs[attr]; set != nil && *set {
+			conds = append(conds, filters[attr].from("--"+attr))
+		}
+	}
+	if f.linkType != "" {
+		cond, ok := filters[f.linkType]
+		if !ok || !slices.Contains(linkTypes, f.linkType) {
+			return nil, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --link-type %q", f.linkType),
+				"Use one of: "+strings.Join(linkTypes, ", "))
+		}
+		conds = append(conds, cond.from("--link-type "+f.linkType))
+	}
+	return conds, nil
+}
@@ -0,0 +1,71 @@
+package siteexplorer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLinkFlags_Conditions(t *testing.T) {
+	set := true
+	tests := []struct {
+		name    string
+		filters linkFilters
+		flags   linkFlags
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", filters: backlinkFilters},
+		{
+			name:    "attributes and type",
+			filters: backlinkFilters,
+			flags:   linkFlags{attrs: map[string]*bool{"sponsored": &set, "nofollow": &set}, linkType: "image"},
+			want: []string{
+				`{"field":"is_nofollow","is":["eq",true]}`,
+				`{"field":"is_sponsored","is":["eq",true]}`,
+				`{"field":"is_image","is":["eq",true]}`,
+			},
+		},
+		{
+			name:    "refdomains dofollow",
+			filters: refdomainFilters,
+			flags:   linkFlags{attrs: map[string]*bool{"dofollow": &set}},
+			want:    []string{`{"field":"dofollow_links","is":["gt",0]}`},
+		},
+		{name: "unknown type", filters: backlinkFilters, flags: linkFlags{linkType: "form"}, wantErr: true},
+		{name: "unsupported type", filters: refdomainFilters, flags: linkFlags{linkType: "text"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.flags.conditions(tt.filters)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("conditions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := filterStrings(got); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("conditions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddLinkFlags(t *testing.T) {
+	var f linkFlags
+	c := &cobra.Command{Use: "refdomains", RunE: func(*cobra.Command, []string) error { return nil }}
+	addLinkFlags(c, refdomainFilters, &f)
+	for _, name := range []string{"ugc", "sponsored", "link-type"} {
+		if c.Flags().Lookup(name) != nil {
+			t.Errorf("--%s registered for filters without it", name)
+		}
+	}
+
+	var b linkFlags
+	c = &cobra.Command{Use: "backlinks", RunE: func(*cobra.Command, []string) error { return nil }}
+	c.SilenceErrors, c.SilenceUsage = true, true
+	addLinkFlags(c, backlinkFilters, &b)
+	c.SetArgs([]string{"--dofollow", "--sponsored"})
+	if err := c.Execute(); err == nil || !strings.Contains(err.Error(), "dofollow") {
+		t.Errorf("Execute() error = %v, want --dofollow and --sponsored rejected together", err)
+	}
+}
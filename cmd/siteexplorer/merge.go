@@ -0,0 +1,136 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/aminemat/ahrefs-cli/internal/urlnorm"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// mergeColumns are the columns --merge-url-variants adds
+var mergeColumns = []string{"merged_from"}
+
+// mergeSums are the columns of merged rows that are the total of their
+// variants'. Other counts, such as referring domains and keywords, would be
+// counted twice where the variants share them, so are their largest.
+var mergeSums = []string{"traffic", "traffic_value", "backlinks"}
+
+// mergeMaxes are the columns of merged rows that are the largest of their
+// variants'
+var mergeMaxes = []string{"url_rating", "refdomains", "keywords"}
+
+// mergedResult returns the result --merge-url-variants decodes the response
+// of path into
+func mergedResult(path string) interface{} {
+	if path == "/site-explorer/best-by-links" {
+		return &models.MergedPagesByLinksResponse{}
+	}
+	return &models.MergedTopPagesResponse{}
+}
+
+// mergeURLVariants returns a transform merging the pages of a response body
+// whose URLs are variants of each other, after applying prev when it's set.
+// See mergePages.
+func mergeURLVariants(prev transform) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		if prev != nil {
+			var err error
+			if body, err = prev(ctx, body, fetch); err != nil {
+				return nil, err
+			}
+		}
+
+		var resp interface{}
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		if obj, ok := resp.(map[string]interface{}); ok {
+			if rows, ok := obj["pages"].([]interface{}); ok {
+				obj["pages"] = mergePages(rows)
+			}
+		}
+
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// mergePages merges generically decoded rows whose url fields share a
+// urlnorm key into the first of them, in the order listed, so the merged row
+// keeps the URL the API ranked first. Its mergeSums are totalled, its
+// mergeMaxes are the largest, and merged_from counts the rows merged. Rows
+// without a url are kept as they are.
+func mergePages(rows []interface{}) []interface{} {
+	merged := make([]interface{}, 0, len(rows))
+	byKey := map[string]map[string]interface{}{}
+	for _, row := range rows {
+		fields, ok := row.(map[string]interface{})
+		if !ok {
+			merged = append(merged, row)
+			continue
+		}
+		rawURL, ok := fields["url"].(string)
+		if !ok {
+			merged = append(merged, row)
+			continue
+		}
+
+		key := urlnorm.Key(rawURL)
+		first, ok := byKey[key]
+		if !ok {
+			fields["merged_from"] = 1
+			byKey[key] = fields
+			merged = append(merged, fields)
+			continue
+		}
+		first["merged_from"] = first["merged_from"].(int) + 1
+		for _, name := range mergeSums {
+			mergeNumber(first, fields, name, func(a, b float64) float64 { return a + b })
+		}
+		for _, name := range mergeMaxes {
+			mergeNumber(first, fields, name, math.Max)
+		}
+		if seen, ok := fields["first_seen"].(string); ok && seen != "" {
+			if s, _ := first["first_seen"].(string); s == "" || seen < s {
+				first["first_seen"] = seen
+			}
+		}
+	}
+	return merged
+}
+
+// mergeNumber sets the numeric field name of into to combine of its value
+// and that of from. A field missing from either is left as into has it,
+// unless only from has it.
+func mergeNumber(into, from map[string]interface{}, name string, combine func(a, b float64) float64) {
+	b, ok := numberField(from, name)
+	if !ok {
+		return
+	}
+	a, ok := numberField(into, name)
+	if !ok {
+		into[name] = from[name]
+		return
+	}
+	into[name] = json.Number(strconv.FormatFloat(combine(a, b), 'f', -1, 64))
+}
+
+// numberField returns the value of a generically decoded row's numeric field,
+// and whether it has one
+func numberField(fields map[string]interface{}, name string) (float64, bool) {
+	n, ok := fields[name].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
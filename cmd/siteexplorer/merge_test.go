@@ -0,0 +1,70 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mergeFixture is a top pages response listing the home page and the
+// pricing page under several URLs each
+const mergeFixture = `{"pages":[
+	{"url":"https://ahrefs.com/","traffic":50000,"keywords":9000,"url_rating":80},
+	{"url":"https://ahrefs.com/pricing","traffic":12000,"keywords":700,"url_rating":55.5},
+	{"url":"http://www.ahrefs.com","traffic":300,"keywords":120,"url_rating":82},
+	{"url":"https://ahrefs.com/pricing/","traffic":800,"keywords":900,"url_rating":40},
+	{"url":"https://ahrefs.com/blog","traffic":7000,"keywords":2500,"url_rating":60},
+	{"url":"http://AHREFS.com/pricing","traffic":45,"keywords":10,"url_rating":30}
+]}`
+
+func TestMergePages(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"url": "https://ahrefs.com/blog/", "backlinks": json.Number("120"), "refdomains": json.Number("40"), "url_rating": json.Number("50"), "first_seen": "2020-05-01"},
+		map[string]interface{}{"url": "https://ahrefs.com/about"},
+		map[string]interface{}{"url": "http://www.ahrefs.com/blog", "backlinks": json.Number("30"), "refdomains": json.Number("45"), "traffic": json.Number("10"), "first_seen": "2019-01-01"},
+		map[string]interface{}{"backlinks": json.Number("5")},
+		map[string]interface{}{"url": "https://ahrefs.com/blog", "backlinks": json.Number("2.5"), "url_rating": json.Number("65.5")},
+	}
+	want := []interface{}{
+		map[string]interface{}{"url": "https://ahrefs.com/blog/", "backlinks": json.Number("152.5"), "refdomains": json.Number("45"), "url_rating": json.Number("65.5"), "traffic": json.Number("10"), "first_seen": "2019-01-01", "merged_from": 3},
+		map[string]interface{}{"url": "https://ahrefs.com/about", "merged_from": 1},
+		map[string]interface{}{"backlinks": json.Number("5")},
+	}
+	if got := mergePages(rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergePages() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeURLVariants_Command(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var selects []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		selects = append(selects, r.URL.Query().Get("select"))
+		fmt.Fprint(w, mergeFixture)
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"top-pages", "-t", "ahrefs.com", "--merge-url-variants",
+		"--select", "traffic,keywords,url_rating", "--format", "csv"})
+	want := "traffic,keywords,url_rating,url,merged_from\n" +
+		"50300,9000,82,https://ahrefs.com/,2\n" +
+		"12845,900,55.5,https://ahrefs.com/pricing,3\n" +
+		"7000,2500,60,https://ahrefs.com/blog,1\n"
+	if out != want {
+		t.Errorf("--merge-url-variants output = %q, want %q", out, want)
+	}
+	if selects[0] != "traffic,keywords,url_rating,url" {
+		t.Errorf("select = %q, want url added", selects[0])
+	}
+
+	// Off by default: the rows are the API's
+	out = runCommand(t, srv.URL, []string{"top-pages", "-t", "ahrefs.com", "--select", "url", "--format", "csv"})
+	if want := 7; strings.Count(out, "\n") != want {
+		t.Errorf("output without --merge-url-variants = %q, want %d lines", out, want)
+	}
+}
@@ -0,0 +1,151 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// movements are the accepted values for the --movement flag
+var movements = []string{"new", "lost", "up", "down"}
+
+// keywordMovements returns a transform that compares the organic keywords in
+// a response with the same request's keywords on the date compared, fetched
+// with the same paging, keeping those whose movement matches
+func keywordMovements(endpoint string, params url.Values, page pageOptions, compared, movement string) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		var current models.OrganicKeywordsResponse
+		if _, err := decodeResponse(body, &current); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		prevParams := cloneValues(params)
+		prevParams.Set("date", compared)
+		prevBody, _, err := fetch(ctx, endpoint, prevParams, page)
+		if err != nil {
+			return nil, err
+		}
+		defer prevBody.Close()
+		var previous models.OrganicKeywordsResponse
+		if _, err := decodeResponse(prevBody, &previous); err != nil {
+			return nil, fmt.Errorf("failed to parse response for %s: %w", compared, err)
+		}
+
+		rows := []models.KeywordMovement{}
+		for _, m := range compareKeywords(previous.Keywords, current.Keywords) {
+			if m.Movement == movement {
+				rows = append(rows, m)
+			}
+		}
+		data, err := json.Marshal(models.KeywordMovementsResponse{Keywords: rows})
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// compareKeywords pairs the rows of each keyword on two dates, biggest moves
+// first. Rows are matched on keyword and URL; a keyword left unmatched on a
+// different URL on each date switched URLs, and is matched across them.
+// Keywords on only one date are new or lost. Unchanged positions are
+// returned with no movement.
+func compareKeywords(previous, current []models.OrganicKeyword) []models.KeywordMovement {
+	type key struct{ keyword, url string }
+	prevIndex := map[key]int{}
+	for j, k := range previous {
+		if _, ok := prevIndex[key{k.Keyword, k.URL}]; !ok {
+			prevIndex[key{k.Keyword, k.URL}] = j
+		}
+	}
+
+	matched := make([]bool, len(previous))
+	pairs := make([]int, len(current))
+	for i, k := range current {
+		pairs[i] = -1
+		if j, ok := prevIndex[key{k.Keyword, k.URL}]; ok && !matched[j] {
+			pairs[i] = j
+			matched[j] = true
+		}
+	}
+
+	switched := map[string][]int{}
+	for j, k := range previous {
+		if !matched[j] {
+			switched[k.Keyword] = append(switched[k.Keyword], j)
+		}
+	}
+	for i, k := range current {
+		if js := switched[k.Keyword]; pairs[i] < 0 && len(js) > 0 {
+			pairs[i] = js[0]
+			matched[js[0]] = true
+			switched[k.Keyword] = js[1:]
+		}
+	}
+
+	var moves []models.KeywordMovement
+	for i := range current {
+		if pairs[i] < 0 {
+			moves = append(moves, moveKeyword(nil, &current[i]))
+		} else {
+			moves = append(moves, moveKeyword(&previous[pairs[i]], &current[i]))
+		}
+	}
+	for j := range previous {
+		if !matched[j] {
+			moves = append(moves, moveKeyword(&previous[j], nil))
+		}
+	}
+
+	sort.SliceStable(moves, func(i, j int) bool {
+		return distance(moves[i].PositionDiff) > distance(moves[j].PositionDiff)
+	})
+	return moves
+}
+
+// moveKeyword describes a keyword's move from prev to cur, either of which is
+// nil when the keyword didn't rank on that date
+func moveKeyword(prev, cur *models.OrganicKeyword) models.KeywordMovement {
+	switch {
+	case prev == nil:
+		position := cur.Position
+		return models.KeywordMovement{Keyword: cur.Keyword, Movement: "new", URL: cur.URL, Position: &position, SearchVolume: cur.SearchVolume}
+	case cur == nil:
+		position := prev.Position
+		return models.KeywordMovement{Keyword: prev.Keyword, Movement: "lost", URL: prev.URL, PreviousPosition: &position, SearchVolume: prev.SearchVolume}
+	}
+
+	before, after, diff := prev.Position, cur.Position, prev.Position-cur.Position
+	m := models.KeywordMovement{
+		Keyword:          cur.Keyword,
+		URL:              cur.URL,
+		PreviousPosition: &before,
+		Position:         &after,
+		PositionDiff:     &diff,
+		SearchVolume:     cur.SearchVolume,
+	}
+	if prev.URL != cur.URL {
+		m.PreviousURL = prev.URL
+	}
+	switch {
+	case diff > 0:
+		m.Movement = "up"
+	case diff < 0:
+		m.Movement = "down"
+	}
+	return m
+}
+
+// distance returns the size of a position change, 0 for none
+func distance(diff *int) int {
+	if diff == nil {
+		return 0
+	}
+	return max(*diff, -*diff)
+}
@@ -0,0 +1,121 @@
+package siteexplorer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func intp(v int) *int { return &v }
+
+func TestCompareKeywords(t *testing.T) {
+	previous := []models.OrganicKeyword{
+		{Keyword: "shoes", URL: "https://t.com/shoes", Position: 8},
+		{Keyword: "boots", URL: "https://t.com/boots", Position: 2},
+		{Keyword: "socks", URL: "https://t.com/socks", Position: 4},
+		{Keyword: "laces", URL: "https://t.com/a", Position: 12},
+		{Keyword: "hats", URL: "https://t.com/hats", Position: 30, SearchVolume: 50},
+	}
+	current := []models.OrganicKeyword{
+		{Keyword: "shoes", URL: "https://t.com/shoes", Position: 3},
+		{Keyword: "boots", URL: "https://t.com/boots", Position: 5},
+		{Keyword: "socks", URL: "https://t.com/socks", Position: 4},
+		{Keyword: "laces", URL: "https://t.com/b", Position: 10},
+		{Keyword: "gloves", URL: "https://t.com/gloves", Position: 7, SearchVolume: 90},
+	}
+
+	want := []models.KeywordMovement{
+		{Keyword: "shoes", Movement: "up", URL: "https://t.com/shoes", PreviousPosition: intp(8), Position: intp(3), PositionDiff: intp(5)},
+		{Keyword: "boots", Movement: "down", URL: "https://t.com/boots", PreviousPosition: intp(2), Position: intp(5), PositionDiff: intp(-3)},
+		{Keyword: "laces", Movement: "up", URL: "https://t.com/b", PreviousURL: "https://t.com/a", PreviousPosition: intp(12), Position: intp(10), PositionDiff: intp(2)},
+		{Keyword: "socks", URL: "https://t.com/socks", PreviousPosition: intp(4), Position: intp(4), PositionDiff: intp(0)},
+		{Keyword: "gloves", Movement: "new", URL: "https://t.com/gloves", Position: intp(7), SearchVolume: 90},
+		{Keyword: "hats", Movement: "lost", URL: "https://t.com/hats", PreviousPosition: intp(30), SearchVolume: 50},
+	}
+	if got := compareKeywords(previous, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("compareKeywords() =\n%s\nwant\n%s", movementsJSON(got), movementsJSON(want))
+	}
+}
+
+func TestCompareKeywords_SeveralURLs(t *testing.T) {
+	// A keyword ranking with two URLs matches each on its own URL first
+	previous := []models.OrganicKeyword{
+		{Keyword: "shoes", URL: "https://t.com/b", Position: 9},
+		{Keyword: "shoes", URL: "https://t.com/a", Position: 4},
+	}
+	current := []models.OrganicKeyword{
+		{Keyword: "shoes", URL: "https://t.com/a", Position: 6},
+		{Keyword: "shoes", URL: "https://t.com/c", Position: 7},
+	}
+
+	got := compareKeywords(previous, current)
+	want := []models.KeywordMovement{
+		{Keyword: "shoes", Movement: "down", URL: "https://t.com/a", PreviousPosition: intp(4), Position: intp(6), PositionDiff: intp(-2)},
+		{Keyword: "shoes", Movement: "up", URL: "https://t.com/c", PreviousURL: "https://t.com/b", PreviousPosition: intp(9), Position: intp(7), PositionDiff: intp(2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareKeywords() =\n%s\nwant\n%s", movementsJSON(got), movementsJSON(want))
+	}
+}
+
+func TestKeywordMovements(t *testing.T) {
+	params := url.Values{"target": {"t.com"}, "date": {"2024-06-30"}, "limit": {"10"}}
+	var requested []url.Values
+	fetch := func(ctx context.Context, endpoint string, p url.Values, page pageOptions) (io.ReadCloser, client.ResponseMeta, error) {
+		requested = append(requested, p)
+		return io.NopCloser(strings.NewReader(`{"keywords":[
+			{"keyword":"a","url":"https://t.com/","position":3},
+			{"keyword":"b","url":"https://t.com/","position":1}]}`)), client.ResponseMeta{}, nil
+	}
+	current := `{"keywords":[{"keyword":"a","url":"https://t.com/","position":1},{"keyword":"c","url":"https://t.com/","position":2}]}`
+
+	tr := keywordMovements("/site-explorer/organic-keywords", params, pageOptions{}, "2024-06-01", "lost")
+	body, err := tr(context.Background(), strings.NewReader(current), fetch)
+	if err != nil {
+		t.Fatalf("transform error = %v", err)
+	}
+
+	if len(requested) != 1 || requested[0].Get("date") != "2024-06-01" || requested[0].Get("limit") != "10" {
+		t.Errorf("compared request params = %v, want date=2024-06-01 and the other params", requested)
+	}
+	if params.Get("date") != "2024-06-30" {
+		t.Errorf("params date = %q, changed by the compared request", params.Get("date"))
+	}
+
+	var got models.KeywordMovementsResponse
+	if err := json.NewDecoder(body).Decode(&got); err != nil {
+		t.Fatalf("invalid transformed body: %v", err)
+	}
+	if len(got.Keywords) != 1 || got.Keywords[0].Keyword != "b" || got.Keywords[0].Movement != "lost" {
+		t.Errorf("keywords = %s, want only b lost", movementsJSON(got.Keywords))
+	}
+}
+
+func TestEndpointRequest_MovementNeedsDate(t *testing.T) {
+	var e endpoint
+	for _, ep := range endpoints {
+		if ep.Name == "organic-keywords" {
+			e = ep
+		}
+	}
+	_, _, err := e.request(requestFlags{target: "t.com", mode: "domain", limit: 10, movement: "up"})
+	if err == nil {
+		t.Fatal("request() error = nil, want an error for --movement without --date-compared")
+	}
+}
+
+func movementsJSON(moves []models.KeywordMovement) string {
+	var lines []string
+	for _, m := range moves {
+		data, _ := json.Marshal(m)
+		lines = append(lines, string(data))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,130 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/internal/textnorm"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// normalizeColumns are the columns --normalize-text adds
+var normalizeColumns = []string{"normalized_from"}
+
+// normalizeConflicts are the flags that replace the rows --normalize-text
+// would merge
+var normalizeConflicts = []string{
+	"movement", "expand-domains", "detect-language", "cluster", "clusters-only",
+	"compare-url", "summary", "summary-only",
+}
+
+// normalizeSums are the columns of merged rows that are the total of those
+// merged. Referring domains would be counted twice where the rows share
+// them, and a keyword's volume is the same whichever way it's written, so
+// those are their largest.
+var normalizeSums = []string{"backlinks", "traffic"}
+
+// normalizeMaxes are the columns of merged rows that are the largest of
+// those merged
+var normalizeMaxes = []string{"refdomains", "volume"}
+
+// normalizedResult returns the result --normalize-text decodes the response
+// of path into
+func normalizedResult(path string) interface{} {
+	if path == "/site-explorer/anchors" {
+		return &models.NormalizedAnchorsResponse{}
+	}
+	return &models.NormalizedKeywordsResponse{}
+}
+
+// normalizeText returns a transform normalizing the text of the first of
+// columns in the rows of a response body and merging the rows left the same
+// in all of columns, after applying prev when it's set. With fold, the text
+// is lowercased too. See normalizeRows.
+func normalizeText(columns []string, fold bool, prev transform) transform {
+	norm := textnorm.Normalize
+	if fold {
+		norm = textnorm.Fold
+	}
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		if prev != nil {
+			var err error
+			if body, err = prev(ctx, body, fetch); err != nil {
+				return nil, err
+			}
+		}
+
+		var resp interface{}
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		if obj, ok := resp.(map[string]interface{}); ok {
+			if key := firstList(obj); key != "" {
+				obj[key] = normalizeRows(obj[key].([]interface{}), columns, norm)
+			}
+		}
+
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// normalizeRows sets the text of the first of columns in generically decoded
+// rows to norm of it, and merges the rows then the same in all of columns,
+// and in country when they're tagged with one, into the first of them in the
+// order listed. Their normalizeSums are totalled, their normalizeMaxes are the
+// largest, and normalized_from counts the rows merged. Rows without the text
+// are kept as they are.
+func normalizeRows(rows []interface{}, columns []string, norm func(string) string) []interface{} {
+	merged := make([]interface{}, 0, len(rows))
+	byKey := map[string]map[string]interface{}{}
+	for _, row := range rows {
+		fields, ok := row.(map[string]interface{})
+		if !ok {
+			merged = append(merged, row)
+			continue
+		}
+		text, ok := fields[columns[0]].(string)
+		if !ok {
+			merged = append(merged, row)
+			continue
+		}
+		fields[columns[0]] = norm(text)
+
+		country, _ := fields["country"].(string)
+		parts := []string{country}
+		for _, name := range columns {
+			v, _ := fields[name].(string)
+			parts = append(parts, v)
+		}
+		key := strings.Join(parts, "\x00")
+		first, ok := byKey[key]
+		if !ok {
+			fields["normalized_from"] = 1
+			byKey[key] = fields
+			merged = append(merged, fields)
+			continue
+		}
+		first["normalized_from"] = first["normalized_from"].(int) + 1
+		for _, name := range normalizeSums {
+			mergeNumber(first, fields, name, func(a, b float64) float64 { return a + b })
+		}
+		for _, name := range normalizeMaxes {
+			mergeNumber(first, fields, name, math.Max)
+		}
+		if seen, ok := fields["first_seen"].(string); ok && seen != "" {
+			if s, _ := first["first_seen"].(string); s == "" || seen < s {
+				first["first_seen"] = seen
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,119 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/internal/textnorm"
+)
+
+// normalizeFixture is an anchors response listing the same anchor texts
+// written with a non-breaking space, curly quotes, a zero-width space, a
+// combining accent, and in another case
+const normalizeFixture = `{"anchors":[
+	{"anchor":"seo tools","backlinks":120,"refdomains":40,"first_seen":"2021-03-01"},
+	{"anchor":"ahrefs’ blog","backlinks":80,"refdomains":30},
+	{"anchor":"seo\u00A0tools","backlinks":15,"refdomains":12,"first_seen":"2020-01-10"},
+	{"anchor":"ahrefs' blog","backlinks":10,"refdomains":35},
+	{"anchor":"café","backlinks":6,"refdomains":3},
+	{"anchor":"SEO\u200B tools","backlinks":4,"refdomains":2},
+	{"anchor":"cafe\u0301 ","backlinks":2,"refdomains":1}
+]}`
+
+func TestNormalizeRows(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"keyword": "seo tools", "url": "https://ahrefs.com/", "traffic": json.Number("900"), "volume": json.Number("5000"), "position": json.Number("1")},
+		map[string]interface{}{"keyword": "seo  tools", "url": "https://ahrefs.com/blog", "traffic": json.Number("40"), "volume": json.Number("5000")},
+		map[string]interface{}{"keyword": "seo\u00A0tools ", "url": "https://ahrefs.com/", "traffic": json.Number("60"), "volume": json.Number("5200"), "position": json.Number("3")},
+		map[string]interface{}{"keyword": "seo tools", "url": "https://ahrefs.com/", "country": "gb", "traffic": json.Number("70")},
+		map[string]interface{}{"traffic": json.Number("5")},
+	}
+	want := []interface{}{
+		map[string]interface{}{"keyword": "seo tools", "url": "https://ahrefs.com/", "traffic": json.Number("960"), "volume": json.Number("5200"), "position": json.Number("1"), "normalized_from": 2},
+		map[string]interface{}{"keyword": "seo tools", "url": "https://ahrefs.com/blog", "traffic": json.Number("40"), "volume": json.Number("5000"), "normalized_from": 1},
+		map[string]interface{}{"keyword": "seo tools", "url": "https://ahrefs.com/", "country": "gb", "traffic": json.Number("70"), "normalized_from": 1},
+		map[string]interface{}{"traffic": json.Number("5")},
+	}
+	if got := normalizeRows(rows, []string{"keyword", "url"}, textnorm.Normalize); !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeRows() = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeText_Command(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var selects []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		selects = append(selects, r.URL.Query().Get("select"))
+		fmt.Fprint(w, normalizeFixture)
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"anchors", "-t", "ahrefs.com", "--normalize-text",
+		"--select", "backlinks,refdomains,first_seen", "--format", "csv"})
+	want := "backlinks,refdomains,first_seen,anchor,normalized_from\n" +
+		"135,40,2020-01-10,seo tools,2\n" +
+		"90,35,,ahrefs' blog,2\n" +
+		"8,3,,café,2\n" +
+		"4,2,,SEO tools,1\n"
+	if out != want {
+		t.Errorf("--normalize-text output = %q, want %q", out, want)
+	}
+	if selects[0] != "backlinks,refdomains,first_seen,anchor" {
+		t.Errorf("select = %q, want anchor added", selects[0])
+	}
+
+	out = runCommand(t, srv.URL, []string{"anchors", "-t", "ahrefs.com", "--normalize-text", "--casefold",
+		"--select", "anchor,backlinks", "--format", "csv"})
+	want = "anchor,backlinks,normalized_from\n" +
+		"seo tools,139,3\n" +
+		"ahrefs' blog,90,2\n" +
+		"café,8,2\n"
+	if out != want {
+		t.Errorf("--normalize-text --casefold output = %q, want %q", out, want)
+	}
+
+	// Off by default: the rows are the API's
+	out = runCommand(t, srv.URL, []string{"anchors", "-t", "ahrefs.com", "--select", "anchor", "--format", "csv"})
+	if want := 8; strings.Count(out, "\n") != want {
+		t.Errorf("output without --normalize-text = %q, want %d lines", out, want)
+	}
+}
+
+func TestNormalizeText_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+	defer srv.Close()
+	if _, err := execCommand(t, srv.URL, []string{"anchors", "-t", "ahrefs.com", "--casefold"}); err == nil || !strings.Contains(err.Error(), "--normalize-text") {
+		t.Errorf("--casefold alone: error = %v, want one about --normalize-text", err)
+	}
+
+	for _, tt := range []struct {
+		command string
+		args    []string
+	}{
+		{"anchors", []string{"--normalize-text", "--summary"}},
+		{"organic-keywords", []string{"--normalize-text", "--cluster", "url"}},
+		{"page-keywords", []string{"--normalize-text", "--compare-url", "https://example.com/"}},
+		{"organic-keywords", []string{"--normalize-text", "--count-only"}},
+	} {
+		c, _, err := NewSiteExplorerCmd().Find([]string{tt.command})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.ParseFlags(tt.args); err != nil {
+			t.Fatalf("ParseFlags(%v) error = %v", tt.args, err)
+		}
+		if err := c.ValidateFlagGroups(); err == nil {
+			t.Errorf("%s: ValidateFlagGroups() should reject %v", tt.command, tt.args)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package siteexplorer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/jobs"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+// cursorParam is the query parameter carrying a continuation token
+const cursorParam = "cursor"
+
+// defaultCursorPaths are the JSON paths checked for a next-page token when an
+// endpoint has no entry in cursorPaths
+var defaultCursorPaths = []string{"next_cursor", "next_page_token", "pagination.next_cursor"}
+
+// cursorPaths maps endpoints to the dotted JSON path of their next-page token,
+// for endpoints that don't use one of defaultCursorPaths
+var cursorPaths = map[string]string{}
+
+// pageOptions controls how list endpoints are paged
+type pageOptions struct {
+	All    bool
+	Cursor string
+	Resume bool
+
+	// Max stops fetching pages once this many rows have been fetched; zero
+	// fetches every page
+	Max int
+
+	// MaxRows is --max-rows, the bound on the rows of --all that Max is set
+	// to; zero when Max is a --limit fetched in chunks, or none
+	MaxRows int
+
+	// Countries requests each of several countries separately, paging
+	// each, and merges their rows tagged with the country
+	Countries []string
+
+	// Sample fetches this many rows spread from the first to the last,
+	// counted with SampleCount, and tags them with their offset. Without a
+	// count, they're the first rows.
+	Sample      int
+	SampleCount *sampleCount
+}
+
+// findCursor returns the next-page token in a decoded response, if any
+func findCursor(endpoint string, body map[string]interface{}) string {
+	paths := defaultCursorPaths
+	if path, ok := cursorPaths[endpoint]; ok {
+		paths = []string{path}
+	}
+
+	for _, path := range paths {
+		var v interface{} = body
+		for _, key := range strings.Split(path, ".") {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				v = nil
+				break
+			}
+			v = m[key]
+		}
+		if s, ok := v.(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// rowsKey returns the key of the single list field in a response
+func rowsKey(body map[string]json.RawMessage) (string, bool) {
+	for key, raw := range body {
+		if len(raw) > 0 && raw[0] == '[' {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// fetchAll requests every page of endpoint, following continuation tokens
+// when the response carries one and falling back to offset paging otherwise.
+// It returns a single response body with the rows of all pages merged, and
+// reports each page as a progress event.
+func fetchAll(ctx context.Context, c *client.Client, endpoint string, params url.Values, page pageOptions, log *slog.Logger) ([]byte, client.ResponseMeta, error) {
+	var meta client.ResponseMeta
+
+	params = cloneValues(params)
+	limit, _ := strconv.Atoi(params.Get("limit"))
+	offset, _ := strconv.Atoi(params.Get("offset"))
+
+	// Every export of several pages records its progress after each one,
+	// so --resume can continue it from the last completed page
+	statePath, err := jobs.Path(endpoint, params)
+	if err != nil {
+		return nil, meta, err
+	}
+	state := jobs.New(statePath, endpoint, params)
+	var rows []json.RawMessage
+	if page.Resume {
+		saved, err := jobs.Load(statePath, endpoint, params)
+		if errors.Is(err, jobs.ErrParamsChanged) {
+			return nil, meta, cmd.NewError(cmd.CodeUsage, err.Error(), "Run without --resume to start the export over")
+		}
+		if err != nil {
+			return nil, meta, err
+		}
+		if saved != nil {
+			if rows, err = jobs.ReadRows(*saved); err != nil {
+				return nil, meta, err
+			}
+			state = *saved
+			if state.Cursor != "" {
+				page.Cursor = state.Cursor
+			} else {
+				offset = state.Offset
+			}
+			if log != nil {
+				log.Info(fmt.Sprintf("Resuming after page %d, with %d row(s) fetched", state.Pages, state.Rows))
+			}
+		}
+	}
+	if page.Cursor != "" {
+		params.Set(cursorParam, page.Cursor)
+		params.Del("offset")
+	}
+
+	var merged map[string]json.RawMessage
+	var key string
+	report := cmd.GetGlobalFlags(ctx).Progress
+
+	for {
+		if page.Max > 0 {
+			params.Set("limit", strconv.Itoa(min(limit, page.Max-len(rows))))
+		}
+		if page.Cursor == "" && offset > 0 {
+			params.Set("offset", strconv.Itoa(offset))
+		}
+		resp, err := c.Get(ctx, endpoint, params)
+		if err != nil {
+			return nil, meta, err
+		}
+		meta.Requests++
+		meta.UnitsConsumed += resp.Meta.UnitsConsumed
+		meta.ResponseTimeMS += resp.Meta.ResponseTimeMS
+		meta.Attempts += resp.Meta.Attempts
+		meta.TotalWaitMS += resp.Meta.TotalWaitMS
+		meta.RateLimitRemaining = resp.Meta.RateLimitRemaining
+
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			return nil, meta, fmt.Errorf("failed to parse response: %w", err)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(resp.Body, &decoded); err != nil {
+			return nil, meta, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		if merged == nil {
+			merged = body
+			var ok bool
+			if key, ok = rowsKey(body); !ok {
+				// Not a list endpoint; there is nothing to page through
+				return resp.Body, resp.Meta, nil
+			}
+		}
+
+		var pageRows []json.RawMessage
+		if err := json.Unmarshal(body[key], &pageRows); err != nil {
+			return nil, meta, fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+		rows = append(rows, pageRows...)
+		if page.Max > 0 && len(rows) >= page.Max {
+			rows = rows[:page.Max]
+		}
+
+		report.Page(endpoint, state.Pages+1, len(rows), meta.UnitsConsumed)
+
+		done := len(pageRows) == 0 || page.Max > 0 && len(rows) == page.Max
+		if next := findCursor(endpoint, decoded); next != "" {
+			page.Cursor = next
+			params.Set(cursorParam, next)
+			params.Del("offset")
+		} else if page.Cursor == "" && limit > 0 && len(pageRows) >= limit {
+			offset += len(pageRows)
+		} else {
+			done = true
+		}
+
+		if done {
+			if err := jobs.Remove(statePath, state); err != nil {
+				return nil, meta, err
+			}
+			return mergeRows(endpoint, merged, key, rows, meta)
+		}
+
+		state.Pages++
+		state.Units += resp.Meta.UnitsConsumed
+		state.Cursor, state.Offset = page.Cursor, offset
+		if err := jobs.AppendRows(&state, pageRows); err != nil {
+			return nil, meta, err
+		}
+		if err := jobs.Save(statePath, state); err != nil {
+			return nil, meta, err
+		}
+	}
+}
+
+// mergeRows replaces the list field of the first page with rows and drops
+// the first page's next-page token, which no longer applies
+func mergeRows(endpoint string, body map[string]json.RawMessage, key string, rows []json.RawMessage, meta client.ResponseMeta) ([]byte, client.ResponseMeta, error) {
+	for _, path := range append(defaultCursorPaths, cursorPaths[endpoint]) {
+		if !strings.Contains(path, ".") {
+			delete(body, path)
+		}
+	}
+	if rows == nil {
+		rows = []json.RawMessage{}
+	}
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return nil, meta, err
+	}
+	body[key] = raw
+
+	data, err := json.Marshal(body)
+	return data, meta, err
+}
+
+func cloneValues(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for k, vals := range v {
+		out[k] = append([]string(nil), vals...)
+	}
+	return out
+}
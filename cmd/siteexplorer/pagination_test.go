@@ -0,0 +1,328 @@
+package siteexplorer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/jobs"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+// pagedServer serves total rows in pages of the requested limit, using
+// continuation tokens when withCursor is set and offsets otherwise
+func pagedServer(t *testing.T, total int, withCursor bool, failAt int) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requests []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		q := r.URL.Query()
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		start, _ := strconv.Atoi(q.Get("offset"))
+		if c := q.Get("cursor"); c != "" {
+			start, _ = strconv.Atoi(c)
+		}
+		if failAt > 0 && start >= failAt {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var rows []map[string]int
+		for i := start; i < total && i < start+limit; i++ {
+			rows = append(rows, map[string]int{"n": i})
+		}
+		body := map[string]interface{}{"anchors": rows}
+		if withCursor && start+limit < total {
+			body["next_cursor"] = strconv.Itoa(start + limit)
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func decodeRows(t *testing.T, body []byte) []int {
+	t.Helper()
+	var resp struct {
+		Anchors []struct {
+			N int `json:"n"`
+		} `json:"anchors"`
+		NextCursor string `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("invalid merged body: %v", err)
+	}
+	if resp.NextCursor != "" {
+		t.Errorf("merged body still has next_cursor %q", resp.NextCursor)
+	}
+	var got []int
+	for _, r := range resp.Anchors {
+		got = append(got, r.N)
+	}
+	return got
+}
+
+func TestFetchAll(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	tests := []struct {
+		name       string
+		withCursor bool
+	}{
+		{name: "continuation tokens", withCursor: true},
+		{name: "offset fallback", withCursor: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := pagedServer(t, 25, tt.withCursor, 0)
+			c := client.NewClient(client.Config{APIKey: "test-key", BaseURL: srv.URL})
+			params := url.Values{"target": {"example.com"}, "limit": {"10"}}
+
+			body, _, err := fetchAll(context.Background(), c, "/site-explorer/anchors", params, pageOptions{All: true}, nil)
+			if err != nil {
+				t.Fatalf("fetchAll() error = %v", err)
+			}
+
+			got := decodeRows(t, body)
+			if len(got) != 25 || got[0] != 0 || got[24] != 24 {
+				t.Errorf("fetchAll() rows = %v, want 0..24", got)
+			}
+			if len(*requests) != 3 {
+				t.Errorf("fetchAll() made %d requests, want 3", len(*requests))
+			}
+		})
+	}
+}
+
+func TestFetchAll_Resume(t *testing.T) {
+	tests := []struct {
+		name  string
+		first pageOptions
+	}{
+		{name: "after --resume", first: pageOptions{Resume: true}},
+		{name: "after --all", first: pageOptions{All: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			params := url.Values{"target": {"example.com"}, "limit": {"10"}}
+
+			// First run fails on the third page after saving its progress
+			srv, _ := pagedServer(t, 25, true, 20)
+			c := client.NewClient(client.Config{APIKey: "test-key", BaseURL: srv.URL, MaxRetries: 1})
+			if _, _, err := fetchAll(context.Background(), c, "/site-explorer/anchors", params, tt.first, nil); err == nil {
+				t.Fatal("fetchAll() expected error from failing page")
+			}
+
+			srv, requests := pagedServer(t, 25, true, 0)
+			c = client.NewClient(client.Config{APIKey: "test-key", BaseURL: srv.URL})
+			body, _, err := fetchAll(context.Background(), c, "/site-explorer/anchors", params, pageOptions{Resume: true}, nil)
+			if err != nil {
+				t.Fatalf("fetchAll() resume error = %v", err)
+			}
+
+			// The rows of the pages fetched before are kept
+			got := decodeRows(t, body)
+			if len(got) != 25 || got[0] != 0 || got[20] != 20 || got[24] != 24 {
+				t.Errorf("resumed rows = %v, want 0..24", got)
+			}
+			if len(*requests) != 1 {
+				t.Errorf("resume made %d requests, want 1", len(*requests))
+			}
+
+			path, err := jobs.Path("/site-explorer/anchors", params)
+			if err != nil {
+				t.Fatalf("jobs.Path() error = %v", err)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("export state %s should be removed after completion", path)
+			}
+			if list, _ := jobs.List(); len(list) != 0 {
+				t.Errorf("jobs.List() after completion = %v, want none", list)
+			}
+		})
+	}
+}
+
+func TestFindCursor(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "top level", body: `{"next_cursor":"abc"}`, want: "abc"},
+		{name: "nested", body: `{"pagination":{"next_cursor":"def"}}`, want: "def"},
+		{name: "absent", body: `{"anchors":[]}`, want: ""},
+		{name: "empty", body: `{"next_cursor":""}`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.body), &body); err != nil {
+				t.Fatal(err)
+			}
+			if got := findCursor("/site-explorer/anchors", body); got != tt.want {
+				t.Errorf("findCursor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointRequest_Chunks(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	e := endpoint{Name: "anchors", Path: "/site-explorer/anchors", List: true, MaxLimit: 10}
+
+	tests := []struct {
+		name      string
+		limit     int
+		wantRows  int
+		wantLimit []string
+	}{
+		{name: "exact cap", limit: 10, wantRows: 10, wantLimit: []string{"10"}},
+		{name: "cap+1", limit: 11, wantRows: 11, wantLimit: []string{"10", "1"}},
+		{name: "multiple chunks", limit: 25, wantRows: 25, wantLimit: []string{"10", "10", "5"}},
+		{name: "fewer rows than the limit", limit: 50, wantRows: 35, wantLimit: []string{"10", "10", "10", "10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := pagedServer(t, 35, false, 0)
+			c := client.NewClient(client.Config{APIKey: "test-key", BaseURL: srv.URL})
+
+			params, page, err := e.request(requestFlags{target: "example.com", mode: "domain", limit: tt.limit})
+			if err != nil {
+				t.Fatalf("request() error = %v", err)
+			}
+			body, meta, err := fetch(context.Background(), c, e.Path, params, page, nil)
+			if err != nil {
+				t.Fatalf("fetch() error = %v", err)
+			}
+			data, _ := io.ReadAll(body)
+
+			if got := decodeRows(t, data); len(got) != tt.wantRows || got[len(got)-1] != tt.wantRows-1 {
+				t.Errorf("rows = %v, want 0..%d", got, tt.wantRows-1)
+			}
+			var limits []string
+			for _, q := range *requests {
+				v, _ := url.ParseQuery(q)
+				limits = append(limits, v.Get("limit"))
+			}
+			if strings.Join(limits, ",") != strings.Join(tt.wantLimit, ",") {
+				t.Errorf("request limits = %v, want %v", limits, tt.wantLimit)
+			}
+			if len(tt.wantLimit) > 1 && meta.Requests != len(tt.wantLimit) {
+				t.Errorf("meta.Requests = %d, want %d", meta.Requests, len(tt.wantLimit))
+			}
+		})
+	}
+}
+
+func TestEndpointRequest_InvalidLimit(t *testing.T) {
+	e := endpoint{Name: "anchors", Path: "/site-explorer/anchors", List: true, MaxLimit: 10}
+
+	tests := []struct {
+		name  string
+		flags requestFlags
+	}{
+		{name: "zero", flags: requestFlags{limit: 0}},
+		{name: "above cap with --all", flags: requestFlags{limit: 11, page: pageOptions{All: true}}},
+		{name: "above cap with --resume", flags: requestFlags{limit: 11, page: pageOptions{Resume: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := e.request(tt.flags)
+			var cmdErr *cmd.Error
+			if !errors.As(err, &cmdErr) || cmdErr.Code != cmd.CodeUsage {
+				t.Errorf("request() error = %v, want a usage error", err)
+			}
+		})
+	}
+}
+
+func TestAll_DryRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "every page",
+			args: []string{"--all"},
+			want: []string{
+				"  Pages: every page, 100 row(s) per request; add --max-rows to bound them\n",
+				"  Estimated cost: ~1100 units (1 request(s), 100 row(s)) per page, for every page: unbounded\n",
+			},
+		},
+		{
+			name: "bounded by --max-rows",
+			args: []string{"--all", "--max-rows", "250"},
+			want: []string{
+				"  Pages: up to 250 row(s), at most 100 per request\n",
+				"  Estimated cost: ~2750 units (3 request(s), 250 row(s))\n",
+			},
+		},
+		{
+			name: "chunked --limit",
+			args: []string{"--limit", "2500"},
+			want: []string{
+				"  Pages: up to 2500 row(s), at most 1000 per request\n",
+				"(3 request(s), 2500 row(s))",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := runCommand(t, "https://api.ahrefs.com/v3", append([]string{"backlinks", "-t", "ahrefs.com", "--dry-run"}, tt.args...))
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("dry-run output = %q, want it to contain %q", out, want)
+				}
+			}
+		})
+	}
+}
+
+// TestAll_EstimateMatchesPages checks that --all bounded by --max-rows makes
+// as many requests as estimateRequest predicts
+func TestAll_EstimateMatchesPages(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	e := endpoint{Name: "anchors", Path: "/site-explorer/anchors", List: true, MaxLimit: 10}
+	srv, requests := pagedServer(t, 35, false, 0)
+	c := client.NewClient(client.Config{APIKey: "test-key", BaseURL: srv.URL})
+
+	params, page, err := e.request(requestFlags{target: "example.com", mode: "domain", limit: 10, page: pageOptions{All: true, MaxRows: 25}})
+	if err != nil {
+		t.Fatalf("request() error = %v", err)
+	}
+	est := estimateRequest(e.Path, params, page)
+	body, _, err := fetch(context.Background(), c, e.Path, params, page, nil)
+	if err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+	data, _ := io.ReadAll(body)
+
+	if got := decodeRows(t, data); len(got) != 25 {
+		t.Errorf("rows = %d, want 25", len(got))
+	}
+	if len(*requests) != est.Requests || est.Rows != 25 || est.Unbounded {
+		t.Errorf("requests = %d, estimate = %+v, want the %d request(s) estimated for 25 rows", len(*requests), est, est.Requests)
+	}
+}
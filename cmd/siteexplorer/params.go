@@ -0,0 +1,100 @@
+package siteexplorer
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+)
+
+// listParams are the query parameters every list endpoint accepts
+var listParams = []string{"target", "mode", "protocol", "limit", "offset", "select", "where", "order_by"}
+
+// apiParams are the query parameters each endpoint accepts. The API ignores
+// others without an error, which makes for misleading results, so requests
+// are checked against these before they are sent.
+var apiParams = map[string][]string{
+	"/site-explorer/domain-rating":      {"target", "mode", "protocol", "date"},
+	"/site-explorer/backlinks":          withListParams("aggregation", "history"),
+	"/site-explorer/backlinks-stats":    {"target", "mode", "protocol", "date"},
+	"/site-explorer/refdomains":         withListParams("history"),
+	"/site-explorer/anchors":            withListParams("history"),
+	"/site-explorer/organic-keywords":   withListParams("country", "date", "date_compared", "volume_mode"),
+	"/site-explorer/top-pages":          withListParams("country", "date", "date_compared", "volume_mode"),
+	competitorsPath:                     withListParams("country", "date", "date_compared", "volume_mode"),
+	"/site-explorer/broken-backlinks":   withListParams("aggregation"),
+	"/site-explorer/linked-domains":     withListParams(),
+	"/site-explorer/metrics":            {"target", "mode", "protocol", "select", "country", "date", "volume_mode"},
+	"/site-explorer/metrics-history":    {"target", "mode", "protocol", "select", "country", "date_from", "date_to", intervalParam, "volume_mode"},
+	refDomainsHistoryPath:               {"target", "mode", "protocol", "date_from", "date_to", intervalParam},
+	"/site-explorer/url-rating-history": {"target", "mode", "protocol", "date_from", "date_to", intervalParam},
+	"/site-explorer/pages-by-traffic":   withListParams("country", "volume_mode"),
+	"/site-explorer/best-by-links":      withListParams("history"),
+}
+
+// withListParams returns listParams and extra
+func withListParams(extra ...string) []string {
+	return append(slices.Clone(listParams), extra...)
+}
+
+// paramFlags are the flags setting each query parameter, for messages
+var paramFlags = map[string]string{
+	"target":        "--target",
+	"mode":          "--mode",
+	"limit":         "--limit",
+	"offset":        "--offset",
+	"select":        "--select",
+	"where":         "--where",
+	"order_by":      "--order-by",
+	"country":       "--country",
+	"date":          "--date",
+	"date_from":     "--date-from",
+	"date_to":       "--date-to",
+	intervalParam:   "--interval",
+	"date_compared": "--date-compared",
+}
+
+// checkParams returns params without those endpoint doesn't accept. Sending
+// one is a usage error, or with lenient a warning logged to log.
+// Endpoints without a list of parameters accept any.
+func checkParams(endpoint string, params url.Values, lenient bool, log *slog.Logger) (url.Values, error) {
+	accepted, ok := apiParams[endpoint]
+	if !ok {
+		return params, nil
+	}
+
+	var unknown []string
+	for name := range params {
+		if !slices.Contains(accepted, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return params, nil
+	}
+	sort.Strings(unknown)
+
+	names := make([]string, len(unknown))
+	for i, name := range unknown {
+		names[i] = name
+		if flag, ok := paramFlags[name]; ok {
+			names[i] = flag
+		}
+	}
+	if !lenient {
+		return nil, cmd.NewError(cmd.CodeUsage,
+			fmt.Sprintf("%s doesn't accept %s, so the API would ignore it", endpoint, strings.Join(names, ", ")),
+			"Remove it, or add --lenient to send the request without it")
+	}
+
+	log.Warn(fmt.Sprintf("%s doesn't accept %s; leaving it out", endpoint, strings.Join(names, ", ")))
+	kept := cloneValues(params)
+	for _, name := range unknown {
+		kept.Del(name)
+	}
+	return kept, nil
+}
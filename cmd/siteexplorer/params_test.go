@@ -0,0 +1,56 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/logging"
+)
+
+func TestAPIParams_Endpoints(t *testing.T) {
+	for _, e := range endpoints {
+		if _, ok := apiParams[e.Path]; !ok {
+			t.Errorf("%s: no accepted parameters for %s", e.Name, e.Path)
+		}
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	params := url.Values{"target": {"example.com"}, "mode": {"domain"}, "where": {"x>1"}, "country": {"us"}}
+
+	_, err := checkParams("/site-explorer/domain-rating", params, false, nil)
+	var coded *cmd.Error
+	if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+		t.Fatalf("checkParams() error = %v, want a usage error", err)
+	}
+	if !strings.Contains(coded.Message, "--country, --where") {
+		t.Errorf("message = %q, want the flags named", coded.Message)
+	}
+
+	var log bytes.Buffer
+	kept, err := checkParams("/site-explorer/domain-rating", params, true, logging.New(&log, logging.FormatText, slog.LevelInfo))
+	if err != nil {
+		t.Fatalf("checkParams(lenient) error = %v", err)
+	}
+	if got := kept.Encode(); got != "mode=domain&target=example.com" {
+		t.Errorf("kept = %s, want target and mode", got)
+	}
+	if params.Get("where") == "" {
+		t.Error("checkParams() modified its argument")
+	}
+	if !strings.HasPrefix(log.String(), "Warning: /site-explorer/domain-rating doesn't accept --country, --where") {
+		t.Errorf("warning = %q", log.String())
+	}
+
+	if _, err := checkParams("/site-explorer/organic-keywords", params, false, nil); err != nil {
+		t.Errorf("checkParams() error = %v for accepted parameters", err)
+	}
+	if _, err := checkParams("/unlisted", params, false, nil); err != nil {
+		t.Errorf("checkParams() error = %v for an endpoint without a list", err)
+	}
+}
@@ -0,0 +1,125 @@
+package siteexplorer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// columnPresets are the built-in column presets of each endpoint, by path.
+// Selecting one with --preset sets --select, and so the columns written, to
+// its columns, keeping shared reports the same for everyone running them.
+var columnPresets = map[string]map[string][]string{
+	"/site-explorer/backlinks": {
+		"audit": {"url_from", "domain_rating", "anchor", "first_seen", "is_dofollow"},
+	},
+	"/site-explorer/refdomains": {
+		"brief": {"domain", "domain_rating", "dofollow", "first_seen"},
+	},
+	"/site-explorer/organic-keywords": {
+		"brief": {"keyword", "volume", "kd", "position", "url"},
+	},
+	"/site-explorer/top-pages": {
+		"brief": {"url", "top_keyword", "position", "traffic", "keywords"},
+	},
+}
+
+// selectConflicts are the flags --select can't be combined with, and so
+// neither can a column preset
+var selectConflicts = []string{"movement", "expand-domains", "group-by-domain", "aggregate", "clusters-only", "histogram-only"}
+
+// columnPresets returns e's column presets by name: the built-in ones, and
+// those set for e.Name in the config file, which replace any of the same name
+func (e endpoint) columnPresets() map[string][]string {
+	presets := map[string][]string{}
+	for name, columns := range columnPresets[e.Path] {
+		presets[name] = columns
+	}
+	if cfg, err := config.Load(); err == nil {
+		for name, columns := range cfg.Presets[e.Name] {
+			presets[name] = columns
+		}
+	}
+	return presets
+}
+
+// applyPreset sets --select to the columns of the column preset --preset
+// names, if it names one of e's. The preset is then taken off the global
+// flags c runs with, as it isn't an output preset.
+func (e endpoint) applyPreset(c *cobra.Command) error {
+	flags := cmd.GetGlobalFlags(c.Context())
+	if flags.Preset == "" {
+		return nil
+	}
+	presets := e.columnPresets()
+	columns, ok := presets[flags.Preset]
+	if !ok {
+		if _, err := output.LookupPreset(flags.Preset); err != nil {
+			return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("unknown preset %q for %s", flags.Preset, e.Name),
+				fmt.Sprintf("Run 'ahrefs site-explorer %s --preset list' for the presets available", e.Name))
+		}
+		return nil
+	}
+
+	if c.Flags().Lookup("select") == nil {
+		return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("%s has no --select for column preset %q to set", e.Name, flags.Preset), "")
+	}
+	if c.Flags().Changed("select") {
+		return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("--preset %s sets --select", flags.Preset), "Leave out either --preset or --select")
+	}
+	for _, name := range selectConflicts {
+		if c.Flags().Changed(name) {
+			return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("--preset %s can't be combined with --%s", flags.Preset, name), "Use --select's default columns with --"+name)
+		}
+	}
+	if err := c.Flags().Set("select", strings.Join(columns, ",")); err != nil {
+		return err
+	}
+
+	flags.Preset = ""
+	c.SetContext(cmd.WithGlobalFlags(c.Context(), flags))
+	return nil
+}
+
+// presetInfo describes a preset for --preset list
+type presetInfo struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Columns     string `json:"columns,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// listPresets writes the presets available to e: its column presets, then
+// the output presets every command takes
+func (e endpoint) listPresets(flags cmd.GlobalFlags) error {
+	presets := e.columnPresets()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var infos []presetInfo
+	for _, name := range names {
+		infos = append(infos, presetInfo{Name: name, Kind: "columns", Columns: strings.Join(presets[name], ",")})
+	}
+	for _, name := range output.PresetNames() {
+		infos = append(infos, presetInfo{Name: name, Kind: "output", Description: output.Presets[name].Description})
+	}
+
+	flags.Preset = ""
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteSuccess(infos, nil); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
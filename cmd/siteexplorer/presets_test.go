@@ -0,0 +1,88 @@
+package siteexplorer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+)
+
+func TestColumnPresets_Endpoints(t *testing.T) {
+	paths := map[string]bool{}
+	for _, e := range endpoints {
+		if e.List && !e.TrafficShare {
+			paths[e.Path] = true
+		}
+	}
+	for path := range columnPresets {
+		if !paths[path] {
+			t.Errorf("column presets for %s, which no command with --select requests", path)
+		}
+	}
+}
+
+func TestPreset_Columns(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	config := `{"presets":{"backlinks":{"mine":["url_from","anchor"],"audit":["url_to"]}}}`
+	if err := os.WriteFile(filepath.Join(home, ".ahrefsrc"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var selects []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		selects = append(selects, r.URL.Query().Get("select"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"keywords":[{"keyword":"seo","volume":10,"kd":3,"position":1,"url":"https://a.com/","traffic":5}]}`)
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"organic-keywords", "-t", "a.com", "--preset", "brief", "--format", "csv"})
+	if want := "keyword,volume,kd,position,url"; selects[0] != want {
+		t.Errorf("select = %q, want %q", selects[0], want)
+	}
+	if header, _, _ := strings.Cut(out, "\n"); header != "keyword,volume,kd,position,url" {
+		t.Errorf("CSV header = %q, want the preset's columns", header)
+	}
+
+	// The config file adds presets and replaces built-in ones of the same name
+	runCommand(t, srv.URL, []string{"backlinks", "-t", "a.com", "--preset", "mine"})
+	runCommand(t, srv.URL, []string{"backlinks", "-t", "a.com", "--preset", "audit"})
+	if selects[1] != "url_from,anchor" || selects[2] != "url_to" {
+		t.Errorf("selects = %q, want the config file's presets", selects[1:])
+	}
+}
+
+func TestPreset_Errors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for _, args := range [][]string{
+		{"backlinks", "-t", "a.com", "--preset", "audit", "--select", "url_to"},
+		{"backlinks", "-t", "a.com", "--preset", "nope"},
+		{"domain-rating", "-t", "a.com", "--preset", "audit"},
+		{"organic-keywords", "-t", "a.com", "--preset", "brief", "--date-compared", "2024-01-01", "--movement", "lost"},
+	} {
+		_, err := execCommand(t, "http://127.0.0.1:1", append(args, "--dry-run"))
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+			t.Errorf("%v: error = %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestPreset_List(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	out := runCommand(t, "http://127.0.0.1:1", []string{"backlinks", "-t", "a.com", "--preset", "list", "--format", "csv"})
+	for _, want := range []string{"audit,columns,", "looker,output,"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("--preset list output = %q, want a %q row", out, want)
+		}
+	}
+}
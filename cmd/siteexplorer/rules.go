@@ -0,0 +1,108 @@
+package siteexplorer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+// paramRule is a constraint on the query parameters of an endpoint's
+// requests beyond which ones it accepts, such as a parameter the API can't do
+// without or dates that must be in order
+type paramRule struct {
+	param string // the parameter a violation is reported for
+
+	// check describes a violation, or returns "". now anchors dates, in
+	// --timezone.
+	check func(params url.Values, now time.Time) string
+}
+
+// keywordRules are the rules of the organic keywords endpoint
+var keywordRules = []paramRule{
+	requireParam("country", "organic keywords are ranked per country"),
+	validDate("date"),
+	validDate("date_compared"),
+	datesInOrder("date_compared", "date"),
+}
+
+// requireParam returns a rule that param is set, for the reason given
+func requireParam(param, reason string) paramRule {
+	return paramRule{param, func(params url.Values, _ time.Time) string {
+		if params.Get(param) != "" {
+			return ""
+		}
+		return fmt.Sprintf("%s is required: %s", flagOf(param), reason)
+	}}
+}
+
+// validDate returns a rule that param, when set, is a YYYY-MM-DD date that
+// isn't in the future
+func validDate(param string) paramRule {
+	return paramRule{param, func(params url.Values, now time.Time) string {
+		value := params.Get(param)
+		if value == "" {
+			return ""
+		}
+		date, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return fmt.Sprintf("%s %q is not a YYYY-MM-DD date", flagOf(param), value)
+		}
+		if date.After(truncateDay(now)) {
+			return fmt.Sprintf("%s %s is in the future", flagOf(param), value)
+		}
+		return ""
+	}}
+}
+
+// datesInOrder returns a rule that the date from, when both are set, isn't
+// after the date to
+func datesInOrder(from, to string) paramRule {
+	return paramRule{to, func(params url.Values, _ time.Time) string {
+		first, err1 := time.Parse(dateLayout, params.Get(from))
+		last, err2 := time.Parse(dateLayout, params.Get(to))
+		if err1 != nil || err2 != nil || !first.After(last) {
+			return ""
+		}
+		return fmt.Sprintf("%s %s is before %s %s", flagOf(to), params.Get(to), flagOf(from), params.Get(from))
+	}}
+}
+
+// flagOf returns the flag setting param, or param when no flag does
+func flagOf(param string) string {
+	if flag, ok := paramFlags[param]; ok {
+		return flag
+	}
+	return param
+}
+
+// checkRules checks params against e's rules at now, returning a usage
+// error listing every violation in its details
+func (e endpoint) checkRules(params url.Values, now time.Time) error {
+	var violations []client.FieldError
+	for _, rule := range e.Rules {
+		if msg := rule.check(params, now); msg != "" {
+			violations = append(violations, client.FieldError{Field: rule.param, Message: msg})
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	problems := "a problem"
+	if len(violations) > 1 {
+		problems = fmt.Sprintf("%d problems", len(violations))
+	}
+	details := make([]string, len(violations))
+	for i, v := range violations {
+		details[i] = v.Message
+	}
+	err := cmd.NewError(cmd.CodeUsage,
+		fmt.Sprintf("the request to %s has %s: %s", e.Path, problems, strings.Join(details, "; ")),
+		"Fix the flags listed, then run again")
+	err.Details = violations
+	return err
+}
@@ -0,0 +1,101 @@
+package siteexplorer
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+)
+
+func TestCheckRules(t *testing.T) {
+	var history endpoint
+	for _, e := range endpoints {
+		if e.Name == "metrics-history" {
+			history = e
+		}
+	}
+
+	tests := []struct {
+		name   string
+		params url.Values
+		want   []client.FieldError
+	}{
+		{
+			name:   "valid",
+			params: url.Values{"date_from": {"2024-01-01"}, "date_to": {"2024-06-30"}},
+		},
+		{
+			name:   "dates out of order",
+			params: url.Values{"date_from": {"2024-06-30"}, "date_to": {"2024-01-01"}},
+			want:   []client.FieldError{{Field: "date_to", Message: "--date-to 2024-01-01 is before --date-from 2024-06-30"}},
+		},
+		{
+			name:   "every violation",
+			params: url.Values{"date_to": {"2024-13-01"}},
+			want: []client.FieldError{
+				{Field: "date_from", Message: "--date-from is required: the history starts there"},
+				{Field: "date_to", Message: `--date-to "2024-13-01" is not a YYYY-MM-DD date`},
+			},
+		},
+		{
+			name:   "future date",
+			params: url.Values{"date_from": {"2999-01-01"}},
+			want:   []client.FieldError{{Field: "date_from", Message: "--date-from 2999-01-01 is in the future"}},
+		},
+	}
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := history.checkRules(tt.params, now)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("checkRules() error = %v", err)
+				}
+				return
+			}
+			var coded *cmd.Error
+			if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+				t.Fatalf("checkRules() error = %v, want a usage error", err)
+			}
+			if !reflect.DeepEqual(coded.Details, tt.want) {
+				t.Errorf("Details = %v, want %v", coded.Details, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidDate_Timezone(t *testing.T) {
+	rule := validDate("date")
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 08:00 on July 2nd in Tokyo is still July 1st in UTC
+	now := time.Date(2024, 7, 2, 8, 0, 0, 0, tokyo)
+
+	if msg := rule.check(url.Values{"date": {"2024-07-02"}}, now); msg != "" {
+		t.Errorf("check(today in Tokyo) = %q, want no violation", msg)
+	}
+	if msg := rule.check(url.Values{"date": {"2024-07-02"}}, now.In(time.UTC)); msg == "" {
+		t.Error("check(tomorrow in UTC) passed, want a future date violation")
+	}
+}
+
+func TestDryRun_Rules(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AHREFS_COUNTRY", "")
+
+	_, err := execCommand(t, "http://127.0.0.1:0", []string{"organic-keywords", "-t", "example.com", "--date", "2024-06-01", "--date-compared", "2024-06-30", "--dry-run"})
+	want := []client.FieldError{
+		{Field: "country", Message: "--country is required: organic keywords are ranked per country"},
+		{Field: "date", Message: "--date 2024-06-01 is before --date-compared 2024-06-30"},
+	}
+	if details := output.FormatError(err)["details"]; !reflect.DeepEqual(details, want) {
+		t.Errorf("details = %v, want %v", details, want)
+	}
+}
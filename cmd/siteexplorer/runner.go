@@ -0,0 +1,465 @@
+package siteexplorer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/bigquery"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/pricing"
+	"github.com/aminemat/ahrefs-cli/internal/redact"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+)
+
+// transform rewrites a response body before it is decoded, such as to
+// aggregate its rows. It may request more data with fetch.
+type transform func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error)
+
+// fetchFunc requests endpoint with params for a transform. It may be called
+// concurrently; with --enforce-budget it refuses once the command has spent
+// the budget. The caller closes the body.
+type fetchFunc func(ctx context.Context, endpoint string, params url.Values, page pageOptions) (io.ReadCloser, client.ResponseMeta, error)
+
+// runRequest executes a GET request against endpoint and writes the decoded
+// result using the global output settings. List endpoints may start from a
+// continuation token and fetch every page. With a transform, result is the
+// model of the transformed body, and extra are the columns the transform adds
+// to those of --select. The response written is kept as last's, or with
+// last.replay the kept one is written without a request.
+func runRequest(ctx context.Context, endpoint string, params url.Values, page pageOptions, last lastResponse, result interface{}, tr transform, extra ...string) error {
+	flags, err := cmd.ExpandOutput(cmd.GetGlobalFlags(ctx), cmd.OutputVars{Target: params.Get("target"), Endpoint: path.Base(endpoint)})
+	if err != nil {
+		return err
+	}
+	if last.replay {
+		return replayLast(flags, last, params, result, extra)
+	}
+
+	apiKey := flags.APIKey
+	if apiKey == "" {
+		apiKey = config.GetAPIKey()
+	}
+	if apiKey == "" {
+		return cmd.ErrAPIKeyRequired
+	}
+
+	c := client.NewClient(client.Config{
+		APIKey:      apiKey,
+		BaseURL:     flags.BaseURL,
+		BaseURLs:    flags.BaseURLs,
+		Headers:     flags.Headers,
+		Timeout:     flags.Timeout,
+		MaxBodySize: flags.MaxBodySize,
+		MaxRetries:  flags.MaxRetries,
+		BodyParams:  bodyParams(),
+		Middleware:  cmd.ClientMiddleware(flags),
+		Logger:      flags.Log(),
+		OnRetry:     flags.Progress.Retry,
+	})
+
+	if page.Cursor != "" {
+		if flags.ResumeOutput {
+			// The rows skipped are counted from the first
+			return cmd.NewError(cmd.CodeUsage, "--resume-output can't continue from a --cursor",
+				"Rerun the export with the flags that started it, or add --resume to continue its pages")
+		}
+		params.Set(cursorParam, page.Cursor)
+		params.Del("offset")
+	}
+
+	// A bq:// destination loads every page into BigQuery; other output
+	// goes to stdout
+	var bq *bigQueryExport
+	if bigquery.IsURL(flags.OutputFile) {
+		if flags.PipeTo != "" {
+			return cmd.NewError(cmd.CodeUsage, "--pipe-to replaces --output", "Leave out --output, or have the command load BigQuery")
+		}
+		var err error
+		if bq, err = newBigQueryExport(flags.OutputFile, result, selectColumns(params)); err != nil {
+			return err
+		}
+		flags.OutputFile = ""
+		page.All = true
+		page.Max = 0
+	}
+
+	est := estimateRequest(endpoint, params, page)
+
+	if flags.Estimate {
+		w, err := newWriter(flags)
+		if err != nil {
+			return err
+		}
+		return writeAndClose(w, flags, est, nil)
+	}
+
+	if flags.DryRun {
+		printDryRun(redact.Writer(flags.Stdout, flags.Secrets()...), c, endpoint, params, page, est)
+		if bq != nil {
+			bq.printSchema(flags.Stdout)
+		}
+		return nil
+	}
+
+	// An export of every page may cost any number of units
+	if flags.Confirm && (est.Unbounded || est.Units > flags.ConfirmAbove) {
+		ok, err := promptConfirm(fmt.Sprintf("This request is estimated at %s. Continue?", est))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("aborted by user")
+		}
+	}
+
+	budget := loadBudget()
+	if err := checkBudgetBefore(budget, flags); err != nil {
+		return err
+	}
+
+	// Open the destination first so a bad --output, such as an object URL
+	// without credentials, fails before any units are spent
+	w, err := newWriter(flags)
+	if err != nil {
+		return err
+	}
+	if tr == nil {
+		// Transformed rows don't have the endpoint's columns
+		w.SetEndpoint(endpoint)
+	}
+	if bq != nil {
+		if err := bq.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	log := flags.Log()
+	// A single request's response, written as it came, can be revalidated
+	// next time
+	single := tr == nil && !page.All && !page.Resume && len(page.Countries) <= 1 && page.Sample == 0
+	var body io.ReadCloser
+	var meta client.ResponseMeta
+	if single {
+		body, meta, err = last.fetch(ctx, c, endpoint, params, log)
+	} else {
+		body, meta, err = fetch(ctx, c, endpoint, params, page, log)
+		meta.Validators = client.Validators{}
+	}
+	if err != nil {
+		w.WriteError(err)
+		w.Abort()
+		return err
+	}
+	defer body.Close()
+	if page.Max > 0 && page.MaxRows == 0 {
+		log.Info(fmt.Sprintf("Split --limit %d into %d requests of at most %s rows", page.Max, meta.Requests, params.Get("limit")))
+	}
+	meta.Interval = params.Get(intervalParam)
+	for _, e := range meta.Errors {
+		log.Warn(e)
+	}
+
+	var out io.Reader = body
+	if tr != nil {
+		guard := newSpendGuard(budget, flags)
+		var mu sync.Mutex
+		more := func(ctx context.Context, endpoint string, params url.Values, page pageOptions) (io.ReadCloser, client.ResponseMeta, error) {
+			mu.Lock()
+			err := guard.check(meta.UnitsConsumed)
+			mu.Unlock()
+			if err != nil {
+				return nil, client.ResponseMeta{}, err
+			}
+
+			body, m, err := fetch(ctx, c, endpoint, params, page, log)
+			mu.Lock()
+			meta.UnitsConsumed += m.UnitsConsumed
+			mu.Unlock()
+			return body, m, err
+		}
+		if out, err = tr(ctx, body, more); err != nil {
+			w.Abort()
+			return err
+		}
+	}
+
+	// Keep what is written, for --last
+	var kept bytes.Buffer
+	out = io.TeeReader(out, &kept)
+
+	if bq != nil {
+		w.Close()
+		var data []byte
+		if data, err = io.ReadAll(out); err == nil {
+			err = bq.load(ctx, data, time.Now(), log)
+		}
+	} else {
+		// A single page may point to the next; merged, sampled, and
+		// transformed responses don't
+		cursorEndpoint := endpoint
+		if page.All || page.Resume || len(page.Countries) > 1 || page.Sample > 0 || tr != nil {
+			cursorEndpoint = ""
+		}
+		err = writeResponse(w, flags, out, cursorEndpoint, outputColumns(params, extra), result, &meta)
+	}
+	if err != nil {
+		// The units are spent, so keep the body for decoding again
+		name := last.name()
+		keepUndecoded(flags, name, err, &kept, out, decodeArgs(name, params, result, tr))
+		return err
+	}
+	io.Copy(io.Discard, out)
+	last.save(flags, kept.Bytes(), meta)
+
+	// Prefer the units reported by the API over the estimate. A
+	// revalidated response may cost none.
+	units := meta.UnitsConsumed
+	if units == 0 && !meta.Revalidated {
+		units = est.Units
+	}
+	return trackUsage(budget, flags, endpoint, units)
+}
+
+// outputColumns returns params with extra added to its --select, if any, for
+// the columns written
+func outputColumns(params url.Values, extra []string) url.Values {
+	if len(extra) == 0 || params.Get("select") == "" {
+		return params
+	}
+	columns := cloneValues(params)
+	columns.Set("select", params.Get("select")+","+strings.Join(extra, ","))
+	return columns
+}
+
+// writeResponse decodes body into result and writes it to w. With an
+// endpoint, the body's next-page token is reported in meta.
+func writeResponse(w *output.Writer, flags cmd.GlobalFlags, body io.Reader, endpoint string, params url.Values, result interface{}, meta *client.ResponseMeta) error {
+	// Typed models only know a subset of the API's columns, so decode
+	// generically when --select may ask for others. A preset needs to tell
+	// missing values from zeros, which typed models can't.
+	columns := selectColumns(params)
+	if columns != nil || flags.Preset != "" {
+		result = new(interface{})
+	}
+
+	// Checking the schema needs the response as decoded generically too
+	model := result
+	if _, generic := result.(*interface{}); !generic && (flags.CheckSchema || flags.Verbose) {
+		result = new(interface{})
+	}
+
+	fields, err := decodeResponse(body, result)
+	if err != nil {
+		w.Abort()
+		return &parseError{err}
+	}
+	if endpoint != "" && meta != nil {
+		meta.NextCursor = findCursor(endpoint, fields)
+	}
+
+	if model != result {
+		raw := *result.(*interface{})
+		warnDrift(flags.Log(), models.CompareFields(reflect.TypeOf(model), raw))
+		if err := remarshal(raw, model); err != nil {
+			w.Abort()
+			return &parseError{err}
+		}
+		result = model
+	}
+
+	w.SetColumns(columns)
+	return writeAndClose(w, flags, result, meta)
+}
+
+// fetch requests a single page, or every page with --all, for each country
+// when there are several, or a --sample, and returns the response body for the caller to
+// close. A single page is streamed.
+func fetch(ctx context.Context, c *client.Client, endpoint string, params url.Values, page pageOptions, log *slog.Logger) (io.ReadCloser, client.ResponseMeta, error) {
+	if len(page.Countries) > 1 {
+		return fetchCountries(ctx, c, endpoint, params, page, log)
+	}
+	if page.Sample > 0 {
+		return fetchSample(ctx, c, endpoint, params, page, log)
+	}
+	if page.All || page.Resume {
+		body, meta, err := fetchAll(ctx, c, endpoint, params, page, log)
+		if err != nil {
+			return nil, meta, err
+		}
+		return io.NopCloser(bytes.NewReader(body)), meta, nil
+	}
+
+	resp, err := c.GetStream(ctx, endpoint, params)
+	if err != nil {
+		return nil, client.ResponseMeta{}, err
+	}
+	return resp.Stream, resp.Meta, nil
+}
+
+// warnDrift reports differences between a response and its model
+func warnDrift(log *slog.Logger, d models.Drift) {
+	if len(d.Unknown) > 0 {
+		log.Warn("response fields not in the model, so not in the output (request them with --select): " + strings.Join(d.Unknown, ", "))
+	}
+	if len(d.Missing) > 0 {
+		log.Warn("model fields missing from the response: " + strings.Join(d.Missing, ", "))
+	}
+}
+
+// remarshal decodes the generically decoded value v into result
+func remarshal(v interface{}, result interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+// selectColumns returns the fields requested with --select, in order
+func selectColumns(params url.Values) []string {
+	var columns []string
+	for _, field := range strings.Split(params.Get("select"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			columns = append(columns, field)
+		}
+	}
+	return columns
+}
+
+// newWriter creates an output writer from the global output settings
+func newWriter(flags cmd.GlobalFlags) (*output.Writer, error) {
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return nil, err
+	}
+	if flags.Timestamp {
+		w.SetFetchedAt(time.Now())
+	}
+	return w, nil
+}
+
+// writeResult writes data either as a bare --value or as a full response
+func writeResult(w *output.Writer, flags cmd.GlobalFlags, data interface{}, meta *client.ResponseMeta) error {
+	if flags.ValueField != "" {
+		return w.WriteValue(data, flags.ValueField)
+	}
+	return w.WriteSuccess(data, meta)
+}
+
+// writeAndClose writes data with writeResult and closes w, discarding the
+// output if writing fails
+func writeAndClose(w *output.Writer, flags cmd.GlobalFlags, data interface{}, meta *client.ResponseMeta) error {
+	if err := writeResult(w, flags, data, meta); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+// printDryRun describes the request that would be sent, one per country
+// when there are several, using the client's effective base URL
+func printDryRun(w io.Writer, c *client.Client, endpoint string, params url.Values, page pageOptions, est pricing.Estimate) {
+	if len(page.Countries) > 1 {
+		for i, country := range page.Countries {
+			p := cloneValues(params)
+			p.Set("country", country)
+			if i == 0 {
+				fmt.Fprintf(w, "✓ Valid request. Would call: %s\n", describeCall(c, endpoint, p))
+			} else {
+				fmt.Fprintf(w, "  And: %s\n", describeCall(c, endpoint, p))
+			}
+		}
+	} else {
+		fmt.Fprintf(w, "✓ Valid request. Would call: %s\n", describeCall(c, endpoint, params))
+	}
+	if where := params.Get("where"); where != "" {
+		fmt.Fprintf(w, "  Filter: %s\n", where)
+		fmt.Fprintf(w, "  Encoded: where=%s\n", url.QueryEscape(where))
+	}
+	switch {
+	case page.Max > 0:
+		fmt.Fprintf(w, "  Pages: up to %d row(s), at most %s per request\n", page.Max, params.Get("limit"))
+	case page.All || page.Resume:
+		fmt.Fprintf(w, "  Pages: every page, %s row(s) per request; add --max-rows to bound them\n", params.Get("limit"))
+	}
+	fmt.Fprintf(w, "  Estimated cost: %s\n", est)
+}
+
+// describeCall returns the method and URL of a request to endpoint with
+// params, with its body when the parameters are sent in it
+func describeCall(c *client.Client, endpoint string, params url.Values) string {
+	body, how, err := c.Body(client.Request{Endpoint: endpoint, Params: params})
+	if body == nil || err != nil {
+		return "GET " + c.URL(endpoint, params)
+	}
+	return fmt.Sprintf("POST %s\n  Body (%s): %s", c.URL(endpoint, nil), how, body)
+}
+
+// bodyParams returns how the endpoints sending their parameters in the
+// request body encode them
+func bodyParams() map[string]client.ParamEncoding {
+	encodings := map[string]client.ParamEncoding{}
+	for _, e := range endpoints {
+		if e.BodyParams != client.ParamsQuery {
+			encodings[e.Path] = e.BodyParams
+		}
+	}
+	return encodings
+}
+
+// estimateRequest predicts the unit cost of a request from its params. A
+// --limit fetched in chunks, or --all bounded by --max-rows, costs a request
+// per page of --limit rows up to the bound; --all without one is unbounded,
+// estimated by its first page. Each of several countries costs the same
+// again. A counted --sample costs the stats request and a request per chunk.
+func estimateRequest(endpoint string, params url.Values, page pageOptions) pricing.Estimate {
+	rows, _ := strconv.Atoi(params.Get("limit"))
+	est := pricing.EstimateUnits(endpoint, rows, 0)
+	switch {
+	case page.Max > 0:
+		est = pricing.EstimateUnits(endpoint, page.Max, rows)
+	case page.All || page.Resume:
+		est.Unbounded = true
+	}
+	if page.SampleCount != nil {
+		est = pricing.EstimateUnits(endpoint, page.Sample, sampleChunks(page.Sample, page.Sample+1)[0].limit)
+		count := pricing.EstimateUnits(page.SampleCount.Path, 1, 0)
+		est.Requests += count.Requests
+		est.Units += count.Units
+	}
+	if n := len(page.Countries); n > 1 {
+		est.Rows *= n
+		est.Requests *= n
+		est.Units *= n
+	}
+	return est
+}
+
+// promptConfirm asks a yes/no question on stderr and reads the answer from stdin
+func promptConfirm(question string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,249 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/logging"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+)
+
+func TestPrintDryRun_EffectiveBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+	}{
+		{
+			name: "default base URL",
+			want: "GET https://api.ahrefs.com/v3/site-explorer/backlinks?limit=10&mode=domain&target=example.com",
+		},
+		{
+			name:    "custom base URL",
+			baseURL: "http://localhost:8080",
+			want:    "GET http://localhost:8080/site-explorer/backlinks?limit=10&mode=domain&target=example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := client.NewClient(client.Config{APIKey: "test-key", BaseURL: tt.baseURL})
+			params := url.Values{
+				"target": []string{"example.com"},
+				"mode":   []string{"domain"},
+				"limit":  []string{"10"},
+			}
+
+			var buf bytes.Buffer
+			printDryRun(&buf, c, "/site-explorer/backlinks", params, pageOptions{}, estimateRequest("/site-explorer/backlinks", params, pageOptions{}))
+
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("dry-run output = %q, want it to contain %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateRequest(t *testing.T) {
+	params := url.Values{"target": []string{"example.com"}, "limit": []string{"100"}}
+	tests := []struct {
+		name          string
+		page          pageOptions
+		wantRequests  int
+		wantUnits     int
+		wantUnbounded bool
+	}{
+		{name: "one page", wantRequests: 1, wantUnits: 1100},
+		{name: "every page", page: pageOptions{All: true}, wantRequests: 1, wantUnits: 1100, wantUnbounded: true},
+		{name: "resumed", page: pageOptions{Resume: true}, wantRequests: 1, wantUnits: 1100, wantUnbounded: true},
+		{name: "every page up to --max-rows", page: pageOptions{All: true, Max: 250, MaxRows: 250}, wantRequests: 3, wantUnits: 2750},
+		{name: "each country", page: pageOptions{All: true, Max: 200, MaxRows: 200, Countries: []string{"us", "gb"}}, wantRequests: 4, wantUnits: 4400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est := estimateRequest("/site-explorer/backlinks", params, tt.page)
+			if est.Requests != tt.wantRequests || est.Units != tt.wantUnits || est.Unbounded != tt.wantUnbounded {
+				t.Errorf("estimateRequest() = %d request(s), %d units, unbounded %v, want %d, %d, %v",
+					est.Requests, est.Units, est.Unbounded, tt.wantRequests, tt.wantUnits, tt.wantUnbounded)
+			}
+		})
+	}
+}
+
+func TestMaxRows_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, args := range [][]string{
+		{"backlinks", "-t", "ahrefs.com", "--max-rows", "500"},
+		{"backlinks", "-t", "ahrefs.com", "--all", "--max-rows", "-1"},
+	} {
+		_, err := execCommand(t, "http://127.0.0.1:0", append(args, "--dry-run"))
+		var cliErr *cmd.Error
+		if !errors.As(err, &cliErr) || cliErr.Code != cmd.CodeUsage || !strings.Contains(err.Error(), "--max-rows") {
+			t.Errorf("%v: error = %v, want a usage error about --max-rows", args, err)
+		}
+	}
+}
+
+func TestWriteResponse_Preset(t *testing.T) {
+	body := []byte(`{"backlinks":[
+		{"url_from":"https://a.com/x","url_to":"https://ahrefs.com/","anchor":"seo","domain_rating":71,"url_rating":null,"http_code":200,"first_seen":"2023-04-01T12:00:00Z","last_visited":"2024-05-06T07:08:09+02:00"},
+		{"url_from":"https://b.com/","url_to":"https://ahrefs.com/blog","domain_rating":0,"traffic":12}
+	]}`)
+
+	var buf bytes.Buffer
+	flags := cmd.GlobalFlags{OutputFormat: "csv", Preset: "looker", Stdout: &buf}
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		t.Fatalf("OpenOutput() error = %v", err)
+	}
+	w.SetEndpoint("/site-explorer/backlinks")
+
+	if err := writeResponse(w, flags, bytes.NewReader(body), "", url.Values{}, &models.BacklinksResponse{}, nil); err != nil {
+		t.Fatalf("writeResponse() error = %v", err)
+	}
+
+	want := "Referring Page URL,Target URL,Anchor,Domain Rating,URL Rating,Traffic,HTTP Status Code,Link Type,First Seen,Last Visited\n" +
+		"https://a.com/x,https://ahrefs.com/,seo,71,,,200,,2023-04-01T12:00:00Z,2024-05-06T05:08:09Z\n" +
+		"https://b.com/,https://ahrefs.com/blog,,0,,12,,,,\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestOpenOutput_PresetRequiresCSV(t *testing.T) {
+	if _, err := cmd.OpenOutput(cmd.GlobalFlags{OutputFormat: "json", Preset: "looker"}); err == nil {
+		t.Error("OpenOutput() error = nil, want error for --preset with json")
+	}
+	if _, err := cmd.OpenOutput(cmd.GlobalFlags{OutputFormat: "csv", Preset: "nope"}); err == nil {
+		t.Error("OpenOutput() error = nil, want error for unknown preset")
+	}
+}
+
+func TestWriteResponse_CheckSchema(t *testing.T) {
+	body := `{"anchors":[{"anchor":"a","backlinks":3,"is_new":true}]}`
+
+	for _, check := range []bool{false, true} {
+		var buf bytes.Buffer
+		flags := cmd.GlobalFlags{OutputFormat: "csv", CheckSchema: check, Quiet: true, Stdout: &buf}
+		w := output.NewWriterTo(flags.OutputFormat, &buf)
+
+		if err := writeResponse(w, flags, strings.NewReader(body), "", url.Values{}, &models.AnchorsResponse{}, nil); err != nil {
+			t.Fatalf("writeResponse() error = %v", err)
+		}
+		// The output is that of the model either way
+		want := "anchor,backlinks,refdomains,first_seen,last_visited\na,3,0,,\n"
+		if buf.String() != want {
+			t.Errorf("CheckSchema=%v: output = %q, want %q", check, buf.String(), want)
+		}
+	}
+}
+
+func TestWarnDrift(t *testing.T) {
+	var buf bytes.Buffer
+	log := logging.New(&buf, logging.FormatText, slog.LevelInfo)
+	warnDrift(log, models.Drift{Unknown: []string{"anchors[].is_new"}, Missing: []string{"anchors[].refdomains"}})
+
+	for _, want := range []string{
+		"response fields not in the model, so not in the output (request them with --select): anchors[].is_new\n",
+		"model fields missing from the response: anchors[].refdomains\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("warnDrift() = %q, want it to contain %q", buf.String(), want)
+		}
+	}
+
+	buf.Reset()
+	warnDrift(log, models.Drift{})
+	if buf.Len() != 0 {
+		t.Errorf("warnDrift() with no drift = %q, want nothing", buf.String())
+	}
+}
+
+func TestFetch_Canceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+
+	for _, page := range []pageOptions{{}, {All: true}} {
+		c := client.NewClient(client.Config{APIKey: "test-key", BaseURL: srv.URL, MaxRetries: 3})
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, _, err := fetch(ctx, c, "/site-explorer/anchors", url.Values{}, page, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("fetch(All=%v) error = %v, want context.Canceled", page.All, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("fetch(All=%v) returned after %s, want prompt return on cancel", page.All, elapsed)
+		}
+		cancel()
+	}
+}
+
+func TestRunRequest_NoRetry(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := execCommand(t, srv.URL, []string{"domain-rating", "-t", "t.com", "--no-retry"})
+	var reqErr *client.RequestError
+	if !errors.As(err, &reqErr) || reqErr.MaxRetries != 0 {
+		t.Fatalf("error = %v, want a request error without retries", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestRunRequest_ResumeOutput(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"backlinks":[{"url_from":"https://a.com/"},{"url_from":"https://b.com/"},{"url_from":"https://c.com/"}]}`))
+	}))
+	defer srv.Close()
+
+	// The run writing this died partway through its second row
+	file := filepath.Join(t.TempDir(), "backlinks.csv")
+	if err := os.WriteFile(file, []byte("url_from\nhttps://a.com/\nhttps://b."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"backlinks", "-t", "t.com", "--select", "url_from", "--format", "csv", "-o", file, "--append", "--resume-output"}
+	runCommand(t, srv.URL, args)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "url_from\nhttps://a.com/\nhttps://b.com/\nhttps://c.com/\n"; string(data) != want {
+		t.Errorf("resumed file = %q, want %q", data, want)
+	}
+
+	_, err = execCommand(t, srv.URL, append(args, "--cursor", "abc"))
+	var coded *cmd.Error
+	if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+		t.Errorf("--resume-output with --cursor: error = %v, want a usage error", err)
+	}
+}
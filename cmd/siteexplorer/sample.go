@@ -0,0 +1,168 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strconv"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+// sampleColumns are the columns --sample adds
+var sampleColumns = []string{"sample_offset"}
+
+// sampleConflicts are the flags choosing which rows are fetched, or
+// rewriting them, so --sample can't take them
+var sampleConflicts = append([]string{"count-only", "limit", "offset", "cluster", "detect-language", "interval"}, countConflicts...)
+
+// sampleCount is the stats request counting the rows a sample is taken from
+type sampleCount struct {
+	rowCount
+	params url.Values
+}
+
+// sampleChunk is a run of rows fetched for a sample
+type sampleChunk struct {
+	offset int
+	limit  int
+}
+
+// sampleRequest returns the params and paging of a request for a sample of n
+// of e's rows. The rows are counted first when e can count them, which it
+// can't with filters: the stats endpoint doesn't apply them.
+func (e endpoint) sampleRequest(params url.Values, page pageOptions, n int) (url.Values, pageOptions, error) {
+	if n < 1 || n > e.MaxLimit {
+		return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --sample %d", n),
+			fmt.Sprintf("Use a --sample between 1 and %d, the rows %s returns per page", e.MaxLimit, e.Name))
+	}
+	if len(page.Countries) > 1 {
+		return nil, page, cmd.NewError(cmd.CodeUsage, "--sample takes a single --country", "Run the command once per country")
+	}
+
+	params.Set("limit", strconv.Itoa(n))
+	page.Sample = n
+	if e.Count != nil && params.Get("where") == "" {
+		counted, err := e.countRequest(params, page)
+		if err != nil {
+			return nil, page, err
+		}
+		page.SampleCount = &sampleCount{rowCount: *e.Count, params: counted}
+	}
+	return params, page, nil
+}
+
+// sampleChunks returns the chunks of a sample of n of total rows: up to three
+// of about the same size, the first starting at the first row, the last
+// ending at the last, and another centred between them. When total is
+// unknown or no more than n, the sample is the first n rows.
+func sampleChunks(n, total int) []sampleChunk {
+	if total <= n {
+		return []sampleChunk{{offset: 0, limit: n}}
+	}
+
+	chunks := make([]sampleChunk, min(n, 3))
+	for i := range chunks {
+		chunks[i].limit = n / len(chunks)
+		if i < n%len(chunks) {
+			chunks[i].limit++
+		}
+	}
+	last := len(chunks) - 1
+	chunks[last].offset = total - chunks[last].limit
+	if len(chunks) == 3 {
+		chunks[1].offset = (total - chunks[1].limit) / 2
+	}
+	return chunks
+}
+
+// fetchSample counts the rows with page.SampleCount, when set, and requests
+// the chunks of a sample of page.Sample of them. Their rows are merged,
+// tagged with their offset as sample_offset, and meta.Sample describes them.
+func fetchSample(ctx context.Context, c *client.Client, endpoint string, params url.Values, page pageOptions, log *slog.Logger) (io.ReadCloser, client.ResponseMeta, error) {
+	n, count := page.Sample, page.SampleCount
+	page.Sample, page.SampleCount = 0, nil
+
+	var meta client.ResponseMeta
+	add := func(m client.ResponseMeta) {
+		meta.UnitsConsumed += m.UnitsConsumed
+		meta.ResponseTimeMS += m.ResponseTimeMS
+		meta.Requests += max(m.Requests, 1)
+		meta.Attempts += m.Attempts
+		meta.TotalWaitMS += m.TotalWaitMS
+		if m.RateLimitRemaining > 0 {
+			meta.RateLimitRemaining = m.RateLimitRemaining
+		}
+	}
+
+	total := 0
+	if count == nil {
+		log.Info(fmt.Sprintf("The rows of %s can't be counted with these flags, so the sample is the first %d", endpoint, n))
+	} else {
+		body, m, err := fetch(ctx, c, count.Path, count.params, page, log)
+		add(m)
+		if err != nil {
+			return nil, meta, err
+		}
+		total, err = count.read(body)
+		body.Close()
+		if err != nil {
+			return nil, meta, err
+		}
+	}
+
+	meta.Sample = &client.Sample{Total: total, Offsets: []int{}}
+	merged := map[string]interface{}{}
+	for _, chunk := range sampleChunks(n, total) {
+		p := cloneValues(params)
+		p.Set("limit", strconv.Itoa(chunk.limit))
+		if chunk.offset > 0 {
+			p.Set("offset", strconv.Itoa(chunk.offset))
+		}
+		body, m, err := fetchObject(ctx, c, endpoint, p, page, log)
+		add(m)
+		if err != nil {
+			return nil, meta, err
+		}
+		meta.Sample.Offsets = append(meta.Sample.Offsets, chunk.offset)
+		meta.Sample.Rows += mergeSample(merged, body, chunk.offset)
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, meta, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), meta, nil
+}
+
+// mergeSample appends the rows of a chunk's response starting at offset to
+// merged, each tagged with its own offset, and returns how many there were.
+// Other fields, such as paging tokens, are dropped.
+func mergeSample(merged, body map[string]interface{}, offset int) int {
+	appended := 0
+	for key, v := range body {
+		rows, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+
+		list, _ := merged[key].([]interface{})
+		if list == nil {
+			list = []interface{}{}
+		}
+		for i, row := range rows {
+			if obj, ok := row.(map[string]interface{}); ok {
+				obj["sample_offset"] = offset + i
+			}
+			list = append(list, row)
+		}
+		merged[key] = list
+		appended += len(rows)
+	}
+	return appended
+}
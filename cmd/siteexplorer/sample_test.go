@@ -0,0 +1,165 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+)
+
+func TestSampleChunks(t *testing.T) {
+	tests := []struct {
+		n, total int
+		want     []sampleChunk
+	}{
+		{10, 0, []sampleChunk{{0, 10}}},
+		{10, 10, []sampleChunk{{0, 10}}},
+		{10, 2500, []sampleChunk{{0, 4}, {1248, 3}, {2497, 3}}},
+		{10, 11, []sampleChunk{{0, 4}, {4, 3}, {8, 3}}},
+		{2, 100, []sampleChunk{{0, 1}, {99, 1}}},
+		{1, 100, []sampleChunk{{99, 1}}},
+	}
+	for _, tt := range tests {
+		if got := sampleChunks(tt.n, tt.total); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sampleChunks(%d, %d) = %v, want %v", tt.n, tt.total, got, tt.want)
+		}
+	}
+}
+
+// sampleServer serves backlinks stats counting 2500 backlinks, and backlinks
+// numbered by their offset, recording the list requests' queries
+func sampleServer(t *testing.T, queries *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/site-explorer/backlinks-stats":
+			fmt.Fprint(w, `{"metrics":{"live":2500,"refdomains":120}}`)
+		case "/site-explorer/backlinks":
+			*queries = append(*queries, r.URL.RawQuery)
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			var rows []string
+			for i := offset; i < offset+limit; i++ {
+				rows = append(rows, fmt.Sprintf(`{"url_from":"https://s.com/%d"}`, i))
+			}
+			fmt.Fprintf(w, `{"backlinks":[%s],"next":"token"}`, strings.Join(rows, ","))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSample(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var queries []string
+	srv := sampleServer(t, &queries)
+
+	out := runCommand(t, srv.URL, []string{"backlinks", "-t", "t.com", "--sample", "5", "--select", "url_from", "--format", "json"})
+	var got struct {
+		Data struct {
+			Backlinks []map[string]interface{} `json:"backlinks"`
+		} `json:"data"`
+		Meta struct {
+			Sample struct {
+				Rows    int   `json:"rows"`
+				Total   int   `json:"total"`
+				Offsets []int `json:"offsets"`
+			} `json:"sample"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+
+	var offsets []float64
+	for _, row := range got.Data.Backlinks {
+		offsets = append(offsets, row["sample_offset"].(float64))
+		if want := fmt.Sprintf("https://s.com/%v", row["sample_offset"]); row["url_from"] != want {
+			t.Errorf("row %v, want url_from %s", row, want)
+		}
+	}
+	if want := []float64{0, 1, 1249, 1250, 2499}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("sample offsets = %v, want %v", offsets, want)
+	}
+	if s := got.Meta.Sample; s.Rows != 5 || s.Total != 2500 || !reflect.DeepEqual(s.Offsets, []int{0, 1249, 2499}) {
+		t.Errorf("meta sample = %+v, want 5 of 2500 rows from 0, 1249, and 2499", s)
+	}
+	chunks := [][2]string{{"", "2"}, {"1249", "2"}, {"2499", "1"}}
+	if len(queries) != len(chunks) {
+		t.Fatalf("requests = %v, want %d", queries, len(chunks))
+	}
+	for i, chunk := range chunks {
+		q, _ := url.ParseQuery(queries[i])
+		if q.Get("offset") != chunk[0] || q.Get("limit") != chunk[1] {
+			t.Errorf("request %d = %q, want offset %q and limit %s", i, queries[i], chunk[0], chunk[1])
+		}
+	}
+}
+
+func TestSample_FirstRows(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var queries []string
+	srv := sampleServer(t, &queries)
+
+	// Filters aren't counted by the stats endpoint, so the sample is the
+	// first rows
+	out := runCommand(t, srv.URL, []string{"backlinks", "-t", "t.com", "--sample", "3", "--dofollow", "--select", "url_from", "--format", "csv"})
+	want := "url_from,sample_offset\n" +
+		"https://s.com/0,0\n" +
+		"https://s.com/1,1\n" +
+		"https://s.com/2,2\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], "limit=3") || strings.Contains(queries[0], "offset") {
+		t.Errorf("requests = %v, want one of the first 3 rows", queries)
+	}
+}
+
+func TestSample_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var queries []string
+	srv := sampleServer(t, &queries)
+
+	for _, args := range [][]string{
+		{"backlinks", "-t", "t.com", "--sample", "-1"},
+		{"backlinks", "-t", "t.com", "--sample", "1001"},
+		{"organic-keywords", "-t", "t.com", "-c", "us,gb", "--sample", "10"},
+	} {
+		_, err := execCommand(t, srv.URL, args)
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+			t.Errorf("%v: error = %v, want a usage error", args, err)
+		}
+	}
+	for _, args := range [][]string{
+		{"--sample", "10", "--all"},
+		{"--sample", "10", "--offset", "50"},
+		{"--sample", "10", "--count-only"},
+	} {
+		c, _, err := NewSiteExplorerCmd().Find([]string{"backlinks"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.ParseFlags(args); err != nil {
+			t.Fatalf("ParseFlags(%v) error = %v", args, err)
+		}
+		if err := c.ValidateFlagGroups(); err == nil {
+			t.Errorf("ValidateFlagGroups() should reject %v", args)
+		}
+	}
+	if len(queries) != 0 {
+		t.Errorf("requests = %v, want none", queries)
+	}
+}
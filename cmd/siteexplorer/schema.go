@@ -0,0 +1,20 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// writeSchema writes the JSON Schema of the response model result to w
+func writeSchema(w io.Writer, result interface{}) error {
+	data, err := json.MarshalIndent(models.GenerateSchema(reflect.TypeOf(result)), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
@@ -0,0 +1,32 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	for _, tt := range []struct {
+		args      []string
+		wantTitle string
+		wantField string
+	}{
+		{[]string{"backlinks", "-t", "a.com", "--schema"}, "BacklinksResponse", "backlinks"},
+		{[]string{"metrics", "-t", "a.com", "--schema", "--format", "csv"}, "MetricsResponse", "metrics"},
+		{[]string{"link-velocity", "-t", "a.com", "--schema"}, "LinkVelocityResponse", "summary"},
+	} {
+		// No API to call
+		out := runCommand(t, "http://127.0.0.1:1", tt.args)
+		var schema struct {
+			Dialect    string                     `json:"$schema"`
+			Title      string                     `json:"title"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		}
+		if err := json.Unmarshal([]byte(out), &schema); err != nil {
+			t.Fatalf("%v: output %q: %v", tt.args, out, err)
+		}
+		if schema.Title != tt.wantTitle || schema.Dialect == "" || schema.Properties[tt.wantField] == nil {
+			t.Errorf("%v: schema = %s, want %s with %s", tt.args, out, tt.wantTitle, tt.wantField)
+		}
+	}
+}
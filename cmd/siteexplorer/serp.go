@@ -0,0 +1,149 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+)
+
+// serpFeatures are the SERP feature names accepted by --serp-features and
+// --exclude-serp-features
+var serpFeatures = []string{
+	"ai_overview",
+	"discussions",
+	"featured_snippet",
+	"image_pack",
+	"knowledge_card",
+	"knowledge_panel",
+	"local_pack",
+	"paid_bottom",
+	"paid_top",
+	"people_also_ask",
+	"shopping",
+	"sitelinks",
+	"thumbnail",
+	"top_stories",
+	"twitter",
+	"video",
+	"video_preview",
+}
+
+// serpFilter selects organic keywords by the features of their results: any
+// of include, if set, and none of exclude
+type serpFilter struct {
+	include []string
+	exclude []string
+}
+
+func (s serpFilter) empty() bool {
+	return len(s.include) == 0 && len(s.exclude) == 0
+}
+
+// validate rejects unknown feature names
+func (s serpFilter) validate() error {
+	for _, name := range slices.Concat(s.include, s.exclude) {
+		if !slices.Contains(serpFeatures, name) {
+			return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("unknown SERP feature %q", name),
+				"Use one of: "+strings.Join(serpFeatures, ", "))
+		}
+	}
+	return nil
+}
+
+// clientSide reports whether the filter has to be applied to the response
+// rather than sent with where, the --where expression: only structured
+// filters can be combined with it
+func (s serpFilter) clientSide(where string) bool {
+	return !s.empty() && where != "" && !isStructured(where)
+}
+
+// conditions returns the structured filter conditions matching the filter:
+// any of the included features, and none of the excluded
+func (s serpFilter) conditions() []*filterNode {
+	has := func(name string) *filterNode {
+		return listCond("serp_features", "any", "eq", name)
+	}
+
+	include := make([]*filterNode, len(s.include))
+	for i, name := range s.include {
+		include[i] = has(name)
+	}
+	var conds []*filterNode
+	if n := anyOf(include...); n != nil {
+		conds = append(conds, n.from("--serp-features"))
+	}
+	for _, name := range s.exclude {
+		conds = append(conds, not(has(name)).from("--exclude-serp-features"))
+	}
+	return conds
+}
+
+// matches reports whether a keyword with the SERP features features passes
+// the filter
+func (s serpFilter) matches(features []string) bool {
+	for _, name := range s.exclude {
+		if slices.Contains(features, name) {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, name := range s.include {
+		if slices.Contains(features, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterKeywords returns a transform that drops the organic keywords the
+// filter doesn't match, leaving their other fields as they are
+func (s serpFilter) filterKeywords(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+	var resp interface{}
+	if _, err := decodeResponse(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if obj, ok := resp.(map[string]interface{}); ok {
+		rows, _ := obj["keywords"].([]interface{})
+		kept := []interface{}{}
+		for _, row := range rows {
+			fields, _ := row.(map[string]interface{})
+			if s.matches(rowFeatures(fields["serp_features"])) {
+				kept = append(kept, row)
+			}
+		}
+		obj["keywords"] = kept
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// rowFeatures returns the feature names of a generically decoded
+// serp_features value, a list or a comma-separated string
+func rowFeatures(v interface{}) []string {
+	switch v := v.(type) {
+	case []interface{}:
+		names := make([]string, 0, len(v))
+		for _, name := range v {
+			if name, ok := name.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	case string:
+		return strings.Split(v, ",")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package siteexplorer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSERPFilter_Conditions(t *testing.T) {
+	const video = `{"field":"serp_features","list_is":{"any":["eq","video"]}}`
+	const snippet = `{"field":"serp_features","list_is":{"any":["eq","featured_snippet"]}}`
+	const traffic = `{"field":"traffic","is":["gt",100]}`
+
+	tests := []struct {
+		name   string
+		filter serpFilter
+		where  string
+		want   string
+	}{
+		{"one feature", serpFilter{include: []string{"video"}}, "", video},
+		{"any of several", serpFilter{include: []string{"video", "featured_snippet"}}, "", `{"or":[` + video + `,` + snippet + `]}`},
+		{"excluded", serpFilter{exclude: []string{"video"}}, "", `{"not":` + video + `}`},
+		{"with where", serpFilter{include: []string{"video"}, exclude: []string{"featured_snippet"}}, traffic,
+			`{"and":[` + traffic + `,` + video + `,{"not":` + snippet + `}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allOf(append([]*filterNode{whereExpr(tt.where)}, tt.filter.conditions()...)...).String()
+			if got != tt.want {
+				t.Errorf("conditions() = %s, want %s", got, tt.want)
+			}
+			if !json.Valid([]byte(got)) {
+				t.Errorf("conditions() = %s, not valid JSON", got)
+			}
+		})
+	}
+}
+
+func TestSERPFilter_ClientSide(t *testing.T) {
+	filter := serpFilter{include: []string{"video"}}
+	tests := []struct {
+		filter serpFilter
+		where  string
+		want   bool
+	}{
+		{filter, "", false},
+		{filter, ` {"field":"traffic","is":["gt",100]}`, false},
+		{filter, "traffic>100", true},
+		{serpFilter{}, "traffic>100", false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.clientSide(tt.where); got != tt.want {
+			t.Errorf("clientSide(%q) = %v, want %v", tt.where, got, tt.want)
+		}
+	}
+}
+
+func TestSERPFilter_Validate(t *testing.T) {
+	if err := (serpFilter{include: []string{"video"}, exclude: []string{"local_pack"}}).validate(); err != nil {
+		t.Errorf("validate() error = %v", err)
+	}
+	err := (serpFilter{exclude: []string{"featured-snippet"}}).validate()
+	if err == nil || !strings.Contains(err.Error(), "featured-snippet") {
+		t.Errorf("validate() error = %v, want one naming the unknown feature", err)
+	}
+}
+
+func TestSERPFilter_FilterKeywords(t *testing.T) {
+	body := `{"keywords":[
+		{"keyword":"a","serp_features":["video","sitelinks"],"volume":10},
+		{"keyword":"b","serp_features":["featured_snippet","local_pack"]},
+		{"keyword":"c","serp_features":"featured_snippet"},
+		{"keyword":"d","serp_features":null}],"next":"x"}`
+	filter := serpFilter{include: []string{"video", "featured_snippet"}, exclude: []string{"local_pack"}}
+
+	out, err := filter.filterKeywords(context.Background(), strings.NewReader(body), nil)
+	if err != nil {
+		t.Fatalf("filterKeywords() error = %v", err)
+	}
+	data, _ := io.ReadAll(out)
+	var got struct {
+		Keywords []map[string]interface{} `json:"keywords"`
+		Next     string                   `json:"next"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid filtered body %s: %v", data, err)
+	}
+
+	var names []string
+	for _, k := range got.Keywords {
+		names = append(names, k["keyword"].(string))
+	}
+	if want := []string{"a", "c"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("keywords = %v, want %v", names, want)
+	}
+	if got.Keywords[0]["volume"] != float64(10) || got.Next != "x" {
+		t.Errorf("filtered body = %s, want other fields kept", data)
+	}
+}
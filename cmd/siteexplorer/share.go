@@ -0,0 +1,121 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+const (
+	// competitorsPath is the endpoint domains' traffic share is computed from
+	competitorsPath = "/site-explorer/organic-competitors"
+
+	// metricsPath is the endpoint the target's own traffic is requested from
+	metricsPath = "/site-explorer/metrics"
+)
+
+// shareBy are the accepted values for the --by flag
+var shareBy = []string{"pages", "domains"}
+
+// trafficShare returns a transform turning a top-pages response, or an
+// organic-competitors one with by domains, into each row's share of their
+// total traffic. Competing domains are ranked with the target, whose traffic
+// is requested from the metrics endpoint with the same target, mode,
+// country, and date as params.
+func trafficShare(by string, params url.Values) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		var rows []models.TrafficShare
+		if by == "domains" {
+			var resp models.OrganicCompetitorsResponse
+			if _, err := decodeResponse(body, &resp); err != nil {
+				return nil, fmt.Errorf("failed to parse response: %w", err)
+			}
+			own, err := targetTraffic(ctx, fetch, params)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, models.TrafficShare{Entity: params.Get("target"), Traffic: own})
+			for _, c := range resp.Competitors {
+				rows = append(rows, models.TrafficShare{Entity: c.Domain, Traffic: c.Traffic})
+			}
+		} else {
+			var resp models.TopPagesResponse
+			if _, err := decodeResponse(body, &resp); err != nil {
+				return nil, fmt.Errorf("failed to parse response: %w", err)
+			}
+			for _, p := range resp.Pages {
+				rows = append(rows, models.TrafficShare{Entity: p.URL, Traffic: p.Traffic})
+			}
+		}
+
+		data, err := json.Marshal(models.TrafficSharesResponse{Shares: shareOfTotal(rows)})
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// targetTraffic requests the organic traffic of the target of params
+func targetTraffic(ctx context.Context, fetch fetchFunc, params url.Values) (int, error) {
+	p := url.Values{}
+	for _, name := range []string{"target", "mode", "country", "date"} {
+		if v := params.Get(name); v != "" {
+			p.Set(name, v)
+		}
+	}
+	body, _, err := fetch(ctx, metricsPath, p, pageOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("target traffic: %w", err)
+	}
+	defer body.Close()
+
+	var resp models.MetricsResponse
+	if _, err := decodeResponse(body, &resp); err != nil {
+		return 0, fmt.Errorf("target traffic: failed to parse response: %w", err)
+	}
+	return resp.Metrics.OrgTraffic, nil
+}
+
+// shareOfTotal sorts rows by traffic, most first, and sets their percent of
+// the total. Percents are rounded to two decimals by largest remainder, so
+// they sum to exactly 100 unless there is no traffic at all.
+func shareOfTotal(rows []models.TrafficShare) []models.TrafficShare {
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Traffic > rows[j].Traffic })
+
+	total := 0
+	for _, r := range rows {
+		total += max(r.Traffic, 0)
+	}
+	if rows == nil || total == 0 {
+		return append([]models.TrafficShare{}, rows...)
+	}
+
+	// Work in hundredths of a percent: floor each share, then hand the
+	// hundredths lost to rounding to the largest remainders
+	const whole = 10000
+	hundredths := make([]int, len(rows))
+	order := make([]int, len(rows))
+	left := whole
+	for i, r := range rows {
+		hundredths[i] = max(r.Traffic, 0) * whole / total
+		left -= hundredths[i]
+		order[i] = i
+	}
+	remainder := func(i int) int { return max(rows[i].Traffic, 0) * whole % total }
+	sort.SliceStable(order, func(a, b int) bool { return remainder(order[a]) > remainder(order[b]) })
+	for _, i := range order[:left] {
+		hundredths[i]++
+	}
+
+	for i := range rows {
+		rows[i].Percent = float64(hundredths[i]) / 100
+	}
+	return rows
+}
@@ -0,0 +1,95 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestShareOfTotal(t *testing.T) {
+	tests := []struct {
+		name    string
+		traffic []int
+		want    []float64
+	}{
+		{"even thirds", []int{1, 1, 1}, []float64{33.34, 33.33, 33.33}},
+		{"sorted by traffic", []int{1, 3}, []float64{75, 25}},
+		{"largest remainder", []int{2, 2, 3}, []float64{42.86, 28.57, 28.57}},
+		{"no traffic", []int{0, 0}, []float64{0, 0}},
+		{"none", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []models.TrafficShare
+			for i, traffic := range tt.traffic {
+				rows = append(rows, models.TrafficShare{Entity: fmt.Sprint(i), Traffic: traffic})
+			}
+			var got []float64
+			for _, r := range shareOfTotal(rows) {
+				got = append(got, r.Percent)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("percents = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShareOfTotal_SumsTo100(t *testing.T) {
+	var rows []models.TrafficShare
+	for i := 1; i <= 37; i++ {
+		rows = append(rows, models.TrafficShare{Entity: fmt.Sprint(i), Traffic: i * 7919 % 1000})
+	}
+	sum := 0.0
+	for _, r := range shareOfTotal(rows) {
+		sum += r.Percent
+	}
+	if math.Abs(sum-100) > 1e-9 {
+		t.Errorf("percents sum to %v, want 100", sum)
+	}
+}
+
+func TestTrafficShare_Domains(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path+"?country="+r.URL.Query().Get("country"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case competitorsPath:
+			fmt.Fprint(w, `{"competitors":[{"competitor_domain":"a.com","traffic":300},{"competitor_domain":"b.com","traffic":100}]}`)
+		case metricsPath:
+			fmt.Fprint(w, `{"metrics":{"org_traffic":600}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"traffic-share", "-t", "t.com", "--by", "domains", "-c", "us", "--format", "json"})
+	var got struct {
+		Data models.TrafficSharesResponse `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+
+	want := []models.TrafficShare{
+		{Entity: "t.com", Traffic: 600, Percent: 60},
+		{Entity: "a.com", Traffic: 300, Percent: 30},
+		{Entity: "b.com", Traffic: 100, Percent: 10},
+	}
+	if !reflect.DeepEqual(got.Data.Shares, want) {
+		t.Errorf("shares = %+v, want %+v", got.Data.Shares, want)
+	}
+	wantPaths := []string{competitorsPath + "?country=us", metricsPath + "?country=us"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("requests = %v, want %v", paths, wantPaths)
+	}
+}
@@ -1,13 +1,19 @@
 package siteexplorer
 
 import (
-	"context"
-	"encoding/json"
+	"cmp"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aminemat/ahrefs-cli/cmd"
-	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/publicsuffix"
+	"github.com/aminemat/ahrefs-cli/internal/resolve"
 	"github.com/aminemat/ahrefs-cli/pkg/client"
 	"github.com/aminemat/ahrefs-cli/pkg/models"
 	"github.com/aminemat/ahrefs-cli/pkg/output"
@@ -16,7 +22,7 @@ import (
 
 // NewSiteExplorerCmd creates the site-explorer command
 func NewSiteExplorerCmd() *cobra.Command {
-	cmd := &cobra.Command{
+	c := &cobra.Command{
 		Use:   "site-explorer",
 		Short: "Site Explorer API endpoints",
 		Long: `Access Site Explorer data including domain rating, backlinks,
@@ -24,404 +30,923 @@ referring domains, anchors, organic keywords, and more.`,
 		Aliases: []string{"se"},
 	}
 
-	cmd.AddCommand(newDomainRatingCmd())
-	cmd.AddCommand(newBacklinksCmd())
-	cmd.AddCommand(newBacklinksStatsCmd())
-	cmd.AddCommand(newRefDomainsCmd())
-	cmd.AddCommand(newAnchorsCmd())
-	cmd.AddCommand(newOrganicKeywordsCmd())
-	cmd.AddCommand(newTopPagesCmd())
-	cmd.AddCommand(newBrokenBacklinksCmd())
-	cmd.AddCommand(newLinkedDomainsCmd())
-	cmd.AddCommand(newMetricsCmd())
-	cmd.AddCommand(newMetricsHistoryCmd())
-	cmd.AddCommand(newPagesByTrafficCmd())
-	cmd.AddCommand(newBestByLinksCmd())
-
-	return cmd
-}
-
-func newDomainRatingCmd() *cobra.Command {
-	var (
-		target string
-		mode   string
-		date   string
-	)
-
-	cmd := &cobra.Command{
-		Use:   "domain-rating",
-		Short: "Get domain rating for a target",
-		Long: `Get the domain rating (DR) for a domain or URL.
-
-Domain Rating is a metric that shows the strength of a website's backlink profile
-on a logarithmic scale from 0 to 100, with the latter being the strongest.`,
-		Example: `  # Get domain rating for a domain
-  ahrefs site-explorer domain-rating --target example.com
-
-  # Get domain rating for a specific URL
-  ahrefs site-explorer domain-rating --target example.com/page --mode exact
-
-  # Get historical domain rating
-  ahrefs site-explorer domain-rating --target example.com --date 2024-01-01`,
-		RunE: func(cobraCmd *cobra.Command, args []string) error {
-			return runDomainRating(target, mode, date)
-		},
+	for _, e := range endpoints {
+		c.AddCommand(newEndpointCmd(e))
 	}
 
-	cmd.Flags().StringVar(&target, "target", "", "Target domain or URL (required)")
-	cmd.Flags().StringVar(&mode, "mode", "domain", "Mode: exact, domain, prefix, subdomains")
-	cmd.Flags().StringVar(&date, "date", "", "Date for historical data (YYYY-MM-DD)")
-
-	cmd.MarkFlagRequired("target")
-
-	return cmd
+	return c
 }
 
-func newBacklinksStatsCmd() *cobra.Command {
-	var (
-		target string
-		mode   string
-		date   string
-	)
-
-	cmd := &cobra.Command{
-		Use:   "backlinks-stats",
-		Short: "Get backlinks statistics",
-		Long:  "Get aggregated statistics about backlinks for a target.",
-		Example: `  # Get backlinks stats for a domain
-  ahrefs site-explorer backlinks-stats --target example.com
-
-  # Get stats for a specific URL
-  ahrefs site-explorer backlinks-stats --target example.com/page --mode exact`,
-		RunE: func(cobraCmd *cobra.Command, args []string) error {
-			return runBacklinksStats(target, mode, date)
-		},
-	}
+// endpoint describes an API endpoint and the command that requests it. Every
+// command takes --target and --mode; the other flags are opted into.
+type endpoint struct {
+	Name    string
+	Path    string
+	Short   string
+	Long    string
+	Example string
 
-	cmd.Flags().StringVar(&target, "target", "", "Target domain or URL (required)")
-	cmd.Flags().StringVar(&mode, "mode", "domain", "Mode: exact, domain, prefix, subdomains")
-	cmd.Flags().StringVar(&date, "date", "", "Date for historical data (YYYY-MM-DD)")
+	// List endpoints return rows, and take --limit, --offset, --select,
+	// --where, --order-by, and the paging flags
+	List bool
 
-	cmd.MarkFlagRequired("target")
+	// PageURL takes the target as --url, requested in exact mode, in place
+	// of --target and --mode
+	PageURL bool
 
-	return cmd
-}
+	// URLTarget defaults --mode to exact, for an endpoint about a page, and
+	// checks a --target in exact mode is a URL before requesting it
+	URLTarget bool
 
-func newBacklinksCmd() *cobra.Command {
-	var (
-		target string
-		mode   string
-		limit  int
-		offset int
-		sel    string
-		where  string
-	)
-
-	cmd := &cobra.Command{
-		Use:   "backlinks",
-		Short: "Get backlinks for a target",
-		Long:  "List backlinks pointing to a target domain or URL.",
-		Example: `  # Get backlinks for a domain
-  ahrefs site-explorer backlinks --target example.com --limit 100
-
-  # Get specific fields
-  ahrefs site-explorer backlinks --target example.com \
-    --select url_from,domain_rating,anchor --limit 50
-
-  # Filter backlinks
-  ahrefs site-explorer backlinks --target example.com \
-    --where 'domain_rating>50' --limit 100`,
-		RunE: func(cobraCmd *cobra.Command, args []string) error {
-			return runBacklinks(target, mode, limit, offset, sel, where)
-		},
-	}
+	// DefaultSelect is the --select used when none is given
+	DefaultSelect string
 
-	cmd.Flags().StringVar(&target, "target", "", "Target domain or URL (required)")
-	cmd.Flags().StringVar(&mode, "mode", "domain", "Mode: exact, domain, prefix, subdomains")
-	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum number of results")
-	cmd.Flags().IntVar(&offset, "offset", 0, "Offset for pagination")
-	cmd.Flags().StringVar(&sel, "select", "", "Comma-separated list of fields to return")
-	cmd.Flags().StringVar(&where, "where", "", "Filter expression (Ahrefs filter syntax)")
+	// MaxLimit is the most rows a list endpoint returns per request
+	MaxLimit int
 
-	cmd.MarkFlagRequired("target")
+	// OrderBy is the example sort order in the --order-by help
+	OrderBy string
 
-	return cmd
-}
+	// Select adds --select to an endpoint that isn't a list
+	Select bool
 
-func runDomainRating(target, mode, date string) error {
-	flags := cmd.GetGlobalFlags()
+	// Country adds --country
+	Country bool
 
-	// Get API key
-	apiKey := flags.APIKey
-	if apiKey == "" {
-		apiKey = config.GetAPIKey()
-	}
-	if apiKey == "" {
-		return fmt.Errorf("API key required. Set via --api-key flag, AHREFS_API_KEY env var, or 'ahrefs config set-key'")
-	}
+	// Countries lets --country take a comma-separated list, requesting each
+	// country and tagging the rows with it
+	Countries bool
 
-	// Create client
-	c := client.NewClient(client.Config{
-		APIKey: apiKey,
-	})
+	// CountriesResult returns the model for several countries' merged
+	// responses, when it isn't Result's
+	CountriesResult func() interface{}
 
-	// Build request params
-	params := url.Values{}
-	params.Set("target", target)
-	params.Set("mode", mode)
-	if date != "" {
-		params.Set("date", date)
-	}
+	// Date adds --date, for historical data
+	Date bool
 
-	if flags.DryRun {
-		fmt.Printf("✓ Valid request. Would call: GET %s/site-explorer/domain-rating?%s\n",
-			client.BaseURL, params.Encode())
-		return nil
-	}
-
-	if flags.Verbose {
-		fmt.Printf("Requesting: GET /site-explorer/domain-rating?%s\n", params.Encode())
-	}
+	// DateRange adds --date-from and --date-to
+	DateRange bool
 
-	// Make request
-	resp, err := c.Get(context.Background(), "/site-explorer/domain-rating", params)
-	if err != nil {
-		w, _ := output.NewWriter(flags.OutputFormat, flags.OutputFile)
-		w.WriteError(err)
-		return err
-	}
+	// Interval adds --interval, the granularity of a history
+	Interval bool
 
-	// Parse response
-	var result models.DomainRatingResponse
-	if err := json.Unmarshal(resp.Body, &result); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
-	}
+	// SERPFeatures adds --serp-features and --exclude-serp-features, which
+	// filter organic keywords by the features of their results
+	SERPFeatures bool
 
-	// Output result
-	w, err := output.NewWriter(flags.OutputFormat, flags.OutputFile)
-	if err != nil {
-		return err
-	}
-	defer w.Close()
+	// Movement adds --date-compared and --movement, which keeps the organic
+	// keywords that are new, lost, up, or down since the date compared
+	Movement bool
 
-	return w.WriteSuccess(result, &resp.Meta)
-}
+	// ExpandDomains adds --expand-domains, --expand-limit, and --concurrency,
+	// which list the referring domains linking with each of the top anchors
+	ExpandDomains bool
 
-func runBacklinksStats(target, mode, date string) error {
-	flags := cmd.GetGlobalFlags()
+	// LinkFilters adds shortcuts such as --dofollow and --link-type for the
+	// filters listed, which are added to --where
+	LinkFilters linkFilters
 
-	apiKey := flags.APIKey
-	if apiKey == "" {
-		apiKey = config.GetAPIKey()
-	}
-	if apiKey == "" {
-		return fmt.Errorf("API key required")
-	}
+	// FirstSeen adds --first-seen-since and --first-seen-until, which filter
+	// links by when they were first seen
+	FirstSeen bool
 
-	c := client.NewClient(client.Config{
-		APIKey: apiKey,
-	})
+	// History adds --history, --date-from, and --date-to, which pick the
+	// live links, every link ever seen, or those new or lost in a window
+	History bool
 
-	params := url.Values{}
-	params.Set("target", target)
-	params.Set("mode", mode)
-	if date != "" {
-		params.Set("date", date)
-	}
+	// ExcludeDomains adds --exclude-own and --exclude-domain, which leave
+	// out linked domains of the target's own site and of sister sites
+	ExcludeDomains bool
 
-	if flags.DryRun {
-		fmt.Printf("✓ Valid request. Would call: GET %s/site-explorer/backlinks-stats?%s\n",
-			client.BaseURL, params.Encode())
-		return nil
-	}
+	// CompareURL adds --compare-url, which lists a competing page's organic
+	// keywords alongside and marks those only it ranks for
+	CompareURL bool
 
-	if flags.Verbose {
-		fmt.Printf("Requesting: GET /site-explorer/backlinks-stats?%s\n", params.Encode())
-	}
+	// TrafficShare adds --by and turns the rows into their share of the total
+	// traffic: Path's pages, or with --by domains the target's organic
+	// competitors. It leaves out --select, as the columns are fixed.
+	TrafficShare bool
 
-	resp, err := c.Get(context.Background(), "/site-explorer/backlinks-stats", params)
-	if err != nil {
-		w, _ := output.NewWriter(flags.OutputFormat, flags.OutputFile)
-		w.WriteError(err)
-		return err
-	}
+	// GroupByDomain adds --group-by-domain, which aggregates backlinks into
+	// one row per referring domain
+	GroupByDomain bool
 
-	var result models.BacklinksStatsResponse
-	if err := json.Unmarshal(resp.Body, &result); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
-	}
+	// Aggregate adds --aggregate, which rolls referring domains up into one
+	// row per TLD or registrable domain
+	Aggregate bool
 
-	w, err := output.NewWriter(flags.OutputFormat, flags.OutputFile)
-	if err != nil {
-		return err
-	}
-	defer w.Close()
+	// ResolveIPs adds --resolve-ips, which looks up the IPv4 address of each
+	// referring domain in DNS and adds it with its /24 subnet, and
+	// --group-by-subnet, which rolls the domains up into one row per subnet
+	ResolveIPs bool
 
-	return w.WriteSuccess(result, &resp.Meta)
-}
+	// DetectLanguage adds --detect-language, which detects the language of
+	// each row's anchor text and adds it as a column
+	DetectLanguage bool
 
-func runBacklinks(target, mode string, limit, offset int, sel, where string) error {
-	flags := cmd.GetGlobalFlags()
+	// Velocity adds --months and --date-to, and turns the referring domains
+	// history of Path into their net growth in each of those months
+	Velocity bool
 
-	apiKey := flags.APIKey
-	if apiKey == "" {
-		apiKey = config.GetAPIKey()
-	}
-	if apiKey == "" {
-		return fmt.Errorf("API key required")
-	}
+	// Cluster adds --cluster and --clusters-only, which group organic
+	// keywords by the URL ranking best for them, or also by shared words
+	Cluster bool
 
-	c := client.NewClient(client.Config{
-		APIKey: apiKey,
-	})
+	// AnchorSummary adds --summary and --summary-only, which summarise the
+	// anchor texts: their most common words, those without text, and with
+	// --brand, the share of branded anchors
+	AnchorSummary bool
 
-	params := url.Values{}
-	params.Set("target", target)
-	params.Set("mode", mode)
-	params.Set("limit", fmt.Sprintf("%d", limit))
-	if offset > 0 {
-		params.Set("offset", fmt.Sprintf("%d", offset))
-	}
-	if sel != "" {
-		params.Set("select", sel)
-	}
-	if where != "" {
-		params.Set("where", where)
-	}
+	// DRHistogram adds --histogram and --histogram-only, which count the
+	// referring domains in each range of ten domain rating points
+	DRHistogram bool
 
-	if flags.DryRun {
-		fmt.Printf("✓ Valid request. Would call: GET %s/site-explorer/backlinks?%s\n",
-			client.BaseURL, params.Encode())
-		return nil
-	}
+	// MergeURLVariants adds --merge-url-variants, which merges pages whose
+	// URLs differ only by scheme, www., trailing slash, or the host's case
+	MergeURLVariants bool
 
-	if flags.Verbose {
-		fmt.Printf("Requesting: GET /site-explorer/backlinks?%s\n", params.Encode())
-	}
+	// NormalizeText adds --normalize-text and --casefold, which normalize the
+	// text of the first of these columns, such as anchor texts or keywords,
+	// and merge the rows left the same in all of them
+	NormalizeText []string
 
-	resp, err := c.Get(context.Background(), "/site-explorer/backlinks", params)
-	if err != nil {
-		w, _ := output.NewWriter(flags.OutputFormat, flags.OutputFile)
-		w.WriteError(err)
-		return err
-	}
+	// Rules constrain the parameters of a request, and are checked by
+	// --dry-run
+	Rules []paramRule
+
+	// BodyParams sends the parameters in the request body, so encoded, for
+	// operations the API expects them there. Others move to the body only
+	// when the query string grows too long, such as with a long --where.
+	BodyParams client.ParamEncoding
 
-	var result models.BacklinksResponse
-	if err := json.Unmarshal(resp.Body, &result); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
-	}
+	// Count adds --count-only, which requests the total rows from a stats
+	// endpoint in place of the rows, with what fetching them all would cost
+	Count *rowCount
 
-	w, err := output.NewWriter(flags.OutputFormat, flags.OutputFile)
-	if err != nil {
-		return err
-	}
-	defer w.Close()
+	// Result returns a new response model to decode into
+	Result func() interface{}
+}
 
-	return w.WriteSuccess(result, &resp.Meta)
+// requestFlags holds the flag values of an endpoint command
+type requestFlags struct {
+	target   string
+	mode     string
+	limit    int
+	offset   int
+	page     pageOptions
+	sel      string
+	where    string
+	orderBy  string
+	country  string
+	date     string
+	dateFrom string
+	dateTo   string
+	interval string
+
+	firstSeenSince string
+	firstSeenUntil string
+	links          linkFlags
+
+	history     string
+	historyFrom string
+	historyTo   string
+
+	dateCompared  string
+	movement      string
+	groupByDomain bool
+
+	serpFeatures        []string
+	excludeSERPFeatures []string
+
+	expandDomains int
+	expandLimit   int
+	concurrency   int
+
+	excludeOwn     bool
+	excludeDomains []string
+
+	by         string
+	compareURL string
+
+	detectLanguage bool
+	aggregate      string
+	cluster        string
+	clustersOnly   bool
+	months         int
+	mergeVariants  bool
+	normalizeText  bool
+	casefold       bool
+	summary        bool
+	summaryOnly    bool
+	brand          []string
+	histogram      bool
+	histogramOnly  bool
+
+	resolveIPs         bool
+	groupBySubnet      bool
+	resolveTimeout     time.Duration
+	resolveConcurrency int
+
+	stats     bool
+	statsOnly bool
+
+	// modeSet is whether --mode was given, rather than left at its default
+	modeSet  bool
+	autoMode bool
+
+	// now anchors relative dates, in --timezone; time.Now() when unset
+	now time.Time
+
+	countOnly bool
+	sample    int
+	lenient   bool
+	last      bool
+	schema    bool
+
+	// explainFilter prints how where was built from --where and the
+	// shortcuts
+	explainFilter bool
 }
 
-func newRefDomainsCmd() *cobra.Command {
-	var (
-		target  string
-		mode    string
-		limit   int
-		offset  int
-		sel     string
-		where   string
-		orderBy string
-	)
-
-	cmd := &cobra.Command{
-		Use:   "refdomains",
-		Short: "Get referring domains",
-		Long:  "List referring domains that contain backlinks to the target.",
-		Example: `  # Get referring domains for a domain
-  ahrefs site-explorer refdomains --target example.com --limit 100
-
-  # Get specific fields
-  ahrefs site-explorer refdomains --target example.com \
-    --select domain,domain_rating,backlinks --limit 50
-
-  # Filter and sort by domain rating
-  ahrefs site-explorer refdomains --target example.com \
-    --where 'domain_rating>50' --order-by domain_rating:desc --limit 100`,
+// newEndpointCmd creates the command for e
+func newEndpointCmd(e endpoint) *cobra.Command {
+	var f requestFlags
+
+	c := &cobra.Command{
+		Use:         e.Name,
+		Short:       e.Short,
+		Long:        e.Long,
+		Example:     e.Example,
+		Annotations: map[string]string{cmd.ListsPresetsAnnotation: "true", cmd.EndpointAnnotation: e.Path},
 		RunE: func(cobraCmd *cobra.Command, args []string) error {
-			return runRefDomains(target, mode, limit, offset, sel, where, orderBy)
+			if flags := cmd.GetGlobalFlags(cobraCmd.Context()); flags.Preset == cmd.ListPresets {
+				return e.listPresets(flags)
+			}
+			if f.schema {
+				return writeSchema(cmd.GetGlobalFlags(cobraCmd.Context()).Stdout, e.Result())
+			}
+			if err := e.applyPreset(cobraCmd); err != nil {
+				return err
+			}
+			f.modeSet = cobraCmd.Flags().Changed("mode")
+			f.now = cmd.GetGlobalFlags(cobraCmd.Context()).Now()
+			params, page, err := e.request(f)
+			if err != nil {
+				return err
+			}
+			if target, mode := params.Get("target"), params.Get("mode"); mode != f.mode {
+				cmd.GetGlobalFlags(cobraCmd.Context()).Log().Info(fmt.Sprintf("--auto-mode: requesting %s with --mode %s", target, mode))
+			} else if target != f.target {
+				cmd.GetGlobalFlags(cobraCmd.Context()).Log().Debug("Normalized the target for its mode", "target", target, "mode", mode)
+			}
+			if params, err = checkParams(e.Path, params, f.lenient, cmd.GetGlobalFlags(cobraCmd.Context()).Log()); err != nil {
+				return err
+			}
+			if f.explainFilter {
+				// The filter was built by e.request already
+				where, _ := e.requestFilter(f)
+				explainFilter(cobraCmd.ErrOrStderr(), cmd.GetGlobalFlags(cobraCmd.Context()).LogFormat, where, params.Get("where"), f.responseFilters())
+			}
+			last := newLastResponse(cobraCmd, f.last)
+			if f.countOnly {
+				counted, err := e.countRequest(params, page)
+				if err != nil {
+					return err
+				}
+				return runRequest(cobraCmd.Context(), e.Count.Path, counted, pageOptions{}, last, &models.RowCount{}, e.countRows())
+			}
+			if cmd.GetGlobalFlags(cobraCmd.Context()).DryRun {
+				// The flags' parameters, before any are requested separately
+				if err := e.checkRules(e.params(f), f.anchor()); err != nil {
+					return err
+				}
+			}
+			result, tr := e.Result(), transform(nil)
+			if len(page.Countries) > 1 && e.CountriesResult != nil {
+				result = e.CountriesResult()
+			}
+			if f.groupByDomain {
+				result, tr = &models.BacklinkDomainsResponse{}, groupBacklinks
+			}
+			if f.aggregate != "" {
+				result, tr = &models.RefDomainGroupsResponse{}, aggregateRefDomains(f.aggregate)
+			}
+			if filter := f.serpFilter(); filter.clientSide(f.where) {
+				tr = filter.filterKeywords
+			}
+			if f.movement != "" {
+				result, tr = &models.KeywordMovementsResponse{}, keywordMovements(e.Path, params, page, f.dateCompared, f.movement)
+			}
+			if f.interval != "" {
+				tr = downsample(f.interval)
+			}
+			if x := f.exclusion(); !x.empty() {
+				tr = x.filterDomains
+			}
+			if f.expandDomains > 0 {
+				x := expansion(f, cmd.GetGlobalFlags(cobraCmd.Context()))
+				result, tr = &models.AnchorDomainsResponse{}, x.transform(params.Get("target"), params.Get("mode"))
+				if x.flatten {
+					result = &models.AnchorDomainRowsResponse{}
+				}
+			}
+			var extra []string
+			if f.resolveIPs || f.groupBySubnet {
+				r := resolve.New(f.resolveTimeout, f.resolveConcurrency)
+				log := cmd.GetGlobalFlags(cobraCmd.Context()).Log()
+				if f.groupBySubnet {
+					result, tr = &models.SubnetGroupsResponse{}, groupBySubnet(r, log, tr)
+				} else {
+					result, tr, extra = &models.RefDomainIPsResponse{}, resolveIPs(r, log, tr), ipColumns
+				}
+			}
+			if f.detectLanguage {
+				result, tr, extra = languageResult(e.Path), anchorLanguages, languageColumns
+			}
+			if f.cluster != "" || f.clustersOnly {
+				by := cmp.Or(f.cluster, "url")
+				if f.clustersOnly {
+					result, tr = &models.KeywordClustersResponse{}, clusterKeywords(by, true, tr)
+				} else {
+					result, tr, extra = &models.ClusteredKeywordsResponse{}, clusterKeywords(by, false, tr), clusterColumns
+				}
+			}
+			if f.mergeVariants {
+				result, tr, extra = mergedResult(e.Path), mergeURLVariants(tr), mergeColumns
+			}
+			if f.normalizeText {
+				result, tr, extra = normalizedResult(e.Path), normalizeText(e.NormalizeText, f.casefold, tr), normalizeColumns
+			}
+			if f.summary || f.summaryOnly {
+				result, tr = &models.AnchorSummaryResponse{}, anchorSummary(f.brand, f.summaryOnly, cmd.GetGlobalFlags(cobraCmd.Context()).Log())
+				if f.summaryOnly {
+					result = &models.AnchorSummary{}
+				}
+			}
+			if f.histogram || f.histogramOnly {
+				flags := cmd.GetGlobalFlags(cobraCmd.Context())
+				bars := flags.OutputFormat == string(output.FormatTable)
+				var table io.Writer = os.Stderr
+				if flags.Quiet {
+					table = nil
+				}
+				result, tr = &models.RefDomainsHistogramResponse{}, drHistogram(f.histogramOnly, bars, table)
+				if f.histogramOnly {
+					result = &models.DRHistogram{}
+					if bars {
+						result = &models.DRHistogramBars{}
+					}
+				}
+			}
+			if page.Sample > 0 {
+				// The models don't have the offsets the rows are tagged with
+				result, extra = new(interface{}), sampleColumns
+			}
+			if f.compareURL != "" {
+				result, tr, extra = &models.PageKeywordGapsResponse{}, compareURL(e.Path, params, page, f.compareURL), gapColumns
+			}
+			if e.Velocity {
+				result, tr = &models.LinkVelocityResponse{}, linkVelocity(params, f.months, cmd.GetGlobalFlags(cobraCmd.Context()).Log())
+			}
+			path := e.Path
+			if e.TrafficShare {
+				result, tr = &models.TrafficSharesResponse{}, trafficShare(f.by, params)
+				if f.by == "domains" {
+					path = competitorsPath
+				}
+			}
+			if f.stats || f.statsOnly {
+				flags := cmd.GetGlobalFlags(cobraCmd.Context())
+				key, columns := listFields(result)
+				if sel := selectColumns(params); sel != nil {
+					columns = append(sel, extra...)
+				}
+				embed := flags.OutputFormat == string(output.FormatJSON) || flags.OutputFormat == string(output.FormatYAML)
+				var table io.Writer = os.Stderr
+				if flags.Quiet {
+					table = nil
+				}
+				tr = fieldStats(key, columns, f.statsOnly, embed, table, tr)
+				if f.statsOnly {
+					result = &models.FieldStatsResponse{}
+				} else if embed {
+					// The models don't have the stats
+					result = new(interface{})
+				}
+			}
+			return runRequest(cobraCmd.Context(), path, params, page, last, result, tr, extra...)
 		},
 	}
 
-	cmd.Flags().StringVar(&target, "target", "", "Target domain or URL (required)")
-	cmd.Flags().StringVar(&mode, "mode", "domain", "Mode: exact, domain, prefix, subdomains")
-	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum number of results")
-	cmd.Flags().IntVar(&offset, "offset", 0, "Offset for pagination")
-	cmd.Flags().StringVar(&sel, "select", "", "Comma-separated list of fields to return")
-	cmd.Flags().StringVar(&where, "where", "", "Filter expression (Ahrefs filter syntax)")
-	cmd.Flags().StringVar(&orderBy, "order-by", "", "Sort order (e.g., domain_rating:desc)")
-
-	cmd.MarkFlagRequired("target")
-
-	return cmd
+	if e.PageURL {
+		c.Flags().StringVar(&f.target, "url", "", "Page URL (required)")
+		c.MarkFlagRequired("url")
+		f.mode = models.ModeExact.String()
+	} else if e.URLTarget {
+		addTargetFlag(c, &f.target)
+		addModeFlag(c, &f.mode, models.ModeExact)
+	} else {
+		addTargetFlag(c, &f.target)
+		addModeFlag(c, &f.mode, models.ModeDomain)
+		c.Flags().BoolVar(&f.autoMode, "auto-mode", false, "When --target is a URL, request it with --mode prefix if it ends in a slash and --mode exact otherwise, instead of failing")
+		c.MarkFlagsMutuallyExclusive("mode", "auto-mode")
+	}
+	if e.List {
+		addLimitFlag(c, &f.limit)
+		c.Flags().IntVar(&f.offset, "offset", 0, "Offset for pagination")
+		addPageFlags(c, &f.page)
+	}
+	if (e.List || e.Select) && !e.TrafficShare {
+		c.Flags().StringVar(&f.sel, "select", "", "Comma-separated list of fields to return")
+	}
+	if e.List {
+		addWhereFlags(c, &f.where)
+		c.Flags().BoolVar(&f.explainFilter, "explain-filter", false, "Print how the filter expression was built from --where and the shortcuts to stderr")
+		c.Flags().StringVar(&f.orderBy, "order-by", "", "Sort order (e.g., "+e.OrderBy+")")
+	}
+	if e.Countries {
+		addCountriesFlag(c, &f.country)
+	} else if e.Country {
+		addCountryFlag(c, &f.country)
+	}
+	if e.Date {
+		c.Flags().StringVar(&f.date, "date", "", "Date for historical data (YYYY-MM-DD)")
+	}
+	if e.DateRange {
+		c.Flags().StringVar(&f.dateFrom, "date-from", "", "Start date (YYYY-MM-DD)")
+		c.Flags().StringVar(&f.dateTo, "date-to", "", "End date (YYYY-MM-DD)")
+	}
+	if e.Velocity {
+		c.Flags().IntVar(&f.months, "months", 12, "Number of months to report, ending with the month of --date-to")
+		c.Flags().StringVar(&f.dateTo, "date-to", "", "Last day of the report (YYYY-MM-DD; default today)")
+	}
+	if e.Interval {
+		c.Flags().StringVar(&f.interval, "interval", "", "Granularity of the history: daily, weekly, monthly (the last value of each interval)")
+		cmd.SetAllowedValues(c, "interval", models.Strings(models.Intervals())...)
+	}
+	if e.SERPFeatures {
+		c.Flags().StringSliceVar(&f.serpFeatures, "serp-features", nil, "Only keywords whose results have any of these SERP features (e.g., featured_snippet,people_also_ask)")
+		c.Flags().StringSliceVar(&f.excludeSERPFeatures, "exclude-serp-features", nil, "Leave out keywords whose results have any of these SERP features")
+		cmd.SetAllowedListValues(c, "serp-features", serpFeatures...)
+		cmd.SetAllowedListValues(c, "exclude-serp-features", serpFeatures...)
+	}
+	if e.Movement {
+		c.Flags().StringVar(&f.dateCompared, "date-compared", "", "Date to compare with (YYYY-MM-DD)")
+		c.Flags().StringVar(&f.movement, "movement", "", "Only keywords that are new, lost, up, or down since --date-compared, with both positions and the change: "+strings.Join(movements, ", "))
+		cmd.SetAllowedValues(c, "movement", movements...)
+		c.MarkFlagsMutuallyExclusive("select", "movement")
+		c.MarkFlagsMutuallyExclusive("cursor", "movement")
+		c.MarkFlagsMutuallyExclusive("resume", "movement")
+	}
+	if e.ExpandDomains {
+		c.Flags().IntVar(&f.expandDomains, "expand-domains", 0, "List the top referring domains of the first N anchors, with a refdomains request per anchor")
+		c.Flags().IntVar(&f.expandLimit, "expand-limit", 5, "Referring domains listed per anchor with --expand-domains")
+		c.Flags().IntVar(&f.concurrency, "concurrency", 4, "Most refdomains requests in flight at once with --expand-domains")
+		c.MarkFlagsMutuallyExclusive("select", "expand-domains")
+	}
+	if e.LinkFilters != nil {
+		addLinkFlags(c, e.LinkFilters, &f.links)
+	}
+	if e.FirstSeen {
+		c.Flags().StringVar(&f.firstSeenSince, "first-seen-since", "", "Only links first seen on or after this date (YYYY-MM-DD, today, or a time ago such as 30d, 6w, 3m, 1y)")
+		c.Flags().StringVar(&f.firstSeenUntil, "first-seen-until", "", "Only links first seen on or before this date (YYYY-MM-DD, today, or a time ago)")
+	}
+	if e.History {
+		c.Flags().StringVar(&f.history, "history", "", "Which links: live (the default), all_time (lost ones too), or new or lost from --date-from to --date-to")
+		c.Flags().StringVar(&f.historyFrom, "date-from", "", "First day of the window of --history new or lost (YYYY-MM-DD, today, or a time ago such as 30d)")
+		c.Flags().StringVar(&f.historyTo, "date-to", "", "Last day of the window of --history new or lost (default today)")
+		cmd.SetAllowedValues(c, "history", histories...)
+	}
+	if e.ExcludeDomains {
+		c.Flags().BoolVar(&f.excludeOwn, "exclude-own", false, "Leave out domains sharing the target's registrable domain, such as its subdomains")
+		c.Flags().StringArrayVar(&f.excludeDomains, "exclude-domain", nil, "Leave out this domain and its subdomains, e.g. a sister site (repeatable)")
+	}
+	if e.CompareURL {
+		c.Flags().StringVar(&f.compareURL, "compare-url", "", "Competing page URL: list its keywords too, marking those only it ranks for")
+		c.MarkFlagsMutuallyExclusive("cursor", "compare-url")
+		c.MarkFlagsMutuallyExclusive("resume", "compare-url")
+	}
+	if e.TrafficShare {
+		c.Flags().StringVar(&f.by, "by", "pages", "Share of traffic by: pages (top pages), domains (the target and its organic competitors)")
+		cmd.SetAllowedValues(c, "by", shareBy...)
+	}
+	if e.GroupByDomain {
+		c.Flags().BoolVar(&f.groupByDomain, "group-by-domain", false, "Aggregate backlinks into one row per referring domain: links, best DR, anchors, dofollow share, first seen")
+		c.MarkFlagsMutuallyExclusive("select", "group-by-domain")
+	}
+	if e.Aggregate {
+		c.Flags().StringVar(&f.aggregate, "aggregate", "", "Roll referring domains up into one row per TLD or registrable domain: domains, backlinks, max DR ("+strings.Join(models.Strings(models.Aggregations()), ", ")+")")
+		cmd.SetAllowedValues(c, "aggregate", models.Strings(models.Aggregations())...)
+		c.MarkFlagsMutuallyExclusive("select", "aggregate")
+	}
+	if e.DetectLanguage {
+		c.Flags().BoolVar(&f.detectLanguage, "detect-language", false, "Add the language of each anchor text, detected offline, and its confidence: an ISO 639-1 code, or und for text too short to tell")
+		for _, name := range languageConflicts {
+			if c.Flags().Lookup(name) != nil {
+				c.MarkFlagsMutuallyExclusive("detect-language", name)
+			}
+		}
+	}
+	if e.Cluster {
+		c.Flags().StringVar(&f.cluster, "cluster", "", "Add cluster_id and cluster_label columns, grouping keywords by the URL ranking best for them ("+strings.Join(clusterings, ", ")+": also by shared words)")
+		cmd.SetAllowedValues(c, "cluster", clusterings...)
+		c.Flags().BoolVar(&f.clustersOnly, "clusters-only", false, "Write one row per cluster in place of the keywords: label, keywords, total volume and traffic, URL")
+		c.MarkFlagsMutuallyExclusive("select", "clusters-only")
+		for _, name := range clusterConflicts {
+			if c.Flags().Lookup(name) != nil {
+				c.MarkFlagsMutuallyExclusive("cluster", name)
+				c.MarkFlagsMutuallyExclusive("clusters-only", name)
+			}
+		}
+	}
+	if e.MergeURLVariants {
+		c.Flags().BoolVar(&f.mergeVariants, "merge-url-variants", false, "Merge pages whose URLs differ only by scheme, www., trailing slash, or host case: traffic and backlinks summed, the highest UR, and a merged_from count")
+	}
+	if e.AnchorSummary {
+		c.Flags().BoolVar(&f.summary, "summary", false, "Add a summary of the anchor texts: their most common words weighted by backlinks, and how many are empty or images")
+		c.Flags().BoolVar(&f.summaryOnly, "summary-only", false, "Write the summary of --summary in place of the anchor texts, a row per word")
+		c.Flags().StringSliceVar(&f.brand, "brand", nil, "Brand terms, such as the brand and domain names, to split the summary into branded and non-branded anchors by (comma-separated)")
+		c.MarkFlagsMutuallyExclusive("summary", "summary-only")
+		c.MarkFlagsMutuallyExclusive("select", "summary-only")
+		for _, name := range summaryConflicts {
+			if c.Flags().Lookup(name) != nil {
+				c.MarkFlagsMutuallyExclusive("summary", name)
+				c.MarkFlagsMutuallyExclusive("summary-only", name)
+			}
+		}
+	}
+	if e.DRHistogram {
+		c.Flags().BoolVar(&f.histogram, "histogram", false, "Add the number of referring domains in each domain rating range (0-10, 11-20, ..., 91-100), written to stderr as a table")
+		c.Flags().BoolVar(&f.histogramOnly, "histogram-only", false, "Write the histogram of --histogram in place of the referring domains, a row per range")
+		c.MarkFlagsMutuallyExclusive("histogram", "histogram-only")
+		c.MarkFlagsMutuallyExclusive("select", "histogram-only")
+		for _, name := range histogramConflicts {
+			if c.Flags().Lookup(name) != nil {
+				c.MarkFlagsMutuallyExclusive("histogram", name)
+				c.MarkFlagsMutuallyExclusive("histogram-only", name)
+			}
+		}
+	}
+	if len(e.NormalizeText) > 0 {
+		c.Flags().BoolVar(&f.normalizeText, "normalize-text", false, "Normalize the "+e.NormalizeText[0]+" text (NFC, ASCII quotes, no zero-width characters, single spaces) and merge rows left the same, with a normalized_from count")
+		c.Flags().BoolVar(&f.casefold, "casefold", false, "Lowercase the text too with --normalize-text, merging rows differing only by case")
+		for _, name := range normalizeConflicts {
+			if c.Flags().Lookup(name) != nil {
+				c.MarkFlagsMutuallyExclusive("normalize-text", name)
+			}
+		}
+	}
+	if e.ResolveIPs {
+		c.Flags().BoolVar(&f.resolveIPs, "resolve-ips", false, "Add the IPv4 address each referring domain resolves to, and its /24 subnet, looked up in DNS without using units")
+		c.Flags().BoolVar(&f.groupBySubnet, "group-by-subnet", false, "Roll referring domains up into one row per /24 subnet they resolve to: domains, addresses, backlinks, max DR, domain names")
+		c.Flags().DurationVar(&f.resolveTimeout, "resolve-timeout", 5*time.Second, "Longest wait for a DNS answer per domain with --resolve-ips or --group-by-subnet; a domain not answered is left unresolved")
+		c.Flags().IntVar(&f.resolveConcurrency, "resolve-concurrency", 16, "Most DNS lookups in flight at once with --resolve-ips or --group-by-subnet")
+		c.MarkFlagsMutuallyExclusive("resolve-ips", "group-by-subnet")
+		c.MarkFlagsMutuallyExclusive("select", "group-by-subnet")
+		for _, name := range ipConflicts {
+			if c.Flags().Lookup(name) != nil {
+				c.MarkFlagsMutuallyExclusive("resolve-ips", name)
+				c.MarkFlagsMutuallyExclusive("group-by-subnet", name)
+			}
+		}
+	}
+	if e.Count != nil {
+		c.Flags().BoolVar(&f.countOnly, "count-only", false, "Print the total rows and the units a full export with --all would use, with one stats request")
+		for _, name := range countConflicts {
+			if c.Flags().Lookup(name) != nil {
+				c.MarkFlagsMutuallyExclusive("count-only", name)
+			}
+		}
+	}
+	if e.List && !e.TrafficShare {
+		c.Flags().IntVar(&f.sample, "sample", 0, "Fetch a sample of this many rows from the start, middle, and end of the rows, counted first, each with its sample_offset")
+		for _, name := range sampleConflicts {
+			if c.Flags().Lookup(name) != nil {
+				c.MarkFlagsMutuallyExclusive("sample", name)
+			}
+		}
+	}
+	if e.List {
+		c.Flags().BoolVar(&f.stats, "stats", false, "Add the count, min, max, mean, median, p90, and sum of each numeric column, and the distinct values of the others, written to stderr as a table")
+		c.Flags().BoolVar(&f.statsOnly, "stats-only", false, "Write the statistics of --stats in place of the rows, a row per column")
+		c.MarkFlagsMutuallyExclusive("stats", "stats-only")
+		if c.Flags().Lookup("select") != nil {
+			c.MarkFlagsMutuallyExclusive("select", "stats-only")
+		}
+		for _, name := range statsConflicts {
+			if c.Flags().Lookup(name) != nil {
+				c.MarkFlagsMutuallyExclusive("stats", name)
+				c.MarkFlagsMutuallyExclusive("stats-only", name)
+			}
+		}
+	}
+	c.Flags().BoolVar(&f.lenient, "lenient", false, "Leave out parameters the endpoint doesn't accept, with a warning, instead of failing")
+	c.Flags().BoolVar(&f.last, "last", false, "Write the response of the last successful run with the same flags again, without an API call")
+	c.Flags().BoolVar(&f.schema, cmd.SchemaFlag, false, "Print the JSON Schema of the response data, without an API call")
+
+	return c
 }
 
-func runRefDomains(target, mode string, limit, offset int, sel, where, orderBy string) error {
-	flags := cmd.GetGlobalFlags()
-
-	apiKey := flags.APIKey
-	if apiKey == "" {
-		apiKey = config.GetAPIKey()
+// request returns the parameters and paging for a request with the flag
+// values f. A --limit above the endpoint's maximum is fetched in chunks of the
+// maximum, unless every page is being fetched anyway.
+func (e endpoint) request(f requestFlags) (url.Values, pageOptions, error) {
+	params, page := e.params(f), f.page
+	switch {
+	case page.MaxRows < 0:
+		return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --max-rows %d", page.MaxRows), "Use a --max-rows of at least 1, or 0 for every page")
+	case page.MaxRows > 0 && !page.All && !page.Resume:
+		return nil, page, cmd.NewError(cmd.CodeUsage, "--max-rows bounds the rows of --all", "Add --all, or use --limit for a single request")
+	case page.MaxRows > 0:
+		page.Max = page.MaxRows
+	}
+	target, mode, err := resolveTarget(f.target, f.mode, f.modeSet, f.autoMode)
+	if err != nil {
+		return nil, page, err
 	}
-	if apiKey == "" {
-		return fmt.Errorf("API key required")
+	if e.URLTarget && mode == models.ModeExact {
+		if err := checkPageURL(target); err != nil {
+			return nil, page, err
+		}
 	}
-
-	c := client.NewClient(client.Config{
-		APIKey: apiKey,
-	})
-
-	params := url.Values{}
 	params.Set("target", target)
-	params.Set("mode", mode)
-	params.Set("limit", fmt.Sprintf("%d", limit))
-	if offset > 0 {
-		params.Set("offset", fmt.Sprintf("%d", offset))
-	}
-	if sel != "" {
-		params.Set("select", sel)
+	params.Set("mode", mode.String())
+	countries := parseCountries(f.country)
+	for _, country := range countries {
+		if _, err := models.ParseCountry(country); err != nil {
+			return nil, page, cmd.NewError(cmd.CodeUsage, err.Error(), "Use a two-letter ISO 3166-1 country code, e.g. --country us")
+		}
+	}
+	if e.Countries && len(countries) > 1 {
+		if page.Cursor != "" || page.Resume || f.movement != "" {
+			return nil, page, cmd.NewError(cmd.CodeUsage, "--cursor, --resume, and --movement take a single --country",
+				"Run the command once per country")
+		}
+		params.Del("country")
+		page.Countries = countries
+	}
+	if f.movement != "" {
+		if f.dateCompared == "" {
+			return nil, page, cmd.NewError(cmd.CodeUsage, "--movement needs --date-compared", "Add --date-compared YYYY-MM-DD, the date to compare --date with")
+		}
+		// The dates are requested separately and compared here
+		params.Del("date_compared")
+	}
+	if f.interval != "" {
+		if columns := selectColumns(params); columns != nil && !slices.Contains(columns, "date") {
+			// Rows are downsampled by their date
+			params.Set("select", params.Get("select")+",date")
+		}
+	}
+	if e.Velocity {
+		if f.months < 1 {
+			return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --months %d", f.months), "Use --months 1 or more")
+		}
+		end := f.anchor()
+		if f.dateTo != "" {
+			var err error
+			if end, err = time.Parse(dateLayout, f.dateTo); err != nil {
+				return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --date-to %q", f.dateTo), "Use a YYYY-MM-DD date")
+			}
+		}
+		// The month before the first is requested too, to measure its growth
+		params.Set("date_from", velocityStart(end, f.months).Format(dateLayout))
+		params.Set(intervalParam, "monthly")
+	}
+	if f.cluster != "" {
+		if columns := selectColumns(params); columns != nil {
+			// Keywords are clustered by these columns
+			for _, field := range clusterFields {
+				if !slices.Contains(columns, field) {
+					params.Set("select", params.Get("select")+","+field)
+				}
+			}
+		}
+	}
+	if f.mergeVariants {
+		if columns := selectColumns(params); columns != nil && !slices.Contains(columns, "url") {
+			// Pages are merged by their URL
+			params.Set("select", params.Get("select")+",url")
+		}
+	}
+	if f.casefold && !f.normalizeText {
+		return nil, page, cmd.NewError(cmd.CodeUsage, "--casefold lowercases the text of --normalize-text", "Add --normalize-text")
+	}
+	if f.normalizeText {
+		if columns := selectColumns(params); columns != nil {
+			// Rows are merged by these columns
+			for _, field := range e.NormalizeText {
+				if !slices.Contains(columns, field) {
+					params.Set("select", params.Get("select")+","+field)
+				}
+			}
+		}
+	}
+	if len(f.brand) > 0 && !f.summary && !f.summaryOnly {
+		return nil, page, cmd.NewError(cmd.CodeUsage, "--brand splits the summary of --summary", "Add --summary or --summary-only")
+	}
+	if f.summary {
+		if columns := selectColumns(params); columns != nil {
+			// Anchors are summarised by their text and backlinks
+			for _, field := range summaryFields {
+				if !slices.Contains(columns, field) {
+					params.Set("select", params.Get("select")+","+field)
+				}
+			}
+		}
+	}
+	if f.histogram {
+		if columns := selectColumns(params); columns != nil && !slices.Contains(columns, "domain_rating") {
+			// Domains are counted by their domain rating
+			params.Set("select", params.Get("select")+",domain_rating")
+		}
+	}
+	if f.resolveIPs || f.groupBySubnet {
+		if f.resolveConcurrency < 1 {
+			return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --resolve-concurrency %d", f.resolveConcurrency), "Use a --resolve-concurrency of at least 1")
+		}
+		if f.resolveTimeout <= 0 {
+			return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --resolve-timeout %s", f.resolveTimeout), "Use a --resolve-timeout such as 5s")
+		}
+		if columns := selectColumns(params); columns != nil && !slices.Contains(columns, "domain") {
+			// The domains are looked up
+			params.Set("select", params.Get("select")+",domain")
+		}
+	}
+	if f.expandDomains > 0 {
+		if f.expandLimit < 1 || f.expandLimit > e.MaxLimit {
+			return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --expand-limit %d", f.expandLimit),
+				fmt.Sprintf("Use an --expand-limit between 1 and %d", e.MaxLimit))
+		}
+		if f.concurrency < 1 {
+			return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --concurrency %d", f.concurrency), "Use a --concurrency of at least 1")
+		}
+	}
+	history, _, err := historyRequest(f)
+	if err != nil {
+		return nil, page, err
 	}
-	if where != "" {
-		params.Set("where", where)
+	if history != "" {
+		params.Set("history", history.String())
 	}
-	if orderBy != "" {
-		params.Set("order_by", orderBy)
+	if f.history == historyLost {
+		// Lost links say when and why
+		for _, column := range lostColumns {
+			if columns := selectColumns(params); columns != nil && !slices.Contains(columns, column) {
+				params.Set("select", params.Get("select")+","+column)
+			}
+		}
 	}
+	// Filter shortcuts are added to --where
+	where, err := e.requestFilter(f)
+	if err != nil {
+		return nil, page, err
+	}
+	if where != nil {
+		params.Set("where", where.String())
+	}
+	if f.compareURL != "" {
+		if hostName(f.compareURL) == "" || f.compareURL == f.target {
+			return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --compare-url %q", f.compareURL),
+				"Use the URL of another page, e.g. https://competitor.com/post")
+		}
+		// Keywords are matched by name and compared on position and traffic
+		for _, column := range []string{"keyword", "position", "traffic"} {
+			if columns := selectColumns(params); columns != nil && !slices.Contains(columns, column) {
+				params.Set("select", params.Get("select")+","+column)
+			}
+		}
+	}
+	if e.TrafficShare {
+		if f.orderBy == "" {
+			// The biggest shares are the ones worth listing
+			params.Set("order_by", e.OrderBy)
+		}
+	}
+	if x := f.exclusion(); f.excludeOwn || !x.empty() {
+		if f.excludeOwn && x.own == "" {
+			return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("--exclude-own: %q has no registrable domain", f.target),
+				"Use a --target under a registered domain, e.g. example.co.uk")
+		}
+		if columns := selectColumns(params); columns != nil && !slices.Contains(columns, "domain") {
+			// Rows are excluded by their domain
+			params.Set("select", params.Get("select")+",domain")
+		}
+	}
+	if f.detectLanguage {
+		if columns := selectColumns(params); columns != nil && !slices.Contains(columns, "anchor") {
+			// The language is detected in the anchor
+			params.Set("select", params.Get("select")+",anchor")
+		}
+	}
+	if filter := f.serpFilter(); filter.clientSide(f.where) {
+		if f.movement != "" {
+			return nil, page, cmd.NewError(cmd.CodeUsage, "SERP feature filters need a structured --where with --movement",
+				`Write --where as JSON, e.g. {"field":"traffic","is":["gt",100]}`)
+		} else if columns := selectColumns(params); columns != nil && !slices.Contains(columns, "serp_features") {
+			// Filtering the rows needs their features
+			params.Set("select", params.Get("select")+",serp_features")
+		}
+	}
+	if !e.List {
+		return params, page, nil
+	}
+	if f.sample != 0 {
+		return e.sampleRequest(params, page, f.sample)
+	}
+
+	if f.limit < 1 {
+		return nil, page, cmd.NewError(cmd.CodeUsage, fmt.Sprintf("invalid --limit %d", f.limit), "Use a --limit of at least 1")
+	}
+	if f.limit <= e.MaxLimit {
+		return params, page, nil
+	}
+	if page.All || page.Resume {
+		return nil, page, cmd.NewError(cmd.CodeUsage,
+			fmt.Sprintf("--limit %d is above the %d rows %s returns per page", f.limit, e.MaxLimit, e.Name),
+			fmt.Sprintf("Use a --limit of at most %d with --all", e.MaxLimit))
+	}
+
+	params.Set("limit", strconv.Itoa(e.MaxLimit))
+	page.All = true
+	page.Max = f.limit
+	return params, page, nil
+}
 
-	if flags.DryRun {
-		fmt.Printf("✓ Valid request. Would call: GET %s/site-explorer/refdomains?%s\n",
-			client.BaseURL, params.Encode())
-		return nil
-	}
+// expansion returns the --expand-domains settings of f, for the output
+// settings of flags
+func expansion(f requestFlags, flags cmd.GlobalFlags) anchorExpansion {
+	x := anchorExpansion{
+		anchors:     f.expandDomains,
+		domains:     f.expandLimit,
+		concurrency: f.concurrency,
+		flatten:     tabular(flags.OutputFormat),
+	}
+	x.log = flags.Log()
+	return x
+}
 
-	if flags.Verbose {
-		fmt.Printf("Requesting: GET /site-explorer/refdomains?%s\n", params.Encode())
+// tabular reports whether format writes rows as a table, without nested
+// values
+func tabular(format string) bool {
+	switch output.Format(format) {
+	case output.FormatCSV, output.FormatTable, output.FormatMarkdown:
+		return true
 	}
+	return false
+}
 
-	resp, err := c.Get(context.Background(), "/site-explorer/refdomains", params)
-	if err != nil {
-		w, _ := output.NewWriter(flags.OutputFormat, flags.OutputFile)
-		w.WriteError(err)
-		return err
+// exclusion returns the domains --exclude-own and --exclude-domain leave out
+func (f requestFlags) exclusion() domainExclusion {
+	var x domainExclusion
+	if f.excludeOwn {
+		x.own = publicsuffix.Registrable(hostName(f.target))
 	}
-
-	var result models.RefDomainsResponse
-	if err := json.Unmarshal(resp.Body, &result); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
+	for _, d := range f.excludeDomains {
+		if d = hostName(d); d != "" {
+			x.domains = append(x.domains, d)
+		}
 	}
+	return x
+}
 
-	w, err := output.NewWriter(flags.OutputFormat, flags.OutputFile)
-	if err != nil {
-		return err
-	}
-	defer w.Close()
+// serpFilter returns the SERP feature filter set by f
+func (f requestFlags) serpFilter() serpFilter {
+	return serpFilter{include: f.serpFeatures, exclude: f.excludeSERPFeatures}
+}
 
-	return w.WriteSuccess(result, &resp.Meta)
+// params returns the query parameters for a request with the flag values f.
+// Optional parameters are only sent when set.
+func (e endpoint) params(f requestFlags) url.Values {
+	params := url.Values{}
+	params.Set("target", f.target)
+	params.Set("mode", f.mode)
+	if e.List {
+		params.Set("limit", strconv.Itoa(f.limit))
+	}
+	if f.offset > 0 {
+		params.Set("offset", strconv.Itoa(f.offset))
+	}
+
+	sel := f.sel
+	if sel == "" {
+		sel = e.DefaultSelect
+	}
+	optional := []struct{ name, value string }{
+		{"select", sel},
+		{"where", f.where},
+		{"order_by", f.orderBy},
+		{"country", f.country},
+		{"date", f.date},
+		{"date_from", f.dateFrom},
+		{"date_to", f.dateTo},
+		{intervalParam, f.interval},
+		{"date_compared", f.dateCompared},
+	}
+	for _, p := range optional {
+		if p.value != "" {
+			params.Set(p.name, p.value)
+		}
+	}
+	return params
 }
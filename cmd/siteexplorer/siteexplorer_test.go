@@ -0,0 +1,414 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/spf13/pflag"
+)
+
+// findFlag returns the named flag of the named subcommand from command metadata
+func findFlag(t *testing.T, info cmd.CommandInfo, command, flag string) cmd.FlagInfo {
+	t.Helper()
+
+	for _, sub := range info.Subcommands {
+		if sub.Name != command {
+			continue
+		}
+		for _, f := range sub.Flags {
+			if f.Name == flag {
+				return f
+			}
+		}
+		t.Fatalf("flag --%s not found on %s", flag, command)
+	}
+	t.Fatalf("command %s not found", command)
+	return cmd.FlagInfo{}
+}
+
+func TestCommandInfo_FlagMetadata(t *testing.T) {
+	info := cmd.BuildCommandInfo(NewSiteExplorerCmd())
+
+	target := findFlag(t, info, "backlinks", "target")
+	if !target.Required {
+		t.Error("backlinks --target should be reported as required")
+	}
+	if target.Type != "string" {
+		t.Errorf("backlinks --target type = %v, want string", target.Type)
+	}
+
+	limit := findFlag(t, info, "backlinks", "limit")
+	if limit.Required {
+		t.Error("backlinks --limit should not be reported as required")
+	}
+	if limit.Type != "int" {
+		t.Errorf("backlinks --limit type = %v, want int", limit.Type)
+	}
+
+	mode := findFlag(t, info, "backlinks", "mode")
+	if modes := models.Strings(models.Modes()); !reflect.DeepEqual(mode.AllowedValues, modes) {
+		t.Errorf("backlinks --mode allowed values = %v, want %v", mode.AllowedValues, modes)
+	}
+}
+
+func TestCommandInfo_ModeAllowedValuesJSON(t *testing.T) {
+	c, _, err := NewSiteExplorerCmd().Find([]string{"backlinks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(cmd.BuildCommandInfo(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"mode","shorthand":"m","type":"string","usage":"Mode: exact, domain, prefix, subdomains","default":"domain","required":false,"allowed_values":["exact","domain","prefix","subdomains"]}`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("--list-commands JSON = %s, want --mode as %s", data, want)
+	}
+}
+
+func TestMode_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	c, _, err := NewSiteExplorerCmd().Find([]string{"backlinks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Prepare(c, []string{"-t", "t.com", "--mode", "domains"})
+	var coded *cmd.Error
+	if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage || !strings.Contains(coded.Message, "want one of exact, domain, prefix, subdomains") {
+		t.Errorf("error = %v, want a usage error listing the modes", err)
+	}
+}
+
+func TestCountry_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, country := range []string{"uk", "us,usa"} {
+		_, err := execCommand(t, "http://127.0.0.1:0", []string{"organic-keywords", "-t", "t.com", "-c", country})
+		var coded *cmd.Error
+		if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage || !strings.Contains(coded.Message, "invalid country") {
+			t.Errorf("--country %s: error = %v, want a usage error", country, err)
+		}
+	}
+}
+
+func TestCommandInfo_Shorthands(t *testing.T) {
+	info := cmd.BuildCommandInfo(NewSiteExplorerCmd())
+
+	tests := []struct {
+		command   string
+		flag      string
+		shorthand string
+	}{
+		{"backlinks", "target", "t"},
+		{"backlinks", "mode", "m"},
+		{"backlinks", "limit", "l"},
+		{"domain-rating", "target", "t"},
+		{"organic-keywords", "country", "c"},
+		{"metrics", "country", "c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command+" "+tt.flag, func(t *testing.T) {
+			f := findFlag(t, info, tt.command, tt.flag)
+			if f.Shorthand != tt.shorthand {
+				t.Errorf("%s --%s shorthand = %q, want %q", tt.command, tt.flag, f.Shorthand, tt.shorthand)
+			}
+		})
+	}
+}
+
+func TestWhereFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain expression", path: write("plain", "domain_rating>50\n"), want: "domain_rating>50"},
+		{name: "structured JSON", path: write("json", `{"and":[{"field":"anchor","is":["substring","it's \"quoted\""]}]}`), want: `{"and":[{"field":"anchor","is":["substring","it's \"quoted\""]}]}`},
+		{name: "stdin", path: "-", stdin: `{"field":"traffic","is":["gt",100]}`, want: `{"field":"traffic","is":["gt",100]}`},
+		{name: "invalid JSON", path: write("bad", `{"and":[`), wantErr: true},
+		{name: "empty", path: write("empty", "  \n"), wantErr: true},
+		{name: "missing file", path: filepath.Join(dir, "missing"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var where string
+			f := &whereFile{where: &where, stdin: strings.NewReader(tt.stdin)}
+
+			err := f.Set(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if where != tt.want {
+				t.Errorf("where = %q, want %q", where, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhereFile_MutuallyExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter")
+	if err := os.WriteFile(path, []byte("traffic>100"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _, err := NewSiteExplorerCmd().Find([]string{"backlinks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ParseFlags([]string{"--where", "x>1", "--where-file", path}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	if err := c.ValidateFlagGroups(); err == nil {
+		t.Error("ValidateFlagGroups() should reject --where with --where-file")
+	}
+}
+
+func TestLongWhere_SentInBody(t *testing.T) {
+	var conditions []string
+	for i := 0; i < 200; i++ {
+		conditions = append(conditions, fmt.Sprintf(`{"field":"anchor","is":["substring","brand %d"]}`, i))
+	}
+	where := `{"or":[` + strings.Join(conditions, ",") + `]}`
+
+	var method, contentType, sent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		method, contentType, sent = r.Method, r.Header.Get("Content-Type"), r.PostForm.Get("where")
+		w.Write([]byte(`{"backlinks":[]}`))
+	}))
+	defer srv.Close()
+
+	runCommand(t, srv.URL, []string{"backlinks", "--target", "ahrefs.com", "--where", where})
+	if method != http.MethodPost || contentType != "application/x-www-form-urlencoded" || sent != where {
+		t.Errorf("sent %s %s with where of %d bytes, want the form-encoded --where", method, contentType, len(sent))
+	}
+
+	out := runCommand(t, "https://api.ahrefs.com/v3", []string{"backlinks", "--target", "ahrefs.com", "--where", where, "--dry-run"})
+	if !strings.Contains(out, "Would call: POST https://api.ahrefs.com/v3/site-explorer/backlinks\n  Body (form): ") {
+		t.Errorf("dry-run output = %q, want the POST and its body", out)
+	}
+}
+
+// dryRunURL runs a site-explorer command with --dry-run and returns the URL
+// it would request
+func dryRunURL(t *testing.T, args []string) string {
+	t.Helper()
+
+	out := runCommand(t, "https://api.ahrefs.com/v3", append(args, "--dry-run"))
+	line, _, _ := strings.Cut(out, "\n")
+	_, u, ok := strings.Cut(line, "GET ")
+	if !ok {
+		t.Fatalf("%v: dry-run output = %q, want a GET line", args, out)
+	}
+	return u
+}
+
+// runCommand runs the site-explorer subcommand and flags in args against
+// baseURL, and returns its output
+func runCommand(t *testing.T, baseURL string, args []string) string {
+	t.Helper()
+
+	out, err := execCommand(t, baseURL, args)
+	if err != nil {
+		t.Fatalf("%v: error = %v", args, err)
+	}
+	return out
+}
+
+// execCommand is runCommand returning the command's error
+func execCommand(t *testing.T, baseURL string, args []string) (string, error) {
+	t.Helper()
+
+	group := NewSiteExplorerCmd()
+	cmd.AddCommands(group)
+	sub, rest, err := group.Find(args)
+	if err != nil {
+		t.Fatalf("Find(%v) error = %v", args, err)
+	}
+	rest = append(rest, "--api-key", "test-key", "--base-url", baseURL)
+
+	// Global flags outlive a run, so start each from their defaults
+	sub.Root().PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if list, ok := f.Value.(pflag.SliceValue); ok {
+			_ = list.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	var buf bytes.Buffer
+	sub.SetOut(&buf)
+	sub.SetContext(context.Background())
+	if err := cmd.Prepare(sub, rest); err != nil {
+		t.Fatalf("Prepare(%v) error = %v", args, err)
+	}
+	err = sub.RunE(sub, nil)
+	return buf.String(), err
+}
+
+func TestDryRun_URLs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AHREFS_COUNTRY", "")
+
+	tests := []struct {
+		args []string
+		want string // relative to the base URL
+	}{
+		{[]string{"domain-rating", "-t", "example.com"},
+			"/site-explorer/domain-rating?mode=domain&target=example.com"},
+		{[]string{"domain-rating", "-t", "example.com/a", "-m", "exact", "--date", "2024-01-01"},
+			"/site-explorer/domain-rating?date=2024-01-01&mode=exact&target=https%3A%2F%2Fexample.com%2Fa"},
+		{[]string{"backlinks-stats", "-t", "example.com", "--date", "2024-01-01"},
+			"/site-explorer/backlinks-stats?date=2024-01-01&mode=domain&target=example.com"},
+		{[]string{"backlinks", "-t", "example.com"},
+			"/site-explorer/backlinks?limit=100&mode=domain&target=example.com"},
+		{[]string{"backlinks", "-t", "example.com", "-m", "prefix", "-l", "5", "--offset", "10", "--select", "url_from,anchor", "--where", "domain_rating>50"},
+			"/site-explorer/backlinks?limit=5&mode=prefix&offset=10&select=url_from%2Canchor&target=example.com&where=domain_rating%3E50"},
+		{[]string{"refdomains", "-t", "example.com", "--offset", "0", "--order-by", "domain_rating:desc"},
+			"/site-explorer/refdomains?limit=100&mode=domain&order_by=domain_rating%3Adesc&target=example.com"},
+		{[]string{"refdomains", "-t", "example.com", "-l", "1", "--offset", "2", "--select", "domain", "--where", "dofollow>1", "--order-by", "backlinks:asc"},
+			"/site-explorer/refdomains?limit=1&mode=domain&offset=2&order_by=backlinks%3Aasc&select=domain&target=example.com&where=dofollow%3E1"},
+		{[]string{"anchors", "-t", "example.com", "--order-by", "backlinks:desc", "--select", "anchor"},
+			"/site-explorer/anchors?limit=100&mode=domain&order_by=backlinks%3Adesc&select=anchor&target=example.com"},
+		{[]string{"organic-keywords", "-t", "example.com", "-c", "us", "--where", "traffic>100", "--order-by", "traffic:desc", "--offset", "3"},
+			"/site-explorer/organic-keywords?country=us&limit=100&mode=domain&offset=3&order_by=traffic%3Adesc&target=example.com&where=traffic%3E100"},
+		{[]string{"organic-keywords", "-t", "example.com", "-c", "us", "--date", "2024-06-30", "--date-compared", "2024-06-01"},
+			"/site-explorer/organic-keywords?country=us&date=2024-06-30&date_compared=2024-06-01&limit=100&mode=domain&target=example.com"},
+		{[]string{"organic-keywords", "-t", "example.com", "-c", "us", "--date", "2024-06-30", "--date-compared", "2024-06-01", "--movement", "lost"},
+			"/site-explorer/organic-keywords?country=us&date=2024-06-30&limit=100&mode=domain&target=example.com"},
+		{[]string{"organic-keywords", "-t", "example.com", "-c", "us", "--serp-features", "video", "--exclude-serp-features", "local_pack"},
+			"/site-explorer/organic-keywords?country=us&limit=100&mode=domain&target=example.com&where=" + url.QueryEscape(`{"and":[{"field":"serp_features","list_is":{"any":["eq","video"]}},{"not":{"field":"serp_features","list_is":{"any":["eq","local_pack"]}}}]}`)},
+		{[]string{"organic-keywords", "-t", "example.com", "-c", "us", "--where", "traffic>100", "--select", "keyword", "--serp-features", "video"},
+			"/site-explorer/organic-keywords?country=us&limit=100&mode=domain&select=keyword%2Cserp_features&target=example.com&where=traffic%3E100"},
+		{[]string{"page-keywords", "--url", "https://example.com/post", "-c", "us"},
+			"/site-explorer/organic-keywords?country=us&limit=100&mode=exact&select=keyword%2Cposition%2Cvolume%2Ctraffic%2Cserp_features&target=https%3A%2F%2Fexample.com%2Fpost"},
+		{[]string{"page-keywords", "--url", "https://example.com/post", "-c", "us", "--select", "keyword", "--compare-url", "https://competitor.com/post"},
+			"/site-explorer/organic-keywords?country=us&limit=100&mode=exact&select=keyword%2Cposition%2Ctraffic&target=https%3A%2F%2Fexample.com%2Fpost"},
+		{[]string{"top-pages", "-t", "example.com", "-c", "gb", "-l", "20", "--select", "url,traffic"},
+			"/site-explorer/top-pages?country=gb&limit=20&mode=domain&select=url%2Ctraffic&target=example.com"},
+		{[]string{"traffic-share", "-t", "example.com", "-c", "gb", "-l", "20"},
+			"/site-explorer/top-pages?country=gb&limit=20&mode=domain&order_by=traffic%3Adesc&target=example.com"},
+		{[]string{"traffic-share", "-t", "example.com", "--by", "domains", "--order-by", "keywords_common:desc"},
+			"/site-explorer/organic-competitors?limit=100&mode=domain&order_by=keywords_common%3Adesc&target=example.com"},
+		{[]string{"organic-competitors", "-t", "example.com", "-c", "us", "--date", "2024-06-01"},
+			"/site-explorer/organic-competitors?country=us&date=2024-06-01&limit=100&mode=domain&target=example.com"},
+		{[]string{"broken-backlinks", "-t", "example.com", "--order-by", "domain_rating:desc", "--where", "http_code=404"},
+			"/site-explorer/broken-backlinks?limit=100&mode=domain&order_by=domain_rating%3Adesc&target=example.com&where=http_code%3D404"},
+		{[]string{"linked-domains", "-t", "example.com", "-m", "subdomains", "--offset", "7"},
+			"/site-explorer/linked-domains?limit=100&mode=subdomains&offset=7&target=example.com"},
+		{[]string{"linked-domains", "-t", "example.com", "--exclude-own", "--select", "domain_rating"},
+			"/site-explorer/linked-domains?limit=100&mode=domain&select=domain_rating%2Cdomain&target=example.com"},
+		{[]string{"metrics", "-t", "example.com"},
+			"/site-explorer/metrics?mode=domain&target=example.com"},
+		{[]string{"metrics", "-t", "example.com", "-c", "de", "--select", "org_traffic"},
+			"/site-explorer/metrics?country=de&mode=domain&select=org_traffic&target=example.com"},
+		{[]string{"metrics-history", "-t", "example.com", "-c", "us", "--date-from", "2024-01-01", "--date-to", "2024-12-31", "--select", "date,org_traffic"},
+			"/site-explorer/metrics-history?country=us&date_from=2024-01-01&date_to=2024-12-31&mode=domain&select=date%2Corg_traffic&target=example.com"},
+		{[]string{"metrics-history", "-t", "example.com", "--date-from", "2024-01-01", "--interval", "weekly", "--select", "org_traffic"},
+			"/site-explorer/metrics-history?date_from=2024-01-01&history_grouping=weekly&mode=domain&select=org_traffic%2Cdate&target=example.com"},
+		{[]string{"url-rating-history", "-t", "example.com/blog/post", "--date-from", "2024-01-01", "--date-to", "2024-06-30", "--interval", "monthly"},
+			"/site-explorer/url-rating-history?date_from=2024-01-01&date_to=2024-06-30&history_grouping=monthly&mode=exact&target=https%3A%2F%2Fexample.com%2Fblog%2Fpost"},
+		{[]string{"pages-by-traffic", "-t", "example.com", "-c", "fr", "--order-by", "traffic:desc", "--offset", "1"},
+			"/site-explorer/pages-by-traffic?country=fr&limit=100&mode=domain&offset=1&order_by=traffic%3Adesc&target=example.com"},
+		{[]string{"best-by-links", "-t", "example.com", "--order-by", "refdomains:desc", "--where", "refdomains>5", "-l", "50"},
+			"/site-explorer/best-by-links?limit=50&mode=domain&order_by=refdomains%3Adesc&target=example.com&where=refdomains%3E5"},
+		{[]string{"best-by-links", "-t", "example.com", "--first-seen-since", "2024-01-01", "--first-seen-until", "2024-12-31"},
+			"/site-explorer/best-by-links?limit=100&mode=domain&target=example.com&where=" + url.QueryEscape(`{"and":[{"field":"first_seen","is":["gte","2024-01-01"]},{"field":"first_seen","is":["lte","2024-12-31"]}]}`)},
+		{[]string{"backlinks", "-t", "example.com", "--where", `{"field":"domain_rating","is":["gt",50]}`, "--first-seen-since", "2024-06-01"},
+			"/site-explorer/backlinks?limit=100&mode=domain&target=example.com&where=" + url.QueryEscape(`{"and":[{"field":"domain_rating","is":["gt",50]},{"field":"first_seen","is":["gte","2024-06-01"]}]}`)},
+		{[]string{"backlinks", "-t", "example.com", "--nofollow", "--link-type", "redirect", "--first-seen-since", "2024-06-01"},
+			"/site-explorer/backlinks?limit=100&mode=domain&target=example.com&where=" + url.QueryEscape(`{"and":[{"field":"is_nofollow","is":["eq",true]},{"field":"is_redirect","is":["eq",true]},{"field":"first_seen","is":["gte","2024-06-01"]}]}`)},
+		{[]string{"backlinks", "-t", "example.com", "--history", "all_time"},
+			"/site-explorer/backlinks?history=all_time&limit=100&mode=domain&target=example.com"},
+		{[]string{"backlinks", "-t", "example.com", "--history", "new", "--date-from", "2024-06-01"},
+			"/site-explorer/backlinks?history=since%3A2024-06-01&limit=100&mode=domain&target=example.com&where=" + url.QueryEscape(`{"field":"first_seen","is":["gte","2024-06-01"]}`)},
+		{[]string{"backlinks", "-t", "example.com", "--history", "lost", "--date-from", "2024-06-01", "--date-to", "2024-06-30", "--select", "url_from,anchor"},
+			"/site-explorer/backlinks?history=since%3A2024-06-01&limit=100&mode=domain&select=url_from%2Canchor%2Clast_seen%2Clost_reason%2Cdrop_reason&target=example.com&where=" + url.QueryEscape(`{"and":[{"field":"last_seen","is":["gte","2024-06-01"]},{"field":"last_seen","is":["lte","2024-06-30"]}]}`)},
+		{[]string{"refdomains", "-t", "example.com", "--dofollow"},
+			"/site-explorer/refdomains?limit=100&mode=domain&target=example.com&where=" + url.QueryEscape(`{"field":"dofollow_links","is":["gt",0]}`)},
+		{[]string{"backlinks", "-t", "example.com", "--order-by", "domain_rating:desc"},
+			"/site-explorer/backlinks?limit=100&mode=domain&order_by=domain_rating%3Adesc&target=example.com"},
+		{[]string{"backlinks", "-t", "example.com", "-l", "2500"},
+			"/site-explorer/backlinks?limit=1000&mode=domain&target=example.com"},
+		{[]string{"backlinks", "-t", "example.com", "--cursor", "abc"},
+			"/site-explorer/backlinks?cursor=abc&limit=100&mode=domain&target=example.com"},
+		{[]string{"backlinks", "-t", "https://example.com/"},
+			"/site-explorer/backlinks?limit=100&mode=domain&target=example.com"},
+		{[]string{"backlinks", "-t", "https://example.com/blog/", "--auto-mode"},
+			"/site-explorer/backlinks?limit=100&mode=prefix&target=https%3A%2F%2Fexample.com%2Fblog%2F"},
+		{[]string{"backlinks", "-t", "example.com/blog/post", "--auto-mode"},
+			"/site-explorer/backlinks?limit=100&mode=exact&target=https%3A%2F%2Fexample.com%2Fblog%2Fpost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			if got := dryRunURL(t, tt.args); got != "https://api.ahrefs.com/v3"+tt.want {
+				t.Errorf("dry-run URL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseURLFailover(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := "http://" + l.Addr().String()
+	l.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"domain_rating":{"domain_rating":70}}`))
+	}))
+	defer server.Close()
+
+	// The unreachable base URL is preferred, and the server is failed over to
+	out := runCommand(t, server.URL, []string{"domain-rating", "-t", "example.com", "--base-url", down})
+	if !strings.Contains(out, `"base_url": "`+server.URL+`"`) {
+		t.Errorf("output = %s, want the base URL failed over to in meta", out)
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CORP_TOKEN", "corp-secret-123")
+
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Write([]byte(`{"domain_rating":{"domain_rating":70}}`))
+	}))
+	defer server.Close()
+
+	runCommand(t, server.URL, []string{"domain-rating", "-t", "example.com", "--header", "X-Corp-Auth: ${CORP_TOKEN}"})
+	if got.Get("X-Corp-Auth") != "corp-secret-123" || got.Get("Authorization") != "Bearer test-key" {
+		t.Errorf("headers = %v, want X-Corp-Auth expanded and the API key kept", got)
+	}
+}
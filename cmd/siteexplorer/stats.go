@@ -0,0 +1,271 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// statsConflicts are the flags whose output isn't the list --stats
+// summarizes
+var statsConflicts = []string{"count-only", "summary-only", "histogram-only"}
+
+// fieldStats returns a transform computing the statistics of each column of
+// the rows of a response body, after applying prev when it's set. The rows
+// are those under key, or under the first list of the body when key is "".
+// With only, the statistics replace the body; with embed, they are added to
+// it under "stats", for JSON and YAML output. They are also written to table
+// as a table of their own, when table is set and not only.
+func fieldStats(key string, columns []string, only, embed bool, table io.Writer, prev transform) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		if prev != nil {
+			var err error
+			if body, err = prev(ctx, body, fetch); err != nil {
+				return nil, err
+			}
+		}
+
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		var resp interface{}
+		if _, err := decodeResponse(bytes.NewReader(data), &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		obj, _ := resp.(map[string]interface{})
+		if key == "" {
+			key = firstList(obj)
+		}
+		rows, _ := obj[key].([]interface{})
+
+		stats := columnStats(rows, columns)
+		if only {
+			data, err := json.Marshal(models.FieldStatsResponse{Stats: stats})
+			if err != nil {
+				return nil, err
+			}
+			return bytes.NewReader(data), nil
+		}
+		if table != nil {
+			if err := writeStats(table, stats); err != nil {
+				return nil, err
+			}
+		}
+		if !embed {
+			return bytes.NewReader(data), nil
+		}
+		obj["stats"] = stats
+		if data, err = json.Marshal(obj); err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// firstList returns the name of the first list field of obj, by name, as
+// output takes the rows of a generic response from
+func firstList(obj map[string]interface{}) string {
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := obj[name].([]interface{}); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// listFields returns the JSON name of the list field of the response model
+// result, and the names of its rows' fields in order, or "" and nil when
+// result isn't a typed list
+func listFields(result interface{}) (string, []string) {
+	t := reflect.TypeOf(result)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Type.Kind() != reflect.Slice {
+			continue
+		}
+		row := f.Type.Elem()
+		for row.Kind() == reflect.Pointer {
+			row = row.Elem()
+		}
+		return jsonName(f), structFieldNames(row)
+	}
+	return "", nil
+}
+
+// structFieldNames returns the JSON names of the fields of t, including those
+// of embedded structs, or nil when t isn't a struct
+func structFieldNames(t reflect.Type) []string {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			names = append(names, structFieldNames(f.Type)...)
+			continue
+		}
+		if name := jsonName(f); f.IsExported() && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// jsonName returns the name of a struct field in JSON
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// columnStats returns the statistics of each column of generically decoded
+// rows: those of columns first, in order, then the others by name. A column
+// is numeric when all its values are numbers; nulls, and rows without it,
+// aren't counted.
+func columnStats(rows []interface{}, columns []string) []models.FieldStats {
+	values := map[string][]interface{}{}
+	for _, row := range rows {
+		fields, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for name, v := range fields {
+			if v != nil {
+				values[name] = append(values[name], v)
+			} else if _, ok := values[name]; !ok {
+				values[name] = nil
+			}
+		}
+	}
+
+	var names []string
+	listed := map[string]bool{}
+	for _, name := range columns {
+		if _, ok := values[name]; ok && !listed[name] {
+			listed[name] = true
+			names = append(names, name)
+		}
+	}
+	var rest []string
+	for name := range values {
+		if !listed[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	names = append(names, rest...)
+
+	stats := make([]models.FieldStats, len(names))
+	for i, name := range names {
+		stats[i] = fieldStatsOf(name, values[name])
+	}
+	return stats
+}
+
+// fieldStatsOf returns the statistics of a column's non-null values
+func fieldStatsOf(name string, values []interface{}) models.FieldStats {
+	s := models.FieldStats{Field: name, Type: "number", Count: len(values)}
+	numbers := make([]float64, 0, len(values))
+	for _, v := range values {
+		n, ok := v.(json.Number)
+		if !ok {
+			break
+		}
+		f, err := n.Float64()
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, f)
+	}
+
+	if len(numbers) < len(values) || len(values) == 0 {
+		s.Type = "text"
+		distinct := map[string]bool{}
+		for _, v := range values {
+			distinct[distinctValue(v)] = true
+		}
+		n := len(distinct)
+		s.Distinct = &n
+		return s
+	}
+
+	sort.Float64s(numbers)
+	sum := 0.0
+	for _, f := range numbers {
+		sum += f
+	}
+	s.Min = statNumber(numbers[0])
+	s.Max = statNumber(numbers[len(numbers)-1])
+	s.Mean = statNumber(sum / float64(len(numbers)))
+	s.Median = statNumber(percentile(numbers, 50))
+	s.P90 = statNumber(percentile(numbers, 90))
+	s.Sum = statNumber(sum)
+	return s
+}
+
+// percentile returns the pth percentile of sorted numbers, interpolated
+// linearly between the closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// statNumber returns f rounded to two decimals, written without an exponent
+// so output is the same in every format
+func statNumber(f float64) json.Number {
+	return json.Number(strconv.FormatFloat(round2(f), 'f', -1, 64))
+}
+
+// distinctValue returns a generically decoded value as the text it is
+// counted as distinct by
+func distinctValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// writeStats writes stats to w as a table
+func writeStats(w io.Writer, stats []models.FieldStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tCOUNT\tMIN\tMAX\tMEAN\tMEDIAN\tP90\tSUM\tDISTINCT\t")
+	for _, s := range stats {
+		distinct := ""
+		if s.Distinct != nil {
+			distinct = strconv.Itoa(*s.Distinct)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", s.Field, s.Count, s.Min, s.Max, s.Mean, s.Median, s.P90, s.Sum, distinct)
+	}
+	return tw.Flush()
+}
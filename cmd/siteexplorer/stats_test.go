@@ -0,0 +1,129 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/internal/fixture"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// statsServer serves testdata/stats: top pages with a traffic outlier, a
+// missing URL rating, and a repeated URL
+func statsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	fixtures, err := fixture.Load("testdata/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(fixture.Handler(fixtures))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestStats_Only(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := statsServer(t)
+
+	out := runCommand(t, srv.URL, []string{"top-pages", "-t", "t.com", "--stats-only", "--format", "csv"})
+	want := "field,type,count,min,max,mean,median,p90,sum,distinct\n" +
+		"url,text,5,,,,,,,4\n" +
+		"traffic,number,5,10,1000000,200020,30,600016,1000100,\n" +
+		"top_keyword,text,4,,,,,,,3\n" +
+		"url_rating,number,4,5,30,16.25,15,27,65,\n"
+	if out != want {
+		t.Errorf("--stats-only output = %q, want %q", out, want)
+	}
+}
+
+func TestStats_WithRows(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := statsServer(t)
+
+	out := runCommand(t, srv.URL, []string{"top-pages", "-t", "t.com", "--stats", "--format", "json", "--quiet"})
+	var got struct {
+		Data struct {
+			Pages []models.TopPage    `json:"pages"`
+			Stats []models.FieldStats `json:"stats"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if len(got.Data.Pages) != 5 || len(got.Data.Stats) != 4 {
+		t.Fatalf("response = %+v, want the 5 rows and 4 columns' stats", got.Data)
+	}
+	if s := got.Data.Stats[1]; s.Field != "traffic" || s.Sum != "1000100" || s.P90 != "600016" {
+		t.Errorf("traffic stats = %+v, want a sum of 1000100 and p90 of 600016", s)
+	}
+}
+
+func TestStats_Table(t *testing.T) {
+	body := strings.NewReader(`{"pages":[{"url":"a","traffic":3},{"url":"b","traffic":1.5}]}`)
+	var table bytes.Buffer
+	out, err := fieldStats("pages", []string{"url", "traffic"}, false, false, &table, nil)(context.Background(), body, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data bytes.Buffer
+	data.ReadFrom(out)
+	if want := `{"pages":[{"url":"a","traffic":3},{"url":"b","traffic":1.5}]}`; data.String() != want {
+		t.Errorf("body = %s, want it unchanged %s", data.String(), want)
+	}
+
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "FIELD") {
+		t.Fatalf("stats table = %q, want a header and 2 columns", table.String())
+	}
+	if fields := strings.Fields(lines[2]); !reflect.DeepEqual(fields, []string{"traffic", "2", "1.5", "3", "2.25", "2.25", "2.85", "4.5"}) {
+		t.Errorf("traffic row = %q", lines[2])
+	}
+}
+
+func TestColumnStats(t *testing.T) {
+	distinct := func(n int) *int { return &n }
+	rows := []interface{}{
+		map[string]interface{}{"dr": json.Number("7"), "mixed": json.Number("1"), "tags": []interface{}{"a", "b"}, "empty": nil},
+		map[string]interface{}{"dr": json.Number("0.125"), "mixed": "n/a", "tags": []interface{}{"a", "b"}},
+		map[string]interface{}{"tags": []interface{}{"c"}, "empty": nil},
+	}
+
+	got := columnStats(rows, []string{"tags", "missing"})
+	want := []models.FieldStats{
+		{Field: "tags", Type: "text", Count: 3, Distinct: distinct(2)},
+		{Field: "dr", Type: "number", Count: 2, Min: "0.13", Max: "7", Mean: "3.56", Median: "3.56", P90: "6.31", Sum: "7.13"},
+		{Field: "empty", Type: "text", Count: 0, Distinct: distinct(0)},
+		{Field: "mixed", Type: "text", Count: 2, Distinct: distinct(2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("columnStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStats_Conflicts(t *testing.T) {
+	for _, tt := range []struct {
+		command string
+		args    []string
+	}{
+		{"top-pages", []string{"--stats", "--stats-only"}},
+		{"top-pages", []string{"--stats-only", "--select", "url"}},
+		{"refdomains", []string{"--stats", "--histogram-only"}},
+		{"anchors", []string{"--stats-only", "--summary-only"}},
+	} {
+		c, _, err := NewSiteExplorerCmd().Find([]string{tt.command})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.ParseFlags(tt.args); err != nil {
+			t.Fatalf("ParseFlags(%v) error = %v", tt.args, err)
+		}
+		if err := c.ValidateFlagGroups(); err == nil {
+			t.Errorf("%s: ValidateFlagGroups() should reject %v", tt.command, tt.args)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// summaryWords is how many of the most common words an anchor summary lists
+const summaryWords = 20
+
+// summaryConflicts are the flags replacing the rows --summary summarises
+var summaryConflicts = []string{"expand-domains", "detect-language"}
+
+// summaryFields are the columns anchors are summarised by, added to --select
+// when it leaves them out
+var summaryFields = []string{"anchor", "backlinks"}
+
+// imageExtensions are the file extensions of the images an anchor text may
+// name, when the link is an image's
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".avif", ".bmp", ".ico"}
+
+// anchorSummary returns a transform adding a summary of the anchor texts of a
+// response body, with the share of those containing one of brand when it's
+// set, or with only replacing the rows by the summary. The summary is also
+// logged to log.
+func anchorSummary(brand []string, only bool, log *slog.Logger) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		var resp interface{}
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		obj, _ := resp.(map[string]interface{})
+		rows, _ := obj["anchors"].([]interface{})
+
+		summary := summarizeAnchors(rows, brand)
+		if log != nil {
+			log.Info(anchorSummaryLine(summary))
+		}
+		var out interface{} = summary
+		if !only {
+			out = map[string]interface{}{"anchors": rows, "summary": summary}
+		}
+		data, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// summarizeAnchors summarises generically decoded anchor rows. A word counts
+// once per anchor using it, with the anchor's backlinks. Anchors without text
+// or naming an image have no words.
+func summarizeAnchors(rows []interface{}, brand []string) models.AnchorSummary {
+	var s models.AnchorSummary
+	var terms []string
+	for _, term := range brand {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) > 0 {
+		s.Brand = &models.AnchorBrand{Terms: terms}
+	}
+
+	words := map[string]*models.AnchorWord{}
+	for _, row := range rows {
+		fields, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		anchor := strings.TrimSpace(stringField(fields, "anchor"))
+		backlinks, _ := numberField(fields, "backlinks")
+		links := int(backlinks)
+
+		s.Anchors++
+		s.Backlinks += links
+		if s.Brand != nil {
+			if branded(anchor, terms) {
+				s.Brand.BrandedAnchors++
+				s.Brand.BrandedBacklinks += links
+			} else {
+				s.Brand.NonBrandedAnchors++
+				s.Brand.NonBrandedBacklinks += links
+			}
+		}
+		switch {
+		case anchor == "":
+			s.EmptyAnchors++
+			continue
+		case imageAnchor(anchor):
+			s.ImageAnchors++
+			continue
+		}
+
+		for _, word := range anchorWords(anchor) {
+			w, ok := words[word]
+			if !ok {
+				w = &models.AnchorWord{Word: word}
+				words[word] = w
+			}
+			w.Anchors++
+			w.Backlinks += links
+		}
+	}
+
+	s.Words = make([]models.AnchorWord, 0, len(words))
+	for _, w := range words {
+		w.BacklinksPercent = percentOf(w.Backlinks, s.Backlinks)
+		s.Words = append(s.Words, *w)
+	}
+	sort.Slice(s.Words, func(i, j int) bool {
+		a, b := s.Words[i], s.Words[j]
+		if a.Backlinks != b.Backlinks {
+			return a.Backlinks > b.Backlinks
+		}
+		if a.Anchors != b.Anchors {
+			return a.Anchors > b.Anchors
+		}
+		return a.Word < b.Word
+	})
+	if len(s.Words) > summaryWords {
+		s.Words = s.Words[:summaryWords]
+	}
+	if s.Brand != nil {
+		s.Brand.BrandedPercent = percentOf(s.Brand.BrandedBacklinks, s.Backlinks)
+		s.Brand.NonBrandedPercent = percentOf(s.Brand.NonBrandedBacklinks, s.Backlinks)
+	}
+	return s
+}
+
+// anchorWords returns the distinct lowercase words of anchor, in the order
+// they first appear. Words are runs of letters, digits, and the marks
+// combined with them, such as Devanagari vowel signs. Han characters are
+// words of their own, as Chinese and Japanese don't space their words.
+// Stop words and other single characters are left out.
+func anchorWords(anchor string) []string {
+	var words []string
+	seen := map[string]bool{}
+	add := func(word string) {
+		if word == "" || stopWords[word] || seen[word] {
+			return
+		}
+		if r, _ := utf8.DecodeRuneInString(word); utf8.RuneCountInString(word) == 1 && !unicode.Is(unicode.Han, r) {
+			return
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+
+	start := -1
+	anchor = strings.ToLower(anchor)
+	for i, r := range anchor {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if start >= 0 {
+				add(anchor[start:i])
+				start = -1
+			}
+			add(string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			if start < 0 {
+				start = i
+			}
+		default:
+			if start >= 0 {
+				add(anchor[start:i])
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		add(anchor[start:])
+	}
+	return words
+}
+
+// imageAnchor reports whether anchor is an image's rather than text: an
+// img tag, or the name or URL of an image file
+func imageAnchor(anchor string) bool {
+	anchor = strings.ToLower(anchor)
+	if strings.HasPrefix(anchor, "<img") {
+		return true
+	}
+	if strings.ContainsFunc(anchor, unicode.IsSpace) {
+		return false
+	}
+	name, _, _ := strings.Cut(anchor, "?")
+	return slices.Contains(imageExtensions, path.Ext(name))
+}
+
+// branded reports whether anchor contains one of the lowercase terms,
+// ignoring case
+func branded(anchor string, terms []string) bool {
+	anchor = strings.ToLower(anchor)
+	for _, term := range terms {
+		if strings.Contains(anchor, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// percentOf returns n as a percent of total, rounded to two decimals
+func percentOf(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round2(float64(n) / float64(total) * 100)
+}
+
+// anchorSummaryLine describes an anchor summary in a line
+func anchorSummaryLine(s models.AnchorSummary) string {
+	line := fmt.Sprintf("%d anchors with %d backlinks: %d empty, %d images", s.Anchors, s.Backlinks, s.EmptyAnchors, s.ImageAnchors)
+	if s.Brand != nil {
+		line += fmt.Sprintf(", %g%% of backlinks branded", s.Brand.BrandedPercent)
+	}
+	var top []string
+	for _, w := range s.Words[:min(len(s.Words), 5)] {
+		top = append(top, fmt.Sprintf("%s (%d)", w.Word, w.Backlinks))
+	}
+	if len(top) > 0 {
+		line += "; top words: " + strings.Join(top, ", ")
+	}
+	return line
+}
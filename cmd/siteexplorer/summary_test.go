@@ -0,0 +1,139 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestAnchorWords(t *testing.T) {
+	tests := []struct {
+		anchor string
+		want   []string
+	}{
+		{"The Best SEO Tools for 2024", []string{"best", "seo", "tools", "2024"}},
+		{"SEO tools, seo TOOLS!", []string{"seo", "tools"}},
+		{"Outils de référencement", []string{"outils", "de", "référencement"}},
+		{"Straße & Größe", []string{"straße", "größe"}},
+		{"Инструменты для SEO", []string{"инструменты", "для", "seo"}},
+		{"हिन्दी समाचार", []string{"हिन्दी", "समाचार"}},
+		{"最好的SEO工具", []string{"最", "好", "的", "seo", "工", "具"}},
+		{"ブログの書き方", []string{"ブログの", "書", "方"}},
+		{"أدوات تحسين محركات البحث", []string{"أدوات", "تحسين", "محركات", "البحث"}},
+		{"click here: x", []string{"click", "here"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := anchorWords(tt.anchor); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("anchorWords(%q) = %q, want %q", tt.anchor, got, tt.want)
+		}
+	}
+}
+
+func TestImageAnchor(t *testing.T) {
+	for anchor, want := range map[string]bool{
+		"logo.png":                           true,
+		"https://cdn.s.com/a/Banner.JPG?w=2": true,
+		`<img src="x.gif">`:                  true,
+		"photo.webp":                         true,
+		"my photo.png":                       false,
+		"ahrefs.com":                         false,
+		"seo tools":                          false,
+	} {
+		if got := imageAnchor(anchor); got != want {
+			t.Errorf("imageAnchor(%q) = %v, want %v", anchor, got, want)
+		}
+	}
+}
+
+func TestSummarizeAnchors(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"anchor": "Ahrefs SEO tools", "backlinks": json.Number("600")},
+		map[string]interface{}{"anchor": "seo tools", "backlinks": json.Number("300")},
+		map[string]interface{}{"anchor": "  ", "backlinks": json.Number("50")},
+		map[string]interface{}{"anchor": "logo.png", "backlinks": json.Number("40")},
+		map[string]interface{}{"anchor": "ahrefs.com", "backlinks": json.Number("10")},
+	}
+	got := summarizeAnchors(rows, []string{" Ahrefs ", ""})
+	want := models.AnchorSummary{
+		Words: []models.AnchorWord{
+			{Word: "seo", Anchors: 2, Backlinks: 900, BacklinksPercent: 90},
+			{Word: "tools", Anchors: 2, Backlinks: 900, BacklinksPercent: 90},
+			{Word: "ahrefs", Anchors: 2, Backlinks: 610, BacklinksPercent: 61},
+			{Word: "com", Anchors: 1, Backlinks: 10, BacklinksPercent: 1},
+		},
+		Anchors:      5,
+		Backlinks:    1000,
+		EmptyAnchors: 1,
+		ImageAnchors: 1,
+		Brand: &models.AnchorBrand{
+			Terms:               []string{"ahrefs"},
+			BrandedAnchors:      2,
+			BrandedBacklinks:    610,
+			BrandedPercent:      61,
+			NonBrandedAnchors:   3,
+			NonBrandedBacklinks: 390,
+			NonBrandedPercent:   39,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summarizeAnchors() = %+v, want %+v", got, want)
+	}
+
+	if got := summarizeAnchors(rows, nil); got.Brand != nil {
+		t.Errorf("summary without brand terms has brand %+v", got.Brand)
+	}
+}
+
+func TestAnchorSummary_Command(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var selects []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		selects = append(selects, r.URL.Query().Get("select"))
+		fmt.Fprint(w, `{"anchors":[
+			{"anchor":"Ahrefs","backlinks":500,"refdomains":40},
+			{"anchor":"Outils SEO","backlinks":300,"refdomains":20},
+			{"anchor":"SEO工具","backlinks":200,"refdomains":10}
+		]}`)
+	}))
+	defer srv.Close()
+
+	out := runCommand(t, srv.URL, []string{"anchors", "-t", "ahrefs.com", "--summary-only", "--format", "csv"})
+	want := "word,anchors,backlinks,backlinks_percent\n" +
+		"seo,2,500,50\n" +
+		"ahrefs,1,500,50\n" +
+		"outils,1,300,30\n" +
+		"具,1,200,20\n" +
+		"工,1,200,20\n"
+	if out != want {
+		t.Errorf("--summary-only output = %q, want %q", out, want)
+	}
+
+	out = runCommand(t, srv.URL, []string{"anchors", "-t", "ahrefs.com", "--summary", "--brand", "ahrefs", "--select", "anchor,refdomains", "--format", "json"})
+	var got struct {
+		Data models.AnchorSummaryResponse `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if len(got.Data.Anchors) != 3 || got.Data.Anchors[0]["refdomains"] != float64(40) {
+		t.Errorf("anchors = %v, want the 3 rows as returned", got.Data.Anchors)
+	}
+	if b := got.Data.Summary.Brand; b == nil || b.BrandedPercent != 50 || b.NonBrandedAnchors != 2 {
+		t.Errorf("brand = %+v, want 50%% of backlinks branded", b)
+	}
+	if selects[1] != "anchor,refdomains,backlinks" {
+		t.Errorf("select = %q, want backlinks added", selects[1])
+	}
+
+	if _, err := execCommand(t, srv.URL, []string{"anchors", "-t", "ahrefs.com", "--brand", "ahrefs"}); err == nil || !strings.Contains(err.Error(), "--summary") {
+		t.Errorf("--brand without --summary: error = %v, want one asking for --summary", err)
+	}
+}
@@ -0,0 +1,73 @@
+package siteexplorer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// resolveTarget returns the target and mode to request for --target and
+// --mode, set says whether --mode was given. A target with a path or query
+// is a URL, which the default domain mode would quietly widen to its whole
+// domain: that's a usage error, or with auto the mode becomes prefix for a
+// URL ending in a slash and exact otherwise.
+//
+// The domain modes take a host, so a scheme is dropped; exact takes a full
+// URL, so https:// is added when it has none. Prefix targets are sent as
+// they are.
+func resolveTarget(target, mode string, set, auto bool) (string, models.Mode, error) {
+	target = strings.TrimSpace(target)
+	scheme, rest, ok := strings.Cut(target, "://")
+	if !ok {
+		scheme, rest = "", target
+	}
+	host, path := rest, ""
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		host, path = rest[:i], rest[i:]
+	}
+
+	m := models.Mode(mode)
+	domainMode := m == models.ModeDomain || m == models.ModeSubdomains
+	if domainMode && !set && path != "" && path != "/" {
+		if !auto {
+			return "", "", cmd.NewError(cmd.CodeUsage,
+				fmt.Sprintf("--target %s is a URL, but --mode %s requests all of %s", target, m, host),
+				"Add --mode exact for the page, --mode prefix for the URLs under it, or --auto-mode to pick one")
+		}
+		m = models.ModeExact
+		if strings.HasSuffix(path, "/") {
+			m = models.ModePrefix
+		}
+		domainMode = false
+	}
+
+	switch {
+	case domainMode && path == "/":
+		return host, m, nil
+	case domainMode:
+		return rest, m, nil
+	case m == models.ModeExact && scheme == "":
+		return "https://" + target, m, nil
+	}
+	return target, m, nil
+}
+
+// checkPageURL returns a usage error unless target, requested in exact
+// mode, looks like the URL of a page: http or https, with a host of a
+// domain name or localhost, and no spaces
+func checkPageURL(target string) error {
+	u, err := url.Parse(target)
+	ok := err == nil && (u.Scheme == "http" || u.Scheme == "https") && !strings.ContainsAny(target, " \t\n")
+	if ok {
+		host := u.Hostname()
+		ok = host == "localhost" || strings.Contains(strings.Trim(host, "."), ".")
+	}
+	if !ok {
+		return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("--target %s is not a page URL, which --mode exact requests", target),
+			"Use the URL of a page, such as https://example.com/blog/post")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package siteexplorer
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestResolveTarget(t *testing.T) {
+	tests := []struct {
+		target     string
+		mode       models.Mode
+		set, auto  bool
+		wantTarget string
+		wantMode   models.Mode
+		wantErr    bool
+	}{
+		// Domains, with or without a scheme
+		{"example.com", models.ModeDomain, false, false, "example.com", models.ModeDomain, false},
+		{"https://example.com", models.ModeDomain, false, false, "example.com", models.ModeDomain, false},
+		{"http://example.com/", models.ModeDomain, false, false, "example.com", models.ModeDomain, false},
+		{" https://blog.example.com/ ", models.ModeSubdomains, true, false, "blog.example.com", models.ModeSubdomains, false},
+
+		// URLs with the default mode
+		{"https://example.com/blog/post", models.ModeDomain, false, false, "", "", true},
+		{"example.com?p=1", models.ModeDomain, false, false, "", "", true},
+		{"https://example.com/blog/post", models.ModeDomain, false, true, "https://example.com/blog/post", models.ModeExact, false},
+		{"example.com/blog/post", models.ModeDomain, false, true, "https://example.com/blog/post", models.ModeExact, false},
+		{"https://example.com/blog/", models.ModeDomain, false, true, "https://example.com/blog/", models.ModePrefix, false},
+		{"example.com/blog/", models.ModeDomain, false, true, "example.com/blog/", models.ModePrefix, false},
+		{"https://example.com/?p=1", models.ModeDomain, false, true, "https://example.com/?p=1", models.ModeExact, false},
+		{"https://example.com/", models.ModeDomain, false, true, "example.com", models.ModeDomain, false},
+
+		// An explicit mode is kept
+		{"https://example.com/blog/post", models.ModeDomain, true, false, "example.com/blog/post", models.ModeDomain, false},
+		{"example.com/blog/post", models.ModeExact, true, false, "https://example.com/blog/post", models.ModeExact, false},
+		{"http://example.com/blog/post", models.ModeExact, true, false, "http://example.com/blog/post", models.ModeExact, false},
+		{"example.com/blog/", models.ModePrefix, true, false, "example.com/blog/", models.ModePrefix, false},
+		{"https://example.com/blog/", models.ModePrefix, true, false, "https://example.com/blog/", models.ModePrefix, false},
+	}
+	for _, tt := range tests {
+		target, mode, err := resolveTarget(tt.target, tt.mode.String(), tt.set, tt.auto)
+		if tt.wantErr {
+			var coded *cmd.Error
+			if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+				t.Errorf("resolveTarget(%q, %s) error = %v, want a usage error", tt.target, tt.mode, err)
+			}
+			continue
+		}
+		if err != nil || target != tt.wantTarget || mode != tt.wantMode {
+			t.Errorf("resolveTarget(%q, %s, set %v, auto %v) = %q, %s, %v, want %q, %s",
+				tt.target, tt.mode, tt.set, tt.auto, target, mode, err, tt.wantTarget, tt.wantMode)
+		}
+	}
+}
+
+func TestTarget_URLWithDefaultMode(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	_, err := execCommand(t, "http://127.0.0.1:0", []string{"backlinks", "-t", "https://example.com/blog/post"})
+	var coded *cmd.Error
+	if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage || coded.Suggestion == "" {
+		t.Errorf("error = %v, want a usage error suggesting a mode", err)
+	}
+
+	c, _, err := NewSiteExplorerCmd().Find([]string{"backlinks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ParseFlags([]string{"--mode", "exact", "--auto-mode"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ValidateFlagGroups(); err == nil {
+		t.Error("ValidateFlagGroups() should reject --mode with --auto-mode")
+	}
+}
+
+func TestCheckPageURL(t *testing.T) {
+	for _, target := range []string{
+		"https://example.com/blog/post",
+		"http://example.com",
+		"https://sub.example.co.uk/a?b=c",
+		"http://localhost:8080/page",
+	} {
+		if err := checkPageURL(target); err != nil {
+			t.Errorf("checkPageURL(%q) error = %v", target, err)
+		}
+	}
+
+	for _, target := range []string{
+		"https://example",
+		"https://my site.com/page",
+		"ftp://example.com/file",
+		"https://",
+		"https://%zz",
+	} {
+		var coded *cmd.Error
+		if err := checkPageURL(target); !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+			t.Errorf("checkPageURL(%q) error = %v, want a usage error", target, err)
+		}
+	}
+}
+
+func TestTarget_URLRatingHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+	defer srv.Close()
+
+	_, err := execCommand(t, srv.URL, []string{"url-rating-history", "-t", "not a url", "--date-from", "2024-01-01"})
+	var coded *cmd.Error
+	if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage || !strings.Contains(err.Error(), "not a page URL") {
+		t.Errorf("error = %v, want a usage error about the URL", err)
+	}
+}
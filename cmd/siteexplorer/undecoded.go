@@ -0,0 +1,160 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/history"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// parseError is a response that failed to decode into its model, whether
+// cut short or not matching it
+type parseError struct {
+	err error
+}
+
+func (e *parseError) Error() string {
+	return "failed to parse response: " + e.err.Error()
+}
+
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
+// keepUndecoded saves the body of a response that failed to decode with
+// err, so it can be decoded again with the decode command rather than
+// requested again. body is read to its end, copying into kept, which holds
+// what was read of it already. The path is logged, with the decode command
+// for it when args, its flags, are known; a failure to save is only a
+// warning.
+func keepUndecoded(flags cmd.GlobalFlags, name string, err error, kept *bytes.Buffer, body io.Reader, args string) {
+	var parseErr *parseError
+	if !errors.As(err, &parseErr) {
+		return
+	}
+	log := flags.Log()
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		log.Debug("Response only kept in part", "err", err)
+	}
+
+	p, saveErr := history.SaveUndecoded(name, kept.Bytes(), time.Now())
+	if saveErr != nil {
+		log.Warn("response body not kept", "err", saveErr)
+		return
+	}
+	if args == "" {
+		log.Warn(fmt.Sprintf("Kept the response body in %s", p))
+		return
+	}
+	log.Warn(fmt.Sprintf("Kept the response body in %s; decode it again without a request, such as after an upgrade: ahrefs decode %s %s", p, p, args))
+}
+
+// decodeArgs returns the decode flags that write a response of the command
+// name with params as it would have been, or "" when decode can't: the
+// response was transformed, or isn't the command's own model.
+func decodeArgs(name string, params url.Values, result interface{}, tr transform) string {
+	e, ok := endpointNamed(name)
+	if !ok || tr != nil || reflect.TypeOf(e.Result()) != reflect.TypeOf(result) {
+		return ""
+	}
+	args := "--as " + name
+	if sel := params.Get("select"); sel != "" {
+		args += " --select " + sel
+	}
+	return args
+}
+
+// endpointNamed returns the endpoint of the site-explorer command name
+func endpointNamed(name string) (endpoint, bool) {
+	for _, e := range endpoints {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return endpoint{}, false
+}
+
+// NewDecodeCmd creates the decode command
+func NewDecodeCmd() *cobra.Command {
+	var as, sel string
+
+	names := make([]string, len(endpoints))
+	for i, e := range endpoints {
+		names[i] = e.Name
+	}
+
+	c := &cobra.Command{
+		Use:   "decode <file>",
+		Short: "Decode a saved response body without a request",
+		Long: `Decode a response body saved in a file as the response of a site-explorer
+command, and write it in any output format. No request is made, so no units
+are spent.
+
+When a response fails to decode, such as when it was cut off or doesn't
+match the CLI's model of it, its body is kept in the cache directory and
+the path logged. Decode it again once the cause is fixed, such as after an
+upgrade, rather than paying for the request again.`,
+		Example: `  # A kept backlinks response, as CSV
+  ahrefs decode ~/.cache/ahrefs-cli/undecoded/backlinks-20240601T120000Z-123.json --as backlinks --format csv
+
+  # Only some of its fields, as the request's --select had
+  ahrefs decode response.json --as organic-keywords --select keyword,position --format table
+
+  # A body piped in
+  curl -s ... | ahrefs decode - --as refdomains`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runDecode(cobraCmd.Context(), args[0], as, sel)
+		},
+	}
+
+	c.Flags().StringVar(&as, "as", "", "The site-explorer command the body is a response of (required)")
+	c.Flags().StringVar(&sel, "select", "", "Comma-separated list of fields to write, as --select for the request")
+	cmd.SetAllowedValues(c, "as", names...)
+	c.MarkFlagRequired("as")
+
+	return c
+}
+
+func runDecode(ctx context.Context, file, as, sel string) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	e, ok := endpointNamed(as)
+	if !ok {
+		return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("unknown command %q for --as", as),
+			"Use the name of a site-explorer command, e.g. --as backlinks")
+	}
+
+	var body io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		defer f.Close()
+		body = f
+	}
+
+	params := url.Values{}
+	if sel = strings.TrimSpace(sel); sel != "" {
+		params.Set("select", sel)
+	}
+
+	w, err := newWriter(flags)
+	if err != nil {
+		return err
+	}
+	flags.MarkCached()
+	return writeResponse(w, flags, body, "", params, e.Result(), &client.ResponseMeta{})
+}
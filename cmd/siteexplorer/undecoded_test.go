@@ -0,0 +1,156 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/history"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/spf13/pflag"
+)
+
+// execDecode runs the decode command with args, and returns its output
+func execDecode(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	c := NewDecodeCmd()
+	cmd.AddCommands(c)
+	c.Root().PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if list, ok := f.Value.(pflag.SliceValue); ok {
+			_ = list.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	var buf bytes.Buffer
+	c.SetOut(&buf)
+	c.SetContext(context.Background())
+	if err := cmd.Prepare(c, args); err != nil {
+		t.Fatalf("Prepare(%v) error = %v", args, err)
+	}
+	err := c.RunE(c, c.Flags().Args())
+	return buf.String(), err
+}
+
+func TestRunRequest_KeepsUndecodedBody(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	const body = `{"backlinks":[{"url_from":"https://a.example/","url_to":"https://example.com/","domain_rating":"high"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	_, err := execCommand(t, server.URL, []string{"backlinks", "-t", "example.com"})
+	if err == nil || !strings.Contains(err.Error(), "failed to parse response") {
+		t.Fatalf("error = %v, want a parse error", err)
+	}
+
+	cache, err := config.CacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(cache, history.UndecodedDirName, "backlinks-*.json"))
+	if len(files) != 1 {
+		t.Fatalf("kept bodies = %v, want 1", files)
+	}
+	// The body is kept whole, though decoding stopped part way
+	if data, _ := os.ReadFile(files[0]); string(data) != body {
+		t.Errorf("kept body = %s, want %s", data, body)
+	}
+}
+
+func TestRunRequest_DecodedBodyNotKept(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"backlinks":[]}`))
+	}))
+	defer server.Close()
+
+	runCommand(t, server.URL, []string{"backlinks", "-t", "example.com"})
+	cache, err := config.CacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(cache, history.UndecodedDirName)); !os.IsNotExist(err) {
+		t.Errorf("undecoded directory error = %v, want none made", err)
+	}
+}
+
+func TestDecodeCmd(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	file := filepath.Join(t.TempDir(), "backlinks.json")
+	body := `{"backlinks":[{"url_from":"https://a.example/","url_to":"https://example.com/","domain_rating":71,"anchor":"a & b"}]}`
+	if err := os.WriteFile(file, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := execDecode(t, file, "--as", "backlinks", "--format", "csv", "--select", "url_from,domain_rating")
+	if err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if want := "url_from,domain_rating\nhttps://a.example/,71\n"; out != want {
+		t.Errorf("decode output = %q, want %q", out, want)
+	}
+
+	out, err = execDecode(t, file, "--as", "backlinks", "--format", "json")
+	if err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if !strings.Contains(out, `"domain_rating": 71`) || !strings.Contains(out, `"anchor": "a \u0026 b"`) {
+		t.Errorf("decode output = %s, want the typed rows", out)
+	}
+}
+
+func TestDecodeCmd_Errors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	file := filepath.Join(t.TempDir(), "cut.json")
+	if err := os.WriteFile(file, []byte(`{"backlinks":[{"url_from":`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execDecode(t, file, "--as", "backlinks"); err == nil || !strings.Contains(err.Error(), "response ended early") {
+		t.Errorf("decode of a cut body error = %v, want it reported", err)
+	}
+	if _, err := execDecode(t, filepath.Join(t.TempDir(), "missing.json"), "--as", "backlinks"); err == nil {
+		t.Error("decode of a missing file error = nil")
+	}
+}
+
+func TestDecodeArgs(t *testing.T) {
+	sel := url.Values{"select": {"url_from,anchor"}}
+	tests := []struct {
+		name   string
+		params url.Values
+		result interface{}
+		tr     transform
+		want   string
+	}{
+		{"backlinks", url.Values{}, &models.BacklinksResponse{}, nil, "--as backlinks"},
+		{"backlinks", sel, &models.BacklinksResponse{}, nil, "--as backlinks --select url_from,anchor"},
+		{"backlinks", url.Values{}, &models.BacklinkDomainsResponse{}, groupBacklinks, ""},
+		{"backlinks", url.Values{}, &models.RowCount{}, nil, ""},
+		{"digest", url.Values{}, &models.BacklinksResponse{}, nil, ""},
+	}
+	for _, tt := range tests {
+		if got := decodeArgs(tt.name, tt.params, tt.result, tt.tr); got != tt.want {
+			t.Errorf("decodeArgs(%s, %v, %T) = %q, want %q", tt.name, tt.params, tt.result, got, tt.want)
+		}
+	}
+}
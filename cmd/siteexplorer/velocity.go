@@ -0,0 +1,189 @@
+package siteexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+const (
+	// refDomainsHistoryPath is the endpoint link velocity is computed from
+	refDomainsHistoryPath = "/site-explorer/refdomains-history"
+
+	// monthLayout is the format of the months of a link velocity report
+	monthLayout = "2006-01"
+
+	// flatGrowth is the average monthly growth, in percent either way,
+	// within which the referring domains of a target are flat
+	flatGrowth = 1.0
+)
+
+// velocityStart returns the first day of the month before the months of a
+// link velocity report ending with the month of end, the month its growth
+// is measured from
+func velocityStart(end time.Time, months int) time.Time {
+	return time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -months, 0)
+}
+
+// linkVelocity returns a transform turning a referring domains history into
+// their net growth in each of the months after the month of params'
+// date_from, with a summary of the period logged to log
+func linkVelocity(params url.Values, months int, log *slog.Logger) transform {
+	return func(ctx context.Context, body io.Reader, fetch fetchFunc) (io.Reader, error) {
+		var resp models.RefDomainsHistoryResponse
+		if _, err := decodeResponse(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		start, err := time.Parse(dateLayout, params.Get("date_from"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid date_from %q: %w", params.Get("date_from"), err)
+		}
+
+		velocity := monthlyVelocity(resp.RefDomains, start, months)
+		if log != nil {
+			log.Info(velocitySummary(velocity.Summary))
+		}
+		data, err := json.Marshal(velocity)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+// monthlyVelocity returns the net new referring domains of each of the
+// months after that of start, from the last value of each month of history.
+// A month without history has no values, nor has the growth of the month
+// after it.
+func monthlyVelocity(history []models.RefDomainsHistoryEntry, start time.Time, months int) models.LinkVelocityResponse {
+	type last struct {
+		date  string
+		value int
+	}
+	byMonth := map[string]last{}
+	for _, h := range history {
+		if len(h.Date) < len(monthLayout) {
+			continue
+		}
+		month := h.Date[:len(monthLayout)]
+		if l, ok := byMonth[month]; !ok || h.Date >= l.date {
+			byMonth[month] = last{h.Date, h.RefDomains}
+		}
+	}
+	valueOf := func(t time.Time) *int {
+		l, ok := byMonth[t.Format(monthLayout)]
+		if !ok {
+			return nil
+		}
+		return &l.value
+	}
+
+	resp := models.LinkVelocityResponse{Months: make([]models.LinkVelocityMonth, 0, months)}
+	// The known values of the period, the month before it included, by the
+	// month's index from that one
+	var xs, ys []float64
+	prev := valueOf(start)
+	if prev != nil {
+		xs, ys = append(xs, 0), append(ys, float64(*prev))
+	}
+	for i := 1; i <= months; i++ {
+		month := start.AddDate(0, i, 0)
+		row := models.LinkVelocityMonth{Month: month.Format(monthLayout), RefDomains: valueOf(month)}
+		if row.RefDomains != nil {
+			xs, ys = append(xs, float64(i)), append(ys, float64(*row.RefDomains))
+			if prev != nil {
+				netNew := *row.RefDomains - *prev
+				row.NetNew, row.GrowthPercent = &netNew, growth(netNew, *prev)
+			}
+		}
+		prev = row.RefDomains
+		resp.Months = append(resp.Months, row)
+	}
+
+	s := models.LinkVelocitySummary{Months: months, Trend: "unknown"}
+	if len(ys) > 0 {
+		first, last := int(ys[0]), int(ys[len(ys)-1])
+		netNew := last - first
+		s.StartRefDomains, s.EndRefDomains = &first, &last
+		s.NetNew, s.GrowthPercent = &netNew, growth(netNew, first)
+	}
+	if rate := monthlyGrowthRate(xs, ys); rate != nil {
+		s.MonthlyGrowthRate = rate
+		switch {
+		case *rate > flatGrowth:
+			s.Trend = "growing"
+		case *rate < -flatGrowth:
+			s.Trend = "declining"
+		default:
+			s.Trend = "flat"
+		}
+	}
+	resp.Summary = s
+	return resp
+}
+
+// growth returns netNew as a percentage of from, rounded to two decimals, or
+// nil when from is 0
+func growth(netNew, from int) *float64 {
+	if from == 0 {
+		return nil
+	}
+	pct := round2(float64(netNew) / float64(from) * 100)
+	return &pct
+}
+
+// monthlyGrowthRate returns the slope of the least squares line through the
+// points (xs, ys) as a percentage of the mean of ys, rounded to two
+// decimals: the average growth per month. It returns nil for fewer than two
+// points or a mean of 0.
+func monthlyGrowthRate(xs, ys []float64) *float64 {
+	if len(xs) < 2 {
+		return nil
+	}
+	n := float64(len(xs))
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var cov, varX float64
+	for i := range xs {
+		cov += (xs[i] - meanX) * (ys[i] - meanY)
+		varX += (xs[i] - meanX) * (xs[i] - meanX)
+	}
+	if varX == 0 || meanY == 0 {
+		return nil
+	}
+	rate := round2(cov / varX / meanY * 100)
+	return &rate
+}
+
+// round2 rounds f to two decimals
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// velocitySummary describes s in a sentence
+func velocitySummary(s models.LinkVelocitySummary) string {
+	if s.NetNew == nil {
+		return fmt.Sprintf("No referring domains history in the %d months", s.Months)
+	}
+	msg := fmt.Sprintf("Referring domains %d to %d (%+d", *s.StartRefDomains, *s.EndRefDomains, *s.NetNew)
+	if s.GrowthPercent != nil {
+		msg += fmt.Sprintf(", %+.2f%%", *s.GrowthPercent)
+	}
+	msg += fmt.Sprintf(") over %d months: %s", s.Months, s.Trend)
+	if s.MonthlyGrowthRate != nil {
+		msg += fmt.Sprintf(", %+.2f%% a month", *s.MonthlyGrowthRate)
+	}
+	return msg
+}
@@ -0,0 +1,100 @@
+package siteexplorer
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// refDomainsHistory returns a referring domains history of the dates and values given
+// in pairs
+func refDomainsHistory(pairs ...interface{}) []models.RefDomainsHistoryEntry {
+	var h []models.RefDomainsHistoryEntry
+	for i := 0; i < len(pairs); i += 2 {
+		h = append(h, models.RefDomainsHistoryEntry{Date: pairs[i].(string), RefDomains: pairs[i+1].(int)})
+	}
+	return h
+}
+
+func TestMonthlyVelocity(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		history     []models.RefDomainsHistoryEntry
+		wantMonths  string
+		wantSummary string
+	}{
+		{
+			name:    "growing",
+			history: refDomainsHistory("2024-01-01", 1000, "2024-02-01", 1100, "2024-03-01", 1210, "2024-04-01", 1331),
+			wantMonths: `[{"month":"2024-02","refdomains":1100,"net_new":100,"growth_percent":10},` +
+				`{"month":"2024-03","refdomains":1210,"net_new":110,"growth_percent":10},` +
+				`{"month":"2024-04","refdomains":1331,"net_new":121,"growth_percent":10}]`,
+			wantSummary: `{"months":3,"start_refdomains":1000,"end_refdomains":1331,"net_new":331,"growth_percent":33.1,"monthly_growth_percent":9.51,"trend":"growing"}`,
+		},
+		{
+			name:    "declining, last value of each month",
+			history: refDomainsHistory("2024-01-01", 500, "2024-01-31", 480, "2024-02-15", 470, "2024-02-29", 450, "2024-03-31", 400, "2024-04-30", 380),
+			wantMonths: `[{"month":"2024-02","refdomains":450,"net_new":-30,"growth_percent":-6.25},` +
+				`{"month":"2024-03","refdomains":400,"net_new":-50,"growth_percent":-11.11},` +
+				`{"month":"2024-04","refdomains":380,"net_new":-20,"growth_percent":-5}]`,
+			wantSummary: `{"months":3,"start_refdomains":480,"end_refdomains":380,"net_new":-100,"growth_percent":-20.83,"monthly_growth_percent":-8.19,"trend":"declining"}`,
+		},
+		{
+			name:    "gap",
+			history: refDomainsHistory("2024-01-01", 200, "2024-02-01", 201, "2024-04-01", 203),
+			wantMonths: `[{"month":"2024-02","refdomains":201,"net_new":1,"growth_percent":0.5},` +
+				`{"month":"2024-03","refdomains":null,"net_new":null,"growth_percent":null},` +
+				`{"month":"2024-04","refdomains":203,"net_new":null,"growth_percent":null}]`,
+			wantSummary: `{"months":3,"start_refdomains":200,"end_refdomains":203,"net_new":3,"growth_percent":1.5,"monthly_growth_percent":0.5,"trend":"flat"}`,
+		},
+		{
+			name:    "history starting late",
+			history: refDomainsHistory("2024-03-01", 0, "2024-04-01", 12),
+			wantMonths: `[{"month":"2024-02","refdomains":null,"net_new":null,"growth_percent":null},` +
+				`{"month":"2024-03","refdomains":0,"net_new":null,"growth_percent":null},` +
+				`{"month":"2024-04","refdomains":12,"net_new":12,"growth_percent":null}]`,
+			wantSummary: `{"months":3,"start_refdomains":0,"end_refdomains":12,"net_new":12,"growth_percent":null,"monthly_growth_percent":200,"trend":"growing"}`,
+		},
+		{
+			name: "no history",
+			wantMonths: `[{"month":"2024-02","refdomains":null,"net_new":null,"growth_percent":null},` +
+				`{"month":"2024-03","refdomains":null,"net_new":null,"growth_percent":null},` +
+				`{"month":"2024-04","refdomains":null,"net_new":null,"growth_percent":null}]`,
+			wantSummary: `{"months":3,"start_refdomains":null,"end_refdomains":null,"net_new":null,"growth_percent":null,"monthly_growth_percent":null,"trend":"unknown"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := monthlyVelocity(tt.history, start, 3)
+			months, _ := json.Marshal(got.Months)
+			if string(months) != tt.wantMonths {
+				t.Errorf("months = %s, want %s", months, tt.wantMonths)
+			}
+			summary, _ := json.Marshal(got.Summary)
+			if string(summary) != tt.wantSummary {
+				t.Errorf("summary = %s, want %s", summary, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestVelocityStart(t *testing.T) {
+	end := time.Date(2024, 3, 31, 15, 0, 0, 0, time.UTC)
+	if got := velocityStart(end, 12).Format(dateLayout); got != "2023-03-01" {
+		t.Errorf("velocityStart() = %s, want 2023-03-01", got)
+	}
+}
+
+func TestLinkVelocity_InvalidMonths(t *testing.T) {
+	_, err := execCommand(t, "http://127.0.0.1:0", []string{"link-velocity", "-t", "t.com", "--months", "0"})
+	var coded *cmd.Error
+	if !errors.As(err, &coded) || coded.Code != cmd.CodeUsage {
+		t.Errorf("--months 0 error = %v, want a usage error", err)
+	}
+}
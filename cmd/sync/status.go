@@ -0,0 +1,118 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/sqlite"
+	"github.com/spf13/cobra"
+)
+
+// status is the sync state of one endpoint for one target
+type status struct {
+	Target    string `json:"target"`
+	Endpoint  string `json:"endpoint"`
+	LastSync  string `json:"last_sync"`
+	HighWater string `json:"high_water,omitempty"`
+	Rows      int64  `json:"rows"`
+}
+
+func newStatusCmd() *cobra.Command {
+	var db string
+
+	c := &cobra.Command{
+		Use:   "status",
+		Short: "Show last sync times and row counts",
+		Long:  "List every synced target and endpoint with its last sync time, high-water mark, and stored row count.",
+		Example: `  ahrefs sync status --db ahrefs.db
+  ahrefs sync status --db ahrefs.db --format table`,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runStatus(cobraCmd.Context(), db)
+		},
+	}
+
+	c.Flags().StringVar(&db, "db", "", "SQLite database file")
+	c.MarkFlagRequired("db")
+
+	return c
+}
+
+func runStatus(ctx context.Context, path string) error {
+	db, err := sqlite.Open(path)
+	if err != nil {
+		return cmd.NewError(cmd.CodeConfig, err.Error(), "Install the sqlite3 command-line shell")
+	}
+
+	statuses, err := readStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	w, err := cmd.OpenOutput(cmd.GetGlobalFlags(ctx))
+	if err != nil {
+		return err
+	}
+	if err := w.WriteSuccess(statuses, nil); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+// readStatus joins the sync state with per-target row counts of each table
+func readStatus(ctx context.Context, db *sqlite.DB) ([]status, error) {
+	exists, err := db.Query(ctx, fmt.Sprintf("SELECT name FROM sqlite_master WHERE type = 'table' AND name = '%s';", stateTable))
+	if err != nil {
+		return nil, err
+	}
+	statuses := []status{}
+	if len(exists) == 0 {
+		return statuses, nil
+	}
+
+	rows, err := db.Query(ctx, fmt.Sprintf("SELECT target, endpoint, high_water, last_sync FROM %s ORDER BY target, endpoint;", stateTable))
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]map[string]int64{}
+	for _, row := range rows {
+		s := status{}
+		s.Target, _ = row["target"].(string)
+		s.Endpoint, _ = row["endpoint"].(string)
+		s.LastSync, _ = row["last_sync"].(string)
+		s.HighWater, _ = row["high_water"].(string)
+
+		t, ok := tables[s.Endpoint]
+		if !ok {
+			continue
+		}
+		if counts[t.name] == nil {
+			if counts[t.name], err = countRows(ctx, db, t.name); err != nil {
+				return nil, err
+			}
+		}
+		s.Rows = counts[t.name][s.Target]
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// countRows returns the number of rows per target in a table
+func countRows(ctx context.Context, db *sqlite.DB, name string) (map[string]int64, error) {
+	rows, err := db.Query(ctx, fmt.Sprintf("SELECT target, count(*) AS n FROM %s GROUP BY target;", sqlite.Ident(name)))
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{}
+	for _, row := range rows {
+		target, _ := row["target"].(string)
+		if n, ok := row["n"].(json.Number); ok {
+			counts[target], _ = n.Int64()
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,535 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/sqlite"
+	"github.com/aminemat/ahrefs-cli/internal/usage"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+	"github.com/aminemat/ahrefs-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// stateTable records the high-water mark and last sync time per target and
+// endpoint
+const stateTable = "sync_state"
+
+// table describes how an endpoint is stored
+type table struct {
+	endpoint string
+	name     string
+	row      interface{} // model struct of one row
+	key      []string    // natural key, after target
+
+	// watermark is the date field used to pull only rows changed since the
+	// previous sync; endpoints without one are re-pulled in full
+	watermark string
+}
+
+// tables maps --endpoints names to their storage
+var tables = map[string]table{
+	"backlinks":        {"/site-explorer/backlinks", "backlinks", models.Backlink{}, []string{"url_from", "url_to"}, "last_visited"},
+	"refdomains":       {"/site-explorer/refdomains", "refdomains", models.RefDomain{}, []string{"domain"}, "last_visited"},
+	"organic-keywords": {"/site-explorer/organic-keywords", "organic_keywords", models.OrganicKeyword{}, []string{"keyword", "country", "url"}, ""},
+}
+
+// endpointNames returns the supported --endpoints values, sorted
+func endpointNames() []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// column is a SQL column derived from a model field
+type column struct {
+	name    string
+	sqlType string
+}
+
+// columns returns the row columns of t, in model order
+func (t table) columns() []column {
+	var cols []column
+	rt := reflect.TypeOf(t.row)
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		sqlType := "TEXT"
+		switch ft.Kind() {
+		case reflect.Int, reflect.Int64, reflect.Bool:
+			sqlType = "INTEGER"
+		case reflect.Float64:
+			sqlType = "REAL"
+		}
+		cols = append(cols, column{name, sqlType})
+	}
+	return cols
+}
+
+func (t table) isKey(name string) bool {
+	for _, k := range t.key {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// createSQL creates t and the state table if they don't exist. Key columns
+// are NOT NULL so missing values can't defeat the uniqueness constraint.
+func (t table) createSQL() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE IF NOT EXISTS %s (\n  target TEXT NOT NULL", sqlite.Ident(t.name))
+	for _, c := range t.columns() {
+		fmt.Fprintf(&b, ",\n  %s %s", sqlite.Ident(c.name), c.sqlType)
+		if t.isKey(c.name) {
+			b.WriteString(" NOT NULL DEFAULT ''")
+		}
+	}
+	fmt.Fprintf(&b, ",\n  synced_at TEXT NOT NULL,\n  PRIMARY KEY (target")
+	for _, k := range t.key {
+		b.WriteString(", " + sqlite.Ident(k))
+	}
+	b.WriteString(")\n);\n")
+	fmt.Fprintf(&b, "CREATE TABLE IF NOT EXISTS %s (\n  target TEXT NOT NULL,\n  endpoint TEXT NOT NULL,\n  high_water TEXT,\n  last_sync TEXT NOT NULL,\n  PRIMARY KEY (target, endpoint)\n);\n", stateTable)
+	return b.String()
+}
+
+// upsertSQL inserts rows, updating rows whose natural key already exists
+func (t table) upsertSQL(target string, rows []map[string]interface{}, syncedAt string) string {
+	cols := t.columns()
+	names := []string{"target"}
+	for _, c := range cols {
+		names = append(names, sqlite.Ident(c.name))
+	}
+	names = append(names, "synced_at")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES", sqlite.Ident(t.name), strings.Join(names, ", "))
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		values := []string{sqlite.Quote(target)}
+		for _, c := range cols {
+			v := row[c.name]
+			if v == nil && t.isKey(c.name) {
+				v = ""
+			}
+			values = append(values, sqlite.Quote(v))
+		}
+		values = append(values, sqlite.Quote(syncedAt))
+		fmt.Fprintf(&b, "\n  (%s)", strings.Join(values, ", "))
+	}
+
+	b.WriteString("\nON CONFLICT (target")
+	for _, k := range t.key {
+		b.WriteString(", " + sqlite.Ident(k))
+	}
+	b.WriteString(") DO UPDATE SET ")
+	var updates []string
+	for _, n := range names[1:] {
+		if !t.isKey(strings.Trim(n, `"`)) {
+			updates = append(updates, n+" = excluded."+n)
+		}
+	}
+	b.WriteString(strings.Join(updates, ", ") + ";\n")
+	return b.String()
+}
+
+// stateSQL records the sync of target's endpoint
+func stateSQL(target, endpoint, highWater, at string) string {
+	hw := sqlite.Quote(nil)
+	if highWater != "" {
+		hw = sqlite.Quote(highWater)
+	}
+	return fmt.Sprintf("INSERT INTO %s (target, endpoint, high_water, last_sync) VALUES (%s, %s, %s, %s)\n"+
+		"ON CONFLICT (target, endpoint) DO UPDATE SET high_water = COALESCE(excluded.high_water, high_water), last_sync = excluded.last_sync;\n",
+		stateTable, sqlite.Quote(target), sqlite.Quote(endpoint), hw, sqlite.Quote(at))
+}
+
+// options configures a sync run
+type options struct {
+	db        string
+	targets   []string
+	endpoints []string
+	mode      string
+	pageSize  int
+	failFast  bool
+
+	// limits are the request limits of each endpoint, by name
+	limits map[string]limits
+}
+
+// limits are the timeout and retries of an endpoint's requests, 0 for the
+// client's
+type limits struct {
+	timeout    time.Duration
+	maxRetries int
+}
+
+// NewSyncCmd creates the sync command
+func NewSyncCmd() *cobra.Command {
+	var opts options
+
+	c := &cobra.Command{
+		Use:   "sync",
+		Short: "Pull site data into a local SQLite database",
+		Long: `Pull backlinks, referring domains, and organic keywords for one or more
+targets into a SQLite database, one table per endpoint with a target column.
+Rows are upserted on their natural key, so running sync again updates rows
+in place instead of duplicating them:
+
+  backlinks          target, url_from, url_to
+  refdomains         target, domain
+  organic_keywords   target, keyword, country, url
+
+Backlinks and referring domains are pulled incrementally: each run only
+requests rows last visited on or after the newest last_visited stored by the
+previous run (the high-water mark, kept in the sync_state table). Organic
+keywords have no such date and are re-pulled in full. Pages are committed as
+they arrive, so an interrupted sync picks up where it stopped.
+
+Each endpoint's requests take the --timeout and --max-retries set in the
+config defaults of its site-explorer command, such as "site-explorer
+backlinks", unless sync's own flags are given.
+
+A target that fails, such as one the API doesn't know, is skipped for the
+endpoints left and written with its error after the others' results, and
+the command exits 3; --fail-fast stops at the first instead.
+
+Requires the sqlite3 shell (SQLite 3.33 or later) on PATH.`,
+		Example: `  # Build or refresh a local warehouse
+  ahrefs sync --db ahrefs.db --target example.com --endpoints backlinks,refdomains,organic-keywords
+
+  # Query it
+  sqlite3 ahrefs.db 'SELECT domain, domain_rating FROM refdomains ORDER BY domain_rating DESC LIMIT 10'
+
+  # Show what has been synced
+  ahrefs sync status --db ahrefs.db`,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			opts.limits = map[string]limits{}
+			for _, e := range opts.endpoints {
+				timeout, maxRetries, err := cmd.RequestLimits(cobraCmd, "site-explorer "+e)
+				if err != nil {
+					return err
+				}
+				opts.limits[e] = limits{timeout, maxRetries}
+			}
+			return run(cobraCmd.Context(), opts)
+		},
+	}
+
+	c.Flags().StringVar(&opts.db, "db", "", "SQLite database file (created if missing)")
+	c.Flags().StringSliceVarP(&opts.targets, "target", "t", nil, "Target domain or URL (repeatable)")
+	c.Flags().StringSliceVar(&opts.endpoints, "endpoints", endpointNames(), "Endpoints to sync")
+	c.Flags().StringVarP(&opts.mode, "mode", "m", "domain", "Mode: exact, domain, prefix, subdomains")
+	c.Flags().IntVar(&opts.pageSize, "page-size", 1000, "Rows requested per API call")
+	c.Flags().BoolVar(&opts.failFast, "fail-fast", false, "Stop at the first target that fails instead of syncing the others")
+
+	cmd.SetAllowedValues(c, "endpoints", endpointNames()...)
+	cmd.SetAllowedValues(c, "mode", models.Strings(models.Modes())...)
+	c.MarkFlagRequired("db")
+	c.MarkFlagRequired("target")
+
+	c.AddCommand(newStatusCmd())
+
+	return c
+}
+
+// result summarizes the sync of one endpoint for one target
+type result struct {
+	Target    string `json:"target"`
+	Endpoint  string `json:"endpoint"`
+	Rows      int    `json:"rows"`
+	HighWater string `json:"high_water,omitempty"`
+	Units     int    `json:"units"`
+}
+
+func run(ctx context.Context, opts options) error {
+	for _, e := range opts.endpoints {
+		if _, ok := tables[e]; !ok {
+			return cmd.NewError(cmd.CodeUsage, fmt.Sprintf("unknown endpoint %q", e),
+				"Supported endpoints: "+strings.Join(endpointNames(), ", "))
+		}
+	}
+	if opts.pageSize <= 0 {
+		return cmd.NewError(cmd.CodeUsage, "--page-size must be positive", "")
+	}
+
+	flags := cmd.GetGlobalFlags(ctx)
+	apiKey := flags.APIKey
+	if apiKey == "" {
+		apiKey = config.GetAPIKey()
+	}
+	if apiKey == "" {
+		return cmd.ErrAPIKeyRequired
+	}
+
+	// A templated --output writes a summary per target; check the names first
+	outputs, err := cmd.TargetOutputs(flags, "sync", opts.targets)
+	if err != nil {
+		return err
+	}
+
+	db, err := sqlite.Open(opts.db)
+	if err != nil {
+		return cmd.NewError(cmd.CodeConfig, err.Error(), "Install the sqlite3 command-line shell")
+	}
+
+	c := client.NewClient(client.Config{
+		APIKey:      apiKey,
+		BaseURL:     flags.BaseURL,
+		BaseURLs:    flags.BaseURLs,
+		Headers:     flags.Headers,
+		Timeout:     flags.Timeout,
+		MaxBodySize: flags.MaxBodySize,
+		MaxRetries:  flags.MaxRetries,
+		Middleware:  cmd.ClientMiddleware(flags),
+		Logger:      flags.Log(),
+		OnRetry:     flags.Progress.Retry,
+	})
+	s := &syncer{client: c, db: db, mode: opts.mode, pageSize: opts.pageSize, limits: opts.limits}
+
+	var results []result
+	var rows, units int
+	batch := cmd.NewBatch(flags, opts.failFast)
+	for i, target := range opts.targets {
+		var syncErr error
+		for _, e := range opts.endpoints {
+			res, err := s.sync(ctx, target, e, tables[e])
+			recordUsage(flags.Log(), tables[e].endpoint, res.Units)
+			if err != nil {
+				// The endpoints left would most likely fail the same way
+				syncErr = err
+				break
+			}
+			flags.Log().Info(fmt.Sprintf("Synced %d %s row(s) for %s", res.Rows, e, target), "endpoint", tables[e].endpoint, "target", target)
+			results = append(results, res)
+			rows, units = rows+res.Rows, units+res.Units
+		}
+		if err := batch.Done(target, syncErr); err != nil {
+			return err
+		}
+		flags.Progress.Target(target, i+1, len(opts.targets), rows, units)
+	}
+
+	if outputs == nil {
+		err = writeResults(flags, results, batch.Failures)
+	} else {
+		for i, target := range opts.targets {
+			if err = writeResults(outputs[i], targetResults(results, target), batch.FailuresOf(target)); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return batch.Err()
+}
+
+// writeResults writes results, and the targets that failed, with the
+// output settings of flags
+func writeResults(flags cmd.GlobalFlags, results []result, failures []output.Failure) error {
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return err
+	}
+	w.SetFailures(failures)
+	if err := w.WriteSuccess(results, nil); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+// targetResults returns the results of target
+func targetResults(results []result, target string) []result {
+	var kept []result
+	for _, r := range results {
+		if r.Target == target {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// syncer pulls endpoints into a database
+type syncer struct {
+	client   *client.Client
+	db       *sqlite.DB
+	mode     string
+	pageSize int
+	limits   map[string]limits
+}
+
+// sync pulls one endpoint for target, committing each page together with
+// the advanced high-water mark
+func (s *syncer) sync(ctx context.Context, target, name string, t table) (result, error) {
+	res := result{Target: target, Endpoint: name}
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	if err := s.db.Exec(ctx, t.createSQL()); err != nil {
+		return res, err
+	}
+	if err := s.addColumns(ctx, t); err != nil {
+		return res, err
+	}
+
+	params := url.Values{
+		"target": {target},
+		"mode":   {s.mode},
+		"limit":  {strconv.Itoa(s.pageSize)},
+	}
+	if t.watermark != "" {
+		hw, err := s.highWater(ctx, target, name)
+		if err != nil {
+			return res, err
+		}
+		res.HighWater = hw
+		params.Set("order_by", t.watermark+":asc")
+		if hw != "" {
+			params.Set("where", fmt.Sprintf(`{"field":%q,"is":["gte",%q]}`, t.watermark, hw))
+		}
+	}
+
+	for offset := 0; ; offset += s.pageSize {
+		if offset > 0 {
+			params.Set("offset", strconv.Itoa(offset))
+		}
+		l := s.limits[name]
+		resp, err := s.client.Do(ctx, client.Request{
+			Method:     http.MethodGet,
+			Endpoint:   t.endpoint,
+			Params:     params,
+			Timeout:    l.timeout,
+			MaxRetries: l.maxRetries,
+		})
+		if err != nil {
+			return res, err
+		}
+		res.Units += resp.Meta.UnitsConsumed
+
+		rows, err := decodeRows(resp.Body)
+		if err != nil {
+			return res, fmt.Errorf("failed to parse %s response: %w", t.endpoint, err)
+		}
+		for _, row := range rows {
+			if v, ok := row[t.watermark].(string); ok && v > res.HighWater {
+				res.HighWater = v
+			}
+		}
+
+		script := "BEGIN;\n"
+		if len(rows) > 0 {
+			script += t.upsertSQL(target, rows, now)
+		}
+		script += stateSQL(target, name, res.HighWater, now) + "COMMIT;\n"
+		if err := s.db.Exec(ctx, script); err != nil {
+			return res, err
+		}
+		res.Rows += len(rows)
+
+		if len(rows) < s.pageSize {
+			return res, nil
+		}
+	}
+}
+
+// highWater returns the stored high-water mark of target's endpoint
+// addColumns adds model columns missing from a table created by an earlier
+// version
+func (s *syncer) addColumns(ctx context.Context, t table) error {
+	rows, err := s.db.Query(ctx, fmt.Sprintf("PRAGMA table_info(%s);", sqlite.Ident(t.name)))
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, row := range rows {
+		if name, ok := row["name"].(string); ok {
+			existing[name] = true
+		}
+	}
+
+	var b strings.Builder
+	for _, c := range t.columns() {
+		if !existing[c.name] && !t.isKey(c.name) {
+			fmt.Fprintf(&b, "ALTER TABLE %s ADD COLUMN %s %s;\n", sqlite.Ident(t.name), sqlite.Ident(c.name), c.sqlType)
+		}
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	return s.db.Exec(ctx, b.String())
+}
+
+func (s *syncer) highWater(ctx context.Context, target, name string) (string, error) {
+	rows, err := s.db.Query(ctx, fmt.Sprintf("SELECT high_water FROM %s WHERE target = %s AND endpoint = %s;",
+		stateTable, sqlite.Quote(target), sqlite.Quote(name)))
+	if err != nil || len(rows) == 0 {
+		return "", err
+	}
+	hw, _ := rows[0]["high_water"].(string)
+	return hw, nil
+}
+
+// decodeRows returns the rows of the single list field of a response
+func decodeRows(body []byte) ([]map[string]interface{}, error) {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(body, &wrapper); err != nil {
+		return nil, err
+	}
+	for _, raw := range wrapper {
+		if len(raw) == 0 || raw[0] != '[' {
+			continue
+		}
+		var rows []map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("response has no list of rows")
+}
+
+// recordUsage adds the units spent to the usage ledger when a budget is
+// being tracked
+func recordUsage(log *slog.Logger, endpoint string, units int) {
+	if units == 0 {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil || !cfg.Budget.Enabled() {
+		return
+	}
+	if err := usage.Record(usage.Entry{Time: time.Now(), Endpoint: endpoint, Units: units}); err != nil {
+		log.Warn("failed to record usage", "err", err)
+	}
+}
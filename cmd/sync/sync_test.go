@@ -0,0 +1,166 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/internal/sqlite"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+func TestUpsertSQL(t *testing.T) {
+	rows := []map[string]interface{}{{"domain": "a.example", "domain_rating": json.Number("55")}, {"backlinks": json.Number("3")}}
+	got := tables["refdomains"].upsertSQL("example.com", rows, "2025-06-01T00:00:00Z")
+
+	for _, want := range []string{
+		`INSERT INTO "refdomains" (target, "domain", "domain_rating", `,
+		`('example.com', 'a.example', 55, NULL, `,
+		// A missing key column is stored as '' so the key stays unique
+		`('example.com', '', NULL, NULL, NULL, 3, `,
+		`ON CONFLICT (target, "domain") DO UPDATE SET "domain_rating" = excluded."domain_rating", `,
+		`synced_at = excluded.synced_at;`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("upsertSQL() missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `"domain" = excluded`) {
+		t.Errorf("upsertSQL() updates the key column:\n%s", got)
+	}
+}
+
+func TestCreateSQL(t *testing.T) {
+	got := tables["backlinks"].createSQL()
+	for _, want := range []string{
+		`"url_from" TEXT NOT NULL DEFAULT ''`,
+		`"domain_rating" REAL`,
+		`"traffic" INTEGER`,
+		`"is_dofollow" INTEGER`,
+		`"page_size" INTEGER`,
+		`"title" TEXT`,
+		`PRIMARY KEY (target, "url_from", "url_to")`,
+		`CREATE TABLE IF NOT EXISTS sync_state`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("createSQL() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+// fakeShell installs a sqlite3 stand-in that logs every script it is given
+// and answers queries with answer
+func fakeShell(t *testing.T, answer string) string {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "scripts.log")
+	script := "#!/bin/sh\nin=$(cat)\nprintf '%s\\n--\\n' \"$in\" >> " + log + "\n" +
+		"case \"$in\" in *'.mode json'*) printf '%s' '" + answer + "' ;; esac\n"
+	if err := os.WriteFile(filepath.Join(dir, "sqlite3"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+func TestSyncer_Incremental(t *testing.T) {
+	log := fakeShell(t, `[{"high_water":"2025-05-01"}]`)
+
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Header().Set("X-API-Units-Consumed", "10")
+		if r.URL.Query().Get("offset") == "" {
+			w.Write([]byte(`{"refdomains":[{"domain":"a.example","last_visited":"2025-05-03"},{"domain":"b.example","last_visited":"2025-05-07"}]}`))
+			return
+		}
+		w.Write([]byte(`{"refdomains":[{"domain":"c.example","last_visited":"2025-05-02"}]}`))
+	}))
+	defer srv.Close()
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("sqlite.Open() error = %v", err)
+	}
+	s := &syncer{client: client.NewClient(client.Config{APIKey: "test", BaseURL: srv.URL}), db: db, mode: "domain", pageSize: 2}
+
+	res, err := s.sync(context.Background(), "example.com", "refdomains", tables["refdomains"])
+	if err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	if res.Rows != 3 || res.HighWater != "2025-05-07" || res.Units != 20 {
+		t.Errorf("sync() = %+v, want 3 rows, high water 2025-05-07, 20 units", res)
+	}
+
+	if len(queries) != 2 || !strings.Contains(queries[1], "offset=2") {
+		t.Fatalf("queries = %v, want two pages", queries)
+	}
+	if !strings.Contains(queries[0], "where=%7B%22field%22%3A%22last_visited%22%2C%22is%22%3A%5B%22gte%22%2C%222025-05-01%22%5D%7D") {
+		t.Errorf("first query %q does not filter on the stored high-water mark", queries[0])
+	}
+
+	data, _ := os.ReadFile(log)
+	scripts := string(data)
+	if n := strings.Count(scripts, "COMMIT;"); n != 2 {
+		t.Errorf("committed %d page(s), want 2:\n%s", n, scripts)
+	}
+	if !strings.Contains(scripts, `('example.com', 'refdomains', '2025-05-07'`) {
+		t.Errorf("high-water mark not recorded:\n%s", scripts)
+	}
+}
+
+func TestSyncer_Limits(t *testing.T) {
+	fakeShell(t, `[]`)
+
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("sqlite.Open() error = %v", err)
+	}
+	s := &syncer{
+		client:   client.NewClient(client.Config{APIKey: "test", BaseURL: srv.URL, MaxRetries: 3}),
+		db:       db,
+		mode:     "domain",
+		pageSize: 2,
+		limits:   map[string]limits{"refdomains": {maxRetries: -1}},
+	}
+
+	if _, err := s.sync(context.Background(), "example.com", "refdomains", tables["refdomains"]); err == nil {
+		t.Fatal("sync() error = nil, want the 503")
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1: the endpoint's limits allow no retries", attempts)
+	}
+}
+
+func TestSyncer_AddColumns(t *testing.T) {
+	log := fakeShell(t, `[{"name":"target"},{"name":"url_from"},{"name":"url_to"},{"name":"title"}]`)
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("sqlite.Open() error = %v", err)
+	}
+	s := &syncer{db: db}
+	if err := s.addColumns(context.Background(), tables["backlinks"]); err != nil {
+		t.Fatalf("addColumns() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(log)
+	scripts := string(data)
+	if !strings.Contains(scripts, `ALTER TABLE "backlinks" ADD COLUMN "page_size" INTEGER;`) {
+		t.Errorf("missing column not added:\n%s", scripts)
+	}
+	if strings.Contains(scripts, `ADD COLUMN "title"`) || strings.Contains(scripts, `ADD COLUMN "url_from"`) {
+		t.Errorf("existing column added again:\n%s", scripts)
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/telemetry"
+)
+
+// telemetryFlushTimeout bounds the final export on exit
+const telemetryFlushTimeout = 5 * time.Second
+
+var (
+	telemetryMu      sync.Mutex
+	telemetryStarted bool
+	tel              *telemetry.Telemetry
+)
+
+// ClientMiddleware returns the middleware API clients of a command run with
+// flags should be built with: request and unit counting for the run
+// summaries, request logging, and telemetry. Telemetry is set up on first
+// use, and only when an OTLP endpoint is configured.
+func ClientMiddleware(flags GlobalFlags) []client.Middleware {
+	var mw []client.Middleware
+	if flags.run != nil {
+		mw = append(mw, flags.run.countRequests)
+	}
+	mw = append(mw, logRequests(flags.Log(), flags.headers))
+
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+	if !telemetryStarted {
+		tel = telemetry.FromEnv()
+		if tel != nil {
+			tel.SetLogger(flags.Log())
+		}
+		telemetryStarted = true
+	}
+	if tel != nil {
+		mw = append(mw, tel.Middleware())
+	}
+	return mw
+}
+
+// shutdownTelemetry exports what telemetry has buffered before exit
+func shutdownTelemetry() {
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+	if tel != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), telemetryFlushTimeout)
+		defer cancel()
+		if err := tel.Shutdown(ctx); err != nil {
+			tel.Log().Warn("telemetry export failed", "err", err)
+		}
+	}
+	tel = nil
+	telemetryStarted = false
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	// Zones resolve on systems without a zoneinfo database, such as
+	// Windows or a scratch container
+	_ "time/tzdata"
+)
+
+// timezone is a flag value holding an IANA time zone, such as
+// America/New_York. Empty is the local zone.
+type timezone struct {
+	name string
+	loc  *time.Location
+}
+
+func (z *timezone) String() string { return z.name }
+
+func (z *timezone) Type() string { return "zone" }
+
+func (z *timezone) Set(value string) error {
+	if value == "" {
+		*z = timezone{}
+		return nil
+	}
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q: want an IANA name such as Europe/Paris, or UTC", value)
+	}
+	*z = timezone{name: value, loc: loc}
+	return nil
+}
+
+// location returns the zone, the local one when none is set
+func (z *timezone) location() *time.Location {
+	if z.loc == nil {
+		return time.Local
+	}
+	return z.loc
+}
+
+// Now returns the current time in --timezone, the anchor of relative dates
+// such as today or 7d
+func (f GlobalFlags) Now() time.Time {
+	if f.Location == nil {
+		return time.Now()
+	}
+	return time.Now().In(f.Location)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestTimezone(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "America/New_York", want: "America/New_York"},
+		{value: "UTC", want: "UTC"},
+		{value: "", want: "Local"},
+		{value: "Mars/Olympus_Mons", wantErr: true},
+		{value: "EST5EDT,M3.2.0,M11.1.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var z timezone
+			err := z.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := z.location().String(); got != tt.want || z.String() != tt.value {
+				t.Errorf("Set(%q) = %s (%q), want %s", tt.value, got, z.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGlobalFlags_Timezone(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	c := &cobra.Command{Use: "test"}
+	c.Flags().Var(&timezone{}, "timezone", "Time zone")
+
+	if flags := globalFlags(c); flags.Location != time.Local {
+		t.Errorf("Location = %s, want Local by default", flags.Location)
+	}
+	if err := c.Flags().Set("timezone", "Asia/Tokyo"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	flags := globalFlags(c)
+	if flags.Location.String() != "Asia/Tokyo" {
+		t.Errorf("Location = %s, want Asia/Tokyo", flags.Location)
+	}
+	if _, offset := flags.Now().Zone(); offset != 9*60*60 {
+		t.Errorf("Now() offset = %ds, want Tokyo's", offset)
+	}
+}
+
+func TestExecute_InvalidTimezone(t *testing.T) {
+	_, err := runWithTestCommand(t, nil, "test-cmd", "--target", "example.com", "--timezone", "Nowhere/Special")
+	var coded *Error
+	if !errors.As(err, &coded) || coded.Code != CodeUsage {
+		t.Errorf("error = %v, want a usage error for an unknown zone", err)
+	}
+}
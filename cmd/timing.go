@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// ExitTiming is where the time of a run's requests went, summed over them, in
+// its --print-exit-summary line
+type ExitTiming struct {
+	DNSMS             int64 `json:"dns_ms"`
+	ConnectMS         int64 `json:"connect_ms"`
+	TLSMS             int64 `json:"tls_ms"`
+	ServerMS          int64 `json:"server_ms"`
+	TransferMS        int64 `json:"transfer_ms"`
+	BackoffMS         int64 `json:"backoff_ms"`
+	RateLimitWaitMS   int64 `json:"rate_limit_wait_ms"`
+	Attempts          int   `json:"attempts"`
+	NewConnections    int   `json:"new_connections"`
+	ReusedConnections int   `json:"reused_connections"`
+}
+
+// exitTiming converts t to milliseconds
+func exitTiming(t client.Timing) ExitTiming {
+	return ExitTiming{
+		DNSMS:             t.DNS.Milliseconds(),
+		ConnectMS:         t.Connect.Milliseconds(),
+		TLSMS:             t.TLS.Milliseconds(),
+		ServerMS:          t.Server.Milliseconds(),
+		TransferMS:        t.Transfer.Milliseconds(),
+		BackoffMS:         t.Backoff.Milliseconds(),
+		RateLimitWaitMS:   t.RateLimit.Milliseconds(),
+		Attempts:          t.Attempts,
+		NewConnections:    t.NewConnections,
+		ReusedConnections: t.ReusedConnections,
+	}
+}
+
+// reportTiming logs where the time of c's requests went, with -v, when it
+// made more than one: a single request's time is its response time
+func reportTiming(c *cobra.Command, run *runStats, elapsed time.Duration) {
+	run.mu.Lock()
+	requests, t := run.requests, run.timing
+	run.mu.Unlock()
+	if requests < 2 {
+		return
+	}
+
+	round := func(d time.Duration) string { return d.Round(time.Millisecond).String() }
+	globalFlags(c).Log().Debug("Request timing",
+		"requests", requests,
+		"attempts", t.Attempts,
+		"elapsed", round(elapsed),
+		"server", round(t.Server),
+		"transfer", round(t.Transfer),
+		"dns", round(t.DNS),
+		"connect", round(t.Connect),
+		"tls", round(t.TLS),
+		"backoff", round(t.Backoff),
+		"rate_limit_wait", round(t.RateLimit),
+		"new_connections", t.NewConnections,
+		"reused_connections", t.ReusedConnections)
+}
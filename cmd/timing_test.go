@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// runRequestsCommand executes args against the root command with a
+// "requests-cmd" subcommand making n requests to baseURL, returning stderr
+func runRequestsCommand(t *testing.T, baseURL string, n int, args ...string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AHREFS_VERBOSE", "")
+
+	requestsCmd := &cobra.Command{
+		Use: "requests-cmd",
+		RunE: func(c *cobra.Command, args []string) error {
+			flags := GetGlobalFlags(c.Context())
+			api := client.NewClient(client.Config{
+				APIKey:     "test",
+				BaseURL:    baseURL,
+				Middleware: ClientMiddleware(flags),
+			})
+			for i := 0; i < n; i++ {
+				if _, err := api.Get(c.Context(), "/site-explorer/domain-rating", nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	rootCmd.AddCommand(requestsCmd)
+	defer rootCmd.RemoveCommand(requestsCmd)
+	defer resetFlags(rootCmd)
+
+	var stderr bytes.Buffer
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetOut(&bytes.Buffer{})
+	defer rootCmd.SetErr(nil)
+	defer rootCmd.SetOut(nil)
+
+	if err := execute(context.Background(), append([]string{"requests-cmd"}, args...)); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+	return stderr.String()
+}
+
+func TestReportTiming(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+	defer api.Close()
+
+	tests := []struct {
+		name     string
+		requests int
+		args     []string
+		want     bool
+	}{
+		{name: "verbose", requests: 3, args: []string{"-v"}, want: true},
+		{name: "single request", requests: 1, args: []string{"-v"}},
+		{name: "not verbose", requests: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stderr := runRequestsCommand(t, api.URL, tt.requests, tt.args...)
+
+			var report string
+			for _, line := range strings.Split(stderr, "\n") {
+				if strings.HasPrefix(line, "Request timing ") {
+					report = line
+				}
+			}
+			if got := report != ""; got != tt.want {
+				t.Fatalf("stderr = %q, want a timing report: %v", stderr, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+			for _, want := range []string{"requests=3 attempts=3 ", " server=", " backoff=0s", " rate_limit_wait=", " new_connections=", " reused_connections="} {
+				if !strings.Contains(report, want) {
+					t.Errorf("report = %q, want %q", report, want)
+				}
+			}
+		})
+	}
+}
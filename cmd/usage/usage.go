@@ -0,0 +1,108 @@
+package usage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+// EndpointUsage is the month-to-date unit total for one endpoint
+type EndpointUsage struct {
+	Endpoint string `json:"endpoint"`
+	Requests int    `json:"requests"`
+	Units    int    `json:"units"`
+}
+
+// NewUsageCmd creates the usage command
+func NewUsageCmd() *cobra.Command {
+	var showBudget bool
+
+	c := &cobra.Command{
+		Use:   "usage",
+		Short: "Show locally tracked API unit usage",
+		Long: `Show month-to-date API unit usage recorded in the local usage ledger.
+
+Usage is only recorded once a monthly budget is configured with
+'ahrefs config set-budget'. Nothing is sent anywhere; the ledger lives in the
+CLI cache directory.`,
+		Example: `  # Month-to-date units by endpoint
+  ahrefs usage
+
+  # Budget standing
+  ahrefs usage --budget`,
+		SilenceUsage: true,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return runUsage(cobraCmd.Context(), showBudget)
+		},
+	}
+
+	c.Flags().BoolVar(&showBudget, "budget", false, "Show month-to-date consumption against the monthly budget")
+
+	return c
+}
+
+func runUsage(ctx context.Context, showBudget bool) error {
+	flags := cmd.GetGlobalFlags(ctx)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	entries, err := usage.Entries(now)
+	if err != nil {
+		return err
+	}
+
+	var data interface{} = byEndpoint(entries)
+	if showBudget {
+		if !cfg.Budget.Enabled() {
+			return cmd.NewError(cmd.CodeConfig, "no monthly budget configured",
+				"Run 'ahrefs config set-budget <monthly-units> [warn-percent]' to enable budget tracking")
+		}
+		used := 0
+		for _, e := range entries {
+			used += e.Units
+		}
+		data = usage.Check(now, used, cfg.Budget)
+	}
+
+	w, err := cmd.OpenOutput(flags)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteSuccess(data, nil); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+// byEndpoint totals entries per endpoint, sorted by endpoint
+func byEndpoint(entries []usage.Entry) []EndpointUsage {
+	totals := map[string]*EndpointUsage{}
+	for _, e := range entries {
+		t, ok := totals[e.Endpoint]
+		if !ok {
+			t = &EndpointUsage{Endpoint: e.Endpoint}
+			totals[e.Endpoint] = t
+		}
+		t.Requests++
+		t.Units += e.Units
+	}
+
+	result := make([]EndpointUsage, 0, len(totals))
+	for _, t := range totals {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Endpoint < result[j].Endpoint
+	})
+	return result
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// verbosity is a flag value counting how often --verbose is given, so -vv
+// logs more than -v. Values set from the environment or config may be a
+// count or a boolean, as --verbose was before it counted.
+type verbosity int
+
+func (v *verbosity) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *verbosity) Type() string { return "count" }
+
+func (v *verbosity) Set(value string) error {
+	// pflag passes NoOptDefVal for a flag given without a value
+	if value == "+1" {
+		*v++
+		return nil
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+		*v = verbosity(n)
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid verbosity %q: want a count or true/false", value)
+	}
+	*v = 0
+	if b {
+		*v = 1
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+// StateFileName is the name of the alert command's last-values file inside
+// the cache directory
+const StateFileName = "alert-state.json"
+
+// Source is the endpoint and response field a metric is read from
+type Source struct {
+	Endpoint string
+	Field    string
+}
+
+// Sources maps metric names to where they are read from
+var Sources = map[string]Source{
+	"domain_rating": {"/site-explorer/domain-rating", "domain_rating"},
+	"org_traffic":   {"/site-explorer/metrics", "org_traffic"},
+	"org_keywords":  {"/site-explorer/metrics", "org_keywords"},
+	"org_cost":      {"/site-explorer/metrics", "org_cost"},
+	"paid_traffic":  {"/site-explorer/metrics", "paid_traffic"},
+	"paid_keywords": {"/site-explorer/metrics", "paid_keywords"},
+	"paid_cost":     {"/site-explorer/metrics", "paid_cost"},
+	"live":          {"/site-explorer/backlinks-stats", "live"},
+	"refdomains":    {"/site-explorer/backlinks-stats", "refdomains"},
+}
+
+// Metrics returns the supported metric names, sorted
+func Metrics() []string {
+	names := make([]string, 0, len(Sources))
+	for name := range Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fetch reads metrics for target as of today, with one request per
+// endpoint. It returns the values and the units consumed per endpoint.
+// Metrics the API omits are reported as zero.
+func Fetch(ctx context.Context, c *client.Client, target, mode string, metrics []string) (map[string]float64, map[string]int, error) {
+	byEndpoint := map[string][]string{}
+	for _, m := range metrics {
+		src, ok := Sources[m]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown metric %q (supported: %s)", m, strings.Join(Metrics(), ", "))
+		}
+		byEndpoint[src.Endpoint] = append(byEndpoint[src.Endpoint], m)
+	}
+
+	params := requestParams(target, mode)
+	values := map[string]float64{}
+	units := map[string]int{}
+	for endpoint, names := range byEndpoint {
+		fields, n, err := fetchFields(ctx, c, endpoint, params)
+		units[endpoint] += n
+		if err != nil {
+			return nil, units, err
+		}
+		for _, name := range names {
+			v, _ := fields[Sources[name].Field].Float64()
+			values[name] = v
+		}
+	}
+	return values, units, nil
+}
+
+// FetchSource reads a single field of any single-object endpoint for
+// target as of today, returning the value and the units consumed. Unlike
+// Fetch, a field missing from the response is an error.
+func FetchSource(ctx context.Context, c *client.Client, target, mode string, src Source) (float64, int, error) {
+	fields, units, err := fetchFields(ctx, c, src.Endpoint, requestParams(target, mode))
+	if err != nil {
+		return 0, units, err
+	}
+	n, ok := fields[src.Field]
+	if !ok {
+		return 0, units, fmt.Errorf("%s response has no numeric field %q", src.Endpoint, src.Field)
+	}
+	v, _ := n.Float64()
+	return v, units, nil
+}
+
+func requestParams(target, mode string) url.Values {
+	return url.Values{
+		"target": {target},
+		"mode":   {mode},
+		"date":   {time.Now().UTC().Format("2006-01-02")},
+	}
+}
+
+// fetchFields requests endpoint and returns the numeric fields of its
+// response object along with the units consumed
+func fetchFields(ctx context.Context, c *client.Client, endpoint string, params url.Values) (map[string]json.Number, int, error) {
+	resp, err := c.Get(ctx, endpoint, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	fields, err := unwrapFields(resp.Body)
+	if err != nil {
+		return nil, resp.Meta.UnitsConsumed, fmt.Errorf("failed to parse %s response: %w", endpoint, err)
+	}
+	return fields, resp.Meta.UnitsConsumed, nil
+}
+
+// unwrapFields decodes a single-object response such as
+// {"metrics": {...}} into its inner fields
+func unwrapFields(body []byte) (map[string]json.Number, error) {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(body, &wrapper); err != nil {
+		return nil, err
+	}
+	for _, inner := range wrapper {
+		fields := map[string]json.Number{}
+		dec := json.NewDecoder(bytes.NewReader(inner))
+		dec.UseNumber()
+		var generic map[string]interface{}
+		if dec.Decode(&generic) != nil {
+			continue
+		}
+		for k, v := range generic {
+			if n, ok := v.(json.Number); ok {
+				fields[k] = n
+			}
+		}
+		return fields, nil
+	}
+	return nil, errors.New("response has no metrics object")
+}
+
+// Rule is a set of thresholds; a value triggers the rule if it breaks any
+// threshold that is set
+type Rule struct {
+	Below     *float64
+	Above     *float64
+	ChangePct *float64 // absolute change versus the previous value, in percent
+}
+
+// Result is the outcome of checking one metric of one target
+type Result struct {
+	Target    string   `json:"target"`
+	Metric    string   `json:"metric"`
+	Current   float64  `json:"current"`
+	Previous  *float64 `json:"previous,omitempty"`
+	ChangePct *float64 `json:"change_pct,omitempty"`
+	Triggered bool     `json:"triggered"`
+	Reason    string   `json:"reason,omitempty"`
+	Link      string   `json:"link"`
+}
+
+// Check evaluates current against r, with previous the value from the last
+// run, if any
+func (r Rule) Check(target, metric string, current float64, previous *float64) Result {
+	res := Result{Target: target, Metric: metric, Current: current, Previous: previous, Link: Link(target)}
+
+	if previous != nil && *previous != 0 {
+		pct := math.Round((current-*previous) / *previous * 10000) / 100
+		res.ChangePct = &pct
+	}
+
+	var reasons []string
+	if r.Below != nil && current < *r.Below {
+		reasons = append(reasons, "below "+formatValue(*r.Below))
+	}
+	if r.Above != nil && current > *r.Above {
+		reasons = append(reasons, "above "+formatValue(*r.Above))
+	}
+	if r.ChangePct != nil && res.ChangePct != nil && math.Abs(*res.ChangePct) >= *r.ChangePct {
+		reasons = append(reasons, fmt.Sprintf("changed %+.2f%% (threshold %s%%)", *res.ChangePct, formatValue(*r.ChangePct)))
+	}
+
+	res.Triggered = len(reasons) > 0
+	res.Reason = strings.Join(reasons, "; ")
+	return res
+}
+
+// Link returns the Ahrefs overview page for target
+func Link(target string) string {
+	return "https://app.ahrefs.com/site-explorer/overview/v2/subdomains/live?target=" + url.QueryEscape(target)
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Observation is a metric value recorded by a previous run
+type Observation struct {
+	Value float64   `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+// State holds the last observed value of each target's metrics
+type State map[string]map[string]Observation
+
+// StatePath returns the path to the named state file
+func StatePath(name string) (string, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// LoadState reads the named state file; a missing file is an empty state
+func LoadState(name string) (State, error) {
+	path, err := StatePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert state: %w", err)
+	}
+
+	state := State{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse alert state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// Save writes the named state file
+func (s State) Save(name string) error {
+	path, err := StatePath(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write alert state: %w", err)
+	}
+	return nil
+}
+
+// Previous returns the last recorded value of target's metric, if any
+func (s State) Previous(target, metric string) *float64 {
+	obs, ok := s[target][metric]
+	if !ok {
+		return nil
+	}
+	return &obs.Value
+}
+
+// Set records the current value of target's metric
+func (s State) Set(target, metric string, value float64, at time.Time) {
+	if s[target] == nil {
+		s[target] = map[string]Observation{}
+	}
+	s[target][metric] = Observation{Value: value, Time: at}
+}
+
+// Notify posts triggered results to a webhook. The payload is a Slack
+// incoming-webhook message, with the results attached for other receivers.
+func Notify(ctx context.Context, webhook string, results []Result) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, ":rotating_light: %d Ahrefs alert(s)", len(results))
+	for _, r := range results {
+		fmt.Fprintf(&text, "\n• *%s* %s = %s", r.Target, r.Metric, formatValue(r.Current))
+		if r.Previous != nil {
+			fmt.Fprintf(&text, " (was %s)", formatValue(*r.Previous))
+		}
+		fmt.Fprintf(&text, ": %s <%s|View in Ahrefs>", r.Reason, r.Link)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"text":   text.String(),
+		"alerts": results,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
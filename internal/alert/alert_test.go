@@ -0,0 +1,149 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestRule_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		current  float64
+		previous *float64
+		want     bool
+		reason   string
+	}{
+		{"below triggers", Rule{Below: ptr(70)}, 68, nil, true, "below 70"},
+		{"below not reached", Rule{Below: ptr(70)}, 70, nil, false, ""},
+		{"above triggers", Rule{Above: ptr(1000)}, 1200, nil, true, "above 1000"},
+		{"change without previous run", Rule{ChangePct: ptr(10)}, 500, nil, false, ""},
+		{"change triggers on drop", Rule{ChangePct: ptr(10)}, 80, ptr(100), true, "changed -20.00% (threshold 10%)"},
+		{"change under threshold", Rule{ChangePct: ptr(10)}, 95, ptr(100), false, ""},
+		{"several thresholds", Rule{Below: ptr(90), ChangePct: ptr(5)}, 80, ptr(100), true, "below 90; changed -20.00% (threshold 5%)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rule.Check("example.com", "domain_rating", tt.current, tt.previous)
+			if got.Triggered != tt.want || got.Reason != tt.reason {
+				t.Errorf("Check() = %v %q, want %v %q", got.Triggered, got.Reason, tt.want, tt.reason)
+			}
+		})
+	}
+}
+
+func TestState(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	state, err := LoadState(StateFileName)
+	if err != nil {
+		t.Fatalf("LoadState(StateFileName) error = %v", err)
+	}
+	if state.Previous("example.com", "domain_rating") != nil {
+		t.Fatal("Previous() on empty state != nil")
+	}
+
+	state.Set("example.com", "domain_rating", 72, time.Now())
+	if err := state.Save(StateFileName); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadState(StateFileName)
+	if err != nil {
+		t.Fatalf("LoadState(StateFileName) error = %v", err)
+	}
+	if prev := loaded.Previous("example.com", "domain_rating"); prev == nil || *prev != 72 {
+		t.Errorf("Previous() = %v, want 72", prev)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("X-API-Units-Consumed", "10")
+		switch r.URL.Path {
+		case "/site-explorer/domain-rating":
+			io.WriteString(w, `{"domain_rating":{"domain_rating":91}}`)
+		case "/site-explorer/metrics":
+			io.WriteString(w, `{"metrics":{"org_traffic":672410}}`)
+		}
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(client.Config{APIKey: "test", BaseURL: srv.URL})
+	values, units, err := Fetch(context.Background(), c, "ahrefs.com", "domain", []string{"domain_rating", "org_traffic", "org_keywords"})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	// org_traffic and org_keywords share one request
+	if len(paths) != 2 {
+		t.Errorf("Fetch() made %d requests, want 2: %v", len(paths), paths)
+	}
+	want := map[string]float64{"domain_rating": 91, "org_traffic": 672410, "org_keywords": 0}
+	for k, v := range want {
+		if values[k] != v {
+			t.Errorf("values[%s] = %v, want %v", k, values[k], v)
+		}
+	}
+	if units["/site-explorer/metrics"] != 10 {
+		t.Errorf("units = %v, want 10 for /site-explorer/metrics", units)
+	}
+}
+
+func TestFetchSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-API-Units-Consumed", "5")
+		io.WriteString(w, `{"metrics":{"urls":1200}}`)
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(client.Config{APIKey: "test", BaseURL: srv.URL})
+	v, units, err := FetchSource(context.Background(), c, "ahrefs.com", "domain", Source{"/site-explorer/pages-stats", "urls"})
+	if err != nil || v != 1200 || units != 5 {
+		t.Errorf("FetchSource() = %v, %v, %v, want 1200, 5, nil", v, units, err)
+	}
+
+	if _, _, err := FetchSource(context.Background(), c, "ahrefs.com", "domain", Source{"/site-explorer/pages-stats", "typo"}); err == nil {
+		t.Error("FetchSource() with a missing field error = nil, want error")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var payload struct {
+		Text   string   `json:"text"`
+		Alerts []Result `json:"alerts"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer srv.Close()
+
+	res := Rule{Below: ptr(70)}.Check("example.com", "domain_rating", 68, ptr(72))
+	if err := Notify(context.Background(), srv.URL, []Result{res}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	for _, want := range []string{"example.com", "domain_rating = 68 (was 72)", "below 70", Link("example.com")} {
+		if !strings.Contains(payload.Text, want) {
+			t.Errorf("text = %q, want it to contain %q", payload.Text, want)
+		}
+	}
+	if len(payload.Alerts) != 1 || *payload.Alerts[0].Previous != 72 {
+		t.Errorf("alerts = %+v", payload.Alerts)
+	}
+}
@@ -0,0 +1,399 @@
+package bigquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/gcpauth"
+)
+
+// PartitionField is the TIMESTAMP column added to every row and used to
+// partition tables created by EnsureTable
+const PartitionField = "fetched_at"
+
+// batchSize is the number of rows sent per insertAll request
+const batchSize = 500
+
+// scope is the OAuth scope needed to create tables and insert rows
+const scope = "https://www.googleapis.com/auth/bigquery"
+
+// Table identifies a BigQuery table
+type Table struct {
+	Project string
+	Dataset string
+	Table   string
+}
+
+// IsURL reports whether s is a bq:// table URL
+func IsURL(s string) bool {
+	return strings.HasPrefix(s, "bq://")
+}
+
+// ParseURL parses bq://project/dataset/table
+func ParseURL(raw string) (Table, error) {
+	parts := strings.Split(strings.TrimPrefix(raw, "bq://"), "/")
+	if !IsURL(raw) || len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return Table{}, fmt.Errorf("invalid BigQuery URL %q, want bq://project/dataset/table", raw)
+	}
+	return Table{Project: parts[0], Dataset: parts[1], Table: parts[2]}, nil
+}
+
+func (t Table) String() string {
+	return t.Project + "." + t.Dataset + "." + t.Table
+}
+
+// Field is a column of a table schema, in the API's TableFieldSchema form
+type Field struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Mode   string  `json:"mode,omitempty"`
+	Fields []Field `json:"fields,omitempty"`
+}
+
+// RowType returns the row type of a response model, and the JSON key of its
+// list field. Single-object responses are one row and have no key; a lone
+// wrapper field, as in {"metrics": {...}}, is unwrapped.
+func RowType(response reflect.Type) (reflect.Type, string) {
+	for response.Kind() == reflect.Pointer {
+		response = response.Elem()
+	}
+	if response.Kind() != reflect.Struct {
+		return response, ""
+	}
+	for i := 0; i < response.NumField(); i++ {
+		f := response.Field(i)
+		if f.IsExported() && f.Type.Kind() == reflect.Slice {
+			return f.Type.Elem(), jsonName(f)
+		}
+	}
+	if response.NumField() == 1 && response.Field(0).Type.Kind() == reflect.Struct {
+		return response.Field(0).Type, ""
+	}
+	return response, ""
+}
+
+// SchemaOf derives a table schema from a row model, followed by the
+// PartitionField column. Every model column is NULLABLE: the API omits
+// metrics it has no value for, and those load as NULL rather than zero.
+func SchemaOf(row reflect.Type) ([]Field, error) {
+	fields, err := structFields(row)
+	if err != nil {
+		return nil, err
+	}
+	return append(fields, Field{Name: PartitionField, Type: "TIMESTAMP", Mode: "REQUIRED"}), nil
+}
+
+// structFields maps the JSON fields of a struct type, including those of
+// embedded structs, to columns
+func structFields(t reflect.Type) ([]Field, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't derive a schema from %s", t)
+	}
+
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			embedded, err := structFields(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+		name := jsonName(f)
+		if !f.IsExported() || name == "" {
+			continue
+		}
+		field, err := fieldOf(name, f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// fieldOf maps one Go type to a column
+func fieldOf(name string, t reflect.Type) (Field, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Field{Name: name, Type: "STRING", Mode: "NULLABLE"}, nil
+	case reflect.Bool:
+		return Field{Name: name, Type: "BOOL", Mode: "NULLABLE"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Field{Name: name, Type: "INT64", Mode: "NULLABLE"}, nil
+	case reflect.Float32, reflect.Float64:
+		return Field{Name: name, Type: "FLOAT64", Mode: "NULLABLE"}, nil
+	case reflect.Struct:
+		nested, err := structFields(t)
+		if err != nil {
+			return Field{}, err
+		}
+		return Field{Name: name, Type: "RECORD", Mode: "NULLABLE", Fields: nested}, nil
+	case reflect.Slice, reflect.Array:
+		elem, err := fieldOf(name, t.Elem())
+		if err != nil {
+			return Field{}, err
+		}
+		elem.Mode = "REPEATED"
+		return elem, nil
+	case reflect.Map, reflect.Interface:
+		return Field{Name: name, Type: "JSON", Mode: "NULLABLE"}, nil
+	}
+	return Field{}, fmt.Errorf("unsupported type %s", t)
+}
+
+// jsonName returns the JSON name of a struct field, or "" if it's skipped
+func jsonName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// Select narrows schema to columns, in order, keeping PartitionField
+func Select(schema []Field, columns []string) ([]Field, error) {
+	byName := map[string]Field{}
+	for _, f := range schema {
+		byName[f.Name] = f
+	}
+
+	selected := make([]Field, 0, len(columns)+1)
+	for _, c := range columns {
+		f, ok := byName[c]
+		if !ok {
+			return nil, fmt.Errorf("column %q isn't in the response model, so its BigQuery type is unknown", c)
+		}
+		selected = append(selected, f)
+	}
+	return append(selected, byName[PartitionField]), nil
+}
+
+// Rows extracts the rows of a response body, stamping each with
+// fetchedAt. Values are taken from the raw JSON, so fields the API omitted
+// stay absent (NULL) and numbers keep their exact representation.
+func Rows(body []byte, key string, fetchedAt time.Time) ([]map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var decoded map[string]interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	var raw []interface{}
+	if key == "" {
+		raw = []interface{}{unwrap(decoded)}
+	} else {
+		list, ok := decoded[key].([]interface{})
+		if !ok && decoded[key] != nil {
+			return nil, fmt.Errorf("response field %q is not a list", key)
+		}
+		raw = list
+	}
+
+	stamp := fetchedAt.UTC().Format(time.RFC3339Nano)
+	rows := make([]map[string]interface{}, 0, len(raw))
+	for _, r := range raw {
+		row, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("response row is not an object")
+		}
+		row[PartitionField] = stamp
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// unwrap returns the object inside a single-key wrapper such as
+// {"metrics": {...}}, matching RowType's view of single-object models
+func unwrap(v map[string]interface{}) map[string]interface{} {
+	if len(v) != 1 {
+		return v
+	}
+	for _, inner := range v {
+		if m, ok := inner.(map[string]interface{}); ok {
+			return m
+		}
+	}
+	return v
+}
+
+// Client creates tables and streams rows through the BigQuery REST API
+type Client struct {
+	endpoint string
+	token    *gcpauth.TokenSource // nil for an emulator
+	http     *http.Client
+}
+
+// NewClient creates a client from Google's application default
+// credentials, or for the emulator at BIGQUERY_EMULATOR_HOST without
+// authentication
+func NewClient(ctx context.Context) (*Client, error) {
+	c := &Client{endpoint: "https://bigquery.googleapis.com", http: &http.Client{Timeout: 2 * time.Minute}}
+
+	if host := os.Getenv("BIGQUERY_EMULATOR_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		c.endpoint = strings.TrimSuffix(host, "/")
+		return c, nil
+	}
+
+	ts, err := gcpauth.Default(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	c.token = ts
+	return c, nil
+}
+
+// EnsureTable creates t with schema, partitioned by day on PartitionField,
+// unless it already exists. It reports whether the table was created.
+func (c *Client) EnsureTable(ctx context.Context, t Table, schema []Field) (bool, error) {
+	path := fmt.Sprintf("/projects/%s/datasets/%s/tables", url.PathEscape(t.Project), url.PathEscape(t.Dataset))
+
+	status, err := c.do(ctx, http.MethodGet, path+"/"+url.PathEscape(t.Table), nil, nil)
+	if err == nil {
+		return false, nil
+	}
+	if status != http.StatusNotFound {
+		return false, err
+	}
+
+	table := map[string]interface{}{
+		"tableReference":   map[string]string{"projectId": t.Project, "datasetId": t.Dataset, "tableId": t.Table},
+		"schema":           map[string]interface{}{"fields": schema},
+		"timePartitioning": map[string]string{"type": "DAY", "field": PartitionField},
+	}
+	if _, err := c.do(ctx, http.MethodPost, path, table, nil); err != nil {
+		return false, fmt.Errorf("failed to create table %s: %w", t, err)
+	}
+	return true, nil
+}
+
+// Insert streams rows into t in batches. Rows carry insert IDs derived from
+// idPrefix, so a batch retried after a transient failure isn't duplicated.
+func (c *Client) Insert(ctx context.Context, t Table, rows []map[string]interface{}, idPrefix string) error {
+	path := fmt.Sprintf("/projects/%s/datasets/%s/tables/%s/insertAll",
+		url.PathEscape(t.Project), url.PathEscape(t.Dataset), url.PathEscape(t.Table))
+
+	for start := 0; start < len(rows); start += batchSize {
+		end := min(start+batchSize, len(rows))
+
+		type insertRow struct {
+			InsertID string                 `json:"insertId"`
+			JSON     map[string]interface{} `json:"json"`
+		}
+		req := struct {
+			IgnoreUnknownValues bool        `json:"ignoreUnknownValues"`
+			Rows                []insertRow `json:"rows"`
+		}{IgnoreUnknownValues: true}
+		for i := start; i < end; i++ {
+			req.Rows = append(req.Rows, insertRow{InsertID: idPrefix + "-" + strconv.Itoa(i), JSON: rows[i]})
+		}
+
+		var resp struct {
+			InsertErrors []struct {
+				Index  int `json:"index"`
+				Errors []struct {
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"errors"`
+			} `json:"insertErrors"`
+		}
+
+		status, err := c.do(ctx, http.MethodPost, path, req, &resp)
+		if err != nil && (status == 0 || status >= 500) {
+			status, err = c.do(ctx, http.MethodPost, path, req, &resp)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to insert rows into %s: %w", t, err)
+		}
+		if n := len(resp.InsertErrors); n > 0 {
+			first := resp.InsertErrors[0]
+			msg := "unknown error"
+			if len(first.Errors) > 0 {
+				msg = first.Errors[0].Reason + ": " + first.Errors[0].Message
+			}
+			return fmt.Errorf("%d row(s) rejected by %s; row %d: %s", n, t, start+first.Index, msg)
+		}
+	}
+	return nil
+}
+
+// do sends a JSON request to the API and decodes a JSON response into out.
+// It returns the HTTP status, or 0 if no response was received.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) (int, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+"/bigquery/v2"+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != nil {
+		token, err := c.token.Token(ctx)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Error.Message != "" {
+			return resp.StatusCode, fmt.Errorf("BigQuery returned %d: %s", resp.StatusCode, e.Error.Message)
+		}
+		return resp.StatusCode, fmt.Errorf("BigQuery returned %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to parse BigQuery response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,209 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestParseURL(t *testing.T) {
+	got, err := ParseURL("bq://my-project/seo/backlinks")
+	if err != nil {
+		t.Fatalf("ParseURL() error = %v", err)
+	}
+	if want := (Table{Project: "my-project", Dataset: "seo", Table: "backlinks"}); got != want {
+		t.Errorf("ParseURL() = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"bq://seo/backlinks", "bq://p//t", "s3://p/d/t", "bq://p/d/t/x"} {
+		if _, err := ParseURL(bad); err == nil {
+			t.Errorf("ParseURL(%q) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestRowType(t *testing.T) {
+	tests := []struct {
+		response interface{}
+		wantType reflect.Type
+		wantKey  string
+	}{
+		{&models.BacklinksResponse{}, reflect.TypeOf(models.Backlink{}), "backlinks"},
+		{&models.MetricsResponse{}, reflect.TypeOf(models.SiteMetrics{}), ""},
+		{&models.DomainRatingResponse{}, reflect.TypeOf(models.DomainRating{}), ""},
+	}
+
+	for _, tt := range tests {
+		gotType, gotKey := RowType(reflect.TypeOf(tt.response))
+		if gotType != tt.wantType || gotKey != tt.wantKey {
+			t.Errorf("RowType(%T) = %v, %q, want %v, %q", tt.response, gotType, gotKey, tt.wantType, tt.wantKey)
+		}
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	type nested struct {
+		Name string `json:"name"`
+	}
+	type row struct {
+		URL      string   `json:"url"`
+		Rank     int      `json:"rank,omitempty"`
+		Rating   float64  `json:"rating,omitempty"`
+		Traffic  *int64   `json:"traffic"`
+		Lost     bool     `json:"lost"`
+		Tags     []string `json:"tags"`
+		Owner    nested   `json:"owner"`
+		Internal string   `json:"-"`
+		hidden   string
+	}
+
+	got, err := SchemaOf(reflect.TypeOf(row{}))
+	if err != nil {
+		t.Fatalf("SchemaOf() error = %v", err)
+	}
+
+	want := []Field{
+		{Name: "url", Type: "STRING", Mode: "NULLABLE"},
+		{Name: "rank", Type: "INT64", Mode: "NULLABLE"},
+		{Name: "rating", Type: "FLOAT64", Mode: "NULLABLE"},
+		{Name: "traffic", Type: "INT64", Mode: "NULLABLE"},
+		{Name: "lost", Type: "BOOL", Mode: "NULLABLE"},
+		{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+		{Name: "owner", Type: "RECORD", Mode: "NULLABLE", Fields: []Field{{Name: "name", Type: "STRING", Mode: "NULLABLE"}}},
+		{Name: "fetched_at", Type: "TIMESTAMP", Mode: "REQUIRED"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SchemaOf() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	schema, _ := SchemaOf(reflect.TypeOf(models.Backlink{}))
+
+	got, err := Select(schema, []string{"url_to", "url_from"})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	var names []string
+	for _, f := range got {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "url_to,url_from,fetched_at" {
+		t.Errorf("Select() columns = %v, want [url_to url_from fetched_at]", names)
+	}
+
+	if _, err := Select(schema, []string{"not_a_column"}); err == nil {
+		t.Error("Select() with an unknown column error = nil, want error")
+	}
+}
+
+func TestRows(t *testing.T) {
+	fetchedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"backlinks":[{"url_from":"https://a.example/","domain_rating":0},{"url_from":"https://b.example/","traffic":12345678901234}]}`)
+
+	rows, err := Rows(body, "backlinks", fetchedAt)
+	if err != nil {
+		t.Fatalf("Rows() error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Rows() returned %d rows, want 2", len(rows))
+	}
+
+	// A reported zero is kept; an omitted metric stays absent, loading as NULL
+	if v, ok := rows[0]["domain_rating"]; !ok || v.(json.Number) != "0" {
+		t.Errorf("rows[0].domain_rating = %v, want 0", v)
+	}
+	if _, ok := rows[1]["domain_rating"]; ok {
+		t.Error("rows[1].domain_rating present, want absent")
+	}
+	if v := rows[1]["traffic"].(json.Number); v != "12345678901234" {
+		t.Errorf("rows[1].traffic = %v, want exact 12345678901234", v)
+	}
+	if rows[0][PartitionField] != "2025-06-01T12:00:00Z" {
+		t.Errorf("rows[0].fetched_at = %v", rows[0][PartitionField])
+	}
+
+	single, err := Rows([]byte(`{"domain_rating":{"domain_rating":91}}`), "", fetchedAt)
+	if err != nil {
+		t.Fatalf("Rows() single error = %v", err)
+	}
+	if len(single) != 1 || single[0]["domain_rating"].(json.Number) != "91" {
+		t.Errorf("Rows() single = %v", single)
+	}
+}
+
+func TestClient(t *testing.T) {
+	var created map[string]interface{}
+	var inserted struct {
+		Rows []struct {
+			InsertID string                 `json:"insertId"`
+			JSON     map[string]interface{} `json:"json"`
+		} `json:"rows"`
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const tables = "/bigquery/v2/projects/p/datasets/seo/tables"
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == tables+"/backlinks":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"Not found: Table p:seo.backlinks"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == tables:
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == tables+"/backlinks/insertAll":
+			json.NewDecoder(r.Body).Decode(&inserted)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("BIGQUERY_EMULATOR_HOST", srv.URL)
+	c, err := NewClient(context.Background())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	table := Table{Project: "p", Dataset: "seo", Table: "backlinks"}
+	schema, _ := SchemaOf(reflect.TypeOf(models.Backlink{}))
+
+	ok, err := c.EnsureTable(context.Background(), table, schema)
+	if err != nil || !ok {
+		t.Fatalf("EnsureTable() = %v, %v, want true, nil", ok, err)
+	}
+	partitioning, _ := created["timePartitioning"].(map[string]interface{})
+	if partitioning["field"] != PartitionField {
+		t.Errorf("created table partitioning = %v, want field %s", created["timePartitioning"], PartitionField)
+	}
+
+	rows := []map[string]interface{}{{"url_from": "https://a.example/"}, {"url_from": "https://b.example/"}}
+	if err := c.Insert(context.Background(), table, rows, "run1"); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if len(inserted.Rows) != 2 || inserted.Rows[1].InsertID != "run1-1" || inserted.Rows[1].JSON["url_from"] != "https://b.example/" {
+		t.Errorf("inserted = %+v", inserted.Rows)
+	}
+}
+
+func TestClient_InsertErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"insertErrors":[{"index":1,"errors":[{"reason":"invalid","message":"no such field"}]}]}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("BIGQUERY_EMULATOR_HOST", srv.URL)
+	c, _ := NewClient(context.Background())
+
+	err := c.Insert(context.Background(), Table{"p", "d", "t"}, []map[string]interface{}{{}, {}}, "run1")
+	if err == nil || !strings.Contains(err.Error(), "row 1: invalid: no such field") {
+		t.Errorf("Insert() error = %v, want rejected row 1", err)
+	}
+}
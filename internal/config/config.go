@@ -15,11 +15,76 @@ const (
 // Config represents the CLI configuration
 type Config struct {
 	APIKey string `json:"api_key"`
+
+	// Defaults maps flag names to default values, applied when neither the
+	// flag nor its environment variable is set
+	Defaults map[string]string `json:"defaults,omitempty"`
+
+	// Commands maps command paths below ahrefs, such as "site-explorer
+	// backlinks", to flag defaults for that command alone. They take
+	// precedence over Defaults and environment variables, so a command can
+	// keep its own --timeout or --max-retries.
+	Commands map[string]map[string]string `json:"commands,omitempty"`
+
+	// Budget configures local monthly unit budget tracking
+	Budget Budget `json:"budget,omitzero"`
+
+	// Presets maps command names to column presets for --preset, by name.
+	// They add to the built-in presets, replacing any of the same name.
+	Presets map[string]map[string][]string `json:"presets,omitempty"`
+
+	// Headers are sent with every API request, e.g. the X-Corp-Auth an
+	// enterprise proxy requires. ${NAME} in a value is replaced by the
+	// environment variable NAME, so secrets can stay out of the file.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// SensitiveHeaders names headers whose values are masked in verbose
+	// output and errors, besides those named like credentials
+	SensitiveHeaders []string `json:"sensitive_headers,omitempty"`
+
+	// Schedules are the jobs cron runs with ahrefs schedule run, by name
+	Schedules map[string]Schedule `json:"schedules,omitempty"`
+}
+
+// Schedule is a command run on a cron schedule
+type Schedule struct {
+	Cron string `json:"cron"`
+
+	// Args are the command's arguments below ahrefs, such as
+	// ["site-explorer", "backlinks", "--target", "example.com"]
+	Args []string `json:"args"`
+
+	// Webhook is sent the run's summary, as with --notify-webhook
+	Webhook string `json:"webhook,omitempty"`
+}
+
+// DefaultWarnPercent is the budget share at which a warning is printed when
+// budget.warn_percent is not set
+const DefaultWarnPercent = 80
+
+// Budget is an opt-in monthly API unit budget. Tracking is disabled while
+// MonthlyUnits is zero.
+type Budget struct {
+	MonthlyUnits int `json:"monthly_units,omitempty"`
+	WarnPercent  int `json:"warn_percent,omitempty"`
+}
+
+// Enabled reports whether a monthly budget is configured
+func (b Budget) Enabled() bool {
+	return b.MonthlyUnits > 0
+}
+
+// WarnAt returns the warning threshold as a percentage of the budget
+func (b Budget) WarnAt() int {
+	if b.WarnPercent <= 0 {
+		return DefaultWarnPercent
+	}
+	return b.WarnPercent
 }
 
 // Load loads the configuration from file
 func Load() (*Config, error) {
-	path, err := getConfigPath()
+	path, err := Path()
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +107,7 @@ func Load() (*Config, error) {
 
 // Save saves the configuration to file
 func Save(cfg *Config) error {
-	path, err := getConfigPath()
+	path, err := Path()
 	if err != nil {
 		return err
 	}
@@ -52,6 +117,9 @@ func Save(cfg *Config) error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
 	if err := os.WriteFile(path, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
@@ -59,8 +127,14 @@ func Save(cfg *Config) error {
 	return nil
 }
 
-// getConfigPath returns the path to the config file
-func getConfigPath() (string, error) {
+// Path returns the path to the config file: ~/.ahrefsrc, or on Windows
+// ahrefs-cli\config.json in the user's config directory
+func Path() (string, error) {
+	return defaultPath()
+}
+
+// homePath returns the path to the config file in the home directory
+func homePath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
@@ -69,6 +143,22 @@ func getConfigPath() (string, error) {
 	return filepath.Join(home, ConfigFileName), nil
 }
 
+// CacheDir returns the directory used for cached responses and other local
+// state, creating it if needed
+func CacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+
+	dir := filepath.Join(base, "ahrefs-cli")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	return dir, nil
+}
+
 // GetAPIKey gets the API key from config, env var, or returns empty string
 func GetAPIKey() string {
 	// First check env var
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package config
+
+// PermissionsApply reports whether the config file's mode bits control who
+// can read it
+const PermissionsApply = true
+
+// defaultPath returns ~/.ahrefsrc
+func defaultPath() (string, error) {
+	return homePath()
+}
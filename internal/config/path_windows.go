@@ -0,0 +1,35 @@
+//go:build windows
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PermissionsApply reports whether the config file's mode bits control who
+// can read it. Windows guards files with ACLs, which Go's file modes don't
+// reflect, so checking them is meaningless there.
+const PermissionsApply = false
+
+// defaultPath returns ahrefs-cli\config.json in %AppData%. A ~/.ahrefsrc written
+// by earlier versions is used instead while no such file exists, so a saved
+// key keeps working.
+func defaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	path := filepath.Join(dir, "ahrefs-cli", "config.json")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if legacy, err := homePath(); err == nil {
+		if _, err := os.Stat(legacy); err == nil {
+			return legacy, nil
+		}
+	}
+	return path, nil
+}
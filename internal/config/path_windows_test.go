@@ -0,0 +1,39 @@
+//go:build windows
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPath_Windows(t *testing.T) {
+	appData, home := t.TempDir(), t.TempDir()
+	t.Setenv("APPDATA", appData)
+	t.Setenv("USERPROFILE", home)
+
+	want := filepath.Join(appData, "ahrefs-cli", "config.json")
+	if got, err := Path(); err != nil || got != want {
+		t.Fatalf("Path() = %q, %v; want %q", got, err, want)
+	}
+
+	legacy := filepath.Join(home, ConfigFileName)
+	if err := os.WriteFile(legacy, []byte(`{"api_key":"legacy"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Path(); got != legacy {
+		t.Errorf("Path() = %q, want the existing %q", got, legacy)
+	}
+
+	if err := os.Remove(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(&Config{APIKey: "saved"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	cfg, err := Load()
+	if err != nil || cfg.APIKey != "saved" {
+		t.Errorf("Load() = %+v, %v; want the saved key from %s", cfg, err, want)
+	}
+}
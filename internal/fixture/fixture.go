@@ -0,0 +1,146 @@
+package fixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Fixture is a canned API exchange: a request to match and the response to
+// serve for it. Fixtures are stored as one JSON file each.
+type Fixture struct {
+	Name     string   `json:"-"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request identifies the requests a fixture answers
+type Request struct {
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path"`
+
+	// Query lists parameters the request must carry with exactly these
+	// values. Parameters not listed are ignored.
+	Query url.Values `json:"query,omitempty"`
+}
+
+// Response is the response served for a matching request
+type Response struct {
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// Load reads every *.json fixture in dir
+func Load(dir string) ([]Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	fixtures := make([]Fixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture: %w", err)
+		}
+
+		var f Fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+		}
+		if f.Request.Path == "" {
+			return nil, fmt.Errorf("fixture %s has no request path", path)
+		}
+		f.Name = filepath.Base(path)
+		fixtures = append(fixtures, f)
+	}
+
+	return fixtures, nil
+}
+
+// Matches reports whether the fixture answers a request for method, path,
+// and query
+func (f Fixture) Matches(method, path string, query url.Values) bool {
+	if f.Request.Method != "" && f.Request.Method != method {
+		return false
+	}
+	if f.Request.Path != path {
+		return false
+	}
+	for key, want := range f.Request.Query {
+		got := query[key]
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Find returns the fixture that matches the request with the most query
+// parameters, so specific fixtures take precedence over catch-alls
+func Find(fixtures []Fixture, method, path string, query url.Values) (Fixture, bool) {
+	var best Fixture
+	found := false
+	for _, f := range fixtures {
+		if !f.Matches(method, path, query) {
+			continue
+		}
+		if !found || len(f.Request.Query) > len(best.Request.Query) {
+			best, found = f, true
+		}
+	}
+	return best, found
+}
+
+// StatusCode returns the response status, defaulting to 200 OK
+func (r Response) StatusCode() int {
+	if r.Status == 0 {
+		return http.StatusOK
+	}
+	return r.Status
+}
+
+// Write writes the response to w, as JSON unless its headers say otherwise
+func (r Response) Write(w http.ResponseWriter) {
+	for k, v := range r.Headers {
+		w.Header().Set(k, v)
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(r.StatusCode())
+	w.Write(r.Body)
+}
+
+// Handler returns a handler answering each request with the fixture Find
+// returns for it, and unmatched requests with a 404
+func Handler(fixtures []Fixture) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := Find(fixtures, r.Method, r.URL.Path, r.URL.Query())
+		if !ok {
+			WriteError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("no fixture for %s %s", r.Method, r.URL.RequestURI()))
+			return
+		}
+		f.Response.Write(w)
+	})
+}
+
+// WriteError writes an error in the API's error envelope
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
@@ -0,0 +1,98 @@
+package fixture
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	fixtures := []Fixture{
+		{Name: "any", Request: Request{Path: "/site-explorer/domain-rating"}},
+		{Name: "target", Request: Request{Path: "/site-explorer/domain-rating", Query: url.Values{"target": {"ahrefs.com"}}}},
+		{Name: "post", Request: Request{Method: "POST", Path: "/site-explorer/backlinks"}},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		query  string
+		want   string
+		found  bool
+	}{
+		{name: "specific query wins", method: "GET", path: "/site-explorer/domain-rating", query: "target=ahrefs.com&date=2024-01-01", want: "target", found: true},
+		{name: "catch-all", method: "GET", path: "/site-explorer/domain-rating", query: "target=example.com", want: "any", found: true},
+		{name: "method mismatch", method: "GET", path: "/site-explorer/backlinks"},
+		{name: "unknown path", method: "GET", path: "/site-explorer/anchors"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			got, found := Find(fixtures, tt.method, tt.path, query)
+			if found != tt.found {
+				t.Fatalf("Find() found = %v, want %v", found, tt.found)
+			}
+			if got.Name != tt.want {
+				t.Errorf("Find() = %v, want %v", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("ok.json", `{"request":{"path":"/x"},"response":{"body":{"a":1}}}`)
+	write("notes.txt", "ignored")
+
+	fixtures, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(fixtures) != 1 || fixtures[0].Name != "ok.json" {
+		t.Fatalf("Load() = %+v, want only ok.json", fixtures)
+	}
+	if fixtures[0].Response.StatusCode() != 200 {
+		t.Errorf("StatusCode() = %v, want 200", fixtures[0].Response.StatusCode())
+	}
+
+	write("bad.json", `{"response":{}}`)
+	if _, err := Load(dir); err == nil {
+		t.Error("Load() should reject a fixture without a request path")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	fixtures := []Fixture{{
+		Request:  Request{Path: "/site-explorer/domain-rating"},
+		Response: Response{Headers: map[string]string{"X-API-Units-Consumed": "50"}, Body: json.RawMessage(`{"domain_rating":{"domain_rating":91}}`)},
+	}}
+	h := Handler(fixtures)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/site-explorer/domain-rating?target=ahrefs.com", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"domain_rating":{"domain_rating":91}}` {
+		t.Errorf("matched: %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "application/json" || rec.Header().Get("X-API-Units-Consumed") != "50" {
+		t.Errorf("headers = %v, want JSON and the fixture's", rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/site-explorer/anchors", nil))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "no fixture for GET /site-explorer/anchors") {
+		t.Errorf("unmatched: %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,59 @@
+// Package golden compares the output of tests with golden files holding the
+// output expected, byte for byte. Run the tests with -update to rewrite the
+// golden files after an intended change, and review the diff.
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the output of the tests")
+
+// Assert fails t unless got is the content of the golden file at path. With
+// -update, it writes got to path instead.
+func Assert(t testing.TB, path string, got []byte) {
+	t.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run go test -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run go test -update to accept it)\n%s", path, firstDiff(want, got))
+	}
+}
+
+// firstDiff describes the first line where got differs from want
+func firstDiff(want, got []byte) string {
+	wantLines := strings.SplitAfter(string(want), "\n")
+	gotLines := strings.SplitAfter(string(got), "\n")
+	for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want %q\n  got  %q", i+1, w, g)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,252 @@
+package gcpauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// metadataTimeout bounds probes of the metadata server, which only answers
+// on Google Cloud
+const metadataTimeout = 2 * time.Second
+
+// httpClient is used for token requests
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// TokenSource fetches and caches OAuth access tokens
+type TokenSource struct {
+	fetch   func(ctx context.Context) (*http.Request, error)
+	timeout time.Duration // bounds each fetch when set
+	token   string
+	expires time.Time
+}
+
+// Token returns a cached access token, fetching a new one shortly before
+// expiry
+func (ts *TokenSource) Token(ctx context.Context) (string, error) {
+	if ts.token != "" && time.Until(ts.expires) > time.Minute {
+		return ts.token, nil
+	}
+
+	if ts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ts.timeout)
+		defer cancel()
+	}
+	req, err := ts.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := getJSON(req, &tok); err != nil {
+		return "", fmt.Errorf("failed to fetch Google access token: %w", err)
+	}
+	ts.token = tok.AccessToken
+	ts.expires = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return ts.token, nil
+}
+
+// googleCredentials is an application default credentials file
+type googleCredentials struct {
+	Type string `json:"type"`
+
+	// service_account
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+
+	// authorized_user
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Default returns a token source for scope from Google's application default
+// credentials: GOOGLE_APPLICATION_CREDENTIALS, the gcloud ADC file, then the
+// metadata server. A first token is fetched so broken setups fail early.
+func Default(ctx context.Context, scope string) (*TokenSource, error) {
+	ts, err := defaultTokenSource(scope)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ts.Token(ctx); err != nil {
+		return nil, fmt.Errorf("%w (set GOOGLE_APPLICATION_CREDENTIALS or run 'gcloud auth application-default login')", err)
+	}
+	return ts, nil
+}
+
+func defaultTokenSource(scope string) (*TokenSource, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		path = gcloudADCPath()
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var creds googleCredentials
+		if err := json.Unmarshal(data, &creds); err != nil {
+			return nil, fmt.Errorf("failed to parse Google credentials %s: %w", path, err)
+		}
+		switch creds.Type {
+		case "service_account":
+			return serviceAccountTokenSource(creds, scope)
+		case "authorized_user":
+			return authorizedUserTokenSource(creds), nil
+		default:
+			return nil, fmt.Errorf("unsupported Google credentials type %q in %s", creds.Type, path)
+		}
+	}
+	if os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" {
+		return nil, fmt.Errorf("failed to read Google credentials: %w", err)
+	}
+
+	return metadataTokenSource(), nil
+}
+
+// gcloudADCPath returns where 'gcloud auth application-default login'
+// stores credentials
+func gcloudADCPath() string {
+	dir := os.Getenv("CLOUDSDK_CONFIG")
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			dir = filepath.Join(os.Getenv("APPDATA"), "gcloud")
+		} else {
+			home, _ := os.UserHomeDir()
+			dir = filepath.Join(home, ".config", "gcloud")
+		}
+	}
+	return filepath.Join(dir, "application_default_credentials.json")
+}
+
+// serviceAccountTokenSource exchanges a signed JWT for access tokens
+func serviceAccountTokenSource(creds googleCredentials, scope string) (*TokenSource, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return nil, errors.New("service account credentials have no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account key is not an RSA key")
+	}
+	tokenURI := creds.TokenURI
+	if tokenURI == "" {
+		tokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	return &TokenSource{fetch: func(ctx context.Context) (*http.Request, error) {
+		assertion, err := signJWT(key, map[string]interface{}{
+			"iss":   creds.ClientEmail,
+			"scope": scope,
+			"aud":   tokenURI,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return formRequest(ctx, tokenURI, url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		})
+	}}, nil
+}
+
+// authorizedUserTokenSource redeems a gcloud user's refresh token
+func authorizedUserTokenSource(creds googleCredentials) *TokenSource {
+	return &TokenSource{fetch: func(ctx context.Context) (*http.Request, error) {
+		return formRequest(ctx, "https://oauth2.googleapis.com/token", url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		})
+	}}
+}
+
+// metadataTokenSource asks the GCE metadata server for the instance's
+// service account token
+func metadataTokenSource() *TokenSource {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+
+	return &TokenSource{timeout: metadataTimeout, fetch: func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			"http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		return req, nil
+	}}
+}
+
+// formRequest builds a form-encoded POST
+func formRequest(ctx context.Context, endpoint string, form url.Values) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// signJWT returns an RS256-signed JWT with claims
+func signJWT(key *rsa.PrivateKey, claims map[string]interface{}) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// getJSON sends req and decodes a 2xx JSON response into v
+func getJSON(req *http.Request, v interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", req.URL.Host, resp.StatusCode)
+	}
+	return json.Unmarshal(body, v)
+}
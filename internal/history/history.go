@@ -0,0 +1,201 @@
+// Package history keeps the latest successful response of each command, so
+// it can be written again without an API call, and the bodies of responses
+// that failed to decode, so they can be decoded again.
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+// DirName is the directory of saved responses inside the cache directory
+const DirName = "history"
+
+// UndecodedDirName is the directory of response bodies that failed to
+// decode inside the cache directory
+const UndecodedDirName = "undecoded"
+
+// ErrNotFound is returned by Load when no response is saved for a key
+var ErrNotFound = errors.New("no saved response")
+
+// Entry is the latest successful response of a command
+type Entry struct {
+	// Key identifies the command and the flags its request was made with
+	Key     string              `json:"key"`
+	Command string              `json:"command"`
+	SavedAt time.Time           `json:"saved_at"`
+	Meta    client.ResponseMeta `json:"meta"`
+	Body    json.RawMessage     `json:"body"`
+
+	// Validators are the caching headers of a response from a single
+	// request, for asking the API whether it has changed
+	Validators client.Validators `json:"validators"`
+}
+
+// Info describes a saved entry without its response
+type Info struct {
+	Key     string    `json:"key"`
+	Command string    `json:"command"`
+	SavedAt time.Time `json:"saved_at"`
+	Size    int64     `json:"size_bytes"`
+	Path    string    `json:"path"`
+}
+
+// Dir returns the directory of saved responses
+func Dir() (string, error) {
+	cache, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cache, DirName), nil
+}
+
+// path returns the file holding the entry for key
+func path(key string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// Save replaces the entry for e.Key
+func Save(e Entry) error {
+	p, err := path(e.Key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	// Write a temporary file and rename it so a reader never sees half
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to save response: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save response: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save response: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("failed to save response: %w", err)
+	}
+	return nil
+}
+
+// Load returns the entry saved for key, or ErrNotFound
+func Load(key string) (*Entry, error) {
+	p, err := path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved response: %w", err)
+	}
+
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse saved response %s: %w", p, err)
+	}
+	// Entries are named by a prefix of the key's hash, so check it's this one
+	if e.Key != key {
+		return nil, ErrNotFound
+	}
+	return &e, nil
+}
+
+// List returns every saved entry, newest first
+func List() ([]Info, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var infos []Info
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		p := filepath.Join(dir, f.Name())
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var e struct {
+			Key     string    `json:"key"`
+			Command string    `json:"command"`
+			SavedAt time.Time `json:"saved_at"`
+		}
+		if err := json.Unmarshal(data, &e); err != nil {
+			// Skip files that aren't entries
+			continue
+		}
+		infos = append(infos, Info{Key: e.Key, Command: e.Command, SavedAt: e.SavedAt, Size: int64(len(data)), Path: p})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].SavedAt.After(infos[j].SavedAt)
+	})
+	return infos, nil
+}
+
+// SaveUndecoded writes body, a response of command that failed to decode,
+// to a new file in the undecoded directory, and returns its path
+func SaveUndecoded(command string, body []byte, at time.Time) (string, error) {
+	cache, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cache, UndecodedDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create undecoded directory: %w", err)
+	}
+
+	name := strings.ReplaceAll(command, " ", "-") + "-" + at.UTC().Format("20060102T150405Z")
+	f, err := os.CreateTemp(dir, name+"-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to save response: %w", err)
+	}
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to save response: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to save response: %w", err)
+	}
+	return f.Name(), nil
+}
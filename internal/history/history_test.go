@@ -0,0 +1,104 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+func TestSaveLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	if _, err := Load("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load() before Save error = %v, want ErrNotFound", err)
+	}
+
+	saved := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := Entry{Key: "a", Command: "ahrefs x", SavedAt: saved, Meta: client.ResponseMeta{UnitsConsumed: 5}, Body: []byte(`{"n":1}`)}
+	if err := Save(e); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	e.Body = []byte(`{"n":2}`)
+	if err := Save(e); err != nil {
+		t.Fatalf("Save() again error = %v", err)
+	}
+
+	got, err := Load("a")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if string(got.Body) != `{"n":2}` || !got.SavedAt.Equal(saved) || got.Meta.UnitsConsumed != 5 {
+		t.Errorf("Load() = %+v, want the last entry saved", got)
+	}
+	if _, err := Load("b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load(other key) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	infos, err := List()
+	if err != nil || len(infos) != 0 {
+		t.Fatalf("List() of no history = %v, %v", infos, err)
+	}
+
+	now := time.Now()
+	for i, key := range []string{"old", "new", "mid"} {
+		at := map[int]time.Duration{0: -time.Hour, 1: 0, 2: -time.Minute}[i]
+		if err := Save(Entry{Key: key, SavedAt: now.Add(at), Body: []byte(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	infos, err = List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var keys []string
+	for _, info := range infos {
+		keys = append(keys, info.Key)
+		if info.Size == 0 || info.Path == "" {
+			t.Errorf("List() entry %+v is missing its size or path", info)
+		}
+	}
+	if len(keys) != 3 || keys[0] != "new" || keys[1] != "mid" || keys[2] != "old" {
+		t.Errorf("List() keys = %v, want newest first", keys)
+	}
+}
+
+func TestSaveUndecoded(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	first, err := SaveUndecoded("backlinks", []byte(`{"backlinks":[{"url_from":`), at)
+	if err != nil {
+		t.Fatalf("SaveUndecoded() error = %v", err)
+	}
+	second, err := SaveUndecoded("backlinks", []byte(`{}`), at)
+	if err != nil {
+		t.Fatalf("SaveUndecoded() again error = %v", err)
+	}
+	if first == second {
+		t.Errorf("SaveUndecoded() twice = %s, want a file each", first)
+	}
+	if base := filepath.Base(first); !strings.HasPrefix(base, "backlinks-20240102T030405Z-") || filepath.Base(filepath.Dir(first)) != UndecodedDirName {
+		t.Errorf("SaveUndecoded() = %s, want a backlinks file in %s", first, UndecodedDirName)
+	}
+	if data, err := os.ReadFile(first); err != nil || string(data) != `{"backlinks":[{"url_from":` {
+		t.Errorf("saved body = %q, %v, want the body as given", data, err)
+	}
+
+	// Undecoded bodies aren't entries for --last
+	if infos, err := List(); err != nil || len(infos) != 0 {
+		t.Errorf("List() = %v, %v, want no entries", infos, err)
+	}
+}
@@ -0,0 +1,267 @@
+// Package jobs records the progress of multi-page exports, so an export
+// interrupted part way can continue from its last completed page instead of
+// spending units on the pages already fetched.
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+)
+
+// DirName is the directory of export states inside the cache directory
+const DirName = "cursors"
+
+// ErrParamsChanged is returned by Load when the state found was saved for
+// other parameters
+var ErrParamsChanged = errors.New("saved export is for other parameters")
+
+// State is the progress of an export after its last completed page
+type State struct {
+	Endpoint string `json:"endpoint"`
+
+	// Params are the parameters selecting the rows exported, and
+	// ParamsHash identifies them with the endpoint
+	Params     string `json:"params"`
+	ParamsHash string `json:"params_hash"`
+
+	// Cursor or Offset is the position of the next page
+	Cursor string `json:"cursor,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+
+	Pages int `json:"pages"`
+	Rows  int `json:"rows"`
+	Units int `json:"units"`
+
+	// Partial holds the rows fetched so far, one JSON row per line, of
+	// which the first PartialSize bytes are complete
+	Partial     string `json:"partial"`
+	PartialSize int64  `json:"partial_size"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Job is a saved export, as listed
+type Job struct {
+	State
+	Path string `json:"path"`
+}
+
+// Dir returns the directory of export states
+func Dir() (string, error) {
+	cache, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cache, DirName), nil
+}
+
+// Hash identifies an export by its endpoint and the parameters selecting
+// its rows; paging parameters are left out
+func Hash(endpoint string, params url.Values) string {
+	sum := sha256.Sum256([]byte(endpoint + "?" + key(params).Encode()))
+	return hex.EncodeToString(sum[:])
+}
+
+// key returns params without the paging parameters
+func key(params url.Values) url.Values {
+	k := make(url.Values, len(params))
+	for name, values := range params {
+		if name != "cursor" && name != "offset" {
+			k[name] = values
+		}
+	}
+	return k
+}
+
+// Path returns the state file of an export
+func Path(endpoint string, params url.Values) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, Hash(endpoint, params)[:16]+".json"), nil
+}
+
+// New returns the state of an export that has fetched nothing yet, with its
+// rows kept next to the state file at path
+func New(path, endpoint string, params url.Values) State {
+	return State{
+		Endpoint:   endpoint,
+		Params:     key(params).Encode(),
+		ParamsHash: Hash(endpoint, params),
+		Partial:    strings.TrimSuffix(path, ".json") + ".rows",
+	}
+}
+
+// Load returns the state saved at path for an export of endpoint with
+// params, or nil if there is none
+func Load(path, endpoint string, params url.Values) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export state: %w", err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse export state %s: %w", path, err)
+	}
+	if s.ParamsHash == "" {
+		// Saved by an earlier version, without the rows fetched
+		return nil, nil
+	}
+	if s.ParamsHash != Hash(endpoint, params) {
+		return nil, fmt.Errorf("%s: %w", path, ErrParamsChanged)
+	}
+	return &s, nil
+}
+
+// Save records s at path
+func Save(path string, s State) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create export state directory: %w", err)
+	}
+	s.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	// Write a temporary file and rename it so an interruption never leaves
+	// half a state
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write export state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write export state: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the state at path and its rows
+func Remove(path string, s State) error {
+	for _, p := range []string{s.Partial, path} {
+		if p == "" {
+			continue
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove export state: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReadRows returns the rows of s fetched so far, dropping any written after
+// its last completed page
+func ReadRows(s State) ([]json.RawMessage, error) {
+	if s.Rows == 0 {
+		return nil, nil
+	}
+	f, err := os.Open(s.Partial)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exported rows: %w", err)
+	}
+	defer f.Close()
+
+	rows := make([]json.RawMessage, 0, s.Rows)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for len(rows) < s.Rows && scanner.Scan() {
+		rows = append(rows, json.RawMessage(append([]byte(nil), scanner.Bytes()...)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read exported rows: %w", err)
+	}
+	if len(rows) < s.Rows {
+		return nil, fmt.Errorf("%s has %d of the %d rows exported", s.Partial, len(rows), s.Rows)
+	}
+	return rows, nil
+}
+
+// AppendRows adds the rows of a completed page to those of s
+func AppendRows(s *State, rows []json.RawMessage) error {
+	// A row per line, so rows are compacted
+	var buf bytes.Buffer
+	for _, row := range rows {
+		if err := json.Compact(&buf, row); err != nil {
+			return fmt.Errorf("failed to write exported rows: %w", err)
+		}
+		buf.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Partial), 0700); err != nil {
+		return fmt.Errorf("failed to create export state directory: %w", err)
+	}
+	f, err := os.OpenFile(s.Partial, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write exported rows: %w", err)
+	}
+	// Anything past the last completed page, such as the rows of a page
+	// whose state wasn't saved, is overwritten
+	_, err = f.WriteAt(buf.Bytes(), s.PartialSize)
+	if err == nil {
+		err = f.Truncate(s.PartialSize + int64(buf.Len()))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write exported rows: %w", err)
+	}
+
+	s.Rows += len(rows)
+	s.PartialSize += int64(buf.Len())
+	return nil
+}
+
+// List returns the saved exports, most recently updated first
+func List() ([]Job, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export state directory: %w", err)
+	}
+
+	var list []Job
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		p := filepath.Join(dir, f.Name())
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var s State
+		if err := json.Unmarshal(data, &s); err != nil {
+			continue
+		}
+		list = append(list, Job{State: s, Path: p})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].UpdatedAt.After(list[j].UpdatedAt)
+	})
+	return list, nil
+}
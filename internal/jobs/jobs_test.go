@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	params := url.Values{"target": {"example.com"}, "limit": {"10"}}
+
+	path, err := Path("/site-explorer/anchors", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := Load(path, "/site-explorer/anchors", params); s != nil || err != nil {
+		t.Fatalf("Load() before Save = %v, %v; want nil", s, err)
+	}
+
+	s := New(path, "/site-explorer/anchors", params)
+	if err := AppendRows(&s, []json.RawMessage{[]byte(`{"n": 0}`), []byte("{\n\"n\": 1}")}); err != nil {
+		t.Fatalf("AppendRows() error = %v", err)
+	}
+	s.Cursor = "c1"
+	if err := Save(path, s); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Paging parameters don't change the export
+	paged := url.Values{"target": {"example.com"}, "limit": {"10"}, "cursor": {"c1"}}
+	got, err := Load(path, "/site-explorer/anchors", paged)
+	if err != nil || got == nil {
+		t.Fatalf("Load() = %v, %v", got, err)
+	}
+	if got.Cursor != "c1" || got.Rows != 2 || got.UpdatedAt.IsZero() {
+		t.Errorf("Load() = %+v, want the state saved", got)
+	}
+
+	other := url.Values{"target": {"other.com"}, "limit": {"10"}}
+	if _, err := Load(path, "/site-explorer/anchors", other); !errors.Is(err, ErrParamsChanged) {
+		t.Errorf("Load() for other params error = %v, want ErrParamsChanged", err)
+	}
+
+	if list, err := List(); err != nil || len(list) != 1 || list[0].Path != path {
+		t.Errorf("List() = %v, %v; want the export", list, err)
+	}
+
+	if err := Remove(path, *got); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	for _, p := range []string{path, got.Partial} {
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s after Remove: %v, want it gone", p, err)
+		}
+	}
+}
+
+func TestRows_LastCompletedPage(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	params := url.Values{"target": {"example.com"}}
+	path, _ := Path("/site-explorer/anchors", params)
+
+	s := New(path, "/site-explorer/anchors", params)
+	if err := AppendRows(&s, []json.RawMessage{[]byte(`{"n":0}`), []byte(`{"n":1}`)}); err != nil {
+		t.Fatal(err)
+	}
+	saved := s
+
+	// Rows of a page whose state wasn't saved are dropped
+	if err := AppendRows(&s, []json.RawMessage{[]byte(`{"n":2}`)}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := ReadRows(saved)
+	if err != nil || len(rows) != 2 || string(rows[1]) != `{"n":1}` {
+		t.Fatalf("ReadRows() = %s, %v; want the first two rows", rows, err)
+	}
+
+	// and replaced by the next page written after it
+	if err := AppendRows(&saved, []json.RawMessage{[]byte(`{"n":3}`)}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(saved.Partial)
+	if want := "{\"n\":0}\n{\"n\":1}\n{\"n\":3}\n"; string(data) != want {
+		t.Errorf("rows file = %q, want %q", data, want)
+	}
+}
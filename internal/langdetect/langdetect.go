@@ -0,0 +1,209 @@
+// Package langdetect guesses the language of short texts, such as anchor
+// texts, without network calls. Languages written in a script of their own
+// are told apart by script, and the others by how likely the character
+// trigrams of the text are in a sample text embedded for each language, so
+// a text is given the same language on every run.
+package langdetect
+
+import (
+	"embed"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Undetermined is the ISO 639 code of texts whose language isn't detected:
+// those with fewer than MinLetters letters, or only letters of scripts
+// without a language here
+const Undetermined = "und"
+
+// MinLetters is the fewest letters a text needs for its language to be
+// detected
+const MinLetters = 3
+
+// Result is the language detected in a text, as an ISO 639-1 code, and the
+// confidence of the detection, from 0 to 1
+type Result struct {
+	Language   string
+	Confidence float64
+}
+
+//go:embed corpus/*.txt
+var corpora embed.FS
+
+// scripts are the scripts told apart, in the order ties are broken in
+var scripts = []struct {
+	name  string
+	table *unicode.RangeTable
+	lang  string // the script's language, or "" to compare trigrams
+}{
+	{"Latin", unicode.Latin, ""},
+	{"Cyrillic", unicode.Cyrillic, ""},
+	{"Greek", unicode.Greek, "el"},
+	{"Arabic", unicode.Arabic, "ar"},
+	{"Hebrew", unicode.Hebrew, "he"},
+	{"Hangul", unicode.Hangul, "ko"},
+	{"Hiragana", unicode.Hiragana, "ja"},
+	{"Katakana", unicode.Katakana, "ja"},
+	{"Han", unicode.Han, "zh"},
+	{"Thai", unicode.Thai, "th"},
+	{"Devanagari", unicode.Devanagari, "hi"},
+}
+
+// profile holds the trigram frequencies of a language's sample text
+type profile struct {
+	lang   string
+	script string
+	counts map[string]int
+	total  int
+}
+
+// profiles are the trigram profiles by script, built from the embedded
+// samples on first use
+var profiles = sync.OnceValue(func() map[string][]profile {
+	entries, err := corpora.ReadDir("corpus")
+	if err != nil {
+		panic(err)
+	}
+	byScript := map[string][]profile{}
+	for _, entry := range entries {
+		text, err := corpora.ReadFile(path.Join("corpus", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		p := profile{
+			lang:   strings.TrimSuffix(entry.Name(), ".txt"),
+			script: dominantScript(string(text)),
+			counts: map[string]int{},
+		}
+		for _, t := range trigrams(string(text)) {
+			p.counts[t]++
+			p.total++
+		}
+		byScript[p.script] = append(byScript[p.script], p)
+	}
+	for _, ps := range byScript {
+		sort.Slice(ps, func(i, j int) bool { return ps[i].lang < ps[j].lang })
+	}
+	return byScript
+})
+
+// Detect returns the language of text
+func Detect(text string) Result {
+	counts, letters := scriptCounts(text)
+	if letters < MinLetters {
+		return Result{Language: Undetermined}
+	}
+
+	// Japanese mixes kanji with kana, so Han text with any kana is Japanese
+	if kana := counts["Hiragana"] + counts["Katakana"]; kana > 0 {
+		counts["Hiragana"] += counts["Katakana"] + counts["Han"]
+		counts["Katakana"], counts["Han"] = 0, 0
+	}
+	s, n := scripts[0], 0
+	for _, script := range scripts {
+		if counts[script.name] > n {
+			s, n = script, counts[script.name]
+		}
+	}
+	if n == 0 {
+		return Result{Language: Undetermined}
+	}
+	share := float64(n) / float64(letters)
+	if s.lang != "" {
+		return Result{Language: s.lang, Confidence: round(share)}
+	}
+
+	candidates := profiles()[s.name]
+	if len(candidates) == 0 {
+		return Result{Language: Undetermined}
+	}
+	lang, p := mostLikely(candidates, trigrams(text))
+	return Result{Language: lang, Confidence: round(p * share)}
+}
+
+// mostLikely returns the language whose profile makes grams likeliest, and
+// its probability among the candidates, taken as equally likely beforehand
+func mostLikely(candidates []profile, grams []string) (string, float64) {
+	// Trigrams a sample lacks are smoothed over every trigram seen
+	vocabulary := map[string]bool{}
+	for _, p := range candidates {
+		for t := range p.counts {
+			vocabulary[t] = true
+		}
+	}
+
+	scores := make([]float64, len(candidates))
+	best := 0
+	for i, p := range candidates {
+		denom := math.Log(float64(p.total + len(vocabulary) + 1))
+		for _, t := range grams {
+			scores[i] += math.Log(float64(p.counts[t]+1)) - denom
+		}
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+
+	sum := 0.0
+	for _, s := range scores {
+		sum += math.Exp(s - scores[best])
+	}
+	return candidates[best].lang, 1 / sum
+}
+
+// trigrams returns the character trigrams of the words of text, lowercased
+// and padded with a space at either end
+func trigrams(text string) []string {
+	var grams []string
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		runes := []rune(" " + strings.Trim(word, "'") + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			grams = append(grams, string(runes[i:i+3]))
+		}
+	}
+	return grams
+}
+
+// scriptCounts returns how many of the letters of text are in each script,
+// by name, and how many letters it has
+func scriptCounts(text string) (map[string]int, int) {
+	counts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.name]++
+				break
+			}
+		}
+	}
+	return counts, letters
+}
+
+// dominantScript returns the name of the script most letters of text are in
+func dominantScript(text string) string {
+	counts, _ := scriptCounts(text)
+	best := scripts[0].name
+	for _, s := range scripts {
+		if counts[s.name] > counts[best] {
+			best = s.name
+		}
+	}
+	return best
+}
+
+// round rounds a confidence to two decimals
+func round(p float64) float64 {
+	return math.Round(p*100) / 100
+}
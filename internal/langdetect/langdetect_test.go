@@ -0,0 +1,77 @@
+package langdetect
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"the best guide to link building", "en"},
+		{"read more", "en"},
+		{"backlink checker", "en"},
+		{"Anleitung für Anfänger", "de"},
+		{"mehr erfahren", "de"},
+		{"guide du référencement", "fr"},
+		{"en savoir plus", "fr"},
+		{"guía de posicionamiento web", "es"},
+		{"herramientas gratis", "es"},
+		{"scopri di più", "it"},
+		{"strumenti gratuiti", "it"},
+		{"ferramentas grátis", "pt"},
+		{"lees meer", "nl"},
+		{"gratis hulpmiddelen", "nl"},
+		{"czytaj więcej", "pl"},
+		{"darmowe narzędzia", "pl"},
+		{"läs mer", "sv"},
+		{"бесплатные инструменты", "ru"},
+		{"безкоштовні інструменти", "uk"},
+		{"日本語のテキスト", "ja"},
+		{"中文网站", "zh"},
+		{"한국어 검색", "ko"},
+		{"Ελληνικά", "el"},
+		{"עברית", "he"},
+		{"العربية", "ar"},
+		{"ภาษาไทย", "th"},
+		{"हिन्दी", "hi"},
+	}
+	for _, tt := range tests {
+		got := Detect(tt.text)
+		if got.Language != tt.want {
+			t.Errorf("Detect(%q) = %+v, want %s", tt.text, got, tt.want)
+		}
+		if got.Confidence <= 0 || got.Confidence > 1 {
+			t.Errorf("Detect(%q) confidence = %v, want within (0, 1]", tt.text, got.Confidence)
+		}
+	}
+}
+
+func TestDetect_Undetermined(t *testing.T) {
+	for _, text := range []string{"", "  ", "ok", "12345", "→ ★", "a b", "ա բ գ"} {
+		if got := Detect(text); got != (Result{Language: Undetermined}) {
+			t.Errorf("Detect(%q) = %+v, want und", text, got)
+		}
+	}
+}
+
+func TestDetect_Deterministic(t *testing.T) {
+	for _, text := range []string{"click here", "SEO", "gratis verktyg", "ahrefs"} {
+		first := Detect(text)
+		for i := 0; i < 20; i++ {
+			if got := Detect(text); got != first {
+				t.Fatalf("Detect(%q) = %+v, then %+v", text, first, got)
+			}
+		}
+	}
+}
+
+func TestDetect_MixedScripts(t *testing.T) {
+	// Latin letters lower the confidence of text mostly in Cyrillic
+	pure, mixed := Detect("подробнее о сайте"), Detect("подробнее о сайте SEO")
+	if pure.Language != "ru" || mixed.Language != "ru" {
+		t.Fatalf("Detect() = %+v, %+v, want ru", pure, mixed)
+	}
+	if mixed.Confidence >= pure.Confidence {
+		t.Errorf("confidence with Latin letters = %v, want below %v", mixed.Confidence, pure.Confidence)
+	}
+}
@@ -0,0 +1,150 @@
+// Package logging builds the leveled loggers diagnostics are written
+// through: lines for people by default, or JSON objects for log pipelines.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelTrace is below debug, for what -vv adds on top of -v
+const LevelTrace = slog.LevelDebug - 4
+
+// Formats of log output
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Formats lists the accepted --log-format values
+var Formats = []string{FormatText, FormatJSON}
+
+// Level returns the minimum level logged for a --verbose count and --quiet:
+// errors only when quiet, debug at -v, trace at -vv, and info otherwise
+func Level(verbosity int, quiet bool) slog.Level {
+	switch {
+	case quiet:
+		return slog.LevelError
+	case verbosity >= 2:
+		return LevelTrace
+	case verbosity == 1:
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// New returns a logger writing records of level and above to w in format.
+// Unknown formats are written as text.
+func New(w io.Writer, format string, level slog.Level) *slog.Logger {
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level:       level,
+			ReplaceAttr: replaceLevel,
+		}))
+	}
+	return slog.New(&textHandler{mu: &sync.Mutex{}, w: w, level: level})
+}
+
+// Discard returns a logger writing nothing
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// replaceLevel names LevelTrace in JSON records, which slog would write as
+// DEBUG-4
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level <= LevelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
+	}
+	return a
+}
+
+// textHandler writes a record as its message followed by its own attributes,
+// warnings and errors prefixed as such. An "err" attribute is written after
+// the message as "msg: err". Attributes added with With, such as the command
+// and endpoint, are left out to keep lines short; JSON output has them.
+type textHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Level
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	switch {
+	case r.Level >= slog.LevelError:
+		buf.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		buf.WriteString("Warning: ")
+	}
+	buf.WriteString(r.Message)
+
+	var attrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "err" {
+			fmt.Fprintf(&buf, ": %s", a.Value.Resolve())
+		} else {
+			attrs = append(attrs, a)
+		}
+		return true
+	})
+	for _, a := range attrs {
+		appendAttr(&buf, "", a)
+	}
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+func (h *textHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *textHandler) WithGroup(string) slog.Handler { return h }
+
+// appendAttr writes a as key=value, its key qualified by prefix. Groups are
+// flattened into dotted keys.
+func appendAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := prefix + a.Key
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			key += "."
+		}
+		for _, ga := range a.Value.Group() {
+			appendAttr(buf, key, ga)
+		}
+		return
+	}
+
+	var s string
+	switch a.Value.Kind() {
+	case slog.KindDuration:
+		s = a.Value.Duration().Round(time.Millisecond).String()
+	case slog.KindTime:
+		s = a.Value.Time().Format(time.RFC3339)
+	default:
+		s = a.Value.String()
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		s = strconv.Quote(s)
+	}
+	fmt.Fprintf(buf, " %s=%s", key, s)
+}
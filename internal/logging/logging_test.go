@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		verbosity int
+		quiet     bool
+		want      slog.Level
+	}{
+		{0, false, slog.LevelInfo},
+		{1, false, slog.LevelDebug},
+		{2, false, LevelTrace},
+		{3, false, LevelTrace},
+		{2, true, slog.LevelError},
+	}
+	for _, tt := range tests {
+		if got := Level(tt.verbosity, tt.quiet); got != tt.want {
+			t.Errorf("Level(%d, %v) = %v, want %v", tt.verbosity, tt.quiet, got, tt.want)
+		}
+	}
+}
+
+func TestNew_Text(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, FormatText, slog.LevelInfo).With("command", "ahrefs test")
+
+	log.Debug("hidden")
+	log.Info("Synced 3 row(s)")
+	log.Warn("failed to record usage", "err", errors.New("disk full"), "target", "ahrefs.com")
+	log.Error("budget exceeded", "duration", 1500*time.Millisecond, "anchor", "best shoes")
+
+	want := "Synced 3 row(s)\n" +
+		"Warning: failed to record usage: disk full target=ahrefs.com\n" +
+		"Error: budget exceeded duration=1.5s anchor=\"best shoes\"\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, FormatJSON, LevelTrace).With("command", "ahrefs test")
+
+	log.Log(t.Context(), LevelTrace, "sending", "endpoint", "/site-explorer/backlinks")
+	log.Warn("failed", "err", errors.New("boom"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+	var first, second map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[1], err)
+	}
+	if first["level"] != "TRACE" || first["command"] != "ahrefs test" || first["endpoint"] != "/site-explorer/backlinks" {
+		t.Errorf("trace record = %v", first)
+	}
+	if second["level"] != "WARN" || second["err"] != "boom" {
+		t.Errorf("warning record = %v", second)
+	}
+}
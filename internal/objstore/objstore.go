@@ -0,0 +1,173 @@
+package objstore
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options configures an upload
+type Options struct {
+	// ContentType is the object's MIME type. Objects named *.gz are always
+	// stored as application/gzip.
+	ContentType string
+
+	// SSE is the S3 server-side encryption algorithm: AES256 or aws:kms
+	SSE string
+
+	// KMSKey is the KMS key for server-side encryption: a key ID or ARN on
+	// S3, a Cloud KMS key name on GCS
+	KMSKey string
+}
+
+// uploader stores a spooled object in a bucket
+type uploader interface {
+	upload(ctx context.Context, body io.ReadSeeker, size int64, sum string) error
+}
+
+// Object is an upload in progress. Writes are spooled to a temporary file and
+// uploaded in a single request on Close, so a failed or aborted run never
+// leaves a partial object behind.
+type Object struct {
+	ctx  context.Context
+	up   uploader
+	tmp  *os.File
+	gz   *gzip.Writer
+	w    io.Writer
+	done bool
+}
+
+// IsURL reports whether s is an s3:// or gs:// object URL
+func IsURL(s string) bool {
+	return strings.HasPrefix(s, "s3://") || strings.HasPrefix(s, "gs://")
+}
+
+// Create starts an upload to an s3:// or gs:// URL. Credentials are resolved
+// up front, from the provider's default chain, so a missing or broken setup
+// is reported before any work is done. ctx also bounds the upload on Close,
+// so a canceled command doesn't keep uploading.
+func Create(ctx context.Context, rawURL string, opts Options) (*Object, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object URL: %w", err)
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("object URL %s must name a bucket and an object, e.g. %s://bucket/path/file.csv", rawURL, u.Scheme)
+	}
+
+	if strings.HasSuffix(key, ".gz") {
+		opts.ContentType = "application/gzip"
+	}
+
+	var up uploader
+	switch u.Scheme {
+	case "s3":
+		up, err = newS3Uploader(ctx, bucket, key, opts)
+	case "gs":
+		up, err = newGCSUploader(ctx, bucket, key, opts)
+	default:
+		err = fmt.Errorf("unsupported object URL scheme %q (supported: s3, gs)", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "ahrefs-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload buffer: %w", err)
+	}
+
+	o := &Object{ctx: ctx, up: up, tmp: tmp, w: tmp}
+	if strings.HasSuffix(key, ".gz") {
+		o.gz = gzip.NewWriter(tmp)
+		o.w = o.gz
+	}
+	return o, nil
+}
+
+// Write spools p for upload
+func (o *Object) Write(p []byte) (int, error) {
+	if o.done {
+		return 0, errors.New("write to closed object")
+	}
+	return o.w.Write(p)
+}
+
+// Close uploads everything written and removes the spool file
+func (o *Object) Close() error {
+	if o.done {
+		return nil
+	}
+	defer o.Abort()
+
+	if o.gz != nil {
+		if err := o.gz.Close(); err != nil {
+			return fmt.Errorf("failed to compress upload: %w", err)
+		}
+	}
+
+	size, err := o.tmp.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to read upload buffer: %w", err)
+	}
+	if _, err := o.tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read upload buffer: %w", err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, o.tmp); err != nil {
+		return fmt.Errorf("failed to read upload buffer: %w", err)
+	}
+
+	if err := o.up.upload(o.ctx, o.tmp, size, hex.EncodeToString(h.Sum(nil))); err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	return nil
+}
+
+// Abort discards everything written without uploading
+func (o *Object) Abort() error {
+	if o.done {
+		return nil
+	}
+	o.done = true
+	o.tmp.Close()
+	return os.Remove(o.tmp.Name())
+}
+
+// httpClient is used for uploads and credential lookups
+var httpClient = &http.Client{Timeout: 10 * time.Minute}
+
+// metadataTimeout bounds probes of cloud metadata services, which only
+// answer on cloud instances
+const metadataTimeout = 2 * time.Second
+
+// doRequest sends req, seeking body back to the start first so the request
+// can be retried
+func doRequest(req *http.Request, body io.ReadSeeker, size int64) (*http.Response, error) {
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(body)
+	req.ContentLength = size
+	return httpClient.Do(req)
+}
+
+// homeFile returns path under the user's home directory
+func homeFile(path ...string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(append([]string{home}, path...)...)
+}
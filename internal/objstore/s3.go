@@ -0,0 +1,445 @@
+package objstore
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3MaxPutSize is the largest object S3 takes in a single PUT
+const s3MaxPutSize = 5 << 30
+
+// awsCredentials is a resolved AWS access key
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time
+}
+
+// s3Uploader puts objects with a SigV4-signed request
+type s3Uploader struct {
+	bucket   string
+	key      string
+	region   string
+	endpoint string // custom endpoint for S3-compatible stores; path-style
+	opts     Options
+	creds    awsCredentials
+}
+
+func newS3Uploader(ctx context.Context, bucket, key string, opts Options) (*s3Uploader, error) {
+	if opts.KMSKey != "" && opts.SSE == "" {
+		opts.SSE = "aws:kms"
+	}
+	switch opts.SSE {
+	case "", "AES256", "aws:kms":
+	default:
+		return nil, fmt.Errorf("unsupported server-side encryption %q (supported: AES256, aws:kms)", opts.SSE)
+	}
+	if opts.KMSKey != "" && opts.SSE != "aws:kms" {
+		return nil, fmt.Errorf("a KMS key requires aws:kms server-side encryption")
+	}
+
+	creds, err := resolveAWSCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+
+	return &s3Uploader{
+		bucket:   bucket,
+		key:      key,
+		region:   awsRegion(),
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		opts:     opts,
+		creds:    creds,
+	}, nil
+}
+
+// objectURL returns the object's URL, virtual-hosted style unless a custom
+// endpoint is set or the bucket name can't be a host label
+func (s *s3Uploader) objectURL() string {
+	path := "/" + awsURIEncode(s.key, false)
+	if s.endpoint != "" {
+		return s.endpoint + "/" + s.bucket + path
+	}
+	if strings.Contains(s.bucket, ".") {
+		return fmt.Sprintf("https://s3.%s.amazonaws.com/%s%s", s.region, s.bucket, path)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", s.bucket, s.region, path)
+}
+
+func (s *s3Uploader) upload(ctx context.Context, body io.ReadSeeker, size int64, sum string) error {
+	if size > s3MaxPutSize {
+		return fmt.Errorf("%d bytes is more than the 5 GiB S3 takes in a single upload; write to a local file and copy it with 'aws s3 cp', which uploads in parts", size)
+	}
+	if !s.creds.Expires.IsZero() && time.Until(s.creds.Expires) < time.Minute {
+		creds, err := resolveAWSCredentials(ctx)
+		if err != nil {
+			return err
+		}
+		s.creds = creds
+	}
+
+	// A bucket in another region answers with its region; retry there once
+	for attempt := 0; ; attempt++ {
+		resp, err := s.put(ctx, body, size, sum)
+		if err != nil {
+			return err
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		if region := resp.Header.Get("X-Amz-Bucket-Region"); attempt == 0 && region != "" && region != s.region {
+			s.region = region
+			continue
+		}
+		return s3Error(resp.StatusCode, respBody)
+	}
+}
+
+func (s *s3Uploader) put(ctx context.Context, body io.ReadSeeker, size int64, sum string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.ContentType != "" {
+		req.Header.Set("Content-Type", s.opts.ContentType)
+	}
+	req.Header.Set("X-Amz-Content-Sha256", sum)
+	if s.opts.SSE != "" {
+		req.Header.Set("X-Amz-Server-Side-Encryption", s.opts.SSE)
+	}
+	if s.opts.KMSKey != "" {
+		req.Header.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", s.opts.KMSKey)
+	}
+	signV4(req, s.creds, s.region, "s3", sum, time.Now())
+
+	return doRequest(req, body, size)
+}
+
+// s3Error converts an S3 XML error response to an error
+func s3Error(status int, body []byte) error {
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("S3 returned %d %s: %s", status, e.Code, e.Message)
+	}
+	return fmt.Errorf("S3 returned %d %s", status, http.StatusText(status))
+}
+
+// signV4 signs req with AWS Signature Version 4, covering the host and every
+// header already set on req
+func signV4(req *http.Request, creds awsCredentials, region, service, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsURIEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query sorted by key, as SigV4 requires
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for k, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEncode percent-encodes every byte except unreserved characters, and
+// slashes unless encodeSlash is set
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// resolveAWSCredentials follows the AWS default credential chain:
+// environment, shared credentials file, container role, then instance role
+func resolveAWSCredentials(ctx context.Context) (awsCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return awsCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		path = homeFile(".aws", "credentials")
+	}
+	if section, err := readINISection(path, awsProfile()); err == nil && section["aws_access_key_id"] != "" {
+		return awsCredentials{
+			AccessKeyID:     section["aws_access_key_id"],
+			SecretAccessKey: section["aws_secret_access_key"],
+			SessionToken:    section["aws_session_token"],
+		}, nil
+	}
+
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		return containerCredentials(ctx)
+	}
+
+	if !strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		if creds, err := instanceCredentials(ctx); err == nil {
+			return creds, nil
+		}
+	}
+
+	return awsCredentials{}, errors.New("no AWS credentials found: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, " +
+		"configure ~/.aws/credentials, or run with an IAM role")
+}
+
+// awsProfile returns the shared config profile to use
+func awsProfile() string {
+	if p := os.Getenv("AWS_PROFILE"); p != "" {
+		return p
+	}
+	return "default"
+}
+
+// awsRegion returns the configured region, defaulting to us-east-1
+func awsRegion() string {
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if r := os.Getenv(env); r != "" {
+			return r
+		}
+	}
+
+	path := os.Getenv("AWS_CONFIG_FILE")
+	if path == "" {
+		path = homeFile(".aws", "config")
+	}
+	// The config file prefixes named profiles with "profile "
+	profile := awsProfile()
+	if profile != "default" {
+		profile = "profile " + profile
+	}
+	if section, err := readINISection(path, profile); err == nil && section["region"] != "" {
+		return section["region"]
+	}
+	return "us-east-1"
+}
+
+// readINISection returns the key/value pairs of one [section] of an INI file
+func readINISection(path, name string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	in := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			in = strings.TrimSpace(line[1:len(line)-1]) == name
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok && in {
+			values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// roleCredentials is the credential document served by the container and
+// instance metadata endpoints
+type roleCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (r roleCredentials) credentials() awsCredentials {
+	return awsCredentials{AccessKeyID: r.AccessKeyID, SecretAccessKey: r.SecretAccessKey, SessionToken: r.Token, Expires: r.Expiration}
+}
+
+// containerCredentials fetches the task role's credentials on ECS or EKS
+func containerCredentials(ctx context.Context) (awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if rel := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		endpoint = "http://169.254.170.2" + rel
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	var rc roleCredentials
+	if err := getJSON(req, &rc); err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to fetch container credentials: %w", err)
+	}
+	return rc.credentials(), nil
+}
+
+// instanceCredentials fetches the EC2 instance role's credentials with IMDSv2
+func instanceCredentials(ctx context.Context) (awsCredentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	const base = "http://169.254.169.254/latest"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, base+"/api/token", nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "300")
+	token, err := getText(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+
+	get := func(path string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path, nil)
+		if err == nil {
+			req.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+		}
+		return req, err
+	}
+
+	req, err = get("/meta-data/iam/security-credentials/")
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	roles, err := getText(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(roles), "\n")
+
+	req, err = get("/meta-data/iam/security-credentials/" + role)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	var rc roleCredentials
+	if err := getJSON(req, &rc); err != nil {
+		return awsCredentials{}, err
+	}
+	return rc.credentials(), nil
+}
+
+// getText sends req and returns the body of a 2xx response
+func getText(req *http.Request) (string, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s returned %d", req.URL.Host, resp.StatusCode)
+	}
+	return string(body), nil
+}
+
+// getJSON sends req and decodes a 2xx JSON response into v
+func getJSON(req *http.Request, v interface{}) error {
+	body, err := getText(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(body), v)
+}
@@ -0,0 +1,82 @@
+package pager
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// Keys other than printable characters
+const (
+	esc         = "\x1b"
+	ctrlC       = "\x03"
+	backspace   = "\x7f"
+	keyUp       = "\x1b[A"
+	keyDown     = "\x1b[B"
+	keyRight    = "\x1b[C"
+	keyLeft     = "\x1b[D"
+	keyHome     = "\x1b[H"
+	keyEnd      = "\x1b[F"
+	keyPageUp   = "\x1b[5~"
+	keyPageDown = "\x1b[6~"
+)
+
+// keyReader splits the bytes typed on a raw terminal into keys: a character,
+// or an escape sequence such as an arrow's
+type keyReader struct {
+	in  io.Reader
+	buf []byte
+}
+
+func newKeyReader(in io.Reader) *keyReader {
+	return &keyReader{in: in}
+}
+
+// next returns the next key typed
+func (k *keyReader) next() (string, error) {
+	for {
+		if n := k.keyLen(); n > 0 {
+			key := string(k.buf[:n])
+			k.buf = k.buf[n:]
+			return key, nil
+		}
+		chunk := make([]byte, 64)
+		n, err := k.in.Read(chunk)
+		k.buf = append(k.buf, chunk[:n]...)
+		if n == 0 && err != nil {
+			if len(k.buf) > 0 {
+				// A lone escape, or a sequence cut short
+				key := string(k.buf)
+				k.buf = nil
+				return key, nil
+			}
+			return "", err
+		}
+	}
+}
+
+// keyLen returns the length of the key at the start of the buffer, or 0 if
+// it is incomplete. A terminal sends an escape sequence in a single write,
+// so an escape with nothing after it in the buffer is the escape key.
+func (k *keyReader) keyLen() int {
+	b := k.buf
+	if len(b) == 0 {
+		return 0
+	}
+	if b[0] != 0x1b {
+		if !utf8.FullRune(b) {
+			return 0
+		}
+		_, n := utf8.DecodeRune(b)
+		return n
+	}
+	if len(b) == 1 || b[1] != '[' {
+		return 1
+	}
+	// CSI: parameters, then a final byte from @ to ~
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,272 @@
+// Package pager shows text too long for the terminal a screen at a time, as
+// less does: it scrolls by line, page, and column, and searches for text.
+// It takes over the terminal's alternate screen, so the shell's scrollback is
+// left as it was when it quits.
+package pager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Terminal control sequences
+const (
+	altScreenOn  = "\x1b[?1049h\x1b[H"
+	altScreenOff = "\x1b[?1049l"
+	clearScreen  = "\x1b[H\x1b[2J"
+	reverseOn    = "\x1b[7m"
+	reverseOff   = "\x1b[27m"
+	clearLine    = "\r\x1b[K"
+)
+
+// Help is the hint shown in the status line
+const Help = "q quit, space/b page, ←/→ scroll, / search, n/N next/previous"
+
+// Run pages text on the terminal in and out until the user quits. It returns
+// an error if out isn't a terminal whose size can be read.
+func Run(text []byte, in, out *os.File) error {
+	height, width, err := Size(out)
+	if err != nil {
+		return err
+	}
+	restore, err := makeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	io.WriteString(out, altScreenOn)
+	defer io.WriteString(out, altScreenOff)
+	return newView(text, height, width).run(in, out)
+}
+
+// Fits reports whether text fits on a screen height lines tall, leaving a
+// line for the shell's prompt
+func Fits(text []byte, height int) bool {
+	return bytes.Count(text, []byte("\n")) < height
+}
+
+// view is the state of the pager: the lines, the first shown, and the
+// search
+type view struct {
+	lines  []string
+	height int // lines shown, leaving the status line
+	width  int
+	top    int // first line shown
+	left   int // first column shown
+
+	pattern string
+	status  string // a message shown in place of the position, once
+}
+
+// newView returns a view of text on a screen height lines by width columns
+func newView(text []byte, height, width int) *view {
+	lines := strings.Split(strings.TrimSuffix(string(text), "\n"), "\n")
+	return &view{lines: lines, height: max(height-1, 1), width: max(width, 1)}
+}
+
+// run draws the view and handles keys read from in until q
+func (v *view) run(in io.Reader, out io.Writer) error {
+	keys := newKeyReader(in)
+	for {
+		v.draw(out)
+		key, err := keys.next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if key == "/" {
+			pattern, ok, err := v.prompt(keys, out)
+			if err != nil {
+				return err
+			}
+			if ok && pattern != "" {
+				v.pattern = pattern
+				v.search(v.top+1, 1)
+			}
+			continue
+		}
+		if !v.handle(key) {
+			return nil
+		}
+	}
+}
+
+// handle moves the view for key, and reports whether to keep paging
+func (v *view) handle(key string) bool {
+	switch key {
+	case "q", "Q", ctrlC, esc:
+		return false
+	case "j", "\r", "\n", keyDown:
+		v.scroll(1)
+	case "k", "y", keyUp:
+		v.scroll(-1)
+	case " ", "f", keyPageDown:
+		v.scroll(v.height)
+	case "b", keyPageUp:
+		v.scroll(-v.height)
+	case "d":
+		v.scroll(v.height / 2)
+	case "u":
+		v.scroll(-v.height / 2)
+	case "g", "<", keyHome:
+		v.top = 0
+	case "G", ">", keyEnd:
+		v.top = v.lastTop()
+	case keyRight:
+		v.left += v.width / 2
+	case keyLeft:
+		v.left = max(v.left-v.width/2, 0)
+	case "n":
+		v.search(v.top+1, 1)
+	case "N":
+		v.search(v.top-1, -1)
+	}
+	return true
+}
+
+// scroll moves the view n lines, down when n is positive
+func (v *view) scroll(n int) {
+	v.top = min(max(v.top+n, 0), v.lastTop())
+}
+
+// lastTop is the top line of the last screen
+func (v *view) lastTop() int {
+	return max(len(v.lines)-v.height, 0)
+}
+
+// search moves the view to the next line matching the pattern from line
+// from, in direction dir
+func (v *view) search(from, dir int) {
+	if v.pattern == "" {
+		v.status = "No previous search"
+		return
+	}
+	for i := from; i >= 0 && i < len(v.lines); i += dir {
+		if matchIndex(v.lines[i], v.pattern) >= 0 {
+			v.top = min(i, v.lastTop())
+			if i > v.top {
+				// The last screen can't scroll the match to the top
+				v.status = fmt.Sprintf("Match on line %d", i+1)
+			}
+			return
+		}
+	}
+	v.status = "Pattern not found: " + v.pattern
+}
+
+// prompt reads a search pattern on the status line. It reports false when
+// the search is canceled with escape.
+func (v *view) prompt(keys *keyReader, out io.Writer) (string, bool, error) {
+	var pattern []rune
+	for {
+		fmt.Fprintf(out, "%s/%s", clearLine, string(pattern))
+		key, err := keys.next()
+		if err != nil {
+			return "", false, err
+		}
+		switch {
+		case key == "\r" || key == "\n":
+			return string(pattern), true, nil
+		case key == esc || key == ctrlC:
+			return "", false, nil
+		case key == backspace || key == "\b":
+			if len(pattern) > 0 {
+				pattern = pattern[:len(pattern)-1]
+			}
+		case utf8.RuneCountInString(key) == 1:
+			if r, _ := utf8.DecodeRuneInString(key); unicode.IsPrint(r) {
+				pattern = append(pattern, r)
+			}
+		}
+	}
+}
+
+// draw writes the screen: the lines shown, cut to the width from the left
+// column with matches highlighted, then the status line
+func (v *view) draw(out io.Writer) {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	for i := v.top; i < v.top+v.height; i++ {
+		if i < len(v.lines) {
+			b.WriteString(v.highlight(cut(v.lines[i], v.left, v.width)))
+		} else {
+			b.WriteString("~")
+		}
+		b.WriteString("\r\n")
+	}
+
+	status := v.status
+	v.status = ""
+	if status == "" {
+		last := min(v.top+v.height, len(v.lines))
+		status = fmt.Sprintf("lines %d-%d/%d", v.top+1, last, len(v.lines))
+		if last == len(v.lines) {
+			status += " (END)"
+		}
+		status += " · " + Help
+	}
+	b.WriteString(reverseOn + cut(status, 0, v.width-1) + reverseOff)
+	io.WriteString(out, b.String())
+}
+
+// highlight shows the matches of the pattern in line in reverse video
+func (v *view) highlight(line string) string {
+	if v.pattern == "" {
+		return line
+	}
+	var b strings.Builder
+	for {
+		i := matchIndex(line, v.pattern)
+		if i < 0 {
+			break
+		}
+		end := i + len(v.pattern)
+		b.WriteString(line[:i] + reverseOn + line[i:end] + reverseOff)
+		line = line[end:]
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+// matchIndex returns the index of pattern in line, or -1. A pattern without
+// capitals matches regardless of case.
+func matchIndex(line, pattern string) int {
+	if strings.ToLower(pattern) == pattern && strings.ToLower(line) != line {
+		return indexFold(line, pattern)
+	}
+	return strings.Index(line, pattern)
+}
+
+// indexFold is strings.Index ignoring case, with an index into s itself
+func indexFold(s, pattern string) int {
+	for i := range s {
+		if len(s)-i < len(pattern) {
+			break
+		}
+		if strings.EqualFold(s[i:i+len(pattern)], pattern) {
+			return i
+		}
+	}
+	return -1
+}
+
+// cut returns the part of s from column left that fits in width columns
+func cut(s string, left, width int) string {
+	runes := []rune(s)
+	if left >= len(runes) {
+		return ""
+	}
+	runes = runes[left:]
+	if len(runes) > width {
+		runes = runes[:width]
+	}
+	return string(runes)
+}
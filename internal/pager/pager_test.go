@@ -0,0 +1,144 @@
+package pager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// numbered returns n lines, "line 1" to "line n"
+func numbered(n int) []byte {
+	var b bytes.Buffer
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	return b.Bytes()
+}
+
+func TestView_Keys(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		wantTop int
+	}{
+		{name: "line down", keys: []string{"j", keyDown}, wantTop: 2},
+		{name: "page down", keys: []string{" "}, wantTop: 9},
+		{name: "page down past the end", keys: []string{" ", " ", " ", " "}, wantTop: 21},
+		{name: "page back", keys: []string{"G", "b"}, wantTop: 12},
+		{name: "back past the start", keys: []string{"k", keyPageUp}, wantTop: 0},
+		{name: "half page", keys: []string{"d", "d", "u"}, wantTop: 4},
+		{name: "end and home", keys: []string{"G", "g"}, wantTop: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newView(numbered(30), 10, 80)
+			for _, key := range tt.keys {
+				if !v.handle(key) {
+					t.Fatalf("handle(%q) quit", key)
+				}
+			}
+			if v.top != tt.wantTop {
+				t.Errorf("top = %d, want %d", v.top, tt.wantTop)
+			}
+		})
+	}
+
+	v := newView(numbered(30), 10, 80)
+	v.handle(keyRight)
+	v.handle(keyRight)
+	v.handle(keyLeft)
+	if v.left != 40 {
+		t.Errorf("left = %d, want half a screen right", v.left)
+	}
+	if v.handle("q") {
+		t.Error("handle(q) kept paging")
+	}
+}
+
+func TestView_Search(t *testing.T) {
+	v := newView([]byte("Alpha\nbeta\ngamma\nBeta\ndelta\nepsilon\nbeta blocker\nzeta\n"), 4, 80)
+	v.pattern = "beta"
+
+	// Lowercase patterns ignore case
+	for _, want := range []int{1, 3} {
+		v.search(v.top+1, 1)
+		if v.top != want {
+			t.Fatalf("top = %d, want %d", v.top, want)
+		}
+	}
+	// The last match is past the last screen's top
+	v.search(v.top+1, 1)
+	if v.top != 5 || v.status != "Match on line 7" {
+		t.Errorf("top = %d, status = %q, want the last screen and the line", v.top, v.status)
+	}
+	v.handle("N")
+	if v.top != 3 {
+		t.Errorf("top = %d after N, want 3", v.top)
+	}
+
+	v.pattern = "Beta"
+	v.top = 0
+	v.search(v.top+1, 1)
+	if v.top != 3 {
+		t.Errorf("top = %d, want the capitalized match only", v.top)
+	}
+	v.pattern = "omega"
+	v.search(v.top+1, 1)
+	if v.top != 3 || v.status != "Pattern not found: omega" {
+		t.Errorf("top = %d, status = %q, want no move", v.top, v.status)
+	}
+}
+
+func TestView_Run(t *testing.T) {
+	var out bytes.Buffer
+	v := newView(numbered(50), 10, 20)
+	keys := " /line 43" + backspace + "2\rq"
+	if err := v.run(strings.NewReader(keys), &out); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if v.top != 41 {
+		t.Errorf("top = %d, want line 42's", v.top)
+	}
+
+	screens := strings.Split(out.String(), clearScreen)
+	last := screens[len(screens)-1]
+	if !strings.HasPrefix(last, reverseOn+"line 42"+reverseOff+"\r\nline 43\r\n") {
+		t.Errorf("last screen = %q, want the match highlighted", last)
+	}
+	if !strings.Contains(screens[1], "lines 1-9/50") {
+		t.Errorf("first screen = %q, want the position and help", screens[1])
+	}
+}
+
+func TestView_RunEndOfInput(t *testing.T) {
+	if err := newView(numbered(50), 10, 20).run(strings.NewReader("j"), io.Discard); err != nil {
+		t.Errorf("run() error = %v, want the input ending to quit", err)
+	}
+}
+
+func TestKeyReader(t *testing.T) {
+	k := newKeyReader(strings.NewReader("a" + keyUp + keyPageDown + "é" + esc))
+	var got []string
+	for {
+		key, err := k.next()
+		if err != nil {
+			break
+		}
+		got = append(got, key)
+	}
+	want := []string{"a", keyUp, keyPageDown, "é", esc}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("keys = %q, want %q", got, want)
+	}
+}
+
+func TestFits(t *testing.T) {
+	if !Fits(numbered(9), 10) {
+		t.Error("Fits(9 lines, 10) = false, want true with a line for the prompt")
+	}
+	if Fits(numbered(10), 10) {
+		t.Error("Fits(10 lines, 10) = true, want false")
+	}
+}
@@ -0,0 +1,9 @@
+package pager
+
+import "syscall"
+
+// The ioctls reading and setting a terminal's mode
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,27 @@
+//go:build !linux && !darwin
+
+package pager
+
+import (
+	"errors"
+	"os"
+)
+
+// errUnsupported is returned where the terminal can't be controlled
+var errUnsupported = errors.New("the pager isn't supported on this platform")
+
+// IsTerminal reports whether f is a terminal. Terminals aren't detected
+// here, so output is never paged.
+func IsTerminal(f *os.File) bool {
+	return false
+}
+
+// Size returns the lines and columns of the terminal f
+func Size(f *os.File) (height, width int, err error) {
+	return 0, 0, errUnsupported
+}
+
+// makeRaw is unsupported here
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,70 @@
+//go:build linux || darwin
+
+package pager
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// winsize is the terminal size TIOCGWINSZ reads
+type winsize struct {
+	Row, Col       uint16
+	Xpixel, Ypixel uint16
+}
+
+// IsTerminal reports whether f is a terminal
+func IsTerminal(f *os.File) bool {
+	_, err := getTermios(f)
+	return err == nil
+}
+
+// Size returns the lines and columns of the terminal f
+func Size(f *os.File) (height, width int, err error) {
+	var ws winsize
+	if err := ioctl(f, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)); err != nil {
+		return 0, 0, err
+	}
+	if ws.Row == 0 || ws.Col == 0 {
+		return 0, 0, errors.New("terminal size unknown")
+	}
+	return int(ws.Row), int(ws.Col), nil
+}
+
+// makeRaw passes each key typed on f through as it is typed, without echoing
+// it or turning control characters into signals, and returns a function
+// restoring the terminal's mode
+func makeRaw(f *os.File) (func(), error) {
+	old, err := getTermios(f)
+	if err != nil {
+		return nil, err
+	}
+	raw := *old
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG | syscall.IEXTEN
+	raw.Iflag &^= syscall.IXON | syscall.ICRNL
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(f, ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+	return func() { ioctl(f, ioctlSetTermios, unsafe.Pointer(old)) }, nil
+}
+
+// getTermios returns the mode of the terminal f
+func getTermios(f *os.File) (*syscall.Termios, error) {
+	var t syscall.Termios
+	if err := ioctl(f, ioctlGetTermios, unsafe.Pointer(&t)); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ioctl performs the ioctl request on f with arg
+func ioctl(f *os.File, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
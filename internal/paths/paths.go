@@ -0,0 +1,35 @@
+// Package paths expands the file paths given on the command line the way a
+// shell would have, for paths that reach the CLI unexpanded: quoted, read
+// from an environment variable, or typed in a shell that doesn't expand ~,
+// such as cmd.exe or PowerShell.
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Expand replaces a leading ~ in path with the home directory and the
+// environment variables it references with their values: $VAR and ${VAR}
+// everywhere, and %VAR% on Windows. ~user paths and a ~ whose home
+// directory is unknown are left alone.
+func Expand(path string) string {
+	path = expandEnv(path)
+	if path != "~" && !hasHomePrefix(path) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	if path == "~" {
+		return home
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// hasHomePrefix reports whether path starts with ~ and a separator
+func hasHomePrefix(path string) bool {
+	return len(path) > 1 && path[0] == '~' && strings.ContainsRune(separators, rune(path[1]))
+}
@@ -0,0 +1,37 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+	t.Setenv("AHREFS_TEST_DIR", "exports")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", ""},
+		{"out.csv", "out.csv"},
+		{"~", home},
+		{"~/out.csv", filepath.Join(home, "out.csv")},
+		{"~/reports/{target}.csv", filepath.Join(home, "reports", "{target}.csv")},
+		{"$AHREFS_TEST_DIR/out.csv", "exports/out.csv"},
+		{"${AHREFS_TEST_DIR}/out.csv", "exports/out.csv"},
+		{"~/$AHREFS_TEST_DIR/out.csv", filepath.Join(home, "exports", "out.csv")},
+		{"~other/out.csv", "~other/out.csv"},
+		{"reports/~/out.csv", "reports/~/out.csv"},
+		{"s3://bucket/{target}.json", "s3://bucket/{target}.json"},
+	}
+	for _, tt := range tests {
+		if got := Expand(tt.path); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
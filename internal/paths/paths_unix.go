@@ -0,0 +1,13 @@
+//go:build !windows
+
+package paths
+
+import "os"
+
+// separators are the characters that end the ~ of a home directory path
+const separators = "/"
+
+// expandEnv expands $VAR and ${VAR}
+func expandEnv(path string) string {
+	return os.ExpandEnv(path)
+}
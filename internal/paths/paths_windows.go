@@ -0,0 +1,26 @@
+//go:build windows
+
+package paths
+
+import (
+	"os"
+	"regexp"
+)
+
+// separators are the characters that end the ~ of a home directory path
+const separators = `/\`
+
+// percentVar matches a cmd.exe style %VAR% reference
+var percentVar = regexp.MustCompile(`%[A-Za-z_][A-Za-z0-9_()]*%`)
+
+// expandEnv expands %VAR% as cmd.exe does, leaving references to unset
+// variables as they are, and then $VAR and ${VAR}
+func expandEnv(path string) string {
+	path = percentVar.ReplaceAllStringFunc(path, func(ref string) string {
+		if value, ok := os.LookupEnv(ref[1 : len(ref)-1]); ok {
+			return value
+		}
+		return ref
+	})
+	return os.ExpandEnv(path)
+}
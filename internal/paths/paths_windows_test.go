@@ -0,0 +1,32 @@
+//go:build windows
+
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpand_Windows(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+	t.Setenv("AHREFS_TEST_DIR", `C:\exports`)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`~\out.csv`, filepath.Join(home, "out.csv")},
+		{`%AHREFS_TEST_DIR%\out.csv`, `C:\exports\out.csv`},
+		{`%AHREFS_TEST_UNSET%\out.csv`, `%AHREFS_TEST_UNSET%\out.csv`},
+		{`50%\out.csv`, `50%\out.csv`},
+	}
+	for _, tt := range tests {
+		if got := Expand(tt.path); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
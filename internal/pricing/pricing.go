@@ -0,0 +1,109 @@
+package pricing
+
+import "fmt"
+
+const (
+	// MinUnitsPerRequest is the minimum number of units charged for any API request
+	MinUnitsPerRequest = 50
+
+	// DefaultUnitsPerRow is used for endpoints missing from the pricing table
+	DefaultUnitsPerRow = 10
+)
+
+// EndpointCost describes how an endpoint consumes API units
+type EndpointCost struct {
+	// UnitsPerRow is the approximate cost of one row with the default field selection
+	UnitsPerRow int
+	// SingleRow marks endpoints that always return a single object
+	SingleRow bool
+}
+
+// table holds the per-endpoint pricing, based on the Ahrefs API v3 published
+// unit consumption. Keep every endpoint here so estimates stay in one place.
+var table = map[string]EndpointCost{
+	"/site-explorer/domain-rating":       {UnitsPerRow: 1, SingleRow: true},
+	"/site-explorer/backlinks-stats":     {UnitsPerRow: 1, SingleRow: true},
+	"/site-explorer/metrics":             {UnitsPerRow: 1, SingleRow: true},
+	"/site-explorer/metrics-history":     {UnitsPerRow: 5},
+	"/site-explorer/refdomains-history":  {UnitsPerRow: 5},
+	"/site-explorer/url-rating-history":  {UnitsPerRow: 5},
+	"/site-explorer/backlinks":           {UnitsPerRow: 11},
+	"/site-explorer/refdomains":          {UnitsPerRow: 9},
+	"/site-explorer/anchors":             {UnitsPerRow: 5},
+	"/site-explorer/organic-keywords":    {UnitsPerRow: 10},
+	"/site-explorer/top-pages":           {UnitsPerRow: 10},
+	"/site-explorer/organic-competitors": {UnitsPerRow: 10},
+	"/site-explorer/broken-backlinks":    {UnitsPerRow: 7},
+	"/site-explorer/linked-domains":      {UnitsPerRow: 5},
+	"/site-explorer/pages-by-traffic":    {UnitsPerRow: 5},
+	"/site-explorer/best-by-links":       {UnitsPerRow: 6},
+}
+
+// Estimate is the predicted unit consumption of a command
+type Estimate struct {
+	Endpoint string `json:"endpoint"`
+	Rows     int    `json:"rows"`
+	Requests int    `json:"requests"`
+	Units    int    `json:"units"`
+	Known    bool   `json:"known"`
+
+	// Unbounded marks an export of every page, whose number isn't known
+	// before it runs: Rows, Requests, and Units are those of its first page
+	Unbounded bool `json:"unbounded,omitempty"`
+}
+
+// Lookup returns the pricing for an endpoint and whether it is in the table
+func Lookup(endpoint string) (EndpointCost, bool) {
+	cost, ok := table[endpoint]
+	if !ok {
+		return EndpointCost{UnitsPerRow: DefaultUnitsPerRow}, false
+	}
+	return cost, true
+}
+
+// EstimateUnits predicts the units consumed when fetching rows from endpoint.
+// When pageSize is positive and smaller than rows, the fetch is assumed to be
+// split into multiple requests, each charged at least MinUnitsPerRequest, so the
+// result is an upper bound for paginated exports.
+func EstimateUnits(endpoint string, rows, pageSize int) Estimate {
+	cost, known := Lookup(endpoint)
+	if cost.SingleRow || rows < 1 {
+		rows = 1
+	}
+
+	requests := 1
+	if pageSize > 0 && rows > pageSize {
+		requests = (rows + pageSize - 1) / pageSize
+	}
+
+	units := 0
+	remaining := rows
+	for i := 0; i < requests; i++ {
+		pageRows := remaining
+		if pageSize > 0 && pageRows > pageSize {
+			pageRows = pageSize
+		}
+		remaining -= pageRows
+		units += max(pageRows*cost.UnitsPerRow, MinUnitsPerRequest)
+	}
+
+	return Estimate{
+		Endpoint: endpoint,
+		Rows:     rows,
+		Requests: requests,
+		Units:    units,
+		Known:    known,
+	}
+}
+
+// String formats the estimate for human-readable output
+func (e Estimate) String() string {
+	s := fmt.Sprintf("~%d units (%d request(s), %d row(s))", e.Units, e.Requests, e.Rows)
+	if e.Unbounded {
+		s = fmt.Sprintf("~%d units (%d request(s), %d row(s)) per page, for every page: unbounded", e.Units, e.Requests, e.Rows)
+	}
+	if !e.Known {
+		s += " [endpoint not in pricing table, using default rate]"
+	}
+	return s
+}
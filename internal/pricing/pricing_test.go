@@ -0,0 +1,84 @@
+package pricing
+
+import "testing"
+
+func TestEstimateUnits(t *testing.T) {
+	tests := []struct {
+		name         string
+		endpoint     string
+		rows         int
+		pageSize     int
+		wantRequests int
+		wantUnits    int
+		wantKnown    bool
+	}{
+		{
+			name:         "single row endpoint charged minimum",
+			endpoint:     "/site-explorer/domain-rating",
+			rows:         100,
+			wantRequests: 1,
+			wantUnits:    MinUnitsPerRequest,
+			wantKnown:    true,
+		},
+		{
+			name:         "list endpoint scales with rows",
+			endpoint:     "/site-explorer/backlinks",
+			rows:         100,
+			wantRequests: 1,
+			wantUnits:    1100,
+			wantKnown:    true,
+		},
+		{
+			name:         "paginated upper bound",
+			endpoint:     "/site-explorer/anchors",
+			rows:         2500,
+			pageSize:     1000,
+			wantRequests: 3,
+			wantUnits:    12500,
+			wantKnown:    true,
+		},
+		{
+			name:         "small last page charged minimum",
+			endpoint:     "/site-explorer/anchors",
+			rows:         1001,
+			pageSize:     1000,
+			wantRequests: 2,
+			wantUnits:    5000 + MinUnitsPerRequest,
+			wantKnown:    true,
+		},
+		{
+			name:         "unknown endpoint uses default rate",
+			endpoint:     "/unknown",
+			rows:         10,
+			wantRequests: 1,
+			wantUnits:    10 * DefaultUnitsPerRow,
+			wantKnown:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateUnits(tt.endpoint, tt.rows, tt.pageSize)
+			if got.Requests != tt.wantRequests {
+				t.Errorf("EstimateUnits() requests = %v, want %v", got.Requests, tt.wantRequests)
+			}
+			if got.Units != tt.wantUnits {
+				t.Errorf("EstimateUnits() units = %v, want %v", got.Units, tt.wantUnits)
+			}
+			if got.Known != tt.wantKnown {
+				t.Errorf("EstimateUnits() known = %v, want %v", got.Known, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestEstimate_String(t *testing.T) {
+	est := EstimateUnits("/site-explorer/backlinks", 100, 0)
+	if got, want := est.String(), "~1100 units (1 request(s), 100 row(s))"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	est.Unbounded = true
+	if got, want := est.String(), "~1100 units (1 request(s), 100 row(s)) per page, for every page: unbounded"; got != want {
+		t.Errorf("String() of an unbounded estimate = %q, want %q", got, want)
+	}
+}
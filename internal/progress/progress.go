@@ -0,0 +1,157 @@
+// Package progress reports the steps of long-running commands, such as the
+// pages of an export: as a status line people can watch, or as JSON events,
+// one per line, that programs driving the CLI can parse. Both are rendered
+// from the same events, so they always agree.
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+// Formats of progress output
+const (
+	FormatBar  = "bar"
+	FormatJSON = "json"
+)
+
+// Formats lists the accepted --progress values
+var Formats = []string{FormatBar, FormatJSON}
+
+// Kinds of event
+const (
+	EventPage   = "page"   // a page of an export was fetched
+	EventTarget = "target" // one of several targets was done
+	EventRetry  = "retry"  // a failed request is about to be retried
+	EventDone   = "done"   // the command finished
+)
+
+// Event is one step of a command. Counts are totals so far: the rows and
+// units of a page event are those of its export up to that page.
+type Event struct {
+	Event     string `json:"event"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	Target    string `json:"target,omitempty"`
+	Page      int    `json:"page,omitempty"`
+	Targets   int    `json:"targets,omitempty"` // targets in all, for target events
+	Done      int    `json:"done,omitempty"`    // targets done, for target events
+	Rows      int    `json:"rows"`
+	Units     int    `json:"units"`
+	Requests  int    `json:"requests,omitempty"`
+	Attempt   int    `json:"attempt,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	BackoffMS int64  `json:"backoff_ms,omitempty"`
+	OK        *bool  `json:"ok,omitempty"`
+	ExitCode  *int   `json:"exit_code,omitempty"`
+	ElapsedMS int64  `json:"elapsed_ms"`
+}
+
+// Reporter renders the events of one command run. A nil Reporter discards
+// them, so callers needn't check whether progress was asked for.
+type Reporter struct {
+	mu     sync.Mutex
+	render func(Event)
+	start  time.Time
+}
+
+// New returns a reporter writing events to w in format, or nil when format is
+// empty or unknown
+func New(w io.Writer, format string) *Reporter {
+	r := &Reporter{start: time.Now()}
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		r.render = func(e Event) { _ = enc.Encode(e) }
+	case FormatBar:
+		b := &bar{w: w}
+		r.render = b.render
+	default:
+		return nil
+	}
+	return r
+}
+
+// Report renders e, stamped with the time since the reporter was created
+func (r *Reporter) Report(e Event) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.ElapsedMS = time.Since(r.start).Milliseconds()
+	r.render(e)
+}
+
+// Page reports the page-th page of an export of endpoint, with its rows and
+// units so far
+func (r *Reporter) Page(endpoint string, page, rows, units int) {
+	r.Report(Event{Event: EventPage, Endpoint: endpoint, Page: page, Rows: rows, Units: units})
+}
+
+// Target reports target done, the done-th of targets, with the rows and units
+// of the targets done so far
+func (r *Reporter) Target(target string, done, targets, rows, units int) {
+	r.Report(Event{Event: EventTarget, Target: target, Done: done, Targets: targets, Rows: rows, Units: units})
+}
+
+// Retry reports a retry; it is a client.Config OnRetry hook
+func (r *Reporter) Retry(ctx context.Context, retry client.Retry) {
+	r.Report(Event{Event: EventRetry, Endpoint: retry.Endpoint, Attempt: retry.Attempt, Reason: retry.Reason, BackoffMS: retry.Backoff.Milliseconds()})
+}
+
+// Finish reports the end of the command, with its totals
+func (r *Reporter) Finish(ok bool, exitCode, rows, units, requests int) {
+	r.Report(Event{Event: EventDone, OK: &ok, ExitCode: &exitCode, Rows: rows, Units: units, Requests: requests})
+}
+
+// bar renders events as a status line, rewritten in place with a carriage
+// return, and ended by the done event
+type bar struct {
+	w    io.Writer
+	last int // width of the line last written
+}
+
+func (b *bar) render(e Event) {
+	line := describe(e)
+	width := utf8.RuneCountInString(line)
+	pad := ""
+	if n := b.last - width; n > 0 {
+		// Blank out the rest of a longer line
+		pad = strings.Repeat(" ", n)
+	}
+	end := ""
+	if e.Event == EventDone {
+		end = "\n"
+	}
+	fmt.Fprint(b.w, "\r"+line+pad+end)
+	b.last = width
+}
+
+// describe returns the status line of e
+func describe(e Event) string {
+	elapsed := (time.Duration(e.ElapsedMS) * time.Millisecond).Round(100 * time.Millisecond)
+	switch e.Event {
+	case EventPage:
+		return fmt.Sprintf("page %d · %d rows · %d units · %s", e.Page, e.Rows, e.Units, elapsed)
+	case EventTarget:
+		return fmt.Sprintf("target %d/%d done (%s) · %d rows · %d units · %s", e.Done, e.Targets, e.Target, e.Rows, e.Units, elapsed)
+	case EventRetry:
+		backoff := time.Duration(e.BackoffMS) * time.Millisecond
+		return fmt.Sprintf("retrying %s in %s (attempt %d: %s)", e.Endpoint, backoff, e.Attempt, e.Reason)
+	case EventDone:
+		status := "done"
+		if e.OK != nil && !*e.OK {
+			status = "failed"
+		}
+		return fmt.Sprintf("%s · %d rows · %d units · %d requests · %s", status, e.Rows, e.Units, e.Requests, elapsed)
+	}
+	return e.Event
+}
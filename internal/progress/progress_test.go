@@ -0,0 +1,106 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+// report sends the events of an export of two pages with a retry to r
+func report(r *Reporter) {
+	r.Page("/site-explorer/backlinks", 1, 1000, 30)
+	r.Retry(context.Background(), client.Retry{Endpoint: "/site-explorer/backlinks", Attempt: 2, Reason: "rate limited (429)", Backoff: time.Second, Err: errors.New("429")})
+	r.Page("/site-explorer/backlinks", 2, 1500, 45)
+	r.Target("example.com", 1, 2, 1500, 45)
+	r.Finish(true, 0, 1500, 45, 3)
+}
+
+func TestReporter_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	report(New(&buf, FormatJSON))
+
+	var events []map[string]interface{}
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var e map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %q is not JSON: %v", sc.Text(), err)
+		}
+		if _, ok := e["elapsed_ms"]; !ok {
+			t.Errorf("event %q has no elapsed_ms", sc.Text())
+		}
+		delete(e, "elapsed_ms")
+		events = append(events, e)
+	}
+
+	want := []string{
+		`{"endpoint":"/site-explorer/backlinks","event":"page","page":1,"rows":1000,"units":30}`,
+		`{"attempt":2,"backoff_ms":1000,"endpoint":"/site-explorer/backlinks","event":"retry","reason":"rate limited (429)","rows":0,"units":0}`,
+		`{"endpoint":"/site-explorer/backlinks","event":"page","page":2,"rows":1500,"units":45}`,
+		`{"done":1,"event":"target","rows":1500,"target":"example.com","targets":2,"units":45}`,
+		`{"event":"done","exit_code":0,"ok":true,"requests":3,"rows":1500,"units":45}`,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d:\n%s", len(events), len(want), buf.String())
+	}
+	for i, e := range events {
+		got, _ := json.Marshal(e)
+		if string(got) != want[i] {
+			t.Errorf("event %d = %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestReporter_Bar(t *testing.T) {
+	var buf bytes.Buffer
+	report(New(&buf, FormatBar))
+
+	out := buf.String()
+	if !strings.HasSuffix(out, "\n") || strings.Count(out, "\n") != 1 {
+		t.Errorf("bar output = %q, want one line ended by the done event", out)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\r")[1:]
+	wantPrefixes := []string{
+		"page 1 · 1000 rows · 30 units",
+		"retrying /site-explorer/backlinks in 1s (attempt 2: rate limited (429))",
+		"page 2 · 1500 rows · 45 units",
+		"target 1/2 done (example.com) · 1500 rows · 45 units",
+		"done · 1500 rows · 45 units · 3 requests",
+	}
+	if len(lines) != len(wantPrefixes) {
+		t.Fatalf("bar wrote %d lines, want %d: %q", len(lines), len(wantPrefixes), out)
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, wantPrefixes[i]) {
+			t.Errorf("line %d = %q, want it to start with %q", i, line, wantPrefixes[i])
+		}
+	}
+	// The page line after the longer retry line blanks out its rest
+	if len([]rune(lines[2])) != len([]rune(lines[1])) {
+		t.Errorf("line %q doesn't cover %q", lines[2], lines[1])
+	}
+}
+
+func TestReporter_Off(t *testing.T) {
+	for _, format := range []string{"", "none"} {
+		if r := New(&bytes.Buffer{}, format); r != nil {
+			t.Errorf("New(%q) = %v, want nil", format, r)
+		}
+	}
+	// A nil reporter discards events
+	report(nil)
+}
+
+func TestDescribe_Failed(t *testing.T) {
+	ok := false
+	if got := describe(Event{Event: EventDone, OK: &ok}); !strings.HasPrefix(got, "failed") {
+		t.Errorf("describe() = %q, want a failure", got)
+	}
+}
@@ -0,0 +1,106 @@
+// Package publicsuffix finds the registrable domain of a host name, the part
+// a registrant controls: example.com for blog.example.com, and example.co.uk
+// for shop.example.co.uk.
+//
+// It carries the multi-label suffixes of the Public Suffix List that commonly
+// show up in link data rather than the whole list; any other host's suffix is
+// its last label.
+package publicsuffix
+
+import "strings"
+
+// suffixes are the public suffixes of more than one label. Private suffixes,
+// such as github.io, are included: sites under them belong to different
+// owners.
+var suffixes = map[string]bool{}
+
+func init() {
+	for _, list := range []string{
+		// United Kingdom
+		"co.uk org.uk me.uk ltd.uk plc.uk net.uk ac.uk gov.uk sch.uk nhs.uk police.uk",
+		// Australia and New Zealand
+		"com.au net.au org.au edu.au gov.au asn.au id.au co.nz net.nz org.nz ac.nz govt.nz school.nz",
+		// Asia
+		"co.jp ne.jp or.jp ac.jp go.jp gr.jp ed.jp ad.jp lg.jp " +
+			"co.kr or.kr ne.kr ac.kr go.kr re.kr " +
+			"com.cn net.cn org.cn gov.cn edu.cn ac.cn " +
+			"com.hk org.hk net.hk edu.hk gov.hk com.tw org.tw net.tw edu.tw gov.tw idv.tw " +
+			"co.in net.in org.in firm.in gen.in ind.in ac.in edu.in gov.in res.in " +
+			"com.sg net.sg org.sg edu.sg gov.sg com.my net.my org.my edu.my gov.my " +
+			"co.id or.id ac.id go.id web.id co.th in.th ac.th go.th or.th " +
+			"com.ph net.ph org.ph edu.ph gov.ph com.vn net.vn org.vn edu.vn gov.vn " +
+			"com.pk net.pk org.pk edu.pk gov.pk com.bd org.bd edu.bd gov.bd",
+		// Middle East and Africa
+		"co.il org.il ac.il gov.il net.il com.tr net.tr org.tr edu.tr gov.tr gen.tr " +
+			"com.sa net.sa org.sa edu.sa gov.sa co.ae net.ae org.ae gov.ae ac.ae " +
+			"com.eg edu.eg gov.eg co.za org.za net.za ac.za gov.za web.za " +
+			"co.ke or.ke ac.ke go.ke com.ng org.ng edu.ng gov.ng co.ma",
+		// Americas
+		"com.br net.br org.br edu.br gov.br art.br blog.br " +
+			"com.mx net.mx org.mx edu.mx gob.mx com.ar net.ar org.ar edu.ar gob.ar " +
+			"com.co net.co org.co edu.co gov.co com.pe net.pe org.pe edu.pe gob.pe " +
+			"com.ve co.ve com.uy edu.uy gub.uy com.ec com.bo com.py " +
+			"qc.ca on.ca bc.ca ab.ca",
+		// Europe
+		"com.pl net.pl org.pl edu.pl gov.pl co.at or.at ac.at gv.at " +
+			"com.es org.es nom.es edu.es gob.es com.pt org.pt edu.pt gov.pt " +
+			"com.gr edu.gr gov.gr com.ua net.ua org.ua edu.ua gov.ua " +
+			"com.ru net.ru org.ru co.hu org.hu com.ro org.ro com.cy co.it gov.it " +
+			"com.fr asso.fr gouv.fr co.no priv.no co.nl",
+		// Private suffixes whose subdomains are separate sites
+		"github.io gitlab.io blogspot.com wordpress.com tumblr.com substack.com medium.com " +
+			"herokuapp.com netlify.app vercel.app pages.dev workers.dev web.app firebaseapp.com " +
+			"appspot.com azurewebsites.net cloudfront.net s3.amazonaws.com wixsite.com " +
+			"myshopify.com squarespace.com weebly.com webflow.io glitch.me",
+	} {
+		for _, suffix := range strings.Fields(list) {
+			suffixes[suffix] = true
+		}
+	}
+}
+
+// normalize lowercases a host name and drops any trailing dot
+func normalize(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// PublicSuffix returns the public suffix of host: co.uk for example.co.uk,
+// and com for example.com
+func PublicSuffix(host string) string {
+	host = normalize(host)
+	// The longest known suffix wins, so check from the most labels down
+	for i := 0; i < len(host); i++ {
+		if i > 0 && host[i-1] != '.' {
+			continue
+		}
+		if suffixes[host[i:]] {
+			return host[i:]
+		}
+	}
+	return host[strings.LastIndex(host, ".")+1:]
+}
+
+// TLD returns the top-level domain of host, its last label: uk for
+// example.co.uk, and com for example.com
+func TLD(host string) string {
+	host = normalize(host)
+	return host[strings.LastIndex(host, ".")+1:]
+}
+
+// Registrable returns the registrable domain of host, its public suffix and
+// the label before it, or "" when host is itself a public suffix
+func Registrable(host string) string {
+	host = normalize(host)
+	suffix := PublicSuffix(host)
+	if len(host) <= len(suffix) {
+		return ""
+	}
+	rest := host[:len(host)-len(suffix)-1]
+	return rest[strings.LastIndex(rest, ".")+1:] + "." + suffix
+}
+
+// SameSite reports whether hosts a and b share a registrable domain
+func SameSite(a, b string) bool {
+	ra := Registrable(a)
+	return ra != "" && ra == Registrable(b)
+}
@@ -0,0 +1,86 @@
+package publicsuffix
+
+import "testing"
+
+func TestPublicSuffix(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "com"},
+		{"blog.example.com", "com"},
+		{"example.co.uk", "co.uk"},
+		{"shop.example.co.uk", "co.uk"},
+		{"co.uk", "co.uk"},
+		{"uk", "uk"},
+		{"Example.COM.AU.", "com.au"},
+		{"user.github.io", "github.io"},
+		{"example.unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := PublicSuffix(tt.host); got != tt.want {
+			t.Errorf("PublicSuffix(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestTLD(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "com"},
+		{"cs.stanford.edu", "edu"},
+		{"shop.example.co.uk", "uk"},
+		{"Example.GOV.", "gov"},
+		{"localhost", "localhost"},
+	}
+	for _, tt := range tests {
+		if got := TLD(tt.host); got != tt.want {
+			t.Errorf("TLD(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestRegistrable(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"a.b.example.com", "example.com"},
+		{"example.co.uk", "example.co.uk"},
+		{"shop.example.co.uk", "example.co.uk"},
+		{"www.bbc.co.uk", "bbc.co.uk"},
+		{"blog.example.com.br", "example.com.br"},
+		{"user.github.io", "user.github.io"},
+		{"docs.user.github.io", "user.github.io"},
+		{"co.uk", ""},
+		{"com", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Registrable(tt.host); got != tt.want {
+			t.Errorf("Registrable(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestSameSite(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"example.co.uk", "shop.example.co.uk", true},
+		{"example.co.uk", "other.co.uk", false},
+		{"example.co.uk", "example.com", false},
+		{"blog.example.com", "EXAMPLE.com.", true},
+		{"alice.github.io", "bob.github.io", false},
+		{"co.uk", "co.uk", false},
+	}
+	for _, tt := range tests {
+		if got := SameSite(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameSite(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package redact masks secrets, such as the API key, in the URLs, logs, and
+// errors the CLI prints, so diagnostics can be pasted into an issue as they
+// are.
+package redact
+
+import (
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Mask replaces each secret
+const Mask = "***"
+
+// MinLength is the length below which a secret is left unmasked: masking a
+// few characters would mangle ordinary text without hiding a real key
+const MinLength = 6
+
+// Scrub returns s with every occurrence of each secret, as is or
+// URL-encoded, masked
+func Scrub(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if len(secret) < MinLength {
+			continue
+		}
+		s = strings.ReplaceAll(s, secret, Mask)
+		if escaped := url.QueryEscape(secret); escaped != secret {
+			s = strings.ReplaceAll(s, escaped, Mask)
+		}
+	}
+	return s
+}
+
+// Writer returns a writer scrubbing secrets from what is written to w, or w
+// itself when there is none to mask. Each write is scrubbed on its own, so
+// lines must be written whole, as loggers and fmt.Fprintf do.
+func Writer(w io.Writer, secrets ...string) io.Writer {
+	var masked []string
+	for _, secret := range secrets {
+		if len(secret) >= MinLength {
+			masked = append(masked, secret)
+		}
+	}
+	if len(masked) == 0 {
+		return w
+	}
+	return &writer{w: w, secrets: masked}
+}
+
+// writer is a scrubbing io.Writer
+type writer struct {
+	w       io.Writer
+	secrets []string
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, Scrub(string(p), w.secrets...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,49 @@
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestScrub(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		secrets []string
+		want    string
+	}{
+		{"header", "Authorization: Bearer sk_live_abc123", []string{"sk_live_abc123"}, "Authorization: Bearer ***"},
+		{"every occurrence", "sk_live_abc123 and sk_live_abc123", []string{"sk_live_abc123"}, "*** and ***"},
+		{"URL-encoded", "GET /v3?key=sk%2Blive%2Fabc", []string{"sk+live/abc"}, "GET /v3?key=***"},
+		{"several", "a=first-secret b=second-secret", []string{"first-secret", "second-secret"}, "a=*** b=***"},
+		{"too short", "the key k is everywhere", []string{"k"}, "the key k is everywhere"},
+		{"none", "nothing to hide", nil, "nothing to hide"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Scrub(tt.s, tt.secrets...); got != tt.want {
+				t.Errorf("Scrub() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := Writer(&buf, "sk_live_abc123", "")
+	n, err := fmt.Fprintf(w, "Error: API error (401): invalid key %s\n", "sk_live_abc123")
+	if err != nil {
+		t.Fatalf("Fprintf() error = %v", err)
+	}
+	if want := "Error: API error (401): invalid key ***\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+	if n != len("Error: API error (401): invalid key sk_live_abc123\n") {
+		t.Errorf("Fprintf() = %d, want the length written", n)
+	}
+
+	if w := Writer(&buf, "", "short"); w != &buf {
+		t.Error("Writer() without a secret to mask should return w")
+	}
+}
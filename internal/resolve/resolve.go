@@ -0,0 +1,135 @@
+// Package resolve looks up the IPv4 addresses of domains in DNS, several
+// at once, and each domain only once
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// LookupFunc returns the IP addresses of host
+type LookupFunc func(ctx context.Context, host string) ([]net.IP, error)
+
+// Resolver resolves domains to an IPv4 address, keeping the answers, and the
+// failures, for the resolver's lifetime
+type Resolver struct {
+	// Lookup queries DNS; nil uses the system resolver
+	Lookup LookupFunc
+
+	// Timeout bounds each lookup; 0 for none
+	Timeout time.Duration
+
+	// Concurrency is the most lookups in flight at once
+	Concurrency int
+
+	mu    sync.Mutex
+	cache map[string]string // a domain's address, or "" when it has none
+}
+
+// New returns a resolver querying the system's DNS
+func New(timeout time.Duration, concurrency int) *Resolver {
+	return &Resolver{Timeout: timeout, Concurrency: concurrency}
+}
+
+// Resolve returns the address of each of domains that resolved. A domain
+// failing to, for want of an A record or in time, is left out rather than
+// failing the others. It returns ctx's error if it's canceled first.
+func (r *Resolver) Resolve(ctx context.Context, domains []string) (map[string]string, error) {
+	var todo []string
+	seen := map[string]bool{}
+	r.mu.Lock()
+	if r.cache == nil {
+		r.cache = map[string]string{}
+	}
+	for _, d := range domains {
+		if _, ok := r.cache[d]; !ok && !seen[d] {
+			seen[d] = true
+			todo = append(todo, d)
+		}
+	}
+	r.mu.Unlock()
+
+	sem := make(chan struct{}, max(r.Concurrency, 1))
+	var wg sync.WaitGroup
+start:
+	for _, d := range todo {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break start
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			ip := r.lookup(ctx, d)
+			if ctx.Err() != nil {
+				// Canceled rather than failed; not worth keeping
+				return
+			}
+			r.mu.Lock()
+			r.cache[d] = ip
+			r.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ips := map[string]string{}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range domains {
+		if ip := r.cache[d]; ip != "" {
+			ips[d] = ip
+		}
+	}
+	return ips, nil
+}
+
+// lookup returns the lowest IPv4 address of domain, so the same one is
+// picked from round-robin answers, or "" when it has none
+func (r *Resolver) lookup(ctx context.Context, domain string) string {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	lookup := r.Lookup
+	if lookup == nil {
+		lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip4", host)
+		}
+	}
+	addrs, err := lookup(ctx, domain)
+	if err != nil {
+		return ""
+	}
+	var lowest net.IP
+	for _, a := range addrs {
+		if v4 := a.To4(); v4 != nil && (lowest == nil || bytes.Compare(v4, lowest) < 0) {
+			lowest = v4
+		}
+	}
+	if lowest == nil {
+		return ""
+	}
+	return lowest.String()
+}
+
+// Subnet returns the /24 subnet of the IPv4 address ip, such as
+// 203.0.113.0/24 for 203.0.113.7, or "" if ip isn't one
+func Subnet(ip string) string {
+	v4 := net.ParseIP(ip).To4()
+	if v4 == nil {
+		return ""
+	}
+	network := net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}
+	return network.String()
+}
@@ -0,0 +1,148 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDNS answers lookups from records, counting them and the most in
+// flight at once
+type fakeDNS struct {
+	records map[string][]string
+	delay   time.Duration
+
+	mu       sync.Mutex
+	calls    map[string]int
+	inFlight atomic.Int32
+	most     atomic.Int32
+}
+
+func (d *fakeDNS) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	d.mu.Lock()
+	if d.calls == nil {
+		d.calls = map[string]int{}
+	}
+	d.calls[host]++
+	d.mu.Unlock()
+
+	n := d.inFlight.Add(1)
+	defer d.inFlight.Add(-1)
+	for {
+		most := d.most.Load()
+		if n <= most || d.most.CompareAndSwap(most, n) {
+			break
+		}
+	}
+	select {
+	case <-time.After(d.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	addrs, ok := d.records[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	var ips []net.IP
+	for _, a := range addrs {
+		ips = append(ips, net.ParseIP(a))
+	}
+	return ips, nil
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	dns := &fakeDNS{records: map[string][]string{
+		"a.com":  {"203.0.113.9", "203.0.113.7"},
+		"b.com":  {"2001:db8::1", "198.51.100.4"},
+		"v6.com": {"2001:db8::2"},
+	}}
+	r := &Resolver{Lookup: dns.lookup, Concurrency: 2}
+
+	got, err := r.Resolve(context.Background(), []string{"a.com", "b.com", "a.com", "missing.com", "v6.com"})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := map[string]string{"a.com": "203.0.113.7", "b.com": "198.51.100.4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() = %v, want %v", got, want)
+	}
+
+	// Answers, and failures, are kept for the next call
+	if _, err := r.Resolve(context.Background(), []string{"a.com", "missing.com", "c.com"}); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	for host, n := range dns.calls {
+		if n != 1 {
+			t.Errorf("%s looked up %d times, want once", host, n)
+		}
+	}
+}
+
+func TestResolver_Concurrency(t *testing.T) {
+	dns := &fakeDNS{records: map[string][]string{}, delay: 5 * time.Millisecond}
+	var domains []string
+	for i := 0; i < 20; i++ {
+		domain := string(rune('a'+i)) + ".com"
+		dns.records[domain] = []string{"192.0.2.1"}
+		domains = append(domains, domain)
+	}
+	r := &Resolver{Lookup: dns.lookup, Concurrency: 3}
+
+	got, err := r.Resolve(context.Background(), domains)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(got) != 20 {
+		t.Errorf("Resolve() resolved %d domains, want 20", len(got))
+	}
+	if most := dns.most.Load(); most > 3 {
+		t.Errorf("%d lookups in flight at once, want at most 3", most)
+	}
+}
+
+func TestResolver_Timeout(t *testing.T) {
+	dns := &fakeDNS{records: map[string][]string{"slow.com": {"192.0.2.1"}}, delay: time.Minute}
+	r := &Resolver{Lookup: dns.lookup, Timeout: 10 * time.Millisecond, Concurrency: 1}
+
+	got, err := r.Resolve(context.Background(), []string{"slow.com"})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v, want the timeout tolerated", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Resolve() = %v, want nothing resolved in time", got)
+	}
+}
+
+func TestResolver_Canceled(t *testing.T) {
+	dns := &fakeDNS{records: map[string][]string{"slow.com": {"192.0.2.1"}}, delay: time.Minute}
+	r := &Resolver{Lookup: dns.lookup, Concurrency: 1}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.Resolve(ctx, []string{"slow.com"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Resolve() error = %v, want the context's", err)
+	}
+}
+
+func TestSubnet(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.7", "203.0.113.0/24"},
+		{"10.0.0.255", "10.0.0.0/24"},
+		{"2001:db8::1", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Subnet(tt.ip); got != tt.want {
+			t.Errorf("Subnet(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Package rowfile reads back the rows of output the CLI saved, as JSON,
+// NDJSON, or CSV, with every value as the text of a CSV cell
+package rowfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats of saved output
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// File is the rows of a saved output
+type File struct {
+	// Columns are the fields of the rows: in header order for CSV, sorted
+	// for JSON
+	Columns []string
+
+	// Rows map each column to its value. Fields a row lacks are empty.
+	Rows []map[string]string
+
+	columns map[string]bool // Columns, as a set, while JSON rows are added
+}
+
+// HasColumn reports whether the rows have the field name
+func (f *File) HasColumn(name string) bool {
+	for _, c := range f.Columns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Read reads the saved output in path, its format told by its extension,
+// or by its content for other extensions
+func Read(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(data, DetectFormat(path, data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// DetectFormat returns the format of saved output from its file name:
+// .json, .ndjson or .jsonl, and .csv. Otherwise data starting with a JSON
+// object or array is JSON, or NDJSON when it isn't a single value, and
+// anything else CSV.
+func DetectFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".csv":
+		return FormatCSV
+	}
+
+	trimmed := bytes.TrimSpace(trimBOM(data))
+	if len(trimmed) == 0 || trimmed[0] != '{' && trimmed[0] != '[' {
+		return FormatCSV
+	}
+	if json.Valid(trimmed) {
+		return FormatJSON
+	}
+	return FormatNDJSON
+}
+
+// Parse reads the rows of saved output in format
+func Parse(data []byte, format string) (*File, error) {
+	data = trimBOM(data)
+	switch format {
+	case FormatJSON:
+		return parseJSON(data)
+	case FormatNDJSON:
+		return parseNDJSON(data)
+	case FormatCSV:
+		return parseCSV(data)
+	}
+	return nil, fmt.Errorf("unsupported format %q (supported: json, ndjson, csv)", format)
+}
+
+// parseJSON reads the rows of JSON output. The rows are those of the data
+// of a {"status": ..., "data": ...} response, or of a bare response: the
+// first list of the object, by name, or the object itself when it has none.
+func parseJSON(data []byte) (*File, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON: unexpected data after the output; use a .ndjson file name for a row per line")
+	}
+
+	if obj, ok := v.(map[string]interface{}); ok {
+		if obj["status"] == "error" {
+			return nil, errors.New("the output is an error response, without rows")
+		}
+		if data, ok := obj["data"]; ok && obj["status"] != nil {
+			v = data
+		}
+	}
+	rows := rowsOf(v)
+	f := &File{}
+	for i, row := range rows {
+		obj, ok := row.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("row %d is not an object", i+1)
+		}
+		f.add(obj)
+	}
+	sort.Strings(f.Columns)
+	return f, nil
+}
+
+// rowsOf returns the rows of v: v itself for a list, the first list of an
+// object by name, or the object as a single row
+func rowsOf(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if list, ok := v[name].([]interface{}); ok {
+				return list
+			}
+		}
+		return []interface{}{v}
+	case nil:
+		return nil
+	}
+	return []interface{}{v}
+}
+
+// parseNDJSON reads NDJSON output, a row per line. Lines with only the
+// response meta or errors, which some commands write after the rows, are
+// left out.
+func parseNDJSON(data []byte) (*File, error) {
+	f := &File{}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		var obj map[string]interface{}
+		if err := dec.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON object: %w", i+1, err)
+		}
+		if isTrailer(obj) {
+			continue
+		}
+		f.add(obj)
+	}
+	sort.Strings(f.Columns)
+	return f, nil
+}
+
+// isTrailer reports whether an NDJSON line holds only the meta and errors
+// of the output rather than a row
+func isTrailer(obj map[string]interface{}) bool {
+	for name := range obj {
+		if name != "meta" && name != "errors" {
+			return false
+		}
+	}
+	return len(obj) > 0
+}
+
+// parseCSV reads CSV output, whose first record is the header
+func parseCSV(data []byte) (*File, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	header, err := r.Read()
+	if err == io.EOF {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, name := range header {
+		if seen[name] {
+			return nil, fmt.Errorf("invalid CSV: the header has %q twice", name)
+		}
+		seen[name] = true
+	}
+
+	f := &File{Columns: header}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			row[name] = record[i]
+		}
+		f.Rows = append(f.Rows, row)
+	}
+	return f, nil
+}
+
+// add adds the JSON object obj as a row
+func (f *File) add(obj map[string]interface{}) {
+	if f.columns == nil {
+		f.columns = map[string]bool{}
+	}
+	row := make(map[string]string, len(obj))
+	for name, v := range obj {
+		if !f.columns[name] {
+			f.columns[name] = true
+			f.Columns = append(f.Columns, name)
+		}
+		row[name] = Text(v)
+	}
+	f.Rows = append(f.Rows, row)
+}
+
+// Text returns a decoded JSON value as CSV output writes it, so rows read
+// from JSON and from CSV compare equal: lists are joined with commas, and
+// objects are written as JSON.
+func Text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = Text(item)
+		}
+		return strings.Join(items, ",")
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// trimBOM removes the byte order mark spreadsheets add to CSV they save
+func trimBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+}
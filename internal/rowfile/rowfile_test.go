@@ -0,0 +1,130 @@
+package rowfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	// The same two rows, as each format writes them
+	want := &File{
+		Columns: []string{"anchor", "domain_rating", "is_dofollow", "url_from"},
+		Rows: []map[string]string{
+			{"anchor": "a, b", "domain_rating": "45.5", "is_dofollow": "true", "url_from": "https://a.example/"},
+			{"anchor": "", "domain_rating": "10", "is_dofollow": "false", "url_from": "https://b.example/"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"json", FormatJSON, `{"status":"success","data":{"backlinks":[{"url_from":"https://a.example/","anchor":"a, b","domain_rating":45.5,"is_dofollow":true},{"url_from":"https://b.example/","anchor":null,"domain_rating":10,"is_dofollow":false}]},"meta":{"response_time_ms":12}}`},
+		{"bare json", FormatJSON, `[{"url_from":"https://a.example/","anchor":"a, b","domain_rating":45.5,"is_dofollow":true},{"url_from":"https://b.example/","anchor":"","domain_rating":10,"is_dofollow":false}]`},
+		{"ndjson", FormatNDJSON, "{\"url_from\":\"https://a.example/\",\"anchor\":\"a, b\",\"domain_rating\":45.5,\"is_dofollow\":true}\n\n{\"url_from\":\"https://b.example/\",\"anchor\":null,\"domain_rating\":10,\"is_dofollow\":false}\n{\"meta\":{\"units_consumed\":50}}\n"},
+		{"csv", FormatCSV, "\xef\xbb\xbfanchor,domain_rating,is_dofollow,url_from\n\"a, b\",45.5,true,https://a.example/\n,10,false,https://b.example/\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data), tt.format)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got.columns = nil
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Parse() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParse_Values(t *testing.T) {
+	got, err := Parse([]byte(`{"data":{"keywords":[{"keyword":"seo","serp_features":["snippet","video"],"position":{"top":1}}]},"status":"success"}`), FormatJSON)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := map[string]string{"keyword": "seo", "serp_features": "snippet,video", "position": `{"top":1}`}
+	if !reflect.DeepEqual(got.Rows[0], want) {
+		t.Errorf("row = %v, want %v", got.Rows[0], want)
+	}
+
+	// A single object is a single row
+	got, err = Parse([]byte(`{"status":"success","data":{"domain_rating":{"domain_rating":71,"ahrefs_rank":1200}}}`), FormatJSON)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(got.Rows) != 1 || got.Rows[0]["domain_rating"] != `{"ahrefs_rank":1200,"domain_rating":71}` {
+		t.Errorf("rows = %v, want the object as a row", got.Rows)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   string
+	}{
+		{"error response", FormatJSON, `{"status":"error","error":{"code":"AUTH_ERROR"}}`, "error response"},
+		{"rows per line", FormatJSON, "{\"a\":1}\n{\"a\":2}\n", ".ndjson"},
+		{"rows that aren't objects", FormatJSON, `[1,2]`, "row 1 is not an object"},
+		{"bad line", FormatNDJSON, "{\"a\":1}\n{\"a\":\n", "line 2"},
+		{"ragged csv", FormatCSV, "a,b\n1,2\n3\n", "wrong number of fields"},
+		{"repeated column", FormatCSV, "a,a\n1,2\n", `"a" twice`},
+		{"unknown format", "yaml", "a: 1", "unsupported format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), tt.format)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		path string
+		data string
+		want string
+	}{
+		{"out.json", "", FormatJSON},
+		{"out.JSONL", "", FormatNDJSON},
+		{"out.ndjson", "", FormatNDJSON},
+		{"out.csv", "{", FormatCSV},
+		{"out.txt", `{"status":"success","data":[]}`, FormatJSON},
+		{"out.txt", "{\"a\":1}\n{\"a\":2}\n", FormatNDJSON},
+		{"out", "a,b\n1,2\n", FormatCSV},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.path, []byte(tt.data)); got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) = %s, want %s", tt.path, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export")
+	if err := os.WriteFile(path, []byte("{\"a\":1}\n{\"a\":2}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(f.Rows) != 2 || !f.HasColumn("a") {
+		t.Errorf("Read() = %+v, want two rows with a", f)
+	}
+
+	if err := os.WriteFile(path, []byte("a,b\n1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil || !strings.HasPrefix(err.Error(), path+": ") {
+		t.Errorf("Read() error = %v, want one naming the file", err)
+	}
+}
@@ -0,0 +1,200 @@
+// Package schedule checks the cron expressions of scheduled jobs, writes
+// the crontab lines running them, and keeps the outcome of their last runs
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+)
+
+// StateFileName is the name of the last runs file inside the cache
+// directory, keyed by job name
+const StateFileName = "schedule-state.json"
+
+// validName matches job names, which are written unquoted in crontab lines
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateName returns an error when name can't name a job
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid job name %q: use letters, digits, '.', '_', and '-'", name)
+	}
+	return nil
+}
+
+// cronField is a field of a cron expression: its name, its range, and the
+// names its values may go by, from its minimum up
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+// cronFields are the five fields of a cron expression, in order
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// cronMacros are the shorthands cron accepts in place of the five fields
+var cronMacros = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+// ValidateCron returns an error when expr isn't a cron schedule: five
+// fields of values, ranges, steps, and lists, or a macro such as @daily
+func ValidateCron(expr string) error {
+	fields := strings.Fields(expr)
+	if len(fields) == 1 && strings.HasPrefix(fields[0], "@") {
+		for _, m := range cronMacros {
+			if fields[0] == m {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid cron expression %q: unknown macro (supported: %s)", expr, strings.Join(cronMacros, ", "))
+	}
+	if len(fields) != len(cronFields) {
+		return fmt.Errorf("invalid cron expression %q: want 5 fields (minute hour day-of-month month day-of-week), got %d", expr, len(fields))
+	}
+	for i, f := range cronFields {
+		for _, item := range strings.Split(fields[i], ",") {
+			if err := f.check(item); err != nil {
+				return fmt.Errorf("invalid cron expression %q: %s: %w", expr, f.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// check returns an error when item isn't a value of f, a range of them, or
+// either with a /step
+func (f cronField) check(item string) error {
+	span, step, stepped := strings.Cut(item, "/")
+	if stepped {
+		n, err := strconv.Atoi(step)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid step %q", step)
+		}
+	}
+	if span == "*" {
+		return nil
+	}
+	lo, hi, ranged := strings.Cut(span, "-")
+	from, err := f.value(lo)
+	if err != nil {
+		return err
+	}
+	if !ranged {
+		return nil
+	}
+	to, err := f.value(hi)
+	if err != nil {
+		return err
+	}
+	if to < from {
+		return fmt.Errorf("range %q ends before it starts", span)
+	}
+	return nil
+}
+
+// value returns the number s stands for in f, by number or name
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%q is not a value from %d to %d", s, f.min, f.max)
+	}
+	return n, nil
+}
+
+// CrontabLine returns the crontab line running the job name on the schedule
+// expr with the ahrefs binary at executable
+func CrontabLine(expr, executable, name string) string {
+	return strings.Join(strings.Fields(expr), " ") + " " + crontabQuote(executable) + " schedule run " + name
+}
+
+// crontabQuote quotes s for the shell crontab lines run in, escaping the %
+// cron turns into newlines
+func crontabQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789/._-+") == "" {
+		return s
+	}
+	quoted := "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	return strings.ReplaceAll(quoted, "%", `\%`)
+}
+
+// Run is the outcome of a job's last run, from the exit summary of the
+// command it ran
+type Run struct {
+	Time          time.Time `json:"time"`
+	OK            bool      `json:"ok"`
+	ExitCode      int       `json:"exit_code"`
+	Rows          int       `json:"rows"`
+	UnitsConsumed int       `json:"units_consumed"`
+	DurationMS    int64     `json:"duration_ms"`
+}
+
+// State is the last run of each job, by name
+type State map[string]Run
+
+// StatePath returns the path to the last runs file
+func StatePath() (string, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, StateFileName), nil
+}
+
+// LoadState reads the last runs file; a missing file is an empty state
+func LoadState() (State, error) {
+	path, err := StatePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule state: %w", err)
+	}
+
+	state := State{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// Save writes the last runs file
+func (s State) Save() error {
+	path, err := StatePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write schedule state: %w", err)
+	}
+	return nil
+}
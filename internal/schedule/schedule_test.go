@@ -0,0 +1,90 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateCron(t *testing.T) {
+	for _, expr := range []string{
+		"0 7 * * 1",
+		"*/15 * * * *",
+		"0 9-17/2 1,15 jan-jun mon-fri",
+		"30 2 * * 7",
+		"@daily",
+	} {
+		if err := ValidateCron(expr); err != nil {
+			t.Errorf("ValidateCron(%q) error = %v", expr, err)
+		}
+	}
+
+	for _, expr := range []string{
+		"",
+		"0 7 * *",
+		"0 7 * * 1 2",
+		"60 * * * *",
+		"0 24 * * *",
+		"0 0 0 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"0 0 * * funday",
+		"@often",
+	} {
+		if err := ValidateCron(expr); err == nil {
+			t.Errorf("ValidateCron(%q) should fail", expr)
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"weekly-links", "dr.daily", "job_2"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "-flag", "two words", "a;b", "50%"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) should fail", name)
+		}
+	}
+}
+
+func TestCrontabLine(t *testing.T) {
+	tests := []struct {
+		expr, executable, want string
+	}{
+		{"0 7 * * 1", "/usr/local/bin/ahrefs", "0 7 * * 1 /usr/local/bin/ahrefs schedule run weekly"},
+		{" 0  7 * * 1 ", "/opt/my tools/ahrefs", "0 7 * * 1 '/opt/my tools/ahrefs' schedule run weekly"},
+		{"@daily", "/home/o'neil/100%/ahrefs", `@daily '/home/o'\''neil/100\%/ahrefs' schedule run weekly`},
+	}
+	for _, tt := range tests {
+		if got := CrontabLine(tt.expr, tt.executable, "weekly"); got != tt.want {
+			t.Errorf("CrontabLine(%q, %q) = %q, want %q", tt.expr, tt.executable, got, tt.want)
+		}
+	}
+}
+
+func TestState_SaveLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	state, err := LoadState()
+	if err != nil || len(state) != 0 {
+		t.Fatalf("LoadState() = %v, %v, want an empty state", state, err)
+	}
+	state["weekly"] = Run{Time: time.Date(2024, 6, 3, 7, 0, 0, 0, time.UTC), OK: true, Rows: 120, UnitsConsumed: 600, DurationMS: 4200}
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, state) {
+		t.Errorf("LoadState() = %+v, want %+v", got, state)
+	}
+}
@@ -0,0 +1,115 @@
+// Package sqlite runs SQL against a SQLite database file through the sqlite3
+// command-line shell, which keeps the CLI free of cgo and driver
+// dependencies. Statements are piped on stdin; queries are read back with
+// the shell's JSON output mode (sqlite3 3.33 or later).
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Binary is the sqlite3 shell looked up on PATH
+var Binary = "sqlite3"
+
+// DB is a database file
+type DB struct {
+	path  string
+	shell string
+}
+
+// Open returns the database at path, which is created on first write. It
+// fails if the sqlite3 shell is not installed.
+func Open(path string) (*DB, error) {
+	shell, err := exec.LookPath(Binary)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found on PATH; install SQLite 3.33 or later", Binary)
+	}
+	return &DB{path: path, shell: shell}, nil
+}
+
+// Exec runs a script of semicolon-terminated statements, stopping at the
+// first error. A transaction left open by a failed script is rolled back.
+func (db *DB) Exec(ctx context.Context, script string) error {
+	_, err := db.run(ctx, script)
+	return err
+}
+
+// Query runs a single SELECT and returns its rows keyed by column name.
+// Numbers are returned as json.Number.
+func (db *DB) Query(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	out, err := db.run(ctx, ".mode json\n"+query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []map[string]interface{}{}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return rows, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.UseNumber()
+	if err := dec.Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to parse sqlite3 output: %w", err)
+	}
+	return rows, nil
+}
+
+func (db *DB) run(ctx context.Context, script string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, db.shell, "-bail", "-batch", db.path)
+	c.Stdin = strings.NewReader(script)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	if err := c.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		var exitErr *exec.ExitError
+		if msg == "" || !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("sqlite3 %s: %w", db.path, err)
+		}
+		return nil, fmt.Errorf("sqlite3 %s: %s", db.path, msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Quote returns v as a SQL literal. Strings are single-quoted, json.Number
+// and Go numbers are written as is, bools become 0 or 1, and nil is NULL.
+// Other values are stored as their JSON text.
+func Quote(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case json.Number:
+		return v.String()
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "NULL"
+	}
+	return Quote(string(data))
+}
+
+// Ident returns name as a quoted SQL identifier
+func Ident(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
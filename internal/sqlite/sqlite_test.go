@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, "NULL"},
+		{"it's", "'it''s'"},
+		{json.Number("12345678901234"), "12345678901234"},
+		{42, "42"},
+		{1.5, "1.5"},
+		{true, "1"},
+		{[]string{"a"}, `'["a"]'`},
+	}
+
+	for _, tt := range tests {
+		if got := Quote(tt.v); got != tt.want {
+			t.Errorf("Quote(%#v) = %s, want %s", tt.v, got, tt.want)
+		}
+	}
+
+	if got := Ident(`we"ird`); got != `"we""ird"` {
+		t.Errorf("Ident() = %s", got)
+	}
+}
+
+func TestDB(t *testing.T) {
+	if _, err := exec.LookPath(Binary); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if err := db.Exec(ctx, "CREATE TABLE t (k TEXT PRIMARY KEY, n INTEGER);\nINSERT INTO t VALUES ('a', 1), ('b', 2);\n"); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	rows, err := db.Query(ctx, "SELECT k, n FROM t ORDER BY k;")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(rows) != 2 || rows[1]["k"] != "b" || rows[1]["n"] != json.Number("2") {
+		t.Errorf("Query() = %v", rows)
+	}
+
+	if rows, err := db.Query(ctx, "SELECT k FROM t WHERE n > 5;"); err != nil || len(rows) != 0 {
+		t.Errorf("Query() with no rows = %v, %v, want empty", rows, err)
+	}
+	if err := db.Exec(ctx, "INSERT INTO nope VALUES (1);"); err == nil {
+		t.Error("Exec() with a bad statement error = nil, want error")
+	}
+}
+
+func TestOpen_MissingShell(t *testing.T) {
+	old := Binary
+	Binary = "sqlite3-not-installed"
+	defer func() { Binary = old }()
+
+	if _, err := Open("x.db"); err == nil {
+		t.Error("Open() without the shell error = nil, want error")
+	}
+}
@@ -0,0 +1,287 @@
+package textnorm
+
+import (
+	"slices"
+	"unicode/utf8"
+)
+
+// compositions are the letters composed of a base and a combining mark, for
+// the marks of Latin, Greek, and Cyrillic letters. Each lists the mark, its
+// canonical combining class, the bases it composes with, and in the same
+// order the letters they compose. Letters composed of others, such as ệ of ẹ
+// and a circumflex, decompose in turn.
+var compositions = []struct {
+	mark     rune
+	class    uint8
+	bases    string
+	composed string
+}{
+	// Grave accent
+	{0x0300, 230,
+		"AEIOUaeiouÜüNnЕИеиĒēŌōWwÂâĂăÊêÔôƠơƯưYyἀἁἈἉἐἑἘἙἠἡἨἩἰἱἸἹὀὁὈὉὐὑὙὠὡὨὩαεηιουωΑΕΗ᾿ϊΙ῾ϋΥ¨ΟΩ",
+		"ÀÈÌÒÙàèìòùǛǜǸǹЀЍѐѝḔḕṐṑẀẁẦầẰằỀềỒồỜờỪừỲỳἂἃἊἋἒἓἚἛἢἣἪἫἲἳἺἻὂὃὊὋὒὓὛὢὣὪὫὰὲὴὶὸὺὼᾺῈῊ῍ῒῚ῝ῢῪ῭ῸῺ"},
+	// Acute accent
+	{0x0301, 230,
+		"AEIOUYaeiouyCcLlNnRrSsZzÜüGgÅåÆæØø¨ΑΕΗΙΟΥΩϊαεηιϋουωϒГКгкÇçĒēÏïKkMmÕõŌōPpŨũWwÂâĂăÊêÔôƠơƯưἀἁἈἉἐἑἘἙἠἡἨἩἰἱἸἹὀὁὈὉὐὑὙὠὡὨὩ᾿῾",
+		"ÁÉÍÓÚÝáéíóúýĆćĹĺŃńŔŕŚśŹźǗǘǴǵǺǻǼǽǾǿ΅ΆΈΉΊΌΎΏΐάέήίΰόύώϓЃЌѓќḈḉḖḗḮḯḰḱḾḿṌṍṒṓṔṕṸṹẂẃẤấẮắẾếỐốỚớỨứἄἅἌἍἔἕἜἝἤἥἬἭἴἵἼἽὄὅὌὍὔὕὝὤὥὬὭ῎῞"},
+	// Circumflex accent
+	{0x0302, 230,
+		"AEIOUaeiouCcGgHhJjSsWwYyZzẠạẸẹỌọ",
+		"ÂÊÎÔÛâêîôûĈĉĜĝĤĥĴĵŜŝŴŵŶŷẐẑẬậỆệỘộ"},
+	// Tilde
+	{0x0303, 230,
+		"ANOanoIiUuVvÂâĂăEeÊêÔôƠơƯưYy",
+		"ÃÑÕãñõĨĩŨũṼṽẪẫẴẵẼẽỄễỖỗỠỡỮữỸỹ"},
+	// Macron
+	{0x0304, 230,
+		"AaEeIiOoUuÜüÄäȦȧÆæǪǫÖöÕõȮȯYyИиУуGgḶḷṚṛαΑιΙυΥ",
+		"ĀāĒēĪīŌōŪūǕǖǞǟǠǡǢǣǬǭȪȫȬȭȰȱȲȳӢӣӮӯḠḡḸḹṜṝᾱᾹῑῙῡῩ"},
+	// Breve
+	{0x0306, 230,
+		"AaEeGgIiOoUuУИиуЖжАаЕеȨȩẠạαΑιΙυΥ",
+		"ĂăĔĕĞğĬĭŎŏŬŭЎЙйўӁӂӐӑӖӗḜḝẶặᾰᾸῐῘῠῨ"},
+	// Dot above
+	{0x0307, 230,
+		"CcEeGgIZzAaOoBbDdFfHhMmNnPpRrSsŚśŠšṢṣTtWwXxYyſ",
+		"ĊċĖėĠġİŻżȦȧȮȯḂḃḊḋḞḟḢḣṀṁṄṅṖṗṘṙṠṡṤṥṦṧṨṩṪṫẆẇẊẋẎẏẛ"},
+	// Diaeresis
+	{0x0308, 230,
+		"AEIOUaeiouyYΙΥιυϒЕІеіАаӘәЖжЗзИиОоӨөЭэУуЧчЫыHhÕõŪūWwXxt",
+		"ÄËÏÖÜäëïöüÿŸΪΫϊϋϔЁЇёїӒӓӚӛӜӝӞӟӤӥӦӧӪӫӬӭӰӱӴӵӸӹḦḧṎṏṺṻẄẅẌẍẗ"},
+	// Hook above
+	{0x0309, 230,
+		"AaÂâĂăEeÊêIiOoÔôƠơUuƯưYy",
+		"ẢảẨẩẲẳẺẻỂểỈỉỎỏỔổỞởỦủỬửỶỷ"},
+	// Ring above
+	{0x030A, 230,
+		"AaUuwy",
+		"ÅåŮůẘẙ"},
+	// Double acute accent
+	{0x030B, 230,
+		"OoUuУу",
+		"ŐőŰűӲӳ"},
+	// Caron
+	{0x030C, 230,
+		"CcDdEeLlNnRrSsTtZzAaIiOoUuÜüGgKkƷʒjHh",
+		"ČčĎďĚěĽľŇňŘřŠšŤťŽžǍǎǏǐǑǒǓǔǙǚǦǧǨǩǮǯǰȞȟ"},
+	// Double grave accent
+	{0x030F, 230,
+		"AaEeIiOoRrUuѴѵ",
+		"ȀȁȄȅȈȉȌȍȐȑȔȕѶѷ"},
+	// Inverted breve
+	{0x0311, 230,
+		"AaEeIiOoRrUu",
+		"ȂȃȆȇȊȋȎȏȒȓȖȗ"},
+	// Comma above
+	{0x0313, 230,
+		"αΑεΕηΗιΙοΟυωΩρ",
+		"ἀἈἐἘἠἨἰἸὀὈὐὠὨῤ"},
+	// Reversed comma above
+	{0x0314, 230,
+		"αΑεΕηΗιΙοΟυΥωΩρΡ",
+		"ἁἉἑἙἡἩἱἹὁὉὑὙὡὩῥῬ"},
+	// Horn
+	{0x031B, 216,
+		"OoUu",
+		"ƠơƯư"},
+	// Dot below
+	{0x0323, 220,
+		"BbDdHhKkLlMmNnRrSsTtVvWwZzAaEeIiOoƠơUuƯưYy",
+		"ḄḅḌḍḤḥḲḳḶḷṂṃṆṇṚṛṢṣṬṭṾṿẈẉẒẓẠạẸẹỊịỌọỢợỤụỰựỴỵ"},
+	// Diaeresis below
+	{0x0324, 220,
+		"Uu",
+		"Ṳṳ"},
+	// Ring below
+	{0x0325, 220,
+		"Aa",
+		"Ḁḁ"},
+	// Comma below
+	{0x0326, 220,
+		"SsTt",
+		"ȘșȚț"},
+	// Cedilla
+	{0x0327, 202,
+		"CcGgKkLlNnRrSsTtEeDdHh",
+		"ÇçĢģĶķĻļŅņŖŗŞşŢţȨȩḐḑḨḩ"},
+	// Ogonek
+	{0x0328, 202,
+		"AaEeIiUuOo",
+		"ĄąĘęĮįŲųǪǫ"},
+	// Circumflex accent below
+	{0x032D, 220,
+		"DdEeLlNnTtUu",
+		"ḒḓḘḙḼḽṊṋṰṱṶṷ"},
+	// Breve below
+	{0x032E, 220,
+		"Hh",
+		"Ḫḫ"},
+	// Tilde below
+	{0x0330, 220,
+		"EeIiUu",
+		"ḚḛḬḭṴṵ"},
+	// Macron below
+	{0x0331, 220,
+		"BbDdKkLlNnRrTtZzh",
+		"ḆḇḎḏḴḵḺḻṈṉṞṟṮṯẔẕẖ"},
+	// Greek perispomeni
+	{0x0342, 230,
+		"ἀἁἈἉἠἡἨἩἰἱἸἹὐὑὙὠὡὨὩα¨η᾿ιϊ῾υϋω",
+		"ἆἇἎἏἦἧἮἯἶἷἾἿὖὗὟὦὧὮὯᾶ῁ῆ῏ῖῗ῟ῦῧῶ"},
+	// Greek ypogegrammeni
+	{0x0345, 240,
+		"ἀἁἂἃἄἅἆἇἈἉἊἋἌἍἎἏἠἡἢἣἤἥἦἧἨἩἪἫἬἭἮἯὠὡὢὣὤὥὦὧὨὩὪὫὬὭὮὯὰαάᾶΑὴηήῆΗὼωώῶΩ",
+		"ᾀᾁᾂᾃᾄᾅᾆᾇᾈᾉᾊᾋᾌᾍᾎᾏᾐᾑᾒᾓᾔᾕᾖᾗᾘᾙᾚᾛᾜᾝᾞᾟᾠᾡᾢᾣᾤᾥᾦᾧᾨᾩᾪᾫᾬᾭᾮᾯᾲᾳᾴᾷᾼῂῃῄῇῌῲῳῴῷῼ"},
+}
+
+// singletons are the characters NFC replaces by another on its own
+var singletons = map[rune]rune{
+	0x0340: 0x0300, // Combining grave tone mark
+	0x0341: 0x0301, // Combining acute tone mark
+	0x0343: 0x0313, // Combining Greek koronis
+	0x0374: 0x02B9, // Greek numeral sign
+	0x037E: ';',    // Greek question mark
+	0x0387: 0x00B7, // Greek ano teleia
+	0x2126: 0x03A9, // Ohm sign
+	0x212A: 'K',    // Kelvin sign
+	0x212B: 0x00C5, // Angstrom sign
+}
+
+// pair is a base followed by a combining mark
+type pair struct{ base, mark rune }
+
+var (
+	// composed holds the letter each pair composes
+	composed = map[pair]rune{}
+	// decomposed holds the pair each letter decomposes into
+	decomposed = map[rune]pair{}
+	// classes holds the canonical combining class of each mark
+	classes = map[rune]uint8{}
+)
+
+func init() {
+	for _, c := range compositions {
+		bases, letters := []rune(c.bases), []rune(c.composed)
+		if len(bases) != len(letters) {
+			panic("textnorm: composition table out of step for mark " + string(c.mark))
+		}
+		classes[c.mark] = c.class
+		for i, base := range bases {
+			composed[pair{base, c.mark}] = letters[i]
+			decomposed[letters[i]] = pair{base, c.mark}
+		}
+	}
+}
+
+// Hangul syllables are composed of a leading consonant, a vowel, and an
+// optional trailing consonant, by arithmetic rather than a table
+const (
+	hangulBase    = 0xAC00
+	hangulCount   = 11172
+	leadingBase   = 0x1100
+	vowelBase     = 0x1161
+	trailingBase  = 0x11A7
+	vowelCount    = 21
+	trailingCount = 28
+	leadingCount  = 19
+)
+
+// NFC returns s in Unicode Normalization Form C, as far as its tables
+// reach: characters are decomposed, their combining marks put in canonical
+// order, and composed again. Marks outside the tables are left where they
+// are, and block those after them from composing.
+func NFC(s string) string {
+	if isASCII(s) {
+		return s
+	}
+
+	runes := make([]rune, 0, utf8.RuneCountInString(s))
+	for _, r := range s {
+		runes = decompose(runes, r)
+	}
+	reorder(runes)
+	return string(compose(runes))
+}
+
+// isASCII reports whether s is ASCII, which is always in NFC
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// decompose appends the canonical decomposition of r to runes
+func decompose(runes []rune, r rune) []rune {
+	if to, ok := singletons[r]; ok {
+		r = to
+	}
+	if p, ok := decomposed[r]; ok {
+		return append(decompose(runes, p.base), p.mark)
+	}
+	if i := r - hangulBase; i >= 0 && i < hangulCount {
+		runes = append(runes, leadingBase+i/(vowelCount*trailingCount), vowelBase+i%(vowelCount*trailingCount)/trailingCount)
+		if t := i % trailingCount; t > 0 {
+			runes = append(runes, trailingBase+t)
+		}
+		return runes
+	}
+	return append(runes, r)
+}
+
+// reorder sorts each run of combining marks in runes by their class, keeping
+// the order of marks of the same class
+func reorder(runes []rune) {
+	for i := 0; i < len(runes); {
+		if classes[runes[i]] == 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && classes[runes[j]] != 0 {
+			j++
+		}
+		slices.SortStableFunc(runes[i:j], func(a, b rune) int { return int(classes[a]) - int(classes[b]) })
+		i = j
+	}
+}
+
+// compose composes the decomposed, reordered runes in place, and returns
+// them. A mark composes with the last starter unless a mark of the same or
+// a higher class comes between them that doesn't.
+func compose(runes []rune) []rune {
+	out := runes[:0]
+	starter := -1
+	var last uint8
+	for _, r := range runes {
+		class := classes[r]
+		if starter >= 0 && (last < class || last == 0 && starter == len(out)-1) {
+			if c, ok := combine(out[starter], r); ok {
+				out[starter] = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		last = class
+		out = append(out, r)
+	}
+	return out
+}
+
+// combine returns the character a and b compose, if any
+func combine(a, b rune) (rune, bool) {
+	if c, ok := composed[pair{a, b}]; ok {
+		return c, true
+	}
+	if l, v := a-leadingBase, b-vowelBase; l >= 0 && l < leadingCount && v >= 0 && v < vowelCount {
+		return hangulBase + (l*vowelCount+v)*trailingCount, true
+	}
+	if s, t := a-hangulBase, b-trailingBase; s >= 0 && s < hangulCount && s%trailingCount == 0 && t > 0 && t < trailingCount {
+		return a + t, true
+	}
+	return 0, false
+}
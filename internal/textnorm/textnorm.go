@@ -0,0 +1,66 @@
+// Package textnorm normalizes text, such as anchor texts and keywords, so the
+// same words typed or encoded differently compare equal: "café" with a
+// precomposed é or with e and a combining accent, "new york" with a
+// non-breaking space, and ‘quoted’ with curly quotes.
+//
+// NFC composition carries the combining marks of Latin, Greek, and Cyrillic
+// letters and Hangul syllables rather than the whole Unicode database; text
+// in other scripts is left as it is.
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// dropped are the invisible characters text is normalized without: zero
+// width spaces and joiners, the byte order mark, and soft hyphens
+var dropped = map[rune]bool{
+	0x00AD: true, // Soft hyphen
+	0x200B: true, // Zero width space
+	0x200C: true, // Zero width non-joiner
+	0x200D: true, // Zero width joiner
+	0x2060: true, // Word joiner
+	0xFEFF: true, // Zero width no-break space, or byte order mark
+}
+
+// quotes are the typographic quotes text is normalized with the ASCII quote
+// of
+var quotes = map[rune]rune{
+	'‘': '\'', '’': '\'', '‚': '\'', '‛': '\'',
+	'“': '"', '”': '"', '„': '"', '‟': '"',
+}
+
+// Normalize returns s in NFC with its typographic quotes made ASCII, its
+// invisible characters removed, and each run of spaces, including
+// non-breaking and other Unicode spaces, made a single space. Leading and
+// trailing spaces are trimmed.
+func Normalize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range NFC(s) {
+		switch {
+		case dropped[r]:
+			continue
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+			continue
+		}
+		if q, ok := quotes[r]; ok {
+			r = q
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Fold returns s normalized by Normalize and lowercased, so texts differing
+// only by case compare equal
+func Fold(s string) string {
+	return strings.ToLower(Normalize(s))
+}
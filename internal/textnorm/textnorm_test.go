@@ -0,0 +1,92 @@
+package textnorm
+
+import "testing"
+
+func TestNFC(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"", ""},
+		{"plain ascii", "plain ascii"},
+		{"cafe\u0301", "café"},
+		{"café", "café"},
+		{"A\u030Angström", "Ångström"},
+		{"\u212Bngström", "Ångström"},
+		{"i\u0307", "i\u0307"},
+		{"й", "й"},
+		{"и\u0306", "й"},
+
+		// Vietnamese: e with a dot below and a circumflex, in any order and
+		// from any partial composition
+		{"e\u0323\u0302", "ệ"},
+		{"e\u0302\u0323", "ệ"},
+		{"ê\u0323", "ệ"},
+		{"ẹ\u0302", "ệ"},
+
+		// Greek with breathing, accent, and iota subscript
+		{"ω\u0314\u0342\u0345", "ᾧ"},
+
+		// Hangul from its jamo
+		{"\u1112\u1161\u11AB\u1100\u1173\u11AF", "한글"},
+		{"\u1100\u1161", "가"},
+
+		// A mark of the same class between blocks the second
+		{"a\u0301\u0301", "á\u0301"},
+		// Marks outside the tables are kept
+		{"a\u0360b", "a\u0360b"},
+		// A mark without a base
+		{"\u0301e", "\u0301e"},
+	}
+	for _, tt := range tests {
+		if got := NFC(tt.s); got != tt.want {
+			t.Errorf("NFC(%+q) = %+q, want %+q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"", ""},
+		{"seo tools", "seo tools"},
+		{"  seo   tools ", "seo tools"},
+		{"seo\u00A0tools", "seo tools"},
+		{"seo\t\ntools", "seo tools"},
+		{"seo\u2009\u200Atools", "seo tools"},
+		{"seo\u3000tools", "seo tools"},
+		{"se\u200Bo too\u200Dls", "seo tools"},
+		{"\uFEFFseo tools", "seo tools"},
+		{"seo \u200B tools", "seo tools"},
+		{"web\u00ADsite", "website"},
+		{"‘best’ tools", "'best' tools"},
+		{"“best” tools", `"best" tools`},
+		{"„best“ tools", `"best" tools`},
+		{"cafe\u0301\u00A0menu", "café menu"},
+		{"Café", "Café"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.s); got != tt.want {
+			t.Errorf("Normalize(%+q) = %+q, want %+q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"SEO Tools", "seo tools"},
+		{"CAFE\u0301", "café"},
+		{"Ångström\u00A0Units", "ångström units"},
+		{"ΔΕΛΤΑ", "δελτα"},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.s); got != tt.want {
+			t.Errorf("Fold(%+q) = %+q, want %+q", tt.s, got, tt.want)
+		}
+	}
+}
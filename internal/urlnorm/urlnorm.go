@@ -0,0 +1,52 @@
+// Package urlnorm finds the variants of a page's URL: the same page
+// reported once per scheme, with and without www., with and without a
+// trailing slash, or with its host in another case.
+//
+// It only normalizes what never tells two pages apart on a typical site.
+// Paths and queries are kept as they are, since servers may treat their
+// case as significant.
+package urlnorm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// defaultPorts are the ports left out of the hosts of URLs with each scheme
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// Key returns the form rawURL shares with its variants: its host, lowercased
+// and without www., a trailing dot, or the scheme's default port, followed by
+// its path without trailing slashes and its query. The scheme and fragment
+// are left out. https://WWW.Example.com/Blog/ and http://example.com/Blog
+// both have the key example.com/Blog.
+//
+// A URL without a scheme is read as a host and path. One that can't be
+// parsed is its own key, trimmed of spaces.
+func Key(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	s := rawURL
+	if !strings.Contains(s, "://") {
+		s = "//" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	host = strings.TrimPrefix(host, "www.")
+	if strings.Contains(host, ":") {
+		// An IPv6 literal
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && port != defaultPorts[strings.ToLower(u.Scheme)] {
+		host += ":" + port
+	}
+
+	key := host + strings.TrimRight(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
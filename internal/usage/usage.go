@@ -0,0 +1,150 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+)
+
+// LedgerFileName is the name of the usage ledger inside the cache directory
+const LedgerFileName = "usage.jsonl"
+
+// Budget standings
+const (
+	StatusOK       = "ok"
+	StatusWarn     = "warn"
+	StatusExceeded = "exceeded"
+)
+
+// Entry is one recorded API request
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Endpoint string    `json:"endpoint"`
+	Units    int       `json:"units"`
+}
+
+// Standing summarizes month-to-date consumption against a budget
+type Standing struct {
+	Month        string  `json:"month"`
+	UnitsUsed    int     `json:"units_used"`
+	MonthlyUnits int     `json:"monthly_units"`
+	WarnPercent  int     `json:"warn_percent"`
+	PercentUsed  float64 `json:"percent_used"`
+	Remaining    int     `json:"remaining"`
+	Status       string  `json:"status"`
+}
+
+// LedgerPath returns the path to the usage ledger
+func LedgerPath() (string, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, LedgerFileName), nil
+}
+
+// Record appends an entry to the ledger
+func Record(e Entry) error {
+	path, err := LedgerPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage entry: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage ledger: %w", err)
+	}
+	return nil
+}
+
+// Entries returns every ledger entry recorded in the same calendar month as now
+func Entries(now time.Time) ([]Entry, error) {
+	path, err := LedgerPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	start := monthStart(now)
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// Skip lines torn by a concurrent or interrupted write
+			continue
+		}
+		if !e.Time.Before(start) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+
+	return entries, nil
+}
+
+// MonthToDate returns the units recorded in the same calendar month as now
+func MonthToDate(now time.Time) (int, error) {
+	entries, err := Entries(now)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, e := range entries {
+		total += e.Units
+	}
+	return total, nil
+}
+
+// Check compares month-to-date usage against budget
+func Check(now time.Time, used int, budget config.Budget) Standing {
+	s := Standing{
+		Month:        now.Format("2006-01"),
+		UnitsUsed:    used,
+		MonthlyUnits: budget.MonthlyUnits,
+		WarnPercent:  budget.WarnAt(),
+		Remaining:    budget.MonthlyUnits - used,
+		Status:       StatusOK,
+	}
+	if budget.MonthlyUnits > 0 {
+		s.PercentUsed = float64(used) * 100 / float64(budget.MonthlyUnits)
+	}
+
+	switch {
+	case used > budget.MonthlyUnits:
+		s.Status = StatusExceeded
+	case s.PercentUsed >= float64(s.WarnPercent):
+		s.Status = StatusWarn
+	}
+	return s
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
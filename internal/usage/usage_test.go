@@ -0,0 +1,73 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/internal/config"
+)
+
+func TestMonthToDate(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: time.Date(2025, 2, 28, 23, 59, 0, 0, time.UTC), Endpoint: "/site-explorer/backlinks", Units: 500},
+		{Time: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), Endpoint: "/site-explorer/backlinks", Units: 200},
+		{Time: time.Date(2025, 3, 14, 8, 0, 0, 0, time.UTC), Endpoint: "/site-explorer/metrics", Units: 50},
+	}
+	for _, e := range entries {
+		if err := Record(e); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	got, err := MonthToDate(now)
+	if err != nil {
+		t.Fatalf("MonthToDate() error = %v", err)
+	}
+	if got != 250 {
+		t.Errorf("MonthToDate() = %v, want %v", got, 250)
+	}
+}
+
+func TestMonthToDate_NoLedger(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	got, err := MonthToDate(time.Now())
+	if err != nil {
+		t.Fatalf("MonthToDate() error = %v", err)
+	}
+	if got != 0 {
+		t.Errorf("MonthToDate() = %v, want 0", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		used   int
+		budget config.Budget
+		want   string
+	}{
+		{name: "under threshold", used: 500, budget: config.Budget{MonthlyUnits: 1000}, want: StatusOK},
+		{name: "default threshold", used: 800, budget: config.Budget{MonthlyUnits: 1000}, want: StatusWarn},
+		{name: "custom threshold", used: 800, budget: config.Budget{MonthlyUnits: 1000, WarnPercent: 90}, want: StatusOK},
+		{name: "at budget", used: 1000, budget: config.Budget{MonthlyUnits: 1000}, want: StatusWarn},
+		{name: "exceeded", used: 1001, budget: config.Budget{MonthlyUnits: 1000}, want: StatusExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Check(now, tt.used, tt.budget)
+			if s.Status != tt.want {
+				t.Errorf("Check().Status = %v, want %v", s.Status, tt.want)
+			}
+			if s.Month != "2025-03" {
+				t.Errorf("Check().Month = %v, want 2025-03", s.Month)
+			}
+		})
+	}
+}
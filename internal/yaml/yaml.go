@@ -0,0 +1,339 @@
+// Package yaml reads the subset of YAML used by hand-written config files:
+// block maps and lists, "- key: value" list items, flow lists such as
+// [a, b], quoted and plain scalars, and # comments. Anchors, multi-line
+// strings and multiple documents are not supported. A document starting
+// with { is read as JSON.
+package yaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal parses data and stores the result in the value pointed to by v,
+// following encoding/json rules for field names and types
+func Unmarshal(data []byte, v interface{}) error {
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		return json.Unmarshal(data, v)
+	}
+
+	p := &parser{}
+	if err := p.split(string(data)); err != nil {
+		return err
+	}
+
+	var doc interface{}
+	if len(p.lines) > 0 {
+		var err error
+		if doc, err = p.block(p.lines[0].indent); err != nil {
+			return err
+		}
+		if p.pos < len(p.lines) {
+			if l := p.lines[p.pos]; l.indent < p.lines[0].indent {
+				return p.errorf(l, "unexpected indentation")
+			}
+			return p.errorf(p.lines[p.pos], "expected a list item")
+		}
+	}
+
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, v)
+}
+
+// line is a non-blank, comment-stripped source line
+type line struct {
+	num    int
+	indent int
+	text   string
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+func (p *parser) errorf(l line, format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", l.num, fmt.Sprintf(format, args...))
+}
+
+// split breaks src into lines, dropping blanks and comments
+func (p *parser) split(src string) error {
+	for i, raw := range strings.Split(src, "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+		}
+		indent := len(raw) - len(text)
+		text = stripComment(text)
+		if text == "" || text == "---" {
+			continue
+		}
+		p.lines = append(p.lines, line{num: i + 1, indent: indent, text: text})
+	}
+	return nil
+}
+
+// stripComment removes a trailing # comment outside quotes
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return strings.TrimRight(s[:i], " \t")
+		}
+	}
+	return s
+}
+
+// block parses the map or list starting at the current line
+func (p *parser) block(indent int) (interface{}, error) {
+	if isListItem(p.lines[p.pos].text) {
+		return p.list(indent)
+	}
+	return p.mapping(indent)
+}
+
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *parser) list(indent int) (interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf(l, "unexpected indentation")
+		}
+		if !isListItem(l.text) {
+			// The next key of a map whose list sits at the key's indentation
+			break
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		switch {
+		case rest == "":
+			p.pos++
+			item, err := p.nested(indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		case isListItem(rest) || keyOf(rest) != "":
+			// The item is a block starting on the dash line; reparse the
+			// remainder as if it were on its own line
+			p.lines[p.pos] = line{num: l.num, indent: l.indent + len(l.text) - len(rest), text: rest}
+			item, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		default:
+			v, err := p.scalar(l, rest)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+			p.pos++
+		}
+	}
+	return items, nil
+}
+
+func (p *parser) mapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf(l, "unexpected indentation")
+		}
+		key := keyOf(l.text)
+		if key == "" {
+			return nil, p.errorf(l, "expected \"key: value\"")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf(l, "duplicate key %q", key)
+		}
+
+		rest := strings.TrimSpace(l.text[strings.Index(l.text, ":")+1:])
+		p.pos++
+		if rest != "" {
+			v, err := p.scalar(l, rest)
+			if err != nil {
+				return nil, err
+			}
+			m[unquote(key)] = v
+			continue
+		}
+
+		// A list may sit at the same indentation as its key
+		if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text) {
+			v, err := p.list(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[unquote(key)] = v
+			continue
+		}
+		v, err := p.nested(indent)
+		if err != nil {
+			return nil, err
+		}
+		m[unquote(key)] = v
+	}
+	return m, nil
+}
+
+// nested parses a block indented deeper than parent, or returns nil when
+// there is none
+func (p *parser) nested(parent int) (interface{}, error) {
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent <= parent {
+		return nil, nil
+	}
+	return p.block(p.lines[p.pos].indent)
+}
+
+// keyOf returns the key of a "key: value" or "key:" line, or "" if text is
+// not a map entry
+func keyOf(text string) string {
+	end := len(text)
+	if text[0] == '"' || text[0] == '\'' {
+		closing := strings.IndexByte(text[1:], text[0])
+		if closing < 0 {
+			return ""
+		}
+		end = closing + 2
+		if end >= len(text) || text[end] != ':' {
+			return ""
+		}
+		return text[:end]
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			end = i
+			break
+		}
+	}
+	if end == len(text) || strings.ContainsAny(text[:1], "[{") {
+		return ""
+	}
+	return text[:end]
+}
+
+func unquote(key string) string {
+	if s, err := parseQuoted(key); err == nil {
+		return s
+	}
+	return key
+}
+
+// scalar parses an inline value: a quoted string, a flow list, or a plain
+// scalar
+func (p *parser) scalar(l line, s string) (interface{}, error) {
+	switch {
+	case s[0] == '"' || s[0] == '\'':
+		v, err := parseQuoted(s)
+		if err != nil {
+			return nil, p.errorf(l, "%v", err)
+		}
+		return v, nil
+	case s[0] == '[':
+		if !strings.HasSuffix(s, "]") {
+			return nil, p.errorf(l, "unterminated flow list")
+		}
+		items := []interface{}{}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		if inner == "" {
+			return items, nil
+		}
+		for _, part := range splitFlow(inner) {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				return nil, p.errorf(l, "empty flow list item")
+			}
+			v, err := p.scalar(l, part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case s[0] == '{' || s[0] == '&' || s[0] == '*' || s[0] == '|' || s[0] == '>':
+		return nil, p.errorf(l, "unsupported YAML syntax %q", s[:1])
+	}
+	return plain(s), nil
+}
+
+// splitFlow splits a flow list body on commas outside quotes
+func splitFlow(s string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func parseQuoted(s string) (string, error) {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	if s[0] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	v, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	return v, nil
+}
+
+// plain resolves an unquoted scalar to null, a bool, a number or a string
+func plain(s string) interface{} {
+	switch s {
+	case "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "xXnN") {
+		if json.Valid([]byte(s)) {
+			return json.Number(s)
+		}
+		// Forms like +5 or .5 are numbers in YAML but not in JSON
+		return json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return s
+}
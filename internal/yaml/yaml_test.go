@@ -0,0 +1,97 @@
+package yaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	src := `# monitors
+interval: 1h
+notify:
+- type: webhook
+  url: "https://hooks.example.com/x#y"   # quoted, so # is kept
+- type: stdout
+checks:
+  - name: dr
+    target: example.com
+    metrics: [domain_rating, 'org traffic']
+    below: 70
+    change_pct: +2.5
+    enabled: true
+    nested:
+      deep: ~
+  -
+    name: second
+empty: []
+`
+	var got map[string]interface{}
+	if err := Unmarshal([]byte(src), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"interval": "1h",
+		"notify": []interface{}{
+			map[string]interface{}{"type": "webhook", "url": "https://hooks.example.com/x#y"},
+			map[string]interface{}{"type": "stdout"},
+		},
+		"checks": []interface{}{
+			map[string]interface{}{
+				"name":       "dr",
+				"target":     "example.com",
+				"metrics":    []interface{}{"domain_rating", "org traffic"},
+				"below":      float64(70),
+				"change_pct": 2.5,
+				"enabled":    true,
+				"nested":     map[string]interface{}{"deep": nil},
+			},
+			map[string]interface{}{"name": "second"},
+		},
+		"empty": []interface{}{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", got, want)
+	}
+}
+
+func TestUnmarshal_Struct(t *testing.T) {
+	var got struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	if err := Unmarshal([]byte("name: x\ncount: 3\n"), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Name != "x" || got.Count != 3 {
+		t.Errorf("Unmarshal() = %+v, want {x 3}", got)
+	}
+
+	if err := Unmarshal([]byte(`{"name": "json", "count": 1}`), &got); err != nil || got.Name != "json" {
+		t.Errorf("Unmarshal() JSON = %+v, %v", got, err)
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"a: 1\n  b: 2\n", "line 2: unexpected indentation"},
+		{"a: 1\na: 2\n", `line 2: duplicate key "a"`},
+		{"a: 1\njust text\n", "line 2: expected \"key: value\""},
+		{"a:\n\tb: 1\n", "line 2: tabs"},
+		{"a: \"open\n", "line 1: unterminated string"},
+		{"a: |\n  text\n", "line 1: unsupported"},
+		{"- a\nb: 1\n", "line 2: expected a list item"},
+	}
+
+	for _, tt := range tests {
+		var v interface{}
+		err := Unmarshal([]byte(tt.src), &v)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Unmarshal(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -4,18 +4,46 @@ import (
 	"os"
 
 	"github.com/aminemat/ahrefs-cli/cmd"
+	"github.com/aminemat/ahrefs-cli/cmd/alert"
+	"github.com/aminemat/ahrefs-cli/cmd/cache"
 	"github.com/aminemat/ahrefs-cli/cmd/config"
+	"github.com/aminemat/ahrefs-cli/cmd/difffiles"
+	"github.com/aminemat/ahrefs-cli/cmd/doctor"
+	"github.com/aminemat/ahrefs-cli/cmd/exporter"
+	"github.com/aminemat/ahrefs-cli/cmd/jobs"
+	"github.com/aminemat/ahrefs-cli/cmd/keywords"
+	"github.com/aminemat/ahrefs-cli/cmd/mockserver"
+	"github.com/aminemat/ahrefs-cli/cmd/monitor"
+	"github.com/aminemat/ahrefs-cli/cmd/schedule"
+	"github.com/aminemat/ahrefs-cli/cmd/serve"
 	"github.com/aminemat/ahrefs-cli/cmd/siteexplorer"
+	"github.com/aminemat/ahrefs-cli/cmd/sync"
+	"github.com/aminemat/ahrefs-cli/cmd/usage"
 )
 
 func main() {
 	// Register all subcommands
 	cmd.AddCommands(
+		alert.NewAlertCmd(),
+		cache.NewCacheCmd(),
 		config.NewConfigCmd(),
+		siteexplorer.NewDecodeCmd(),
+		difffiles.NewDiffFilesCmd(),
+		siteexplorer.NewDigestCmd(),
+		doctor.NewDoctorCmd(),
+		exporter.NewExporterCmd(),
+		jobs.NewJobsCmd(),
+		keywords.NewKeywordsCmd(),
+		mockserver.NewMockServerCmd(),
+		monitor.NewMonitorCmd(),
+		schedule.NewScheduleCmd(),
+		serve.NewServeCmd(siteexplorer.NewSiteExplorerCmd),
 		siteexplorer.NewSiteExplorerCmd(),
+		sync.NewSyncCmd(),
+		usage.NewUsageCmd(),
 	)
 
 	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
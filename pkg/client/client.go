@@ -1,13 +1,22 @@
 package client
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
+	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -19,44 +28,170 @@ const (
 
 	// DefaultMaxRetries for failed requests
 	DefaultMaxRetries = 3
+
+	// DefaultMaxBodySize caps the response bodies read into memory
+	DefaultMaxBodySize = 100 << 20
 )
 
 // Client is the Ahrefs API client
 type Client struct {
-	baseURL    string
+	bases      *baseURLs
 	apiKey     string
 	httpClient *http.Client
+	timeout    time.Duration
 	maxRetries int
+	maxBody    int64
+	maxQuery   int
+	bodyParams map[string]ParamEncoding
+	headers    http.Header
+	limiter    *limiter
+	handler    Handler
+	log        *slog.Logger
+	onRetry    func(ctx context.Context, r Retry)
+
+	// sleep waits out a retry's backoff, or until ctx is done
+	sleep func(ctx context.Context, d time.Duration) error
 }
 
 // Config holds client configuration
 type Config struct {
-	APIKey     string
-	BaseURL    string
-	Timeout    time.Duration
+	APIKey  string
+	BaseURL string
+
+	// BaseURLs, when set in place of BaseURL, are the base URLs to send
+	// requests to in order of preference. When one can't be reached, such
+	// as during a regional outage, the client moves on to the next for every
+	// later attempt. Errors in responses don't move it on.
+	BaseURLs []string
+
+	// Timeout bounds each attempt of a request that doesn't set its own; 0
+	// means DefaultTimeout
+	Timeout time.Duration
+
+	// MaxRetries caps the attempts made after the first of a request that
+	// doesn't set its own; 0 means DefaultMaxRetries and a negative count
+	// means no retries
 	MaxRetries int
+
+	// RateLimit caps requests per second, including retries; 0 means
+	// unlimited. The limit is shared by every goroutine using the client.
+	RateLimit float64
+
+	// MaxBodySize caps the bytes of a response body read into memory; 0
+	// means DefaultMaxBodySize and a negative size means no limit. Streamed
+	// bodies aren't limited.
+	MaxBodySize int64
+
+	// MaxQueryLength is the longest query string sent; a request's
+	// parameters encoding longer are form-encoded in its body instead. 0
+	// means DefaultMaxQueryLength and a negative length means no limit.
+	MaxQueryLength int
+
+	// BodyParams lists the endpoints whose parameters are sent in the
+	// request body, and how they are encoded, for operations the API
+	// expects them there
+	BodyParams map[string]ParamEncoding
+
+	// Headers are sent with every request, e.g. for a proxy that requires
+	// its own. They replace the client's headers of the same name,
+	// Authorization included.
+	Headers http.Header
+
+	// Middleware wraps every call to Do, outermost first. Each sees the
+	// request once, however many times it is retried.
+	Middleware []Middleware
+
+	// Logger receives a debug record for each retry, with the attempt, the
+	// reason, and the backoff slept; nil discards them
+	Logger *slog.Logger
+
+	// OnRetry, when set, is called before each retry's backoff, e.g. to
+	// report progress
+	OnRetry func(ctx context.Context, r Retry)
 }
 
+// Retry describes a failed attempt of a request that is about to be retried
+type Retry struct {
+	Endpoint string
+	Attempt  int // the attempt about to be made, from 2
+	Reason   string
+	Backoff  time.Duration
+	Err      error
+}
+
+// Handler performs an API request
+type Handler func(ctx context.Context, req Request) (*Response, error)
+
+// Middleware wraps a Handler, e.g. to trace or log requests
+type Middleware func(next Handler) Handler
+
 // NewClient creates a new Ahrefs API client
 func NewClient(cfg Config) *Client {
 	if cfg.BaseURL == "" {
 		cfg.BaseURL = BaseURL
 	}
+	var urls []string
+	for _, u := range cfg.BaseURLs {
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		urls = []string{cfg.BaseURL}
+	}
 	if cfg.Timeout == 0 {
 		cfg.Timeout = DefaultTimeout
 	}
 	if cfg.MaxRetries == 0 {
 		cfg.MaxRetries = DefaultMaxRetries
+	} else if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = DefaultMaxBodySize
+	}
+	if cfg.MaxQueryLength == 0 {
+		cfg.MaxQueryLength = DefaultMaxQueryLength
 	}
 
-	return &Client{
-		baseURL: cfg.BaseURL,
-		apiKey:  cfg.APIKey,
-		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
-		},
+	c := &Client{
+		bases:  &baseURLs{urls: urls},
+		apiKey: cfg.APIKey,
+		// Each attempt is bounded by its request's timeout instead
+		httpClient: &http.Client{},
+		timeout:    cfg.Timeout,
 		maxRetries: cfg.MaxRetries,
+		maxBody:    cfg.MaxBodySize,
+		maxQuery:   cfg.MaxQueryLength,
+		bodyParams: cfg.BodyParams,
+		headers:    cfg.Headers,
+		limiter:    newLimiter(cfg.RateLimit),
+		log:        cfg.Logger,
+		onRetry:    cfg.OnRetry,
+		sleep:      sleep,
+	}
+	if c.log == nil {
+		c.log = slog.New(slog.DiscardHandler)
 	}
+	c.handler = c.do
+	for i := len(cfg.Middleware) - 1; i >= 0; i-- {
+		c.handler = cfg.Middleware[i](c.handler)
+	}
+	return c
+}
+
+// BaseURL returns the effective base URL requests are sent to
+func (c *Client) BaseURL() string {
+	return c.bases.get()
+}
+
+// URL returns the full URL for an endpoint and query parameters
+func (c *Client) URL(endpoint string, params url.Values) string {
+	u := c.BaseURL() + endpoint
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	return u
 }
 
 // Request represents an API request
@@ -64,6 +199,29 @@ type Request struct {
 	Method   string
 	Endpoint string
 	Params   url.Values
+
+	// Stream leaves the body of a successful response unread, in
+	// Response.Stream, so large responses can be decoded as they arrive.
+	// Error responses are read as usual.
+	Stream bool
+
+	// Timeout bounds each attempt, reading a streamed body included; 0
+	// means the client's
+	Timeout time.Duration
+
+	// MaxRetries caps the attempts made after the first; 0 means the
+	// client's and a negative count means no retries
+	MaxRetries int
+
+	// Body, when set, sends Params in the request body, encoded so. Left
+	// unset, the client's BodyParams for the endpoint apply, then its
+	// MaxQueryLength.
+	Body ParamEncoding
+
+	// Conditional, when set, asks for the response only if it has changed
+	// from the version it identifies, such as a cached one. An unchanged
+	// response is returned with NotModified set and no body.
+	Conditional Validators
 }
 
 // Response represents an API response with metadata
@@ -72,6 +230,14 @@ type Response struct {
 	Body       []byte
 	Headers    http.Header
 	Meta       ResponseMeta
+
+	// Stream is the unread body of a streamed request; the caller must
+	// close it
+	Stream io.ReadCloser
+
+	// NotModified reports a 304 answer to a Conditional request: the
+	// version the caller has is current
+	NotModified bool
 }
 
 // ResponseMeta contains metadata about the API response
@@ -79,86 +245,322 @@ type ResponseMeta struct {
 	UnitsConsumed      int   `json:"units_consumed,omitempty"`
 	RateLimitRemaining int   `json:"rate_limit_remaining,omitempty"`
 	ResponseTimeMS     int64 `json:"response_time_ms"`
+
+	// NextCursor is the continuation token for the next page, when the
+	// endpoint returns one
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Requests is the number of requests made when pages were fetched and
+	// merged into the response
+	Requests int `json:"requests,omitempty"`
+
+	// Interval is the granularity of a history response's rows
+	Interval string `json:"interval,omitempty"`
+
+	// Errors describes the parts of a merged response that failed, such as
+	// one country of several
+	Errors []string `json:"errors,omitempty"`
+
+	// Sample describes a response of rows sampled from several offsets
+	// rather than the first rows
+	Sample *Sample `json:"sample,omitempty"`
+
+	// Retries is the number of attempts made after the first
+	Retries int `json:"-"`
+
+	// Attempts is the number of requests sent for the response: the first,
+	// its retries, and those sent to another base URL on failing over
+	Attempts int `json:"attempts,omitempty"`
+
+	// TotalWaitMS is the time spent waiting to send the requests rather than
+	// on them: backing off before retries and waiting for the rate limit
+	TotalWaitMS int64 `json:"total_wait_ms,omitempty"`
+
+	// Cached reports a response served from a local cache rather than sent
+	// by the API, such as one replayed or revalidated
+	Cached bool `json:"cached,omitempty"`
+
+	// BaseURL is the base URL the response came from, when the client
+	// failed over to it from its preferred one
+	BaseURL string `json:"base_url,omitempty"`
+
+	// Revalidated reports a response served from a cache after the API
+	// confirmed it unchanged
+	Revalidated bool `json:"revalidated,omitempty"`
+
+	// Validators are the response's caching headers, for revalidating it
+	Validators Validators `json:"-"`
+
+	// Timing is where the time of the request went, over all its attempts
+	Timing Timing `json:"-"`
+}
+
+// Sample describes the rows of a sampled response
+type Sample struct {
+	Rows    int   `json:"rows"`            // rows sampled
+	Total   int   `json:"total,omitempty"` // rows sampled from, when counted
+	Offsets []int `json:"offsets"`         // where each chunk of rows starts
+}
+
+// RequestError is returned by Do when a request failed, after any retries
+type RequestError struct {
+	URL        string
+	Retries    int // attempts made after the first
+	MaxRetries int
+	Err        error
+
+	// NoRetry is why the request wasn't retried, when Retries is 0
+	NoRetry string
+
+	// Timing is where the time of the failed attempts went
+	Timing Timing
+}
+
+func (e *RequestError) Error() string {
+	if e.Retries == 0 && e.NoRetry != "" {
+		return fmt.Sprintf("request to %s failed (no retry: %s): %v", e.URL, e.NoRetry, e.Err)
+	}
+	return fmt.Sprintf("request to %s failed after %d retries: %v", e.URL, e.Retries, e.Err)
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RequestError) Unwrap() error {
+	return e.Err
 }
 
-// Do executes an API request
+// Do executes an API request through the configured middleware
 func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
+	return c.handler(ctx, req)
+}
+
+// do executes an API request, retrying the failures retryReason allows with
+// backoff
+func (c *Client) do(ctx context.Context, req Request) (*Response, error) {
 	if c.apiKey == "" {
 		return nil, fmt.Errorf("API key is required")
 	}
 
-	// Build URL
-	u, err := url.Parse(c.baseURL + req.Endpoint)
-	if err != nil {
+	if _, err := url.Parse(c.BaseURL() + req.Endpoint); err != nil {
 		return nil, fmt.Errorf("invalid endpoint: %w", err)
 	}
-	if req.Params != nil {
-		u.RawQuery = req.Params.Encode()
+	params, err := c.encodeParams(req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+	if params.body != nil {
+		c.log.DebugContext(ctx, "Sending parameters in the request body", "endpoint", req.Endpoint,
+			"method", params.method, "content_type", params.contentType, "size", len(params.body))
 	}
 
+	timeout, maxRetries := c.limits(req)
 	var lastErr error
-	for attempt := 0; attempt <= c.maxRetries; attempt++ {
-		if attempt > 0 {
+	var lastResp *Response
+	var reason string
+	var timing Timing
+	base := c.BaseURL()
+	failedOver := false
+	attempt := 0
+	for ; attempt <= maxRetries; attempt++ {
+		if attempt > 0 && !failedOver {
 			// Exponential backoff
 			backoff := time.Duration(attempt) * time.Second
-			select {
-			case <-time.After(backoff):
-			case <-ctx.Done():
-				return nil, ctx.Err()
+			if c.onRetry != nil {
+				c.onRetry(ctx, Retry{Endpoint: req.Endpoint, Attempt: attempt + 1, Reason: reason, Backoff: backoff, Err: lastErr})
+			}
+			if err := c.sleep(ctx, backoff); err != nil {
+				return nil, err
 			}
+			timing.Backoff += backoff
+			c.log.DebugContext(ctx, "Retrying API request", "endpoint", req.Endpoint, "attempt", attempt+1,
+				"reason", reason, "backoff", backoff, "err", lastErr)
 		}
 
-		resp, err := c.doRequest(ctx, req.Method, u.String())
+		waited := time.Now()
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		timing.RateLimit += time.Since(waited)
+		timing.Attempts++
+		failedOver = false
+		base = c.BaseURL()
+		u, err := url.Parse(base + req.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint: %w", err)
+		}
+		u.RawQuery = params.query
+		resp, err := c.doRequest(ctx, params, u.String(), req.Conditional, req.Stream, timeout, &timing)
 		if err == nil {
+			resp.Meta.Retries = attempt
+			resp.Meta.Attempts = timing.Attempts
+			resp.Meta.TotalWaitMS = (timing.Backoff + timing.RateLimit).Milliseconds()
+			resp.Meta.Timing = timing
+			if !c.bases.first(base) {
+				resp.Meta.BaseURL = base
+			}
 			return resp, nil
 		}
 
-		lastErr = err
-
-		// Don't retry on client errors (4xx) except 429
-		if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != 429 {
+		lastErr, lastResp = err, resp
+		if resp == nil && isConnectionFailure(err) {
+			if next, moved := c.bases.failover(base); next != "" {
+				if moved {
+					c.log.InfoContext(ctx, fmt.Sprintf("Can't reach %s; sending requests to %s instead", base, next),
+						"endpoint", req.Endpoint, "err", err)
+				}
+				// Another base URL is tried at once, and not as a retry
+				failedOver = true
+				attempt--
+				continue
+			}
+		}
+		if reason = retryReason(req.Method, resp, err); reason == "" {
 			break
 		}
 	}
 
-	return nil, fmt.Errorf("request failed after %d retries: %w", c.maxRetries, lastErr)
+	reqErr := &RequestError{
+		URL:        base + req.Endpoint,
+		Retries:    min(attempt, maxRetries),
+		MaxRetries: maxRetries,
+		Err:        lastErr,
+		Timing:     timing,
+	}
+	switch {
+	case reqErr.Retries > 0:
+	case reason != "":
+		reqErr.NoRetry = "retries disabled"
+	default:
+		reqErr.NoRetry = noRetryReason(req.Method, lastResp, lastErr)
+	}
+	return nil, reqErr
 }
 
-// doRequest performs a single HTTP request
-func (c *Client) doRequest(ctx context.Context, method, url string) (*Response, error) {
+// sleep blocks for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limits returns the timeout of each attempt of req and the retries it may
+// make: its own, or the client's where it leaves them 0
+func (c *Client) limits(req Request) (time.Duration, int) {
+	timeout, maxRetries := c.timeout, c.maxRetries
+	if req.Timeout > 0 {
+		timeout = req.Timeout
+	}
+	switch {
+	case req.MaxRetries > 0:
+		maxRetries = req.MaxRetries
+	case req.MaxRetries < 0:
+		maxRetries = 0
+	}
+	return timeout, maxRetries
+}
+
+// retryReason returns why a failed attempt of a request with method should be
+// retried, or "" if it shouldn't be.
+//
+// Client errors fail the same way however often they are sent, so only 429
+// is retried: 409 conflicts and 422 validation errors never are. Nor are
+// successful responses that aren't JSON, such as a captive portal's login
+// page, or bodies too large to read.
+//
+// A request that failed in transit or with a server error may have taken
+// effect, so is only retried when repeating it is harmless: the API's reads
+// are GETs, which are idempotent, while a POST isn't retried. A 429 is sent
+// before the request is processed, so is retried whatever the method.
+func retryReason(method string, resp *Response, err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode < 400 || apiErr.Code == CodeTooLarge) {
+		return ""
+	}
+
+	idempotent := method == "" || method == http.MethodGet || method == http.MethodHead
+	switch {
+	case resp == nil:
+		if idempotent {
+			return "request failed"
+		}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "rate limited (429)"
+	case resp.StatusCode >= 500:
+		if idempotent {
+			return fmt.Sprintf("server error (%d)", resp.StatusCode)
+		}
+	}
+	return ""
+}
+
+// noRetryReason returns why a failed attempt of a request with method wasn't
+// retried, for one retryReason returns "" for
+func noRetryReason(method string, resp *Response, err error) string {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Code == CodeTooLarge:
+		return "response too large"
+	case errors.As(err, &apiErr) && apiErr.StatusCode < 400:
+		return "response isn't JSON"
+	case resp == nil || resp.StatusCode >= 500:
+		return fmt.Sprintf("%s isn't idempotent", method)
+	}
+	return fmt.Sprintf("client error (%d)", resp.StatusCode)
+}
+
+// doRequest performs a single HTTP request with params, bounded by timeout,
+// adding the time of its phases to timing. It is conditional on the
+// response having changed from the version of conditional, if set. With
+// stream, a successful response's body is returned unread, and the timeout
+// runs until it is closed.
+func (c *Client) doRequest(ctx context.Context, params encoded, url string, conditional Validators, stream bool, timeout time.Duration, timing *Timing) (*Response, error) {
 	startTime := time.Now()
 
-	httpReq, err := http.NewRequestWithContext(ctx, method, url, nil)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	ctx, tr := withTracer(ctx)
+	defer func() { timing.Add(tr.finish()) }()
+	streaming := false
+	defer func() {
+		if !streaming {
+			cancel()
+		}
+	}()
+
+	var reqBody io.Reader
+	if params.body != nil {
+		reqBody = bytes.NewReader(params.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, params.method, url, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if params.contentType != "" {
+		httpReq.Header.Set("Content-Type", params.contentType)
+	}
 
 	// Set headers
 	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 	httpReq.Header.Set("Accept", "application/json")
 	httpReq.Header.Set("User-Agent", "ahrefs-cli/0.1.0")
+	for name, values := range c.headers {
+		httpReq.Header[name] = append([]string(nil), values...)
+	}
+	setConditional(httpReq, conditional)
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
-	defer httpResp.Body.Close()
-
-	body, err := io.ReadAll(httpResp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	responseTime := time.Since(startTime)
 
 	resp := &Response{
 		StatusCode: httpResp.StatusCode,
-		Body:       body,
 		Headers:    httpResp.Header,
-		Meta: ResponseMeta{
-			ResponseTimeMS: responseTime.Milliseconds(),
-		},
 	}
+	resp.Meta.Validators = validators(httpResp.Header)
 
 	// Parse units consumed from headers if available
 	if units := httpResp.Header.Get("X-API-Units-Consumed"); units != "" {
@@ -168,13 +570,94 @@ func (c *Client) doRequest(ctx context.Context, method, url string) (*Response,
 		}
 	}
 
+	contentType := httpResp.Header.Get("Content-Type")
+	if httpResp.StatusCode == http.StatusNotModified && !conditional.Empty() {
+		// Unchanged: the caller has the body
+		httpResp.Body.Close()
+		resp.NotModified = true
+		resp.Meta.ResponseTimeMS = time.Since(startTime).Milliseconds()
+		return resp, nil
+	}
+	if stream && httpResp.StatusCode < 400 {
+		// Check the start of the body is JSON before handing it over
+		br := bufio.NewReader(httpResp.Body)
+		head, _ := br.Peek(sniffLen)
+		if isJSON(head) {
+			streaming = true
+			resp.Stream = readCloser{br, cancelCloser{httpResp.Body, cancel}}
+			resp.Meta.ResponseTimeMS = time.Since(startTime).Milliseconds()
+			return resp, nil
+		}
+		httpResp.Body = io.NopCloser(br)
+	}
+	defer httpResp.Body.Close()
+
+	reading := time.Now()
+	body, err := readBody(httpResp.Body, c.maxBody)
+	tr.transfer(reading)
+	if err != nil {
+		return nil, err
+	}
+	if c.maxBody >= 0 && int64(len(body)) > c.maxBody {
+		return resp, tooLargeError(httpResp.StatusCode, contentType, c.maxBody)
+	}
+	resp.Body = body
+	resp.Meta.ResponseTimeMS = time.Since(startTime).Milliseconds()
+
 	if httpResp.StatusCode >= 400 {
-		return resp, c.parseError(httpResp.StatusCode, body)
+		return resp, c.parseError(httpResp.StatusCode, contentType, body)
+	}
+	if !isJSON(body) {
+		return resp, nonJSONError(httpResp.StatusCode, contentType, body)
 	}
 
 	return resp, nil
 }
 
+// readBody reads r into memory, stopping one byte past limit so an oversized
+// body is detected without being read in full. A negative limit reads it all.
+func readBody(r io.Reader, limit int64) ([]byte, error) {
+	if limit >= 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, nil
+}
+
+// tooLargeError reports a response body over the size limit
+func tooLargeError(statusCode int, contentType string, limit int64) *APIError {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "no content type"
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		Code:       CodeTooLarge,
+		Message:    fmt.Sprintf("response body (%s) is larger than the %s limit", mediaType, formatSize(int(limit))),
+		Suggestion: "Request fewer rows per page with --limit, or raise the limit with --max-body-size",
+	}
+}
+
+// readCloser reads from a buffered body and closes the underlying one
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// cancelCloser closes a body, then cancels the context it was read with
+type cancelCloser struct {
+	io.Closer
+	cancel context.CancelFunc
+}
+
+func (c cancelCloser) Close() error {
+	defer c.cancel()
+	return c.Closer.Close()
+}
+
 // APIError represents an error response from the API
 type APIError struct {
 	StatusCode int
@@ -182,56 +665,285 @@ type APIError struct {
 	Message    string
 	Suggestion string
 	DocsURL    string
+
+	// Details lists the individual problems of a validation failure
+	Details []FieldError
+
+	// Excerpt is the start of a response body that wasn't JSON
+	Excerpt string
+
+	// RawDetail is the response body of an API error, indented when it's
+	// JSON and cut to maxRawDetail bytes, for the server's full explanation
+	RawDetail string
+}
+
+// RawSummary returns RawDetail on one line, cut to maxErrorBody characters
+func (e *APIError) RawSummary() string {
+	return truncate(strings.Join(strings.Fields(e.RawDetail), " "), maxErrorBody)
+}
+
+// FieldError is one problem reported in an error response, usually with the
+// parameter it concerns
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
 }
 
 func (e *APIError) Error() string {
-	return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Message)
+	msg := fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Message)
+	if len(e.Details) > 0 {
+		details := make([]string, len(e.Details))
+		for i, d := range e.Details {
+			details[i] = d.String()
+		}
+		msg += " (" + strings.Join(details, "; ") + ")"
+	}
+	if e.Excerpt != "" {
+		msg += fmt.Sprintf(" Response began: %q", e.Excerpt)
+	}
+	return msg
 }
 
+const (
+	// maxErrorBody is the length non-JSON error bodies are truncated to
+	maxErrorBody = 200
+
+	// maxRawDetail is the most bytes of an error body kept in RawDetail
+	maxRawDetail = 2048
+
+	// sniffLen is how much of a streamed body is checked for JSON
+	sniffLen = 512
+
+	// CodeGateway marks responses that came from something other than the
+	// API, such as a proxy, gateway, or captive portal
+	CodeGateway = "GATEWAY_ERROR"
+
+	// CodeTooLarge marks responses whose body is over the size limit
+	CodeTooLarge = "RESPONSE_TOO_LARGE"
+
+	gatewaySuggestion = "Check your proxy settings (HTTPS_PROXY), sign in to any captive portal, or run 'ahrefs doctor'"
+)
+
 // parseError attempts to parse an error response
-func (c *Client) parseError(statusCode int, body []byte) error {
+func (c *Client) parseError(statusCode int, contentType string, body []byte) error {
 	apiErr := &APIError{
 		StatusCode: statusCode,
+		RawDetail:  rawDetail(body),
 	}
 
 	// Try to parse JSON error response
 	var errResp struct {
 		Error struct {
-			Code    string `json:"code"`
-			Message string `json:"message"`
+			Code    string            `json:"code"`
+			Message string            `json:"message"`
+			Details []json.RawMessage `json:"details"`
 		} `json:"error"`
 	}
 
 	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
 		apiErr.Code = errResp.Error.Code
 		apiErr.Message = errResp.Error.Message
+		apiErr.Details = parseDetails(errResp.Error.Details)
+	} else if isHTML(body) || len(bytes.TrimSpace(body)) > 0 && !isJSON(body) && !isJSONType(contentType) {
+		// Proxies and gateways answer with error pages of their own
+		return nonJSONError(statusCode, contentType, body)
 	} else {
 		// Fallback to status text
-		apiErr.Message = string(body)
+		apiErr.Message = truncate(strings.TrimSpace(string(body)), maxErrorBody)
 		if len(apiErr.Message) == 0 {
 			apiErr.Message = http.StatusText(statusCode)
 		}
 	}
 
 	// Add suggestions based on status code
+	if code, suggestion, docsURL := Suggest(statusCode); code != "" {
+		apiErr.Code = code
+		apiErr.Suggestion = suggestion
+		apiErr.DocsURL = docsURL
+	}
+
+	return apiErr
+}
+
+// rawDetail returns an error body for RawDetail: trimmed, indented when it's
+// JSON, and cut on a character boundary to fit maxRawDetail bytes
+func rawDetail(body []byte) string {
+	body = bytes.TrimSpace(body)
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, body, "", "  ") == nil {
+		body = pretty.Bytes()
+	}
+	if len(body) <= maxRawDetail {
+		return string(body)
+	}
+	cut := maxRawDetail - len("...")
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return string(body[:cut]) + "..."
+}
+
+// parseDetails reads error details given either as plain messages or as
+// objects naming the field
+func parseDetails(raw []json.RawMessage) []FieldError {
+	var details []FieldError
+	for _, r := range raw {
+		var msg string
+		if json.Unmarshal(r, &msg) == nil {
+			details = append(details, FieldError{Message: msg})
+			continue
+		}
+
+		var obj struct {
+			Field   string `json:"field"`
+			Param   string `json:"param"`
+			Message string `json:"message"`
+			Msg     string `json:"msg"`
+		}
+		if json.Unmarshal(r, &obj) != nil {
+			continue
+		}
+		d := FieldError{Field: obj.Field, Message: obj.Message}
+		if d.Field == "" {
+			d.Field = obj.Param
+		}
+		if d.Message == "" {
+			d.Message = obj.Msg
+		}
+		if d.Message != "" {
+			details = append(details, d)
+		}
+	}
+	return details
+}
+
+// nonJSONError describes a response that isn't from the API because its body
+// is empty or not JSON
+func nonJSONError(statusCode int, contentType string, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Code:       CodeGateway,
+		Suggestion: gatewaySuggestion,
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "no content type"
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		apiErr.Message = fmt.Sprintf("received an empty response (%s)", mediaType)
+		return apiErr
+	}
+
+	apiErr.Message = fmt.Sprintf("received non-JSON response (%s, %s) — are you behind a proxy or captive portal?", mediaType, formatSize(len(body)))
+	if isHTML(body) {
+		apiErr.Excerpt = truncate(htmlSummary(body), maxErrorBody)
+	} else {
+		apiErr.Excerpt = truncate(strings.Join(strings.Fields(string(body)), " "), maxErrorBody)
+	}
+	return apiErr
+}
+
+// isJSON reports whether body starts like a JSON object or array. Only the
+// start is checked, so truncated bodies still count.
+func isJSON(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && (body[0] == '{' || body[0] == '[')
+}
+
+// isJSONType reports whether contentType is a JSON media type
+func isJSONType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// formatSize returns n bytes in B, KB, or MB
+func formatSize(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%dB", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%dKB", (n+512)/1024)
+	}
+	return fmt.Sprintf("%.1fMB", float64(n)/(1024*1024))
+}
+
+// isHTML reports whether body looks like an HTML page
+func isHTML(body []byte) bool {
+	head := strings.ToLower(strings.TrimSpace(string(body[:min(len(body), 512)])))
+	return strings.HasPrefix(head, "<!doctype html") || strings.HasPrefix(head, "<html") || strings.Contains(head, "<body")
+}
+
+var (
+	htmlTitle = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlTag   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// htmlSummary returns the title of an HTML page, or its text when it has
+// none
+func htmlSummary(body []byte) string {
+	text := string(body)
+	if m := htmlTitle.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	} else {
+		text = htmlTag.ReplaceAllString(text, " ")
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+// truncate shortens s to at most n runes, marking the cut
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+// Suggest returns the error code, remedy, and documentation link for an
+// HTTP status code, or empty strings when there is no specific advice
+func Suggest(statusCode int) (code, suggestion, docsURL string) {
 	switch statusCode {
 	case http.StatusUnauthorized, http.StatusForbidden:
-		apiErr.Code = "AUTH_ERROR"
-		apiErr.Suggestion = "Check your API key. Run 'ahrefs config set-key <your-key>' to configure"
-		apiErr.DocsURL = "https://docs.ahrefs.com/docs/api/reference/api-keys-creation-and-management"
+		return "AUTH_ERROR",
+			"Check your API key. Run 'ahrefs config set-key <your-key>' to configure",
+			"https://docs.ahrefs.com/docs/api/reference/api-keys-creation-and-management"
 	case http.StatusTooManyRequests:
-		apiErr.Code = "RATE_LIMIT_ERROR"
-		apiErr.Suggestion = "Rate limit exceeded. Wait before retrying or check your subscription limits"
-		apiErr.DocsURL = "https://docs.ahrefs.com/docs/api/reference/limits-consumption"
+		return "RATE_LIMIT_ERROR",
+			"Rate limit exceeded. Wait before retrying or check your subscription limits",
+			"https://docs.ahrefs.com/docs/api/reference/limits-consumption"
 	case http.StatusBadRequest:
-		apiErr.Code = "VALIDATION_ERROR"
-		apiErr.Suggestion = "Check request parameters. Use --describe flag to see valid options"
+		return "VALIDATION_ERROR",
+			"Check request parameters. Use --describe flag to see valid options",
+			""
 	case http.StatusNotFound:
-		apiErr.Code = "NOT_FOUND"
-		apiErr.Suggestion = "Endpoint or resource not found. Verify the target and endpoint"
+		return "NOT_FOUND",
+			"Endpoint or resource not found. Verify the target and endpoint",
+			""
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CodeGateway,
+			"The API or a proxy in between is unavailable. Retry later; if it persists, check your proxy settings",
+			""
 	}
+	return "", "", ""
+}
 
-	return apiErr
+// GetStream performs a GET request whose successful response body is left
+// unread in Response.Stream; the caller must close it
+func (c *Client) GetStream(ctx context.Context, endpoint string, params url.Values) (*Response, error) {
+	return c.Do(ctx, Request{
+		Method:   http.MethodGet,
+		Endpoint: endpoint,
+		Params:   params,
+		Stream:   true,
+	})
 }
 
 // Get performs a GET request
@@ -1,11 +1,20 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 func TestNewClient(t *testing.T) {
@@ -24,13 +33,18 @@ func TestNewClient(t *testing.T) {
 			config: Config{APIKey: "test-key", BaseURL: "https://custom.api.com"},
 			want:   "https://custom.api.com",
 		},
+		{
+			name:   "base URLs in order",
+			config: Config{APIKey: "test-key", BaseURL: "https://custom.api.com", BaseURLs: []string{"https://eu.api.com", "https://us.api.com"}},
+			want:   "https://eu.api.com",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := NewClient(tt.config)
-			if c.baseURL != tt.want {
-				t.Errorf("NewClient() baseURL = %v, want %v", c.baseURL, tt.want)
+			if c.BaseURL() != tt.want {
+				t.Errorf("NewClient() BaseURL() = %v, want %v", c.BaseURL(), tt.want)
 			}
 			if c.apiKey != tt.config.APIKey {
 				t.Errorf("NewClient() apiKey = %v, want %v", c.apiKey, tt.config.APIKey)
@@ -187,7 +201,7 @@ func TestAPIError(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Client{}
-			err := c.parseError(tt.statusCode, []byte("test error"))
+			err := c.parseError(tt.statusCode, "application/json", []byte("test error"))
 
 			apiErr, ok := err.(*APIError)
 			if !ok {
@@ -205,6 +219,131 @@ func TestAPIError(t *testing.T) {
 	}
 }
 
+func TestParseError_Body(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantMessage string
+		wantDetails []FieldError
+		wantExcerpt string
+		wantError   string
+	}{
+		{
+			name:        "detail objects",
+			body:        `{"error":{"code":"invalid","message":"Invalid request","details":[{"field":"limit","message":"must be at most 1000"},{"param":"mode","msg":"unknown value"}]}}`,
+			wantMessage: "Invalid request",
+			wantDetails: []FieldError{{Field: "limit", Message: "must be at most 1000"}, {Field: "mode", Message: "unknown value"}},
+			wantError:   "API error (400): Invalid request (limit: must be at most 1000; mode: unknown value)",
+		},
+		{
+			name:        "detail strings",
+			body:        `{"error":{"message":"Invalid request","details":["target is required"]}}`,
+			wantMessage: "Invalid request",
+			wantDetails: []FieldError{{Message: "target is required"}},
+			wantError:   "API error (400): Invalid request (target is required)",
+		},
+		{
+			name:        "HTML error page",
+			body:        "<!DOCTYPE html>\n<html><head><title>403 Forbidden &amp; blocked</title></head><body><h1>Blocked</h1></body></html>",
+			wantMessage: "received non-JSON response (no content type, 113B) — are you behind a proxy or captive portal?",
+			wantExcerpt: "403 Forbidden & blocked",
+			wantError:   `API error (400): received non-JSON response (no content type, 113B) — are you behind a proxy or captive portal? Response began: "403 Forbidden & blocked"`,
+		},
+		{
+			name:        "HTML without title",
+			body:        "<html><body><p>Access   denied</p>\n<p>by policy</p></body></html>",
+			wantMessage: "received non-JSON response (no content type, 65B) — are you behind a proxy or captive portal?",
+			wantExcerpt: "Access denied by policy",
+		},
+		{
+			name:        "long plain text",
+			body:        strings.Repeat("x", 300),
+			wantMessage: "received non-JSON response (no content type, 300B) — are you behind a proxy or captive portal?",
+			wantExcerpt: strings.Repeat("x", 200) + "...",
+		},
+		{
+			name:        "empty",
+			wantMessage: "Bad Request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{}
+			apiErr := c.parseError(http.StatusBadRequest, "", []byte(tt.body)).(*APIError)
+
+			if apiErr.Message != tt.wantMessage {
+				t.Errorf("APIError.Message = %q, want %q", apiErr.Message, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(apiErr.Details, tt.wantDetails) {
+				t.Errorf("APIError.Details = %v, want %v", apiErr.Details, tt.wantDetails)
+			}
+			if apiErr.Excerpt != tt.wantExcerpt {
+				t.Errorf("APIError.Excerpt = %q, want %q", apiErr.Excerpt, tt.wantExcerpt)
+			}
+			if tt.wantError != "" && apiErr.Error() != tt.wantError {
+				t.Errorf("APIError.Error() = %q, want %q", apiErr.Error(), tt.wantError)
+			}
+		})
+	}
+}
+
+func TestParseError_RawDetail(t *testing.T) {
+	c := &Client{}
+
+	body := `{"error":{"code":"invalid_where","message":"Invalid where","details":[{"field":"where","message":"unexpected token","position":{"line":1,"column":14}}]}}`
+	apiErr := c.parseError(http.StatusBadRequest, "application/json", []byte(body)).(*APIError)
+	want := `{
+  "error": {
+    "code": "invalid_where",
+    "message": "Invalid where",
+    "details": [
+      {
+        "field": "where",
+        "message": "unexpected token",
+        "position": {
+          "line": 1,
+          "column": 14
+        }
+      }
+    ]
+  }
+}`
+	if apiErr.RawDetail != want {
+		t.Errorf("APIError.RawDetail = %s, want %s", apiErr.RawDetail, want)
+	}
+	if summary := apiErr.RawSummary(); summary != `{ "error": { "code": "invalid_where", "message": "Invalid where", "details": [ { "field": "where", "message": "unexpected token", "position": { "line": 1, "column": 14 } } ] } }` {
+		t.Errorf("APIError.RawSummary() = %q, want it on one line", summary)
+	}
+
+	// Oversized bodies are capped, without splitting a character
+	big := `{"error":{"message":"Invalid where","details":["` + strings.Repeat("é", 3000) + `"]}}`
+	apiErr = c.parseError(http.StatusBadRequest, "application/json", []byte(big)).(*APIError)
+	if n := len(apiErr.RawDetail); n > maxRawDetail || n < maxRawDetail-4 {
+		t.Errorf("len(APIError.RawDetail) = %d, want at most %d", n, maxRawDetail)
+	}
+	if !strings.HasPrefix(apiErr.RawDetail, "{\n  \"error\"") || !strings.HasSuffix(apiErr.RawDetail, "é...") {
+		t.Errorf("APIError.RawDetail = %.40q...%q, want the indented body cut short", apiErr.RawDetail, apiErr.RawDetail[len(apiErr.RawDetail)-10:])
+	}
+	if !utf8.ValidString(apiErr.RawDetail) {
+		t.Error("APIError.RawDetail is cut inside a character")
+	}
+	if n := utf8.RuneCountInString(apiErr.RawSummary()); n != maxErrorBody+3 {
+		t.Errorf("APIError.RawSummary() has %d characters, want %d", n, maxErrorBody+3)
+	}
+
+	// Bodies that aren't JSON are only trimmed, and gateway pages have an
+	// excerpt instead
+	apiErr = c.parseError(http.StatusBadRequest, "application/json", []byte("  test error\n")).(*APIError)
+	if apiErr.RawDetail != "test error" {
+		t.Errorf("APIError.RawDetail = %q, want the trimmed body", apiErr.RawDetail)
+	}
+	apiErr = c.parseError(http.StatusBadGateway, "text/html", []byte("<html><body>Bad gateway</body></html>")).(*APIError)
+	if apiErr.RawDetail != "" {
+		t.Errorf("APIError.RawDetail = %q, want none for a gateway page", apiErr.RawDetail)
+	}
+}
+
 func TestClient_Retries(t *testing.T) {
 	attempts := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -225,10 +364,15 @@ func TestClient_Retries(t *testing.T) {
 		BaseURL:    server.URL,
 		MaxRetries: 3,
 	})
+	var backoffs []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		backoffs = append(backoffs, d)
+		return nil
+	}
 
 	resp, err := c.Get(context.Background(), "/test", nil)
 	if err != nil {
-		t.Errorf("Client.Get() with retries should succeed, got error: %v", err)
+		t.Fatalf("Client.Get() with retries should succeed, got error: %v", err)
 	}
 
 	if attempts != 3 {
@@ -238,6 +382,15 @@ func TestClient_Retries(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", resp.StatusCode)
 	}
+
+	// Backing off 1s, then 2s
+	if want := []time.Duration{time.Second, 2 * time.Second}; !reflect.DeepEqual(backoffs, want) {
+		t.Errorf("backoffs = %v, want %v", backoffs, want)
+	}
+	if m := resp.Meta; m.Attempts != 3 || m.Retries != 2 || m.TotalWaitMS != 3000 || m.Timing.Backoff != 3*time.Second || m.Cached {
+		t.Errorf("Meta = attempts %d, retries %d, total_wait_ms %d, backoff %v, cached %v, want 3 attempts after 3s of backoff",
+			m.Attempts, m.Retries, m.TotalWaitMS, m.Timing.Backoff, m.Cached)
+	}
 }
 
 func TestClient_NoRetryOn4xx(t *testing.T) {
@@ -263,4 +416,539 @@ func TestClient_NoRetryOn4xx(t *testing.T) {
 	if attempts != 1 {
 		t.Errorf("Expected 1 attempt (no retries on 4xx), got %d", attempts)
 	}
+	if want := "failed (no retry: client error (400))"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Client.Get() error = %v, want it to contain %q", err, want)
+	}
+}
+
+func TestRetryReason(t *testing.T) {
+	tests := []struct {
+		method string
+		status int // 0 for a request that got no response
+		retry  bool
+	}{
+		{http.MethodGet, 0, true},
+		{http.MethodGet, http.StatusBadRequest, false},
+		{http.MethodGet, http.StatusUnauthorized, false},
+		{http.MethodGet, http.StatusForbidden, false},
+		{http.MethodGet, http.StatusNotFound, false},
+		{http.MethodGet, http.StatusConflict, false},
+		{http.MethodGet, http.StatusUnprocessableEntity, false},
+		{http.MethodGet, http.StatusTooManyRequests, true},
+		{http.MethodGet, http.StatusInternalServerError, true},
+		{http.MethodGet, http.StatusBadGateway, true},
+		{http.MethodGet, http.StatusServiceUnavailable, true},
+		{http.MethodGet, http.StatusGatewayTimeout, true},
+		{http.MethodPost, 0, false},
+		{http.MethodPost, http.StatusConflict, false},
+		{http.MethodPost, http.StatusUnprocessableEntity, false},
+		{http.MethodPost, http.StatusTooManyRequests, true},
+		{http.MethodPost, http.StatusInternalServerError, false},
+		{http.MethodPost, http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		var resp *Response
+		err := errors.New("connection reset")
+		if tt.status != 0 {
+			resp = &Response{StatusCode: tt.status}
+			err = &APIError{StatusCode: tt.status}
+		}
+		if got := retryReason(tt.method, resp, err); (got != "") != tt.retry {
+			t.Errorf("retryReason(%s, %d) = %q, want retried %v", tt.method, tt.status, got, tt.retry)
+		}
+	}
+
+	// Responses that aren't JSON or are too large fail again the same way
+	for _, err := range []error{
+		&APIError{StatusCode: http.StatusOK, Code: CodeGateway},
+		&APIError{StatusCode: http.StatusBadGateway, Code: CodeTooLarge},
+	} {
+		resp := &Response{StatusCode: err.(*APIError).StatusCode}
+		if got := retryReason(http.MethodGet, resp, err); got != "" {
+			t.Errorf("retryReason(%v) = %q, want no retry", err, got)
+		}
+	}
+}
+
+func TestClient_NoRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, MaxRetries: -1})
+	_, err := c.Get(context.Background(), "/test", nil)
+
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || reqErr.MaxRetries != 0 {
+		t.Fatalf("Client.Get() error = %#v, want *RequestError with no retries allowed", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+	if want := "failed (no retry: retries disabled)"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Client.Get() error = %v, want it to contain %q", err, want)
+	}
+}
+
+func TestClient_RequestRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		clientRetry  int
+		requestRetry int
+		wantAttempts int
+	}{
+		{"client's", -1, 0, 1},
+		{"none for the request", 3, -1, 1},
+		{"more for the request", -1, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts = 0
+			c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, MaxRetries: tt.clientRetry})
+			_, err := c.Do(context.Background(), Request{Method: http.MethodGet, Endpoint: "/test", MaxRetries: tt.requestRetry})
+
+			var reqErr *RequestError
+			if !errors.As(err, &reqErr) || reqErr.MaxRetries != tt.wantAttempts-1 {
+				t.Fatalf("Client.Do() error = %#v, want *RequestError with %d retries allowed", err, tt.wantAttempts-1)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			want := "failed (no retry: retries disabled)"
+			if tt.wantAttempts > 1 {
+				want = fmt.Sprintf("failed after %d retries", tt.wantAttempts-1)
+			}
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Client.Do() error = %v, want it to contain %q", err, want)
+			}
+		})
+	}
+}
+
+func TestClient_RequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, Timeout: 20 * time.Millisecond, MaxRetries: -1})
+	if _, err := c.Get(context.Background(), "/test", nil); err == nil {
+		t.Error("Client.Get() error = nil, want the client's timeout exceeded")
+	}
+
+	resp, err := c.Do(context.Background(), Request{Method: http.MethodGet, Endpoint: "/test", Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Client.Do() with a longer request timeout error = %v", err)
+	}
+	if string(resp.Body) != `{"ok":true}` {
+		t.Errorf("Body = %q", resp.Body)
+	}
+
+	// A streamed body is read within the request's timeout
+	resp, err = c.Do(context.Background(), Request{Method: http.MethodGet, Endpoint: "/test", Stream: true, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Client.Do() streamed error = %v", err)
+	}
+	defer resp.Stream.Close()
+	if body, err := io.ReadAll(resp.Stream); err != nil || string(body) != `{"ok":true}` {
+		t.Errorf("Stream = %q, %v", body, err)
+	}
+}
+
+func TestClient_RetryLogged(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	var retries []Retry
+	onRetry := func(ctx context.Context, r Retry) { retries = append(retries, r) }
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, MaxRetries: 3, Logger: log, OnRetry: onRetry})
+	if _, err := c.Get(context.Background(), "/test", nil); err != nil {
+		t.Fatalf("Client.Get() error = %v", err)
+	}
+
+	for _, want := range []string{`msg="Retrying API request"`, "endpoint=/test", "attempt=2", `reason="server error (503)"`, "backoff=1s"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log = %q, want %s", buf.String(), want)
+		}
+	}
+	if len(retries) != 1 {
+		t.Fatalf("OnRetry called %d times, want 1", len(retries))
+	}
+	if r := retries[0]; r.Endpoint != "/test" || r.Attempt != 2 || r.Reason != "server error (503)" || r.Backoff != time.Second || r.Err == nil {
+		t.Errorf("OnRetry got %+v", r)
+	}
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{
+		APIKey:    "test-key",
+		BaseURL:   server.URL,
+		RateLimit: 20,
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get(context.Background(), "/test", nil); err != nil {
+			t.Fatalf("Client.Get() error = %v", err)
+		}
+	}
+	// The first request goes straight out, the next two wait 50ms each
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 requests at 20/s took %v, want at least 100ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Get(ctx, "/test", nil); err == nil {
+		t.Error("Client.Get() with a canceled context waiting for the limiter should return error")
+	}
+}
+
+func TestClient_Middleware(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req Request) (*Response, error) {
+				calls = append(calls, name+" "+req.Endpoint)
+				return next(ctx, req)
+			}
+		}
+	}
+
+	c := NewClient(Config{
+		APIKey:     "test-key",
+		BaseURL:    server.URL,
+		MaxRetries: 3,
+		Middleware: []Middleware{trace("outer"), trace("inner")},
+	})
+
+	resp, err := c.Get(context.Background(), "/test", nil)
+	if err != nil {
+		t.Fatalf("Client.Get() error = %v", err)
+	}
+	if want := []string{"outer /test", "inner /test"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("middleware calls = %v, want %v (once per request, outermost first)", calls, want)
+	}
+	if resp.Meta.Retries != 1 {
+		t.Errorf("Meta.Retries = %d, want 1", resp.Meta.Retries)
+	}
+}
+
+func TestClient_Headers(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL,
+		Headers: http.Header{"X-Corp-Auth": {"corp"}, "User-Agent": {"corp-agent"}}})
+	if _, err := c.Get(context.Background(), "/test", nil); err != nil {
+		t.Fatalf("Client.Get() error = %v", err)
+	}
+	if got.Get("X-Corp-Auth") != "corp" || got.Get("User-Agent") != "corp-agent" || got.Get("Authorization") != "Bearer test-key" {
+		t.Errorf("headers = %v, want the extra headers alongside the API key", got)
+	}
+}
+
+func TestClient_RequestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, MaxRetries: 3})
+	_, err := c.Get(context.Background(), "/test", nil)
+
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || reqErr.Retries != 0 {
+		t.Fatalf("Client.Get() error = %#v, want *RequestError with no retries", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Client.Get() error does not wrap the 404 APIError: %v", err)
+	}
+}
+
+func TestClient_Timing(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, RateLimit: 10, MaxRetries: 1})
+	first, err := c.Get(context.Background(), "/test", nil)
+	if err != nil {
+		t.Fatalf("Client.Get() error = %v", err)
+	}
+	if tm := first.Meta.Timing; tm.Attempts != 1 || tm.NewConnections != 1 || tm.Server < 20*time.Millisecond || tm.Backoff != 0 {
+		t.Errorf("first request timing = %+v, want one attempt on a new connection of at least 20ms", tm)
+	}
+
+	// The second request waits for the limiter, fails with a 500, and is
+	// retried after a second's backoff over the kept connection
+	second, err := c.Get(context.Background(), "/test", nil)
+	if err != nil {
+		t.Fatalf("Client.Get() error = %v", err)
+	}
+	tm := second.Meta.Timing
+	if tm.Attempts != 2 || tm.ReusedConnections != 2 || tm.NewConnections != 0 {
+		t.Errorf("second request timing = %+v, want two attempts on the kept connection", tm)
+	}
+	if tm.Backoff < time.Second || tm.RateLimit < 50*time.Millisecond {
+		t.Errorf("second request timing = %+v, want a second's backoff and a limiter wait", tm)
+	}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	_, err = c.Get(context.Background(), "/test", nil)
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || reqErr.Timing.Attempts != 1 {
+		t.Errorf("Client.Get() error = %#v, want a *RequestError timing its attempt", err)
+	}
+}
+
+func TestClient_GetStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"no such endpoint"}}`))
+			return
+		}
+		w.Header().Set("X-API-Units-Consumed", "25")
+		w.Write([]byte(`{"rows":[1,2,3]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, MaxRetries: 1})
+
+	resp, err := c.GetStream(context.Background(), "/rows", nil)
+	if err != nil {
+		t.Fatalf("GetStream() error = %v", err)
+	}
+	defer resp.Stream.Close()
+	if resp.Body != nil {
+		t.Errorf("GetStream() Body = %q, want it left unread", resp.Body)
+	}
+	body, err := io.ReadAll(resp.Stream)
+	if err != nil || string(body) != `{"rows":[1,2,3]}` {
+		t.Errorf("Stream = %q, %v", body, err)
+	}
+	if resp.Meta.UnitsConsumed != 25 {
+		t.Errorf("UnitsConsumed = %d, want 25", resp.Meta.UnitsConsumed)
+	}
+
+	_, err = c.GetStream(context.Background(), "/missing", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "no such endpoint" {
+		t.Errorf("GetStream() error = %v, want the parsed 404 APIError", err)
+	}
+}
+
+func TestClient_NonJSON(t *testing.T) {
+	page := "<html><head><title>502 Bad Gateway</title></head><body>" + strings.Repeat("<p>upstream unavailable</p>", 300) + "</body></html>"
+
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		stream      bool
+		wantMessage string
+		wantExcerpt string
+	}{
+		{
+			name:        "502 HTML",
+			status:      http.StatusBadGateway,
+			contentType: "text/html; charset=utf-8",
+			body:        page,
+			wantMessage: "received non-JSON response (text/html, 8KB) — are you behind a proxy or captive portal?",
+			wantExcerpt: "502 Bad Gateway",
+		},
+		{
+			name:        "200 HTML",
+			status:      http.StatusOK,
+			contentType: "text/html",
+			body:        "<html><body>Please sign in to the guest network</body></html>",
+			wantMessage: "received non-JSON response (text/html, 61B) — are you behind a proxy or captive portal?",
+			wantExcerpt: "Please sign in to the guest network",
+		},
+		{
+			name:        "200 HTML streamed",
+			status:      http.StatusOK,
+			contentType: "text/html",
+			body:        "<html><body>Please sign in to the guest network</body></html>",
+			stream:      true,
+			wantMessage: "received non-JSON response (text/html, 61B) — are you behind a proxy or captive portal?",
+			wantExcerpt: "Please sign in to the guest network",
+		},
+		{
+			name:        "empty 200",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			wantMessage: "received an empty response (application/json)",
+		},
+		{
+			name:        "empty 200 streamed",
+			status:      http.StatusOK,
+			stream:      true,
+			wantMessage: "received an empty response (no content type)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header()["Content-Type"] = []string{tt.contentType}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL})
+			_, err := c.doRequest(context.Background(), encoded{method: http.MethodGet}, server.URL+"/rows", Validators{}, tt.stream, DefaultTimeout, &Timing{})
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("doRequest() error = %v, want *APIError", err)
+			}
+			if apiErr.Code != CodeGateway {
+				t.Errorf("Code = %q, want %q", apiErr.Code, CodeGateway)
+			}
+			if apiErr.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", apiErr.Message, tt.wantMessage)
+			}
+			if apiErr.Excerpt != tt.wantExcerpt {
+				t.Errorf("Excerpt = %q, want %q", apiErr.Excerpt, tt.wantExcerpt)
+			}
+			if apiErr.Suggestion == "" {
+				t.Error("Suggestion is empty")
+			}
+		})
+	}
+}
+
+func TestClient_NonJSONNotRetried(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL})
+	_, err := c.Get(context.Background(), "/rows", nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeGateway {
+		t.Errorf("Get() error = %v, want a %s APIError", err, CodeGateway)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+	if want := "failed (no retry: response isn't JSON)"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Get() error = %v, want it to contain %q", err, want)
+	}
+}
+
+func TestClient_MaxBodySize(t *testing.T) {
+	body := `{"rows":["` + strings.Repeat("x", 100) + `"]}`
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		limit   int64
+		wantErr bool
+	}{
+		{name: "default", limit: 0},
+		{name: "exact", limit: int64(len(body))},
+		{name: "unlimited", limit: -1},
+		{name: "over", limit: int64(len(body)) - 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts = 0
+			c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, MaxBodySize: tt.limit})
+			resp, err := c.Get(context.Background(), "/rows", nil)
+			if !tt.wantErr {
+				if err != nil || string(resp.Body) != body {
+					t.Fatalf("Get() = %v, want the full body", err)
+				}
+				return
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Code != CodeTooLarge {
+				t.Fatalf("Get() error = %v, want a %s APIError", err, CodeTooLarge)
+			}
+			if !strings.Contains(apiErr.Message, "application/json") || apiErr.Suggestion == "" {
+				t.Errorf("error = %+v, want the content type and a suggestion", apiErr)
+			}
+			if attempts != 1 {
+				t.Errorf("attempts = %d, want 1", attempts)
+			}
+		})
+	}
+
+	// Streamed bodies are read by the caller, so they aren't limited
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, MaxBodySize: 10})
+	resp, err := c.GetStream(context.Background(), "/rows", nil)
+	if err != nil {
+		t.Fatalf("GetStream() error = %v", err)
+	}
+	defer resp.Stream.Close()
+	if got, _ := io.ReadAll(resp.Stream); string(got) != body {
+		t.Errorf("streamed body = %q, want %q", got, body)
+	}
 }
@@ -0,0 +1,33 @@
+package client
+
+import "net/http"
+
+// Validators identify a version of a response, from its caching headers, so
+// a later request can ask for it only if it has changed
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// Empty reports whether the response had no caching headers
+func (v Validators) Empty() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// validators returns the caching headers of a response
+func validators(h http.Header) Validators {
+	return Validators{ETag: h.Get("ETag"), LastModified: h.Get("Last-Modified")}
+}
+
+// setConditional makes req conditional on the response having changed from
+// the version v identifies. The ETag is the stronger check, so
+// If-Modified-Since is only sent without one, as RFC 9110 has servers
+// ignore it alongside If-None-Match.
+func setConditional(req *http.Request, v Validators) {
+	switch {
+	case v.ETag != "":
+		req.Header.Set("If-None-Match", v.ETag)
+	case v.LastModified != "":
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// etagServer serves a body with an ETag, honoring If-None-Match with a 304
+// that costs no units
+func etagServer(t *testing.T, etag string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var conditions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditions = append(conditions, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.Header().Set("X-API-Units-Consumed", "0")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("X-API-Units-Consumed", "50")
+		w.Write([]byte(`{"rows":[1,2,3]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &conditions
+}
+
+func TestClient_Conditional(t *testing.T) {
+	server, conditions := etagServer(t, `"v1"`)
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL})
+
+	resp, err := c.Do(context.Background(), Request{Endpoint: "/rows"})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.NotModified || string(resp.Body) != `{"rows":[1,2,3]}` || resp.Meta.Validators.ETag != `"v1"` || resp.Meta.UnitsConsumed != 50 {
+		t.Fatalf("Do() = %+v, want the body and its ETag", resp)
+	}
+
+	for _, stream := range []bool{false, true} {
+		resp, err = c.Do(context.Background(), Request{Endpoint: "/rows", Stream: stream, Conditional: resp.Meta.Validators})
+		if err != nil {
+			t.Fatalf("Do(conditional, stream %v) error = %v", stream, err)
+		}
+		if !resp.NotModified || resp.Body != nil || resp.Stream != nil || resp.Meta.UnitsConsumed != 0 || resp.Meta.Retries != 0 {
+			t.Errorf("Do(conditional, stream %v) = %+v, want not modified without a body", stream, resp)
+		}
+	}
+	if want := []string{"", `"v1"`, `"v1"`}; len(*conditions) != 3 || (*conditions)[0] != want[0] || (*conditions)[2] != want[2] {
+		t.Errorf("If-None-Match sent = %q, want %q", *conditions, want)
+	}
+}
+
+func TestClient_ConditionalChanged(t *testing.T) {
+	server, _ := etagServer(t, `"v2"`)
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL})
+
+	resp, err := c.Do(context.Background(), Request{Endpoint: "/rows", Conditional: Validators{ETag: `"v1"`}})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.NotModified || string(resp.Body) != `{"rows":[1,2,3]}` || resp.Meta.Validators.ETag != `"v2"` {
+		t.Errorf("Do() = %+v, want the changed body and its new ETag", resp)
+	}
+}
+
+func TestSetConditional(t *testing.T) {
+	const date = "Wed, 21 Oct 2015 07:28:00 GMT"
+	tests := []struct {
+		v                   Validators
+		noneMatch, modified string
+	}{
+		{Validators{}, "", ""},
+		{Validators{ETag: `"a"`}, `"a"`, ""},
+		{Validators{LastModified: date}, "", date},
+		{Validators{ETag: `W/"a"`, LastModified: date}, `W/"a"`, ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "http://api.example/", nil)
+		setConditional(req, tt.v)
+		if got := req.Header.Get("If-None-Match"); got != tt.noneMatch {
+			t.Errorf("setConditional(%+v) If-None-Match = %q, want %q", tt.v, got, tt.noneMatch)
+		}
+		if got := req.Header.Get("If-Modified-Since"); got != tt.modified {
+			t.Errorf("setConditional(%+v) If-Modified-Since = %q, want %q", tt.v, got, tt.modified)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+// Example shows the client used as a library, decoding a response into its
+// model. A local server stands in for the API.
+func Example() {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domain_rating":{"domain_rating":91}}`)
+	}))
+	defer api.Close()
+
+	c := client.NewClient(client.Config{
+		APIKey:  "your-api-key",
+		BaseURL: api.URL, // omit to use the Ahrefs API
+	})
+
+	params := url.Values{}
+	params.Set("target", "ahrefs.com")
+	params.Set("mode", "domain")
+	resp, err := c.Get(context.Background(), "/site-explorer/domain-rating", params)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var dr models.DomainRatingResponse
+	if err := json.Unmarshal(resp.Body, &dr); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(dr.DomainRating.DomainRating)
+	// Output: 91
+}
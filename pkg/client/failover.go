@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// baseURLs are the base URLs requests can be sent to, in order of
+// preference. Requests go to the current one; a connection failure moves
+// every later attempt of the client's requests on to the next, and there is
+// no going back, so a region that is down isn't tried again and again.
+type baseURLs struct {
+	mu      sync.Mutex
+	urls    []string
+	current int
+}
+
+// get returns the current base URL
+func (b *baseURLs) get() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.urls[b.current]
+}
+
+// first reports whether base is the preferred base URL
+func (b *baseURLs) first(base string) bool {
+	return base == b.urls[0]
+}
+
+// failover moves on from base, which failed to connect, to the next base
+// URL, unless another request has moved on already. It returns the base URL
+// to send to instead, or "" when base is the last, and whether this call
+// moved on.
+func (b *baseURLs) failover(base string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.urls[b.current] != base {
+		return b.urls[b.current], false
+	}
+	if b.current == len(b.urls)-1 {
+		return "", false
+	}
+	b.current++
+	return b.urls[b.current], true
+}
+
+// isConnectionFailure reports whether err is a failure to reach the API at
+// all, resolving its host or connecting to it, rather than an error in a
+// response or a request cut off once sent. Only such failures say the base
+// URL is unreachable, and only they leave no doubt that the request wasn't
+// received.
+func isConnectionFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// unreachableURL returns a base URL nothing listens on
+func unreachableURL(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return "http://" + addr
+}
+
+// countingServer returns a server answering {} and counting its requests
+func countingServer(t *testing.T, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestClient_Failover(t *testing.T) {
+	down := unreachableURL(t)
+	backup, hits := countingServer(t, http.StatusOK)
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	var retries int
+	c := NewClient(Config{APIKey: "test-key", BaseURLs: []string{down, backup.URL}, MaxRetries: -1, Logger: log,
+		OnRetry: func(context.Context, Retry) { retries++ }})
+
+	// Failing over isn't a retry, so works with none allowed
+	resp, err := c.Get(context.Background(), "/test", nil)
+	if err != nil {
+		t.Fatalf("Client.Get() error = %v", err)
+	}
+	if resp.Meta.BaseURL != backup.URL || resp.Meta.Retries != 0 || resp.Meta.Timing.Attempts != 2 || retries != 0 {
+		t.Errorf("Meta = %+v, retries = %d, want the backup's response after 2 attempts and no retries", resp.Meta, retries)
+	}
+	if want := fmt.Sprintf("Can't reach %s; sending requests to %s instead", down, backup.URL); !strings.Contains(buf.String(), want) {
+		t.Errorf("log = %q, want %q", buf.String(), want)
+	}
+	if c.BaseURL() != backup.URL {
+		t.Errorf("BaseURL() = %s, want the backup", c.BaseURL())
+	}
+
+	// Later requests go to the backup straight away
+	resp, err = c.Get(context.Background(), "/test", nil)
+	if err != nil {
+		t.Fatalf("Client.Get() again error = %v", err)
+	}
+	if resp.Meta.Timing.Attempts != 1 || hits.Load() != 2 || strings.Count(buf.String(), "Can't reach") != 1 {
+		t.Errorf("second request Meta = %+v, backup hits = %d, want 1 attempt to the backup", resp.Meta, hits.Load())
+	}
+}
+
+func TestClient_NoFailoverOnHTTPError(t *testing.T) {
+	primary, primaryHits := countingServer(t, http.StatusServiceUnavailable)
+	backup, backupHits := countingServer(t, http.StatusOK)
+
+	c := NewClient(Config{APIKey: "test-key", BaseURLs: []string{primary.URL, backup.URL}, MaxRetries: -1})
+	_, err := c.Get(context.Background(), "/test", nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Client.Get() error = %v, want the primary's 503", err)
+	}
+	if primaryHits.Load() != 1 || backupHits.Load() != 0 || c.BaseURL() != primary.URL {
+		t.Errorf("hits = %d, %d, BaseURL() = %s, want the primary kept", primaryHits.Load(), backupHits.Load(), c.BaseURL())
+	}
+}
+
+func TestClient_FailoverExhausted(t *testing.T) {
+	first, last := unreachableURL(t), unreachableURL(t)
+
+	c := NewClient(Config{APIKey: "test-key", BaseURLs: []string{first, last}, MaxRetries: -1})
+	_, err := c.Get(context.Background(), "/test", nil)
+
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || !strings.HasPrefix(reqErr.URL, last) || reqErr.Timing.Attempts != 2 {
+		t.Fatalf("Client.Get() error = %#v, want a RequestError of the last base URL after 2 attempts", err)
+	}
+	if c.BaseURL() != last {
+		t.Errorf("BaseURL() = %s, want the last", c.BaseURL())
+	}
+}
+
+func TestClient_SingleBaseURLUnreachable(t *testing.T) {
+	down := unreachableURL(t)
+
+	c := NewClient(Config{APIKey: "test-key", BaseURL: down, MaxRetries: -1})
+	_, err := c.Get(context.Background(), "/test", nil)
+
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || reqErr.URL != down+"/test" || reqErr.Timing.Attempts != 1 {
+		t.Fatalf("Client.Get() error = %#v, want a RequestError after 1 attempt", err)
+	}
+}
+
+func TestIsConnectionFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{fmt.Errorf("HTTP request failed: %w", &net.DNSError{Err: "no such host", Name: "api.example"}), true},
+		{&net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, false},
+		{&APIError{StatusCode: http.StatusBadGateway}, false},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := isConnectionFailure(tt.err); got != tt.want {
+			t.Errorf("isConnectionFailure(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// DefaultMaxQueryLength is the longest query string sent before a request's
+// parameters move to its body. Proxies and servers commonly reject URLs
+// longer than 8KB, which a long --where expression can reach.
+const DefaultMaxQueryLength = 4096
+
+// ParamEncoding is how a request's parameters are sent
+type ParamEncoding string
+
+const (
+	// ParamsQuery sends the parameters in the query string, unless it is
+	// longer than the client's MaxQueryLength, when they are form-encoded
+	// in the body instead
+	ParamsQuery ParamEncoding = ""
+
+	// ParamsForm sends the parameters form-encoded in the body
+	ParamsForm ParamEncoding = "form"
+
+	// ParamsJSON sends the parameters in the body as a JSON object, with a
+	// string for a parameter's single value and a list for several
+	ParamsJSON ParamEncoding = "json"
+)
+
+// encoded is a request's parameters as sent
+type encoded struct {
+	method      string
+	query       string
+	body        []byte
+	contentType string
+}
+
+// Encoding returns how the parameters of req are sent: its Body, else the
+// client's BodyParams for its endpoint, else in the query string unless it
+// is longer than MaxQueryLength
+func (c *Client) Encoding(req Request) ParamEncoding {
+	if req.Body != ParamsQuery {
+		return req.Body
+	}
+	if how, ok := c.bodyParams[req.Endpoint]; ok {
+		return how
+	}
+	if c.maxQuery >= 0 && len(req.Params.Encode()) > c.maxQuery {
+		return ParamsForm
+	}
+	return ParamsQuery
+}
+
+// encodeParams returns req's parameters as sent. A GET whose parameters go
+// in the body is sent as a POST, which the API accepts for its reads; it is
+// still retried as a GET would be.
+func (c *Client) encodeParams(req Request) (encoded, error) {
+	e := encoded{method: req.Method, query: req.Params.Encode()}
+	if e.method == "" {
+		e.method = http.MethodGet
+	}
+
+	switch c.Encoding(req) {
+	case ParamsQuery:
+		return e, nil
+	case ParamsForm:
+		e.body, e.contentType = []byte(e.query), "application/x-www-form-urlencoded"
+	case ParamsJSON:
+		body, err := json.Marshal(jsonParams(req.Params))
+		if err != nil {
+			return e, err
+		}
+		e.body, e.contentType = body, "application/json"
+	default:
+		return e, fmt.Errorf("unknown parameter encoding %q", c.Encoding(req))
+	}
+	e.query = ""
+	if e.method == http.MethodGet || e.method == http.MethodHead {
+		e.method = http.MethodPost
+	}
+	return e, nil
+}
+
+// Body returns the body the parameters of req are sent in, and how it is
+// encoded, or nil when they are sent in the query string
+func (c *Client) Body(req Request) ([]byte, ParamEncoding, error) {
+	e, err := c.encodeParams(req)
+	return e.body, c.Encoding(req), err
+}
+
+// jsonParams returns params as a JSON object's fields
+func jsonParams(params url.Values) map[string]interface{} {
+	fields := make(map[string]interface{}, len(params))
+	for name, values := range params {
+		if len(values) == 1 {
+			fields[name] = values[0]
+		} else {
+			fields[name] = values
+		}
+	}
+	return fields
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// sentRequest is what the server received
+type sentRequest struct {
+	method      string
+	query       string
+	contentType string
+	body        string
+}
+
+func TestClient_ParamEncoding(t *testing.T) {
+	var got sentRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = sentRequest{r.Method, r.URL.RawQuery, r.Header.Get("Content-Type"), string(body)}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	short := url.Values{"target": {"ahrefs.com"}}
+	long := url.Values{"target": {"ahrefs.com"}, "where": {strings.Repeat("x", 100)}}
+	tests := []struct {
+		name   string
+		config Config
+		req    Request
+		want   sentRequest
+	}{
+		{
+			name: "short query",
+			req:  Request{Method: http.MethodGet, Endpoint: "/test", Params: short},
+			want: sentRequest{method: http.MethodGet, query: "target=ahrefs.com"},
+		},
+		{
+			name: "long query",
+			req:  Request{Method: http.MethodGet, Endpoint: "/test", Params: long},
+			want: sentRequest{method: http.MethodPost, contentType: "application/x-www-form-urlencoded", body: long.Encode()},
+		},
+		{
+			name:   "long query without a limit",
+			config: Config{MaxQueryLength: -1},
+			req:    Request{Method: http.MethodGet, Endpoint: "/test", Params: long},
+			want:   sentRequest{method: http.MethodGet, query: long.Encode()},
+		},
+		{
+			name:   "endpoint sending a form",
+			config: Config{BodyParams: map[string]ParamEncoding{"/form": ParamsForm}},
+			req:    Request{Endpoint: "/form", Params: short},
+			want:   sentRequest{method: http.MethodPost, contentType: "application/x-www-form-urlencoded", body: "target=ahrefs.com"},
+		},
+		{
+			name: "request sending JSON",
+			req:  Request{Method: http.MethodPost, Endpoint: "/test", Params: url.Values{"target": {"ahrefs.com"}, "select": {"a", "b"}}, Body: ParamsJSON},
+			want: sentRequest{method: http.MethodPost, contentType: "application/json", body: `{"select":["a","b"],"target":"ahrefs.com"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.config.MaxQueryLength == 0 {
+				tt.config.MaxQueryLength = 64
+			}
+			tt.config.APIKey, tt.config.BaseURL = "test-key", server.URL
+			c := NewClient(tt.config)
+
+			got = sentRequest{}
+			if _, err := c.Do(context.Background(), tt.req); err != nil {
+				t.Fatalf("Client.Do() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("sent %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_ParamEncodingRetried(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		bodies = append(bodies, r.PostForm.Get("where"))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	// A GET sent as a POST is retried, with its body each time
+	c := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, MaxQueryLength: 8, MaxRetries: 1})
+	where := `{"field":"domain_rating","is":["gte",50]}`
+	if _, err := c.Get(context.Background(), "/test", url.Values{"where": {where}}); err != nil {
+		t.Fatalf("Client.Get() error = %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != where || bodies[1] != where {
+		t.Errorf("where sent = %q, want %q twice", bodies, where)
+	}
+}
+
+func TestClient_Body(t *testing.T) {
+	c := NewClient(Config{APIKey: "test-key", BodyParams: map[string]ParamEncoding{"/json": ParamsJSON}})
+
+	body, how, err := c.Body(Request{Endpoint: "/json", Params: url.Values{"limit": {"10"}}})
+	var fields map[string]string
+	if err != nil || how != ParamsJSON || json.Unmarshal(body, &fields) != nil || fields["limit"] != "10" {
+		t.Errorf("Body() = %s, %q, %v, want the JSON parameters", body, how, err)
+	}
+	if body, how, _ := c.Body(Request{Endpoint: "/test", Params: url.Values{"limit": {"10"}}}); body != nil || how != ParamsQuery {
+		t.Errorf("Body() = %s, %q, want the parameters in the query string", body, how)
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing is where the time of a request went, summed over its attempts.
+// Connection setup is only spent on new connections; a request sent over an
+// idle connection kept from an earlier one skips it.
+type Timing struct {
+	DNS     time.Duration // resolving the API's host
+	Connect time.Duration // opening TCP connections
+	TLS     time.Duration // TLS handshakes
+
+	// Server is from the request being written to the first byte of the
+	// response, the time the API took
+	Server time.Duration
+
+	// Transfer is reading the response body, when it isn't streamed
+	Transfer time.Duration
+
+	Backoff   time.Duration // sleeping before retries
+	RateLimit time.Duration // waiting for the client's rate limit
+
+	Attempts          int
+	NewConnections    int
+	ReusedConnections int
+}
+
+// Add adds the timings of o to t
+func (t *Timing) Add(o Timing) {
+	t.DNS += o.DNS
+	t.Connect += o.Connect
+	t.TLS += o.TLS
+	t.Server += o.Server
+	t.Transfer += o.Transfer
+	t.Backoff += o.Backoff
+	t.RateLimit += o.RateLimit
+	t.Attempts += o.Attempts
+	t.NewConnections += o.NewConnections
+	t.ReusedConnections += o.ReusedConnections
+}
+
+// tracer records the phases of an attempt. The transport may call it from
+// several goroutines, such as when dialing addresses in parallel, and after
+// the attempt is over, when a losing dial finishes.
+type tracer struct {
+	mu    sync.Mutex
+	t     Timing
+	start map[string]time.Time
+	done  bool
+}
+
+// withTracer returns ctx tracing the connection and request phases of an
+// attempt
+func withTracer(ctx context.Context) (context.Context, *tracer) {
+	tr := &tracer{start: map[string]time.Time{}}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { tr.begin("dns") },
+		DNSDone:           func(httptrace.DNSDoneInfo) { tr.end("dns", &tr.t.DNS) },
+		ConnectStart:      func(_, addr string) { tr.begin("connect " + addr) },
+		ConnectDone:       func(_, addr string, _ error) { tr.end("connect "+addr, &tr.t.Connect) },
+		TLSHandshakeStart: func() { tr.begin("tls") },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { tr.end("tls", &tr.t.TLS) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			if info.Reused {
+				tr.t.ReusedConnections++
+			} else {
+				tr.t.NewConnections++
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { tr.begin("server") },
+		GotFirstResponseByte: func() { tr.end("server", &tr.t.Server) },
+	}), tr
+}
+
+// begin records the start of phase
+func (tr *tracer) begin(phase string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.start[phase] = time.Now()
+}
+
+// end adds the time since phase began to d
+func (tr *tracer) end(phase string, d *time.Duration) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if start, ok := tr.start[phase]; ok && !tr.done {
+		*d += time.Since(start)
+		delete(tr.start, phase)
+	}
+}
+
+// transfer adds the time spent reading a body, from start
+func (tr *tracer) transfer(start time.Time) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.t.Transfer += time.Since(start)
+}
+
+// finish returns the attempt's timing, ignoring any phases ending later
+func (tr *tracer) finish() Timing {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.done = true
+	return tr.t
+}
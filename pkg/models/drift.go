@@ -0,0 +1,113 @@
+package models
+
+import (
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Drift compares a generically decoded response with the model type it is
+// decoded into. Unknown lists the fields the response has that the model
+// lacks; Missing lists model fields absent from the response. Fields are
+// dotted paths, with [] marking list elements, e.g. backlinks[].title.
+type Drift struct {
+	Unknown []string
+	Missing []string
+}
+
+// Empty reports whether the response and the model agree
+func (d Drift) Empty() bool {
+	return len(d.Unknown) == 0 && len(d.Missing) == 0
+}
+
+// CompareFields diffs the keys of data, decoded into interface{}, against the
+// JSON fields of the model type t. A field counts as present in a list if
+// any row has it.
+func CompareFields(t reflect.Type, data interface{}) Drift {
+	var d Drift
+	compare(&d, t, data, "")
+	sort.Strings(d.Unknown)
+	sort.Strings(d.Missing)
+	return d
+}
+
+func compare(d *Drift, t reflect.Type, data interface{}, path string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for key := range obj {
+			if _, ok := fields[key]; !ok {
+				d.Unknown = append(d.Unknown, path+key)
+			}
+		}
+		for name, ft := range fields {
+			v, ok := obj[name]
+			if !ok {
+				d.Missing = append(d.Missing, path+name)
+				continue
+			}
+			compare(d, ft, v, path+name+".")
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := data.([]interface{})
+		if !ok || len(list) == 0 {
+			return
+		}
+		prefix := strings.TrimSuffix(path, ".") + "[]."
+		compare(d, t.Elem(), mergeRows(list), prefix)
+	}
+}
+
+// mergeRows returns the union of the keys of the object rows in list, each
+// with its first non-null value
+func mergeRows(list []interface{}) interface{} {
+	merged := map[string]interface{}{}
+	objects := false
+	for _, row := range list {
+		obj, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		objects = true
+		for k, v := range obj {
+			if merged[k] == nil {
+				merged[k] = v
+			}
+		}
+	}
+	if !objects {
+		return list[0]
+	}
+	return merged
+}
+
+// jsonFields maps the JSON names of t's exported fields, including those of
+// embedded structs, to their types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			maps.Copy(fields, jsonFields(f.Type))
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// loadFixture decodes testdata/name generically and applies edit to its
+// first row under list
+func loadFixture(t *testing.T, name, list string, edit func(row map[string]interface{})) interface{} {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatal(err)
+	}
+	if edit != nil {
+		edit(body[list].([]interface{})[0].(map[string]interface{}))
+	}
+	return body
+}
+
+func TestCompareFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		model reflect.Type
+		data  interface{}
+		want  Drift
+	}{
+		{
+			name:  "fixture matches model",
+			model: reflect.TypeOf(BacklinksResponse{}),
+			data:  loadFixture(t, "backlinks.json", "backlinks", nil),
+		},
+		{
+			name:  "added and renamed fields",
+			model: reflect.TypeOf(BacklinksResponse{}),
+			data: loadFixture(t, "backlinks.json", "backlinks", func(row map[string]interface{}) {
+				row["is_spam"] = false
+				row["page_title"] = row["title"]
+				delete(row, "title")
+			}),
+			// The second row still has title, so it isn't missing
+			want: Drift{Unknown: []string{"backlinks[].is_spam", "backlinks[].page_title"}},
+		},
+		{
+			name:  "field dropped from every row",
+			model: reflect.TypeOf(TopPagesResponse{}),
+			data: loadFixture(t, "top_pages.json", "pages", func(row map[string]interface{}) {
+				delete(row, "url_rating")
+			}),
+			want: Drift{Missing: []string{"pages[].url_rating"}},
+		},
+		{
+			name:  "nested object",
+			model: reflect.TypeOf(DomainRatingResponse{}),
+			data: map[string]interface{}{
+				"domain_rating": map[string]interface{}{"domain_rating": 91.0, "ahrefs_rank": 3.0},
+				"meta":          map[string]interface{}{},
+			},
+			want: Drift{Unknown: []string{"domain_rating.ahrefs_rank", "meta"}},
+		},
+		{
+			name:  "empty list",
+			model: reflect.TypeOf(AnchorsResponse{}),
+			data:  map[string]interface{}{"anchors": []interface{}{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareFields(tt.model, tt.data)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareFields() = %+v, want %+v", got, tt.want)
+			}
+			if got.Empty() != tt.want.Empty() {
+				t.Errorf("Empty() = %v, want %v", got.Empty(), tt.want.Empty())
+			}
+		})
+	}
+}
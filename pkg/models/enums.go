@@ -0,0 +1,189 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Mode is how a target is matched: as an exact URL, a domain, a URL prefix,
+// or a domain with its subdomains
+type Mode string
+
+const (
+	ModeExact      Mode = "exact"
+	ModeDomain     Mode = "domain"
+	ModePrefix     Mode = "prefix"
+	ModeSubdomains Mode = "subdomains"
+)
+
+// Modes returns the target modes
+func Modes() []Mode {
+	return []Mode{ModeExact, ModeDomain, ModePrefix, ModeSubdomains}
+}
+
+func (m Mode) String() string { return string(m) }
+
+// IsValid reports whether m is one of Modes
+func (m Mode) IsValid() bool { return slices.Contains(Modes(), m) }
+
+// ParseMode returns the mode named s, ignoring case and surrounding spaces
+func ParseMode(s string) (Mode, error) {
+	return parseEnum("mode", s, Modes())
+}
+
+// History is which backlinks a list includes: the live ones, those seen
+// since a date, or every one ever seen
+type History string
+
+const (
+	HistoryLive    History = "live"
+	HistoryAllTime History = "all_time"
+)
+
+// historySince prefixes the date of a History of the links seen since then
+const historySince = "since:"
+
+// HistorySince returns the History of the links seen since date
+func HistorySince(date time.Time) History {
+	return History(historySince + date.Format(time.DateOnly))
+}
+
+func (h History) String() string { return string(h) }
+
+// IsValid reports whether h is HistoryLive, HistoryAllTime, or a
+// HistorySince date
+func (h History) IsValid() bool {
+	if h == HistoryLive || h == HistoryAllTime {
+		return true
+	}
+	date, ok := strings.CutPrefix(string(h), historySince)
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(time.DateOnly, date)
+	return err == nil
+}
+
+// ParseHistory returns the History s names, such as live or
+// since:2024-01-31, ignoring case and surrounding spaces
+func ParseHistory(s string) (History, error) {
+	h := History(strings.ToLower(strings.TrimSpace(s)))
+	if !h.IsValid() {
+		return "", fmt.Errorf("invalid history %q (use %s, %s, or %sYYYY-MM-DD)", s, HistoryLive, HistoryAllTime, historySince)
+	}
+	return h, nil
+}
+
+// Aggregation is what referring domains are rolled up by: their TLD or
+// their registrable domain
+type Aggregation string
+
+const (
+	AggregationTLD         Aggregation = "tld"
+	AggregationRegistrable Aggregation = "registrable"
+)
+
+// Aggregations returns the ways referring domains are rolled up
+func Aggregations() []Aggregation {
+	return []Aggregation{AggregationTLD, AggregationRegistrable}
+}
+
+func (a Aggregation) String() string { return string(a) }
+
+// IsValid reports whether a is one of Aggregations
+func (a Aggregation) IsValid() bool { return slices.Contains(Aggregations(), a) }
+
+// ParseAggregation returns the aggregation named s, ignoring case and
+// surrounding spaces
+func ParseAggregation(s string) (Aggregation, error) {
+	return parseEnum("aggregation", s, Aggregations())
+}
+
+// Interval is the granularity of a history's rows
+type Interval string
+
+const (
+	IntervalDaily   Interval = "daily"
+	IntervalWeekly  Interval = "weekly"
+	IntervalMonthly Interval = "monthly"
+)
+
+// Intervals returns the granularities of a history, finest first
+func Intervals() []Interval {
+	return []Interval{IntervalDaily, IntervalWeekly, IntervalMonthly}
+}
+
+func (i Interval) String() string { return string(i) }
+
+// IsValid reports whether i is one of Intervals
+func (i Interval) IsValid() bool { return slices.Contains(Intervals(), i) }
+
+// ParseInterval returns the interval named s, ignoring case and surrounding
+// spaces
+func ParseInterval(s string) (Interval, error) {
+	return parseEnum("interval", s, Intervals())
+}
+
+// Country is a lowercase ISO 3166-1 alpha-2 country code, such as us or gb
+type Country string
+
+// countries are the ISO 3166-1 alpha-2 codes, sorted
+var countries = strings.Fields(`
+	ad ae af ag ai al am ao aq ar as at au aw ax az ba bb bd be bf bg bh bi bj
+	bl bm bn bo bq br bs bt bv bw by bz ca cc cd cf cg ch ci ck cl cm cn co cr
+	cu cv cw cx cy cz de dj dk dm do dz ec ee eg eh er es et fi fj fk fm fo fr
+	ga gb gd ge gf gg gh gi gl gm gn gp gq gr gs gt gu gw gy hk hm hn hr ht hu
+	id ie il im in io iq ir is it je jm jo jp ke kg kh ki km kn kp kr kw ky kz
+	la lb lc li lk lr ls lt lu lv ly ma mc md me mf mg mh mk ml mm mn mo mp mq
+	mr ms mt mu mv mw mx my mz na nc ne nf ng ni nl no np nr nu nz om pa pe pf
+	pg ph pk pl pm pn pr ps pt pw py qa re ro rs ru rw sa sb sc sd se sg sh si
+	sj sk sl sm sn so sr ss st sv sx sy sz tc td tf tg th tj tk tl tm tn to tr
+	tt tv tw tz ua ug um us uy uz va vc ve vg vi vn vu wf ws ye yt za zm zw`)
+
+// Countries returns the country codes, sorted
+func Countries() []Country {
+	codes := make([]Country, len(countries))
+	for i, code := range countries {
+		codes[i] = Country(code)
+	}
+	return codes
+}
+
+func (c Country) String() string { return string(c) }
+
+// IsValid reports whether c is one of Countries
+func (c Country) IsValid() bool {
+	_, found := slices.BinarySearch(countries, string(c))
+	return found
+}
+
+// ParseCountry returns the country code s, ignoring case and surrounding
+// spaces
+func ParseCountry(s string) (Country, error) {
+	c := Country(strings.ToLower(strings.TrimSpace(s)))
+	if !c.IsValid() {
+		return "", fmt.Errorf("invalid country %q (use a two-letter ISO 3166-1 code, e.g. us or gb)", s)
+	}
+	return c, nil
+}
+
+// Strings returns the names of enum values, such as those of Modes
+func Strings[T ~string](values []T) []string {
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = string(v)
+	}
+	return names
+}
+
+// parseEnum returns the one of values s names, ignoring case and
+// surrounding spaces, or an error naming kind and the values
+func parseEnum[T ~string](kind, s string, values []T) (T, error) {
+	v := T(strings.ToLower(strings.TrimSpace(s)))
+	if !slices.Contains(values, v) {
+		return "", fmt.Errorf("invalid %s %q (use %s)", kind, s, strings.Join(Strings(values), ", "))
+	}
+	return v, nil
+}
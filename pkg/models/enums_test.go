@@ -0,0 +1,98 @@
+package models
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_RoundTrip(t *testing.T) {
+	for _, m := range Modes() {
+		if got, err := ParseMode(m.String()); err != nil || got != m {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q", m, got, err, m)
+		}
+	}
+	for _, a := range Aggregations() {
+		if got, err := ParseAggregation(a.String()); err != nil || got != a {
+			t.Errorf("ParseAggregation(%q) = %q, %v, want %q", a, got, err, a)
+		}
+	}
+	for _, i := range Intervals() {
+		if got, err := ParseInterval(i.String()); err != nil || got != i {
+			t.Errorf("ParseInterval(%q) = %q, %v, want %q", i, got, err, i)
+		}
+	}
+	for _, c := range Countries() {
+		if got, err := ParseCountry(c.String()); err != nil || got != c {
+			t.Errorf("ParseCountry(%q) = %q, %v, want %q", c, got, err, c)
+		}
+	}
+	for _, h := range []History{HistoryLive, HistoryAllTime, HistorySince(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))} {
+		if got, err := ParseHistory(h.String()); err != nil || got != h {
+			t.Errorf("ParseHistory(%q) = %q, %v, want %q", h, got, err, h)
+		}
+	}
+}
+
+func TestParse_Normalizes(t *testing.T) {
+	if got, err := ParseMode(" Subdomains "); err != nil || got != ModeSubdomains {
+		t.Errorf("ParseMode() = %q, %v, want %q", got, err, ModeSubdomains)
+	}
+	if got, err := ParseCountry("GB"); err != nil || got != "gb" {
+		t.Errorf("ParseCountry() = %q, %v, want gb", got, err)
+	}
+	if got, err := ParseHistory("Since:2024-01-31"); err != nil || got != "since:2024-01-31" {
+		t.Errorf("ParseHistory() = %q, %v, want since:2024-01-31", got, err)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) error
+		input string
+		want  string
+	}{
+		{"mode", func(s string) error { _, err := ParseMode(s); return err }, "domains", `invalid mode "domains" (use exact, domain, prefix, subdomains)`},
+		{"empty mode", func(s string) error { _, err := ParseMode(s); return err }, "", `invalid mode ""`},
+		{"aggregation", func(s string) error { _, err := ParseAggregation(s); return err }, "sld", `invalid aggregation "sld" (use tld, registrable)`},
+		{"interval", func(s string) error { _, err := ParseInterval(s); return err }, "yearly", `invalid interval "yearly" (use daily, weekly, monthly)`},
+		{"country", func(s string) error { _, err := ParseCountry(s); return err }, "uk", `invalid country "uk"`},
+		{"country name", func(s string) error { _, err := ParseCountry(s); return err }, "usa", `invalid country "usa"`},
+		{"history", func(s string) error { _, err := ParseHistory(s); return err }, "new", `invalid history "new" (use live, all_time, or since:YYYY-MM-DD)`},
+		{"history date", func(s string) error { _, err := ParseHistory(s); return err }, "since:2024-02-30", `invalid history "since:2024-02-30"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parse(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	for _, v := range []interface{ IsValid() bool }{Mode("Domain"), Aggregation(""), Interval("hourly"), Country("xx"), History("since:")} {
+		if v.IsValid() {
+			t.Errorf("%T(%q).IsValid() = true, want false", v, v)
+		}
+	}
+	for _, v := range []interface{ IsValid() bool }{ModeDomain, AggregationTLD, IntervalWeekly, Country("us"), HistoryLive, History("since:2024-01-31")} {
+		if !v.IsValid() {
+			t.Errorf("%T(%q).IsValid() = false, want true", v, v)
+		}
+	}
+}
+
+func TestCountries(t *testing.T) {
+	codes := Strings(Countries())
+	if len(codes) != 249 || !slices.IsSorted(codes) {
+		t.Errorf("Countries() = %d codes, sorted %v, want 249 sorted", len(codes), slices.IsSorted(codes))
+	}
+	if got, want := Strings(Modes()), []string{"exact", "domain", "prefix", "subdomains"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Strings(Modes()) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+// KeywordsOverviewResponse represents Keywords Explorer metrics for a list of
+// keywords
+type KeywordsOverviewResponse struct {
+	Keywords []KeywordOverview `json:"keywords"`
+}
+
+// KeywordOverview represents the metrics of a single keyword. Metrics are
+// null when Ahrefs has no data for the keyword.
+type KeywordOverview struct {
+	Keyword    string   `json:"keyword"`
+	Volume     *int     `json:"volume"`
+	Difficulty *int     `json:"difficulty"`
+	CPC        *float64 `json:"cpc"`
+	Clicks     *int     `json:"clicks"`
+}
+
+// SERPOverviewResponse represents the search results of a keyword
+type SERPOverviewResponse struct {
+	Positions []SERPPosition `json:"positions"`
+}
+
+// SERPPosition represents a single search result
+type SERPPosition struct {
+	Position     int      `json:"position"`
+	URL          string   `json:"url"`
+	DomainRating *float64 `json:"domain_rating"`
+}
+
+// KeywordDifficulty summarizes a single keyword: its Keywords Explorer
+// metrics and the domain rating of the top-ranking page
+type KeywordDifficulty struct {
+	Keyword         string   `json:"keyword"`
+	Country         string   `json:"country"`
+	Difficulty      *int     `json:"difficulty"`
+	Volume          *int     `json:"volume"`
+	CPC             *float64 `json:"cpc"`
+	Clicks          *int     `json:"clicks"`
+	TopURL          string   `json:"top_url,omitempty"`
+	TopDomainRating *float64 `json:"top_domain_rating"`
+}
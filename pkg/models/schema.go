@@ -0,0 +1,130 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SchemaDialect is the JSON Schema version of the schemas GenerateSchema
+// returns
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema describing the JSON encoding of a model
+type Schema struct {
+	Dialect              string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 SchemaType         `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+}
+
+// SchemaType is the JSON types a value may have, such as integer, or
+// integer and null for a nullable field. A single type is encoded as a
+// string, as JSON Schema usually writes it.
+type SchemaType []string
+
+// MarshalJSON encodes a single type as a string and several as a list
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// GenerateSchema returns the JSON Schema of the model type t, titled with
+// its name. Fields are named by their json tags, and those without omitempty
+// are required. Pointers, lists, and maps, which encode as null when nil,
+// are nullable. A field's enum tag lists the values it takes, comma-separated.
+func GenerateSchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	s := schemaOf(t)
+	s.Dialect = SchemaDialect
+	s.Title = t.Name()
+	return s
+}
+
+// schemaOf returns the schema of values of type t
+func schemaOf(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(schemaOf(t.Elem()))
+	case reflect.String:
+		if t == reflect.TypeOf(json.Number("")) {
+			return &Schema{Type: SchemaType{"number"}}
+		}
+		return &Schema{Type: SchemaType{"string"}}
+	case reflect.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: SchemaType{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: SchemaType{"number"}}
+	case reflect.Slice, reflect.Array:
+		s := &Schema{Type: SchemaType{"array"}, Items: schemaOf(t.Elem())}
+		if t.Kind() == reflect.Slice {
+			return nullable(s)
+		}
+		return s
+	case reflect.Map:
+		return nullable(&Schema{Type: SchemaType{"object"}, AdditionalProperties: schemaOf(t.Elem())})
+	case reflect.Struct:
+		s := &Schema{Type: SchemaType{"object"}, Properties: map[string]*Schema{}}
+		addProperties(s, t)
+		sort.Strings(s.Required)
+		return s
+	}
+	// Interfaces hold any JSON value
+	return &Schema{}
+}
+
+// addProperties adds the JSON fields of the struct type t to s, with those
+// of embedded structs in place of the structs, as encoding/json flattens
+// them
+func addProperties(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			addProperties(s, f.Type)
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := schemaOf(f.Type)
+		if enum := f.Tag.Get("enum"); enum != "" {
+			for _, v := range strings.Split(enum, ",") {
+				prop.Enum = append(prop.Enum, v)
+			}
+			if len(prop.Type) > 1 {
+				prop.Enum = append(prop.Enum, nil)
+			}
+		}
+		s.Properties[name] = prop
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// nullable returns s allowing null as well. A schema of any value already
+// does.
+func nullable(s *Schema) *Schema {
+	if len(s.Type) > 0 && !slices.Contains(s.Type, "null") {
+		s.Type = append(s.Type, "null")
+	}
+	return s
+}
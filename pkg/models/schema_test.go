@@ -0,0 +1,120 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/internal/fixture/golden"
+)
+
+// schemaJSON returns the schema of v's type, indented
+func schemaJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.MarshalIndent(GenerateSchema(reflect.TypeOf(v)), "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent() error = %v", err)
+	}
+	return append(data, '\n')
+}
+
+// TestGenerateSchema_Backlinks compares the schema of BacklinksResponse with
+// testdata/backlinks.schema.json. go test -update rewrites it.
+func TestGenerateSchema_Backlinks(t *testing.T) {
+	golden.Assert(t, "testdata/backlinks.schema.json", schemaJSON(t, &BacklinksResponse{}))
+}
+
+func TestGenerateSchema_Fields(t *testing.T) {
+	type row struct {
+		Name     string         `json:"name"`
+		Count    int            `json:"count,omitempty"`
+		Rating   *float64       `json:"rating"`
+		Tags     StringList     `json:"tags"`
+		Movement *string        `json:"movement" enum:"up,down"`
+		Extra    map[string]int `json:"extra,omitempty"`
+		Raw      interface{}    `json:"raw"`
+		Skipped  string         `json:"-"`
+		Nested   []json.Number  `json:"nested"`
+	}
+
+	got := string(schemaJSON(t, row{}))
+	want := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "row",
+  "type": "object",
+  "properties": {
+    "count": {
+      "type": "integer"
+    },
+    "extra": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "additionalProperties": {
+        "type": "integer"
+      }
+    },
+    "movement": {
+      "type": [
+        "string",
+        "null"
+      ],
+      "enum": [
+        "up",
+        "down",
+        null
+      ]
+    },
+    "name": {
+      "type": "string"
+    },
+    "nested": {
+      "type": [
+        "array",
+        "null"
+      ],
+      "items": {
+        "type": "number"
+      }
+    },
+    "rating": {
+      "type": [
+        "number",
+        "null"
+      ]
+    },
+    "raw": {},
+    "tags": {
+      "type": [
+        "array",
+        "null"
+      ],
+      "items": {
+        "type": "string"
+      }
+    }
+  },
+  "required": [
+    "movement",
+    "name",
+    "nested",
+    "rating",
+    "raw",
+    "tags"
+  ]
+}
+`
+	if got != want {
+		t.Errorf("GenerateSchema() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGenerateSchema_Embedded(t *testing.T) {
+	s := GenerateSchema(reflect.TypeOf(ClusteredKeyword{}))
+	for _, name := range []string{"keyword", "position", "cluster_id", "cluster_label"} {
+		if s.Properties[name] == nil {
+			t.Errorf("properties lack %s, want the embedded and own fields", name)
+		}
+	}
+}
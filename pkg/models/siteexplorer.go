@@ -1,5 +1,19 @@
 package models
 
+import (
+	"encoding/json"
+	"strings"
+)
+
+// StringList is a list of strings, written comma-separated in CSV and table
+// output
+type StringList []string
+
+// String joins the list with commas
+func (l StringList) String() string {
+	return strings.Join(l, ",")
+}
+
 // DomainRatingResponse represents the domain rating API response
 type DomainRatingResponse struct {
 	DomainRating DomainRating `json:"domain_rating"`
@@ -29,7 +43,8 @@ type BacklinksResponse struct {
 	Backlinks []Backlink `json:"backlinks"`
 }
 
-// Backlink represents a single backlink
+// Backlink represents a single backlink. Fields the API may return as null
+// are pointers.
 type Backlink struct {
 	URLFrom      string  `json:"url_from"`
 	URLTo        string  `json:"url_to"`
@@ -42,6 +57,79 @@ type Backlink struct {
 	LinkType     string  `json:"link_type,omitempty"`
 	URLRating    float64 `json:"url_rating,omitempty"`
 	Traffic      int     `json:"traffic,omitempty"`
+
+	// Link attributes
+	IsDofollow   bool    `json:"is_dofollow"`
+	IsNofollow   bool    `json:"is_nofollow"`
+	IsUGC        bool    `json:"is_ugc"`
+	IsSponsored  bool    `json:"is_sponsored"`
+	IsContent    bool    `json:"is_content"`
+	IsText       bool    `json:"is_text"`
+	IsImage      bool    `json:"is_image"`
+	IsFrame      bool    `json:"is_frame"`
+	IsForm       bool    `json:"is_form"`
+	IsCanonical  bool    `json:"is_canonical"`
+	IsAlternate  bool    `json:"is_alternate"`
+	IsRSS        bool    `json:"is_rss"`
+	IsRedirect   bool    `json:"is_redirect"`
+	IsNew        bool    `json:"is_new"`
+	IsLost       bool    `json:"is_lost"`
+	LinkPosition *string `json:"link_position"`
+	Alt          *string `json:"alt"`
+	SnippetLeft  *string `json:"snippet_left"`
+	SnippetRight *string `json:"snippet_right"`
+
+	// Linking page
+	Title            *string    `json:"title"`
+	Languages        StringList `json:"languages"`
+	PageSize         *int       `json:"page_size"`
+	Noindex          bool       `json:"noindex"`
+	RefdomainsSource *int       `json:"refdomains_source"`
+	LinksExternal    *int       `json:"links_external"`
+	LinksInternal    *int       `json:"links_internal"`
+	TrafficDomain    *int       `json:"traffic_domain"`
+
+	// Target and redirects
+	HTTPCodeTarget         *int       `json:"http_code_target"`
+	RefdomainsTarget       *int       `json:"refdomains_target"`
+	RedirectCode           *int       `json:"redirect_code"`
+	RedirectChainURLs      StringList `json:"redirect_chain_urls"`
+	RedirectChainHTTPCodes []int      `json:"redirect_chain_http_codes"`
+
+	// History
+	LastSeen         *string `json:"last_seen"`
+	LostReason       *string `json:"lost_reason"`
+	DropReason       *string `json:"drop_reason"`
+	DiscoveredStatus *string `json:"discovered_status"`
+}
+
+// BacklinkLanguagesResponse is a list of backlinks with the language of
+// their anchor text
+type BacklinkLanguagesResponse struct {
+	Backlinks []BacklinkLanguage `json:"backlinks"`
+}
+
+// BacklinkLanguage is a backlink and the language detected in its anchor,
+// "und" when the anchor is too short to tell
+type BacklinkLanguage struct {
+	Backlink
+	Language           string  `json:"language"`
+	LanguageConfidence float64 `json:"language_confidence"`
+}
+
+// BacklinkDomainsResponse is a list of backlinks grouped by referring domain
+type BacklinkDomainsResponse struct {
+	Domains []BacklinkDomain `json:"domains"`
+}
+
+// BacklinkDomain aggregates the backlinks from one referring domain
+type BacklinkDomain struct {
+	Domain           string     `json:"domain"`
+	Links            int        `json:"links"`
+	BestDomainRating float64    `json:"best_domain_rating"`
+	Anchors          StringList `json:"anchors"`
+	DofollowShare    float64    `json:"dofollow_share"`
+	FirstSeen        string     `json:"first_seen,omitempty"`
 }
 
 // RefDomainsResponse represents a list of referring domains
@@ -62,6 +150,108 @@ type RefDomain struct {
 	LastVisited  string  `json:"last_visited,omitempty"`
 }
 
+// RefDomainsHistogramResponse is a list of referring domains with their
+// spread across domain rating ranges. RefDomains holds the rows as the API
+// returns them.
+type RefDomainsHistogramResponse struct {
+	RefDomains []map[string]interface{} `json:"refdomains"`
+	Histogram  []DRBucket               `json:"histogram"`
+}
+
+// DRHistogram is the spread of referring domains across domain rating
+// ranges: 0-10, then ten points each up to 100
+type DRHistogram struct {
+	Buckets []DRBucket `json:"buckets"`
+}
+
+// DRBucket counts the referring domains with a domain rating in a range, and
+// their percent of those with a domain rating
+type DRBucket struct {
+	Range   string  `json:"range"`
+	MinDR   int     `json:"min_dr"`
+	MaxDR   int     `json:"max_dr"`
+	Domains int     `json:"domains"`
+	Percent float64 `json:"percent"`
+}
+
+// DRHistogramBars is a DRHistogram drawn for a table, with a bar per bucket
+type DRHistogramBars struct {
+	Buckets []DRBucketBar `json:"buckets"`
+}
+
+// DRBucketBar is a DRBucket with a bar of its domains
+type DRBucketBar struct {
+	DRBucket
+	Bar string `json:"bar"`
+}
+
+// FieldStatsResponse is the summary statistics of the columns of a list
+type FieldStatsResponse struct {
+	Stats []FieldStats `json:"stats"`
+}
+
+// FieldStats summarizes a column's values across the rows of a list. Count
+// is the rows with a value. Numeric columns have the statistics of their
+// values, rounded to two decimals, and other columns the number of distinct
+// values.
+type FieldStats struct {
+	Field    string      `json:"field"`
+	Type     string      `json:"type" enum:"number,text"`
+	Count    int         `json:"count"`
+	Min      json.Number `json:"min,omitempty"`
+	Max      json.Number `json:"max,omitempty"`
+	Mean     json.Number `json:"mean,omitempty"`
+	Median   json.Number `json:"median,omitempty"`
+	P90      json.Number `json:"p90,omitempty"`
+	Sum      json.Number `json:"sum,omitempty"`
+	Distinct *int        `json:"distinct,omitempty"`
+}
+
+// RefDomainGroupsResponse is a list of referring domains rolled up by TLD or
+// registrable domain
+type RefDomainGroupsResponse struct {
+	Groups []RefDomainGroup `json:"groups"`
+}
+
+// RefDomainGroup aggregates the referring domains sharing a TLD or
+// registrable domain
+type RefDomainGroup struct {
+	Group           string  `json:"group"`
+	Domains         int     `json:"domains"`
+	Backlinks       int     `json:"backlinks"`
+	MaxDomainRating float64 `json:"max_domain_rating"`
+}
+
+// RefDomainIPsResponse is a list of referring domains with the address
+// they resolve to
+type RefDomainIPsResponse struct {
+	RefDomains []RefDomainIP `json:"refdomains"`
+}
+
+// RefDomainIP is a referring domain with its IPv4 address and the /24
+// subnet of it, both empty when the domain didn't resolve
+type RefDomainIP struct {
+	RefDomain
+	IP     string `json:"ip"`
+	Subnet string `json:"subnet"`
+}
+
+// SubnetGroupsResponse is a list of referring domains grouped by subnet
+type SubnetGroupsResponse struct {
+	Subnets []SubnetGroup `json:"subnets"`
+}
+
+// SubnetGroup aggregates the referring domains hosted in one /24 subnet,
+// which for many domains may be a network of sites linking together
+type SubnetGroup struct {
+	Subnet          string     `json:"subnet"`
+	Domains         int        `json:"domains"`
+	IPs             int        `json:"ips"`
+	Backlinks       int        `json:"backlinks"`
+	MaxDomainRating float64    `json:"max_domain_rating"`
+	DomainNames     StringList `json:"domain_names"`
+}
+
 // AnchorsResponse represents a list of anchor texts
 type AnchorsResponse struct {
 	Anchors []Anchor `json:"anchors"`
@@ -76,6 +266,106 @@ type Anchor struct {
 	LastVisited string `json:"last_visited,omitempty"`
 }
 
+// NormalizedAnchorsResponse lists anchor texts with those the same once
+// normalized merged into one row
+type NormalizedAnchorsResponse struct {
+	Anchors []NormalizedAnchor `json:"anchors"`
+}
+
+// NormalizedAnchor is an anchor text with the number of anchor texts it was
+// normalized from
+type NormalizedAnchor struct {
+	Anchor
+	NormalizedFrom int `json:"normalized_from"`
+}
+
+// AnchorLanguagesResponse is a list of anchor texts with their language
+type AnchorLanguagesResponse struct {
+	Anchors []AnchorLanguage `json:"anchors"`
+}
+
+// AnchorLanguage is an anchor text and the language detected in it, "und"
+// when it's too short to tell
+type AnchorLanguage struct {
+	Anchor
+	Language           string  `json:"language"`
+	LanguageConfidence float64 `json:"language_confidence"`
+}
+
+// AnchorSummaryResponse is a list of anchor texts with a summary of them.
+// Anchors holds the rows as the API returns them.
+type AnchorSummaryResponse struct {
+	Anchors []map[string]interface{} `json:"anchors"`
+	Summary AnchorSummary            `json:"summary"`
+}
+
+// AnchorSummary breaks down anchor texts: their most common words weighted
+// by backlinks, how many have no text or name an image, and with brand terms
+// how much of the backlinks have a branded anchor
+type AnchorSummary struct {
+	Words        []AnchorWord `json:"words"`
+	Anchors      int          `json:"anchors"`
+	Backlinks    int          `json:"backlinks"`
+	EmptyAnchors int          `json:"empty_anchors"`
+	ImageAnchors int          `json:"image_anchors"`
+	Brand        *AnchorBrand `json:"brand,omitempty"`
+}
+
+// AnchorWord is a word of anchor texts, with the anchors using it, their
+// backlinks, and those backlinks' percent of all
+type AnchorWord struct {
+	Word             string  `json:"word"`
+	Anchors          int     `json:"anchors"`
+	Backlinks        int     `json:"backlinks"`
+	BacklinksPercent float64 `json:"backlinks_percent"`
+}
+
+// AnchorBrand splits anchor texts into those with a brand term and the
+// others, which include anchors without text
+type AnchorBrand struct {
+	Terms               []string `json:"terms"`
+	BrandedAnchors      int      `json:"branded_anchors"`
+	BrandedBacklinks    int      `json:"branded_backlinks"`
+	BrandedPercent      float64  `json:"branded_percent"`
+	NonBrandedAnchors   int      `json:"non_branded_anchors"`
+	NonBrandedBacklinks int      `json:"non_branded_backlinks"`
+	NonBrandedPercent   float64  `json:"non_branded_percent"`
+}
+
+// AnchorDomainsResponse lists anchor texts, the top ones with the referring
+// domains that link with them
+type AnchorDomainsResponse struct {
+	Anchors []AnchorDomains `json:"anchors"`
+}
+
+// AnchorDomains is an anchor text and its top referring domains
+type AnchorDomains struct {
+	Anchor      string      `json:"anchor"`
+	Backlinks   int         `json:"backlinks,omitempty"`
+	Refdomains  int         `json:"refdomains,omitempty"`
+	FirstSeen   string      `json:"first_seen,omitempty"`
+	LastVisited string      `json:"last_visited,omitempty"`
+	Domains     []RefDomain `json:"domains,omitempty"`
+}
+
+// AnchorDomainRowsResponse is an AnchorDomainsResponse flattened into one row
+// per anchor and referring domain, for CSV and table output
+type AnchorDomainRowsResponse struct {
+	Rows []AnchorDomainRow `json:"rows"`
+}
+
+// AnchorDomainRow is a referring domain linking with an anchor text. Anchors
+// that weren't expanded have a single row without a domain.
+type AnchorDomainRow struct {
+	Anchor       string  `json:"anchor"`
+	Backlinks    int     `json:"backlinks"`
+	Refdomains   int     `json:"refdomains"`
+	Domain       string  `json:"domain"`
+	DomainRating float64 `json:"domain_rating"`
+	DomainLinks  int     `json:"domain_backlinks"`
+	FirstSeen    string  `json:"first_seen,omitempty"`
+}
+
 // OrganicKeywordsResponse represents a list of organic keywords
 type OrganicKeywordsResponse struct {
 	Keywords []OrganicKeyword `json:"keywords"`
@@ -90,6 +380,98 @@ type OrganicKeyword struct {
 	KD           float64 `json:"kd,omitempty"`
 	URL          string  `json:"url,omitempty"`
 	Country      string  `json:"country,omitempty"`
+
+	// CPC is the cost per click in USD cents
+	CPC            *int       `json:"cpc"`
+	SERPFeatures   StringList `json:"serp_features"`
+	PositionPrev   *int       `json:"position_prev"`
+	BestPosition   *int       `json:"best_position"`
+	IsMainPosition bool       `json:"is_main_position"`
+	LastUpdated    *string    `json:"last_updated"`
+}
+
+// NormalizedKeywordsResponse lists organic keywords with those the same once
+// normalized merged into one row
+type NormalizedKeywordsResponse struct {
+	Keywords []NormalizedKeyword `json:"keywords"`
+}
+
+// NormalizedKeyword is an organic keyword with the number of keywords it was
+// normalized from
+type NormalizedKeyword struct {
+	OrganicKeyword
+	NormalizedFrom int `json:"normalized_from"`
+}
+
+// ClusteredKeywordsResponse lists organic keywords with the cluster each
+// was put in
+type ClusteredKeywordsResponse struct {
+	Keywords []ClusteredKeyword `json:"keywords"`
+}
+
+// ClusteredKeyword is an organic keyword with its cluster, numbered from 1
+// and labelled with its keyword of the highest volume
+type ClusteredKeyword struct {
+	OrganicKeyword
+	ClusterID    int    `json:"cluster_id"`
+	ClusterLabel string `json:"cluster_label"`
+}
+
+// KeywordClustersResponse lists the clusters of a target's organic keywords
+type KeywordClustersResponse struct {
+	Clusters []KeywordCluster `json:"clusters"`
+}
+
+// KeywordCluster is a group of organic keywords ranking with the same URL,
+// or sharing most of their words. Volume and Traffic are the totals of its
+// keywords, and URL is the one its label ranks with.
+type KeywordCluster struct {
+	ClusterID    int    `json:"cluster_id"`
+	ClusterLabel string `json:"cluster_label"`
+	Keywords     int    `json:"keywords"`
+	Volume       int    `json:"volume"`
+	Traffic      int    `json:"traffic"`
+	URL          string `json:"url,omitempty"`
+}
+
+// PageKeywordGapsResponse lists the organic keywords of a page alongside a
+// competing page's
+type PageKeywordGapsResponse struct {
+	Keywords []PageKeywordGap `json:"keywords"`
+}
+
+// PageKeywordGap is a keyword either page ranks for. Position and Traffic
+// are the page's, null when CompetitorOnly.
+type PageKeywordGap struct {
+	Keyword            string     `json:"keyword"`
+	Position           *int       `json:"position"`
+	SearchVolume       int        `json:"volume,omitempty"`
+	Traffic            *int       `json:"traffic"`
+	SERPFeatures       StringList `json:"serp_features"`
+	CompetitorPosition *int       `json:"competitor_position"`
+	CompetitorTraffic  *int       `json:"competitor_traffic"`
+	CompetitorOnly     bool       `json:"competitor_only"`
+}
+
+// KeywordMovementsResponse lists organic keywords whose ranking changed
+// between two dates
+type KeywordMovementsResponse struct {
+	Keywords []KeywordMovement `json:"keywords"`
+}
+
+// KeywordMovement is a keyword's ranking on two dates. Movement is new, lost,
+// up, or down; the position of a new keyword's earlier date, or a lost
+// keyword's later one, is null. PositionDiff is the previous position minus
+// the current one, so positive values are gains.
+type KeywordMovement struct {
+	Keyword          string `json:"keyword"`
+	Movement         string `json:"movement" enum:"new,lost,up,down"`
+	URL              string `json:"url"`
+	PreviousURL      string `json:"previous_url,omitempty"`
+	PreviousPosition *int   `json:"previous_position"`
+	Position         *int   `json:"position"`
+	PositionDiff     *int   `json:"position_diff"`
+	SearchVolume     int    `json:"volume,omitempty"`
 }
 
 // TopPagesResponse represents a list of top pages
@@ -107,6 +489,30 @@ type TopPage struct {
 	Position     int     `json:"position,omitempty"`
 	Volume       int     `json:"volume,omitempty"`
 	URLRating    float64 `json:"url_rating,omitempty"`
+
+	// Changes since the comparison date; only returned when the request
+	// sets date_compared
+	TrafficPrev      *int `json:"traffic_prev"`
+	TrafficDiff      *int `json:"traffic_diff"`
+	TrafficValuePrev *int `json:"traffic_value_prev"`
+	TrafficValueDiff *int `json:"traffic_value_diff"`
+	KeywordsPrev     *int `json:"keywords_prev"`
+	KeywordsDiff     *int `json:"keywords_diff"`
+	PositionPrev     *int `json:"position_prev"`
+	PositionDiff     *int `json:"position_diff"`
+}
+
+// MergedTopPagesResponse lists top pages with the variants of each page's
+// URL merged into one row
+type MergedTopPagesResponse struct {
+	Pages []MergedTopPage `json:"pages"`
+}
+
+// MergedTopPage is a top page with the number of URL variants it was merged
+// from
+type MergedTopPage struct {
+	TopPage
+	MergedFrom int `json:"merged_from"`
 }
 
 // BrokenBacklinksResponse represents a list of broken backlinks
@@ -156,6 +562,24 @@ type SiteMetrics struct {
 	FeaturedSnippets int     `json:"featured_snippets,omitempty"`
 }
 
+// CountryMetricsResponse holds the site metrics of several countries
+type CountryMetricsResponse struct {
+	Metrics []CountryMetrics `json:"metrics"`
+}
+
+// CountryMetrics is SiteMetrics for one country
+type CountryMetrics struct {
+	Country          string  `json:"country"`
+	OrgKeywords      int     `json:"org_keywords,omitempty"`
+	OrgKeywords2     int     `json:"org_keywords_2,omitempty"`
+	OrgTraffic       int     `json:"org_traffic,omitempty"`
+	OrgCost          float64 `json:"org_cost,omitempty"`
+	PaidKeywords     int     `json:"paid_keywords,omitempty"`
+	PaidTraffic      int     `json:"paid_traffic,omitempty"`
+	PaidCost         float64 `json:"paid_cost,omitempty"`
+	FeaturedSnippets int     `json:"featured_snippets,omitempty"`
+}
+
 // MetricsHistoryResponse represents historical metrics data
 type MetricsHistoryResponse struct {
 	Metrics []MetricsHistoryEntry `json:"metrics"`
@@ -172,6 +596,57 @@ type MetricsHistoryEntry struct {
 	DomainRating float64 `json:"domain_rating,omitempty"`
 }
 
+// RefDomainsHistoryResponse lists a target's referring domains over time
+type RefDomainsHistoryResponse struct {
+	RefDomains []RefDomainsHistoryEntry `json:"refdomains"`
+}
+
+// RefDomainsHistoryEntry is the number of referring domains on a date
+type RefDomainsHistoryEntry struct {
+	Date       string `json:"date"`
+	RefDomains int    `json:"refdomains"`
+}
+
+// URLRatingHistoryResponse lists a page's URL rating over time
+type URLRatingHistoryResponse struct {
+	URLRatings []URLRatingHistoryEntry `json:"url_ratings"`
+}
+
+// URLRatingHistoryEntry is the URL rating of a page on a date
+type URLRatingHistoryEntry struct {
+	Date      string  `json:"date"`
+	URLRating float64 `json:"url_rating"`
+}
+
+// LinkVelocityResponse is the monthly growth of a target's referring
+// domains, with a summary of the whole period
+type LinkVelocityResponse struct {
+	Months  []LinkVelocityMonth `json:"months"`
+	Summary LinkVelocitySummary `json:"summary"`
+}
+
+// LinkVelocityMonth is the change in referring domains over a month. Its
+// fields are null for a month without history, and NetNew and GrowthPercent
+// for one following such a month.
+type LinkVelocityMonth struct {
+	Month         string   `json:"month"`
+	RefDomains    *int     `json:"refdomains"`
+	NetNew        *int     `json:"net_new"`
+	GrowthPercent *float64 `json:"growth_percent"`
+}
+
+// LinkVelocitySummary is the change in referring domains over a period.
+// Trend is growing, flat, or declining by the average monthly growth rate.
+type LinkVelocitySummary struct {
+	Months            int      `json:"months"`
+	StartRefDomains   *int     `json:"start_refdomains"`
+	EndRefDomains     *int     `json:"end_refdomains"`
+	NetNew            *int     `json:"net_new"`
+	GrowthPercent     *float64 `json:"growth_percent"`
+	MonthlyGrowthRate *float64 `json:"monthly_growth_percent"`
+	Trend             string   `json:"trend" enum:"growing,flat,declining"`
+}
+
 // PagesByTrafficResponse represents pages sorted by traffic
 type PagesByTrafficResponse struct {
 	Pages []PageByTraffic `json:"pages"`
@@ -200,3 +675,90 @@ type PageByLinks struct {
 	Traffic    int     `json:"traffic,omitempty"`
 	FirstSeen  string  `json:"first_seen,omitempty"`
 }
+
+// MergedPagesByLinksResponse lists pages by backlinks with the variants of
+// each page's URL merged into one row
+type MergedPagesByLinksResponse struct {
+	Pages []MergedPageByLinks `json:"pages"`
+}
+
+// MergedPageByLinks is a page by backlinks with the number of URL variants it
+// was merged from
+type MergedPageByLinks struct {
+	PageByLinks
+	MergedFrom int `json:"merged_from"`
+}
+
+// OrganicCompetitorsResponse represents the domains competing with a target
+// for organic keywords
+type OrganicCompetitorsResponse struct {
+	Competitors []OrganicCompetitor `json:"competitors"`
+}
+
+// OrganicCompetitor represents a single competing domain
+type OrganicCompetitor struct {
+	Domain             string  `json:"competitor_domain"`
+	DomainRating       float64 `json:"domain_rating,omitempty"`
+	KeywordsCommon     int     `json:"keywords_common,omitempty"`
+	KeywordsCompetitor int     `json:"keywords_competitor,omitempty"`
+	KeywordsTarget     int     `json:"keywords_target,omitempty"`
+	Share              float64 `json:"share,omitempty"`
+	Traffic            int     `json:"traffic,omitempty"`
+	Value              int     `json:"value,omitempty"`
+}
+
+// TrafficSharesResponse represents each entity's share of the total traffic
+// of the pages or domains listed
+type TrafficSharesResponse struct {
+	Shares []TrafficShare `json:"shares"`
+}
+
+// TrafficShare represents a page's or domain's share of traffic. Percents
+// are rounded so that they sum to 100.
+type TrafficShare struct {
+	Entity  string  `json:"entity"`
+	Traffic int     `json:"traffic"`
+	Percent float64 `json:"percent"`
+}
+
+// RowCount is how many rows a full export of a list endpoint would return,
+// with the requests and units fetching them all is estimated to take
+type RowCount struct {
+	Endpoint       string `json:"endpoint"`
+	Count          int    `json:"count"`
+	Requests       int    `json:"requests"`
+	EstimatedUnits int    `json:"estimated_units"`
+}
+
+// Digest is how a target changed over a period, section by section: its
+// domain rating, referring domains, and organic traffic, the strongest
+// backlinks first seen in the period, and the keywords that moved the most.
+// A section that failed is null, with its error in Errors.
+type Digest struct {
+	Target           string            `json:"target"`
+	Mode             string            `json:"mode" enum:"exact,domain,prefix,subdomains"`
+	From             string            `json:"from"`
+	To               string            `json:"to"`
+	DomainRating     *MetricChange     `json:"domain_rating"`
+	RefDomains       *MetricChange     `json:"refdomains"`
+	OrgTraffic       *MetricChange     `json:"org_traffic"`
+	NewBacklinks     []Backlink        `json:"new_backlinks"`
+	KeywordMovements []KeywordMovement `json:"keyword_movements"`
+	Errors           []DigestError     `json:"errors,omitempty"`
+	UnitsConsumed    int               `json:"units_consumed"`
+}
+
+// MetricChange is a metric's value at the start and end of a period.
+// ChangePercent is null when the metric started at zero.
+type MetricChange struct {
+	Previous      float64  `json:"previous"`
+	Current       float64  `json:"current"`
+	Change        float64  `json:"change"`
+	ChangePercent *float64 `json:"change_percent"`
+}
+
+// DigestError is why a section of a digest is missing
+type DigestError struct {
+	Section string `json:"section" enum:"domain_rating,refdomains,org_traffic,new_backlinks,keyword_movements"`
+	Error   string `json:"error"`
+}
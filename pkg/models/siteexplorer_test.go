@@ -0,0 +1,97 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeFixture decodes testdata/name into v, failing on fields v lacks, and
+// checks that every field of row appears in the first row of list
+func decodeFixture(t *testing.T, name, list string, row reflect.Type, v interface{}) {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every field of the response must map to a struct field...
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	// ...and every struct field must appear in the response
+	var raw map[string][]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if len(raw[list]) == 0 {
+		t.Fatalf("%s has no %s rows", name, list)
+	}
+	for i := 0; i < row.NumField(); i++ {
+		tag, _, _ := strings.Cut(row.Field(i).Tag.Get("json"), ",")
+		if _, ok := raw[list][0][tag]; !ok {
+			t.Errorf("field %s: json tag %q not in the response", row.Field(i).Name, tag)
+		}
+	}
+}
+
+func TestBacklinksResponse_Decode(t *testing.T) {
+	var resp BacklinksResponse
+	decodeFixture(t, "backlinks.json", "backlinks", reflect.TypeOf(Backlink{}), &resp)
+	if len(resp.Backlinks) != 2 {
+		t.Fatalf("decoded %d backlinks, want 2", len(resp.Backlinks))
+	}
+
+	live, lost := resp.Backlinks[0], resp.Backlinks[1]
+	if !live.IsDofollow || live.Title == nil || *live.Title != "SEO tools compared (2025)" || live.PageSize == nil || *live.PageSize != 84213 {
+		t.Errorf("live backlink = %+v", live)
+	}
+	if live.LastSeen != nil || live.RedirectCode != nil {
+		t.Errorf("null fields decoded as set: last_seen = %v, redirect_code = %v", live.LastSeen, live.RedirectCode)
+	}
+	if lost.LostReason == nil || *lost.LostReason != "linkremoved" || !reflect.DeepEqual(lost.RedirectChainHTTPCodes, []int{301, 200}) {
+		t.Errorf("lost backlink = %+v", lost)
+	}
+	if lost.Title != nil || lost.RefdomainsSource != nil {
+		t.Errorf("null fields decoded as set: title = %v, refdomains_source = %v", lost.Title, lost.RefdomainsSource)
+	}
+}
+
+func TestOrganicKeywordsResponse_Decode(t *testing.T) {
+	var resp OrganicKeywordsResponse
+	decodeFixture(t, "organic_keywords.json", "keywords", reflect.TypeOf(OrganicKeyword{}), &resp)
+	if len(resp.Keywords) != 2 {
+		t.Fatalf("decoded %d keywords, want 2", len(resp.Keywords))
+	}
+
+	ranked, other := resp.Keywords[0], resp.Keywords[1]
+	if ranked.CPC == nil || *ranked.CPC != 350 || ranked.PositionPrev == nil || *ranked.PositionPrev != 3 || !ranked.IsMainPosition {
+		t.Errorf("keyword = %+v", ranked)
+	}
+	if got := ranked.SERPFeatures.String(); got != "sitelink,people_also_ask,video" {
+		t.Errorf("SERPFeatures.String() = %q, want %q", got, "sitelink,people_also_ask,video")
+	}
+	if other.CPC != nil || other.BestPosition != nil || other.LastUpdated != nil {
+		t.Errorf("null fields decoded as set: %+v", other)
+	}
+}
+
+func TestTopPagesResponse_Decode(t *testing.T) {
+	var resp TopPagesResponse
+	decodeFixture(t, "top_pages.json", "pages", reflect.TypeOf(TopPage{}), &resp)
+	if len(resp.Pages) != 1 {
+		t.Fatalf("decoded %d pages, want 1", len(resp.Pages))
+	}
+
+	p := resp.Pages[0]
+	if p.TrafficDiff == nil || *p.TrafficDiff != 7210 || p.PositionDiff == nil || *p.PositionDiff != -1 {
+		t.Errorf("page = %+v", p)
+	}
+}
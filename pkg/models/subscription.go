@@ -0,0 +1,17 @@
+package models
+
+// LimitsAndUsageResponse represents the subscription limits and usage API response
+type LimitsAndUsageResponse struct {
+	LimitsAndUsage LimitsAndUsage `json:"limits_and_usage"`
+}
+
+// LimitsAndUsage contains API unit limits and consumption for the subscription
+type LimitsAndUsage struct {
+	Subscription         string `json:"subscription,omitempty"`
+	UsageResetDate       string `json:"usage_reset_date,omitempty"`
+	UnitsLimitWorkspace  int    `json:"units_limit_workspace,omitempty"`
+	UnitsUsageWorkspace  int    `json:"units_usage_workspace,omitempty"`
+	UnitsLimitAPIKey     int    `json:"units_limit_api_key,omitempty"`
+	UnitsUsageAPIKey     int    `json:"units_usage_api_key,omitempty"`
+	APIKeyExpirationDate string `json:"api_key_expiration_date,omitempty"`
+}
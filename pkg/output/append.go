@@ -0,0 +1,105 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// NewAppendWriter creates a CSV writer adding rows to the end of outputFile,
+// which is created if missing. The header is only written to an empty file,
+// and must match the file's otherwise.
+//
+// With resume, the file is taken to be an interrupted write of the same
+// output: a last row cut off partway is removed, and as many rows as the
+// file has are skipped before appending, so writing the output again
+// continues it.
+func NewAppendWriter(format string, outputFile string, resume bool) (*Writer, error) {
+	if Format(format) != FormatCSV {
+		return nil, fmt.Errorf("appending requires CSV output, not %s", format)
+	}
+
+	f, err := os.OpenFile(outputFile, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+	scan, err := scanCSV(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read output file: %w", err)
+	}
+
+	w := &Writer{format: FormatCSV, writer: f, closer: f, appending: true}
+	if resume {
+		if err := f.Truncate(scan.end); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to truncate output file: %w", err)
+		}
+		w.skip = scan.rows
+		w.resumed = scan.rows
+	}
+	if scan.header != nil {
+		if w.header, err = csv.NewReader(bytes.NewReader(scan.header)).Read(); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to read output file header: %w", err)
+		}
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+	return w, nil
+}
+
+// Resumed returns the rows of the file a resuming append writer skips
+func (w *Writer) Resumed() int {
+	return w.resumed
+}
+
+// csvScan describes the complete records of a CSV file
+type csvScan struct {
+	header []byte // the first record, or nil when there's none
+	rows   int    // records after the header
+	end    int64  // where the last complete record ends
+}
+
+// scanCSV reads r for its complete records: those ending with a newline
+// outside quotes, as a CSV writer ends each record. Anything after the last
+// is a record cut off partway.
+func scanCSV(r io.Reader) (csvScan, error) {
+	var scan csvScan
+	var first bytes.Buffer
+	records := 0
+	quoted := false
+	br := bufio.NewReader(r)
+	for offset := int64(0); ; offset++ {
+		b, err := br.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return scan, err
+		}
+		if records == 0 {
+			first.WriteByte(b)
+		}
+
+		switch {
+		case b == '"':
+			// An escaped quote toggles twice
+			quoted = !quoted
+		case b == '\n' && !quoted:
+			if records == 0 {
+				scan.header = first.Bytes()
+			}
+			records++
+			scan.end = offset + 1
+		}
+	}
+	scan.rows = max(records-1, 0)
+	return scan, nil
+}
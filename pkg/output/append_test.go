@@ -0,0 +1,129 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		header string
+		rows   int
+		end    int64
+	}{
+		{"empty", "", "", 0, 0},
+		{"cut off header", "anchor,back", "", 0, 0},
+		{"header only", "anchor,backlinks\n", "anchor,backlinks\n", 0, 17},
+		{"complete rows", "anchor,backlinks\na,1\nb,2\n", "anchor,backlinks\n", 2, 25},
+		{"cut off row", "anchor,backlinks\na,1\nb,", "anchor,backlinks\n", 1, 21},
+		{"quoted newline", "anchor,backlinks\n\"a\nb\",1\n\"c \"\"d\"\"\",2\n", "anchor,backlinks\n", 2, 37},
+		{"cut off in quotes", "anchor,backlinks\na,1\n\"b\nc", "anchor,backlinks\n", 1, 21},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scan, err := scanCSV(strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("scanCSV() error = %v", err)
+			}
+			if string(scan.header) != tt.header || scan.rows != tt.rows || scan.end != tt.end {
+				t.Errorf("scanCSV() = header %q, %d rows, end %d, want %q, %d, %d", scan.header, scan.rows, scan.end, tt.header, tt.rows, tt.end)
+			}
+		})
+	}
+}
+
+// anchorRows is a generic anchors response of n rows
+func anchorRows(n int) map[string]interface{} {
+	var rows []interface{}
+	for i := 0; i < n; i++ {
+		rows = append(rows, map[string]interface{}{"anchor": string(rune('a' + i)), "backlinks": i})
+	}
+	return map[string]interface{}{"anchors": rows}
+}
+
+func TestNewAppendWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchors.csv")
+	write := func(resume bool, data interface{}) error {
+		t.Helper()
+		w, err := NewAppendWriter("csv", path, resume)
+		if err != nil {
+			t.Fatalf("NewAppendWriter() error = %v", err)
+		}
+		defer w.Close()
+		return w.WriteSuccess(data, nil)
+	}
+	read := func() string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// A new file gets the header; appending to it doesn't repeat it
+	if err := write(false, anchorRows(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := write(false, anchorRows(1)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := read(), "anchor,backlinks\na,0\nb,1\na,0\n"; got != want {
+		t.Errorf("appended file = %q, want %q", got, want)
+	}
+
+	// An interrupted export continues after its complete rows
+	if err := os.WriteFile(path, []byte("anchor,backlinks\na,0\nb,1\nc,"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := write(true, anchorRows(4)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := read(), "anchor,backlinks\na,0\nb,1\nc,2\nd,3\n"; got != want {
+		t.Errorf("resumed file = %q, want %q", got, want)
+	}
+
+	// Resuming a complete export adds nothing
+	if err := write(true, anchorRows(4)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := read(), "anchor,backlinks\na,0\nb,1\nc,2\nd,3\n"; got != want {
+		t.Errorf("file resumed again = %q, want %q", got, want)
+	}
+
+	// Other columns would make a file of mixed rows
+	err := write(false, map[string]interface{}{"anchors": []interface{}{map[string]interface{}{"anchor": "x"}}})
+	if err == nil || !strings.Contains(err.Error(), "columns") {
+		t.Errorf("appending other columns: error = %v, want one about the columns", err)
+	}
+}
+
+func TestNewAppendWriter_Resumed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchors.csv")
+	if err := os.WriteFile(path, []byte("anchor,backlinks\na,0\nb,1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewAppendWriter("csv", path, true)
+	if err != nil {
+		t.Fatalf("NewAppendWriter() error = %v", err)
+	}
+	defer w.Close()
+	if w.Resumed() != 2 {
+		t.Errorf("Resumed() = %d, want 2", w.Resumed())
+	}
+	if err := w.WriteSuccess(anchorRows(3), nil); err != nil {
+		t.Fatal(err)
+	}
+	if w.Rows() != 1 {
+		t.Errorf("Rows() = %d, want the 1 row written", w.Rows())
+	}
+
+	if _, err := NewAppendWriter("json", path, false); err == nil {
+		t.Error("NewAppendWriter() should refuse JSON output")
+	}
+}
@@ -0,0 +1,57 @@
+package output
+
+import (
+	"regexp"
+	"time"
+)
+
+// TimestampLayout is how timestamps are written once SetLocation is called:
+// RFC 3339 with the zone as a numeric offset, +00:00 rather than Z for UTC
+const TimestampLayout = "2006-01-02T15:04:05.999999999-07:00"
+
+// jsonTimestamp matches a JSON string holding a timestamp
+var jsonTimestamp = regexp.MustCompile(`"\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?"`)
+
+// SetLocation writes the timestamps of the output, such as a first_seen of
+// 2024-01-05T08:20:30Z, in loc with TimestampLayout. Dates without a time of
+// day are left as they are, and presets with ISODates keep theirs in UTC.
+func (w *Writer) SetLocation(loc *time.Location) {
+	w.loc = loc
+	w.fetchedAt = w.localTime(w.fetchedAt)
+}
+
+// localTime rewrites s in the writer's location if it is a timestamp, and
+// returns it unchanged otherwise. Timestamps without a zone are taken to be
+// in UTC, like those of the API.
+func (w *Writer) localTime(s string) string {
+	if w.loc == nil || len(s) < 19 || s[4] != '-' || s[7] != '-' {
+		return s
+	}
+	for _, d := range dateLayouts {
+		if !d.isTime {
+			continue
+		}
+		if t, err := time.Parse(d.layout, s); err == nil {
+			return t.In(w.loc).Format(TimestampLayout)
+		}
+	}
+	return s
+}
+
+// cell is formatCell with timestamps in the writer's location
+func (w *Writer) cell(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return w.localTime(s)
+	}
+	return formatCell(v)
+}
+
+// localJSON rewrites the timestamps of encoded JSON in the writer's location
+func (w *Writer) localJSON(b []byte) []byte {
+	if w.loc == nil {
+		return b
+	}
+	return jsonTimestamp.ReplaceAllFunc(b, func(quoted []byte) []byte {
+		return []byte(`"` + w.localTime(string(quoted[1:len(quoted)-1])) + `"`)
+	})
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter_LocalTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Writer{}
+	w.SetLocation(newYork)
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		// Clocks go forward at 2am on 2024-03-10 and back at 2am on 2024-11-03
+		{name: "before spring forward", value: "2024-03-10T06:59:59Z", want: "2024-03-10T01:59:59-05:00"},
+		{name: "after spring forward", value: "2024-03-10T07:00:00Z", want: "2024-03-10T03:00:00-04:00"},
+		{name: "first 1:30 in the fall", value: "2024-11-03T05:30:00Z", want: "2024-11-03T01:30:00-04:00"},
+		{name: "second 1:30 in the fall", value: "2024-11-03T06:30:00Z", want: "2024-11-03T01:30:00-05:00"},
+		{name: "offset", value: "2024-01-05T10:20:30+02:00", want: "2024-01-05T03:20:30-05:00"},
+		{name: "fraction", value: "2024-07-01T12:00:00.25Z", want: "2024-07-01T08:00:00.25-04:00"},
+		{name: "no zone is UTC", value: "2024-07-01 12:00:00", want: "2024-07-01T08:00:00-04:00"},
+		{name: "date", value: "2024-03-10", want: "2024-03-10"},
+		{name: "text", value: "example.com", want: "example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.localTime(tt.value); got != tt.want {
+				t.Errorf("localTime(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if got := (&Writer{}).localTime("2024-03-10T07:00:00Z"); got != "2024-03-10T07:00:00Z" {
+		t.Errorf("localTime() without a location = %q, want it unchanged", got)
+	}
+}
+
+func TestWriter_SetLocation(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"domain": "a.com", "first_seen": "2024-03-10T07:00:00Z", "last_visited": "2024-03-11"},
+	}
+	fetched := time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format Format
+		want   []string
+	}{
+		{FormatJSON, []string{`"first_seen": "2024-03-10T03:00:00-04:00"`, `"last_visited": "2024-03-11"`, `"fetched_at": "2024-11-03T01:30:00-05:00"`}},
+		{FormatNDJSON, []string{`"first_seen":"2024-03-10T03:00:00-04:00"`}},
+		{FormatYAML, []string{"2024-03-10T03:00:00-04:00"}},
+		{FormatCSV, []string{"a.com,2024-03-10T03:00:00-04:00,2024-03-11,2024-11-03T01:30:00-05:00"}},
+		{FormatTable, []string{"2024-03-10T03:00:00-04:00  2024-03-11", "2024-11-03T01:30:00-05:00"}},
+		{FormatMarkdown, []string{"| a.com | 2024-03-10T03:00:00-04:00 | 2024-03-11 | 2024-11-03T01:30:00-05:00 |"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriterTo(string(tt.format), &buf)
+			w.SetFetchedAt(fetched)
+			w.SetLocation(newYork)
+			if err := w.WriteSuccess(rows, nil); err != nil {
+				t.Fatalf("WriteSuccess() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output = %s\nwant it to contain %s", buf.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriter_SetLocationPreset(t *testing.T) {
+	looker, err := LookupPreset("looker")
+	if err != nil {
+		t.Fatalf("LookupPreset() error = %v", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriterTo(string(FormatCSV), &buf)
+	w.SetPreset(looker)
+	w.SetLocation(tokyo)
+	if err := w.WriteSuccess([]map[string]interface{}{{"first_seen": "2024-01-05T10:20:30+02:00"}}, nil); err != nil {
+		t.Fatalf("WriteSuccess() error = %v", err)
+	}
+	// ISO dates are for the downstream tool, which expects UTC
+	if want := "First Seen\n2024-01-05T08:20:30Z\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
@@ -1,15 +1,21 @@
 package output
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/aminemat/ahrefs-cli/internal/redact"
 	"github.com/aminemat/ahrefs-cli/pkg/client"
 )
 
@@ -21,34 +27,176 @@ const (
 	FormatYAML  Format = "yaml"
 	FormatCSV   Format = "csv"
 	FormatTable Format = "table"
+
+	// FormatMarkdown writes rows as a Markdown table, for pasting into chat
+	// or an email. Data with a Markdown rendering of its own is written
+	// that way.
+	FormatMarkdown Format = "markdown"
+
+	// FormatNDJSON writes each row as a line of JSON, for programs reading
+	// the output as a stream. It isn't a --format; --pipe-to uses it.
+	FormatNDJSON Format = "ndjson"
 )
 
+// TimestampField is the name of the column injected by SetFetchedAt
+const TimestampField = "fetched_at"
+
+// ErrorField is the column CSV, table, and Markdown output add for the
+// targets given to SetFailures
+const ErrorField = "error"
+
+// Failure is a target of a command run for several that failed, written
+// alongside the rows of those that succeeded
+type Failure struct {
+	Target  string `json:"target"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 // Writer handles output formatting and writing
 type Writer struct {
-	format Format
-	writer io.Writer
+	format    Format
+	writer    io.Writer
+	fetchedAt string
+	columns   []string
+	preset    *Preset
+	endpoint  string
+	rows      int
+	secrets   []string
+
+	// closer is the destination owned by the writer, if any
+	closer io.Closer
+
+	// appending is set for a writer adding to a file, whose CSV header, once
+	// there is one, is header. skip is how many rows are still to be left
+	// out, as the file has them, of the resumed rows.
+	appending bool
+	header    []string
+	skip      int
+	resumed   int
+
+	// meta receives the response meta of NDJSON output, whose lines are
+	// rows only
+	meta io.Writer
+
+	// failures are the targets that failed, written with the rows
+	failures []Failure
+
+	// loc is the location timestamps are written in; nil leaves them as
+	// they are
+	loc *time.Location
+
+	// rotating is the file written when it is rotated, continued in a
+	// successor once full
+	rotating *rotatingFile
+
+	// verbose writes API error bodies in full rather than summarized
+	verbose bool
 }
 
 // NewWriter creates a new output writer
 func NewWriter(format string, outputFile string) (*Writer, error) {
-	var w io.Writer = os.Stdout
+	if outputFile == "" {
+		return NewWriterTo(format, os.Stdout), nil
+	}
 
-	if outputFile != "" {
-		f, err := os.Create(outputFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create output file: %w", err)
-		}
-		w = f
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
 	}
 
 	return &Writer{
 		format: Format(format),
-		writer: w,
+		writer: f,
+		closer: f,
 	}, nil
 }
 
+// NewWriterCloser creates an output writer that writes to wc and closes it on
+// Close
+func NewWriterCloser(format string, wc io.WriteCloser) *Writer {
+	return &Writer{
+		format: Format(format),
+		writer: wc,
+		closer: wc,
+	}
+}
+
+// NewWriterTo creates an output writer that writes to w. Close leaves w open.
+func NewWriterTo(format string, w io.Writer) *Writer {
+	return &Writer{
+		format: Format(format),
+		writer: w,
+	}
+}
+
+// SetFetchedAt enables a fetched_at field with the given time, in ISO-8601
+// format, on every row (or on the object for single-object responses)
+func (w *Writer) SetFetchedAt(t time.Time) {
+	w.fetchedAt = w.localTime(t.Format(time.RFC3339))
+}
+
+// SetSecrets masks secrets, such as the API key, in the errors WriteError
+// writes
+func (w *Writer) SetSecrets(secrets ...string) {
+	w.secrets = secrets
+}
+
+// SetVerbose makes WriteError include the full body of an API error
+// response, as --verbose does, rather than a one-line summary of it
+func (w *Writer) SetVerbose(verbose bool) {
+	w.verbose = verbose
+}
+
+// SetColumns fixes the CSV and table columns, in order, instead of deriving
+// them from the first row. Rows missing a column get an empty cell.
+func (w *Writer) SetColumns(columns []string) {
+	w.columns = columns
+}
+
+// SetPreset reshapes CSV output with p. Its per-endpoint column order
+// applies once SetEndpoint names the endpoint; SetColumns takes precedence.
+func (w *Writer) SetPreset(p Preset) {
+	w.preset = &p
+}
+
+// SetEndpoint names the API endpoint the output comes from, selecting the
+// preset's column layout for it
+func (w *Writer) SetEndpoint(endpoint string) {
+	w.endpoint = endpoint
+}
+
+// SetFailures sets the targets that failed. JSON and YAML output list them
+// under errors, with a partial status; CSV, table, and Markdown output add
+// an error column, and a row for each with only its target and error.
+func (w *Writer) SetFailures(failures []Failure) {
+	w.failures = failures
+}
+
+// SetMetaWriter writes the response meta of NDJSON output to mw, as a JSON
+// line of its own, since the output itself is rows only
+func (w *Writer) SetMetaWriter(mw io.Writer) {
+	w.meta = mw
+}
+
+// Rows returns the number of rows written so far; a single-object response
+// counts as one
+func (w *Writer) Rows() int {
+	return w.rows
+}
+
 // WriteSuccess writes a successful response
 func (w *Writer) WriteSuccess(data interface{}, meta *client.ResponseMeta) error {
+	w.rows += countRows(data)
+
+	if w.fetchedAt != "" && (w.format == FormatJSON || w.format == FormatYAML || w.format == FormatNDJSON) {
+		stamped, err := addField(data, TimestampField, w.fetchedAt)
+		if err != nil {
+			return err
+		}
+		data = stamped
+	}
+
 	switch w.format {
 	case FormatJSON:
 		return w.writeJSON(data, meta)
@@ -58,19 +206,132 @@ func (w *Writer) WriteSuccess(data interface{}, meta *client.ResponseMeta) error
 		return w.writeCSV(data)
 	case FormatTable:
 		return w.writeTable(data)
+	case FormatMarkdown:
+		return w.writeMarkdown(data)
+	case FormatNDJSON:
+		return w.writeNDJSON(data, meta)
 	default:
 		return fmt.Errorf("unsupported output format: %s", w.format)
 	}
 }
 
-// WriteError writes an error response
+// WriteValue writes a single field from data as a bare value followed by a
+// newline. The field is looked up in the top-level object, descending into
+// wrapper objects and the first row of lists until a scalar is found.
+func (w *Writer) WriteValue(data interface{}, field string) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode data: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return fmt.Errorf("failed to decode data: %w", err)
+	}
+
+	val, ok := lookupValue(generic, field)
+	if !ok {
+		return fmt.Errorf("field %q not found in response", field)
+	}
+
+	if s, ok := val.(string); ok {
+		val = w.localTime(s)
+	}
+	_, err = fmt.Fprintln(w.writer, val)
+	return err
+}
+
+// lookupValue searches v for a scalar field named field
+func lookupValue(v interface{}, field string) (interface{}, bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if val, ok := t[field]; ok && isScalar(val) {
+			return val, true
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if val, ok := lookupValue(t[k], field); ok {
+				return val, true
+			}
+		}
+	case []interface{}:
+		if len(t) > 0 {
+			return lookupValue(t[0], field)
+		}
+	}
+	return nil, false
+}
+
+// addField converts data to its generic JSON form and sets field to value on
+// every row of the first list found, or on the top-level object otherwise
+func addField(data interface{}, field string, value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode data: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to decode data: %w", err)
+	}
+
+	setOnRows := func(rows []interface{}) {
+		for _, row := range rows {
+			if m, ok := row.(map[string]interface{}); ok {
+				m[field] = value
+			}
+		}
+	}
+
+	switch t := generic.(type) {
+	case []interface{}:
+		setOnRows(t)
+	case map[string]interface{}:
+		for _, v := range t {
+			if rows, ok := v.([]interface{}); ok {
+				setOnRows(rows)
+				return t, nil
+			}
+		}
+		t[field] = value
+	}
+
+	return generic, nil
+}
+
+// isScalar reports whether v is a JSON scalar (string, number, bool)
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, json.Number, bool:
+		return true
+	}
+	return false
+}
+
+// WriteError writes an error response. NDJSON output is rows only, so the
+// error is left to the caller to report.
 func (w *Writer) WriteError(err error) error {
+	if w.format == FormatNDJSON {
+		return nil
+	}
+	formatted := FormatError(err)
+	if w.verbose {
+		formatted = FormatVerboseError(err)
+	}
 	errResp := map[string]interface{}{
 		"status": "error",
-		"error":  formatError(err),
+		"error":  formatted,
 	}
 
-	enc := json.NewEncoder(w.writer)
+	enc := json.NewEncoder(redact.Writer(w.writer, w.secrets...))
 	enc.SetIndent("", "  ")
 	return enc.Encode(errResp)
 }
@@ -83,28 +344,126 @@ func (w *Writer) writeJSON(data interface{}, meta *client.ResponseMeta) error {
 	}
 
 	if meta != nil {
-		response["meta"] = map[string]interface{}{
-			"response_time_ms": meta.ResponseTimeMS,
-		}
-		if meta.UnitsConsumed > 0 {
-			response["meta"].(map[string]interface{})["units_consumed"] = meta.UnitsConsumed
+		response["meta"] = metaFields(meta)
+	}
+	if len(w.failures) > 0 {
+		response["status"] = "partial"
+		response["errors"] = w.failures
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(response); err != nil {
+		return err
+	}
+	_, err := w.writer.Write(w.localJSON(buf.Bytes()))
+	return err
+}
+
+// metaFields returns the fields of meta written with JSON output
+func metaFields(meta *client.ResponseMeta) map[string]interface{} {
+	fields := map[string]interface{}{
+		"response_time_ms": meta.ResponseTimeMS,
+	}
+	if meta.UnitsConsumed > 0 {
+		fields["units_consumed"] = meta.UnitsConsumed
+	}
+	if meta.RateLimitRemaining > 0 {
+		fields["rate_limit_remaining"] = meta.RateLimitRemaining
+	}
+	if meta.Interval != "" {
+		fields["interval"] = meta.Interval
+	}
+	if len(meta.Errors) > 0 {
+		fields["errors"] = meta.Errors
+	}
+	if meta.Sample != nil {
+		fields["sample"] = meta.Sample
+	}
+	if meta.BaseURL != "" {
+		fields["base_url"] = meta.BaseURL
+	}
+	if meta.Revalidated {
+		fields["revalidated"] = true
+	}
+	if meta.Attempts > 0 {
+		fields["attempts"] = meta.Attempts
+		fields["total_wait_ms"] = meta.TotalWaitMS
+	}
+	if meta.Cached {
+		fields["cached"] = true
+	}
+	return fields
+}
+
+// writeNDJSON outputs each row of data as a line of JSON, or data itself for
+// a single object, with meta written to the meta writer if set
+func (w *Writer) writeNDJSON(data interface{}, meta *client.ResponseMeta) error {
+	var line bytes.Buffer
+	enc := json.NewEncoder(&line)
+	encode := func(v interface{}) error {
+		line.Reset()
+		if err := enc.Encode(v); err != nil {
+			return err
 		}
-		if meta.RateLimitRemaining > 0 {
-			response["meta"].(map[string]interface{})["rate_limit_remaining"] = meta.RateLimitRemaining
+		_, err := w.writer.Write(w.localJSON(line.Bytes()))
+		return err
+	}
+	if rows := rowsOf(reflect.ValueOf(data)); rows.Kind() == reflect.Slice || rows.Kind() == reflect.Array {
+		for i := 0; i < rows.Len(); i++ {
+			if w.rotateDue() {
+				if err := w.rotate(); err != nil {
+					return err
+				}
+			}
+			if err := encode(rows.Index(i).Interface()); err != nil {
+				return err
+			}
+			if w.rotating != nil {
+				w.rotating.rows++
+			}
 		}
+	} else if err := encode(data); err != nil {
+		return err
 	}
 
-	enc := json.NewEncoder(w.writer)
-	enc.SetIndent("", "  ")
-	return enc.Encode(response)
+	if w.meta == nil || meta == nil && len(w.failures) == 0 {
+		return nil
+	}
+	trailer := map[string]interface{}{}
+	if meta != nil {
+		trailer["meta"] = metaFields(meta)
+	}
+	if len(w.failures) > 0 {
+		trailer["errors"] = w.failures
+	}
+	return json.NewEncoder(w.meta).Encode(trailer)
 }
 
 // writeYAML outputs data as YAML (simple implementation)
 func (w *Writer) writeYAML(data interface{}, meta *client.ResponseMeta) error {
 	// Simple YAML implementation without external deps
-	fmt.Fprintln(w.writer, "status: success")
+	if len(w.failures) == 0 {
+		fmt.Fprintln(w.writer, "status: success")
+	} else {
+		fmt.Fprintln(w.writer, "status: partial")
+	}
 	fmt.Fprintln(w.writer, "data:")
-	return w.writeYAMLValue(data, 1)
+	if err := w.writeYAMLValue(data, 1); err != nil {
+		return err
+	}
+	if meta != nil {
+		fmt.Fprintln(w.writer, "meta:")
+		if err := w.writeYAMLValue(metaFields(meta), 1); err != nil {
+			return err
+		}
+	}
+	if len(w.failures) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w.writer, "errors:")
+	return w.writeYAMLValue(w.failures, 1)
 }
 
 func (w *Writer) writeYAMLValue(v interface{}, indent int) error {
@@ -118,7 +477,11 @@ func (w *Writer) writeYAMLValue(v interface{}, indent int) error {
 
 	switch val.Kind() {
 	case reflect.Map:
-		for _, key := range val.MapKeys() {
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
 			fmt.Fprintf(w.writer, "%s%v:\n", prefix, key.Interface())
 			if err := w.writeYAMLValue(val.MapIndex(key).Interface(), indent+1); err != nil {
 				return err
@@ -132,17 +495,16 @@ func (w *Writer) writeYAMLValue(v interface{}, indent int) error {
 			}
 		}
 	case reflect.Struct:
-		typ := val.Type()
-		for i := 0; i < val.NumField(); i++ {
-			field := typ.Field(i)
-			if field.IsExported() {
-				fmt.Fprintf(w.writer, "%s%s:\n", prefix, field.Name)
-				if err := w.writeYAMLValue(val.Field(i).Interface(), indent+1); err != nil {
-					return err
-				}
+		for _, field := range exportedFields(val.Type()) {
+			fmt.Fprintf(w.writer, "%s%s:\n", prefix, field.Name)
+			if err := w.writeYAMLValue(fieldValue(val.FieldByIndex(field.Index)), indent+1); err != nil {
+				return err
 			}
 		}
 	default:
+		if s, ok := v.(string); ok {
+			v = w.localTime(s)
+		}
 		fmt.Fprintf(w.writer, "%s%v\n", prefix, v)
 	}
 
@@ -152,39 +514,92 @@ func (w *Writer) writeYAMLValue(v interface{}, indent int) error {
 // writeCSV outputs data as CSV
 func (w *Writer) writeCSV(data interface{}) error {
 	csvWriter := csv.NewWriter(w.writer)
-	defer csvWriter.Flush()
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Map {
-		// If data is a map, try to extract an array/slice field
-		for _, key := range val.MapKeys() {
-			fieldVal := val.MapIndex(key)
-			if fieldVal.Kind() == reflect.Slice || fieldVal.Kind() == reflect.Array {
-				val = fieldVal
-				break
-			}
-		}
-	}
+	defer func() { csvWriter.Flush() }()
 
+	val := rowsOf(reflect.ValueOf(data))
 	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
 		return fmt.Errorf("CSV format requires array/slice data")
 	}
 
-	if val.Len() == 0 {
+	if val.Len() == 0 && len(w.failures) == 0 {
 		return nil
 	}
 
-	// Get headers from first element
-	first := val.Index(0)
-	headers := extractHeaders(first)
-	if err := csvWriter.Write(headers); err != nil {
+	headers := w.headers(val)
+	header := w.withTimestampHeader(w.withErrorHeader(headers))
+	cell := w.cell
+	if w.preset != nil {
+		renamed := make([]string, len(header))
+		for i, field := range header {
+			renamed[i] = w.preset.header(field)
+		}
+		header = renamed
+		cell = w.preset.cell
+	}
+	writeHeader := func() error {
+		switch {
+		case w.header == nil:
+			if err := csvWriter.Write(header); err != nil {
+				return err
+			}
+			if w.appending {
+				w.header = header
+			}
+		case !slices.Equal(header, w.header):
+			return fmt.Errorf("the output file's columns (%s) aren't those written (%s)", strings.Join(w.header, ","), strings.Join(header, ","))
+		}
+		return nil
+	}
+	if err := writeHeader(); err != nil {
 		return err
 	}
+	writeRow := func(row []string) error {
+		if w.rotateDue() {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+			if err := w.rotate(); err != nil {
+				return err
+			}
+			csvWriter = csv.NewWriter(w.writer)
+			if err := writeHeader(); err != nil {
+				return err
+			}
+		}
+		if err := csvWriter.Write(row); err != nil {
+			return err
+		}
+		if w.rotating == nil {
+			return nil
+		}
+		// Rows are counted, and their size, once written out whole
+		csvWriter.Flush()
+		w.rotating.rows++
+		return csvWriter.Error()
+	}
 
 	// Write rows
 	for i := 0; i < val.Len(); i++ {
-		row := extractRow(val.Index(i), headers)
-		if err := csvWriter.Write(row); err != nil {
+		if w.skip > 0 {
+			// Already in the file appended to
+			w.skip--
+			w.rows--
+			continue
+		}
+		row := w.withErrorValue(extractRow(val.Index(i), headers, cell), "")
+		if w.fetchedAt != "" {
+			row = append(row, cell(w.fetchedAt))
+		}
+		if err := writeRow(row); err != nil {
+			return err
+		}
+	}
+	for _, row := range w.failureRows(headers) {
+		if w.fetchedAt != "" {
+			row = append(row, cell(w.fetchedAt))
+		}
+		if err := writeRow(row); err != nil {
 			return err
 		}
 	}
@@ -197,60 +612,244 @@ func (w *Writer) writeTable(data interface{}) error {
 	tw := tabwriter.NewWriter(w.writer, 0, 0, 2, ' ', 0)
 	defer tw.Flush()
 
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Map {
-		// If data is a map, try to extract an array/slice field
-		for _, key := range val.MapKeys() {
-			fieldVal := val.MapIndex(key)
-			if fieldVal.Kind() == reflect.Slice || fieldVal.Kind() == reflect.Array {
-				val = fieldVal
-				break
-			}
-		}
-	}
-
+	val := rowsOf(reflect.ValueOf(data))
 	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
 		// Single object - print as key-value pairs
 		return w.writeTableObject(tw, data)
 	}
 
-	if val.Len() == 0 {
+	if val.Len() == 0 && len(w.failures) == 0 {
 		fmt.Fprintln(tw, "(no results)")
 		return nil
 	}
 
-	// Get headers
-	headers := extractHeaders(val.Index(0))
-	fmt.Fprintln(tw, strings.Join(headers, "\t"))
-	fmt.Fprintln(tw, strings.Repeat("-", len(headers)*10))
+	headers := w.headers(val)
+	header := w.withTimestampHeader(w.withErrorHeader(headers))
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	fmt.Fprintln(tw, strings.Repeat("-", len(header)*10))
 
 	// Write rows
 	for i := 0; i < val.Len(); i++ {
-		row := extractRow(val.Index(i), headers)
-		fmt.Fprintln(tw, strings.Join(row, "\t"))
+		row := w.withErrorValue(extractRow(val.Index(i), headers, w.cell), "")
+		fmt.Fprintln(tw, strings.Join(w.withTimestampValue(row), "\t"))
+	}
+	for _, row := range w.failureRows(headers) {
+		fmt.Fprintln(tw, strings.Join(w.withTimestampValue(row), "\t"))
 	}
 
 	return nil
 }
 
+// Markdowner is data with a Markdown rendering of its own, such as a
+// report, which markdown output writes in place of a table
+type Markdowner interface {
+	Markdown() string
+}
+
+// writeMarkdown outputs data as a Markdown table: a row per item of a list,
+// or a field and value per row for a single object
+func (w *Writer) writeMarkdown(data interface{}) error {
+	if m, ok := data.(Markdowner); ok {
+		_, err := io.WriteString(w.writer, m.Markdown())
+		return err
+	}
+
+	var headers []string
+	var rows [][]string
+	val := rowsOf(reflect.ValueOf(data))
+	switch {
+	case val.Kind() == reflect.Slice || val.Kind() == reflect.Array:
+		if val.Len() == 0 && len(w.failures) == 0 {
+			_, err := fmt.Fprintln(w.writer, "(no results)")
+			return err
+		}
+		fields := w.headers(val)
+		headers = w.withTimestampHeader(w.withErrorHeader(fields))
+		for i := 0; i < val.Len(); i++ {
+			rows = append(rows, w.withTimestampValue(w.withErrorValue(extractRow(val.Index(i), fields, w.cell), "")))
+		}
+		for _, row := range w.failureRows(fields) {
+			rows = append(rows, w.withTimestampValue(row))
+		}
+	default:
+		headers = []string{"field", "value"}
+		fields := extractHeaders(val)
+		values := extractRow(val, fields, w.cell)
+		for i, field := range fields {
+			rows = append(rows, []string{field, values[i]})
+		}
+		if len(fields) == 0 {
+			rows = append(rows, []string{"value", w.cell(fieldValue(val))})
+		}
+		if w.fetchedAt != "" {
+			rows = append(rows, []string{TimestampField, w.fetchedAt})
+		}
+	}
+
+	var b strings.Builder
+	writeMarkdownRow(&b, headers)
+	b.WriteString("|" + strings.Repeat(" --- |", len(headers)) + "\n")
+	for _, row := range rows {
+		writeMarkdownRow(&b, row)
+	}
+	_, err := io.WriteString(w.writer, b.String())
+	return err
+}
+
+// writeMarkdownRow writes a row of a Markdown table, escaping the pipes and
+// line breaks that would end its cells
+func writeMarkdownRow(b *strings.Builder, cells []string) {
+	b.WriteString("|")
+	for _, cell := range cells {
+		cell = strings.ReplaceAll(cell, "|", `\|`)
+		cell = strings.Join(strings.Fields(cell), " ")
+		b.WriteString(" " + cell + " |")
+	}
+	b.WriteString("\n")
+}
+
+// rowsOf returns the list held by v: v itself, or the first list field of a
+// wrapper map or struct. Other values are returned unchanged.
+func rowsOf(v reflect.Value) reflect.Value {
+	v = indirect(v)
+
+	switch v.Kind() {
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			if field := indirect(v.MapIndex(key)); field.Kind() == reflect.Slice || field.Kind() == reflect.Array {
+				return field
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if field := v.Field(i); field.Kind() == reflect.Slice || field.Kind() == reflect.Array {
+				return field
+			}
+		}
+	}
+	return v
+}
+
+// countRows returns the number of rows in data
+func countRows(data interface{}) int {
+	rows := rowsOf(reflect.ValueOf(data))
+	switch rows.Kind() {
+	case reflect.Slice, reflect.Array:
+		return rows.Len()
+	case reflect.Invalid:
+		return 0
+	case reflect.Interface, reflect.Pointer:
+		if rows.IsNil() {
+			return 0
+		}
+	}
+	return 1
+}
+
+// indirect unwraps interfaces and pointers
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// headers returns the configured columns, the preset's columns for the
+// endpoint, or those of the first row, or of the rows' type when there are
+// none
+func (w *Writer) headers(rows reflect.Value) []string {
+	if len(w.columns) > 0 {
+		return w.columns
+	}
+	if w.preset != nil {
+		if columns, ok := w.preset.Columns[w.endpoint]; ok {
+			return columns
+		}
+	}
+	if rows.Len() == 0 {
+		return extractHeaders(reflect.Zero(rows.Type().Elem()))
+	}
+	return extractHeaders(rows.Index(0))
+}
+
+// withErrorHeader appends the error header when there are failures
+func (w *Writer) withErrorHeader(headers []string) []string {
+	if len(w.failures) == 0 {
+		return headers
+	}
+	return append(headers[:len(headers):len(headers)], ErrorField)
+}
+
+// withErrorValue appends the error value when there are failures
+func (w *Writer) withErrorValue(row []string, value string) []string {
+	if len(w.failures) == 0 {
+		return row
+	}
+	return append(row, value)
+}
+
+// failureRows returns a row for each failure under headers and the error
+// column, empty but for its target and the error's code and message
+func (w *Writer) failureRows(headers []string) [][]string {
+	rows := make([][]string, len(w.failures))
+	for i, f := range w.failures {
+		row := make([]string, len(headers))
+		if j := slices.Index(headers, "target"); j >= 0 {
+			row[j] = f.Target
+		}
+		rows[i] = w.withErrorValue(row, f.Code+": "+f.Message)
+	}
+	return rows
+}
+
+// withTimestampHeader appends the fetched_at header when enabled
+func (w *Writer) withTimestampHeader(headers []string) []string {
+	if w.fetchedAt == "" {
+		return headers
+	}
+	return append(headers[:len(headers):len(headers)], TimestampField)
+}
+
+// withTimestampValue appends the fetched_at value when enabled
+func (w *Writer) withTimestampValue(row []string) []string {
+	if w.fetchedAt == "" {
+		return row
+	}
+	return append(row, w.fetchedAt)
+}
+
 // writeTableObject writes a single object as a table
 func (w *Writer) writeTableObject(tw *tabwriter.Writer, data interface{}) error {
-	val := reflect.ValueOf(data)
+	if w.fetchedAt != "" {
+		defer fmt.Fprintf(tw, "%s:\t%s\n", TimestampField, w.fetchedAt)
+	}
+
+	val := indirect(reflect.ValueOf(data))
 
 	if val.Kind() == reflect.Map {
 		for _, key := range val.MapKeys() {
-			fmt.Fprintf(tw, "%v:\t%v\n", key.Interface(), val.MapIndex(key).Interface())
+			value := val.MapIndex(key).Interface()
+			if s, ok := value.(string); ok {
+				value = w.localTime(s)
+			}
+			fmt.Fprintf(tw, "%v:\t%v\n", key.Interface(), value)
 		}
 		return nil
 	}
 
 	if val.Kind() == reflect.Struct {
-		typ := val.Type()
-		for i := 0; i < val.NumField(); i++ {
-			field := typ.Field(i)
-			if field.IsExported() {
-				fmt.Fprintf(tw, "%s:\t%v\n", field.Name, val.Field(i).Interface())
-			}
+		for _, field := range exportedFields(val.Type()) {
+			fmt.Fprintf(tw, "%s:\t%s\n", field.Name, w.cell(fieldValue(val.FieldByIndex(field.Index))))
 		}
 		return nil
 	}
@@ -263,26 +862,24 @@ func (w *Writer) writeTableObject(tw *tabwriter.Writer, data interface{}) error
 func extractHeaders(v reflect.Value) []string {
 	var headers []string
 
+	v = indirect(v)
 	if v.Kind() == reflect.Map {
 		for _, key := range v.MapKeys() {
 			headers = append(headers, fmt.Sprintf("%v", key.Interface()))
 		}
+		sort.Strings(headers)
 		return headers
 	}
 
 	if v.Kind() == reflect.Struct {
-		typ := v.Type()
-		for i := 0; i < v.NumField(); i++ {
-			field := typ.Field(i)
-			if field.IsExported() {
-				// Use JSON tag if available
-				jsonTag := field.Tag.Get("json")
-				if jsonTag != "" && jsonTag != "-" {
-					name := strings.Split(jsonTag, ",")[0]
-					headers = append(headers, name)
-				} else {
-					headers = append(headers, field.Name)
-				}
+		for _, field := range exportedFields(v.Type()) {
+			// Use JSON tag if available
+			jsonTag := field.Tag.Get("json")
+			if jsonTag != "" && jsonTag != "-" {
+				name := strings.Split(jsonTag, ",")[0]
+				headers = append(headers, name)
+			} else {
+				headers = append(headers, field.Name)
 			}
 		}
 	}
@@ -290,15 +887,34 @@ func extractHeaders(v reflect.Value) []string {
 	return headers
 }
 
-// extractRow extracts values from a row based on headers
-func extractRow(v reflect.Value, headers []string) []string {
+// formatCell is the default formatting of a CSV or table cell. Nulls are
+// left empty, and generically decoded lists are comma-separated like typed
+// ones.
+func formatCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatCell(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// extractRow extracts values from a row based on headers, formatted with
+// cell. Fields the row lacks are left empty.
+func extractRow(v reflect.Value, headers []string, cell func(interface{}) string) []string {
 	row := make([]string, len(headers))
 
+	v = indirect(v)
 	if v.Kind() == reflect.Map {
 		for i, header := range headers {
 			for _, key := range v.MapKeys() {
 				if fmt.Sprintf("%v", key.Interface()) == header {
-					row[i] = fmt.Sprintf("%v", v.MapIndex(key).Interface())
+					row[i] = cell(v.MapIndex(key).Interface())
 					break
 				}
 			}
@@ -307,17 +923,16 @@ func extractRow(v reflect.Value, headers []string) []string {
 	}
 
 	if v.Kind() == reflect.Struct {
-		typ := v.Type()
+		fields := exportedFields(v.Type())
 		for i, header := range headers {
-			for j := 0; j < v.NumField(); j++ {
-				field := typ.Field(j)
+			for _, field := range fields {
 				jsonTag := field.Tag.Get("json")
 				fieldName := field.Name
 				if jsonTag != "" && jsonTag != "-" {
 					fieldName = strings.Split(jsonTag, ",")[0]
 				}
 				if fieldName == header {
-					row[i] = fmt.Sprintf("%v", v.Field(j).Interface())
+					row[i] = cell(fieldValue(v.FieldByIndex(field.Index)))
 					break
 				}
 			}
@@ -327,15 +942,62 @@ func extractRow(v reflect.Value, headers []string) []string {
 	return row
 }
 
-// formatError formats an error for output
-func formatError(err error) map[string]interface{} {
+// exportedFields returns t's exported fields, in order, with the fields of
+// embedded structs in place of the structs, as encoding/json flattens them
+func exportedFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			for _, inner := range exportedFields(f.Type) {
+				inner.Index = append([]int{i}, inner.Index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		if f.IsExported() {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// fieldValue returns the value of a struct field, dereferencing pointers;
+// nil pointers are nil
+func fieldValue(f reflect.Value) interface{} {
+	f = indirect(f)
+	if (f.Kind() == reflect.Pointer || f.Kind() == reflect.Interface) && f.IsNil() {
+		return nil
+	}
+	return f.Interface()
+}
+
+// FormatError formats an error as a structured object with a machine-readable
+// code, message, and suggestion where available. The body of an API error
+// response is summarized on one line, as raw_detail_summary.
+func FormatError(err error) map[string]interface{} {
+	return formatError(err, false)
+}
+
+// FormatVerboseError is FormatError with the body of an API error response
+// in full, as raw_detail, for --verbose
+func FormatVerboseError(err error) map[string]interface{} {
+	return formatError(err, true)
+}
+
+func formatError(err error, verbose bool) map[string]interface{} {
 	errMap := map[string]interface{}{
+		"code":    "ERROR",
 		"message": err.Error(),
 	}
 
 	// Check if it's an API error
-	if apiErr, ok := err.(*client.APIError); ok {
-		errMap["code"] = apiErr.Code
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		errMap["code"] = "API_ERROR"
+		if apiErr.Code != "" {
+			errMap["code"] = apiErr.Code
+		}
 		errMap["message"] = apiErr.Message
 		if apiErr.Suggestion != "" {
 			errMap["suggestion"] = apiErr.Suggestion
@@ -343,15 +1005,70 @@ func formatError(err error) map[string]interface{} {
 		if apiErr.DocsURL != "" {
 			errMap["docs_url"] = apiErr.DocsURL
 		}
+		if len(apiErr.Details) > 0 {
+			errMap["details"] = apiErr.Details
+		}
+		if apiErr.Excerpt != "" {
+			errMap["excerpt"] = apiErr.Excerpt
+		}
+		if apiErr.RawDetail != "" {
+			if verbose {
+				errMap["raw_detail"] = apiErr.RawDetail
+			} else {
+				errMap["raw_detail_summary"] = apiErr.RawSummary()
+			}
+		}
+		return errMap
+	}
+
+	// Errors from the command layer carry their own code and remedy
+	var coded interface {
+		ErrorCode() string
+		ErrorSuggestion() string
+	}
+	if errors.As(err, &coded) {
+		errMap["code"] = coded.ErrorCode()
+		if suggestion := coded.ErrorSuggestion(); suggestion != "" {
+			errMap["suggestion"] = suggestion
+		}
+	}
+	var detailed interface{ ErrorDetails() []client.FieldError }
+	if errors.As(err, &detailed) && len(detailed.ErrorDetails()) > 0 {
+		errMap["details"] = detailed.ErrorDetails()
 	}
 
 	return errMap
 }
 
-// Close closes the writer if it's a file
+// Close closes the writer if it owns its destination
 func (w *Writer) Close() error {
-	if f, ok := w.writer.(*os.File); ok && f != os.Stdout {
-		return f.Close()
+	if w.closer != nil {
+		return w.closer.Close()
 	}
 	return nil
 }
+
+// Abort discards the output when the destination supports it, such as an
+// object upload that hasn't been sent yet, and closes it otherwise
+func (w *Writer) Abort() error {
+	if a, ok := w.closer.(interface{ Abort() error }); ok {
+		return a.Abort()
+	}
+	return w.Close()
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	switch Format(format) {
+	case FormatJSON:
+		return "application/json"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
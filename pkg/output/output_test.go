@@ -0,0 +1,451 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+	"github.com/aminemat/ahrefs-cli/pkg/models"
+)
+
+func TestWriter_WriteValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    interface{}
+		field   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "nested single object",
+			data:  models.DomainRatingResponse{DomainRating: models.DomainRating{DomainRating: 91}},
+			field: "domain_rating",
+			want:  "91\n",
+		},
+		{
+			name:  "first row of list",
+			data:  models.AnchorsResponse{Anchors: []models.Anchor{{Anchor: "click here", Backlinks: 12}, {Anchor: "other"}}},
+			field: "anchor",
+			want:  "click here\n",
+		},
+		{
+			name:    "missing field",
+			data:    models.BacklinksStatsResponse{Metrics: models.BacklinksMetrics{Live: 5}},
+			field:   "nope",
+			wantErr: true,
+		},
+		{
+			name:    "empty list",
+			data:    models.AnchorsResponse{},
+			field:   "anchor",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := &Writer{format: FormatJSON, writer: &buf}
+
+			err := w.WriteValue(tt.data, tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && buf.String() != tt.want {
+				t.Errorf("WriteValue() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter_SetFetchedAt(t *testing.T) {
+	fetchedAt := time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)
+	want := "2025-06-01T12:30:00Z"
+	rows := models.AnchorsResponse{Anchors: []models.Anchor{{Anchor: "a", Backlinks: 1}, {Anchor: "b", Backlinks: 2}}}
+	single := models.DomainRatingResponse{DomainRating: models.DomainRating{DomainRating: 42}}
+
+	t.Run("json rows", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := &Writer{format: FormatJSON, writer: &buf}
+		w.SetFetchedAt(fetchedAt)
+
+		if err := w.WriteSuccess(rows, nil); err != nil {
+			t.Fatalf("WriteSuccess() error = %v", err)
+		}
+
+		var got struct {
+			Data struct {
+				Anchors []map[string]interface{} `json:"anchors"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		for i, row := range got.Data.Anchors {
+			if row[TimestampField] != want {
+				t.Errorf("row %d fetched_at = %v, want %v", i, row[TimestampField], want)
+			}
+		}
+	})
+
+	t.Run("json single object", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := &Writer{format: FormatJSON, writer: &buf}
+		w.SetFetchedAt(fetchedAt)
+
+		if err := w.WriteSuccess(single, nil); err != nil {
+			t.Fatalf("WriteSuccess() error = %v", err)
+		}
+
+		var got struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if got.Data[TimestampField] != want {
+			t.Errorf("fetched_at = %v, want %v", got.Data[TimestampField], want)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := &Writer{format: FormatCSV, writer: &buf}
+		w.SetFetchedAt(fetchedAt)
+
+		if err := w.WriteSuccess(rows.Anchors, nil); err != nil {
+			t.Fatalf("WriteSuccess() error = %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("got %d lines, want 3", len(lines))
+		}
+		if !strings.HasSuffix(lines[0], ","+TimestampField) {
+			t.Errorf("header = %q, want fetched_at column", lines[0])
+		}
+		if !strings.HasSuffix(lines[1], ","+want) {
+			t.Errorf("row = %q, want fetched_at value", lines[1])
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := &Writer{format: FormatCSV, writer: &buf}
+
+		if err := w.WriteSuccess(rows.Anchors, nil); err != nil {
+			t.Fatalf("WriteSuccess() error = %v", err)
+		}
+		if strings.Contains(buf.String(), TimestampField) {
+			t.Errorf("output should not contain fetched_at by default: %q", buf.String())
+		}
+	})
+}
+
+func TestWriter_CSVColumns(t *testing.T) {
+	title := "Home"
+	rows := map[string]interface{}{
+		"anchors": []interface{}{
+			map[string]interface{}{"anchor": "a", "first_seen": "2024-01-01", "backlinks": json.Number("3")},
+			map[string]interface{}{"anchor": "b"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		data    interface{}
+		columns []string
+		want    string
+	}{
+		{
+			name:    "columns from select order",
+			data:    rows,
+			columns: []string{"first_seen", "anchor", "unknown"},
+			want:    "first_seen,anchor,unknown\n2024-01-01,a,\n,b,\n",
+		},
+		{
+			name: "generic rows without columns",
+			data: rows,
+			want: "anchor,backlinks,first_seen\na,3,2024-01-01\nb,,\n",
+		},
+		{
+			name: "typed wrapper struct",
+			data: models.AnchorsResponse{Anchors: []models.Anchor{{Anchor: "a", Backlinks: 1}}},
+			want: "anchor,backlinks,refdomains,first_seen,last_visited\na,1,0,,\n",
+		},
+		{
+			name:    "generic list cells",
+			data:    map[string]interface{}{"keywords": []interface{}{map[string]interface{}{"keyword": "a", "serp_features": []interface{}{"video", "sitelinks"}}}},
+			columns: []string{"keyword", "serp_features"},
+			want:    "keyword,serp_features\na,\"video,sitelinks\"\n",
+		},
+		{
+			name:    "typed nullable fields",
+			data:    models.BacklinksResponse{Backlinks: []models.Backlink{{URLFrom: "a", Title: &title, IsDofollow: true, Languages: models.StringList{"en", "fr"}}, {URLFrom: "b"}}},
+			columns: []string{"url_from", "title", "page_size", "is_dofollow", "languages"},
+			want:    "url_from,title,page_size,is_dofollow,languages\na,Home,,true,\"en,fr\"\nb,,,false,\n",
+		},
+		{
+			name: "embedded struct fields",
+			data: models.AnchorLanguagesResponse{Anchors: []models.AnchorLanguage{{Anchor: models.Anchor{Anchor: "a", Backlinks: 1}, Language: "en", LanguageConfidence: 0.9}}},
+			want: "anchor,backlinks,refdomains,first_seen,last_visited,language,language_confidence\na,1,0,,,en,0.9\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := &Writer{format: FormatCSV, writer: &buf}
+			w.SetColumns(tt.columns)
+
+			if err := w.WriteSuccess(tt.data, nil); err != nil {
+				t.Fatalf("WriteSuccess() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("WriteSuccess() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter_TableObject(t *testing.T) {
+	kd := 42
+	var buf bytes.Buffer
+	w := &Writer{format: FormatTable, writer: &buf}
+	if err := w.WriteSuccess(&models.KeywordDifficulty{Keyword: "crm", Difficulty: &kd}, nil); err != nil {
+		t.Fatalf("WriteSuccess() error = %v", err)
+	}
+
+	// Pointers are shown by value, nil ones empty
+	lines := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		name, value, _ := strings.Cut(line, ":")
+		lines[name] = strings.TrimSpace(value)
+	}
+	for name, want := range map[string]string{"Keyword": "crm", "Difficulty": "42", "Volume": ""} {
+		if got, ok := lines[name]; !ok || got != want {
+			t.Errorf("%s = %q, want %q in %q", name, got, want, buf.String())
+		}
+	}
+}
+
+func TestWriter_NDJSON(t *testing.T) {
+	var buf, meta bytes.Buffer
+	w := &Writer{format: FormatNDJSON, writer: &buf}
+	w.SetMetaWriter(&meta)
+
+	data := models.AnchorsResponse{Anchors: []models.Anchor{{Anchor: "a", Backlinks: 1}, {Anchor: "b", Backlinks: 2}}}
+	if err := w.WriteSuccess(data, &client.ResponseMeta{ResponseTimeMS: 12, UnitsConsumed: 50}); err != nil {
+		t.Fatalf("WriteSuccess() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], `{"anchor":"a","backlinks":1`) || !strings.HasPrefix(lines[1], `{"anchor":"b"`) {
+		t.Errorf("rows = %q, want a line per anchor", buf.String())
+	}
+	if want := `{"meta":{"response_time_ms":12,"units_consumed":50}}` + "\n"; meta.String() != want {
+		t.Errorf("meta = %q, want %q", meta.String(), want)
+	}
+	if w.Rows() != 2 {
+		t.Errorf("Rows() = %d, want 2", w.Rows())
+	}
+
+	// Errors aren't rows
+	buf.Reset()
+	if err := w.WriteError(errors.New("boom")); err != nil || buf.Len() != 0 {
+		t.Errorf("WriteError() = %v, wrote %q, want nothing", err, buf.String())
+	}
+}
+
+func TestWriter_Meta(t *testing.T) {
+	meta := &client.ResponseMeta{ResponseTimeMS: 12, Attempts: 3, TotalWaitMS: 3004, Cached: true}
+	data := map[string]interface{}{"domain_rating": 91}
+
+	var buf bytes.Buffer
+	w := &Writer{format: FormatJSON, writer: &buf}
+	if err := w.WriteSuccess(data, meta); err != nil {
+		t.Fatalf("WriteSuccess() error = %v", err)
+	}
+	var resp struct {
+		Meta map[string]interface{} `json:"meta"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"response_time_ms": 12.0, "attempts": 3.0, "total_wait_ms": 3004.0, "cached": true}
+	if !reflect.DeepEqual(resp.Meta, want) {
+		t.Errorf("JSON meta = %v, want %v", resp.Meta, want)
+	}
+
+	buf.Reset()
+	w = &Writer{format: FormatYAML, writer: &buf}
+	if err := w.WriteSuccess(data, meta); err != nil {
+		t.Fatalf("WriteSuccess() error = %v", err)
+	}
+	wantYAML := "meta:\n  attempts:\n    3\n  cached:\n    true\n  response_time_ms:\n    12\n  total_wait_ms:\n    3004\n"
+	if !strings.HasSuffix(buf.String(), wantYAML) {
+		t.Errorf("YAML output = %q, want it to end with %q", buf.String(), wantYAML)
+	}
+}
+
+func TestWriter_WriteErrorRawDetail(t *testing.T) {
+	apiErr := &client.APIError{
+		StatusCode: 400,
+		Code:       "VALIDATION_ERROR",
+		Message:    "Invalid where",
+		RawDetail:  "{\n  \"error\": {\n    \"message\": \"Invalid where\"\n  }\n}",
+	}
+	for _, format := range []Format{FormatJSON, FormatCSV, FormatTable} {
+		for _, verbose := range []bool{false, true} {
+			var buf bytes.Buffer
+			w := &Writer{format: format, writer: &buf}
+			w.SetVerbose(verbose)
+			if err := w.WriteError(fmt.Errorf("request failed: %w", apiErr)); err != nil {
+				t.Fatalf("WriteError() error = %v", err)
+			}
+
+			var resp struct {
+				Error map[string]interface{} `json:"error"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &resp); err != nil {
+				t.Fatalf("%s: output is not JSON: %v", format, err)
+			}
+			want := map[string]interface{}{"raw_detail_summary": `{ "error": { "message": "Invalid where" } }`}
+			if verbose {
+				want = map[string]interface{}{"raw_detail": apiErr.RawDetail}
+			}
+			for _, key := range []string{"raw_detail", "raw_detail_summary"} {
+				if resp.Error[key] != want[key] {
+					t.Errorf("%s, verbose %v: %s = %v, want %v", format, verbose, key, resp.Error[key], want[key])
+				}
+			}
+		}
+	}
+}
+
+func TestWriter_Markdown(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		want string
+	}{
+		{
+			name: "rows",
+			data: models.AnchorsResponse{Anchors: []models.Anchor{{Anchor: "a|b", Backlinks: 1}}},
+			want: "| a\\|b | 1 |",
+		},
+		{
+			name: "object",
+			data: models.DomainRating{DomainRating: 71},
+			want: "| field | value |\n| --- | --- |\n| domain_rating | 71 |\n",
+		},
+		{
+			name: "no rows",
+			data: models.AnchorsResponse{Anchors: []models.Anchor{}},
+			want: "(no results)\n",
+		},
+		{
+			name: "own rendering",
+			data: markdownReport("**done**"),
+			want: "**done**",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriterTo(string(FormatMarkdown), &buf)
+			if err := w.WriteSuccess(tt.data, nil); err != nil {
+				t.Fatalf("WriteSuccess() error = %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("output = %q, want it to contain %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+// markdownReport is data with a Markdown rendering of its own
+type markdownReport string
+
+func (r markdownReport) Markdown() string { return string(r) }
+
+// targetRow is a row of a command run for several targets
+type targetRow struct {
+	Target string `json:"target"`
+	Value  int    `json:"value"`
+}
+
+func TestWriter_SetFailures(t *testing.T) {
+	failures := []Failure{{Target: "gone.example", Code: "NOT_FOUND", Message: "target not found"}}
+	rows := []targetRow{{Target: "example.com", Value: 70}}
+
+	tests := []struct {
+		name     string
+		format   Format
+		rows     []targetRow
+		failures []Failure
+		want     string
+	}{
+		{"csv all ok", FormatCSV, rows, nil, "target,value\nexample.com,70\n"},
+		{"csv some fail", FormatCSV, rows, failures, "target,value,error\nexample.com,70,\ngone.example,,NOT_FOUND: target not found\n"},
+		{"csv all fail", FormatCSV, nil, failures, "target,value,error\ngone.example,,NOT_FOUND: target not found\n"},
+		{"markdown some fail", FormatMarkdown, rows, failures, "| gone.example |  | NOT_FOUND: target not found |"},
+		{"table all fail", FormatTable, nil, failures, "NOT_FOUND: target not found"},
+		{"yaml some fail", FormatYAML, rows, failures, "status: partial\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriterTo(string(tt.format), &buf)
+			w.SetFailures(tt.failures)
+			if err := w.WriteSuccess(tt.rows, nil); err != nil {
+				t.Fatalf("WriteSuccess() error = %v", err)
+			}
+			if tt.format == FormatCSV && buf.String() != tt.want || !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("output = %q, want %q", buf.String(), tt.want)
+			}
+			if w.Rows() != len(tt.rows) {
+				t.Errorf("Rows() = %d, want the %d rows that succeeded", w.Rows(), len(tt.rows))
+			}
+		})
+	}
+}
+
+func TestWriter_SetFailuresJSON(t *testing.T) {
+	failures := []Failure{{Target: "gone.example", Code: "NOT_FOUND", Message: "target not found"}}
+
+	var buf bytes.Buffer
+	w := NewWriterTo(string(FormatJSON), &buf)
+	w.SetFailures(failures)
+	if err := w.WriteSuccess([]targetRow{{Target: "example.com", Value: 70}}, nil); err != nil {
+		t.Fatalf("WriteSuccess() error = %v", err)
+	}
+	var got struct {
+		Status string      `json:"status"`
+		Data   []targetRow `json:"data"`
+		Errors []Failure   `json:"errors"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "partial" || len(got.Data) != 1 || len(got.Errors) != 1 || got.Errors[0] != failures[0] {
+		t.Errorf("output = %s, want the row in data and the failure in errors", buf.String())
+	}
+
+	// NDJSON lines are rows only; failures go with the meta
+	var rows, meta bytes.Buffer
+	w = &Writer{format: FormatNDJSON, writer: &rows}
+	w.SetMetaWriter(&meta)
+	w.SetFailures(failures)
+	if err := w.WriteSuccess([]targetRow{{Target: "example.com", Value: 70}}, nil); err != nil {
+		t.Fatalf("WriteSuccess() error = %v", err)
+	}
+	if want := `{"errors":[{"target":"gone.example","code":"NOT_FOUND","message":"target not found"}]}` + "\n"; meta.String() != want {
+		t.Errorf("meta = %q, want %q", meta.String(), want)
+	}
+}
@@ -0,0 +1,141 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Preset reshapes CSV output for a downstream tool: which columns appear and
+// in what order, what they are called, and how values are written
+type Preset struct {
+	Name        string
+	Description string
+
+	// Columns fixes the fields written, in order, per endpoint. Endpoints
+	// without an entry keep the columns of the response.
+	Columns map[string][]string
+
+	// Headers renames fields; fields not listed are title-cased
+	Headers map[string]string
+
+	// ISODates rewrites date and time values as ISO 8601: YYYY-MM-DD for
+	// dates, YYYY-MM-DDTHH:MM:SSZ in UTC for times
+	ISODates bool
+
+	// EmptyNulls writes null and missing values as empty cells
+	EmptyNulls bool
+}
+
+// Presets lists the presets available to --preset
+var Presets = map[string]Preset{
+	"looker": {
+		Name:        "looker",
+		Description: "Looker Studio: readable headers, ISO dates, empty cells for nulls, stable column order",
+		Columns: map[string][]string{
+			"/site-explorer/backlinks":        {"url_from", "url_to", "anchor", "domain_rating", "url_rating", "traffic", "http_code", "link_type", "first_seen", "last_visited"},
+			"/site-explorer/broken-backlinks": {"url_from", "url_to", "anchor", "domain_rating", "http_code", "first_seen", "last_visited"},
+			"/site-explorer/refdomains":       {"domain", "domain_rating", "backlinks", "dofollow", "linked_pages", "first_seen", "last_visited"},
+			"/site-explorer/anchors":          {"anchor", "backlinks", "refdomains", "first_seen", "last_visited"},
+			"/site-explorer/organic-keywords": {"keyword", "country", "position", "volume", "traffic", "kd", "url"},
+			"/site-explorer/top-pages":        {"url", "top_keyword", "position", "volume", "traffic", "traffic_value", "keywords", "url_rating"},
+		},
+		Headers: map[string]string{
+			"url_from":      "Referring Page URL",
+			"url_to":        "Target URL",
+			"domain_rating": "Domain Rating",
+			"url_rating":    "URL Rating",
+			"http_code":     "HTTP Status Code",
+			"kd":            "Keyword Difficulty",
+			"volume":        "Search Volume",
+			"dofollow":      "Dofollow Backlinks",
+			"fetched_at":    "Fetched At",
+		},
+		ISODates:   true,
+		EmptyNulls: true,
+	},
+}
+
+// PresetNames returns the names of the available presets, sorted
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupPreset returns the named preset
+func LookupPreset(name string) (Preset, error) {
+	p, ok := Presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	return p, nil
+}
+
+// header returns the output name of field
+func (p *Preset) header(field string) string {
+	if h, ok := p.Headers[field]; ok {
+		return h
+	}
+	words := strings.Split(field, "_")
+	for i, w := range words {
+		switch w {
+		case "url", "http", "kd", "dr", "ur":
+			words[i] = strings.ToUpper(w)
+		default:
+			if w != "" {
+				words[i] = strings.ToUpper(w[:1]) + w[1:]
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// dateLayouts are the date and time formats rewritten by ISODates, with
+// whether they carry a time of day
+var dateLayouts = []struct {
+	layout string
+	isTime bool
+}{
+	{time.RFC3339Nano, true},
+	{"2006-01-02T15:04:05", true},
+	{"2006-01-02 15:04:05", true},
+	{"2006-01-02", false},
+}
+
+// cell formats a value for the preset
+func (p *Preset) cell(v interface{}) string {
+	if v == nil {
+		if p.EmptyNulls {
+			return ""
+		}
+		return fmt.Sprintf("%v", v)
+	}
+	if s, ok := v.(string); ok && p.ISODates {
+		return isoDate(s)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// isoDate rewrites s as ISO 8601 if it is a date or time, and returns it
+// unchanged otherwise
+func isoDate(s string) string {
+	if len(s) < 10 || s[4] != '-' || s[7] != '-' {
+		return s
+	}
+	for _, d := range dateLayouts {
+		t, err := time.Parse(d.layout, s)
+		if err != nil {
+			continue
+		}
+		if !d.isTime {
+			return t.Format("2006-01-02")
+		}
+		return t.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return s
+}
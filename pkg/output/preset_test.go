@@ -0,0 +1,90 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriter_Preset(t *testing.T) {
+	looker, err := LookupPreset("looker")
+	if err != nil {
+		t.Fatalf("LookupPreset() error = %v", err)
+	}
+
+	rows := map[string]interface{}{
+		"anchors": []interface{}{
+			map[string]interface{}{"anchor": "a", "backlinks": json.Number("3"), "first_seen": "2024-01-05T10:20:30+02:00", "last_visited": "2024-02-01"},
+			map[string]interface{}{"anchor": "b", "backlinks": json.Number("0"), "refdomains": nil},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		endpoint string
+		columns  []string
+		want     string
+	}{
+		{
+			name:     "fixed column order",
+			endpoint: "/site-explorer/anchors",
+			want: "Anchor,Backlinks,Refdomains,First Seen,Last Visited\n" +
+				"a,3,,2024-01-05T08:20:30Z,2024-02-01\n" +
+				"b,0,,,\n",
+		},
+		{
+			name:     "select overrides preset columns",
+			endpoint: "/site-explorer/anchors",
+			columns:  []string{"first_seen", "anchor"},
+			want:     "First Seen,Anchor\n2024-01-05T08:20:30Z,a\n,b\n",
+		},
+		{
+			name: "endpoint without columns",
+			want: "Anchor,Backlinks,First Seen,Last Visited\n" +
+				"a,3,2024-01-05T08:20:30Z,2024-02-01\n" +
+				"b,0,,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := &Writer{format: FormatCSV, writer: &buf}
+			w.SetPreset(looker)
+			w.SetEndpoint(tt.endpoint)
+			w.SetColumns(tt.columns)
+
+			if err := w.WriteSuccess(rows, nil); err != nil {
+				t.Fatalf("WriteSuccess() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("WriteSuccess() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupPreset_Unknown(t *testing.T) {
+	if _, err := LookupPreset("tableau"); err == nil {
+		t.Error("LookupPreset(\"tableau\") error = nil, want error")
+	}
+}
+
+func TestPreset_Header(t *testing.T) {
+	p := Presets["looker"]
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{field: "url_from", want: "Referring Page URL"},
+		{field: "top_keyword", want: "Top Keyword"},
+		{field: "url", want: "URL"},
+		{field: "traffic_value", want: "Traffic Value"},
+	}
+
+	for _, tt := range tests {
+		if got := p.header(tt.field); got != tt.want {
+			t.Errorf("header(%q) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Rotation limits the size of an output file. Once a file reaches either
+// limit, it is closed and the rows continue in a successor numbered after
+// it: report.csv, then report.1.csv, report.2.csv, and so on. A row is never
+// split between files, and each CSV file starts with the header.
+type Rotation struct {
+	MaxBytes int64 // 0 for no limit
+	MaxRows  int   // 0 for no limit
+}
+
+// RotatedName returns the name of the nth successor of outputFile, the
+// number going before the extension, or outputFile itself for 0
+func RotatedName(outputFile string, n int) string {
+	if n == 0 {
+		return outputFile
+	}
+	ext := filepath.Ext(outputFile)
+	return strings.TrimSuffix(outputFile, ext) + "." + strconv.Itoa(n) + ext
+}
+
+// NewRotatingWriter creates a CSV or NDJSON writer to outputFile that
+// rotates it by r. With appending, rows are added to the end of the last
+// file of an earlier run, as NewAppendWriter does, and continue in a
+// successor once it is full.
+func NewRotatingWriter(format, outputFile string, r Rotation, appending bool) (*Writer, error) {
+	if Format(format) != FormatCSV && Format(format) != FormatNDJSON {
+		return nil, fmt.Errorf("rotating output requires CSV or NDJSON, not %s", format)
+	}
+
+	rf := &rotatingFile{Rotation: r, path: outputFile}
+	w := &Writer{format: Format(format), writer: rf, closer: rf, rotating: rf}
+	if !appending {
+		if err := rf.open(os.O_TRUNC); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+
+	w.appending = true
+	for rf.n = 0; ; rf.n++ {
+		if _, err := os.Stat(RotatedName(outputFile, rf.n+1)); err != nil {
+			break
+		}
+	}
+	if err := rf.open(0); err != nil {
+		return nil, err
+	}
+	header, err := rf.scan(w.format)
+	if err != nil {
+		rf.Close()
+		return nil, err
+	}
+	if header != nil {
+		if w.header, err = csv.NewReader(bytes.NewReader(header)).Read(); err != nil {
+			rf.Close()
+			return nil, fmt.Errorf("failed to read output file header: %w", err)
+		}
+	}
+	return w, nil
+}
+
+// rotatingFile is an output file continued in numbered successors. Writes
+// are buffered, and counted towards the current file's size.
+type rotatingFile struct {
+	Rotation
+	path string // the first file
+	n    int    // the number of the current file, 0 for path
+
+	file *os.File
+	buf  *bufio.Writer
+	size int64 // bytes written to the current file
+	rows int   // rows written to the current file
+}
+
+// open opens the current file for writing at its end, with flag added to the
+// flags it is opened with
+func (f *rotatingFile) open(flag int) error {
+	name := RotatedName(f.path, f.n)
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|flag, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+	f.file, f.buf, f.size, f.rows = file, bufio.NewWriter(file), size, 0
+	return nil
+}
+
+// scan counts the rows the current file has, for appending to it, and
+// returns the first line of a CSV file as its header
+func (f *rotatingFile) scan(format Format) ([]byte, error) {
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read output file: %w", err)
+	}
+	defer f.file.Seek(0, io.SeekEnd)
+
+	if format != FormatCSV {
+		// Every line is a row
+		data, err := io.ReadAll(f.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read output file: %w", err)
+		}
+		f.rows = bytes.Count(data, []byte("\n"))
+		return nil, nil
+	}
+	scan, err := scanCSV(f.file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output file: %w", err)
+	}
+	f.rows = scan.rows
+	return scan.header, nil
+}
+
+// full reports whether the current file has reached a limit, so the next
+// row goes to a successor. A file without rows is never full, however big
+// its CSV header.
+func (f *rotatingFile) full() bool {
+	if f.rows == 0 {
+		return false
+	}
+	return f.MaxRows > 0 && f.rows >= f.MaxRows || f.MaxBytes > 0 && f.size >= f.MaxBytes
+}
+
+// next closes the current file and opens its successor, replacing any file
+// of that name
+func (f *rotatingFile) next() error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	f.n++
+	return f.open(os.O_TRUNC)
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	n, err := f.buf.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) Close() error {
+	return errors.Join(f.buf.Flush(), f.file.Close())
+}
+
+// rotateDue reports whether the output file is full, and is rotated before
+// the next row is written
+func (w *Writer) rotateDue() bool {
+	return w.rotating != nil && w.rotating.full()
+}
+
+// rotate continues the output in the successor of the current file, which
+// takes a CSV header of its own
+func (w *Writer) rotate() error {
+	if err := w.rotating.next(); err != nil {
+		return err
+	}
+	w.header = nil
+	return nil
+}
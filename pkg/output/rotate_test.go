@@ -0,0 +1,165 @@
+package output
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatedName(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want string
+	}{
+		{"report-2025-06-01.ndjson", 0, "report-2025-06-01.ndjson"},
+		{"report-2025-06-01.ndjson", 1, "report-2025-06-01.1.ndjson"},
+		{"out/anchors.csv", 12, "out/anchors.12.csv"},
+		{"anchors", 2, "anchors.2"},
+	}
+	for _, tt := range tests {
+		if got := RotatedName(tt.name, tt.n); got != tt.want {
+			t.Errorf("RotatedName(%q, %d) = %q, want %q", tt.name, tt.n, got, tt.want)
+		}
+	}
+}
+
+// readRotated returns the contents of path and its successors, in order
+func readRotated(t *testing.T, path string) []string {
+	t.Helper()
+	var files []string
+	for n := 0; ; n++ {
+		data, err := os.ReadFile(RotatedName(path, n))
+		if os.IsNotExist(err) {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, string(data))
+	}
+}
+
+func TestRotatingWriter_Rows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchors.csv")
+	w, err := NewRotatingWriter("csv", path, Rotation{MaxRows: 2}, false)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter() error = %v", err)
+	}
+	if err := w.WriteSuccess(anchorRows(5), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"anchor,backlinks\na,0\nb,1\n",
+		"anchor,backlinks\nc,2\nd,3\n",
+		"anchor,backlinks\ne,4\n",
+	}
+	if got := readRotated(t, path); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+}
+
+func TestRotatingWriter_Size(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchors.csv")
+	w, err := NewRotatingWriter("csv", path, Rotation{MaxBytes: 24}, false)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter() error = %v", err)
+	}
+	if err := w.WriteSuccess(anchorRows(8), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The header is 17 bytes and each row 4: a file takes rows until it
+	// reaches 24 bytes, and rows are never cut between files
+	files := readRotated(t, path)
+	if len(files) != 4 {
+		t.Fatalf("files = %q, want 4", files)
+	}
+	rows := 0
+	for _, f := range files {
+		records, err := csv.NewReader(strings.NewReader(f)).ReadAll()
+		if err != nil {
+			t.Fatalf("file %q isn't CSV: %v", f, err)
+		}
+		if strings.Join(records[0], ",") != "anchor,backlinks" {
+			t.Errorf("file %q doesn't start with the header", f)
+		}
+		rows += len(records) - 1
+		lastRow := strings.Join(records[len(records)-1], ",") + "\n"
+		if len(f)-len(lastRow) >= 24 {
+			t.Errorf("file %q took a row after reaching 24 bytes", f)
+		}
+	}
+	if rows != 8 {
+		t.Errorf("rows = %d across files, want 8", rows)
+	}
+}
+
+func TestRotatingWriter_Append(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchors.csv")
+	write := func(n int) {
+		t.Helper()
+		w, err := NewRotatingWriter("csv", path, Rotation{MaxRows: 2}, true)
+		if err != nil {
+			t.Fatalf("NewRotatingWriter() error = %v", err)
+		}
+		if err := w.WriteSuccess(anchorRows(n), nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second run fills the last file of the first before moving on
+	write(3)
+	write(2)
+	want := []string{
+		"anchor,backlinks\na,0\nb,1\n",
+		"anchor,backlinks\nc,2\na,0\n",
+		"anchor,backlinks\nb,1\n",
+	}
+	if got := readRotated(t, path); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+}
+
+func TestRotatingWriter_NDJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchors.ndjson")
+	if err := os.WriteFile(path, []byte("{\"anchor\":\"x\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewRotatingWriter("ndjson", path, Rotation{MaxRows: 2}, true)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter() error = %v", err)
+	}
+	if err := w.WriteSuccess(anchorRows(3), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readRotated(t, path)
+	if len(files) != 2 {
+		t.Fatalf("files = %q, want 2", files)
+	}
+	for i, wantLines := range []int{2, 2} {
+		if got := strings.Count(files[i], "\n"); got != wantLines {
+			t.Errorf("file %d has %d lines, want %d: %q", i, got, wantLines, files[i])
+		}
+	}
+
+	if _, err := NewRotatingWriter("json", path, Rotation{MaxRows: 2}, false); err == nil {
+		t.Error("NewRotatingWriter() should refuse JSON output")
+	}
+}
@@ -0,0 +1,442 @@
+// Package telemetry traces and counts API calls and exports them over
+// OTLP/HTTP in its JSON encoding, without the OpenTelemetry SDK.
+//
+// It is configured from the standard environment variables:
+//
+//	OTEL_EXPORTER_OTLP_ENDPOINT          collector base URL, e.g. http://localhost:4318
+//	OTEL_EXPORTER_OTLP_TRACES_ENDPOINT   full traces URL (overrides the base)
+//	OTEL_EXPORTER_OTLP_METRICS_ENDPOINT  full metrics URL (overrides the base)
+//	OTEL_EXPORTER_OTLP_HEADERS           extra headers, e.g. api-key=secret
+//	OTEL_SERVICE_NAME                    service.name resource attribute
+//	OTEL_SDK_DISABLED                    true turns everything off
+//	TRACEPARENT                          W3C trace context to parent spans under
+//
+// With no endpoint set, FromEnv returns nil and nothing is started.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+// ScopeName identifies this instrumentation in exported data
+const ScopeName = "github.com/aminemat/ahrefs-cli/pkg/telemetry"
+
+// exportInterval is how often buffered spans and metrics are sent
+const exportInterval = 5 * time.Second
+
+// Metric names
+const (
+	MetricRequests = "ahrefs.client.requests"
+	MetricRetries  = "ahrefs.client.retries"
+	MetricUnits    = "ahrefs.client.units_consumed"
+)
+
+// Telemetry buffers spans and counters and exports them periodically
+type Telemetry struct {
+	tracesURL  string
+	metricsURL string
+	headers    http.Header
+	resource   []attribute
+	httpClient *http.Client
+
+	// parent is the trace context spans are created under, from TRACEPARENT
+	traceID  string
+	parentID string
+
+	mu       sync.Mutex
+	logger   *slog.Logger
+	spans    []span
+	counters map[counterKey]int64
+	start    time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// FromEnv returns telemetry configured from the environment, or nil when no
+// OTLP endpoint is set. A non-nil Telemetry exports in the background until
+// Shutdown.
+func FromEnv() *Telemetry {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return nil
+	}
+	base := strings.TrimRight(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "/")
+	tracesURL := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	metricsURL := os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT")
+	if base == "" && tracesURL == "" && metricsURL == "" {
+		return nil
+	}
+	if tracesURL == "" && base != "" {
+		tracesURL = base + "/v1/traces"
+	}
+	if metricsURL == "" && base != "" {
+		metricsURL = base + "/v1/metrics"
+	}
+
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = "ahrefs-cli"
+	}
+
+	t := New(tracesURL, metricsURL, parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")), service)
+	t.traceID, t.parentID = parseTraceparent(os.Getenv("TRACEPARENT"))
+	t.run()
+	return t
+}
+
+// New returns telemetry exporting to the given OTLP/HTTP URLs; an empty URL
+// disables that signal. Call Flush to export, or use FromEnv for periodic
+// export.
+func New(tracesURL, metricsURL string, headers http.Header, service string) *Telemetry {
+	if headers == nil {
+		headers = http.Header{}
+	}
+	return &Telemetry{
+		tracesURL:  tracesURL,
+		metricsURL: metricsURL,
+		headers:    headers,
+		resource:   []attribute{attr("service.name", service)},
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		counters:   map[counterKey]int64{},
+		start:      time.Now(),
+	}
+}
+
+// parseHeaders parses the comma-separated key=value list of
+// OTEL_EXPORTER_OTLP_HEADERS; values may be percent-encoded
+func parseHeaders(s string) http.Header {
+	h := http.Header{}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			v = unescaped
+		}
+		h.Set(strings.TrimSpace(k), v)
+	}
+	return h
+}
+
+// parseTraceparent returns the trace and span IDs of a W3C traceparent
+// header value, or empty strings when it is malformed
+func parseTraceparent(s string) (traceID, spanID string) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", ""
+	}
+	if _, err := hex.DecodeString(parts[1] + parts[2]); err != nil {
+		return "", ""
+	}
+	return strings.ToLower(parts[1]), strings.ToLower(parts[2])
+}
+
+func (t *Telemetry) run() {
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(exportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), exportInterval)
+				if err := t.Flush(ctx); err != nil {
+					t.Log().Warn("telemetry export failed", "err", err)
+				}
+				cancel()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// SetLogger sets the logger warnings about failed exports are written to,
+// in place of slog.Default
+func (t *Telemetry) SetLogger(l *slog.Logger) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.logger = l
+}
+
+// Log returns the logger warnings are written to
+func (t *Telemetry) Log() *slog.Logger {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.logger != nil {
+		return t.logger
+	}
+	return slog.Default()
+}
+
+// Shutdown stops periodic export and sends whatever is buffered
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	if t.stop != nil {
+		close(t.stop)
+		<-t.done
+		t.stop = nil
+	}
+	return t.Flush(ctx)
+}
+
+// Middleware returns client middleware that records a span and counters for
+// every API call
+func (t *Telemetry) Middleware() client.Middleware {
+	return func(next client.Handler) client.Handler {
+		return func(ctx context.Context, req client.Request) (*client.Response, error) {
+			start := time.Now()
+			resp, err := next(ctx, req)
+			t.record(req, resp, err, start, time.Now())
+			return resp, err
+		}
+	}
+}
+
+// span is a finished client span
+type span struct {
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    []attribute
+	err      string
+}
+
+// counterKey identifies a counter series
+type counterKey struct {
+	name     string
+	endpoint string
+	status   string
+}
+
+func (t *Telemetry) record(req client.Request, resp *client.Response, err error, start, end time.Time) {
+	status, retries, units := 0, 0, 0
+	if resp != nil {
+		status, retries, units = resp.StatusCode, resp.Meta.Retries, resp.Meta.UnitsConsumed
+	}
+	var reqErr *client.RequestError
+	if errors.As(err, &reqErr) {
+		retries = reqErr.Retries
+	}
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		status = apiErr.StatusCode
+	}
+
+	s := span{
+		traceID:  t.traceID,
+		spanID:   randomHex(8),
+		parentID: t.parentID,
+		name:     req.Method + " " + req.Endpoint,
+		start:    start,
+		end:      end,
+		attrs: []attribute{
+			attr("http.request.method", req.Method),
+			attr("ahrefs.endpoint", req.Endpoint),
+			attr("ahrefs.retries", retries),
+			attr("ahrefs.units_consumed", units),
+		},
+	}
+	if s.traceID == "" {
+		s.traceID = randomHex(16)
+	}
+	if target := req.Params.Get("target"); target != "" {
+		s.attrs = append(s.attrs, attr("ahrefs.target", target))
+	}
+	if status != 0 {
+		s.attrs = append(s.attrs, attr("http.response.status_code", status))
+	}
+	if err != nil {
+		s.err = err.Error()
+	}
+
+	statusLabel := "error"
+	if status != 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tracesURL != "" {
+		t.spans = append(t.spans, s)
+	}
+	t.counters[counterKey{MetricRequests, req.Endpoint, statusLabel}]++
+	t.counters[counterKey{MetricRetries, req.Endpoint, ""}] += int64(retries)
+	t.counters[counterKey{MetricUnits, req.Endpoint, ""}] += int64(units)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Flush exports buffered spans and the current counter values
+func (t *Telemetry) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	var metrics map[string]interface{}
+	if t.metricsURL != "" && len(t.counters) > 0 {
+		metrics = t.metricsPayload(time.Now())
+	}
+	t.mu.Unlock()
+
+	var errs []error
+	if len(spans) > 0 {
+		errs = append(errs, t.post(ctx, t.tracesURL, t.tracesPayload(spans)))
+	}
+	if metrics != nil {
+		errs = append(errs, t.post(ctx, t.metricsURL, metrics))
+	}
+	return errors.Join(errs...)
+}
+
+// tracesPayload encodes spans as an ExportTraceServiceRequest
+func (t *Telemetry) tracesPayload(spans []span) map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		e := map[string]interface{}{
+			"traceId":           s.traceID,
+			"spanId":            s.spanID,
+			"name":              s.name,
+			"kind":              3, // SPAN_KIND_CLIENT
+			"startTimeUnixNano": nanos(s.start),
+			"endTimeUnixNano":   nanos(s.end),
+			"attributes":        s.attrs,
+			"status":            map[string]interface{}{"code": 1}, // STATUS_CODE_OK
+		}
+		if s.parentID != "" {
+			e["parentSpanId"] = s.parentID
+		}
+		if s.err != "" {
+			e["status"] = map[string]interface{}{"code": 2, "message": s.err} // STATUS_CODE_ERROR
+		}
+		encoded = append(encoded, e)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": t.resource},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": ScopeName},
+				"spans": encoded,
+			}},
+		}},
+	}
+}
+
+// metricsPayload encodes the counters as cumulative monotonic sums in an
+// ExportMetricsServiceRequest. The caller holds t.mu.
+func (t *Telemetry) metricsPayload(now time.Time) map[string]interface{} {
+	points := map[string][]interface{}{}
+	for k, v := range t.counters {
+		attrs := []attribute{attr("ahrefs.endpoint", k.endpoint)}
+		if k.status != "" {
+			attrs = append(attrs, attr("status", k.status))
+		}
+		points[k.name] = append(points[k.name], map[string]interface{}{
+			"attributes":        attrs,
+			"startTimeUnixNano": nanos(t.start),
+			"timeUnixNano":      nanos(now),
+			"asInt":             strconv.FormatInt(v, 10),
+		})
+	}
+
+	names := make([]string, 0, len(points))
+	for name := range points {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	units := map[string]string{MetricRequests: "{request}", MetricRetries: "{retry}", MetricUnits: "{unit}"}
+	var metrics []interface{}
+	for _, name := range names {
+		metrics = append(metrics, map[string]interface{}{
+			"name": name,
+			"unit": units[name],
+			"sum": map[string]interface{}{
+				"aggregationTemporality": 2, // AGGREGATION_TEMPORALITY_CUMULATIVE
+				"isMonotonic":            true,
+				"dataPoints":             points[name],
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"resourceMetrics": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": t.resource},
+			"scopeMetrics": []interface{}{map[string]interface{}{
+				"scope":   map[string]interface{}{"name": ScopeName},
+				"metrics": metrics,
+			}},
+		}},
+	}
+}
+
+func (t *Telemetry) post(ctx context.Context, u string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range t.headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d %s", u, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
+
+// nanos formats a time as OTLP JSON encodes fixed64 values: a decimal string
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// attribute is an OTLP KeyValue
+type attribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func attr(key string, v interface{}) attribute {
+	switch v := v.(type) {
+	case int:
+		return attribute{key, map[string]interface{}{"intValue": strconv.Itoa(v)}}
+	default:
+		return attribute{key, map[string]interface{}{"stringValue": fmt.Sprint(v)}}
+	}
+}
@@ -0,0 +1,127 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aminemat/ahrefs-cli/pkg/client"
+)
+
+func TestFromEnv_Inert(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", "")
+	if tel := FromEnv(); tel != nil {
+		t.Errorf("FromEnv() with no endpoint = %v, want nil", tel)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	if tel := FromEnv(); tel != nil {
+		t.Errorf("FromEnv() with OTEL_SDK_DISABLED = %v, want nil", tel)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	traceID, spanID := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spanID != "00f067aa0ba902b7" {
+		t.Errorf("parseTraceparent() = %q, %q", traceID, spanID)
+	}
+	if traceID, _ := parseTraceparent("garbage"); traceID != "" {
+		t.Errorf("parseTraceparent(garbage) = %q, want empty", traceID)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	h := parseHeaders("api-key=secret, x-team=seo%20ops,broken")
+	if h.Get("Api-Key") != "secret" || h.Get("X-Team") != "seo ops" || len(h) != 2 {
+		t.Errorf("parseHeaders() = %v", h)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]map[string]interface{}{}
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		received[r.URL.Path] = body
+		mu.Unlock()
+		if r.Header.Get("Api-Key") != "secret" {
+			t.Errorf("collector request missing configured header")
+		}
+	}))
+	defer collector.Close()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-API-Units-Consumed", "25")
+		w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+
+	tel := New(collector.URL+"/v1/traces", collector.URL+"/v1/metrics", http.Header{"Api-Key": {"secret"}}, "test")
+	tel.traceID, tel.parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	c := client.NewClient(client.Config{APIKey: "k", BaseURL: api.URL, Middleware: []client.Middleware{tel.Middleware()}})
+
+	if _, err := c.Get(context.Background(), "/site-explorer/backlinks", map[string][]string{"target": {"ahrefs.com"}}); err != nil {
+		t.Fatalf("Client.Get() error = %v", err)
+	}
+	if err := tel.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	traces, _ := json.Marshal(received["/v1/traces"])
+	for _, want := range []string{
+		`"name":"GET /site-explorer/backlinks"`,
+		`"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`,
+		`"parentSpanId":"00f067aa0ba902b7"`,
+		`{"key":"ahrefs.target","value":{"stringValue":"ahrefs.com"}}`,
+		`{"key":"ahrefs.units_consumed","value":{"intValue":"25"}}`,
+		`{"key":"http.response.status_code","value":{"intValue":"200"}}`,
+		`{"key":"service.name","value":{"stringValue":"test"}}`,
+	} {
+		if !strings.Contains(string(traces), want) {
+			t.Errorf("exported traces missing %s:\n%s", want, traces)
+		}
+	}
+
+	metrics, _ := json.Marshal(received["/v1/metrics"])
+	for _, want := range []string{
+		`"name":"ahrefs.client.requests"`,
+		`"name":"ahrefs.client.units_consumed"`,
+		`"asInt":"25"`,
+		`"isMonotonic":true`,
+	} {
+		if !strings.Contains(string(metrics), want) {
+			t.Errorf("exported metrics missing %s:\n%s", want, metrics)
+		}
+	}
+}
+
+func TestMiddleware_Error(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer api.Close()
+
+	tel := New("http://unused/v1/traces", "", nil, "test")
+	c := client.NewClient(client.Config{APIKey: "k", BaseURL: api.URL, Middleware: []client.Middleware{tel.Middleware()}})
+	c.Get(context.Background(), "/site-explorer/metrics", nil)
+
+	if len(tel.spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(tel.spans))
+	}
+	s := tel.spans[0]
+	if s.err == "" || len(s.traceID) != 32 || s.parentID != "" {
+		t.Errorf("span = %+v, want an error span in a new trace", s)
+	}
+	if tel.counters[counterKey{MetricRequests, "/site-explorer/metrics", "401"}] != 1 {
+		t.Errorf("counters = %v, want one 401 request", tel.counters)
+	}
+}